@@ -397,6 +397,27 @@ func main() {
 	services.StartObjectStorageHealthCheck(database.DB)
 	logrus.Info("ObjectStorageHealthCheck service started")
 
+	// 启动 Salt 定时作业调度器
+	services.NewSaltScheduleService(database.DB).Start()
+	logrus.Info("SaltSchedule service started")
+
+	// 启动 Salt Master minion 清单同步
+	services.NewSaltMasterService(database.DB).Start(5 * time.Minute)
+	logrus.Info("SaltMaster inventory sync started")
+
+	// 启动动态 minion 分组刷新（定时 + minion 启动事件触发）
+	services.NewMinionDynamicGroupService(database.DB).Start(10 * time.Minute)
+	logrus.Info("MinionDynamicGroup refresher started")
+
+	// 启动配置漂移扫描调度器
+	services.NewSaltDriftService(database.DB).Start()
+	logrus.Info("SaltDrift scanner started")
+
+	// 启动外部清单来源同步调度器（同步结果需管理员审批后才写入清单）
+	services.NewInventoryService(database.DB)
+	services.NewInventorySourceService(database.DB).Start()
+	logrus.Info("InventorySource sync scheduler started")
+
 	// 优雅关闭
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
@@ -413,6 +434,40 @@ func main() {
 		services.StopObjectStorageHealthCheck()
 		logrus.Info("ObjectStorageHealthCheck service stopped")
 
+		// 停止 Salt 定时作业调度器
+		if scheduleService := services.GetSaltScheduleService(); scheduleService != nil {
+			scheduleService.Stop()
+			logrus.Info("SaltSchedule service stopped")
+		}
+
+		// 停止 Salt Master 清单同步
+		if masterService := services.GetSaltMasterService(); masterService != nil {
+			masterService.Stop()
+			logrus.Info("SaltMaster inventory sync stopped")
+		}
+
+		// 停止动态分组刷新
+		if dynamicGroupService := services.GetMinionDynamicGroupService(); dynamicGroupService != nil {
+			dynamicGroupService.Stop()
+			logrus.Info("MinionDynamicGroup refresher stopped")
+		}
+
+		// 停止配置漂移扫描调度器
+		if driftService := services.GetSaltDriftService(); driftService != nil {
+			driftService.Stop()
+			logrus.Info("SaltDrift scanner stopped")
+		}
+
+		// 停止外部清单来源同步调度器
+		if sourceService := services.GetInventorySourceService(); sourceService != nil {
+			sourceService.Stop()
+			logrus.Info("InventorySource sync scheduler stopped")
+		}
+
+		// 关闭 SSH 连接池
+		services.GetSSHClientPool().Stop()
+		logrus.Info("SSH client pool closed")
+
 		// 关闭AI网关服务
 		if err := services.ShutdownAIGateway(); err != nil {
 			logrus.Error("Error shutting down AI Gateway:", err)
@@ -1199,12 +1254,19 @@ func setupAPIRoutes(r *gin.Engine, cfg *config.Config, jobService *services.JobS
 		jobs.GET("/:jobId/status", jobController.GetJobStatus)
 		jobs.POST("/:jobId/cancel", jobController.CancelJob)
 		jobs.GET("/:jobId/output", jobController.GetJobOutput)
+		jobs.POST("/:jobId/diagnose", jobController.DiagnoseJob)
 		jobs.GET("/clusters", jobController.ListClusters)
 	}
 
 	// 初始化 SaltStack 作业持久化服务
 	saltJobService := services.NewSaltJobService(database.DB, cache.RDB)
 	_ = saltJobService // 服务会自动注册为单例，供 handler 使用
+	// 初始化危险命令执行审批服务（高危命令需审批后执行）
+	services.NewSaltCommandApprovalService(database.DB)
+	// 初始化多 Master 拓扑服务（minion 按密钥归属路由到对应 Master）
+	services.NewSaltMasterService(database.DB)
+	// 初始化动态 minion 分组服务（基于 grains 规则计算成员）
+	services.NewMinionDynamicGroupService(database.DB)
 
 	// SaltStack 管理路由（需要认证）
 	saltStackHandler := handlers.NewSaltStackHandler(cfg, cache.RDB)
@@ -1226,6 +1288,7 @@ func setupAPIRoutes(r *gin.Engine, cfg *config.Config, jobService *services.JobS
 		saltstack.POST("/execute-custom/async", saltStackHandler.ExecuteCustomCommandAsync)
 		saltstack.GET("/progress/:opId", saltStackHandler.GetProgress)
 		saltstack.GET("/progress/:opId/stream", saltStackHandler.StreamProgress)
+		saltstack.POST("/progress/:opId/control", saltStackHandler.ControlProgress) // 分批执行的暂停/恢复/中止
 		// 连接性调试端点（仅限已登录用户调用，用于排查Salt API问题）
 		saltstack.GET("/_debug", saltStackHandler.DebugSaltConnectivity)
 
@@ -1235,6 +1298,11 @@ func setupAPIRoutes(r *gin.Engine, cfg *config.Config, jobService *services.JobS
 		saltstack.PUT("/groups/:id", saltStackHandler.UpdateMinionGroup)
 		saltstack.DELETE("/groups/:id", saltStackHandler.DeleteMinionGroup)
 		saltstack.GET("/groups/:id/minions", saltStackHandler.GetGroupMinions)
+		// 动态分组（基于 grains 规则自动计算成员）
+		saltstack.POST("/groups/dynamic", middleware.AdminMiddleware(), saltStackHandler.CreateDynamicMinionGroup)
+		saltstack.POST("/groups/dynamic/preview", middleware.AdminMiddleware(), saltStackHandler.PreviewMinionGroupRule)
+		saltstack.POST("/groups/dynamic/refresh", middleware.AdminMiddleware(), saltStackHandler.RefreshDynamicMinionGroups)
+		saltstack.PUT("/groups/:id/rule", middleware.AdminMiddleware(), saltStackHandler.UpdateMinionGroupRule)
 		saltstack.POST("/minions/set-group", saltStackHandler.SetMinionGroup)
 		saltstack.POST("/minions/batch-set-groups", saltStackHandler.BatchSetMinionGroups)
 		// 批量为 Minion 安装 Categraf
@@ -1256,7 +1324,28 @@ func setupAPIRoutes(r *gin.Engine, cfg *config.Config, jobService *services.JobS
 		saltstack.GET("/jobs/history", saltStackHandler.GetSaltJobHistory)
 		saltstack.GET("/jobs/by-task/:task_id", saltStackHandler.GetSaltJobByTaskID)
 		saltstack.POST("/jobs/cleanup", saltStackHandler.TriggerJobCleanup)
-	}
+		// 规范化作业结果：输出检索、按 minion 结果、minion 作业时间线
+		saltstack.GET("/jobs/results/search", saltStackHandler.SearchSaltJobResults)
+		saltstack.GET("/jobs/:jid/minions", saltStackHandler.GetSaltJobMinionResults)
+		saltstack.GET("/minions/:minionId/timeline", saltStackHandler.GetMinionJobTimeline)
+		// 危险命令执行申请（审批通过 /api/approvals/requests/:id/approve 完成）
+		saltstack.GET("/command-approvals", saltStackHandler.ListCommandApprovals)
+		saltstack.GET("/command-approvals/:id", saltStackHandler.GetCommandApproval)
+		// 定时/周期作业
+		handlers.NewSaltScheduleHandler(services.NewSaltScheduleService(database.DB)).RegisterRoutes(saltstack)
+		// 多 Master / Syndic 拓扑
+		handlers.NewSaltMasterHandler(services.NewSaltMasterService(database.DB)).RegisterRoutes(saltstack)
+		// 制品库与制品分发（file.managed + sha256 校验）
+		handlers.NewSaltArtifactHandler(services.NewSaltArtifactService(database.DB)).RegisterRoutes(saltstack)
+		// 配置漂移检测（test=True 周期扫描与漂移报告）
+		handlers.NewSaltDriftHandler(services.NewSaltDriftService(database.DB)).RegisterRoutes(saltstack)
+	}
+
+	// 统一主机清单（CMDB）：Ansible 主机、Slurm 节点、Salt Minion、主机模板按机器去重关联
+	inventory := api.Group("/inventory")
+	inventory.Use(middleware.AuthMiddlewareWithSession())
+	handlers.NewInventoryHandler(services.NewInventoryService(database.DB)).RegisterRoutes(inventory)
+	handlers.NewInventorySourceHandler(services.NewInventorySourceService(database.DB)).RegisterRoutes(inventory)
 
 	// 仪表板统计路由（需要认证）
 	dashboard := api.Group("/dashboard")
@@ -1284,6 +1373,7 @@ func setupAPIRoutes(r *gin.Engine, cfg *config.Config, jobService *services.JobS
 	jobTemplateController.RegisterRoutes(api)
 
 	// AI助手管理路由（需要认证）
+	services.NewAIToolService(database.DB)
 	aiAssistantController := controllers.NewAIAssistantController()
 	ai := api.Group("/ai")
 	ai.Use(middleware.AuthMiddlewareWithSession())
@@ -1294,6 +1384,7 @@ func setupAPIRoutes(r *gin.Engine, cfg *config.Config, jobService *services.JobS
 		ai.GET("/configs/:id", aiAssistantController.GetConfig)
 		ai.PUT("/configs/:id", aiAssistantController.UpdateConfig)
 		ai.DELETE("/configs/:id", aiAssistantController.DeleteConfig)
+		ai.GET("/configs/:id/mcp", middleware.AdminMiddleware(), aiAssistantController.DiscoverMCP)
 
 		// 对话管理
 		ai.POST("/conversations", aiAssistantController.CreateConversation)
@@ -1306,13 +1397,21 @@ func setupAPIRoutes(r *gin.Engine, cfg *config.Config, jobService *services.JobS
 		// 消息管理
 		ai.POST("/conversations/:id/messages", aiAssistantController.SendMessage)
 		ai.GET("/conversations/:id/messages", aiAssistantController.GetMessages)
+		ai.POST("/conversations/:id/messages/stream", aiAssistantController.StreamMessage)
 		ai.GET("/messages/:id/status", aiAssistantController.GetMessageStatus)
 		ai.PATCH("/messages/:id/stop", aiAssistantController.StopMessage)
+		ai.GET("/messages/:id/stream", aiAssistantController.StreamMessageEvents)
 
 		// 集群操作
 		ai.POST("/cluster-operations", aiAssistantController.SubmitClusterOperation)
 		ai.GET("/cluster-operations/:id/status", aiAssistantController.GetOperationStatus)
 
+		// 工具调用（变更类工具需用户确认后执行）
+		ai.GET("/tools", aiAssistantController.ListTools)
+		ai.GET("/tool-confirmations", aiAssistantController.ListToolConfirmations)
+		ai.POST("/tool-confirmations/:id/confirm", aiAssistantController.ConfirmToolCall)
+		ai.POST("/tool-confirmations/:id/reject", aiAssistantController.RejectToolCall)
+
 		// 系统监控
 		ai.GET("/system/health", aiAssistantController.GetSystemHealth)
 		ai.GET("/system/usage", aiAssistantController.GetUsageStats)
@@ -1326,6 +1425,18 @@ func setupAPIRoutes(r *gin.Engine, cfg *config.Config, jobService *services.JobS
 		ai.POST("/quick-chat", aiAssistantController.QuickChat)
 	}
 
+	// AI token/费用预算与用量报表路由
+	aiBudgetController := controllers.NewAIBudgetController(database.DB)
+	aiBudgetController.RegisterRoutes(api)
+
+	// 平台 MCP 端点（个人 API 令牌认证）与令牌管理路由
+	mcpServerController := controllers.NewMCPServerController(database.DB, jobService)
+	mcpServerController.RegisterRoutes(api)
+
+	// 检索增强知识源管理与检索路由
+	knowledgeController := controllers.NewKnowledgeController(database.DB, jobService)
+	knowledgeController.RegisterRoutes(api)
+
 	// 对象存储管理路由（需要认证）
 	objectStorageController := controllers.NewObjectStorageController(database.DB)
 	objectStorage := api.Group("/object-storage")
@@ -1458,6 +1569,12 @@ func setupAPIRoutes(r *gin.Engine, cfg *config.Config, jobService *services.JobS
 		security.GET("/geoip/:ip", securityHandler.LookupIPGeoInfo)
 		security.POST("/geoip/batch", securityHandler.BatchLookupIPGeoInfo)
 		security.GET("/geoip/stats", securityHandler.GetGeoIPCacheStats)
+
+		// SSH 主机密钥（known_hosts）管理
+		handlers.NewSSHKnownHostsHandler(services.NewSSHKnownHostsService(database.DB)).RegisterRoutes(security)
+
+		// SSH 用户证书颁发机构（短期证书）
+		handlers.NewSSHCAHandler(services.NewSSHCAService(database.DB)).RegisterRoutes(security)
 	}
 
 	// ArgoCD GitOps 管理路由（需要认证）
@@ -1571,6 +1688,13 @@ func setupAPIRoutes(r *gin.Engine, cfg *config.Config, jobService *services.JobS
 		audit.POST("/cleanup", middleware.AdminMiddleware(), auditHandler.CleanupAuditLogs)
 	}
 
+	// Web 终端路由（需要认证；websocket 通过 cookie 认证）
+	terminal := api.Group("/terminal")
+	terminal.Use(middleware.AuthMiddlewareWithSession())
+	{
+		handlers.NewTerminalHandler(services.NewTerminalService(database.DB)).RegisterRoutes(terminal)
+	}
+
 	// 集群权限管理路由（需要认证）
 	clusterPermissionHandler := handlers.NewClusterPermissionHandler(database.DB)
 	clusterPerms := api.Group("/cluster-permissions")
@@ -1,6 +1,8 @@
 package controllers
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -16,6 +18,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
 )
 
 // AIAssistantController AI助手控制器
@@ -89,6 +92,9 @@ func (ctrl *AIAssistantController) CreateConfig(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if !checkMCPConfigRequest(c, req.MCPConfig, nil) {
+		return
+	}
 
 	if err := ctrl.aiService.CreateConfig(&req); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -112,11 +118,36 @@ func (ctrl *AIAssistantController) GetConfig(c *gin.Context) {
 		return
 	}
 
-	// 隐藏API密钥
+	// 隐藏API密钥及MCP请求头、环境变量
 	config.APIKey = "***"
+	if config.MCPConfig != nil {
+		config.MCPConfig.MaskSecrets()
+	}
 	c.JSON(http.StatusOK, gin.H{"data": config})
 }
 
+// DiscoverMCP 连接配置中的 MCP 服务器，返回其工具、资源、提示词及允许提供给模型的工具
+// GET /api/ai/configs/:id/mcp
+func (ctrl *AIAssistantController) DiscoverMCP(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的配置ID"})
+		return
+	}
+
+	config, err := ctrl.aiService.GetConfig(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "配置不存在"})
+		return
+	}
+	if config.MCPConfig == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "该配置未设置MCP服务器"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": services.GetMCPService().Discover(c.Request.Context(), config)})
+}
+
 // ListConfigs 获取AI配置列表
 func (ctrl *AIAssistantController) ListConfigs(c *gin.Context) {
 	configs, err := ctrl.aiService.ListConfigs()
@@ -142,6 +173,15 @@ func (ctrl *AIAssistantController) UpdateConfig(c *gin.Context) {
 		return
 	}
 
+	existing, err := ctrl.aiService.GetConfig(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "配置不存在"})
+		return
+	}
+	if !checkMCPConfigRequest(c, req.MCPConfig, existing.MCPConfig) {
+		return
+	}
+
 	req.ID = uint(id)
 	if err := ctrl.aiService.UpdateConfig(&req); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -151,6 +191,24 @@ func (ctrl *AIAssistantController) UpdateConfig(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "AI配置更新成功"})
 }
 
+// checkMCPConfigRequest 新增或修改含 MCP 服务器的配置仅限管理员（MCP 服务器可在后端主机上启动程序），并校验服务器设置
+func checkMCPConfigRequest(c *gin.Context, config, existing *models.MCPConfig) bool {
+	if config == nil && existing == nil {
+		return true
+	}
+	if !middleware.HasRole(c, "admin") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "仅管理员可以配置MCP服务器"})
+		return false
+	}
+	if config != nil {
+		if err := services.ValidateMCPConfig(config); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return false
+		}
+	}
+	return true
+}
+
 // DeleteConfig 删除AI配置
 func (ctrl *AIAssistantController) DeleteConfig(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
@@ -393,6 +451,9 @@ func (ctrl *AIAssistantController) SendMessage(c *gin.Context) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "无权访问此对话"})
 		return
 	}
+	if rejectOverBudget(c, userID, conversation.ConfigID) {
+		return
+	}
 
 	// 异步发送消息到队列
 	messageID, err := ctrl.messageQueueService.SendChatRequest(
@@ -485,7 +546,7 @@ func (ctrl *AIAssistantController) SubmitClusterOperation(c *gin.Context) {
 	}
 
 	var req struct {
-		Operation   string                 `json:"operation" binding:"required"`
+		Operation   string                 `json:"operation" binding:"required"` // 工具名，见 GET /api/ai/tools
 		Parameters  map[string]interface{} `json:"parameters"`
 		ClusterID   *uint                  `json:"cluster_id"`
 		Description string                 `json:"description"`
@@ -764,6 +825,9 @@ func (ctrl *AIAssistantController) QuickChat(c *gin.Context) {
 		}
 		configID = defaultConfig.ID
 	}
+	if rejectOverBudget(c, userID, configID) {
+		return
+	}
 
 	// 异步处理快速聊天
 	messageID, err := ctrl.messageQueueService.SendChatRequest(
@@ -1097,3 +1161,237 @@ func (ctrl *AIAssistantController) StopMessage(c *gin.Context) {
 		"stopped_at": time.Now().Format(time.RFC3339),
 	})
 }
+
+const (
+	// 单次 SSE 连接的最长持续时间
+	maxAIStreamDuration = 10 * time.Minute
+	// 无新事件时发送心跳注释的间隔，避免代理断开空闲连接
+	aiStreamHeartbeat = 15 * time.Second
+)
+
+// StreamMessage 发送消息并通过 SSE 推送流式回复
+// POST /api/ai/conversations/:id/messages/stream
+// 事件依次为 start（携带 message_id，可用于停止）、delta、tool，以 done/stopped/error 结束
+func (ctrl *AIAssistantController) StreamMessage(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	conversationID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的对话ID"})
+		return
+	}
+
+	var req struct {
+		Message string `json:"message" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 检查对话权限
+	conversation, err := ctrl.aiService.GetConversation(uint(conversationID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "对话不存在"})
+		return
+	}
+	if conversation.UserID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "无权访问此对话"})
+		return
+	}
+	if rejectOverBudget(c, userID, conversation.ConfigID) {
+		return
+	}
+
+	messageID, err := ctrl.messageQueueService.SendChatRequest(
+		userID,
+		&[]uint{uint(conversationID)}[0],
+		req.Message,
+		map[string]interface{}{
+			"page":            c.GetHeader("Referer"),
+			"conversation_id": conversationID,
+		},
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "消息发送失败"})
+		return
+	}
+
+	ctrl.streamMessageEvents(c, messageID, "")
+}
+
+// StreamMessageEvents 订阅消息的流式回复，断线后可通过 Last-Event-ID 续传
+// GET /api/ai/messages/:id/stream
+func (ctrl *AIAssistantController) StreamMessageEvents(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	messageID := c.Param("id")
+	message, err := ctrl.messageQueueService.GetMessage(messageID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "消息不存在或已过期"})
+		return
+	}
+	if message.UserID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "无权访问此消息"})
+		return
+	}
+
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+	ctrl.streamMessageEvents(c, messageID, lastEventID)
+}
+
+// streamMessageEvents 从消息的事件流读取并以 SSE 转发，直至结束事件、客户端断开或超时
+func (ctrl *AIAssistantController) streamMessageEvents(c *gin.Context, messageID, lastEventID string) {
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	if lastEventID == "" {
+		writeAIStreamEvent(c, "", services.AIStreamEventStart, gin.H{"type": services.AIStreamEventStart, "message_id": messageID})
+	}
+
+	ctx := c.Request.Context()
+	deadline := time.Now().Add(maxAIStreamDuration)
+	for time.Now().Before(deadline) {
+		events, err := ctrl.messageQueueService.ReadStreamEvents(ctx, messageID, lastEventID, aiStreamHeartbeat)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			logrus.Errorf("读取流式事件失败 %s: %v", messageID, err)
+			writeAIStreamEvent(c, "", services.AIStreamEventError, gin.H{"type": services.AIStreamEventError, "message_id": messageID, "error": "读取回复失败"})
+			return
+		}
+		if len(events) == 0 {
+			c.Writer.WriteString(": ping\n\n")
+			c.Writer.Flush()
+			continue
+		}
+		for i := range events {
+			event := &events[i]
+			lastEventID = event.ID
+			if event.Type == "" {
+				continue
+			}
+			writeAIStreamEvent(c, event.ID, event.Type, event)
+			if event.Final() {
+				return
+			}
+		}
+	}
+}
+
+// writeAIStreamEvent 写出一条 SSE 事件并立即刷新
+func writeAIStreamEvent(c *gin.Context, id, event string, data interface{}) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+	if id != "" {
+		fmt.Fprintf(c.Writer, "id: %s\n", id)
+	}
+	fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", event, payload)
+	c.Writer.Flush()
+}
+
+// ListTools 获取 AI 助手可调用的工具及其参数定义
+func (ctrl *AIAssistantController) ListTools(c *gin.Context) {
+	toolService := services.GetAIToolService()
+	if toolService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "AI工具服务未初始化"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": toolService.Tools()})
+}
+
+// ListToolConfirmations 获取当前用户的变更类工具调用
+// GET /api/ai/tool-confirmations?conversation_id=1&status=pending
+func (ctrl *AIAssistantController) ListToolConfirmations(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+	toolService := services.GetAIToolService()
+	if toolService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "AI工具服务未初始化"})
+		return
+	}
+
+	conversationID, _ := strconv.ParseUint(c.Query("conversation_id"), 10, 32)
+	confirmations, err := toolService.ListConfirmations(userID, uint(conversationID), c.Query("status"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": confirmations, "total": len(confirmations)})
+}
+
+// ConfirmToolCall 确认并执行 AI 助手发起的变更类工具调用
+// POST /api/ai/tool-confirmations/:id/confirm
+func (ctrl *AIAssistantController) ConfirmToolCall(c *gin.Context) {
+	ctrl.decideToolCall(c, true)
+}
+
+// RejectToolCall 拒绝 AI 助手发起的变更类工具调用
+// POST /api/ai/tool-confirmations/:id/reject
+func (ctrl *AIAssistantController) RejectToolCall(c *gin.Context) {
+	ctrl.decideToolCall(c, false)
+}
+
+func (ctrl *AIAssistantController) decideToolCall(c *gin.Context, confirm bool) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的确认ID"})
+		return
+	}
+	toolService := services.GetAIToolService()
+	if toolService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "AI工具服务未初始化"})
+		return
+	}
+
+	// 以确认者当前的角色与集群权限执行，而非发起调用时的权限
+	caller := toolService.ResolveCaller(userID)
+	var confirmation *models.AIToolConfirmation
+	if confirm {
+		confirmation, err = toolService.Confirm(c.Request.Context(), uint(id), caller)
+	} else {
+		confirmation, err = toolService.Reject(uint(id), caller)
+	}
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "确认记录不存在"})
+		return
+	case errors.Is(err, services.ErrAIToolConfirmationNotPending):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 清理该对话的消息缓存，使追加的执行结果立即可见
+	if confirmation.ConversationID != 0 {
+		ctrl.cacheService.DeleteKeysWithPattern(fmt.Sprintf("messages:%d:*", confirmation.ConversationID))
+	}
+	c.JSON(http.StatusOK, gin.H{"data": confirmation})
+}
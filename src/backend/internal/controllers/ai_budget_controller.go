@@ -0,0 +1,255 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/middleware"
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// AIBudgetController AI token/费用预算管理与用量报表
+type AIBudgetController struct {
+	budgetService *services.AIBudgetService
+}
+
+// NewAIBudgetController 创建 AI 预算控制器
+func NewAIBudgetController(db *gorm.DB) *AIBudgetController {
+	return &AIBudgetController{budgetService: services.NewAIBudgetService(db)}
+}
+
+// RegisterRoutes 注册路由（查看本人预算对所有登录用户开放，预算管理、放行与报表仅管理员可用）
+func (ctrl *AIBudgetController) RegisterRoutes(r *gin.RouterGroup) {
+	ai := r.Group("/ai")
+	ai.Use(middleware.AuthMiddlewareWithSession())
+	{
+		ai.GET("/budgets/me", ctrl.MyBudgets)
+
+		admin := ai.Group("", middleware.AdminMiddleware())
+		admin.GET("/budgets", ctrl.ListBudgets)
+		admin.POST("/budgets", ctrl.CreateBudget)
+		admin.PUT("/budgets/:id", ctrl.UpdateBudget)
+		admin.DELETE("/budgets/:id", ctrl.DeleteBudget)
+		admin.POST("/budgets/:id/overrides", ctrl.CreateOverride)
+		admin.DELETE("/budgets/:id/overrides/:overrideId", ctrl.DeleteOverride)
+		admin.GET("/usage/report", ctrl.UsageReport)
+	}
+}
+
+// MyBudgets 当前用户适用的预算及用量
+// GET /api/ai/budgets/me
+func (ctrl *AIBudgetController) MyBudgets(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+	statuses, err := ctrl.budgetService.UserStatuses(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": statuses})
+}
+
+// ListBudgets 列出全部预算及当前周期用量
+// GET /api/ai/budgets
+func (ctrl *AIBudgetController) ListBudgets(c *gin.Context) {
+	statuses, err := ctrl.budgetService.ListBudgets()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": statuses})
+}
+
+// CreateBudget 创建预算
+// POST /api/ai/budgets
+func (ctrl *AIBudgetController) CreateBudget(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUserID(c)
+	var req models.AIBudgetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	budget, err := ctrl.budgetService.CreateBudget(&req, userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ctrl.audit(c, models.AuditActionCreate, budget, nil)
+	c.JSON(http.StatusCreated, gin.H{"data": budget})
+}
+
+// UpdateBudget 更新预算
+// PUT /api/ai/budgets/:id
+func (ctrl *AIBudgetController) UpdateBudget(c *gin.Context) {
+	id, ok := aiBudgetParam(c, "id")
+	if !ok {
+		return
+	}
+	var req models.AIBudgetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	budget, err := ctrl.budgetService.UpdateBudget(id, &req)
+	if err != nil {
+		aiBudgetError(c, err)
+		return
+	}
+	ctrl.audit(c, models.AuditActionUpdate, budget, nil)
+	c.JSON(http.StatusOK, gin.H{"data": budget})
+}
+
+// DeleteBudget 删除预算
+// DELETE /api/ai/budgets/:id
+func (ctrl *AIBudgetController) DeleteBudget(c *gin.Context) {
+	id, ok := aiBudgetParam(c, "id")
+	if !ok {
+		return
+	}
+	budget, err := ctrl.budgetService.DeleteBudget(id)
+	if err != nil {
+		aiBudgetError(c, err)
+		return
+	}
+	ctrl.audit(c, models.AuditActionDelete, budget, nil)
+	c.JSON(http.StatusOK, gin.H{"message": "预算已删除"})
+}
+
+// CreateOverride 管理员放行：为预算追加额度或临时取消限制
+// POST /api/ai/budgets/:id/overrides
+func (ctrl *AIBudgetController) CreateOverride(c *gin.Context) {
+	id, ok := aiBudgetParam(c, "id")
+	if !ok {
+		return
+	}
+	userID, _ := middleware.GetCurrentUserID(c)
+	var req models.AIBudgetOverrideRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	override, err := ctrl.budgetService.CreateOverride(id, &req, userID)
+	if err != nil {
+		aiBudgetError(c, err)
+		return
+	}
+	ctrl.audit(c, models.AuditActionCreate, &models.AIBudget{ID: id}, override)
+	c.JSON(http.StatusCreated, gin.H{"data": override})
+}
+
+// DeleteOverride 撤销放行
+// DELETE /api/ai/budgets/:id/overrides/:overrideId
+func (ctrl *AIBudgetController) DeleteOverride(c *gin.Context) {
+	id, ok := aiBudgetParam(c, "id")
+	if !ok {
+		return
+	}
+	overrideID, ok := aiBudgetParam(c, "overrideId")
+	if !ok {
+		return
+	}
+	if err := ctrl.budgetService.DeleteOverride(id, overrideID); err != nil {
+		aiBudgetError(c, err)
+		return
+	}
+	ctrl.audit(c, models.AuditActionDelete, &models.AIBudget{ID: id}, &models.AIBudgetOverride{ID: overrideID, BudgetID: id})
+	c.JSON(http.StatusOK, gin.H{"message": "放行已撤销"})
+}
+
+// UsageReport 用量与费用报表，按模型、团队（用户组）和用户分组
+// GET /api/ai/usage/report?start_date=2006-01-02&end_date=2006-01-02（默认本月，结束日期包含当天）
+func (ctrl *AIBudgetController) UsageReport(c *gin.Context) {
+	now := time.Now().UTC()
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	to := now.Truncate(24 * time.Hour).Add(24 * time.Hour)
+	if v := c.Query("start_date"); v != "" {
+		parsed, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的开始日期"})
+			return
+		}
+		from = parsed
+	}
+	if v := c.Query("end_date"); v != "" {
+		parsed, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的结束日期"})
+			return
+		}
+		to = parsed.Add(24 * time.Hour)
+	}
+	if !to.After(from) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "结束日期不能早于开始日期"})
+		return
+	}
+
+	report, err := ctrl.budgetService.UsageReport(from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": report})
+}
+
+func (ctrl *AIBudgetController) audit(c *gin.Context, action models.AuditAction, budget *models.AIBudget, override *models.AIBudgetOverride) {
+	userID, _ := middleware.GetCurrentUserID(c)
+	username, _ := c.Get("username")
+	uname, _ := username.(string)
+	tags := []string{"ai_budget"}
+	var metadata interface{} = budget
+	if override != nil {
+		tags = append(tags, "ai_budget_override")
+		metadata = override
+	}
+	services.GetAuditService().NewAuditEntry(models.AuditCategoryAdmin, action).
+		WithUser(userID, uname, "").
+		WithResource("ai_budget", strconv.FormatUint(uint64(budget.ID), 10), budget.Name).
+		WithClient(c.ClientIP(), c.Request.UserAgent(), "").
+		WithStatus(models.AuditStatusSuccess).
+		WithMetadata(metadata).
+		WithTags(tags...).
+		SaveAsync()
+}
+
+func aiBudgetParam(c *gin.Context, name string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(name), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func aiBudgetError(c *gin.Context, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "记录不存在"})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
+
+// rejectOverBudget 消息入队前提前检查预算，超额时返回 429 并给出触发的预算
+func rejectOverBudget(c *gin.Context, userID, configID uint) bool {
+	budgets := services.GetAIBudgetService()
+	if budgets == nil {
+		return false
+	}
+	_, err := budgets.Check(userID, &models.AIAssistantConfig{ID: configID})
+	var exceeded *services.AIBudgetExceededError
+	if !errors.As(err, &exceeded) {
+		return false
+	}
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":  "已超出 AI 使用预算，请联系管理员调整预算或临时放行",
+		"budget": exceeded.Status,
+	})
+	return true
+}
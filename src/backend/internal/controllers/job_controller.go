@@ -2,24 +2,29 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
 
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/middleware"
 	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
 	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/services"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // JobController 作业管理控制器
 type JobController struct {
-	jobService *services.JobService
+	jobService       *services.JobService
+	diagnosisService *services.JobDiagnosisService
 }
 
 // NewJobController 创建作业控制器
 func NewJobController(jobService *services.JobService) *JobController {
 	return &JobController{
-		jobService: jobService,
+		jobService:       jobService,
+		diagnosisService: services.NewJobDiagnosisService(jobService),
 	}
 }
 
@@ -417,6 +422,90 @@ func (jc *JobController) GetJobOutput(c *gin.Context) {
 	})
 }
 
+// DiagnoseJob AI 诊断失败作业
+// @Summary AI 诊断失败作业
+// @Description 采集 sacct 记录、stderr 末尾、节点状态与监控指标、slurmd 日志后由 AI 分析失败原因，诊断结果保存到作业记录
+// @Tags 作业管理
+// @Accept json
+// @Produce json
+// @Param jobId path int true "作业ID"
+// @Param request body models.JobDiagnoseRequest false "诊断参数"
+// @Success 200 {object} models.Response{data=models.JobDiagnosis}
+// @Router /api/jobs/{jobId}/diagnose [post]
+func (jc *JobController) DiagnoseJob(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.Response{
+			Code:    401,
+			Message: "用户未认证",
+		})
+		return
+	}
+
+	jobID, err := strconv.ParseUint(c.Param("jobId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Code:    400,
+			Message: "无效的作业ID",
+		})
+		return
+	}
+
+	var req models.JobDiagnoseRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, models.Response{
+				Code:    400,
+				Message: "请求参数错误: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	username, _ := c.Get("username")
+	caller := services.AIToolCaller{UserID: userID}
+	caller.Username, _ = username.(string)
+	caller.IsAdmin = middleware.HasRole(c, "admin")
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 3*time.Minute)
+	defer cancel()
+	diagnosis, dctx, err := jc.diagnosisService.Diagnose(ctx, uint(jobID), caller, req)
+
+	entry := services.GetAuditService().NewAuditEntry(models.AuditCategorySlurm, models.AuditActionExecute).
+		WithUser(userID, caller.Username, "").
+		WithResource("job", c.Param("jobId"), "").
+		WithClient(c.ClientIP(), c.Request.UserAgent(), "").
+		WithTags("job_diagnosis")
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, services.ErrJobNotFinished), errors.Is(err, services.ErrAIConfigDisabled):
+			status = http.StatusBadRequest
+		case errors.Is(err, services.ErrJobDiagnosisRunning):
+			status = http.StatusConflict
+		case errors.Is(err, services.ErrAIBudgetExceeded):
+			status = http.StatusTooManyRequests
+		}
+		if status != http.StatusNotFound {
+			entry.WithStatus(models.AuditStatusFailed).WithError(err).SaveAsync()
+		}
+		c.JSON(status, models.Response{
+			Code:    status,
+			Message: "作业诊断失败: " + err.Error(),
+		})
+		return
+	}
+	entry.WithStatus(models.AuditStatusSuccess).WithMetadata(map[string]interface{}{"category": diagnosis.Category, "sources": diagnosis.Sources}).SaveAsync()
+
+	c.JSON(http.StatusOK, models.Response{
+		Code:    200,
+		Message: "success",
+		Data:    gin.H{"diagnosis": diagnosis, "context": dctx},
+	})
+}
+
 // GetDashboardStats 获取仪表板统计信息
 // @Summary 获取仪表板统计信息
 // @Description 获取用户的作业和集群统计信息
@@ -0,0 +1,207 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/middleware"
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// KnowledgeController 检索增强知识源管理与检索
+type KnowledgeController struct {
+	ragService *services.RAGService
+}
+
+// NewKnowledgeController 创建知识库控制器
+func NewKnowledgeController(db *gorm.DB, jobService *services.JobService) *KnowledgeController {
+	return &KnowledgeController{ragService: services.NewRAGService(db, jobService)}
+}
+
+// RegisterRoutes 注册路由（检索对所有登录用户开放，知识源管理仅管理员可用）
+func (ctrl *KnowledgeController) RegisterRoutes(r *gin.RouterGroup) {
+	knowledge := r.Group("/knowledge")
+	knowledge.Use(middleware.AuthMiddlewareWithSession())
+	{
+		knowledge.POST("/search", ctrl.Search)
+
+		admin := knowledge.Group("", middleware.AdminMiddleware())
+		admin.GET("/sources", ctrl.ListSources)
+		admin.POST("/sources", ctrl.CreateSource)
+		admin.GET("/sources/:id", ctrl.GetSource)
+		admin.PUT("/sources/:id", ctrl.UpdateSource)
+		admin.DELETE("/sources/:id", ctrl.DeleteSource)
+		admin.POST("/sources/:id/sync", ctrl.SyncSource)
+		admin.GET("/sources/:id/documents", ctrl.ListDocuments)
+	}
+}
+
+// Search 检索知识库；非管理员只能命中共享文档与本人的作业日志
+// POST /api/knowledge/search
+func (ctrl *KnowledgeController) Search(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+	var req models.KnowledgeSearchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	isAdmin := middleware.HasRole(c, "admin")
+	citations, err := ctrl.ragService.Search(c.Request.Context(), req, userID, isAdmin)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": citations, "vector_store": ctrl.ragService.VectorStore()})
+}
+
+// ListSources 列出知识源
+// GET /api/knowledge/sources
+func (ctrl *KnowledgeController) ListSources(c *gin.Context) {
+	sources, err := ctrl.ragService.ListSources()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": sources, "vector_store": ctrl.ragService.VectorStore()})
+}
+
+// GetSource 获取知识源
+// GET /api/knowledge/sources/:id
+func (ctrl *KnowledgeController) GetSource(c *gin.Context) {
+	id, ok := knowledgeSourceID(c)
+	if !ok {
+		return
+	}
+	source, err := ctrl.ragService.GetSource(id)
+	if err != nil {
+		respondKnowledgeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": source})
+}
+
+// CreateSource 创建知识源
+// POST /api/knowledge/sources
+func (ctrl *KnowledgeController) CreateSource(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUserID(c)
+	var req models.CreateKnowledgeSourceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	source, err := ctrl.ragService.CreateSource(&req, userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ctrl.audit(c, models.AuditActionCreate, source.ID, source.Name)
+	c.JSON(http.StatusCreated, gin.H{"data": source})
+}
+
+// UpdateSource 更新知识源
+// PUT /api/knowledge/sources/:id
+func (ctrl *KnowledgeController) UpdateSource(c *gin.Context) {
+	id, ok := knowledgeSourceID(c)
+	if !ok {
+		return
+	}
+	var req models.CreateKnowledgeSourceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	source, err := ctrl.ragService.UpdateSource(id, &req)
+	if err != nil {
+		respondKnowledgeError(c, err)
+		return
+	}
+	ctrl.audit(c, models.AuditActionUpdate, source.ID, source.Name)
+	c.JSON(http.StatusOK, gin.H{"data": source})
+}
+
+// DeleteSource 删除知识源及其全部片段
+// DELETE /api/knowledge/sources/:id
+func (ctrl *KnowledgeController) DeleteSource(c *gin.Context) {
+	id, ok := knowledgeSourceID(c)
+	if !ok {
+		return
+	}
+	if err := ctrl.ragService.DeleteSource(id); err != nil {
+		respondKnowledgeError(c, err)
+		return
+	}
+	ctrl.audit(c, models.AuditActionDelete, id, "")
+	c.JSON(http.StatusOK, gin.H{"message": "知识源已删除"})
+}
+
+// SyncSource 在后台同步知识源，进度通过知识源的 status 字段查看
+// POST /api/knowledge/sources/:id/sync
+func (ctrl *KnowledgeController) SyncSource(c *gin.Context) {
+	id, ok := knowledgeSourceID(c)
+	if !ok {
+		return
+	}
+	if err := ctrl.ragService.SyncSource(id); err != nil {
+		respondKnowledgeError(c, err)
+		return
+	}
+	ctrl.audit(c, models.AuditActionSync, id, "")
+	c.JSON(http.StatusAccepted, gin.H{"message": "同步已开始"})
+}
+
+// ListDocuments 列出知识源中已索引的文档
+// GET /api/knowledge/sources/:id/documents
+func (ctrl *KnowledgeController) ListDocuments(c *gin.Context) {
+	id, ok := knowledgeSourceID(c)
+	if !ok {
+		return
+	}
+	docs, err := ctrl.ragService.ListDocuments(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": docs})
+}
+
+func (ctrl *KnowledgeController) audit(c *gin.Context, action models.AuditAction, id uint, name string) {
+	userID, _ := middleware.GetCurrentUserID(c)
+	username, _ := c.Get("username")
+	uname, _ := username.(string)
+	services.GetAuditService().NewAuditEntry(models.AuditCategoryAdmin, action).
+		WithUser(userID, uname, "").
+		WithResource("knowledge_source", strconv.FormatUint(uint64(id), 10), name).
+		WithClient(c.ClientIP(), c.Request.UserAgent(), "").
+		WithStatus(models.AuditStatusSuccess).
+		WithTags("knowledge").
+		SaveAsync()
+}
+
+func knowledgeSourceID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的知识源ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func respondKnowledgeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "知识源不存在"})
+	case errors.Is(err, services.ErrKnowledgeSyncRunning):
+		c.JSON(http.StatusConflict, gin.H{"error": "知识源正在同步"})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,172 @@
+package controllers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/middleware"
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 单条 MCP 请求体的最大长度
+const maxMCPRequestBody = 1 << 20
+
+// MCPServerController 平台 MCP 端点与个人 API 令牌管理
+type MCPServerController struct {
+	mcpServer    *services.MCPServerService
+	tokenService *services.APITokenService
+}
+
+// NewMCPServerController 创建 MCP 服务端控制器
+func NewMCPServerController(db *gorm.DB, jobService *services.JobService) *MCPServerController {
+	return &MCPServerController{
+		mcpServer:    services.NewMCPServerService(db, jobService),
+		tokenService: services.NewAPITokenService(db),
+	}
+}
+
+// HandleMCP 处理 MCP Streamable HTTP 请求（无状态，响应均为 application/json）
+// POST /api/mcp
+func (ctrl *MCPServerController) HandleMCP(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxMCPRequestBody+1))
+	if err != nil || len(body) > maxMCPRequestBody {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "请求体过大"})
+		return
+	}
+
+	caller := ctrl.mcpServer.ResolveCaller(userID)
+	resp := ctrl.mcpServer.Handle(c.Request.Context(), caller, c.ClientIP(), body)
+	if resp == nil {
+		// 通知或客户端响应
+		c.Status(http.StatusAccepted)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// MCPMethodNotAllowed 服务端不主动推送消息，也不维护会话
+// GET/DELETE /api/mcp
+func (ctrl *MCPServerController) MCPMethodNotAllowed(c *gin.Context) {
+	c.Header("Allow", http.MethodPost)
+	c.JSON(http.StatusMethodNotAllowed, gin.H{"error": "仅支持 POST"})
+}
+
+// ListAPITokens 获取当前用户的 API 令牌
+// GET /api/api-tokens
+func (ctrl *MCPServerController) ListAPITokens(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+	tokens, err := ctrl.tokenService.List(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": tokens})
+}
+
+// CreateAPIToken 创建 API 令牌，明文令牌仅在本次响应中返回
+// POST /api/api-tokens
+func (ctrl *MCPServerController) CreateAPIToken(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+	var req models.CreateAPITokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	plain, token, err := ctrl.tokenService.Create(userID, req)
+	if errors.Is(err, services.ErrTooManyAPITokens) {
+		c.JSON(http.StatusConflict, gin.H{"error": "有效令牌数量已达上限，请先吊销不再使用的令牌"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	username, _ := c.Get("username")
+	name, _ := username.(string)
+	services.GetAuditService().NewAuditEntry(models.AuditCategorySecurity, models.AuditActionCreate).
+		WithUser(userID, name, "").
+		WithResource("api_token", strconv.FormatUint(uint64(token.ID), 10), token.Name).
+		WithClient(c.ClientIP(), c.Request.UserAgent(), "").
+		WithStatus(models.AuditStatusSuccess).
+		WithTags("api_token").
+		SaveAsync()
+
+	c.JSON(http.StatusCreated, gin.H{"data": token, "token": plain})
+}
+
+// RevokeAPIToken 吊销当前用户的 API 令牌
+// DELETE /api/api-tokens/:id
+func (ctrl *MCPServerController) RevokeAPIToken(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的令牌ID"})
+		return
+	}
+
+	if err := ctrl.tokenService.Revoke(userID, uint(id)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "令牌不存在或已吊销"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	username, _ := c.Get("username")
+	name, _ := username.(string)
+	services.GetAuditService().NewAuditEntry(models.AuditCategorySecurity, models.AuditActionDelete).
+		WithUser(userID, name, "").
+		WithResource("api_token", c.Param("id"), "").
+		WithClient(c.ClientIP(), c.Request.UserAgent(), "").
+		WithStatus(models.AuditStatusSuccess).
+		WithTags("api_token").
+		SaveAsync()
+
+	c.JSON(http.StatusOK, gin.H{"message": "令牌已吊销"})
+}
+
+// RegisterRoutes 注册路由
+func (ctrl *MCPServerController) RegisterRoutes(r *gin.RouterGroup) {
+	// MCP 端点仅接受个人 API 令牌
+	mcp := r.Group("/mcp")
+	mcp.Use(middleware.APITokenAuthMiddleware())
+	{
+		mcp.POST("", ctrl.HandleMCP)
+		mcp.GET("", ctrl.MCPMethodNotAllowed)
+		mcp.DELETE("", ctrl.MCPMethodNotAllowed)
+	}
+
+	// 令牌管理使用登录会话
+	tokens := r.Group("/api-tokens")
+	tokens.Use(middleware.AuthMiddlewareWithSession())
+	{
+		tokens.GET("", ctrl.ListAPITokens)
+		tokens.POST("", ctrl.CreateAPIToken)
+		tokens.DELETE("/:id", ctrl.RevokeAPIToken)
+	}
+}
@@ -245,8 +245,12 @@ func (c *SaltStackClientController) RegisterRoutes(api *gin.RouterGroup) {
 		saltstack.GET("/batch-install/:taskId/stream", c.StreamBatchInstallProgress)
 		saltstack.GET("/batch-install/:taskId/logs", c.GetBatchInstallTaskLogs)
 		saltstack.GET("/batch-install/:taskId/ssh-logs", c.GetBatchInstallSSHLogs)
+		saltstack.POST("/batch-install/:taskId/retry", c.RetryBatchInstall)
 		saltstack.GET("/batch-install", c.ListBatchInstallTasks)
 
+		// 操作系统支持矩阵
+		saltstack.GET("/supported-os", c.GetSupportedOS)
+
 		// SSH 测试（含 sudo 权限检查）
 		saltstack.POST("/ssh/test", c.TestSSHConnection)
 		saltstack.POST("/ssh/test-batch", c.BatchTestSSHConnections)
@@ -402,6 +406,58 @@ func (c *SaltStackClientController) CalculateParallel(ctx *gin.Context) {
 	})
 }
 
+// GetSupportedOS 获取批量安装支持的操作系统矩阵
+// @Summary 获取支持的操作系统
+// @Description 返回操作系统支持矩阵；传入 os/version/arch 时校验该组合是否受支持
+// @Tags SaltStack
+// @Produce json
+// @Param os query string false "发行版 ID（/etc/os-release 中的 ID）"
+// @Param version query string false "系统版本"
+// @Param arch query string false "CPU 架构"
+// @Success 200 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/saltstack/supported-os [get]
+func (c *SaltStackClientController) GetSupportedOS(ctx *gin.Context) {
+	scriptLoader := services.GetScriptLoader()
+	registry, err := scriptLoader.OSRegistry()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to load OS registry",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	osID := ctx.Query("os")
+	if osID == "" {
+		ctx.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data":    registry,
+		})
+		return
+	}
+
+	resolved, err := registry.Resolve(models.OSInfo{OS: osID, Version: ctx.Query("version"), Arch: ctx.Query("arch")})
+	if err != nil {
+		ctx.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data": gin.H{
+				"supported": false,
+				"reason":    err.Error(),
+			},
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"supported": true,
+			"os":        resolved,
+		},
+	})
+}
+
 // GetBatchInstallTask 获取批量安装任务状态
 // @Summary 获取批量安装任务状态
 // @Description 获取指定批量安装任务的详细状态信息
@@ -481,6 +537,52 @@ func (c *SaltStackClientController) ListBatchInstallTasks(ctx *gin.Context) {
 	})
 }
 
+// RetryBatchInstall 重试批量安装任务中失败的主机
+// @Summary 重试失败主机
+// @Description 复用任务保存的加密凭据，跳过已成功的主机，从各主机失败的阶段继续安装，日志追加到原任务
+// @Tags SaltStack
+// @Accept json
+// @Produce json
+// @Param taskId path string true "任务ID"
+// @Param request body object false "可选：{\"hosts\": [\"10.0.0.5\"]} 仅重试指定主机"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/saltstack/batch-install/{taskId}/retry [post]
+func (c *SaltStackClientController) RetryBatchInstall(ctx *gin.Context) {
+	taskID := ctx.Param("taskId")
+	var req struct {
+		Hosts []string `json:"hosts"`
+	}
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   "Invalid request format",
+				"message": err.Error(),
+			})
+			return
+		}
+	}
+
+	count, err := c.batchInstallService.RetryFailedHosts(ctx.Request.Context(), taskID, req.Hosts, ctx.GetUint("user_id"), middleware.HasRole(ctx, "admin"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Failed to retry batch installation",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"message":    "Retry started",
+		"task_id":    taskID,
+		"host_count": count,
+		"stream_url": fmt.Sprintf("/api/saltstack/batch-install/%s/stream", taskID),
+	})
+}
+
 // GetBatchInstallTaskLogs 获取批量安装任务日志
 // @Summary 获取批量安装任务日志
 // @Description 获取指定批量安装任务的详细日志记录
@@ -1167,6 +1269,8 @@ type UninstallMinionRequest struct {
 	KeyPath  string `json:"key_path"`
 	UseSudo  bool   `json:"use_sudo"`
 	SudoPass string `json:"sudo_pass"`
+
+	JumpHosts models.SSHJumpChain `json:"jump_hosts,omitempty"` // 跳板机链
 }
 
 // UninstallMinion 卸载 Minion（通过 SSH 远程卸载）
@@ -1239,6 +1343,8 @@ func (c *SaltStackClientController) UninstallMinion(ctx *gin.Context) {
 		KeyPath:  req.KeyPath,
 		UseSudo:  req.UseSudo,
 		SudoPass: req.SudoPass,
+
+		JumpHosts: req.JumpHosts,
 	}
 
 	err := c.batchInstallService.UninstallSaltMinion(ctx.Request.Context(), config)
@@ -1375,6 +1481,9 @@ func (c *SaltStackClientController) CreateHostTemplate(ctx *gin.Context) {
 	}
 
 	resp, _ := template.ToResponse(false, true)
+	for _, obs := range services.HostTemplateObservations(template, req.Hosts) {
+		services.ObserveInventoryAsync(obs)
+	}
 
 	logrus.WithFields(logrus.Fields{
 		"template_id": template.ID,
@@ -851,6 +851,7 @@ func (c *SlurmClusterController) InitNode(ctx *gin.Context) {
 		MungeKeyContent: mungeKey,
 		SlurmConfPath:   req.SlurmConfPath,
 		InstallPackages: req.InstallPackages,
+		JumpHosts:       c.service.NodeJumpHosts(&node),
 	}
 
 	// 执行初始化
@@ -636,6 +636,8 @@ func (c *SlurmController) ScaleUpAsync(ctx *gin.Context) {
 				User:     node.User,
 				KeyPath:  node.KeyPath,
 				Password: node.Password,
+
+				JumpHosts: node.JumpHosts,
 			}
 		}
 
@@ -758,6 +760,8 @@ func (c *SlurmController) ScaleUp(ctx *gin.Context) {
 			User:     node.User,
 			KeyPath:  node.KeyPath,
 			Password: node.Password,
+
+			JumpHosts: node.JumpHosts,
 		}
 	}
 
@@ -1310,6 +1314,7 @@ func (c *SlurmController) DeploySaltMinion(ctx *gin.Context) {
 		KeyPath:    req.KeyPath,
 		PrivateKey: req.PrivateKey,
 		Password:   req.Password,
+		JumpHosts:  req.JumpHosts,
 	}
 
 	saltConfig := services.SaltStackDeploymentConfig{
@@ -1352,6 +1357,7 @@ func (c *SlurmController) TestSSHConnection(ctx *gin.Context) {
 		KeyPath:    req.KeyPath,
 		PrivateKey: req.PrivateKey,
 		Password:   req.Password,
+		JumpHosts:  req.JumpHosts,
 	}
 
 	// 测试SSH连接并执行简单命令
@@ -1418,6 +1424,7 @@ func (c *SlurmController) TestBatchSSHConnection(ctx *gin.Context) {
 			KeyPath:    node.KeyPath,
 			PrivateKey: node.PrivateKey,
 			Password:   node.Password,
+			JumpHosts:  node.JumpHosts,
 		})
 	}
 
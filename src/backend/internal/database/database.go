@@ -2,6 +2,7 @@ package database
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/config"
@@ -266,12 +267,67 @@ func Migrate() error {
 	return nil
 }
 
+// mergeDuplicateAIUsageStats 创建 (user_id, config_id, date) 唯一索引前合并重复的使用统计行：
+// 各计数与用量累加到每组 ID 最小的行，平均响应时间按请求数加权，其余行删除
+func mergeDuplicateAIUsageStats(db *gorm.DB) error {
+	m := db.Migrator()
+	if !m.HasTable(&models.AIUsageStats{}) || m.HasIndex(&models.AIUsageStats{}, "idx_ai_usage_user_config_date") {
+		return nil
+	}
+
+	columns := []string{"request_count", "tokens_used", "success_count", "error_count"}
+	// 旧表可能尚无输入/输出 token 与费用列，由随后的 AutoMigrate 补齐
+	for _, column := range []string{"input_tokens", "output_tokens", "cost"} {
+		if m.HasColumn(&models.AIUsageStats{}, column) {
+			columns = append(columns, column)
+		}
+	}
+	var sets, sums []string
+	for _, column := range columns {
+		sets = append(sets, fmt.Sprintf("%s = d.%s", column, column))
+		sums = append(sums, fmt.Sprintf("SUM(%s) AS %s", column, column))
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		merge := fmt.Sprintf(`
+			UPDATE ai_usage_stats AS s SET %s, average_response = d.average_response
+			FROM (
+				SELECT MIN(id) AS id, %s,
+					CASE WHEN SUM(request_count) > 0
+						THEN SUM(average_response * request_count) / SUM(request_count)
+						ELSE MAX(average_response) END AS average_response
+				FROM ai_usage_stats
+				GROUP BY user_id, config_id, date
+				HAVING COUNT(*) > 1
+			) AS d
+			WHERE s.id = d.id`, strings.Join(sets, ", "), strings.Join(sums, ", "))
+		result := tx.Exec(merge)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		deleted := tx.Exec(`
+			DELETE FROM ai_usage_stats AS s USING ai_usage_stats AS k
+			WHERE s.user_id = k.user_id AND s.config_id = k.config_id AND s.date = k.date AND s.id > k.id`)
+		if deleted.Error != nil {
+			return deleted.Error
+		}
+		logrus.Infof("✓ Merged %d duplicate ai_usage_stats rows into %d", deleted.RowsAffected, result.RowsAffected)
+		return nil
+	})
+}
+
 func migrateTaskSchema() error {
 	if DB == nil {
 		return fmt.Errorf("primary database connection is not initialized")
 	}
 
 	logrus.Info("Migrating PostgreSQL task store tables...")
+	if err := mergeDuplicateAIUsageStats(DB); err != nil {
+		return fmt.Errorf("merge duplicate ai_usage_stats rows: %w", err)
+	}
 	if err := DB.AutoMigrate(
 		&models.User{},
 		&models.Project{},
@@ -328,6 +384,22 @@ func migrateTaskSchema() error {
 		// Salt 作业历史表（持久化用户任务）
 		&models.SaltJobHistory{},
 		&models.SaltJobConfig{},
+		&models.SaltJobSchedule{},
+		&models.SaltJobScheduleRun{},
+		&models.SaltCommandApproval{},
+		&models.SaltMaster{},
+		&models.SaltMasterMinion{},
+		&models.SaltJobMinionResult{},
+		&models.SaltArtifact{},
+		&models.SaltArtifactDistribution{},
+		&models.SaltArtifactDistributionResult{},
+		&models.SaltDriftPolicy{},
+		&models.SaltDriftRun{},
+		&models.SaltDriftNodeResult{},
+		&models.SaltDriftStateResult{},
+		&models.SaltDriftNodeStatus{},
+		&models.TerminalSession{},
+		&models.SSHKnownHost{},
 		// 安全管理表
 		&models.IPBlacklist{},
 		&models.IPWhitelist{},
@@ -315,6 +315,11 @@ func (h *AuditHandler) GetAuditActions(c *gin.Context) {
 			{"value": string(models.AuditActionBackupCreate), "label": "创建备份"},
 			{"value": string(models.AuditActionBackupRestore), "label": "恢复备份"},
 		},
+		string(models.AuditCategorySecurity): {
+			{"value": string(models.AuditActionTerminalSession), "label": "Web 终端会话"},
+			{"value": string(models.AuditActionSSHCertSign), "label": "签发 SSH 证书"},
+			{"value": string(models.AuditActionSSHCARotate), "label": "轮换 SSH CA"},
+		},
 	}
 
 	result := commonActions
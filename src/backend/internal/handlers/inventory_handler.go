@@ -0,0 +1,257 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/middleware"
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// InventoryHandler 统一主机清单（CMDB）处理器
+type InventoryHandler struct {
+	service *services.InventoryService
+}
+
+// NewInventoryHandler 创建主机清单处理器
+func NewInventoryHandler(service *services.InventoryService) *InventoryHandler {
+	return &InventoryHandler{service: service}
+}
+
+// RegisterRoutes 注册路由（挂载在已认证的 /inventory 分组下，读接口按调用者可见范围过滤，写操作仅管理员可用）
+func (h *InventoryHandler) RegisterRoutes(r *gin.RouterGroup) {
+	r.GET("/hosts", h.List)
+	r.GET("/hosts/:id", h.Get)
+	r.GET("/hosts/:id/overview", h.Overview)
+	r.GET("/export", h.Export)
+	r.GET("/ansible", h.AnsibleInventory)
+	r.GET("/ansible/script", h.AnsibleInventoryScript)
+
+	admin := r.Group("", middleware.AdminMiddleware())
+	admin.POST("/hosts", h.Create)
+	admin.PUT("/hosts/:id", h.Update)
+	admin.DELETE("/hosts/:id", h.Delete)
+	admin.POST("/hosts/:id/merge", h.Merge)
+	admin.POST("/import", h.Import)
+	admin.POST("/sync", h.Sync)
+}
+
+// List 查询调用者可见的清单主机（非管理员仅返回有权限的主机）
+// GET /api/inventory/hosts?q=&label=rack=a1&owner=&rack=&source=slurm&page=1&page_size=50
+func (h *InventoryHandler) List(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "50"))
+	hosts, total, err := h.service.List(c.Request.Context(), inventoryViewer(c), c.Query("q"), c.Query("label"), c.Query("owner"), c.Query("rack"), c.Query("source"), page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": hosts, "total": total})
+}
+
+// Get 获取主机（:id 可为 ID、IP、主机名、节点名或 Minion ID）
+// GET /api/inventory/hosts/:id
+func (h *InventoryHandler) Get(c *gin.Context) {
+	host, err := h.service.Lookup(c.Request.Context(), inventoryViewer(c), c.Param("id"))
+	if err != nil {
+		respondInventoryError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": host})
+}
+
+// Overview 获取主机在 Ansible、Slurm、Salt、安装记录、主机密钥中的全部信息
+// GET /api/inventory/hosts/:id/overview
+func (h *InventoryHandler) Overview(c *gin.Context) {
+	overview, err := h.service.Overview(c.Request.Context(), inventoryViewer(c), c.Param("id"))
+	if err != nil {
+		respondInventoryError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": overview})
+}
+
+// Create 手工登记主机（IP/主机名已存在时合并到已有记录）
+// POST /api/inventory/hosts
+func (h *InventoryHandler) Create(c *gin.Context) {
+	var req models.InventoryHostRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	host, created, err := h.service.Create(&req, c.GetString("username"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": host, "created": created})
+}
+
+// Update 更新主机归属信息
+// PUT /api/inventory/hosts/:id
+func (h *InventoryHandler) Update(c *gin.Context) {
+	id, ok := parseInventoryHostID(c)
+	if !ok {
+		return
+	}
+	var req models.InventoryHostRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	host, err := h.service.Update(id, &req, c.GetString("username"))
+	if err != nil {
+		respondInventoryError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": host})
+}
+
+// Delete 删除清单主机（不影响各子系统中的记录）
+// DELETE /api/inventory/hosts/:id
+func (h *InventoryHandler) Delete(c *gin.Context) {
+	id, ok := parseInventoryHostID(c)
+	if !ok {
+		return
+	}
+	if err := h.service.Delete(id, c.GetString("username")); err != nil {
+		respondInventoryError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// Merge 将重复主机合并到当前主机
+// POST /api/inventory/hosts/:id/merge {"source_id": 12}
+func (h *InventoryHandler) Merge(c *gin.Context) {
+	id, ok := parseInventoryHostID(c)
+	if !ok {
+		return
+	}
+	var req struct {
+		SourceID uint `json:"source_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	host, err := h.service.Merge(id, req.SourceID, c.GetString("username"))
+	if err != nil {
+		respondInventoryError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": host})
+}
+
+// Import 导入主机文件（CSV/JSON/YAML/Ansible INI），按 IP/主机名去重合并
+// POST /api/inventory/import
+func (h *InventoryHandler) Import(c *gin.Context) {
+	var req models.InventoryImportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	result, err := h.service.Import(&req, c.GetUint("user_id"), c.GetString("username"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": result})
+}
+
+// Sync 从 Ansible 项目、Slurm 节点、Salt Minion、主机模板回填清单
+// POST /api/inventory/sync
+func (h *InventoryHandler) Sync(c *gin.Context) {
+	result, err := h.service.Sync(c.Request.Context(), c.GetString("username"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": result})
+}
+
+// Export 导出调用者有权查看的主机
+// GET /api/inventory/export?format=ansible-json|ansible-yaml|salt-roster|slurm-hostlist|csv&label=&rack=&owner=&source=&group=&user=&sudo=
+func (h *InventoryHandler) Export(c *gin.Context) {
+	export, err := h.service.Export(c.Request.Context(), inventoryViewer(c), c.DefaultQuery("format", services.InventoryExportAnsibleJSON), inventoryExportQuery(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if c.Query("download") == "true" {
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", export.Filename))
+	}
+	c.Data(http.StatusOK, export.ContentType, export.Content)
+}
+
+// AnsibleInventory Ansible 动态清单接口：无参数时返回 --list 输出，?host=<name> 时返回 --host 输出
+// GET /api/inventory/ansible
+func (h *InventoryHandler) AnsibleInventory(c *gin.Context) {
+	if name := c.Query("host"); name != "" {
+		vars, err := h.service.AnsibleHostVars(c.Request.Context(), inventoryViewer(c), name)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, vars)
+		return
+	}
+	export, err := h.service.Export(c.Request.Context(), inventoryViewer(c), services.InventoryExportAnsibleJSON, inventoryExportQuery(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	c.Data(http.StatusOK, export.ContentType, export.Content)
+}
+
+// AnsibleInventoryScript 下载调用 /api/inventory/ansible 的动态清单脚本（ansible -i ai-infra-inventory.py）
+// GET /api/inventory/ansible/script
+func (h *InventoryHandler) AnsibleInventoryScript(c *gin.Context) {
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	host := c.GetHeader("X-Forwarded-Host")
+	if host == "" {
+		host = c.Request.Host
+	}
+	c.Header("Content-Disposition", `attachment; filename="ai-infra-inventory.py"`)
+	c.Data(http.StatusOK, "text/x-python; charset=utf-8", []byte(services.AnsibleInventoryScript(scheme+"://"+host)))
+}
+
+func inventoryViewer(c *gin.Context) services.InventoryViewer {
+	return services.InventoryViewer{UserID: c.GetUint("user_id"), Username: c.GetString("username"), IsAdmin: middleware.HasRole(c, "admin")}
+}
+
+func inventoryExportQuery(c *gin.Context) services.InventoryExportQuery {
+	return services.InventoryExportQuery{
+		Label:  c.Query("label"),
+		Rack:   c.Query("rack"),
+		Owner:  c.Query("owner"),
+		Source: c.Query("source"),
+		Group:  c.Query("group"),
+		User:   c.Query("user"),
+		Sudo:   c.Query("sudo") == "true",
+	}
+}
+
+func parseInventoryHostID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "invalid id"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func respondInventoryError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrInventoryHostNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+}
@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/middleware"
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// InventorySourceHandler 外部清单来源（NetBox、Prometheus、DHCP、Ansible 动态清单）处理器
+type InventorySourceHandler struct {
+	service *services.InventorySourceService
+}
+
+// NewInventorySourceHandler 创建外部清单来源处理器
+func NewInventorySourceHandler(service *services.InventorySourceService) *InventorySourceHandler {
+	return &InventorySourceHandler{service: service}
+}
+
+// RegisterRoutes 注册路由（挂载在已认证的 /inventory 分组下，仅管理员可用）
+func (h *InventorySourceHandler) RegisterRoutes(r *gin.RouterGroup) {
+	admin := r.Group("", middleware.AdminMiddleware())
+	admin.GET("/sources", h.ListSources)
+	admin.POST("/sources", h.CreateSource)
+	admin.GET("/sources/:id", h.GetSource)
+	admin.PUT("/sources/:id", h.UpdateSource)
+	admin.DELETE("/sources/:id", h.DeleteSource)
+	admin.GET("/sources/:id/preview", h.Preview)
+	admin.POST("/sources/:id/sync", h.Sync)
+
+	admin.GET("/changesets", h.ListChangesets)
+	admin.GET("/changesets/:id", h.GetChangeset)
+	admin.POST("/changesets/:id/approve", h.Approve)
+	admin.POST("/changesets/:id/reject", h.Reject)
+}
+
+// ListSources 获取外部来源列表
+// GET /api/inventory/sources
+func (h *InventorySourceHandler) ListSources(c *gin.Context) {
+	sources, err := h.service.ListSources()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": sources})
+}
+
+// CreateSource 创建外部来源
+// POST /api/inventory/sources
+func (h *InventorySourceHandler) CreateSource(c *gin.Context) {
+	var req models.InventorySourceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	src, err := h.service.CreateSource(&req, c.GetString("username"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": src})
+}
+
+// GetSource 获取外部来源
+// GET /api/inventory/sources/:id
+func (h *InventorySourceHandler) GetSource(c *gin.Context) {
+	id, ok := parseInventoryHostID(c)
+	if !ok {
+		return
+	}
+	src, err := h.service.GetSource(id)
+	if err != nil {
+		respondInventorySourceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": src})
+}
+
+// UpdateSource 更新外部来源（token 为空时保持原令牌）
+// PUT /api/inventory/sources/:id
+func (h *InventorySourceHandler) UpdateSource(c *gin.Context) {
+	id, ok := parseInventoryHostID(c)
+	if !ok {
+		return
+	}
+	var req models.InventorySourceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	src, err := h.service.UpdateSource(id, &req, c.GetString("username"))
+	if err != nil {
+		respondInventorySourceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": src})
+}
+
+// DeleteSource 删除外部来源（已写入清单的主机保留）
+// DELETE /api/inventory/sources/:id
+func (h *InventorySourceHandler) DeleteSource(c *gin.Context) {
+	id, ok := parseInventoryHostID(c)
+	if !ok {
+		return
+	}
+	if err := h.service.DeleteSource(id, c.GetString("username")); err != nil {
+		respondInventorySourceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// Preview 拉取来源当前主机及映射结果，不生成变更集
+// GET /api/inventory/sources/:id/preview
+func (h *InventorySourceHandler) Preview(c *gin.Context) {
+	id, ok := parseInventoryHostID(c)
+	if !ok {
+		return
+	}
+	hosts, err := h.service.Preview(c.Request.Context(), id)
+	if err != nil {
+		respondInventorySourceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": hosts, "total": len(hosts)})
+}
+
+// Sync 立即同步来源并生成待审批变更集（无变化时 data 为 null）
+// POST /api/inventory/sources/:id/sync
+func (h *InventorySourceHandler) Sync(c *gin.Context) {
+	id, ok := parseInventoryHostID(c)
+	if !ok {
+		return
+	}
+	changeset, err := h.service.SyncSource(c.Request.Context(), id, "manual")
+	if err != nil {
+		respondInventorySourceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": changeset})
+}
+
+// ListChangesets 查询变更集
+// GET /api/inventory/changesets?source_id=1&status=pending&page=1&page_size=20
+func (h *InventorySourceHandler) ListChangesets(c *gin.Context) {
+	sourceID, _ := strconv.ParseUint(c.Query("source_id"), 10, 32)
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	changesets, total, err := h.service.ListChangesets(uint(sourceID), c.Query("status"), page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": changesets, "total": total})
+}
+
+// GetChangeset 获取变更集详情（含逐台主机的字段差异）
+// GET /api/inventory/changesets/:id
+func (h *InventorySourceHandler) GetChangeset(c *gin.Context) {
+	id, ok := parseInventoryHostID(c)
+	if !ok {
+		return
+	}
+	changeset, err := h.service.GetChangeset(id)
+	if err != nil {
+		respondInventorySourceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": changeset})
+}
+
+// Approve 批准并应用变更集
+// POST /api/inventory/changesets/:id/approve {"comment": "..."}
+func (h *InventorySourceHandler) Approve(c *gin.Context) {
+	h.review(c, h.service.ApproveChangeset)
+}
+
+// Reject 拒绝变更集
+// POST /api/inventory/changesets/:id/reject {"comment": "..."}
+func (h *InventorySourceHandler) Reject(c *gin.Context) {
+	h.review(c, h.service.RejectChangeset)
+}
+
+func (h *InventorySourceHandler) review(c *gin.Context, decide func(id uint, username, comment string) (*models.InventoryChangeset, error)) {
+	id, ok := parseInventoryHostID(c)
+	if !ok {
+		return
+	}
+	var req models.InventoryChangesetReview
+	_ = c.ShouldBindJSON(&req)
+	changeset, err := decide(id, c.GetString("username"), req.Comment)
+	if err != nil {
+		respondInventorySourceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": changeset})
+}
+
+func respondInventorySourceError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": err.Error()})
+	case errors.Is(err, services.ErrInventoryChangesetNotPending):
+		c.JSON(http.StatusConflict, gin.H{"success": false, "error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+	}
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// CreateDynamicMinionGroup 创建基于 grains 规则的动态分组
+func (h *SaltStackHandler) CreateDynamicMinionGroup(c *gin.Context) {
+	dynamicService := services.GetMinionDynamicGroupService()
+	if dynamicService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"success": false, "error": "dynamic group service not initialized"})
+		return
+	}
+	var req struct {
+		Name           string                  `json:"name" binding:"required"`
+		DisplayName    string                  `json:"display_name"`
+		Description    string                  `json:"description"`
+		Color          string                  `json:"color"`
+		Priority       int                     `json:"priority"`
+		SlurmPartition string                  `json:"slurm_partition"`
+		Rule           *models.MinionGroupRule `json:"rule" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	group := &models.MinionGroup{
+		Name:           req.Name,
+		DisplayName:    req.DisplayName,
+		Description:    req.Description,
+		Color:          req.Color,
+		Priority:       req.Priority,
+		SlurmPartition: req.SlurmPartition,
+	}
+	if group.Color == "" {
+		group.Color = "purple"
+	}
+	if err := dynamicService.CreateGroup(group, req.Rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	go func() {
+		if err := dynamicService.RefreshAll(context.Background()); err != nil {
+			log.Printf("[MinionDynamicGroup] 分组变更后刷新失败: %v", err)
+		}
+	}()
+	c.JSON(http.StatusCreated, gin.H{"success": true, "data": group, "message": "动态分组已创建，成员正在计算"})
+}
+
+// UpdateMinionGroupRule 更新分组规则（将普通分组转换为动态分组）
+func (h *SaltStackHandler) UpdateMinionGroupRule(c *gin.Context) {
+	dynamicService := services.GetMinionDynamicGroupService()
+	if dynamicService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"success": false, "error": "dynamic group service not initialized"})
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "invalid group id"})
+		return
+	}
+	var req struct {
+		Rule           *models.MinionGroupRule `json:"rule" binding:"required"`
+		SlurmPartition *string                 `json:"slurm_partition"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	group, err := dynamicService.UpdateRule(uint(id), req.Rule, req.SlurmPartition)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "group not found"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	go func() {
+		if err := dynamicService.RefreshAll(context.Background()); err != nil {
+			log.Printf("[MinionDynamicGroup] 分组变更后刷新失败: %v", err)
+		}
+	}()
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": group})
+}
+
+// PreviewMinionGroupRule 预览规则匹配的 minion
+func (h *SaltStackHandler) PreviewMinionGroupRule(c *gin.Context) {
+	dynamicService := services.GetMinionDynamicGroupService()
+	if dynamicService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"success": false, "error": "dynamic group service not initialized"})
+		return
+	}
+	var req struct {
+		Rule *models.MinionGroupRule `json:"rule" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 3*time.Minute)
+	defer cancel()
+	minions, err := dynamicService.Preview(ctx, req.Rule)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": minions, "total": len(minions)})
+}
+
+// RefreshDynamicMinionGroups 立即刷新所有动态分组
+func (h *SaltStackHandler) RefreshDynamicMinionGroups(c *gin.Context) {
+	dynamicService := services.GetMinionDynamicGroupService()
+	if dynamicService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"success": false, "error": "dynamic group service not initialized"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Minute)
+	defer cancel()
+	if err := dynamicService.RefreshAll(ctx); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	groups, _ := dynamicService.ListDynamicGroups()
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": groups})
+}
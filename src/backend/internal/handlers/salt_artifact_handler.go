@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/middleware"
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// SaltArtifactHandler 制品库与制品分发处理器
+type SaltArtifactHandler struct {
+	service *services.SaltArtifactService
+}
+
+// NewSaltArtifactHandler 创建制品处理器
+func NewSaltArtifactHandler(service *services.SaltArtifactService) *SaltArtifactHandler {
+	return &SaltArtifactHandler{service: service}
+}
+
+// RegisterRoutes 注册路由（挂载在已认证的 /saltstack 分组下，写操作与分发仅管理员）
+func (h *SaltArtifactHandler) RegisterRoutes(r *gin.RouterGroup) {
+	artifacts := r.Group("/artifacts")
+	{
+		artifacts.GET("", h.ListArtifacts)
+		artifacts.GET("/distributions", h.ListDistributions)
+		artifacts.GET("/distributions/:id", h.GetDistribution)
+		artifacts.GET("/:id", h.GetArtifact)
+		artifacts.POST("", middleware.AdminMiddleware(), h.UploadArtifact)
+		artifacts.DELETE("/:id", middleware.AdminMiddleware(), h.DeleteArtifact)
+		artifacts.POST("/:id/distribute", middleware.AdminMiddleware(), h.DistributeArtifact)
+	}
+}
+
+func parseArtifactID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "invalid id"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// UploadArtifact 上传制品（multipart: file, name, description）
+func (h *SaltArtifactHandler) UploadArtifact(c *gin.Context) {
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "file is required"})
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	contentType := fileHeader.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	artifact, err := h.service.Upload(c.PostForm("name"), c.PostForm("description"), fileHeader.Filename,
+		contentType, fileHeader.Size, file, c.GetString("username"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"success": true, "data": artifact})
+}
+
+// ListArtifacts 列出制品
+func (h *SaltArtifactHandler) ListArtifacts(c *gin.Context) {
+	artifacts, err := h.service.List(c.Query("name"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": artifacts, "total": len(artifacts)})
+}
+
+// GetArtifact 获取制品详情
+func (h *SaltArtifactHandler) GetArtifact(c *gin.Context) {
+	id, ok := parseArtifactID(c)
+	if !ok {
+		return
+	}
+	artifact, err := h.service.Get(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "artifact not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": artifact})
+}
+
+// DeleteArtifact 删除制品
+func (h *SaltArtifactHandler) DeleteArtifact(c *gin.Context) {
+	id, ok := parseArtifactID(c)
+	if !ok {
+		return
+	}
+	if err := h.service.Delete(id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "artifact not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "artifact deleted"})
+}
+
+// DistributeArtifact 将制品分发到目标 minion（单个 Salt 作业，逐节点校验 sha256）
+func (h *SaltArtifactHandler) DistributeArtifact(c *gin.Context) {
+	id, ok := parseArtifactID(c)
+	if !ok {
+		return
+	}
+	var req models.SaltArtifactDistributeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	dist, err := h.service.Distribute(id, &req, c.GetString("username"))
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "artifact not found"})
+		case dist != nil:
+			c.JSON(http.StatusBadGateway, gin.H{"success": false, "error": err.Error(), "data": dist})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"success": true, "data": dist})
+}
+
+// ListDistributions 列出分发记录
+func (h *SaltArtifactHandler) ListDistributions(c *gin.Context) {
+	artifactID, _ := strconv.ParseUint(c.Query("artifact_id"), 10, 32)
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	dists, total, err := h.service.ListDistributions(uint(artifactID), page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": dists, "total": total})
+}
+
+// GetDistribution 获取分发详情及逐节点校验结果
+func (h *SaltArtifactHandler) GetDistribution(c *gin.Context) {
+	id, ok := parseArtifactID(c)
+	if !ok {
+		return
+	}
+	dist, err := h.service.GetDistribution(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "distribution not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": dist})
+}
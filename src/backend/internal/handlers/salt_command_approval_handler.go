@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/middleware"
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// submitDangerousCommandApproval 为命中 high/critical 规则的命令创建待审批的执行申请
+// 审批通过 /api/approvals/requests/:id/approve 完成（需另一名管理员），批准后命令执行一次
+func (h *SaltStackHandler) submitDangerousCommandApproval(c *gin.Context, function string, args []interface{}, kwarg map[string]interface{}, target, tgtType, masterID, reason string, rule *models.DangerousCommand) {
+	approvalService := services.GetSaltCommandApprovalService()
+	userID, err := middleware.GetUserID(c)
+	if approvalService == nil || err != nil {
+		c.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"error":   fmt.Sprintf("命令被安全策略拦截: %s", rule.Description),
+			"details": gin.H{
+				"pattern":     rule.Pattern,
+				"severity":    rule.Severity,
+				"description": rule.Description,
+			},
+		})
+		return
+	}
+
+	approval, created, err := approvalService.Submit(&services.SaltCommandApprovalInput{
+		RequesterID:   userID,
+		RequesterName: c.GetString("username"),
+		Function:      function,
+		Args:          args,
+		Kwarg:         kwarg,
+		Target:        target,
+		TgtType:       tgtType,
+		MasterID:      masterID,
+		Reason:        reason,
+		Rule:          rule,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	message := "命令命中高危规则，已提交执行申请，需其他管理员审批后执行"
+	if !created {
+		message = "已存在相同的待审批执行申请，请等待审批结果"
+	}
+	c.JSON(http.StatusAccepted, gin.H{
+		"success":           true,
+		"approval_required": true,
+		"message":           message,
+		"data":              approval,
+		"details": gin.H{
+			"pattern":     rule.Pattern,
+			"severity":    rule.Severity,
+			"description": rule.Description,
+		},
+	})
+}
+
+// ListCommandApprovals 获取危险命令执行申请列表（管理员查看全部，普通用户仅查看自己的）
+func (h *SaltStackHandler) ListCommandApprovals(c *gin.Context) {
+	approvalService := services.GetSaltCommandApprovalService()
+	if approvalService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"success": false, "error": "approval service not initialized"})
+		return
+	}
+	var requesterID uint
+	if !middleware.HasRole(c, "admin") {
+		userID, err := middleware.GetUserID(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"success": false, "error": err.Error()})
+			return
+		}
+		requesterID = userID
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	approvals, total, err := approvalService.List(requesterID, c.Query("status"), page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": approvals, "total": total})
+}
+
+// GetCommandApproval 获取危险命令执行申请详情
+func (h *SaltStackHandler) GetCommandApproval(c *gin.Context) {
+	approvalService := services.GetSaltCommandApprovalService()
+	if approvalService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"success": false, "error": "approval service not initialized"})
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "invalid approval id"})
+		return
+	}
+	approval, err := approvalService.Get(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "approval not found"})
+		return
+	}
+	if !middleware.HasRole(c, "admin") {
+		if userID, err := middleware.GetUserID(c); err != nil || userID != approval.RequesterID {
+			c.JSON(http.StatusForbidden, gin.H{"success": false, "error": "无权查看此申请"})
+			return
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": approval})
+}
@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/middleware"
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// SaltDriftHandler 配置漂移检测处理器
+type SaltDriftHandler struct {
+	service *services.SaltDriftService
+}
+
+// NewSaltDriftHandler 创建配置漂移检测处理器
+func NewSaltDriftHandler(service *services.SaltDriftService) *SaltDriftHandler {
+	return &SaltDriftHandler{service: service}
+}
+
+// RegisterRoutes 注册路由（挂载在已认证的 /saltstack 分组下，策略管理与手动扫描仅管理员）
+func (h *SaltDriftHandler) RegisterRoutes(r *gin.RouterGroup) {
+	drift := r.Group("/drift")
+	{
+		drift.GET("/policies", h.ListPolicies)
+		drift.GET("/policies/:id", h.GetPolicy)
+		drift.POST("/policies", middleware.AdminMiddleware(), h.CreatePolicy)
+		drift.PUT("/policies/:id", middleware.AdminMiddleware(), h.UpdatePolicy)
+		drift.DELETE("/policies/:id", middleware.AdminMiddleware(), h.DeletePolicy)
+		drift.POST("/policies/:id/run", middleware.AdminMiddleware(), h.RunPolicy)
+		drift.GET("/runs", h.ListRuns)
+		drift.GET("/runs/:id", h.GetRun)
+		drift.GET("/nodes", h.ListNodeStatus)
+		drift.GET("/nodes/:minionId/history", h.GetNodeHistory)
+		drift.GET("/states", h.GetStateReport)
+	}
+}
+
+func parseDriftID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "invalid id"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func respondDriftError(c *gin.Context, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "drift policy not found"})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+}
+
+// ListPolicies 获取漂移扫描策略列表
+func (h *SaltDriftHandler) ListPolicies(c *gin.Context) {
+	policies, err := h.service.ListPolicies()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": policies})
+}
+
+// GetPolicy 获取漂移扫描策略
+func (h *SaltDriftHandler) GetPolicy(c *gin.Context) {
+	id, ok := parseDriftID(c)
+	if !ok {
+		return
+	}
+	policy, err := h.service.GetPolicy(id)
+	if err != nil {
+		respondDriftError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": policy})
+}
+
+// CreatePolicy 创建漂移扫描策略
+func (h *SaltDriftHandler) CreatePolicy(c *gin.Context) {
+	var req models.SaltDriftPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	policy, err := h.service.CreatePolicy(&req, c.GetString("username"))
+	if err != nil {
+		respondDriftError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"success": true, "data": policy})
+}
+
+// UpdatePolicy 更新漂移扫描策略
+func (h *SaltDriftHandler) UpdatePolicy(c *gin.Context) {
+	id, ok := parseDriftID(c)
+	if !ok {
+		return
+	}
+	var req models.SaltDriftPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	policy, err := h.service.UpdatePolicy(id, &req, c.GetString("username"))
+	if err != nil {
+		respondDriftError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": policy})
+}
+
+// DeletePolicy 删除漂移扫描策略
+func (h *SaltDriftHandler) DeletePolicy(c *gin.Context) {
+	id, ok := parseDriftID(c)
+	if !ok {
+		return
+	}
+	if err := h.service.DeletePolicy(id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "drift policy deleted"})
+}
+
+// RunPolicy 立即执行一次漂移扫描
+func (h *SaltDriftHandler) RunPolicy(c *gin.Context) {
+	id, ok := parseDriftID(c)
+	if !ok {
+		return
+	}
+	run, err := h.service.TriggerNow(id, c.GetString("username"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondDriftError(c, err)
+			return
+		}
+		c.JSON(http.StatusConflict, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"success": true, "data": run})
+}
+
+// ListRuns 分页查询扫描记录
+func (h *SaltDriftHandler) ListRuns(c *gin.Context) {
+	policyID, _ := strconv.ParseUint(c.Query("policy_id"), 10, 32)
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	runs, total, err := h.service.ListRuns(uint(policyID), page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": runs, "total": total})
+}
+
+// GetRun 获取扫描详情（逐节点结果及待变更内容）
+func (h *SaltDriftHandler) GetRun(c *gin.Context) {
+	id, ok := parseDriftID(c)
+	if !ok {
+		return
+	}
+	run, nodes, err := h.service.GetRun(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "drift run not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": gin.H{"run": run, "nodes": nodes}})
+}
+
+// ListNodeStatus 查询节点当前合规状态（?policy_id=&status=drifted）
+func (h *SaltDriftHandler) ListNodeStatus(c *gin.Context) {
+	policyID, _ := strconv.ParseUint(c.Query("policy_id"), 10, 32)
+	statuses, err := h.service.ListNodeStatus(uint(policyID), c.Query("status"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": statuses, "total": len(statuses)})
+}
+
+// GetNodeHistory 查询单个节点的漂移历史
+func (h *SaltDriftHandler) GetNodeHistory(c *gin.Context) {
+	policyID, _ := strconv.ParseUint(c.Query("policy_id"), 10, 32)
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	history, err := h.service.NodeHistory(c.Param("minionId"), uint(policyID), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": history})
+}
+
+// GetStateReport 按状态聚合漂移报告（?policy_id=&days=7&include_failed=true）
+func (h *SaltDriftHandler) GetStateReport(c *gin.Context) {
+	policyID, _ := strconv.ParseUint(c.Query("policy_id"), 10, 32)
+	days, err := strconv.Atoi(c.DefaultQuery("days", "7"))
+	if err != nil || days <= 0 {
+		days = 7
+	}
+	since := time.Now().AddDate(0, 0, -days)
+	report, err := h.service.StateReport(uint(policyID), since, c.Query("include_failed") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": report, "since": since})
+}
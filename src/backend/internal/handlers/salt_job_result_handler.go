@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// SearchSaltJobResults 检索作业输出（如最近一周哪些节点输出了 "ECC error"）
+// GET /api/saltstack/jobs/results/search?q=ECC%20error&since=2025-01-01T00:00:00Z&failed=true
+func (h *SaltStackHandler) SearchSaltJobResults(c *gin.Context) {
+	saltJobService := services.GetSaltJobService()
+	if saltJobService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"success": false, "error": "SaltJobService not initialized"})
+		return
+	}
+	var params models.SaltJobResultSearchParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if params.Query == "" && params.MinionID == "" && params.Function == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "q, minion_id or function is required"})
+		return
+	}
+
+	result, err := saltJobService.SearchMinionResults(c.Request.Context(), &params)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": result})
+}
+
+// GetSaltJobMinionResults 获取作业在各 minion 上的规范化结果
+func (h *SaltStackHandler) GetSaltJobMinionResults(c *gin.Context) {
+	saltJobService := services.GetSaltJobService()
+	if saltJobService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"success": false, "error": "SaltJobService not initialized"})
+		return
+	}
+	results, err := saltJobService.GetJobMinionResults(c.Request.Context(), c.Param("jid"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	succeeded, failed, changed, statesFailed := 0, 0, 0, 0
+	for _, r := range results {
+		if r.Success {
+			succeeded++
+		} else {
+			failed++
+		}
+		changed += r.StatesChanged
+		statesFailed += r.StatesFailed
+	}
+	summary := gin.H{
+		"total":          len(results),
+		"success":        succeeded,
+		"failed":         failed,
+		"states_changed": changed,
+		"states_failed":  statesFailed,
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": results, "summary": summary})
+}
+
+// GetMinionJobTimeline 获取 minion 的作业时间线
+// GET /api/saltstack/minions/:minionId/timeline?since=...&until=...&limit=200
+func (h *SaltStackHandler) GetMinionJobTimeline(c *gin.Context) {
+	saltJobService := services.GetSaltJobService()
+	if saltJobService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"success": false, "error": "SaltJobService not initialized"})
+		return
+	}
+	var since, until time.Time
+	if v := c.Query("since"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "invalid since, expected RFC3339"})
+			return
+		}
+		since = t
+	}
+	if v := c.Query("until"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "invalid until, expected RFC3339"})
+			return
+		}
+		until = t
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "200"))
+
+	results, err := saltJobService.GetMinionTimeline(c.Request.Context(), c.Param("minionId"), since, until, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": results, "total": len(results)})
+}
@@ -0,0 +1,235 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/middleware"
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// SaltMasterHandler Salt 多 Master 拓扑处理器
+type SaltMasterHandler struct {
+	service *services.SaltMasterService
+}
+
+// NewSaltMasterHandler 创建 Salt Master 拓扑处理器
+func NewSaltMasterHandler(service *services.SaltMasterService) *SaltMasterHandler {
+	return &SaltMasterHandler{service: service}
+}
+
+// RegisterRoutes 注册路由（挂载在已认证的 /saltstack 分组下，写操作仅管理员）
+func (h *SaltMasterHandler) RegisterRoutes(r *gin.RouterGroup) {
+	masters := r.Group("/masters")
+	{
+		masters.GET("", h.ListMasters)
+		masters.GET("/topology", h.GetTopology)
+		masters.GET("/minions/:minionId", h.ResolveMinion)
+		masters.GET("/:id", h.GetMaster)
+		masters.GET("/:id/minions", h.ListMasterMinions)
+		masters.POST("", middleware.AdminMiddleware(), h.CreateMaster)
+		masters.PUT("/:id", middleware.AdminMiddleware(), h.UpdateMaster)
+		masters.DELETE("/:id", middleware.AdminMiddleware(), h.DeleteMaster)
+		masters.POST("/sync", middleware.AdminMiddleware(), h.SyncAll)
+		masters.POST("/:id/sync", middleware.AdminMiddleware(), h.SyncMaster)
+	}
+}
+
+func respondMasterError(c *gin.Context, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "salt master not found"})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+}
+
+func (h *SaltMasterHandler) loadMaster(c *gin.Context) (*models.SaltMaster, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "invalid master id"})
+		return nil, false
+	}
+	master, err := h.service.GetMaster(uint(id))
+	if err != nil {
+		respondMasterError(c, err)
+		return nil, false
+	}
+	return master, true
+}
+
+// ListMasters 列出已注册的 Master
+func (h *SaltMasterHandler) ListMasters(c *gin.Context) {
+	masters, err := h.service.ListMasters(c.Query("enabled") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": masters, "total": len(masters)})
+}
+
+// GetTopology 获取 Master/Syndic 层级拓扑
+func (h *SaltMasterHandler) GetTopology(c *gin.Context) {
+	tree, err := h.service.Topology()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": tree})
+}
+
+// GetMaster 获取 Master 详情
+func (h *SaltMasterHandler) GetMaster(c *gin.Context) {
+	master, ok := h.loadMaster(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": master})
+}
+
+// CreateMaster 注册 Master
+func (h *SaltMasterHandler) CreateMaster(c *gin.Context) {
+	var req models.SaltMasterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	master, err := h.service.CreateMaster(&req)
+	if err != nil {
+		respondMasterError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"success": true, "data": master})
+}
+
+// UpdateMaster 更新 Master
+func (h *SaltMasterHandler) UpdateMaster(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "invalid master id"})
+		return
+	}
+	var req models.SaltMasterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	master, err := h.service.UpdateMaster(uint(id), &req)
+	if err != nil {
+		respondMasterError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": master})
+}
+
+// DeleteMaster 删除 Master
+func (h *SaltMasterHandler) DeleteMaster(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "invalid master id"})
+		return
+	}
+	if err := h.service.DeleteMaster(uint(id)); err != nil {
+		respondMasterError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "salt master deleted"})
+}
+
+// SyncMaster 立即同步指定 Master 的 minion 清单
+func (h *SaltMasterHandler) SyncMaster(c *gin.Context) {
+	master, ok := h.loadMaster(c)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 60*time.Second)
+	defer cancel()
+	if err := h.service.SyncMaster(ctx, master); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	master, _ = h.service.GetMaster(master.ID)
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": master})
+}
+
+// SyncAll 立即同步所有启用的 Master
+func (h *SaltMasterHandler) SyncAll(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 120*time.Second)
+	defer cancel()
+	errs := h.service.SyncAll(ctx)
+	failed := make(map[string]string, len(errs))
+	for id, err := range errs {
+		failed[id] = err.Error()
+	}
+	c.JSON(http.StatusOK, gin.H{"success": len(failed) == 0, "failed": failed})
+}
+
+// ListMasterMinions 获取 Master 上的 minion 清单
+func (h *SaltMasterHandler) ListMasterMinions(c *gin.Context) {
+	master, ok := h.loadMaster(c)
+	if !ok {
+		return
+	}
+	minions, err := h.service.ListMinions(master.MasterID, c.Query("key_status"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": minions, "total": len(minions)})
+}
+
+// ResolveMinion 查询 minion 归属的 Master
+func (h *SaltMasterHandler) ResolveMinion(c *gin.Context) {
+	master, err := h.service.ResolveMinionMaster(c.Param("minionId"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "minion key not accepted on any registered master"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": master})
+}
+
+// newSaltAPIClientForTarget 按目标选择 Master 创建客户端，选择规则与 services.ResolveTargetMaster 一致；
+// 目标不归属任何已注册 Master 时使用连接池（环境变量配置）
+func (h *SaltStackHandler) newSaltAPIClientForTarget(masterID, target, tgtType string) (*saltAPIClient, error) {
+	owner, err := services.ResolveTargetMaster(masterID, target, tgtType)
+	if err != nil {
+		return nil, err
+	}
+	return h.newSaltAPIClientForMasterID(owner)
+}
+
+// newSaltAPIClientForMasterID 按 MasterID 创建客户端，空或 default 使用连接池
+func (h *SaltStackHandler) newSaltAPIClientForMasterID(masterID string) (*saltAPIClient, error) {
+	masterService := services.GetSaltMasterService()
+	if masterID == "" || masterID == "default" || masterService == nil {
+		return h.newSaltAPIClient(), nil
+	}
+	master, err := masterService.GetMasterByMasterID(masterID)
+	if err != nil {
+		return nil, fmt.Errorf("unknown salt master: %s", masterID)
+	}
+	if !master.Enabled {
+		return nil, fmt.Errorf("salt master %s is disabled", masterID)
+	}
+	return h.newSaltAPIClientForMaster(saltMasterConfigFromModel(master)), nil
+}
+
+func saltMasterConfigFromModel(master *models.SaltMaster) *SaltMasterConfig {
+	return &SaltMasterConfig{
+		URL:      master.APIURL,
+		Username: master.Username,
+		Password: master.Password,
+		Eauth:    master.Eauth,
+		Priority: master.Priority,
+	}
+}
@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/middleware"
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// SaltScheduleHandler Salt 定时作业处理器
+type SaltScheduleHandler struct {
+	service *services.SaltScheduleService
+}
+
+// NewSaltScheduleHandler 创建 Salt 定时作业处理器
+func NewSaltScheduleHandler(service *services.SaltScheduleService) *SaltScheduleHandler {
+	return &SaltScheduleHandler{service: service}
+}
+
+// RegisterRoutes 注册路由（挂载在已认证的 /saltstack 分组下）
+// 定时作业以创建者身份无人值守执行，创建、修改与手动触发仅管理员可用
+func (h *SaltScheduleHandler) RegisterRoutes(r *gin.RouterGroup) {
+	schedules := r.Group("/schedules")
+	{
+		schedules.GET("", h.ListSchedules)
+		schedules.GET("/preview", h.PreviewSchedule)
+		schedules.GET("/:id", h.GetSchedule)
+		schedules.GET("/:id/runs", h.ListScheduleRuns)
+
+		admin := schedules.Group("", middleware.AdminMiddleware())
+		admin.POST("", h.CreateSchedule)
+		admin.PUT("/:id", h.UpdateSchedule)
+		admin.DELETE("/:id", h.DeleteSchedule)
+		admin.POST("/:id/enable", h.EnableSchedule)
+		admin.POST("/:id/disable", h.DisableSchedule)
+		admin.POST("/:id/run", h.RunSchedule)
+	}
+}
+
+// respondScheduleError 统一处理服务层错误
+func respondScheduleError(c *gin.Context, err error) {
+	var dangerous *services.DangerousCommandError
+	switch {
+	case errors.As(err, &dangerous):
+		c.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"error":   err.Error(),
+			"details": gin.H{
+				"pattern":     dangerous.Rule.Pattern,
+				"severity":    dangerous.Rule.Severity,
+				"description": dangerous.Rule.Description,
+			},
+		})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "schedule not found"})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+	}
+}
+
+func parseScheduleID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "invalid schedule id"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// ListSchedules 获取定时作业列表
+func (h *SaltScheduleHandler) ListSchedules(c *gin.Context) {
+	schedules, err := h.service.ListSchedules()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": schedules})
+}
+
+// CreateSchedule 创建定时作业
+func (h *SaltScheduleHandler) CreateSchedule(c *gin.Context) {
+	var req models.SaltJobScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	schedule, err := h.service.CreateSchedule(&req, c.GetString("username"))
+	if err != nil {
+		respondScheduleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": schedule})
+}
+
+// GetSchedule 获取定时作业详情
+func (h *SaltScheduleHandler) GetSchedule(c *gin.Context) {
+	id, ok := parseScheduleID(c)
+	if !ok {
+		return
+	}
+	schedule, err := h.service.GetSchedule(id)
+	if err != nil {
+		respondScheduleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": schedule})
+}
+
+// UpdateSchedule 更新定时作业
+func (h *SaltScheduleHandler) UpdateSchedule(c *gin.Context) {
+	id, ok := parseScheduleID(c)
+	if !ok {
+		return
+	}
+	var req models.SaltJobScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	schedule, err := h.service.UpdateSchedule(id, &req, c.GetString("username"))
+	if err != nil {
+		respondScheduleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": schedule})
+}
+
+// DeleteSchedule 删除定时作业
+func (h *SaltScheduleHandler) DeleteSchedule(c *gin.Context) {
+	id, ok := parseScheduleID(c)
+	if !ok {
+		return
+	}
+	if err := h.service.DeleteSchedule(id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "schedule deleted"})
+}
+
+// EnableSchedule 启用定时作业
+func (h *SaltScheduleHandler) EnableSchedule(c *gin.Context) {
+	h.setEnabled(c, true)
+}
+
+// DisableSchedule 停用定时作业
+func (h *SaltScheduleHandler) DisableSchedule(c *gin.Context) {
+	h.setEnabled(c, false)
+}
+
+func (h *SaltScheduleHandler) setEnabled(c *gin.Context, enabled bool) {
+	id, ok := parseScheduleID(c)
+	if !ok {
+		return
+	}
+	schedule, err := h.service.SetEnabled(id, enabled, c.GetString("username"))
+	if err != nil {
+		respondScheduleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": schedule})
+}
+
+// RunSchedule 立即触发一次定时作业
+func (h *SaltScheduleHandler) RunSchedule(c *gin.Context) {
+	id, ok := parseScheduleID(c)
+	if !ok {
+		return
+	}
+	run, err := h.service.TriggerNow(id, c.GetString("username"))
+	if err != nil {
+		respondScheduleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": run})
+}
+
+// ListScheduleRuns 获取定时作业运行记录
+func (h *SaltScheduleHandler) ListScheduleRuns(c *gin.Context) {
+	id, ok := parseScheduleID(c)
+	if !ok {
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	runs, err := h.service.ListRuns(id, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": runs})
+}
+
+// PreviewSchedule 预览 cron 表达式的后续触发时间
+// GET /api/saltstack/schedules/preview?cron=*/5 * * * *&timezone=Asia/Shanghai&count=5
+func (h *SaltScheduleHandler) PreviewSchedule(c *gin.Context) {
+	count, _ := strconv.Atoi(c.DefaultQuery("count", "5"))
+	runs, err := h.service.PreviewNextRuns(c.Query("cron"), c.Query("timezone"), count)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	formatted := make([]string, len(runs))
+	for i, t := range runs {
+		formatted[i] = t.Format(time.RFC3339)
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": formatted})
+}
@@ -0,0 +1,424 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/middleware"
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// 暂停（手动暂停或 pause_on_failure 时的失败阈值）后等待人工恢复/中止的最长时间，超时自动中止
+const batchPauseTimeout = 30 * time.Minute
+
+// customCommandRequest 自定义命令执行请求
+type customCommandRequest struct {
+	Target   string `json:"target"`
+	Language string `json:"language"`
+	Code     string `json:"code"`
+	Timeout  int    `json:"timeout"`
+	User     string `json:"user,omitempty"`
+	MasterID string `json:"master_id,omitempty"` // 指定 Master；为空时按 minion 密钥归属路由
+
+	// 分批执行（为空时一次性下发到全部目标）
+	Batch            string `json:"batch,omitempty"`             // 每批数量，如 "10" 或 "10%"
+	BatchWait        int    `json:"batch_wait,omitempty"`        // 批次间等待秒数
+	FailureThreshold string `json:"failure_threshold,omitempty"` // 允许的失败数，如 "3" 或 "5%"，超过后立即中止
+	PauseOnFailure   bool   `json:"pause_on_failure,omitempty"`  // 超过失败阈值时改为暂停，等待人工恢复或中止
+}
+
+// parseBatchSpec 将 "N" 或 "N%" 换算为相对 total 的数量（百分比向上取整，至少为 1）
+func parseBatchSpec(spec string, total int) (int, error) {
+	spec = strings.TrimSpace(spec)
+	if strings.HasSuffix(spec, "%") {
+		pct, err := strconv.ParseFloat(strings.TrimSuffix(spec, "%"), 64)
+		if err != nil || pct <= 0 || pct > 100 {
+			return 0, fmt.Errorf("invalid percentage %q", spec)
+		}
+		n := int(float64(total)*pct/100 + 0.999999)
+		if n < 1 {
+			n = 1
+		}
+		return n, nil
+	}
+	n, err := strconv.Atoi(spec)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid batch size %q", spec)
+	}
+	return n, nil
+}
+
+// parseFailureThreshold 与 parseBatchSpec 类似，但允许 0（任一失败即中止）；空值表示不限制
+func parseFailureThreshold(spec string, total int) (int, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return -1, nil
+	}
+	if spec == "0" || spec == "0%" {
+		return 0, nil
+	}
+	if strings.HasSuffix(spec, "%") {
+		pct, err := strconv.ParseFloat(strings.TrimSuffix(spec, "%"), 64)
+		if err != nil || pct < 0 || pct > 100 {
+			return 0, fmt.Errorf("invalid failure threshold %q", spec)
+		}
+		return int(float64(total) * pct / 100), nil
+	}
+	n, err := strconv.Atoi(spec)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid failure threshold %q", spec)
+	}
+	return n, nil
+}
+
+// extractLocalRetcodes 从 full_return 格式的 local 返回中提取每个 minion 的 retcode
+func extractLocalRetcodes(resp map[string]interface{}) map[string]int {
+	out := map[string]int{}
+	if ret, ok := resp["return"].([]interface{}); ok && len(ret) > 0 {
+		if m, ok := ret[0].(map[string]interface{}); ok {
+			for k, v := range m {
+				if vv, ok := v.(map[string]interface{}); ok {
+					if rc, ok := vv["retcode"].(float64); ok {
+						out[k] = int(rc)
+					}
+				}
+			}
+		}
+	}
+	return out
+}
+
+// resolveTargetMinions 通过 test.ping 解析目标当前在线的 minion 列表
+func (h *SaltStackHandler) resolveTargetMinions(client *saltAPIClient, target string) ([]string, error) {
+	payload := map[string]interface{}{
+		"client": "local",
+		"tgt":    target,
+		"fun":    "test.ping",
+	}
+	res, err := client.makeRequest("/", "POST", payload)
+	if err != nil {
+		return nil, err
+	}
+	var minions []string
+	if ret, ok := res["return"].([]interface{}); ok && len(ret) > 0 {
+		if m, ok := ret[0].(map[string]interface{}); ok {
+			for id, v := range m {
+				if up, ok := v.(bool); ok && up {
+					minions = append(minions, id)
+				}
+			}
+		}
+	}
+	sort.Strings(minions)
+	return minions, nil
+}
+
+// waitForResume 阻塞直到收到 resume/abort 或超时，返回 true 表示继续执行
+func waitForResume(pm *services.ProgressManager, opID string, control <-chan string, reason string) bool {
+	pm.SetPaused(opID, true)
+	pm.Emit(opID, services.ProgressEvent{Type: "paused", Step: "batch", Message: reason + "，等待恢复或中止"})
+	defer pm.SetPaused(opID, false)
+
+	timer := time.NewTimer(batchPauseTimeout)
+	defer timer.Stop()
+	for {
+		select {
+		case action := <-control:
+			switch action {
+			case services.ControlResume:
+				pm.Emit(opID, services.ProgressEvent{Type: "resumed", Step: "batch", Message: "已恢复执行"})
+				return true
+			case services.ControlAbort:
+				return false
+			}
+		case <-timer.C:
+			pm.Emit(opID, services.ProgressEvent{Type: "step-log", Step: "batch", Message: fmt.Sprintf("暂停超过 %s，自动中止", batchPauseTimeout)})
+			return false
+		}
+	}
+}
+
+// runBatchOnMasters 将一批 minion 按归属 Master 拆分后分别下发 payload，合并为单个 local 返回；
+// 指定 masterID 或 minion 未登记归属时使用 fallback 客户端。clients 缓存已认证的各 Master 客户端
+func (h *SaltStackHandler) runBatchOnMasters(clients map[string]*saltAPIClient, fallback *saltAPIClient, masterID string, batch []string, payload map[string]interface{}) (map[string]interface{}, error) {
+	groups := map[string][]string{"": batch}
+	if masterService := services.GetSaltMasterService(); masterService != nil && (masterID == "" || masterID == "default") {
+		var unresolved []string
+		groups, unresolved = masterService.GroupMinionsByMaster(batch)
+		if len(unresolved) > 0 {
+			groups[""] = append(groups[""], unresolved...)
+		}
+	}
+
+	merged := map[string]interface{}{}
+	var errs []string
+	for owner, minions := range groups {
+		client := fallback
+		if owner != "" {
+			if client = clients[owner]; client == nil {
+				c, err := h.newSaltAPIClientForMasterID(owner)
+				if err == nil {
+					err = c.authenticate()
+				}
+				if err != nil {
+					errs = append(errs, fmt.Sprintf("master %s: %v", owner, err))
+					continue
+				}
+				clients[owner], client = c, c
+			}
+		}
+
+		p := make(map[string]interface{}, len(payload)+2)
+		for k, v := range payload {
+			p[k] = v
+		}
+		p["tgt"] = strings.Join(minions, ",")
+		p["tgt_type"] = "list"
+		res, err := client.makeRequest("/", "POST", p)
+		if err != nil {
+			if owner == "" {
+				owner = "default"
+			}
+			errs = append(errs, fmt.Sprintf("master %s: %v", owner, err))
+			continue
+		}
+		if ret, ok := res["return"].([]interface{}); ok && len(ret) > 0 {
+			if m, ok := ret[0].(map[string]interface{}); ok {
+				for id, v := range m {
+					merged[id] = v
+				}
+			}
+		}
+	}
+
+	res := map[string]interface{}{"return": []interface{}{merged}}
+	if len(errs) > 0 {
+		sort.Strings(errs)
+		return res, errors.New(strings.Join(errs, "; "))
+	}
+	return res, nil
+}
+
+// runBatchedCustomCommand 分批下发自定义命令
+// 每批按 minion 归属 Master 拆分下发；每批完成后检查累计失败数，超过阈值时中止剩余批次；设置 pause_on_failure 时改为暂停，
+// 等待通过 /progress/:opId/control 恢复或中止
+func (h *SaltStackHandler) runBatchedCustomCommand(opID, taskID string, control <-chan string, r customCommandRequest, cmd string, kwarg map[string]interface{}) {
+	pm := services.GetProgressManager()
+
+	failed := false
+	finalMsg := "分批执行完成"
+	defer func() { pm.Complete(opID, failed, finalMsg) }()
+
+	pm.Emit(opID, services.ProgressEvent{Type: "step-start", Step: "prepare", Message: "准备连接 Salt API"})
+	client, err := h.newSaltAPIClientForTarget(r.MasterID, r.Target, "glob")
+	if err != nil {
+		failed = true
+		pm.Emit(opID, services.ProgressEvent{Type: "error", Step: "prepare", Message: err.Error()})
+		return
+	}
+	if err := client.authenticate(); err != nil {
+		failed = true
+		pm.Emit(opID, services.ProgressEvent{Type: "error", Step: "auth", Message: fmt.Sprintf("Salt API 认证失败: %v", err)})
+		return
+	}
+
+	minions, err := h.resolveTargetMinions(client, r.Target)
+	if err != nil {
+		failed = true
+		pm.Emit(opID, services.ProgressEvent{Type: "error", Step: "resolve", Message: fmt.Sprintf("解析目标失败: %v", err)})
+		return
+	}
+	if len(minions) == 0 {
+		failed = true
+		finalMsg = "目标没有在线的 minion"
+		pm.Emit(opID, services.ProgressEvent{Type: "error", Step: "resolve", Message: finalMsg})
+		return
+	}
+
+	// 参数已在提交时校验过
+	batchSize, _ := parseBatchSpec(r.Batch, len(minions))
+	threshold, _ := parseFailureThreshold(r.FailureThreshold, len(minions))
+	totalBatches := (len(minions) + batchSize - 1) / batchSize
+	pm.Emit(opID, services.ProgressEvent{Type: "step-log", Step: "resolve", Message: fmt.Sprintf("共 %d 个在线 minion，每批 %d 个，共 %d 批", len(minions), batchSize, totalBatches),
+		Data: map[string]interface{}{"minions": minions, "batch_size": batchSize, "batches": totalBatches, "failure_threshold": threshold}})
+
+	saltJobService := services.GetSaltJobService()
+	argsJSON, _ := json.Marshal([]interface{}{cmd, kwarg})
+	masterClients := map[string]*saltAPIClient{}
+	done, totalFailed, failedSinceResume := 0, 0, 0
+
+	// applyControl 处理批次之间收到的控制请求，返回 false 表示中止
+	// 仅在暂停后恢复时重新累计失败数；运行中收到的恢复请求忽略，不能借此绕过失败阈值
+	applyControl := func(action string, completed int) bool {
+		switch action {
+		case services.ControlPause:
+			if waitForResume(pm, opID, control, "收到暂停请求") {
+				failedSinceResume = 0
+				return true
+			}
+		case services.ControlAbort:
+		default:
+			return true
+		}
+		failed = true
+		finalMsg = fmt.Sprintf("已中止，完成 %d/%d 批", completed, totalBatches)
+		return false
+	}
+
+	for b := 0; b < totalBatches; b++ {
+		select {
+		case action := <-control:
+			if !applyControl(action, b) {
+				return
+			}
+		default:
+		}
+
+		end := b*batchSize + batchSize
+		if end > len(minions) {
+			end = len(minions)
+		}
+		batch := minions[b*batchSize : end]
+		stepName := fmt.Sprintf("batch-%d", b+1)
+		pm.Emit(opID, services.ProgressEvent{Type: "step-start", Step: stepName, Message: fmt.Sprintf("第 %d/%d 批: %s", b+1, totalBatches, strings.Join(batch, ","))})
+
+		payload := map[string]interface{}{
+			"client":      "local",
+			"fun":         "cmd.run",
+			"arg":         []interface{}{cmd},
+			"kwarg":       kwarg,
+			"full_return": true,
+		}
+		start := time.Now()
+		res, err := h.runBatchOnMasters(masterClients, client, r.MasterID, batch, payload)
+		duration := time.Since(start)
+
+		// 部分 Master 失败时仍保留其余 Master 的结果，未返回的 minion 计为失败
+		if err != nil {
+			pm.Emit(opID, services.ProgressEvent{Type: "error", Step: stepName, Message: fmt.Sprintf("执行失败: %v", err)})
+		}
+		outputs := extractLocalResults(res)
+		retcodes := extractLocalRetcodes(res)
+
+		successCount, failedCount := 0, 0
+		for _, minion := range batch {
+			done++
+			output, returned := outputs[minion]
+			rc, hasRC := retcodes[minion]
+			ok := returned && (!hasRC || rc == 0)
+			if ok {
+				successCount++
+			} else {
+				failedCount++
+			}
+			pm.Emit(opID, services.ProgressEvent{Type: "step-log", Step: stepName, Host: minion, Progress: float64(done) / float64(len(minions)), Message: "命令输出",
+				Data: map[string]interface{}{"stdout": output, "retcode": rc, "returned": returned, "success": ok}})
+		}
+		totalFailed += failedCount
+		failedSinceResume += failedCount
+		pm.Emit(opID, services.ProgressEvent{Type: "step-done", Step: stepName, Message: fmt.Sprintf("第 %d 批完成: 成功 %d，失败 %d，用时 %dms", b+1, successCount, failedCount, duration.Milliseconds())})
+
+		if saltJobService != nil {
+			endTime := time.Now()
+			status := "completed"
+			if failedCount > 0 {
+				status = "failed"
+			}
+			resultJSON, _ := json.Marshal(res)
+			dbJob := &models.SaltJobHistory{
+				JID:          fmt.Sprintf("%s-B%03d", taskID, b+1),
+				TaskID:       taskID,
+				Function:     "cmd.run",
+				Arguments:    string(argsJSON),
+				Target:       strings.Join(batch, ","),
+				TgtType:      "list",
+				User:         r.User,
+				Status:       status,
+				SuccessCount: successCount,
+				FailedCount:  failedCount,
+				Result:       string(resultJSON),
+				StartTime:    start,
+				EndTime:      &endTime,
+				Duration:     duration.Milliseconds(),
+			}
+			if err := saltJobService.CreateJob(context.Background(), dbJob); err != nil {
+				log.Printf("[ExecuteCustomCommandAsync] 保存批次作业失败: %v", err)
+			}
+		}
+
+		if b == totalBatches-1 {
+			break
+		}
+
+		if threshold >= 0 && failedSinceResume > threshold {
+			reason := fmt.Sprintf("失败数 %d 超过阈值 %d", failedSinceResume, threshold)
+			if !r.PauseOnFailure {
+				failed = true
+				finalMsg = fmt.Sprintf("已中止（%s），完成 %d/%d 批", reason, b+1, totalBatches)
+				pm.Emit(opID, services.ProgressEvent{Type: "error", Step: stepName, Message: reason + "，剩余批次不再执行"})
+				return
+			}
+			if !waitForResume(pm, opID, control, reason) {
+				failed = true
+				finalMsg = fmt.Sprintf("已中止（%s），完成 %d/%d 批", reason, b+1, totalBatches)
+				return
+			}
+			failedSinceResume = 0
+			continue
+		}
+
+		if r.BatchWait > 0 {
+			pm.Emit(opID, services.ProgressEvent{Type: "step-log", Step: stepName, Message: fmt.Sprintf("等待 %d 秒后执行下一批", r.BatchWait)})
+			select {
+			case <-time.After(time.Duration(r.BatchWait) * time.Second):
+			case action := <-control:
+				if !applyControl(action, b+1) {
+					return
+				}
+			}
+		}
+	}
+
+	failed = totalFailed > 0
+	finalMsg = fmt.Sprintf("分批执行完成: %d 个 minion，失败 %d", len(minions), totalFailed)
+}
+
+// ControlProgress 对支持控制的异步操作执行暂停/恢复/中止（仅发起者或管理员）
+// POST /api/saltstack/progress/:opId/control  { action: "pause"|"resume"|"abort" }
+func (h *SaltStackHandler) ControlProgress(c *gin.Context) {
+	var req struct {
+		Action string `json:"action" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	opID := c.Param("opId")
+	pm := services.GetProgressManager()
+	owner, ok := pm.ControlOwner(opID)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "operation not found"})
+		return
+	}
+	// 仅发起者或管理员可以控制操作
+	if owner != c.GetString("username") && !middleware.HasRole(c, "admin") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only the operation owner or an admin can control it"})
+		return
+	}
+	if err := pm.SendControl(opID, strings.ToLower(req.Action)); err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	log.Printf("[ControlProgress] User=%s, OpID=%s, Action=%s", c.GetString("username"), opID, req.Action)
+	c.JSON(http.StatusOK, gin.H{"opId": opID, "action": req.Action})
+}
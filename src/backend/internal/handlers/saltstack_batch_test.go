@@ -0,0 +1,73 @@
+package handlers
+
+import "testing"
+
+func TestParseBatchSpec(t *testing.T) {
+	cases := []struct {
+		spec    string
+		total   int
+		want    int
+		wantErr bool
+	}{
+		{spec: "10", total: 100, want: 10},
+		{spec: " 5 ", total: 3, want: 5},
+		{spec: "10%", total: 100, want: 10},
+		{spec: "10%", total: 15, want: 2}, // 1.5 向上取整
+		{spec: "1%", total: 10, want: 1},  // 至少 1 个
+		{spec: "100%", total: 7, want: 7},
+		{spec: "", total: 10, wantErr: true},
+		{spec: "0", total: 10, wantErr: true},
+		{spec: "-1", total: 10, wantErr: true},
+		{spec: "abc", total: 10, wantErr: true},
+		{spec: "0%", total: 10, wantErr: true},
+		{spec: "101%", total: 10, wantErr: true},
+		{spec: "x%", total: 10, wantErr: true},
+	}
+	for _, tc := range cases {
+		got, err := parseBatchSpec(tc.spec, tc.total)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("parseBatchSpec(%q, %d) 应返回错误, 得到 %d", tc.spec, tc.total, got)
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Errorf("parseBatchSpec(%q, %d) = %d, %v; 期望 %d", tc.spec, tc.total, got, err, tc.want)
+		}
+	}
+}
+
+func TestParseFailureThreshold(t *testing.T) {
+	cases := []struct {
+		spec    string
+		total   int
+		want    int
+		wantErr bool
+	}{
+		{spec: "", total: 10, want: -1}, // 不限制
+		{spec: "  ", total: 10, want: -1},
+		{spec: "0", total: 10, want: 0},
+		{spec: "0%", total: 10, want: 0},
+		{spec: "3", total: 10, want: 3},
+		{spec: "5%", total: 100, want: 5},
+		{spec: "5%", total: 30, want: 1}, // 1.5 向下取整
+		{spec: "10%", total: 5, want: 0}, // 不足 1 个时任一失败即中止
+		{spec: "100%", total: 8, want: 8},
+		{spec: "-1", total: 10, wantErr: true},
+		{spec: "abc", total: 10, wantErr: true},
+		{spec: "-5%", total: 10, wantErr: true},
+		{spec: "101%", total: 10, wantErr: true},
+	}
+	for _, tc := range cases {
+		got, err := parseFailureThreshold(tc.spec, tc.total)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("parseFailureThreshold(%q, %d) 应返回错误, 得到 %d", tc.spec, tc.total, got)
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Errorf("parseFailureThreshold(%q, %d) = %d, %v; 期望 %d", tc.spec, tc.total, got, err, tc.want)
+		}
+	}
+}
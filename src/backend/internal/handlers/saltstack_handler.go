@@ -18,6 +18,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/database"
 	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
 	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/scripts"
 	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/services"
@@ -2566,13 +2567,16 @@ func (h *SaltStackHandler) extractAPIVersion(resp map[string]interface{}) string
 // ExecuteSaltCommand 执行Salt命令
 func (h *SaltStackHandler) ExecuteSaltCommand(c *gin.Context) {
 	var request struct {
-		Target    string        `json:"target" binding:"required"`
-		Function  string        `json:"function"`  // 支持 function 字段
-		Fun       string        `json:"fun"`       // 兼容 fun 字段（前端常用）
-		Arguments string        `json:"arguments"` // 支持字符串参数
-		Arg       []interface{} `json:"arg"`       // 兼容 arg 数组格式
-		TgtType   string        `json:"tgt_type"`  // 目标类型: glob, list, grain 等
-		TaskID    string        `json:"task_id"`   // 前端传递的任务ID，用于关联作业历史
+		Target    string                 `json:"target" binding:"required"`
+		Function  string                 `json:"function"`  // 支持 function 字段
+		Fun       string                 `json:"fun"`       // 兼容 fun 字段（前端常用）
+		Arguments string                 `json:"arguments"` // 支持字符串参数
+		Arg       []interface{}          `json:"arg"`       // 兼容 arg 数组格式
+		Kwarg     map[string]interface{} `json:"kwarg"`     // 关键字参数
+		TgtType   string                 `json:"tgt_type"`  // 目标类型: glob, list, grain 等
+		TaskID    string                 `json:"task_id"`   // 前端传递的任务ID，用于关联作业历史
+		Reason    string                 `json:"reason"`    // 命中需审批的危险命令时提交的申请理由
+		MasterID  string                 `json:"master_id"` // 指定 Master；为空时按 minion 密钥归属路由
 	}
 
 	if err := c.ShouldBindJSON(&request); err != nil {
@@ -2598,25 +2602,31 @@ func (h *SaltStackHandler) ExecuteSaltCommand(c *gin.Context) {
 		args = request.Arg
 	}
 
+	// tgt_type=group 时展开为分组（含动态分组）成员列表；须在审批与 Master 路由之前展开，
+	// 审批记录保存的是申请时的成员，批准后执行的正是审批人看到的目标
+	target, tgtType, err := services.ExpandMinionGroupTarget(database.DB, request.Target, request.TgtType)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	request.Target, request.TgtType = target, tgtType
+
 	// 危险命令检查（仅对 cmd.run 等执行命令进行检查）
-	if function == "cmd.run" || function == "cmd.shell" || function == "cmd.exec_code" {
+	if services.IsCommandExecutionFunction(function) {
 		saltJobService := services.GetSaltJobService()
 		if saltJobService != nil {
-			// 拼接完整命令进行检查
-			var fullCommand string
-			for _, arg := range args {
-				if s, ok := arg.(string); ok {
-					fullCommand += s + " "
-				}
-			}
-
-			isDangerous, matchedRule, err := saltJobService.CheckDangerousCommand(fullCommand)
+			isDangerous, matchedRule, err := saltJobService.CheckDangerousInvocation(function, args)
 			if err != nil {
 				log.Printf("[WARNING] 危险命令检查失败: %v", err)
 			}
+			// high/critical 级别转为审批流程，批准后由审批服务执行一次
+			if isDangerous && services.RequiresApproval(matchedRule) {
+				h.submitDangerousCommandApproval(c, function, args, request.Kwarg, request.Target, request.TgtType, request.MasterID, request.Reason, matchedRule)
+				return
+			}
 			if isDangerous && matchedRule != nil {
-				log.Printf("[SECURITY] 拦截危险命令: User=%s, Target=%s, Command=%s, Rule=%s",
-					c.GetString("username"), request.Target, fullCommand, matchedRule.Pattern)
+				log.Printf("[SECURITY] 拦截危险命令: User=%s, Target=%s, Args=%v, Rule=%s",
+					c.GetString("username"), request.Target, args, matchedRule.Pattern)
 
 				c.JSON(http.StatusForbidden, gin.H{
 					"success": false,
@@ -2632,8 +2642,12 @@ func (h *SaltStackHandler) ExecuteSaltCommand(c *gin.Context) {
 		}
 	}
 
-	// 使用已有的 saltAPIClient 进行认证和请求
-	client := h.newSaltAPIClient()
+	// 使用已有的 saltAPIClient 进行认证和请求（多 Master 时路由到持有目标 minion 密钥的 Master）
+	client, err := h.newSaltAPIClientForTarget(request.MasterID, request.Target, request.TgtType)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
 	if err := client.authenticate(); err != nil {
 		log.Printf("[ERROR] Salt API 认证失败: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -2655,12 +2669,19 @@ func (h *SaltStackHandler) ExecuteSaltCommand(c *gin.Context) {
 		payload["tgt_type"] = request.TgtType
 	}
 
-	// 对 cmd.run 命令始终启用 python_shell
+	// 对 cmd.run 命令默认启用 python_shell
+	kwarg := request.Kwarg
 	if function == "cmd.run" {
-		payload["kwarg"] = map[string]interface{}{
-			"python_shell": true,
+		if kwarg == nil {
+			kwarg = map[string]interface{}{}
+		}
+		if _, ok := kwarg["python_shell"]; !ok {
+			kwarg["python_shell"] = true
 		}
 	}
+	if len(kwarg) > 0 {
+		payload["kwarg"] = kwarg
+	}
 
 	// 调试：打印请求信息
 	log.Printf("[DEBUG] Salt API 请求 (async): Payload=%+v", payload)
@@ -3018,15 +3039,11 @@ func (h *SaltStackHandler) getSaltAuthToken(ctx context.Context) string {
 
 // ExecuteCustomCommandAsync 异步执行自定义 Bash/Python 命令（通过 Salt cmd.run 下发）
 // 请求: { target: string, language: "bash"|"python", code: string, timeout?: int }
+// 分批: 额外传 batch ("10" 或 "10%")、batch_wait (秒)、failure_threshold ("3" 或 "5%")，
+// 超过失败阈值时立即中止；pause_on_failure=true 时改为暂停等待恢复/中止
 // 返回: { opId: string }
 func (h *SaltStackHandler) ExecuteCustomCommandAsync(c *gin.Context) {
-	var req struct {
-		Target   string `json:"target"`
-		Language string `json:"language"`
-		Code     string `json:"code"`
-		Timeout  int    `json:"timeout"`
-		User     string `json:"user,omitempty"`
-	}
+	var req customCommandRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -3056,12 +3073,46 @@ func (h *SaltStackHandler) ExecuteCustomCommandAsync(c *gin.Context) {
 		return
 	}
 
+	// 分批参数校验（此时尚不知道目标数量，用 100 校验百分比格式）
+	if req.Batch != "" {
+		if _, err := parseBatchSpec(req.Batch, 100); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if _, err := parseFailureThreshold(req.FailureThreshold, 100); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if req.BatchWait < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "batch_wait must be >= 0"})
+			return
+		}
+	}
+
 	pm := services.GetProgressManager()
 	op := pm.Start("salt:execute-custom", "开始下发自定义命令")
 
 	// 生成 TaskID 供前端追踪
 	taskID := fmt.Sprintf("EXEC-%s-%04d", time.Now().Format("20060102-150405"), time.Now().Nanosecond()/1000000%10000)
 
+	if req.Batch != "" {
+		cmd := "bash -s"
+		if req.Language == "python" {
+			cmd = "python3 -"
+		}
+		kwarg := map[string]interface{}{
+			"stdin":        req.Code,
+			"python_shell": true,
+		}
+		if req.Timeout > 0 {
+			kwarg["timeout"] = req.Timeout
+		}
+		control, _ := pm.EnableControl(op.ID, c.GetString("username"))
+		go h.runBatchedCustomCommand(op.ID, taskID, control, req, cmd, kwarg)
+		c.JSON(http.StatusAccepted, gin.H{"opId": op.ID, "taskId": taskID, "batch": req.Batch})
+		return
+	}
+
 	// Avoid anonymous-struct type mismatch by capturing the bound request
 	r := req
 	go func(opID, tID string) {
@@ -3070,7 +3121,13 @@ func (h *SaltStackHandler) ExecuteCustomCommandAsync(c *gin.Context) {
 
 		pm.Emit(opID, services.ProgressEvent{Type: "step-start", Step: "prepare", Message: "准备连接 Salt API"})
 
-		client := h.newSaltAPIClient()
+		// 按目标 minion 的密钥归属路由到对应 Master
+		client, err := h.newSaltAPIClientForTarget(r.MasterID, r.Target, "glob")
+		if err != nil {
+			failed = true
+			pm.Emit(opID, services.ProgressEvent{Type: "error", Step: "prepare", Message: fmt.Sprintf("解析目标 Master 失败: %v", err)})
+			return
+		}
 		if err := client.authenticate(); err != nil {
 			failed = true
 			pm.Emit(opID, services.ProgressEvent{Type: "error", Step: "auth", Message: fmt.Sprintf("Salt API 认证失败: %v", err)})
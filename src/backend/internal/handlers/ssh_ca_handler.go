@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/middleware"
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// SSHCAHandler SSH 用户证书颁发机构处理器
+type SSHCAHandler struct {
+	service *services.SSHCAService
+}
+
+// NewSSHCAHandler 创建 SSH CA 处理器
+func NewSSHCAHandler(service *services.SSHCAService) *SSHCAHandler {
+	return &SSHCAHandler{service: service}
+}
+
+// RegisterRoutes 注册路由（挂载在已认证的 /security 分组下）
+func (h *SSHCAHandler) RegisterRoutes(r *gin.RouterGroup) {
+	g := r.Group("/ssh-ca")
+	g.GET("/public-key", h.GetPublicKey)
+	g.GET("/principals", h.GetPrincipals)
+	g.POST("/sign", h.Sign)
+
+	admin := g.Group("", middleware.AdminMiddleware())
+	admin.GET("/trust-script", h.GetTrustScript)
+	admin.POST("/rotate", h.Rotate)
+}
+
+// GetPublicKey 获取 CA 公钥（节点 TrustedUserCAKeys 使用）
+// GET /api/security/ssh-ca/public-key
+func (h *SSHCAHandler) GetPublicKey(c *gin.Context) {
+	publicKey, err := h.service.PublicKey()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": gin.H{"public_key": publicKey}})
+}
+
+// GetPrincipals 预览当前用户证书将包含的登录主体
+// GET /api/security/ssh-ca/principals
+func (h *SSHCAHandler) GetPrincipals(c *gin.Context) {
+	principals, err := h.service.UserPrincipals(c.Request.Context(), c.GetUint("user_id"), c.GetString("username"), middleware.HasRole(c, "admin"))
+	if err != nil {
+		c.JSON(sshCAErrorStatus(err), gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": principals})
+}
+
+// Sign 为当前用户的公钥签发短期 SSH 证书
+// POST /api/security/ssh-ca/sign?download=true
+func (h *SSHCAHandler) Sign(c *gin.Context) {
+	var req models.SSHUserCertRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	cert, err := h.service.SignUserKey(c.Request.Context(), &req, c.GetUint("user_id"), c.GetString("username"), middleware.HasRole(c, "admin"))
+	if err != nil {
+		c.JSON(sshCAErrorStatus(err), gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if c.Query("download") == "true" {
+		c.Header("Content-Disposition", `attachment; filename="id-cert.pub"`)
+		c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(cert.Certificate+"\n"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": cert})
+}
+
+// GetTrustScript 获取在存量节点上手动配置 CA 信任的脚本
+// GET /api/security/ssh-ca/trust-script?cluster_scope=cluster-1&sudo=true
+func (h *SSHCAHandler) GetTrustScript(c *gin.Context) {
+	sudoPrefix := ""
+	if c.Query("sudo") == "true" {
+		sudoPrefix = "sudo "
+	}
+	var scopes []string
+	if scope := strings.TrimSpace(c.Query("cluster_scope")); scope != "" {
+		scopes = append(scopes, scope)
+	}
+	script, err := h.service.TrustScript(sudoPrefix, scopes...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	c.Data(http.StatusOK, "text/x-shellscript; charset=utf-8", []byte(script))
+}
+
+// Rotate 轮换 CA 密钥（已签发证书失效，节点需重新下发 CA 公钥）
+// POST /api/security/ssh-ca/rotate
+func (h *SSHCAHandler) Rotate(c *gin.Context) {
+	publicKey, err := h.service.Rotate(c.GetUint("user_id"), c.GetString("username"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": gin.H{"public_key": publicKey}})
+}
+
+func sshCAErrorStatus(err error) int {
+	if errors.Is(err, services.ErrNoSSHPrincipals) {
+		return http.StatusForbidden
+	}
+	return http.StatusBadRequest
+}
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/middleware"
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// SSHKnownHostsHandler SSH 主机密钥（known_hosts）管理处理器
+type SSHKnownHostsHandler struct {
+	service *services.SSHKnownHostsService
+}
+
+// NewSSHKnownHostsHandler 创建 SSH 主机密钥管理处理器
+func NewSSHKnownHostsHandler(service *services.SSHKnownHostsService) *SSHKnownHostsHandler {
+	return &SSHKnownHostsHandler{service: service}
+}
+
+// RegisterRoutes 注册路由（挂载在已认证的 /security 分组下，仅管理员可访问）
+func (h *SSHKnownHostsHandler) RegisterRoutes(r *gin.RouterGroup) {
+	g := r.Group("/ssh-known-hosts", middleware.AdminMiddleware())
+	g.GET("", h.List)
+	g.POST("", h.Register)
+	g.GET("/mode", h.GetMode)
+	g.PUT("/mode", h.SetMode)
+	g.POST("/:id/approve", h.Approve)
+	g.POST("/:id/reject", h.Reject)
+	g.DELETE("/:id", h.Delete)
+}
+
+// List 列出已知主机密钥
+// GET /api/security/ssh-known-hosts?host=&status=pending
+func (h *SSHKnownHostsHandler) List(c *gin.Context) {
+	hosts, err := h.service.List(c.Query("host"), c.Query("status"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": hosts, "mode": h.service.Mode()})
+}
+
+// Register 手动登记或轮换主机公钥
+// POST /api/security/ssh-known-hosts
+func (h *SSHKnownHostsHandler) Register(c *gin.Context) {
+	var req models.SSHKnownHostRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	entry, err := h.service.Register(&req, c.GetString("username"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": entry})
+}
+
+// GetMode 获取主机密钥校验模式
+// GET /api/security/ssh-known-hosts/mode
+func (h *SSHKnownHostsHandler) GetMode(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": gin.H{"mode": h.service.Mode()}})
+}
+
+// SetMode 运行时切换主机密钥校验模式
+// PUT /api/security/ssh-known-hosts/mode
+func (h *SSHKnownHostsHandler) SetMode(c *gin.Context) {
+	var req struct {
+		Mode string `json:"mode" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if err := h.service.SetMode(req.Mode, c.GetString("username")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": gin.H{"mode": h.service.Mode()}})
+}
+
+// Approve 审批（信任）主机密钥指纹，同主机同类型的旧指纹被轮换
+// POST /api/security/ssh-known-hosts/:id/approve
+func (h *SSHKnownHostsHandler) Approve(c *gin.Context) {
+	id, ok := parseKnownHostID(c)
+	if !ok {
+		return
+	}
+	entry, err := h.service.Approve(id, c.GetString("username"))
+	if err != nil {
+		respondKnownHostError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": entry})
+}
+
+// Reject 拒绝主机密钥指纹
+// POST /api/security/ssh-known-hosts/:id/reject
+func (h *SSHKnownHostsHandler) Reject(c *gin.Context) {
+	id, ok := parseKnownHostID(c)
+	if !ok {
+		return
+	}
+	entry, err := h.service.Reject(id, c.GetString("username"))
+	if err != nil {
+		respondKnownHostError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": entry})
+}
+
+// Delete 删除主机密钥记录（下次连接重新首次信任）
+// DELETE /api/security/ssh-known-hosts/:id
+func (h *SSHKnownHostsHandler) Delete(c *gin.Context) {
+	id, ok := parseKnownHostID(c)
+	if !ok {
+		return
+	}
+	if err := h.service.Delete(id, c.GetString("username")); err != nil {
+		respondKnownHostError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+func parseKnownHostID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "invalid id"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func respondKnownHostError(c *gin.Context, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "known host not found"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+}
@@ -0,0 +1,352 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/middleware"
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/services"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/net/websocket"
+	"gorm.io/gorm"
+)
+
+// TerminalHandler Web 终端处理器（websocket PTY over SSH，会话录像回放）
+type TerminalHandler struct {
+	service *services.TerminalService
+}
+
+// NewTerminalHandler 创建 Web 终端处理器
+func NewTerminalHandler(service *services.TerminalService) *TerminalHandler {
+	return &TerminalHandler{service: service}
+}
+
+// RegisterRoutes 注册路由（挂载在已认证的 /terminal 分组下）
+func (h *TerminalHandler) RegisterRoutes(r *gin.RouterGroup) {
+	r.GET("/ws", h.Connect)
+	r.GET("/sessions", h.ListSessions)
+	r.GET("/sessions/:id", h.GetSession)
+	r.GET("/sessions/:id/recording", h.GetRecording)
+}
+
+// resolveTarget 解析查询参数中的连接目标
+// ?kind=slurm_node&node_id=1 或 ?kind=host_template&template_id=1&host=10.0.0.5
+func (h *TerminalHandler) resolveTarget(c *gin.Context) (*services.TerminalTarget, error) {
+	switch c.DefaultQuery("kind", models.TerminalTargetSlurmNode) {
+	case models.TerminalTargetSlurmNode:
+		id, err := strconv.ParseUint(c.Query("node_id"), 10, 32)
+		if err != nil {
+			return nil, errors.New("invalid node_id")
+		}
+		return h.service.ResolveSlurmNodeTarget(uint(id))
+	case models.TerminalTargetHostTemplate:
+		id, err := strconv.ParseUint(c.Query("template_id"), 10, 32)
+		if err != nil || c.Query("host") == "" {
+			return nil, errors.New("template_id and host are required")
+		}
+		return h.service.ResolveHostTemplateTarget(uint(id), c.Query("host"))
+	default:
+		return nil, fmt.Errorf("unsupported target kind %q", c.Query("kind"))
+	}
+}
+
+// checkTerminalOrigin 仅接受同源的浏览器连接，防止跨站 websocket 劫持
+func checkTerminalOrigin(config *websocket.Config, req *http.Request) error {
+	origin := req.Header.Get("Origin")
+	if origin == "" {
+		return nil // 非浏览器客户端
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return err
+	}
+	host := req.Host
+	if forwarded := req.Header.Get("X-Forwarded-Host"); forwarded != "" {
+		host = forwarded
+	}
+	if u.Host != host {
+		return fmt.Errorf("cross-origin terminal connection from %s rejected", origin)
+	}
+	config.Origin = u
+	return nil
+}
+
+// Connect 打开 Web 终端（websocket），先解析目标与授权，再升级连接
+func (h *TerminalHandler) Connect(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	username := c.GetString("username")
+
+	target, err := h.resolveTarget(c)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "target host not found"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if err := h.service.Authorize(c.Request.Context(), userID, middleware.HasRole(c, "admin"), target); err != nil {
+		var denied *services.TerminalAccessDeniedError
+		if errors.As(err, &denied) {
+			services.GetAuditService().NewAuditEntry(models.AuditCategorySecurity, models.AuditActionTerminalSession).
+				WithUser(userID, username, c.ClientIP()).
+				WithResource("terminal_session", "", target.Label()).
+				WithStatus(models.AuditStatusFailed).
+				WithSeverity(models.AuditSeverityWarning).
+				WithErrorMessage(denied.Reason).
+				WithTags("terminal", "denied").
+				SaveAsync()
+			c.JSON(http.StatusForbidden, gin.H{"success": false, "error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	cols, _ := strconv.Atoi(c.DefaultQuery("cols", "120"))
+	rows, _ := strconv.Atoi(c.DefaultQuery("rows", "32"))
+	if cols <= 0 || cols > 1000 {
+		cols = 120
+	}
+	if rows <= 0 || rows > 500 {
+		rows = 32
+	}
+	clientIP := c.ClientIP()
+
+	server := websocket.Server{
+		Handshake: checkTerminalOrigin,
+		Handler: func(ws *websocket.Conn) {
+			h.serveTerminal(ws, userID, username, clientIP, target, cols, rows)
+		},
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+// serveTerminal 在 websocket 与 SSH PTY 之间转发数据并录像
+func (h *TerminalHandler) serveTerminal(ws *websocket.Conn, userID uint, username, clientIP string, target *services.TerminalTarget, cols, rows int) {
+	defer ws.Close()
+
+	var sendMu sync.Mutex
+	send := func(msg models.TerminalMessage) error {
+		sendMu.Lock()
+		defer sendMu.Unlock()
+		return websocket.JSON.Send(ws, msg)
+	}
+
+	session, err := h.service.StartSession(userID, username, clientIP, target, cols, rows)
+	if err != nil {
+		send(models.TerminalMessage{Type: "error", Data: err.Error()})
+		return
+	}
+	recorder, err := services.NewTerminalRecorder(cols, rows, target.Label())
+	if err != nil {
+		send(models.TerminalMessage{Type: "error", Data: "cannot start session recording"})
+		h.service.FinishSession(session, nil, models.TerminalCloseError, err)
+		return
+	}
+
+	client, err := h.service.Dial(target)
+	if err != nil {
+		send(models.TerminalMessage{Type: "error", Data: "ssh connect failed: " + err.Error()})
+		h.service.FinishSession(session, recorder, models.TerminalCloseError, err)
+		return
+	}
+	defer client.Close()
+
+	sshSession, err := client.NewSession()
+	if err != nil {
+		send(models.TerminalMessage{Type: "error", Data: err.Error()})
+		h.service.FinishSession(session, recorder, models.TerminalCloseError, err)
+		return
+	}
+	defer sshSession.Close()
+
+	stdin, stdout, err := startShell(sshSession, cols, rows)
+	if err != nil {
+		send(models.TerminalMessage{Type: "error", Data: err.Error()})
+		h.service.FinishSession(session, recorder, models.TerminalCloseError, err)
+		return
+	}
+	h.pump(ws, send, session, recorder, sshSession, stdin, stdout)
+}
+
+// startShell 申请 PTY 并启动登录 shell
+func startShell(sshSession *ssh.Session, cols, rows int) (io.WriteCloser, io.Reader, error) {
+	stdin, err := sshSession.StdinPipe()
+	if err != nil {
+		return nil, nil, err
+	}
+	stdout, err := sshSession.StdoutPipe()
+	if err != nil {
+		return nil, nil, err
+	}
+	modes := ssh.TerminalModes{ssh.ECHO: 1, ssh.TTY_OP_ISPEED: 14400, ssh.TTY_OP_OSPEED: 14400}
+	if err := sshSession.RequestPty("xterm-256color", rows, cols, modes); err != nil {
+		return nil, nil, fmt.Errorf("request pty: %v", err)
+	}
+	if err := sshSession.Shell(); err != nil {
+		return nil, nil, fmt.Errorf("start shell: %v", err)
+	}
+	return stdin, stdout, nil
+}
+
+func (h *TerminalHandler) pump(ws *websocket.Conn, send func(models.TerminalMessage) error, session *models.TerminalSession,
+	recorder *services.TerminalRecorder, sshSession *ssh.Session, stdin io.WriteCloser, stdout io.Reader) {
+	var bytesOut int64
+	done := make(chan struct{})
+	outputDone := make(chan error, 1)
+	go func() {
+		buf := make([]byte, 32*1024)
+		for {
+			n, err := stdout.Read(buf)
+			if n > 0 {
+				atomic.AddInt64(&bytesOut, int64(n))
+				if text := recorder.Output(buf[:n]); text != "" {
+					if sendErr := send(models.TerminalMessage{Type: "output", Data: text}); sendErr != nil {
+						outputDone <- sendErr
+						return
+					}
+				}
+			}
+			if err != nil {
+				outputDone <- err
+				return
+			}
+		}
+	}()
+
+	inputCh := make(chan models.TerminalMessage)
+	inputErr := make(chan error, 1)
+	go func() {
+		for {
+			var msg models.TerminalMessage
+			if err := websocket.JSON.Receive(ws, &msg); err != nil {
+				inputErr <- err
+				return
+			}
+			select {
+			case inputCh <- msg:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	send(models.TerminalMessage{Type: "status", Data: session.SessionID})
+	idle := time.NewTimer(h.service.IdleTimeout)
+	defer idle.Stop()
+	maxTimer := time.NewTimer(h.service.MaxDuration)
+	defer maxTimer.Stop()
+
+	var reason string
+	var sessionErr error
+loop:
+	for {
+		select {
+		case msg := <-inputCh:
+			switch msg.Type {
+			case "input":
+				if _, err := stdin.Write([]byte(msg.Data)); err != nil {
+					reason, sessionErr = models.TerminalCloseError, err
+					break loop
+				}
+				session.BytesIn += int64(len(msg.Data))
+				idle.Reset(h.service.IdleTimeout)
+			case "resize":
+				if msg.Cols > 0 && msg.Rows > 0 && msg.Cols <= 1000 && msg.Rows <= 500 {
+					sshSession.WindowChange(msg.Rows, msg.Cols)
+					recorder.Resize(msg.Cols, msg.Rows)
+					session.Cols, session.Rows = msg.Cols, msg.Rows
+				}
+			case "ping":
+				send(models.TerminalMessage{Type: "pong"})
+			}
+		case <-outputDone:
+			reason = models.TerminalCloseRemote
+			break loop
+		case <-inputErr:
+			reason = models.TerminalCloseClient
+			break loop
+		case <-idle.C:
+			reason = models.TerminalCloseIdle
+			break loop
+		case <-maxTimer.C:
+			reason = models.TerminalCloseMaxDuration
+			break loop
+		}
+	}
+	close(done)
+	sshSession.Close()
+
+	session.BytesOut = atomic.LoadInt64(&bytesOut)
+	send(models.TerminalMessage{Type: "closed", Reason: reason})
+	h.service.FinishSession(session, recorder, reason, sessionErr)
+	log.Printf("[Terminal] 会话结束: session=%s, user=%s, host=%s, reason=%s", session.SessionID, session.Username, session.Host, reason)
+}
+
+// ListSessions 查询终端会话（管理员可查看全部，普通用户仅自己的会话）
+func (h *TerminalHandler) ListSessions(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	if middleware.HasRole(c, "admin") {
+		userID = 0
+		if uid, err := strconv.ParseUint(c.Query("user_id"), 10, 32); err == nil {
+			userID = uint(uid)
+		}
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	sessions, total, err := h.service.ListSessions(userID, c.Query("host"), page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": sessions, "total": total})
+}
+
+// loadSession 加载会话并校验查看权限
+func (h *TerminalHandler) loadSession(c *gin.Context) (*models.TerminalSession, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "invalid id"})
+		return nil, false
+	}
+	session, err := h.service.GetSession(uint(id))
+	if err != nil || (!middleware.HasRole(c, "admin") && session.UserID != c.GetUint("user_id")) {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "session not found"})
+		return nil, false
+	}
+	return session, true
+}
+
+// GetSession 获取终端会话详情
+func (h *TerminalHandler) GetSession(c *gin.Context) {
+	session, ok := h.loadSession(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": session})
+}
+
+// GetRecording 获取会话录像（asciicast v2）的临时下载地址
+func (h *TerminalHandler) GetRecording(c *gin.Context) {
+	session, ok := h.loadSession(c)
+	if !ok {
+		return
+	}
+	recordingURL, err := h.service.RecordingURL(session, 15*time.Minute)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": gin.H{"url": recordingURL, "format": "asciicast-v2", "expires_in": 900}})
+}
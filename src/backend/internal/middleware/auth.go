@@ -311,6 +311,46 @@ func getRolePermissions(db *gorm.DB, roles []string) []string {
 	return permissions
 }
 
+// APITokenAuthMiddleware 个人 API 令牌认证中间件（Authorization: Bearer aim_...）
+// 仅接受 API 令牌，不读取 Cookie，供外部代理访问的端点（如 MCP）使用
+func APITokenAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tokenParts := strings.SplitN(c.GetHeader("Authorization"), " ", 2)
+		if len(tokenParts) != 2 || tokenParts[0] != "Bearer" || !services.IsAPIToken(tokenParts[1]) {
+			c.Header("WWW-Authenticate", `Bearer realm="ai-infra-matrix"`)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "API token is required"})
+			c.Abort()
+			return
+		}
+
+		tokenService := services.GetAPITokenService()
+		if tokenService == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "API token service unavailable"})
+			c.Abort()
+			return
+		}
+		user, token, err := tokenService.Authenticate(tokenParts[1], c.ClientIP())
+		if err != nil {
+			c.Header("WWW-Authenticate", `Bearer realm="ai-infra-matrix", error="invalid_token"`)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			c.Abort()
+			return
+		}
+
+		roles := make([]string, 0, len(user.Roles))
+		for _, role := range user.Roles {
+			roles = append(roles, role.Name)
+		}
+		c.Set("user_id", user.ID)
+		c.Set("username", user.Username)
+		c.Set("roles", roles)
+		c.Set("permissions", getRolePermissions(database.DB, roles))
+		c.Set("auth_source", "api_token")
+		c.Set("api_token_id", token.ID)
+		c.Next()
+	}
+}
+
 // AdminMiddleware 管理员权限中间件
 func AdminMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -1,6 +1,9 @@
 package models
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
 	"time"
 
 	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/utils"
@@ -50,6 +53,7 @@ type AIAssistantConfig struct {
 	IsEnabled        bool           `json:"is_enabled" gorm:"default:true"`
 	IsDefault        bool           `json:"is_default" gorm:"default:false"`
 	MCPConfig        *MCPConfig     `json:"mcp_config,omitempty" gorm:"type:text"` // JSON存储MCP配置
+	RAGConfig        *RAGConfig     `json:"rag_config,omitempty" gorm:"type:text"` // JSON存储检索增强配置
 	RateLimitPerHour int            `json:"rate_limit_per_hour" gorm:"default:100"`
 	RateLimitPerDay  int            `json:"rate_limit_per_day" gorm:"default:1000"`
 	TimeoutSeconds   int            `json:"timeout_seconds" gorm:"default:60"`
@@ -69,11 +73,39 @@ type AIAssistantConfig struct {
 }
 
 // MCPConfig Model Context Protocol配置
+// 机器人可挂载多个 MCP 服务器，其工具仅在 AllowedTools 允许时才提供给模型
 type MCPConfig struct {
-	ServerURL      string            `json:"server_url"`
+	ServerURL      string            `json:"server_url"` // 单个 Streamable HTTP 服务器（兼容旧配置，等价于名为 default 的服务器）
 	Capabilities   []string          `json:"capabilities"`
 	Tools          []MCPTool         `json:"tools"`
-	Authentication map[string]string `json:"authentication,omitempty"`
+	Authentication map[string]string `json:"authentication,omitempty"` // ServerURL 的额外请求头
+
+	Servers []MCPServerConfig `json:"servers,omitempty"`
+	// AllowedTools 允许调用的工具，格式为 "服务器名/工具名"，支持通配符（如 "github/*"）；
+	// 资源与提示词分别以 "服务器名/read_resource"、"服务器名/get_prompt" 授权；为空时不提供任何 MCP 工具
+	AllowedTools []string `json:"allowed_tools,omitempty"`
+	// BaseProvider 提供商为 mcp 时实际负责对话的模型提供商，默认 openai
+	BaseProvider AIProvider `json:"base_provider,omitempty"`
+}
+
+// MCP 服务器传输方式
+const (
+	MCPTransportStdio = "stdio"
+	MCPTransportHTTP  = "http" // Streamable HTTP
+)
+
+// MCPServerConfig MCP 服务器连接配置
+type MCPServerConfig struct {
+	Name      string            `json:"name"`
+	Transport string            `json:"transport"`         // stdio 或 http
+	Command   string            `json:"command,omitempty"` // stdio：MCP_SERVERS_DIR 下的可执行文件绝对路径
+	Args      []string          `json:"args,omitempty"`
+	Env       map[string]string `json:"env,omitempty"` // 不允许 PATH、LD_*、PYTHON* 等可注入代码的变量
+	URL       string            `json:"url,omitempty"` // http：MCP 端点地址，主机须在 MCP_ALLOWED_HOSTS 中
+	Headers   map[string]string `json:"headers,omitempty"`
+	// TimeoutSeconds 单次请求超时，默认 30 秒
+	TimeoutSeconds int  `json:"timeout_seconds,omitempty"`
+	Disabled       bool `json:"disabled,omitempty"`
 }
 
 // MCPTool MCP工具定义
@@ -83,6 +115,92 @@ type MCPTool struct {
 	Schema      interface{} `json:"schema"`
 }
 
+// mcpSecretMask 接口返回时代替请求头与环境变量值的占位符
+const mcpSecretMask = "***"
+
+// Value 实现 driver.Valuer 接口
+func (c MCPConfig) Value() (driver.Value, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan 实现 sql.Scanner 接口
+func (c *MCPConfig) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into MCPConfig", value)
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, c)
+}
+
+// EffectiveServers 返回启用的服务器列表（含兼容的 ServerURL）
+func (c *MCPConfig) EffectiveServers() []MCPServerConfig {
+	var servers []MCPServerConfig
+	if c.ServerURL != "" {
+		servers = append(servers, MCPServerConfig{Name: "default", Transport: MCPTransportHTTP, URL: c.ServerURL, Headers: c.Authentication})
+	}
+	for _, server := range c.Servers {
+		if !server.Disabled {
+			servers = append(servers, server)
+		}
+	}
+	return servers
+}
+
+// MaskSecrets 将请求头与环境变量的值替换为占位符，用于接口返回
+func (c *MCPConfig) MaskSecrets() {
+	maskMCPValues(c.Authentication)
+	for i := range c.Servers {
+		maskMCPValues(c.Servers[i].Headers)
+		maskMCPValues(c.Servers[i].Env)
+	}
+}
+
+// RestoreMaskedSecrets 更新配置时，将仍为占位符的值恢复为已保存的值
+func (c *MCPConfig) RestoreMaskedSecrets(existing *MCPConfig) {
+	if existing == nil {
+		return
+	}
+	restoreMCPValues(c.Authentication, existing.Authentication)
+	for i := range c.Servers {
+		for _, old := range existing.Servers {
+			if old.Name == c.Servers[i].Name {
+				restoreMCPValues(c.Servers[i].Headers, old.Headers)
+				restoreMCPValues(c.Servers[i].Env, old.Env)
+			}
+		}
+	}
+}
+
+func maskMCPValues(values map[string]string) {
+	for k, v := range values {
+		if v != "" {
+			values[k] = mcpSecretMask
+		}
+	}
+}
+
+func restoreMCPValues(values, existing map[string]string) {
+	for k, v := range values {
+		if v == mcpSecretMask {
+			values[k] = existing[k]
+		}
+	}
+}
+
 // AIConversation AI对话记录
 type AIConversation struct {
 	ID         uint               `json:"id" gorm:"primaryKey"`
@@ -118,11 +236,14 @@ type AIMessage struct {
 // AIUsageStats AI使用统计
 type AIUsageStats struct {
 	ID              uint      `json:"id" gorm:"primaryKey"`
-	UserID          uint      `json:"user_id" gorm:"not null;index"`
-	ConfigID        uint      `json:"config_id" gorm:"not null;index"`
-	Date            time.Time `json:"date" gorm:"not null;index"`
+	UserID          uint      `json:"user_id" gorm:"not null;index;uniqueIndex:idx_ai_usage_user_config_date"`
+	ConfigID        uint      `json:"config_id" gorm:"not null;index;uniqueIndex:idx_ai_usage_user_config_date"`
+	Date            time.Time `json:"date" gorm:"not null;index;uniqueIndex:idx_ai_usage_user_config_date"`
 	RequestCount    int       `json:"request_count" gorm:"default:0"`
 	TokensUsed      int       `json:"tokens_used" gorm:"default:0"`
+	InputTokens     int       `json:"input_tokens" gorm:"default:0"`
+	OutputTokens    int       `json:"output_tokens" gorm:"default:0"`
+	Cost            float64   `json:"cost" gorm:"default:0"` // 按模型单价计算的费用
 	SuccessCount    int       `json:"success_count" gorm:"default:0"`
 	ErrorCount      int       `json:"error_count" gorm:"default:0"`
 	AverageResponse int       `json:"average_response" gorm:"default:0"` // 平均响应时间
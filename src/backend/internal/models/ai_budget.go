@@ -0,0 +1,87 @@
+package models
+
+import "time"
+
+// AI 预算适用范围
+const (
+	AIBudgetScopeUser   = "user"   // 单个用户
+	AIBudgetScopeGroup  = "group"  // 用户组（团队）成员共享
+	AIBudgetScopeConfig = "config" // 单个机器人配置的全部调用
+)
+
+// AI 预算周期
+const (
+	AIBudgetPeriodDaily   = "daily"
+	AIBudgetPeriodMonthly = "monthly"
+)
+
+// AI 预算状态
+const (
+	AIBudgetStateOK         = "ok"
+	AIBudgetStateWarning    = "warning"    // 用量达到预警比例
+	AIBudgetStateExceeded   = "exceeded"   // 超出限额，新请求被拒绝
+	AIBudgetStateOverridden = "overridden" // 超出限额但管理员已临时放行
+)
+
+// AIBudget AI token 与费用预算，TokenLimit 与 CostLimit 为 0 表示该项不限制
+type AIBudget struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	Name        string    `json:"name" gorm:"size:100"`
+	Scope       string    `json:"scope" gorm:"size:20;not null;index:idx_ai_budget_scope"`
+	ScopeID     uint      `json:"scope_id" gorm:"not null;index:idx_ai_budget_scope"`
+	Period      string    `json:"period" gorm:"size:20;not null"`
+	TokenLimit  int64     `json:"token_limit"`
+	CostLimit   float64   `json:"cost_limit"`   // 按模型单价计算的费用上限
+	WarnPercent int       `json:"warn_percent"` // 用量达到该百分比时提示，默认 80
+	Enabled     bool      `json:"enabled"`
+	CreatedBy   uint      `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+
+	Overrides []AIBudgetOverride `json:"overrides,omitempty" gorm:"foreignKey:BudgetID"`
+}
+
+// AIBudgetOverride 管理员对预算的临时放行：追加额度或在有效期内不限制
+type AIBudgetOverride struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	BudgetID    uint      `json:"budget_id" gorm:"not null;index"`
+	UserID      uint      `json:"user_id" gorm:"index"` // 0 表示对预算范围内所有用户生效
+	ExtraTokens int64     `json:"extra_tokens"`
+	ExtraCost   float64   `json:"extra_cost"`
+	Unlimited   bool      `json:"unlimited"`
+	Reason      string    `json:"reason" gorm:"size:500"`
+	ExpiresAt   time.Time `json:"expires_at" gorm:"index"`
+	CreatedBy   uint      `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func (AIBudget) TableName() string {
+	return "ai_budgets"
+}
+
+func (AIBudgetOverride) TableName() string {
+	return "ai_budget_overrides"
+}
+
+// AIBudgetRequest 创建/更新预算请求
+type AIBudgetRequest struct {
+	Name        string  `json:"name"`
+	Scope       string  `json:"scope" binding:"required,oneof=user group config"`
+	ScopeID     uint    `json:"scope_id" binding:"required"`
+	Period      string  `json:"period" binding:"required,oneof=daily monthly"`
+	TokenLimit  int64   `json:"token_limit" binding:"min=0"`
+	CostLimit   float64 `json:"cost_limit" binding:"min=0"`
+	WarnPercent int     `json:"warn_percent" binding:"min=0,max=100"`
+	Enabled     *bool   `json:"enabled"`
+}
+
+// AIBudgetOverrideRequest 创建预算放行请求
+type AIBudgetOverrideRequest struct {
+	UserID      uint    `json:"user_id"`
+	ExtraTokens int64   `json:"extra_tokens" binding:"min=0"`
+	ExtraCost   float64 `json:"extra_cost" binding:"min=0"`
+	Unlimited   bool    `json:"unlimited"`
+	Reason      string  `json:"reason" binding:"required"`
+	// DurationHours 放行有效期（小时），默认到当前预算周期结束
+	DurationHours int `json:"duration_hours" binding:"min=0"`
+}
@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// AI 助手变更类工具调用的确认状态
+const (
+	AIToolConfirmationPending  = "pending"  // 等待用户确认
+	AIToolConfirmationRunning  = "running"  // 已确认，正在执行
+	AIToolConfirmationRejected = "rejected" // 用户拒绝
+	AIToolConfirmationExecuted = "executed" // 已确认并执行成功
+	AIToolConfirmationFailed   = "failed"   // 已确认但执行失败
+	AIToolConfirmationExpired  = "expired"  // 超时未确认
+)
+
+// AIToolConfirmation AI 助手发起的变更类工具调用
+// 模型请求调用变更类工具时不直接执行，而是记录待确认的调用，
+// 由发起对话的用户在对话中明确确认后，按记录的参数以该用户的权限执行一次
+type AIToolConfirmation struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	ConversationID uint       `json:"conversation_id" gorm:"index"` // 0 表示来自集群操作接口
+	ConfigID       uint       `json:"config_id"`                    // 对话使用的助手配置，用于解析 MCP 工具
+	UserID         uint       `json:"user_id" gorm:"not null;index"`
+	Username       string     `json:"username" gorm:"size:100"`
+	ToolName       string     `json:"tool_name" gorm:"size:100;not null"`
+	ToolCallID     string     `json:"tool_call_id" gorm:"size:100"`
+	Arguments      string     `json:"arguments" gorm:"type:text"` // JSON 格式的调用参数
+	Summary        string     `json:"summary" gorm:"type:text"`   // 展示给用户的操作说明
+	Status         string     `json:"status" gorm:"size:20;not null;index"`
+	Result         string     `json:"result,omitempty" gorm:"type:text"`
+	Error          string     `json:"error,omitempty" gorm:"type:text"`
+	ExpiresAt      time.Time  `json:"expires_at"`
+	DecidedAt      *time.Time `json:"decided_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (AIToolConfirmation) TableName() string {
+	return "ai_tool_confirmations"
+}
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// APITokenPrefix 个人 API 令牌前缀，用于与 JWT 区分
+const APITokenPrefix = "aim_"
+
+// APIToken 用户个人 API 令牌
+// 供外部代理（IDE、其他助手）以该用户身份访问平台 MCP 端点，权限与该用户一致；
+// 数据库仅保存令牌的 SHA-256 摘要，明文只在创建时返回一次
+type APIToken struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	UserID     uint       `json:"user_id" gorm:"not null;index"`
+	Name       string     `json:"name" gorm:"size:100;not null"`
+	TokenHash  string     `json:"-" gorm:"size:64;uniqueIndex;not null"`
+	Hint       string     `json:"hint" gorm:"size:20"` // 令牌前几位，便于用户辨认
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	LastUsedIP string     `json:"last_used_ip,omitempty" gorm:"size:45"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (APIToken) TableName() string {
+	return "api_tokens"
+}
+
+// Active 令牌未吊销且未过期
+func (t *APIToken) Active(now time.Time) bool {
+	return t.RevokedAt == nil && (t.ExpiresAt == nil || now.Before(*t.ExpiresAt))
+}
+
+// CreateAPITokenRequest 创建 API 令牌请求
+type CreateAPITokenRequest struct {
+	Name          string `json:"name" binding:"required,max=100"`
+	ExpiresInDays int    `json:"expires_in_days" binding:"omitempty,min=1,max=365"` // 为空时默认 90 天
+}
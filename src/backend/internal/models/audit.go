@@ -61,6 +61,7 @@ const (
 	AuditActionSaltKeyAccept    AuditAction = "salt_key_accept"
 	AuditActionSaltKeyReject    AuditAction = "salt_key_reject"
 	AuditActionSaltKeyDelete    AuditAction = "salt_key_delete"
+	AuditActionSaltDriftDetect  AuditAction = "salt_drift_detected"
 
 	// SLURM 特定动作
 	AuditActionJobSubmit       AuditAction = "job_submit"
@@ -105,6 +106,11 @@ const (
 	AuditActionConfigUpdate  AuditAction = "config_update"
 	AuditActionBackupCreate  AuditAction = "backup_create"
 	AuditActionBackupRestore AuditAction = "backup_restore"
+
+	// 安全特定动作
+	AuditActionTerminalSession AuditAction = "terminal_session"
+	AuditActionSSHCertSign     AuditAction = "ssh_cert_sign"
+	AuditActionSSHCARotate     AuditAction = "ssh_ca_rotate"
 )
 
 // AuditStatus 审计状态
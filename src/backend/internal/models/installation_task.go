@@ -20,6 +20,8 @@ type InstallationTask struct {
 	EndTime     *time.Time `json:"endTime,omitempty"`
 	Duration    *int64    `json:"duration,omitempty"` // Duration in seconds
 	Config      string    `json:"config" gorm:"type:text"` // JSON configuration
+	Credentials string    `json:"-" gorm:"type:text"`      // 加密存储的完整请求（含主机凭据），用于重试失败主机
+	UserID      uint      `json:"userId" gorm:"index"`     // 创建者，仅创建者或管理员可以重试
 	CreatedAt   time.Time `json:"createdAt"`
 	UpdatedAt   time.Time `json:"updatedAt"`
 	
@@ -34,7 +36,10 @@ type InstallationHostResult struct {
 	Host     string `json:"host" gorm:"not null"`
 	Port     int    `json:"port"`
 	User     string `json:"user"`
-	Status   string `json:"status" gorm:"not null"` // success, failed
+	Status   string `json:"status" gorm:"not null"` // success, failed, partial
+	Stage    string `json:"stage" gorm:"size:32"`   // 失败所在阶段（connect, detect_os, install, accept_key, ping, categraf），成功为 done
+	MinionID string `json:"minionId" gorm:"size:255"`
+	Attempts int    `json:"attempts" gorm:"default:1"` // 执行次数（含重试）
 	Error    string `json:"error,omitempty"`
 	Duration int64  `json:"duration"` // Duration in milliseconds
 	Output   string `json:"output" gorm:"type:text"`
@@ -0,0 +1,182 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 主机来源（各子系统在统一清单中的关联类型）
+const (
+	InventorySourceManual       = "manual"        // 手工登记
+	InventorySourceImport       = "import"        // 主机文件导入
+	InventorySourceAnsible      = "ansible"       // Ansible 项目主机（models.Host）
+	InventorySourceSlurm        = "slurm"         // Slurm 节点（models.SlurmNode）
+	InventorySourceSalt         = "salt"          // Salt Minion（models.SaltMasterMinion）
+	InventorySourceHostTemplate = "host_template" // 主机模板（models.HostTemplate）
+	InventorySourceBatchInstall = "batch_install" // 批量安装结果
+	InventorySourceExternal     = "external"      // 外部清单来源（NetBox、Prometheus、DHCP、动态清单脚本）
+)
+
+// InventoryLabels 主机标签
+type InventoryLabels map[string]string
+
+func (l InventoryLabels) Value() (driver.Value, error) {
+	if l == nil {
+		return "{}", nil
+	}
+	return json.Marshal(l)
+}
+
+func (l *InventoryLabels) Scan(value interface{}) error {
+	if value == nil {
+		*l = nil
+		return nil
+	}
+
+	var bytes []byte
+	switch v := value.(type) {
+	case []byte:
+		bytes = v
+	case string:
+		bytes = []byte(v)
+	default:
+		return fmt.Errorf("failed to scan InventoryLabels: unsupported type %T", value)
+	}
+
+	return json.Unmarshal(bytes, l)
+}
+
+// InventoryHost 统一主机清单（CMDB），每台物理/虚拟机一条记录
+// Ansible 主机、Slurm 节点、Salt Minion、主机模板通过 InventoryHostLink 关联到同一记录
+type InventoryHost struct {
+	ID            uint            `json:"id" gorm:"primaryKey"`
+	Hostname      string          `json:"hostname" gorm:"size:255;index"` // 小写存储
+	PrimaryIP     string          `json:"primary_ip" gorm:"size:45;index"`
+	IPs           StringArray     `json:"ips" gorm:"type:json"`
+	Port          int             `json:"port" gorm:"default:22"`
+	CredentialRef string          `json:"credential_ref,omitempty" gorm:"size:255"` // 凭据引用（keyvault:<name>、slurm_node:<id>、host_template:<id>），不保存明文
+	Labels        InventoryLabels `json:"labels" gorm:"type:json"`
+	Rack          string          `json:"rack,omitempty" gorm:"size:100;index"`
+	Location      string          `json:"location,omitempty" gorm:"size:255"`
+	Owner         string          `json:"owner,omitempty" gorm:"size:100;index"`
+	Description   string          `json:"description,omitempty" gorm:"type:text"`
+	LastSeenAt    *time.Time      `json:"last_seen_at,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt  `json:"-" gorm:"index"`
+
+	Links []InventoryHostLink `json:"links,omitempty" gorm:"foreignKey:HostID"`
+}
+
+// TableName 指定表名
+func (InventoryHost) TableName() string {
+	return "inventory_hosts"
+}
+
+// InventoryHostAddress 主机地址索引，保证同一 IP 只归属一台主机（导入去重依据）
+type InventoryHostAddress struct {
+	ID      uint   `json:"id" gorm:"primaryKey"`
+	HostID  uint   `json:"host_id" gorm:"not null;index"`
+	Address string `json:"address" gorm:"size:45;not null;uniqueIndex"`
+}
+
+// TableName 指定表名
+func (InventoryHostAddress) TableName() string {
+	return "inventory_host_addresses"
+}
+
+// InventoryHostLink 子系统记录与清单主机的关联
+type InventoryHostLink struct {
+	ID         uint            `json:"id" gorm:"primaryKey"`
+	HostID     uint            `json:"host_id" gorm:"not null;index"`
+	Source     string          `json:"source" gorm:"size:32;not null;uniqueIndex:idx_inventory_link_source"`
+	SourceID   string          `json:"source_id" gorm:"size:255;not null;uniqueIndex:idx_inventory_link_source"` // 子系统内的记录标识
+	Scope      string          `json:"scope,omitempty" gorm:"size:255"`                                          // 所属范围（项目、集群、模板、Master）
+	Name       string          `json:"name,omitempty" gorm:"size:255"`                                           // 子系统内的名称（节点名、Minion ID 等）
+	Attributes InventoryLabels `json:"attributes,omitempty" gorm:"type:json"`
+	LastSeenAt time.Time       `json:"last_seen_at"`
+	CreatedAt  time.Time       `json:"created_at"`
+	UpdatedAt  time.Time       `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (InventoryHostLink) TableName() string {
+	return "inventory_host_links"
+}
+
+// InventoryObservation 某个来源观察到的主机信息，用于合并进清单
+type InventoryObservation struct {
+	Hostname      string
+	IPs           []string
+	Port          int
+	CredentialRef string
+	Labels        map[string]string
+	Rack          string
+	Location      string
+	Owner         string
+
+	// Overwrite 为 true 时观察结果中的非空字段和标签覆盖已有值（外部权威来源）
+	Overwrite bool
+
+	Source     string
+	SourceID   string
+	Scope      string
+	Name       string
+	Attributes map[string]string
+}
+
+// InventoryHostRequest 创建/更新清单主机请求
+type InventoryHostRequest struct {
+	Hostname    string            `json:"hostname"`
+	IPs         []string          `json:"ips"`
+	Port        int               `json:"port"`
+	Labels      map[string]string `json:"labels"`
+	Rack        string            `json:"rack"`
+	Location    string            `json:"location"`
+	Owner       string            `json:"owner"`
+	Description string            `json:"description"`
+}
+
+// InventoryImportRequest 主机文件导入请求（格式同 /api/saltstack/hosts/parse）
+type InventoryImportRequest struct {
+	Content  string            `json:"content" binding:"required"`
+	Format   string            `json:"format"` // csv, json, yaml, ini，为空时自动识别
+	Labels   map[string]string `json:"labels"`
+	Rack     string            `json:"rack"`
+	Location string            `json:"location"`
+	Owner    string            `json:"owner"`
+	// StoreCredentials 为 true 时将文件中的 SSH 凭据加密存入 KeyVault 并记录引用
+	StoreCredentials bool `json:"store_credentials"`
+}
+
+// InventoryImportResult 导入结果
+type InventoryImportResult struct {
+	Created int             `json:"created"`
+	Merged  int             `json:"merged"`
+	Failed  int             `json:"failed"`
+	Errors  []string        `json:"errors,omitempty"`
+	Hosts   []InventoryHost `json:"hosts"`
+}
+
+// InventorySyncResult 从各子系统回填清单的结果
+type InventorySyncResult struct {
+	Sources map[string]int `json:"sources"` // 各来源同步的记录数
+	Created int            `json:"created"`
+	Merged  int            `json:"merged"`
+	Pruned  int            `json:"pruned"` // 来源记录已不存在而移除的关联
+	Errors  []string       `json:"errors,omitempty"`
+}
+
+// InventoryHostOverview 某台主机在所有子系统中的信息汇总
+type InventoryHostOverview struct {
+	Host           InventoryHost            `json:"host"`
+	AnsibleHosts   []Host                   `json:"ansible_hosts"`
+	SlurmNodes     []SlurmNode              `json:"slurm_nodes"`
+	SaltMinions    []SaltMasterMinion       `json:"salt_minions"`
+	InstallResults []InstallationHostResult `json:"install_results"`
+	HostKeys       []SSHKnownHost           `json:"host_keys"`
+}
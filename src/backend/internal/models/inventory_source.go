@@ -0,0 +1,202 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/utils"
+	"gorm.io/gorm"
+)
+
+// 外部清单来源类型
+const (
+	InventorySourceTypeNetBox     = "netbox"         // NetBox 兼容 REST API（dcim/devices、virtualization/virtual-machines）
+	InventorySourceTypePrometheus = "prometheus"     // Prometheus /api/v1/targets 或 file_sd JSON（Nightingale 等导出）
+	InventorySourceTypeDHCP       = "dhcp"           // ISC dhcpd.leases 或 dnsmasq 租约文件
+	InventorySourceTypeAnsible    = "ansible_script" // Ansible 动态清单脚本（--list 输出）
+)
+
+// 变更集状态
+const (
+	InventoryChangesetPending    = "pending"    // 待审批
+	InventoryChangesetApplied    = "applied"    // 已批准并应用
+	InventoryChangesetRejected   = "rejected"   // 已拒绝
+	InventoryChangesetSuperseded = "superseded" // 被同一来源更新的变更集取代
+	InventoryChangesetFailed     = "failed"     // 应用过程中出错（部分变更可能已生效）
+)
+
+// 变更动作
+const (
+	InventoryChangeAdd    = "add"
+	InventoryChangeUpdate = "update"
+	InventoryChangeRemove = "remove"
+)
+
+// InventorySourceConfig 外部来源连接配置（令牌单独加密保存在 InventorySource.Token）
+type InventorySourceConfig struct {
+	URL                string `json:"url,omitempty"`                  // REST API / 文件下载地址
+	Path               string `json:"path,omitempty"`                 // 本地文件或清单脚本路径
+	Format             string `json:"format,omitempty"`               // 来源内格式：dhcp 为 isc/dnsmasq，prometheus 为 api/file_sd，为空时自动识别
+	Query              string `json:"query,omitempty"`                // 附加查询参数（如 NetBox 的 site=dc1&status=active）
+	InsecureSkipVerify bool   `json:"insecure_skip_verify,omitempty"` // 跳过 TLS 证书校验
+	TimeoutSeconds     int    `json:"timeout_seconds,omitempty"`
+}
+
+func (c InventorySourceConfig) Value() (driver.Value, error) {
+	return json.Marshal(c)
+}
+
+func (c *InventorySourceConfig) Scan(value interface{}) error {
+	if value == nil {
+		*c = InventorySourceConfig{}
+		return nil
+	}
+
+	var bytes []byte
+	switch v := value.(type) {
+	case []byte:
+		bytes = v
+	case string:
+		bytes = []byte(v)
+	default:
+		return fmt.Errorf("failed to scan InventorySourceConfig: unsupported type %T", value)
+	}
+
+	return json.Unmarshal(bytes, c)
+}
+
+// InventorySource 外部主机清单来源，周期同步生成待审批的变更集
+type InventorySource struct {
+	ID              uint                  `json:"id" gorm:"primaryKey"`
+	Name            string                `json:"name" gorm:"size:100;not null;uniqueIndex"`
+	Type            string                `json:"type" gorm:"size:32;not null"`
+	Description     string                `json:"description" gorm:"type:text"`
+	Config          InventorySourceConfig `json:"config" gorm:"type:json"`
+	Token           string                `json:"-" gorm:"size:1000"` // API 令牌（加密存储）
+	HasToken        bool                  `json:"has_token" gorm:"-"`
+	Labels          InventoryLabels       `json:"labels" gorm:"type:json"`            // 附加到该来源所有主机的标签
+	IntervalMinutes int                   `json:"interval_minutes" gorm:"default:60"` // 0 表示仅手动同步
+	Enabled         bool                  `json:"enabled" gorm:"default:true"`
+	LastSyncAt      *time.Time            `json:"last_sync_at,omitempty"`
+	NextSyncAt      *time.Time            `json:"next_sync_at,omitempty" gorm:"index"`
+	LastStatus      string                `json:"last_status,omitempty" gorm:"size:32"` // success, no_changes, failed
+	LastError       string                `json:"last_error,omitempty" gorm:"type:text"`
+	HostCount       int                   `json:"host_count"` // 最近一次同步获取的主机数
+	CreatedBy       string                `json:"created_by" gorm:"size:100"`
+	CreatedAt       time.Time             `json:"created_at"`
+	UpdatedAt       time.Time             `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt        `json:"-" gorm:"index"`
+}
+
+// TableName 指定表名
+func (InventorySource) TableName() string {
+	return "inventory_sources"
+}
+
+// BeforeSave 保存前加密令牌
+func (s *InventorySource) BeforeSave(tx *gorm.DB) error {
+	if s.Token != "" {
+		s.Token = utils.EncryptSensitiveField(s.Token)
+	}
+	return nil
+}
+
+// AfterFind 查询后解密令牌
+func (s *InventorySource) AfterFind(tx *gorm.DB) error {
+	if s.Token != "" {
+		s.Token = utils.DecryptSensitiveField(s.Token)
+		s.HasToken = true
+	}
+	return nil
+}
+
+// InventoryFieldChange 单个字段的变化
+type InventoryFieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// InventoryChange 变更集中的一条主机变更
+type InventoryChange struct {
+	Action   string                 `json:"action"`            // add, update, remove
+	Key      string                 `json:"key"`               // 主机在来源中的标识
+	HostID   uint                   `json:"host_id,omitempty"` // 对应的清单主机（add 时为按 IP/主机名匹配到的已有主机）
+	Hostname string                 `json:"hostname,omitempty"`
+	IPs      []string               `json:"ips,omitempty"`
+	Labels   map[string]string      `json:"labels,omitempty"`
+	Rack     string                 `json:"rack,omitempty"`
+	Location string                 `json:"location,omitempty"`
+	Owner    string                 `json:"owner,omitempty"`
+	Diff     []InventoryFieldChange `json:"diff,omitempty"`
+}
+
+// InventoryChangeList 变更列表
+type InventoryChangeList []InventoryChange
+
+func (l InventoryChangeList) Value() (driver.Value, error) {
+	return json.Marshal(l)
+}
+
+func (l *InventoryChangeList) Scan(value interface{}) error {
+	if value == nil {
+		*l = nil
+		return nil
+	}
+
+	var bytes []byte
+	switch v := value.(type) {
+	case []byte:
+		bytes = v
+	case string:
+		bytes = []byte(v)
+	default:
+		return fmt.Errorf("failed to scan InventoryChangeList: unsupported type %T", value)
+	}
+
+	return json.Unmarshal(bytes, l)
+}
+
+// InventoryChangeset 一次同步产生的增删改变更集，管理员批准后才写入清单
+type InventoryChangeset struct {
+	ID            uint                `json:"id" gorm:"primaryKey"`
+	SourceID      uint                `json:"source_id" gorm:"not null;index"`
+	SourceName    string              `json:"source_name" gorm:"size:100"`
+	Status        string              `json:"status" gorm:"size:20;not null;index"`
+	Trigger       string              `json:"trigger" gorm:"size:20"` // schedule, manual
+	Adds          int                 `json:"adds"`
+	Updates       int                 `json:"updates"`
+	Removes       int                 `json:"removes"`
+	Changes       InventoryChangeList `json:"changes,omitempty" gorm:"type:json"`
+	ReviewedBy    string              `json:"reviewed_by,omitempty" gorm:"size:100"`
+	ReviewedAt    *time.Time          `json:"reviewed_at,omitempty"`
+	ReviewComment string              `json:"review_comment,omitempty" gorm:"type:text"`
+	AppliedAt     *time.Time          `json:"applied_at,omitempty"`
+	Error         string              `json:"error,omitempty" gorm:"type:text"`
+	CreatedAt     time.Time           `json:"created_at"`
+	UpdatedAt     time.Time           `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (InventoryChangeset) TableName() string {
+	return "inventory_changesets"
+}
+
+// InventorySourceRequest 创建/更新外部来源请求
+type InventorySourceRequest struct {
+	Name            string                `json:"name" binding:"required"`
+	Type            string                `json:"type" binding:"required,oneof=netbox prometheus dhcp ansible_script"`
+	Description     string                `json:"description"`
+	Config          InventorySourceConfig `json:"config"`
+	Token           string                `json:"token"` // 更新时为空表示保持不变
+	Labels          map[string]string     `json:"labels"`
+	IntervalMinutes *int                  `json:"interval_minutes"`
+	Enabled         *bool                 `json:"enabled"`
+}
+
+// InventoryChangesetReview 审批变更集请求
+type InventoryChangesetReview struct {
+	Comment string `json:"comment"`
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// 作业失败原因分类
+const (
+	JobFailureOOM         = "oom"
+	JobFailureTimeout     = "timeout"
+	JobFailureNode        = "node_failure"
+	JobFailureApplication = "application_error"
+	JobFailureConfig      = "configuration"
+	JobFailureEnvironment = "environment"
+	JobFailureUnknown     = "unknown"
+)
+
+// JobDiagnosis AI 对失败作业的诊断结论，保存在作业记录上
+type JobDiagnosis struct {
+	ProbableCause string    `json:"probable_cause"`
+	Category      string    `json:"category"`
+	Confidence    string    `json:"confidence"` // high, medium, low
+	Evidence      []string  `json:"evidence"`
+	SuggestedFix  []string  `json:"suggested_fix"`
+	Sources       []string  `json:"sources"` // 成功采集的证据类型，如 sacct、stderr、node_state、metrics、slurmd_log
+	ConfigID      uint      `json:"config_id"`
+	Model         string    `json:"model"`
+	TokensUsed    int       `json:"tokens_used"`
+	CreatedBy     uint      `json:"created_by"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// Value 实现 driver.Valuer 接口
+func (d JobDiagnosis) Value() (driver.Value, error) {
+	data, err := json.Marshal(d)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan 实现 sql.Scanner 接口
+func (d *JobDiagnosis) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into JobDiagnosis", value)
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, d)
+}
+
+// JobDiagnoseRequest 诊断作业请求
+type JobDiagnoseRequest struct {
+	// ConfigID 使用的 AI 配置，默认使用默认配置
+	ConfigID uint `json:"config_id"`
+	// StderrLines 采集的 stderr 末尾行数，默认 100，最多 1000
+	StderrLines int `json:"stderr_lines"`
+}
@@ -0,0 +1,227 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+)
+
+// 知识源类型
+const (
+	KnowledgeSourceObjectStorage = "object_storage" // 对象存储中的 Markdown/PDF 文档
+	KnowledgeSourceGitea         = "gitea"          // Gitea 仓库中的文档与运维手册
+	KnowledgeSourceJobLogs       = "job_logs"       // 作业输出日志（按作业所属用户隔离）
+)
+
+// 知识源同步状态
+const (
+	KnowledgeSyncIdle    = "idle"
+	KnowledgeSyncRunning = "syncing"
+	KnowledgeSyncFailed  = "failed"
+)
+
+// KnowledgeSource 检索增强（RAG）的知识源
+// 每个知识源使用一个 embedding 类型的 AI 配置向量化，同一知识源内的向量始终处于同一向量空间
+type KnowledgeSource struct {
+	ID                uint                    `json:"id" gorm:"primaryKey"`
+	Name              string                  `json:"name" gorm:"not null;size:100;uniqueIndex"`
+	Type              string                  `json:"type" gorm:"not null;size:30"`
+	Settings          KnowledgeSourceSettings `json:"settings" gorm:"type:text"`
+	EmbeddingConfigID uint                    `json:"embedding_config_id" gorm:"not null"`
+	Enabled           bool                    `json:"enabled" gorm:"default:true"`
+	Status            string                  `json:"status" gorm:"size:20;default:'idle'"`
+	LastSyncAt        *time.Time              `json:"last_sync_at,omitempty"`
+	LastError         string                  `json:"last_error,omitempty" gorm:"type:text"`
+	DocumentCount     int                     `json:"document_count"`
+	ChunkCount        int                     `json:"chunk_count"`
+	CreatedBy         uint                    `json:"created_by"`
+	CreatedAt         time.Time               `json:"created_at"`
+	UpdatedAt         time.Time               `json:"updated_at"`
+}
+
+// TableName 设置表名
+func (KnowledgeSource) TableName() string {
+	return "knowledge_sources"
+}
+
+// KnowledgeSourceSettings 知识源的拉取参数，按类型使用其中的字段
+type KnowledgeSourceSettings struct {
+	// object_storage：对象存储配置ID与存储桶
+	StorageConfigID uint   `json:"storage_config_id,omitempty"`
+	Bucket          string `json:"bucket,omitempty"`
+	// gitea：仓库所有者、仓库名与分支（默认仓库默认分支）
+	Owner string `json:"owner,omitempty"`
+	Repo  string `json:"repo,omitempty"`
+	Ref   string `json:"ref,omitempty"`
+	// Prefix 对象前缀或仓库内目录
+	Prefix string `json:"prefix,omitempty"`
+	// Extensions 纳入的文件扩展名，默认 .md、.markdown、.txt、.pdf
+	Extensions []string `json:"extensions,omitempty"`
+	// job_logs：纳入的作业状态（默认 FAILED）及最近天数（默认 7）
+	JobStatuses []string `json:"job_statuses,omitempty"`
+	SinceDays   int      `json:"since_days,omitempty"`
+	// MaxDocuments 单次同步的文档数上限，默认 500
+	MaxDocuments int `json:"max_documents,omitempty"`
+}
+
+// Value 实现 driver.Valuer 接口
+func (s KnowledgeSourceSettings) Value() (driver.Value, error) {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan 实现 sql.Scanner 接口
+func (s *KnowledgeSourceSettings) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into KnowledgeSourceSettings", value)
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, s)
+}
+
+// KnowledgeDocument 知识源中的一篇文档
+type KnowledgeDocument struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	SourceID    uint      `json:"source_id" gorm:"not null;uniqueIndex:idx_knowledge_doc_path"`
+	Path        string    `json:"path" gorm:"not null;size:500;uniqueIndex:idx_knowledge_doc_path"`
+	Title       string    `json:"title" gorm:"size:255"`
+	URL         string    `json:"url,omitempty" gorm:"size:1000"`
+	OwnerID     uint      `json:"owner_id" gorm:"index"` // 0 表示所有用户可检索
+	ContentHash string    `json:"-" gorm:"size:64"`
+	ChunkCount  int       `json:"chunk_count"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// TableName 设置表名
+func (KnowledgeDocument) TableName() string {
+	return "knowledge_documents"
+}
+
+// KnowledgeChunk 文档切分后的片段及其向量
+type KnowledgeChunk struct {
+	ID         uint            `json:"id" gorm:"primaryKey"`
+	SourceID   uint            `json:"source_id" gorm:"not null;index"`
+	DocumentID uint            `json:"document_id" gorm:"not null;index"`
+	OwnerID    uint            `json:"owner_id" gorm:"index"`
+	Ordinal    int             `json:"ordinal"`
+	Heading    string          `json:"heading" gorm:"size:500"`
+	Content    string          `json:"content" gorm:"type:text"`
+	Dimensions int             `json:"dimensions"`
+	Embedding  EmbeddingVector `json:"-" gorm:"type:text"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+// TableName 设置表名
+func (KnowledgeChunk) TableName() string {
+	return "knowledge_chunks"
+}
+
+// EmbeddingVector 向量，以 little-endian float32 的 base64 文本存储，兼容不支持 pgvector 的数据库
+type EmbeddingVector []float32
+
+// Value 实现 driver.Valuer 接口
+func (v EmbeddingVector) Value() (driver.Value, error) {
+	buf := make([]byte, 4*len(v))
+	for i, f := range v {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(f))
+	}
+	return base64.StdEncoding.EncodeToString(buf), nil
+}
+
+// Scan 实现 sql.Scanner 接口
+func (v *EmbeddingVector) Scan(value interface{}) error {
+	var text string
+	switch t := value.(type) {
+	case nil:
+		*v = nil
+		return nil
+	case []byte:
+		text = string(t)
+	case string:
+		text = t
+	default:
+		return fmt.Errorf("cannot scan %T into EmbeddingVector", value)
+	}
+	buf, err := base64.StdEncoding.DecodeString(text)
+	if err != nil || len(buf)%4 != 0 {
+		return fmt.Errorf("invalid embedding vector")
+	}
+	vec := make([]float32, len(buf)/4)
+	for i := range vec {
+		vec[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[4*i:]))
+	}
+	*v = vec
+	return nil
+}
+
+// RAGConfig 机器人的检索增强配置，非空且包含知识源时每次对话前检索并注入引用
+type RAGConfig struct {
+	SourceIDs []uint `json:"source_ids"`
+	// TopK 注入的片段数，默认 4
+	TopK int `json:"top_k,omitempty"`
+	// MinScore 最低余弦相似度，默认 0.3
+	MinScore float64 `json:"min_score,omitempty"`
+}
+
+// Value 实现 driver.Valuer 接口
+func (c RAGConfig) Value() (driver.Value, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan 实现 sql.Scanner 接口
+func (c *RAGConfig) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into RAGConfig", value)
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, c)
+}
+
+// CreateKnowledgeSourceRequest 创建或更新知识源请求
+type CreateKnowledgeSourceRequest struct {
+	Name              string                  `json:"name" binding:"required"`
+	Type              string                  `json:"type" binding:"required"`
+	Settings          KnowledgeSourceSettings `json:"settings"`
+	EmbeddingConfigID uint                    `json:"embedding_config_id" binding:"required"`
+	Enabled           *bool                   `json:"enabled"`
+}
+
+// KnowledgeSearchRequest 知识库检索请求
+type KnowledgeSearchRequest struct {
+	Query     string  `json:"query" binding:"required"`
+	SourceIDs []uint  `json:"source_ids"`
+	TopK      int     `json:"top_k"`
+	MinScore  float64 `json:"min_score"`
+}
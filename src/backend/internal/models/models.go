@@ -946,8 +946,10 @@ type Job struct {
 	TimeLimit  string     `json:"time_limit" gorm:"size:50"` // e.g., "01:00:00"
 	StdOut     string     `json:"std_out" gorm:"size:500"`
 	StdErr     string     `json:"std_err" gorm:"size:500"`
-	CreatedAt  time.Time  `json:"created_at"`
-	UpdatedAt  time.Time  `json:"updated_at"`
+	// Diagnosis 最近一次 AI 失败诊断
+	Diagnosis *JobDiagnosis `json:"diagnosis,omitempty" gorm:"type:text"`
+	CreatedAt time.Time     `json:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at"`
 
 	// 关联关系
 	User    User    `json:"user,omitempty" gorm:"foreignKey:UserID"`
@@ -966,17 +968,18 @@ type JobStatus struct {
 
 // Cluster 集群表
 type Cluster struct {
-	ID          string    `json:"id" gorm:"primaryKey;size:100"`
-	Name        string    `json:"name" gorm:"not null;size:255"`
-	Description string    `json:"description" gorm:"type:text"`
-	Host        string    `json:"host" gorm:"not null;size:255"`
-	Port        int       `json:"port" gorm:"default:22"`
-	Username    string    `json:"username" gorm:"size:100"`                        // SSH用户名
-	Password    string    `json:"password,omitempty" gorm:"size:255"`              // SSH密码
-	Status      string    `json:"status" gorm:"not null;default:'active';size:20"` // active, inactive, maintenance
-	Config      string    `json:"config" gorm:"type:json"`                         // 集群配置JSON
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID          string       `json:"id" gorm:"primaryKey;size:100"`
+	Name        string       `json:"name" gorm:"not null;size:255"`
+	Description string       `json:"description" gorm:"type:text"`
+	Host        string       `json:"host" gorm:"not null;size:255"`
+	Port        int          `json:"port" gorm:"default:22"`
+	Username    string       `json:"username" gorm:"size:100"`                        // SSH用户名
+	Password    string       `json:"password,omitempty" gorm:"size:255"`              // SSH密码
+	JumpHosts   SSHJumpChain `json:"jump_hosts,omitempty" gorm:"type:json"`           // SSH跳板机链
+	Status      string       `json:"status" gorm:"not null;default:'active';size:20"` // active, inactive, maintenance
+	Config      string       `json:"config" gorm:"type:json"`                         // 集群配置JSON
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
 
 	// 关联关系
 	Jobs []Job `json:"jobs,omitempty" gorm:"foreignKey:ClusterID"`
@@ -1239,12 +1242,46 @@ type MinionGroup struct {
 	CreatedAt   time.Time      `json:"created_at"`
 	UpdatedAt   time.Time      `json:"updated_at"`
 	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// 动态分组：按 grains 规则自动维护成员（定时刷新，minion 启动时增量刷新）
+	Dynamic         bool             `json:"dynamic" gorm:"default:false;index"`
+	RuleJSON        string           `json:"-" gorm:"column:rule;type:text"`
+	SlurmPartition  string           `json:"slurm_partition,omitempty" gorm:"size:100"` // 非空时作为同名 Slurm 分区的节点来源
+	MemberCount     int              `json:"member_count"`
+	LastEvaluatedAt *time.Time       `json:"last_evaluated_at,omitempty"`
+	Rule            *MinionGroupRule `json:"rule,omitempty" gorm:"-"`
 }
 
 func (MinionGroup) TableName() string {
 	return "minion_groups"
 }
 
+// AfterFind 解析动态分组规则
+func (g *MinionGroup) AfterFind(tx *gorm.DB) error {
+	if g.RuleJSON != "" {
+		var rule MinionGroupRule
+		if err := json.Unmarshal([]byte(g.RuleJSON), &rule); err == nil {
+			g.Rule = &rule
+		}
+	}
+	return nil
+}
+
+// MinionGroupRule 动态分组规则
+type MinionGroupRule struct {
+	Match      string                 `json:"match"` // all（默认，全部满足）或 any（任一满足）
+	Conditions []MinionGroupCondition `json:"conditions"`
+}
+
+// MinionGroupCondition 动态分组条件
+// Grain 为 grains 路径（冒号分隔，如 os、osmajorrelease、ai_infra:rack），
+// 另外提供派生事实：gpu_model、gpu_count、ib_present、datacenter、rack
+type MinionGroupCondition struct {
+	Grain string      `json:"grain"`
+	Op    string      `json:"op"` // eq, ne, in, contains, glob, regex, exists, not_exists, gt, gte, lt, lte
+	Value interface{} `json:"value,omitempty"`
+}
+
 // MinionGroupMembership Minion 分组成员关系表
 type MinionGroupMembership struct {
 	ID        uint      `json:"id" gorm:"primaryKey"`
@@ -18,6 +18,12 @@ const (
 	ApprovalStatusExpired  PermissionApprovalStatus = "expired"  // 已过期
 )
 
+// 申请类型：权限申请复用同一套审批流程
+const (
+	PermissionRequestTypePermission  = "permission"   // 模块权限申请
+	PermissionRequestTypeSaltCommand = "salt_command" // 危险 Salt 命令执行申请
+)
+
 // PermissionModuleType 权限模块类型
 type PermissionModuleType string
 
@@ -63,6 +69,9 @@ type PermissionRequest struct {
 	UpdatedAt        time.Time                `json:"updated_at"`
 	DeletedAt        gorm.DeletedAt           `json:"-" gorm:"index"`
 
+	// 申请类型: permission（模块权限）, salt_command（危险命令执行，详情见 SaltCommandApproval）
+	RequestType string `json:"request_type" gorm:"size:32;index;default:'permission'"`
+
 	// 关联关系
 	Requester  User  `json:"requester,omitempty" gorm:"foreignKey:RequesterID"`
 	TargetUser *User `json:"target_user,omitempty" gorm:"foreignKey:TargetUserID"`
@@ -176,6 +185,7 @@ type PermissionRequestQuery struct {
 	SortBy      string `form:"sort_by" binding:"omitempty"`         // 排序字段
 	SortOrder   string `form:"sort_order" binding:"omitempty"`      // 排序方向
 	OnlyPending bool   `form:"only_pending" binding:"omitempty"`    // 只查询待审批
+	RequestType string `form:"request_type" binding:"omitempty"`    // 申请类型筛选
 }
 
 // PermissionRequestListResponse 权限申请列表响应
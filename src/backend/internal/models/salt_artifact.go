@@ -0,0 +1,108 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SaltArtifact 制品库中的文件（存储在对象存储中，按 sha256 内容寻址）
+type SaltArtifact struct {
+	ID          uint           `json:"id" gorm:"primaryKey"`
+	Name        string         `json:"name" gorm:"size:255;not null;index"` // 制品名称，如 munge.key
+	Description string         `json:"description" gorm:"type:text"`
+	FileName    string         `json:"file_name" gorm:"size:255"`                 // 原始文件名
+	Bucket      string         `json:"bucket" gorm:"size:128;not null"`           // 存储桶
+	ObjectKey   string         `json:"object_key" gorm:"size:512;not null"`       // 对象键
+	Size        int64          `json:"size"`                                      // 文件大小（字节）
+	SHA256      string         `json:"sha256" gorm:"column:sha256;size:64;index"` // 内容校验和
+	ContentType string         `json:"content_type" gorm:"size:128"`
+	UploadedBy  string         `json:"uploaded_by" gorm:"size:100"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// TableName 指定表名
+func (SaltArtifact) TableName() string {
+	return "salt_artifacts"
+}
+
+// 制品分发状态
+const (
+	ArtifactDistributionRunning   = "running"   // 分发中
+	ArtifactDistributionCompleted = "completed" // 全部节点校验通过
+	ArtifactDistributionPartial   = "partial"   // 部分节点失败或校验不一致
+	ArtifactDistributionFailed    = "failed"    // 下发失败或全部节点失败
+)
+
+// 单节点分发结果
+const (
+	ArtifactResultOK       = "ok"       // 校验通过
+	ArtifactResultMismatch = "mismatch" // 文件存在但校验和不一致
+	ArtifactResultFailed   = "failed"   // file.managed 执行失败
+	ArtifactResultMissing  = "missing"  // 超时未返回
+)
+
+// SaltArtifactDistribution 一次制品分发（一个 Salt 作业下发到全部目标）
+type SaltArtifactDistribution struct {
+	ID            uint       `json:"id" gorm:"primaryKey"`
+	ArtifactID    uint       `json:"artifact_id" gorm:"index;not null"`
+	Target        string     `json:"target" gorm:"size:1024;not null"`
+	TgtType       string     `json:"tgt_type" gorm:"size:32;default:'glob'"`
+	MasterID      string     `json:"master_id,omitempty" gorm:"size:100"`
+	DestPath      string     `json:"dest_path" gorm:"size:512;not null"` // minion 上的目标路径
+	Mode          string     `json:"mode,omitempty" gorm:"size:8"`
+	FileUser      string     `json:"file_user,omitempty" gorm:"size:64"`
+	FileGroup     string     `json:"file_group,omitempty" gorm:"size:64"`
+	ExpectedHash  string     `json:"expected_hash" gorm:"size:64"`
+	JID           string     `json:"jid,omitempty" gorm:"column:jid;size:64;index"`
+	VerifyJID     string     `json:"verify_jid,omitempty" gorm:"column:verify_jid;size:64"`
+	Status        string     `json:"status" gorm:"size:20;index"`
+	TotalCount    int        `json:"total_count"`
+	SuccessCount  int        `json:"success_count"`
+	MismatchCount int        `json:"mismatch_count"`
+	FailedCount   int        `json:"failed_count"`
+	ErrorMessage  string     `json:"error_message,omitempty" gorm:"type:text"`
+	RequestedBy   string     `json:"requested_by" gorm:"size:100"`
+	FinishedAt    *time.Time `json:"finished_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+
+	Artifact *SaltArtifact                    `json:"artifact,omitempty" gorm:"foreignKey:ArtifactID"`
+	Results  []SaltArtifactDistributionResult `json:"results,omitempty" gorm:"foreignKey:DistributionID"`
+}
+
+// TableName 指定表名
+func (SaltArtifactDistribution) TableName() string {
+	return "salt_artifact_distributions"
+}
+
+// SaltArtifactDistributionResult 分发在单个 minion 上的校验结果
+type SaltArtifactDistributionResult struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	DistributionID uint      `json:"distribution_id" gorm:"index;not null"`
+	MinionID       string    `json:"minion_id" gorm:"size:255;not null"`
+	Status         string    `json:"status" gorm:"size:20;index"`
+	Changed        bool      `json:"changed"` // 本次分发是否修改了文件
+	ActualHash     string    `json:"actual_hash,omitempty" gorm:"size:64"`
+	Message        string    `json:"message,omitempty" gorm:"type:text"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// TableName 指定表名
+func (SaltArtifactDistributionResult) TableName() string {
+	return "salt_artifact_distribution_results"
+}
+
+// SaltArtifactDistributeRequest 分发请求
+type SaltArtifactDistributeRequest struct {
+	Target   string `json:"target" binding:"required"`
+	TgtType  string `json:"tgt_type"`
+	MasterID string `json:"master_id"`
+	DestPath string `json:"dest_path" binding:"required"`
+	Mode     string `json:"mode"`    // 如 0400
+	User     string `json:"user"`    // 文件属主
+	Group    string `json:"group"`   // 文件属组
+	Timeout  int    `json:"timeout"` // 等待秒数，默认 600
+}
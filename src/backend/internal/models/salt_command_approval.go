@@ -0,0 +1,84 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 危险命令执行审批状态
+const (
+	SaltCommandApprovalPending   = "pending"   // 待审批
+	SaltCommandApprovalApproved  = "approved"  // 已批准，等待执行
+	SaltCommandApprovalRejected  = "rejected"  // 已拒绝
+	SaltCommandApprovalCanceled  = "canceled"  // 申请人已取消
+	SaltCommandApprovalExecuting = "executing" // 执行中
+	SaltCommandApprovalExecuted  = "executed"  // 已执行（全部成功）
+	SaltCommandApprovalFailed    = "failed"    // 已执行但存在失败，或下发失败
+)
+
+// SaltCommandApproval 危险命令执行审批记录
+// 审批流程复用 PermissionRequest/PermissionApprovalLog（request_type=salt_command），
+// 本表保存被批准的精确命令与目标，批准后仅执行一次
+type SaltCommandApproval struct {
+	ID                  uint       `json:"id" gorm:"primaryKey"`
+	PermissionRequestID uint       `json:"permission_request_id" gorm:"uniqueIndex;not null"` // 关联的审批申请
+	RequesterID         uint       `json:"requester_id" gorm:"index;not null"`                // 申请人ID
+	RequesterName       string     `json:"requester_name" gorm:"size:100"`                    // 申请人用户名
+	Function            string     `json:"function" gorm:"size:128;not null"`                 // Salt 函数，如 cmd.run
+	Arguments           string     `json:"-" gorm:"type:text"`                                // 参数（JSON数组）
+	Kwarg               string     `json:"-" gorm:"type:text"`                                // 关键字参数（JSON对象）
+	Target              string     `json:"target" gorm:"size:256;not null"`                   // 目标
+	TgtType             string     `json:"tgt_type" gorm:"size:32;default:'glob'"`            // 目标类型
+	MasterID            string     `json:"master_id,omitempty" gorm:"size:100"`               // 指定 Master；为空时按 minion 密钥归属路由
+	RulePattern         string     `json:"rule_pattern" gorm:"size:255"`                      // 命中的黑名单规则
+	RuleSeverity        string     `json:"rule_severity" gorm:"size:20"`                      // 规则严重级别
+	RuleDescription     string     `json:"rule_description" gorm:"size:500"`                  // 规则描述
+	Status              string     `json:"status" gorm:"size:20;index;default:'pending'"`     // 审批/执行状态
+	ApproverID          *uint      `json:"approver_id,omitempty"`                             // 审批人ID
+	ApproverName        string     `json:"approver_name,omitempty" gorm:"size:100"`           // 审批人用户名
+	ApproveComment      string     `json:"approve_comment,omitempty" gorm:"type:text"`        // 审批意见
+	DecidedAt           *time.Time `json:"decided_at,omitempty"`                              // 审批时间
+	JID                 string     `json:"jid,omitempty" gorm:"column:jid;size:64;index"`     // 执行后的 Salt JID
+	ExecutedAt          *time.Time `json:"executed_at,omitempty"`                             // 执行时间
+	SuccessCount        int        `json:"success_count"`                                     // 成功节点数
+	FailedCount         int        `json:"failed_count"`                                      // 失败节点数
+	ErrorMessage        string     `json:"error_message,omitempty" gorm:"type:text"`          // 错误信息
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+
+	// 非持久化字段，用于 API 返回
+	Args   []interface{}          `json:"args" gorm:"-"`
+	Kwargs map[string]interface{} `json:"kwarg,omitempty" gorm:"-"`
+}
+
+// TableName 指定表名
+func (SaltCommandApproval) TableName() string {
+	return "salt_command_approvals"
+}
+
+// GetArgs 解析参数
+func (a *SaltCommandApproval) GetArgs() []interface{} {
+	var args []interface{}
+	if a.Arguments != "" {
+		json.Unmarshal([]byte(a.Arguments), &args)
+	}
+	return args
+}
+
+// GetKwarg 解析关键字参数
+func (a *SaltCommandApproval) GetKwarg() map[string]interface{} {
+	var kwarg map[string]interface{}
+	if a.Kwarg != "" {
+		json.Unmarshal([]byte(a.Kwarg), &kwarg)
+	}
+	return kwarg
+}
+
+// AfterFind 填充非持久化字段
+func (a *SaltCommandApproval) AfterFind(tx *gorm.DB) error {
+	a.Args = a.GetArgs()
+	a.Kwargs = a.GetKwarg()
+	return nil
+}
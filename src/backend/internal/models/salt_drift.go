@@ -0,0 +1,164 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 节点合规状态
+const (
+	SaltDriftNodeCompliant  = "compliant"   // 所有状态均无待变更
+	SaltDriftNodeDrifted    = "drifted"     // 存在待变更（配置漂移）
+	SaltDriftNodeError      = "error"       // 状态执行/编译失败
+	SaltDriftNodeNoResponse = "no_response" // 超时未返回
+)
+
+// 漂移扫描运行状态
+const (
+	SaltDriftRunRunning   = "running"
+	SaltDriftRunCompleted = "completed"
+	SaltDriftRunFailed    = "failed"
+	SaltDriftRunTimeout   = "timeout"
+)
+
+// SaltDriftPolicy 配置漂移扫描策略：按 cron 周期以 test=True 运行指定状态
+type SaltDriftPolicy struct {
+	ID            uint           `gorm:"primaryKey" json:"id"`
+	Name          string         `gorm:"size:128;not null;uniqueIndex" json:"name"`
+	Description   string         `gorm:"size:500" json:"description"`
+	Target        string         `gorm:"size:256;not null" json:"target"`          // 目标节点
+	TgtType       string         `gorm:"size:32;default:'glob'" json:"tgt_type"`   // 目标类型（支持 group）
+	MasterID      string         `gorm:"size:100" json:"master_id,omitempty"`      // 指定 Master；为空时按 minion 密钥归属路由
+	States        string         `gorm:"size:1000" json:"states"`                  // 逗号分隔的 sls 列表，空为 highstate
+	CronExpr      string         `gorm:"size:128;not null" json:"cron_expr"`       // 5 段 cron 表达式或 @daily 等宏
+	Timezone      string         `gorm:"size:64;default:'UTC'" json:"timezone"`    // IANA 时区
+	Timeout       int            `gorm:"default:600" json:"timeout"`               // 等待结果超时（秒）
+	Enabled       bool           `gorm:"default:true;index" json:"enabled"`        // 是否启用
+	NotifyWebhook string         `gorm:"size:500" json:"notify_webhook,omitempty"` // 节点漂移通知 Webhook（主机须在 WEBHOOK_ALLOWED_HOSTS 中）
+	NextRunAt     *time.Time     `gorm:"index" json:"next_run_at,omitempty"`
+	LastRunAt     *time.Time     `json:"last_run_at,omitempty"`
+	LastStatus    string         `gorm:"size:32" json:"last_status,omitempty"`
+	DriftedCount  int            `json:"drifted_count"` // 最近一次扫描中漂移的节点数
+	CreatedBy     string         `gorm:"size:64" json:"created_by"`
+	UpdatedBy     string         `gorm:"size:64" json:"updated_by,omitempty"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// TableName 指定表名
+func (SaltDriftPolicy) TableName() string {
+	return "salt_drift_policies"
+}
+
+// SaltDriftRun 一次漂移扫描
+type SaltDriftRun struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	PolicyID       uint       `gorm:"index;not null" json:"policy_id"`
+	JID            string     `gorm:"column:jid;size:64;index" json:"jid,omitempty"`
+	Trigger        string     `gorm:"size:16" json:"trigger"` // cron, manual
+	Status         string     `gorm:"size:20;index" json:"status"`
+	TotalCount     int        `json:"total_count"`
+	CompliantCount int        `json:"compliant_count"`
+	DriftedCount   int        `json:"drifted_count"`
+	ErrorCount     int        `json:"error_count"`
+	MissingCount   int        `json:"missing_count"`
+	NewlyDrifted   int        `json:"newly_drifted"` // 由合规变为漂移的节点数
+	ErrorMessage   string     `gorm:"type:text" json:"error_message,omitempty"`
+	TriggeredBy    string     `gorm:"size:64" json:"triggered_by,omitempty"`
+	StartedAt      time.Time  `json:"started_at"`
+	FinishedAt     *time.Time `json:"finished_at,omitempty"`
+}
+
+// TableName 指定表名
+func (SaltDriftRun) TableName() string {
+	return "salt_drift_runs"
+}
+
+// SaltDriftNodeResult 单次扫描中单个节点的结果
+type SaltDriftNodeResult struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	RunID         uint      `gorm:"index;not null" json:"run_id"`
+	PolicyID      uint      `gorm:"index;not null" json:"policy_id"`
+	MinionID      string    `gorm:"size:255;not null;index" json:"minion_id"`
+	Status        string    `gorm:"size:20;index" json:"status"`
+	StatesTotal   int       `json:"states_total"`
+	StatesDrifted int       `json:"states_drifted"`
+	StatesFailed  int       `json:"states_failed"`
+	Message       string    `gorm:"type:text" json:"message,omitempty"` // 编译错误等
+	CreatedAt     time.Time `gorm:"index" json:"created_at"`
+
+	Drifts []SaltDriftStateResult `gorm:"foreignKey:NodeResultID" json:"drifts,omitempty"`
+}
+
+// TableName 指定表名
+func (SaltDriftNodeResult) TableName() string {
+	return "salt_drift_node_results"
+}
+
+// SaltDriftStateResult 节点上存在待变更或失败的单个状态（合规状态不落库）
+type SaltDriftStateResult struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	NodeResultID uint      `gorm:"index;not null" json:"node_result_id"`
+	RunID        uint      `gorm:"index;not null" json:"run_id"`
+	PolicyID     uint      `gorm:"index:idx_drift_state_policy_state;not null" json:"policy_id"`
+	MinionID     string    `gorm:"size:255;not null;index" json:"minion_id"`
+	StateID      string    `gorm:"size:512;index:idx_drift_state_policy_state" json:"state_id"` // 如 file_|-/etc/slurm/slurm.conf_|-/etc/slurm/slurm.conf_|-managed
+	SLS          string    `gorm:"column:sls;size:255" json:"sls,omitempty"`
+	Function     string    `gorm:"size:128" json:"function"` // 如 file.managed
+	Name         string    `gorm:"size:512" json:"name"`
+	Failed       bool      `json:"failed"` // true 表示状态执行失败，否则为待变更
+	Comment      string    `gorm:"type:text" json:"comment,omitempty"`
+	Changes      string    `gorm:"type:text" json:"changes,omitempty"` // 待变更内容（JSON）
+	CreatedAt    time.Time `gorm:"index" json:"created_at"`
+}
+
+// TableName 指定表名
+func (SaltDriftStateResult) TableName() string {
+	return "salt_drift_state_results"
+}
+
+// SaltDriftNodeStatus 节点在某策略下的当前合规状态（用于识别由合规转为漂移）
+type SaltDriftNodeStatus struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	PolicyID      uint       `gorm:"uniqueIndex:idx_drift_node_status;not null" json:"policy_id"`
+	MinionID      string     `gorm:"uniqueIndex:idx_drift_node_status;size:255;not null" json:"minion_id"`
+	Status        string     `gorm:"size:20;index" json:"status"`
+	StatesDrifted int        `json:"states_drifted"`
+	LastRunID     uint       `json:"last_run_id"`
+	LastCheckedAt time.Time  `json:"last_checked_at"`
+	DriftedSince  *time.Time `json:"drifted_since,omitempty"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (SaltDriftNodeStatus) TableName() string {
+	return "salt_drift_node_status"
+}
+
+// SaltDriftPolicyRequest 创建/更新漂移扫描策略请求
+type SaltDriftPolicyRequest struct {
+	Name          string `json:"name" binding:"required"`
+	Description   string `json:"description"`
+	Target        string `json:"target" binding:"required"`
+	TgtType       string `json:"tgt_type"`
+	MasterID      string `json:"master_id"`
+	States        string `json:"states"`
+	CronExpr      string `json:"cron_expr" binding:"required"`
+	Timezone      string `json:"timezone"`
+	Timeout       int    `json:"timeout"`
+	Enabled       *bool  `json:"enabled"`
+	NotifyWebhook string `json:"notify_webhook"`
+}
+
+// SaltDriftStateSummary 按状态聚合的漂移报告
+type SaltDriftStateSummary struct {
+	StateID     string    `json:"state_id"`
+	SLS         string    `json:"sls"`
+	Function    string    `json:"function"`
+	Name        string    `json:"name"`
+	Occurrences int64     `json:"occurrences"` // 时间范围内出现漂移/失败的次数
+	MinionCount int64     `json:"minion_count"`
+	LastSeenAt  time.Time `json:"last_seen_at"`
+}
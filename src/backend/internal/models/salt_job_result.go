@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+// SaltJobMinionResult 作业在单个 minion 上的规范化执行结果
+// 由 SaltJobHistory.Result 原始结果拆分而来，支持 cmd.*、state.*、pkg.* 等函数，
+// 用于全文检索作业输出和按 minion 查看作业时间线
+type SaltJobMinionResult struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	JID           string    `json:"jid" gorm:"column:jid;size:64;not null;uniqueIndex:idx_salt_job_minion"`
+	MinionID      string    `json:"minion_id" gorm:"size:255;not null;uniqueIndex:idx_salt_job_minion;index"`
+	TaskID        string    `json:"task_id,omitempty" gorm:"size:64;index"`
+	Function      string    `json:"function" gorm:"size:128;index"`
+	User          string    `json:"user" gorm:"size:64"`
+	Success       bool      `json:"success" gorm:"index"`
+	Retcode       int       `json:"retcode"`
+	Stdout        string    `json:"stdout" gorm:"type:text"`
+	Stderr        string    `json:"stderr,omitempty" gorm:"type:text"`
+	DurationMs    float64   `json:"duration_ms"`    // state 为各状态耗时之和；其他函数取作业耗时
+	StatesTotal   int       `json:"states_total"`   // state.* 状态总数
+	StatesChanged int       `json:"states_changed"` // state.* 发生变更的状态数 / pkg.* 变更的包数
+	StatesFailed  int       `json:"states_failed"`  // state.* 失败的状态数
+	StartTime     time.Time `json:"start_time" gorm:"index"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// TableName 指定表名
+func (SaltJobMinionResult) TableName() string {
+	return "salt_job_minion_results"
+}
+
+// SaltJobResultSearchParams 作业输出检索参数
+type SaltJobResultSearchParams struct {
+	Query    string    `form:"q"`         // 检索内容（短语匹配 stdout/stderr）
+	Mode     string    `form:"mode"`      // fulltext（默认）或 substring
+	MinionID string    `form:"minion_id"` // 限定 minion
+	Function string    `form:"function"`  // 限定函数
+	Failed   *bool     `form:"failed"`    // 仅失败/仅成功
+	Since    time.Time `form:"since" time_format:"2006-01-02T15:04:05Z07:00"`
+	Until    time.Time `form:"until" time_format:"2006-01-02T15:04:05Z07:00"`
+	Page     int       `form:"page,default=1"`
+	PageSize int       `form:"page_size,default=50"`
+}
+
+// SaltJobResultListResponse 规范化结果列表响应
+type SaltJobResultListResponse struct {
+	Total int64                 `json:"total"`
+	Page  int                   `json:"page"`
+	Size  int                   `json:"size"`
+	Data  []SaltJobMinionResult `json:"data"`
+}
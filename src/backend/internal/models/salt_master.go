@@ -0,0 +1,98 @@
+package models
+
+import (
+	"time"
+
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/utils"
+	"gorm.io/gorm"
+)
+
+// Salt Master 在拓扑中的角色
+const (
+	SaltMasterRoleMaster         = "master"           // 普通 Master（直接管理 minion）
+	SaltMasterRoleSyndic         = "syndic"           // Syndic 节点：本身是 Master，同时挂在上级 Master 下
+	SaltMasterRoleMasterOfMaster = "master_of_master" // 顶层 Master of Masters
+)
+
+// SaltMaster 已注册的 Salt Master（含独立的 Salt API 凭证）
+// MasterID 与 SaltstackClusterPermission.MasterID 对应
+type SaltMaster struct {
+	ID             uint           `json:"id" gorm:"primaryKey"`
+	MasterID       string         `json:"master_id" gorm:"uniqueIndex;size:100;not null"` // Master 标识（权限、路由使用）
+	Name           string         `json:"name" gorm:"size:100"`                           // 显示名称
+	Datacenter     string         `json:"datacenter" gorm:"size:100;index"`               // 所在数据中心
+	APIURL         string         `json:"api_url" gorm:"size:255;not null"`               // Salt API 地址
+	Username       string         `json:"username" gorm:"size:100"`                       // Salt API 用户名
+	Password       string         `json:"-" gorm:"size:500"`                              // Salt API 密码（加密存储）
+	Eauth          string         `json:"eauth" gorm:"size:32;default:'file'"`            // 认证方式
+	Role           string         `json:"role" gorm:"size:32;default:'master'"`           // master, syndic, master_of_master
+	ParentMasterID string         `json:"parent_master_id" gorm:"size:100;index"`         // 上级 Master（syndic 层级关系）
+	Priority       int            `json:"priority" gorm:"default:0"`                      // 优先级，数字越小优先级越高
+	Enabled        bool           `json:"enabled" gorm:"default:true"`                    // 是否启用
+	Healthy        bool           `json:"healthy"`                                        // 最近一次检查是否健康
+	LastError      string         `json:"last_error,omitempty" gorm:"type:text"`          // 最近一次同步错误
+	MinionCount    int            `json:"minion_count"`                                   // 已接受的 minion 数量
+	LastSyncAt     *time.Time     `json:"last_sync_at,omitempty"`                         // 最近一次同步 minion 清单的时间
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// TableName 指定表名
+func (SaltMaster) TableName() string {
+	return "salt_masters"
+}
+
+// BeforeSave 保存前加密密码
+func (m *SaltMaster) BeforeSave(tx *gorm.DB) error {
+	if m.Password != "" {
+		m.Password = utils.EncryptSensitiveField(m.Password)
+	}
+	return nil
+}
+
+// AfterFind 查询后解密密码
+func (m *SaltMaster) AfterFind(tx *gorm.DB) error {
+	if m.Password != "" {
+		m.Password = utils.DecryptSensitiveField(m.Password)
+	}
+	return nil
+}
+
+// SaltMasterMinion 每个 Master 上的 minion 密钥清单
+// 同一 minion 在 syndic 拓扑中可能出现在多个 Master 上，以 accepted 状态所在的 Master 为归属
+type SaltMasterMinion struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	MasterID   string    `json:"master_id" gorm:"size:100;not null;uniqueIndex:idx_salt_master_minion"`
+	MinionID   string    `json:"minion_id" gorm:"size:255;not null;uniqueIndex:idx_salt_master_minion;index"`
+	KeyStatus  string    `json:"key_status" gorm:"size:20;index"` // accepted, pending, rejected, denied
+	LastSeenAt time.Time `json:"last_seen_at"`                    // 最近一次同步时仍存在于该 Master
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (SaltMasterMinion) TableName() string {
+	return "salt_master_minions"
+}
+
+// SaltMasterRequest 注册/更新 Salt Master 请求
+type SaltMasterRequest struct {
+	MasterID       string `json:"master_id" binding:"required"`
+	Name           string `json:"name"`
+	Datacenter     string `json:"datacenter"`
+	APIURL         string `json:"api_url" binding:"required"`
+	Username       string `json:"username"`
+	Password       string `json:"password"` // 更新时为空表示保持不变
+	Eauth          string `json:"eauth"`
+	Role           string `json:"role"`
+	ParentMasterID string `json:"parent_master_id"`
+	Priority       int    `json:"priority"`
+	Enabled        *bool  `json:"enabled"`
+}
+
+// SaltMasterTopologyNode Master 拓扑树节点
+type SaltMasterTopologyNode struct {
+	SaltMaster
+	Children []*SaltMasterTopologyNode `json:"children,omitempty"`
+}
@@ -0,0 +1,144 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Salt 定时作业重叠策略
+const (
+	SaltScheduleOverlapSkip     = "skip"     // 上一次未结束时跳过本次触发
+	SaltScheduleOverlapQueue    = "queue"    // 上一次未结束时排队，结束后立即执行
+	SaltScheduleOverlapParallel = "parallel" // 不限制，直接并行执行
+)
+
+// Salt 定时作业运行状态
+const (
+	SaltScheduleRunQueued    = "queued"
+	SaltScheduleRunRunning   = "running"
+	SaltScheduleRunCompleted = "completed"
+	SaltScheduleRunFailed    = "failed"
+	SaltScheduleRunTimeout   = "timeout"
+	SaltScheduleRunSkipped   = "skipped"
+)
+
+// SaltJobSchedule Salt 定时/周期作业
+type SaltJobSchedule struct {
+	ID            uint           `gorm:"primaryKey" json:"id"`
+	Name          string         `gorm:"size:128;not null;uniqueIndex" json:"name"`
+	Description   string         `gorm:"size:500" json:"description"`
+	CronExpr      string         `gorm:"size:128;not null" json:"cron_expr"`           // 5 段 cron 表达式或 @daily 等宏
+	Timezone      string         `gorm:"size:64;default:'UTC'" json:"timezone"`        // IANA 时区，如 Asia/Shanghai
+	Target        string         `gorm:"size:256;not null" json:"target"`              // 目标节点
+	TgtType       string         `gorm:"size:32;default:'glob'" json:"tgt_type"`       // 目标类型
+	MasterID      string         `gorm:"size:100" json:"master_id,omitempty"`          // 指定 Master；为空时按 minion 密钥归属路由
+	Function      string         `gorm:"size:128;not null" json:"function"`            // Salt 函数，如 cmd.run、state.apply
+	Arguments     string         `gorm:"type:text" json:"-"`                           // 参数（JSON 数组）
+	Kwarg         string         `gorm:"type:text" json:"-"`                           // 关键字参数（JSON 对象）
+	OverlapPolicy string         `gorm:"size:16;default:'skip'" json:"overlap_policy"` // skip, queue, parallel
+	Timeout       int            `gorm:"default:300" json:"timeout"`                   // 等待结果超时（秒）
+	Enabled       bool           `gorm:"default:true;index" json:"enabled"`            // 是否启用
+	NotifyOnFail  bool           `gorm:"default:false" json:"notify_on_fail"`          // 失败时是否通知
+	NotifyWebhook string         `gorm:"size:500" json:"notify_webhook,omitempty"`     // 失败通知 Webhook（主机须在 WEBHOOK_ALLOWED_HOSTS 中）
+	NextRunAt     *time.Time     `gorm:"index" json:"next_run_at,omitempty"`           // 下次触发时间
+	LastRunAt     *time.Time     `json:"last_run_at,omitempty"`                        // 上次触发时间
+	LastStatus    string         `gorm:"size:32" json:"last_status,omitempty"`         // 上次运行状态
+	CreatedBy     string         `gorm:"size:64" json:"created_by"`                    // 创建人
+	UpdatedBy     string         `gorm:"size:64" json:"updated_by,omitempty"`          // 最后修改人
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
+
+	// 非持久化字段，便于前端展示
+	Args   []interface{}          `gorm:"-" json:"args"`
+	Kwargs map[string]interface{} `gorm:"-" json:"kwarg,omitempty"`
+}
+
+// TableName 指定表名
+func (SaltJobSchedule) TableName() string {
+	return "salt_job_schedules"
+}
+
+// GetArgs 解析参数列表
+func (s *SaltJobSchedule) GetArgs() []interface{} {
+	var args []interface{}
+	if s.Arguments == "" {
+		return args
+	}
+	if err := json.Unmarshal([]byte(s.Arguments), &args); err != nil {
+		return []interface{}{}
+	}
+	return args
+}
+
+// GetKwarg 解析关键字参数
+func (s *SaltJobSchedule) GetKwarg() map[string]interface{} {
+	if s.Kwarg == "" {
+		return nil
+	}
+	var kwarg map[string]interface{}
+	if err := json.Unmarshal([]byte(s.Kwarg), &kwarg); err != nil {
+		return nil
+	}
+	return kwarg
+}
+
+// AfterFind 查询后填充展示字段
+func (s *SaltJobSchedule) AfterFind(tx *gorm.DB) error {
+	s.Args = s.GetArgs()
+	s.Kwargs = s.GetKwarg()
+	return nil
+}
+
+// SaltJobScheduleRun 定时作业单次运行记录（通过 JID 关联 SaltJobHistory）
+type SaltJobScheduleRun struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	ScheduleID   uint       `gorm:"index;not null" json:"schedule_id"`
+	JID          string     `gorm:"column:jid;index;size:64" json:"jid,omitempty"` // 对应 salt_job_histories.jid
+	TaskID       string     `gorm:"index;size:64" json:"task_id"`                  // 作业历史中的 task_id
+	Trigger      string     `gorm:"size:16;default:'cron'" json:"trigger"`         // cron, manual
+	Status       string     `gorm:"size:32;index" json:"status"`                   // queued, running, completed, failed, timeout, skipped
+	SuccessCount int        `gorm:"default:0" json:"success_count"`
+	FailedCount  int        `gorm:"default:0" json:"failed_count"`
+	ErrorMessage string     `gorm:"type:text" json:"error_message,omitempty"`
+	ScheduledAt  time.Time  `gorm:"index" json:"scheduled_at"` // 计划触发时间
+	StartedAt    *time.Time `json:"started_at,omitempty"`
+	FinishedAt   *time.Time `json:"finished_at,omitempty"`
+	Duration     int64      `json:"duration,omitempty"` // 毫秒
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (SaltJobScheduleRun) TableName() string {
+	return "salt_job_schedule_runs"
+}
+
+// SaltJobScheduleRequest 创建/更新定时作业请求
+type SaltJobScheduleRequest struct {
+	Name          string                 `json:"name" binding:"required"`
+	Description   string                 `json:"description"`
+	CronExpr      string                 `json:"cron_expr" binding:"required"`
+	Timezone      string                 `json:"timezone"`
+	Target        string                 `json:"target" binding:"required"`
+	TgtType       string                 `json:"tgt_type"`
+	MasterID      string                 `json:"master_id"`
+	Function      string                 `json:"function" binding:"required"`
+	Args          []interface{}          `json:"args"`
+	Kwarg         map[string]interface{} `json:"kwarg"`
+	OverlapPolicy string                 `json:"overlap_policy"`
+	Timeout       int                    `json:"timeout"`
+	Enabled       *bool                  `json:"enabled"`
+	NotifyOnFail  bool                   `json:"notify_on_fail"`
+	NotifyWebhook string                 `json:"notify_webhook"`
+}
+
+// SaltJobScheduleRunListResponse 运行记录列表响应
+type SaltJobScheduleRunListResponse struct {
+	Total int64                `json:"total"`
+	Page  int                  `json:"page"`
+	Size  int                  `json:"size"`
+	Data  []SaltJobScheduleRun `json:"data"`
+}
@@ -20,6 +20,7 @@ type SlurmCluster struct {
 	MasterHost  string         `json:"master_host" gorm:"size:255"`
 	MasterPort  int            `json:"master_port" gorm:"default:22"`
 	MasterSSH   *SSHConfig     `json:"master_ssh,omitempty" gorm:"type:json"` // Master节点SSH连接信息（用于external类型）
+	JumpHosts   SSHJumpChain   `json:"jump_hosts,omitempty" gorm:"type:json"` // 集群默认跳板机链（节点未单独配置时使用）
 	SaltMaster  string         `json:"salt_master" gorm:"size:255"`           // SaltStack Master地址
 	Config      ClusterConfig  `json:"config" gorm:"type:json"`
 	CreatedBy   uint           `json:"created_by"`
@@ -45,6 +46,7 @@ type SlurmNode struct {
 	AuthType       string         `json:"auth_type" gorm:"default:'password';size:20"` // password, key
 	Password       string         `json:"-" gorm:"size:255"`                           // 加密存储，不在JSON中暴露
 	KeyPath        string         `json:"-" gorm:"size:500"`                           // 不在JSON中暴露
+	JumpHosts      SSHJumpChain   `json:"jump_hosts,omitempty" gorm:"type:json"`       // 节点跳板机链，覆盖集群配置
 	Status         string         `json:"status" gorm:"default:'pending';size:50"`     // pending, connecting, installing, configuring, active, failed, removing
 	SaltMinionID   string         `json:"salt_minion_id" gorm:"size:100"`
 	CPUs           int            `json:"cpus" gorm:"default:1"`
@@ -368,7 +370,8 @@ type CreateClusterRequest struct {
 	MasterSSH   *SSHConfig          `json:"master_ssh"`  // external类型时必填
 	SaltMaster  string              `json:"salt_master"` // managed类型时必填
 	Config      ClusterConfig       `json:"config"`
-	Nodes       []CreateNodeRequest `json:"nodes"` // managed类型时必填
+	Nodes       []CreateNodeRequest `json:"nodes"`      // managed类型时必填
+	JumpHosts   SSHJumpChain        `json:"jump_hosts"` // 集群默认跳板机链
 }
 
 type ConnectExternalClusterRequest struct {
@@ -378,22 +381,24 @@ type ConnectExternalClusterRequest struct {
 	MasterPort  int           `json:"master_port"`
 	MasterSSH   SSHConfig     `json:"master_ssh" binding:"required"`
 	Config      ClusterConfig `json:"config"`
+	JumpHosts   SSHJumpChain  `json:"jump_hosts"` // 访问 Master 及节点所经的跳板机链
 }
 
 type CreateNodeRequest struct {
-	NodeName   string     `json:"node_name" binding:"required"`
-	NodeType   string     `json:"node_type" binding:"required,oneof=master compute login"`
-	Host       string     `json:"host" binding:"required"`
-	Port       int        `json:"port"`
-	Username   string     `json:"username" binding:"required"`
-	AuthType   string     `json:"auth_type" binding:"oneof=password key"`
-	Password   string     `json:"password"`
-	KeyPath    string     `json:"key_path"`
-	CPUs       int        `json:"cpus"`
-	Memory     int        `json:"memory"`
-	Storage    int        `json:"storage"`
-	GPUs       int        `json:"gpus"`
-	NodeConfig NodeConfig `json:"node_config"`
+	NodeName   string       `json:"node_name" binding:"required"`
+	NodeType   string       `json:"node_type" binding:"required,oneof=master compute login"`
+	Host       string       `json:"host" binding:"required"`
+	Port       int          `json:"port"`
+	Username   string       `json:"username" binding:"required"`
+	AuthType   string       `json:"auth_type" binding:"oneof=password key"`
+	Password   string       `json:"password"`
+	KeyPath    string       `json:"key_path"`
+	CPUs       int          `json:"cpus"`
+	Memory     int          `json:"memory"`
+	Storage    int          `json:"storage"`
+	GPUs       int          `json:"gpus"`
+	NodeConfig NodeConfig   `json:"node_config"`
+	JumpHosts  SSHJumpChain `json:"jump_hosts"` // 节点跳板机链，为空则使用集群配置
 }
 
 type ScaleClusterRequest struct {
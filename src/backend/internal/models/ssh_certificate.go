@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// SSHUserCertRequest 申请 SSH 用户证书的请求
+type SSHUserCertRequest struct {
+	PublicKey string `json:"public_key" binding:"required"` // authorized_keys 格式的用户公钥
+	TTL       string `json:"ttl"`                           // 有效期（如 "8h"），为空使用默认值，超过上限会被截断
+}
+
+// SSHUserCertificate 签发的 SSH 用户证书
+type SSHUserCertificate struct {
+	Certificate string    `json:"certificate"`   // OpenSSH 证书（保存为 id_xxx-cert.pub）
+	KeyID       string    `json:"key_id"`        // 证书标识，写入 sshd 日志便于追溯
+	Serial      uint64    `json:"serial"`        // 证书序列号
+	Principals  []string  `json:"principals"`    // 授权的登录主体
+	ValidAfter  time.Time `json:"valid_after"`   // 生效时间
+	ValidBefore time.Time `json:"valid_before"`  // 过期时间
+	CAPublicKey string    `json:"ca_public_key"` // 签发该证书的 CA 公钥
+}
@@ -0,0 +1,106 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// SSHJumpHost 跳板机（ProxyJump 链中的一跳），每一跳使用独立凭据
+type SSHJumpHost struct {
+	Host       string `json:"host"`
+	Port       int    `json:"port"`
+	Username   string `json:"username"`
+	Password   string `json:"password,omitempty"`    // 只写：API 响应中不返回
+	PrivateKey string `json:"private_key,omitempty"` // 只写：内联私钥（PEM）
+	KeyPath    string `json:"key_path,omitempty"`    // 只写：后端可读取的私钥路径
+}
+
+// Address 返回 host:port
+func (j SSHJumpHost) Address() string {
+	port := j.Port
+	if port == 0 {
+		port = 22
+	}
+	return fmt.Sprintf("%s:%d", j.Host, port)
+}
+
+// MarshalJSON 序列化时隐藏凭据，仅返回是否已配置
+func (j SSHJumpHost) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Host           string `json:"host"`
+		Port           int    `json:"port"`
+		Username       string `json:"username"`
+		HasCredentials bool   `json:"has_credentials"`
+	}{j.Host, j.Port, j.Username, j.Password != "" || j.PrivateKey != "" || j.KeyPath != ""})
+}
+
+// SSHJumpChain 按顺序经过的跳板机链（对应 ssh -J hop1,hop2）
+type SSHJumpChain []SSHJumpHost
+
+// sshJumpHostRecord 数据库存储格式（保留凭据）
+type sshJumpHostRecord SSHJumpHost
+
+// String 返回 ProxyJump 风格描述，如 "ops@bastion:22,root@10.0.0.1:22"
+func (c SSHJumpChain) String() string {
+	hops := make([]string, 0, len(c))
+	for _, hop := range c {
+		hops = append(hops, hop.Username+"@"+hop.Address())
+	}
+	return strings.Join(hops, ",")
+}
+
+// Validate 检查每一跳的地址、用户与凭据
+func (c SSHJumpChain) Validate() error {
+	for i, hop := range c {
+		if hop.Host == "" || hop.Username == "" {
+			return fmt.Errorf("jump host #%d: host and username are required", i+1)
+		}
+		if hop.Password == "" && hop.PrivateKey == "" && hop.KeyPath == "" {
+			return fmt.Errorf("jump host #%d (%s): no credentials", i+1, hop.Address())
+		}
+	}
+	return nil
+}
+
+// Value 实现 database/sql/driver.Valuer 接口
+func (c SSHJumpChain) Value() (driver.Value, error) {
+	if len(c) == 0 {
+		return nil, nil
+	}
+	records := make([]sshJumpHostRecord, len(c))
+	for i, hop := range c {
+		records[i] = sshJumpHostRecord(hop)
+	}
+	return json.Marshal(records)
+}
+
+// Scan 实现 database/sql.Scanner 接口
+func (c *SSHJumpChain) Scan(value interface{}) error {
+	if value == nil {
+		*c = nil
+		return nil
+	}
+
+	var bytes []byte
+	switch v := value.(type) {
+	case []byte:
+		bytes = v
+	case string:
+		bytes = []byte(v)
+	default:
+		return fmt.Errorf("failed to scan SSHJumpChain: unsupported type %T", value)
+	}
+
+	var records []sshJumpHostRecord
+	if err := json.Unmarshal(bytes, &records); err != nil {
+		return err
+	}
+	chain := make(SSHJumpChain, len(records))
+	for i, r := range records {
+		chain[i] = SSHJumpHost(r)
+	}
+	*c = chain
+	return nil
+}
@@ -0,0 +1,64 @@
+package models
+
+import "time"
+
+// SSH 主机密钥校验模式
+const (
+	SSHHostKeyModeTOFU     = "tofu"     // 首次连接信任并记录；密钥变化时记录待审批、告警并拒绝连接
+	SSHHostKeyModeStrict   = "strict"   // 仅允许已登记或已审批的主机；未知主机与密钥变化均记录待审批并拒绝连接
+	SSHHostKeyModeInsecure = "insecure" // 不校验（仅用于排障）
+)
+
+// SSH 主机密钥状态
+const (
+	SSHKnownHostTrusted  = "trusted"  // 受信任，连接时比对
+	SSHKnownHostPending  = "pending"  // 与受信任密钥不一致或 strict 模式下的未知主机，待管理员审批
+	SSHKnownHostRejected = "rejected" // 已拒绝（或被轮换替换）
+)
+
+// SSHKnownHost 已知主机密钥（集中式 known_hosts）
+type SSHKnownHost struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	Host        string     `json:"host" gorm:"size:255;not null;uniqueIndex:idx_ssh_known_host_key"`
+	Port        int        `json:"port" gorm:"not null;uniqueIndex:idx_ssh_known_host_key"`
+	KeyType     string     `json:"key_type" gorm:"size:64;not null;uniqueIndex:idx_ssh_known_host_key"` // ssh-ed25519, ecdsa-sha2-nistp256, ssh-rsa
+	Fingerprint string     `json:"fingerprint" gorm:"size:128;not null;uniqueIndex:idx_ssh_known_host_key"`
+	PublicKey   string     `json:"public_key" gorm:"type:text;not null"` // authorized_keys 格式
+	Status      string     `json:"status" gorm:"size:20;index;not null"`
+	Source      string     `json:"source" gorm:"size:20"` // tofu, manual, changed
+	SeenCount   int64      `json:"seen_count"`
+	FirstSeenAt time.Time  `json:"first_seen_at"`
+	LastSeenAt  time.Time  `json:"last_seen_at"`
+	ApprovedBy  string     `json:"approved_by,omitempty" gorm:"size:100"`
+	ApprovedAt  *time.Time `json:"approved_at,omitempty"`
+	Comment     string     `json:"comment,omitempty" gorm:"size:500"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (SSHKnownHost) TableName() string {
+	return "ssh_known_hosts"
+}
+
+// SSHKnownHostSetting 主机密钥校验设置（单行），运行时切换的模式在重启后保留
+type SSHKnownHostSetting struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Mode      string    `json:"mode" gorm:"size:20;not null"`
+	UpdatedBy string    `json:"updated_by,omitempty" gorm:"size:100"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (SSHKnownHostSetting) TableName() string {
+	return "ssh_known_host_settings"
+}
+
+// SSHKnownHostRequest 手动登记/轮换主机密钥请求
+type SSHKnownHostRequest struct {
+	Host      string `json:"host" binding:"required"`
+	Port      int    `json:"port"`
+	PublicKey string `json:"public_key" binding:"required"` // authorized_keys 格式，如 "ssh-ed25519 AAAA..."
+	Comment   string `json:"comment"`
+}
@@ -0,0 +1,70 @@
+package models
+
+import "time"
+
+// Web 终端目标类型
+const (
+	TerminalTargetSlurmNode    = "slurm_node"    // SlurmNode 中保存的节点凭据
+	TerminalTargetHostTemplate = "host_template" // 主机模板中的主机凭据
+)
+
+// Web 终端会话状态
+const (
+	TerminalSessionActive = "active"
+	TerminalSessionClosed = "closed"
+	TerminalSessionFailed = "failed"
+)
+
+// Web 终端会话关闭原因
+const (
+	TerminalCloseClient      = "client_closed"   // 浏览器断开
+	TerminalCloseRemote      = "remote_closed"   // 远端 shell 退出
+	TerminalCloseIdle        = "idle_timeout"    // 空闲超时
+	TerminalCloseMaxDuration = "max_duration"    // 超过最长会话时长
+	TerminalCloseError       = "error"           // 连接或传输错误
+	TerminalCloseShutdown    = "server_shutdown" // 服务关闭
+)
+
+// TerminalSession Web 终端会话记录（录像以 asciicast v2 格式保存在对象存储）
+type TerminalSession struct {
+	ID              uint       `json:"id" gorm:"primaryKey"`
+	SessionID       string     `json:"session_id" gorm:"size:64;uniqueIndex;not null"`
+	UserID          uint       `json:"user_id" gorm:"index"`
+	Username        string     `json:"username" gorm:"size:100;index"`
+	ClientIP        string     `json:"client_ip" gorm:"size:64"`
+	TargetKind      string     `json:"target_kind" gorm:"size:32;not null"` // slurm_node, host_template
+	TargetID        uint       `json:"target_id"`                           // SlurmNode.ID 或 HostTemplate.ID
+	ClusterID       uint       `json:"cluster_id,omitempty"`
+	MinionID        string     `json:"minion_id,omitempty" gorm:"size:255;index"`
+	Host            string     `json:"host" gorm:"size:255;index"`
+	Port            int        `json:"port"`
+	SSHUser         string     `json:"ssh_user" gorm:"size:100"`
+	Cols            int        `json:"cols"`
+	Rows            int        `json:"rows"`
+	Status          string     `json:"status" gorm:"size:20;index"`
+	CloseReason     string     `json:"close_reason,omitempty" gorm:"size:32"`
+	ErrorMessage    string     `json:"error_message,omitempty" gorm:"type:text"`
+	BytesIn         int64      `json:"bytes_in"`  // 用户输入字节数
+	BytesOut        int64      `json:"bytes_out"` // 终端输出字节数
+	RecordingBucket string     `json:"recording_bucket,omitempty" gorm:"size:128"`
+	RecordingKey    string     `json:"recording_key,omitempty" gorm:"size:512"`
+	RecordingSize   int64      `json:"recording_size"`
+	StartedAt       time.Time  `json:"started_at" gorm:"index"`
+	EndedAt         *time.Time `json:"ended_at,omitempty"`
+	DurationMs      int64      `json:"duration_ms"`
+}
+
+// TableName 指定表名
+func (TerminalSession) TableName() string {
+	return "terminal_sessions"
+}
+
+// TerminalMessage 浏览器与后端之间的终端消息
+// 客户端发送 input/resize/ping，服务端发送 output/status/error/closed
+type TerminalMessage struct {
+	Type   string `json:"type"`
+	Data   string `json:"data,omitempty"`
+	Cols   int    `json:"cols,omitempty"`
+	Rows   int    `json:"rows,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
@@ -0,0 +1,557 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/services/ai_providers"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// 未设置预警比例时的默认值
+const defaultAIBudgetWarnPercent = 80
+
+// ErrAIBudgetExceeded 超出 AI 预算，请求被拒绝
+var ErrAIBudgetExceeded = errors.New("AI budget exceeded")
+
+// AIBudgetExceededError 携带被触发的预算状态
+type AIBudgetExceededError struct {
+	Status AIBudgetStatus
+}
+
+func (e *AIBudgetExceededError) Error() string {
+	return fmt.Sprintf("%v: %s budget %q (%s) used %d/%d tokens, cost %.4f/%.4f",
+		ErrAIBudgetExceeded, e.Status.Budget.Scope, e.Status.ScopeName, e.Status.Budget.Period,
+		e.Status.TokensUsed, e.Status.TokenLimit, e.Status.Cost, e.Status.CostLimit)
+}
+
+func (e *AIBudgetExceededError) Unwrap() error {
+	return ErrAIBudgetExceeded
+}
+
+// AIBudgetStatus 预算在当前周期内的用量与状态，TokenLimit/CostLimit 为计入放行额度后的有效限额
+type AIBudgetStatus struct {
+	Budget      models.AIBudget `json:"budget"`
+	ScopeName   string          `json:"scope_name"`
+	PeriodStart time.Time       `json:"period_start"`
+	PeriodEnd   time.Time       `json:"period_end"`
+	TokensUsed  int64           `json:"tokens_used"`
+	Cost        float64         `json:"cost"`
+	TokenLimit  int64           `json:"token_limit"`
+	CostLimit   float64         `json:"cost_limit"`
+	Percent     float64         `json:"percent"` // token 与费用用量百分比中的较大者
+	State       string          `json:"state"`
+}
+
+// AIModelPrice 模型单价（每 1K token）
+type AIModelPrice struct {
+	InputPer1K  float64 `json:"input_per_1k"`
+	OutputPer1K float64 `json:"output_per_1k"`
+}
+
+// IsZero 是否未设置任何单价
+func (p AIModelPrice) IsZero() bool {
+	return p.InputPer1K == 0 && p.OutputPer1K == 0
+}
+
+// Cost 计算费用；未区分输入/输出的 token 按两者单价的平均值计费
+func (p AIModelPrice) Cost(input, output, total int) float64 {
+	cost := (float64(input)*p.InputPer1K + float64(output)*p.OutputPer1K) / 1000
+	if rest := total - input - output; rest > 0 {
+		cost += float64(rest) * (p.InputPer1K + p.OutputPer1K) / 2 / 1000
+	}
+	return cost
+}
+
+// AIUsageBreakdown 用量报表中的一行
+type AIUsageBreakdown struct {
+	ID           uint    `json:"id,omitempty"`
+	Name         string  `json:"name"`
+	Provider     string  `json:"provider,omitempty"`
+	Requests     int64   `json:"requests"`
+	Tokens       int64   `json:"tokens"`
+	InputTokens  int64   `json:"input_tokens"`
+	OutputTokens int64   `json:"output_tokens"`
+	Cost         float64 `json:"cost"`
+}
+
+// AIUsageReport 用量与费用报表；同时属于多个用户组的用户在每个组中都会计入
+type AIUsageReport struct {
+	From    time.Time          `json:"from"`
+	To      time.Time          `json:"to"`
+	Total   AIUsageBreakdown   `json:"total"`
+	ByModel []AIUsageBreakdown `json:"by_model"`
+	ByTeam  []AIUsageBreakdown `json:"by_team"`
+	ByUser  []AIUsageBreakdown `json:"by_user"`
+}
+
+// AIBudgetService 按用户、用户组与机器人配置的 token/费用预算：软性预警、超额拦截与管理员放行
+type AIBudgetService struct {
+	db        *gorm.DB
+	providers ai_providers.ProviderFactory
+}
+
+var (
+	aiBudgetServiceInstance *AIBudgetService
+	aiBudgetServiceOnce     sync.Once
+
+	// aiModelPrices 模型单价缓存，键为 provider/model
+	aiModelPrices sync.Map
+)
+
+// NewAIBudgetService 创建 AI 预算服务（单例）
+func NewAIBudgetService(db *gorm.DB) *AIBudgetService {
+	aiBudgetServiceOnce.Do(func() {
+		aiBudgetServiceInstance = &AIBudgetService{db: db, providers: ai_providers.NewProviderFactory()}
+		if err := db.AutoMigrate(&models.AIBudget{}, &models.AIBudgetOverride{}); err != nil {
+			logrus.Errorf("[AIBudget] 自动迁移失败: %v", err)
+		}
+	})
+	return aiBudgetServiceInstance
+}
+
+// GetAIBudgetService 获取 AI 预算服务实例，未初始化时返回 nil（不做预算限制）
+func GetAIBudgetService() *AIBudgetService {
+	return aiBudgetServiceInstance
+}
+
+// lookupAIModelPrice 按提供商公布的模型列表（ModelInfo.Cost）查找单价；仅缓存查到的非零单价，
+// 查询失败或未公布单价时记录日志并按 0 计费，下次使用时重新查询
+func lookupAIModelPrice(factory ai_providers.ProviderFactory, provider models.AIProvider, model string) AIModelPrice {
+	key := string(provider) + "/" + model
+	if cached, ok := aiModelPrices.Load(key); ok {
+		return cached.(AIModelPrice)
+	}
+	p, err := factory.CreateProvider(&models.AIAssistantConfig{Provider: provider, APIKey: "temp", Model: model})
+	if err != nil {
+		logrus.Warnf("[AIBudget] 查询模型 %s 单价失败: %v", key, err)
+		return AIModelPrice{}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	list, err := p.GetAvailableModels(ctx)
+	if err != nil {
+		logrus.Warnf("[AIBudget] 查询模型 %s 单价失败: %v", key, err)
+		return AIModelPrice{}
+	}
+	price, ok := matchAIModelPrice(list, model)
+	if !ok || price.IsZero() {
+		logrus.Warnf("[AIBudget] 模型 %s 未公布单价，费用按 0 计算", key)
+		return AIModelPrice{}
+	}
+	aiModelPrices.Store(key, price)
+	return price
+}
+
+// matchAIModelPrice 优先精确匹配模型ID，其次匹配最长的ID前缀（如 gpt-4o-2024-08-06 匹配 gpt-4o）
+func matchAIModelPrice(list []ai_providers.ModelInfo, model string) (AIModelPrice, bool) {
+	best := -1
+	for i, info := range list {
+		if info.ID == model {
+			best = i
+			break
+		}
+		if strings.HasPrefix(model, info.ID) && (best < 0 || len(info.ID) > len(list[best].ID)) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return AIModelPrice{}, false
+	}
+	cost := list[best].Cost
+	return AIModelPrice{InputPer1K: cost.InputTokenPrice, OutputPer1K: cost.OutputTokenPrice}, true
+}
+
+// aiBudgetPeriod 预算周期的起止时间，与使用统计一致按 UTC 划分日期
+func aiBudgetPeriod(period string, now time.Time) (time.Time, time.Time) {
+	now = now.UTC()
+	if period == models.AIBudgetPeriodMonthly {
+		start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(0, 1, 0)
+	}
+	start := now.Truncate(24 * time.Hour)
+	return start, start.Add(24 * time.Hour)
+}
+
+// evaluateAIBudget 根据用量与放行记录计算预算状态；userID 为 0 时只计入对整个范围生效的放行
+func evaluateAIBudget(budget models.AIBudget, tokens int64, cost float64, overrides []models.AIBudgetOverride, userID uint, now time.Time) AIBudgetStatus {
+	start, end := aiBudgetPeriod(budget.Period, now)
+	status := AIBudgetStatus{
+		Budget:      budget,
+		PeriodStart: start,
+		PeriodEnd:   end,
+		TokensUsed:  tokens,
+		Cost:        cost,
+		TokenLimit:  budget.TokenLimit,
+		CostLimit:   budget.CostLimit,
+		State:       models.AIBudgetStateOK,
+	}
+	unlimited := false
+	for _, o := range overrides {
+		if o.BudgetID != budget.ID || !o.ExpiresAt.After(now) || (o.UserID != 0 && o.UserID != userID) {
+			continue
+		}
+		unlimited = unlimited || o.Unlimited
+		if status.TokenLimit > 0 {
+			status.TokenLimit += o.ExtraTokens
+		}
+		if status.CostLimit > 0 {
+			status.CostLimit += o.ExtraCost
+		}
+	}
+
+	if status.TokenLimit > 0 {
+		status.Percent = float64(tokens) * 100 / float64(status.TokenLimit)
+	}
+	if status.CostLimit > 0 {
+		if p := cost * 100 / status.CostLimit; p > status.Percent {
+			status.Percent = p
+		}
+	}
+	status.Percent = float64(int64(status.Percent*10+0.5)) / 10
+
+	warn := budget.WarnPercent
+	if warn <= 0 {
+		warn = defaultAIBudgetWarnPercent
+	}
+	exceeded := (status.TokenLimit > 0 && tokens >= status.TokenLimit) || (status.CostLimit > 0 && cost >= status.CostLimit)
+	switch {
+	case exceeded && unlimited:
+		status.State = models.AIBudgetStateOverridden
+	case exceeded:
+		status.State = models.AIBudgetStateExceeded
+	case status.Percent >= float64(warn):
+		status.State = models.AIBudgetStateWarning
+	}
+	return status
+}
+
+// Check 发送请求前检查用户使用该机器人配置是否超出预算：超额时返回 *AIBudgetExceededError，
+// 否则返回处于预警或已放行状态的预算供调用方提示
+func (s *AIBudgetService) Check(userID uint, config *models.AIAssistantConfig) ([]AIBudgetStatus, error) {
+	statuses, err := s.statusesFor(userID, config.ID)
+	if err != nil {
+		return nil, err
+	}
+	var warnings []AIBudgetStatus
+	for _, status := range statuses {
+		switch status.State {
+		case models.AIBudgetStateExceeded:
+			return nil, &AIBudgetExceededError{Status: status}
+		case models.AIBudgetStateWarning, models.AIBudgetStateOverridden:
+			warnings = append(warnings, status)
+		}
+	}
+	return warnings, nil
+}
+
+// UserStatuses 用户适用的全部预算（本人、所在用户组及各机器人配置）的当前状态
+func (s *AIBudgetService) UserStatuses(userID uint) ([]AIBudgetStatus, error) {
+	return s.statusesFor(userID, 0)
+}
+
+// statusesFor configID 为 0 时包含所有机器人配置的预算
+func (s *AIBudgetService) statusesFor(userID, configID uint) ([]AIBudgetStatus, error) {
+	var groupIDs []uint
+	if err := s.db.Model(&models.UserGroupMembership{}).Where("user_id = ?", userID).Pluck("user_group_id", &groupIDs).Error; err != nil {
+		return nil, err
+	}
+
+	cond := s.db.Where("scope = ? AND scope_id = ?", models.AIBudgetScopeUser, userID)
+	if len(groupIDs) > 0 {
+		cond = cond.Or("scope = ? AND scope_id IN ?", models.AIBudgetScopeGroup, groupIDs)
+	}
+	if configID > 0 {
+		cond = cond.Or("scope = ? AND scope_id = ?", models.AIBudgetScopeConfig, configID)
+	} else {
+		cond = cond.Or("scope = ?", models.AIBudgetScopeConfig)
+	}
+	var budgets []models.AIBudget
+	if err := s.db.Where("enabled = ?", true).Where(cond).Order("id ASC").Find(&budgets).Error; err != nil {
+		return nil, err
+	}
+	return s.evaluate(budgets, userID)
+}
+
+// evaluate 查询各预算当前周期的用量并计算状态
+func (s *AIBudgetService) evaluate(budgets []models.AIBudget, userID uint) ([]AIBudgetStatus, error) {
+	if len(budgets) == 0 {
+		return nil, nil
+	}
+	now := time.Now()
+	ids := make([]uint, len(budgets))
+	for i, budget := range budgets {
+		ids[i] = budget.ID
+	}
+	var overrides []models.AIBudgetOverride
+	if err := s.db.Where("budget_id IN ? AND expires_at > ?", ids, now).Find(&overrides).Error; err != nil {
+		return nil, err
+	}
+
+	statuses := make([]AIBudgetStatus, 0, len(budgets))
+	for _, budget := range budgets {
+		start, _ := aiBudgetPeriod(budget.Period, now)
+		tokens, cost, err := s.scopeUsage(budget, start)
+		if err != nil {
+			return nil, err
+		}
+		status := evaluateAIBudget(budget, tokens, cost, overrides, userID, now)
+		status.ScopeName = s.scopeName(budget.Scope, budget.ScopeID)
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// scopeUsage 预算范围内自 start 起的 token 与费用合计
+func (s *AIBudgetService) scopeUsage(budget models.AIBudget, start time.Time) (int64, float64, error) {
+	query := s.db.Model(&models.AIUsageStats{}).
+		Select("COALESCE(SUM(tokens_used), 0) AS tokens, COALESCE(SUM(cost), 0) AS cost").
+		Where("date >= ?", start)
+	switch budget.Scope {
+	case models.AIBudgetScopeUser:
+		query = query.Where("user_id = ?", budget.ScopeID)
+	case models.AIBudgetScopeGroup:
+		members := s.db.Model(&models.UserGroupMembership{}).Select("user_id").Where("user_group_id = ?", budget.ScopeID)
+		query = query.Where("user_id IN (?)", members)
+	case models.AIBudgetScopeConfig:
+		query = query.Where("config_id = ?", budget.ScopeID)
+	default:
+		return 0, 0, fmt.Errorf("unknown budget scope: %s", budget.Scope)
+	}
+	var row struct {
+		Tokens int64
+		Cost   float64
+	}
+	if err := query.Scan(&row).Error; err != nil {
+		return 0, 0, err
+	}
+	return row.Tokens, row.Cost, nil
+}
+
+func (s *AIBudgetService) scopeName(scope string, id uint) string {
+	var name string
+	switch scope {
+	case models.AIBudgetScopeUser:
+		s.db.Model(&models.User{}).Where("id = ?", id).Pluck("username", &name)
+	case models.AIBudgetScopeGroup:
+		s.db.Model(&models.UserGroup{}).Where("id = ?", id).Pluck("name", &name)
+	case models.AIBudgetScopeConfig:
+		s.db.Model(&models.AIAssistantConfig{}).Where("id = ?", id).Pluck("name", &name)
+	}
+	return name
+}
+
+// ListBudgets 列出全部预算及其当前状态（仅计入对整个范围生效的放行）
+func (s *AIBudgetService) ListBudgets() ([]AIBudgetStatus, error) {
+	var budgets []models.AIBudget
+	if err := s.db.Preload("Overrides", "expires_at > ?", time.Now()).Order("id ASC").Find(&budgets).Error; err != nil {
+		return nil, err
+	}
+	return s.evaluate(budgets, 0)
+}
+
+// CreateBudget 创建预算
+func (s *AIBudgetService) CreateBudget(req *models.AIBudgetRequest, createdBy uint) (*models.AIBudget, error) {
+	budget := &models.AIBudget{CreatedBy: createdBy, Enabled: true}
+	if err := s.applyBudgetRequest(budget, req); err != nil {
+		return nil, err
+	}
+	if err := s.db.Create(budget).Error; err != nil {
+		return nil, err
+	}
+	return budget, nil
+}
+
+// UpdateBudget 更新预算
+func (s *AIBudgetService) UpdateBudget(id uint, req *models.AIBudgetRequest) (*models.AIBudget, error) {
+	var budget models.AIBudget
+	if err := s.db.First(&budget, id).Error; err != nil {
+		return nil, err
+	}
+	if err := s.applyBudgetRequest(&budget, req); err != nil {
+		return nil, err
+	}
+	if err := s.db.Save(&budget).Error; err != nil {
+		return nil, err
+	}
+	return &budget, nil
+}
+
+// DeleteBudget 删除预算及其放行记录
+func (s *AIBudgetService) DeleteBudget(id uint) (*models.AIBudget, error) {
+	var budget models.AIBudget
+	if err := s.db.First(&budget, id).Error; err != nil {
+		return nil, err
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("budget_id = ?", id).Delete(&models.AIBudgetOverride{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&budget).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &budget, nil
+}
+
+func (s *AIBudgetService) applyBudgetRequest(budget *models.AIBudget, req *models.AIBudgetRequest) error {
+	if req.TokenLimit == 0 && req.CostLimit == 0 {
+		return errors.New("token_limit or cost_limit is required")
+	}
+	if s.scopeName(req.Scope, req.ScopeID) == "" {
+		return fmt.Errorf("%s %d not found", req.Scope, req.ScopeID)
+	}
+	if req.CostLimit > 0 {
+		if err := s.checkCostBudgetPriced(req.Scope, req.ScopeID); err != nil {
+			return err
+		}
+	}
+	budget.Name = strings.TrimSpace(req.Name)
+	budget.Scope = req.Scope
+	budget.ScopeID = req.ScopeID
+	budget.Period = req.Period
+	budget.TokenLimit = req.TokenLimit
+	budget.CostLimit = req.CostLimit
+	budget.WarnPercent = req.WarnPercent
+	if budget.WarnPercent == 0 {
+		budget.WarnPercent = defaultAIBudgetWarnPercent
+	}
+	if req.Enabled != nil {
+		budget.Enabled = *req.Enabled
+	}
+	return nil
+}
+
+// checkCostBudgetPriced 费用按模型单价累计，范围内没有任何已定价模型时费用预算永远不会触发，直接拒绝
+// 机器人配置预算只看该配置的模型，用户/用户组预算看全部启用的配置
+func (s *AIBudgetService) checkCostBudgetPriced(scope string, scopeID uint) error {
+	query := s.db.Model(&models.AIAssistantConfig{}).Select("id, provider, model")
+	if scope == models.AIBudgetScopeConfig {
+		query = query.Where("id = ?", scopeID)
+	} else {
+		query = query.Where("is_enabled = ?", true)
+	}
+	var configs []models.AIAssistantConfig
+	if err := query.Find(&configs).Error; err != nil {
+		return err
+	}
+	if !anyAIModelPriced(s.providers, configs) {
+		return errors.New("cost_limit requires a model with published pricing; use token_limit for unpriced models")
+	}
+	return nil
+}
+
+// anyAIModelPriced 配置列表中是否有模型查得到非零单价
+func anyAIModelPriced(factory ai_providers.ProviderFactory, configs []models.AIAssistantConfig) bool {
+	for _, config := range configs {
+		if !lookupAIModelPrice(factory, config.Provider, config.Model).IsZero() {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateOverride 管理员放行：追加额度或在有效期内不限制；未指定有效期时到当前周期结束
+func (s *AIBudgetService) CreateOverride(budgetID uint, req *models.AIBudgetOverrideRequest, createdBy uint) (*models.AIBudgetOverride, error) {
+	var budget models.AIBudget
+	if err := s.db.First(&budget, budgetID).Error; err != nil {
+		return nil, err
+	}
+	if !req.Unlimited && req.ExtraTokens == 0 && req.ExtraCost == 0 {
+		return nil, errors.New("extra_tokens, extra_cost or unlimited is required")
+	}
+	now := time.Now()
+	_, expiresAt := aiBudgetPeriod(budget.Period, now)
+	if req.DurationHours > 0 {
+		expiresAt = now.Add(time.Duration(req.DurationHours) * time.Hour)
+	}
+	override := &models.AIBudgetOverride{
+		BudgetID:    budgetID,
+		UserID:      req.UserID,
+		ExtraTokens: req.ExtraTokens,
+		ExtraCost:   req.ExtraCost,
+		Unlimited:   req.Unlimited,
+		Reason:      strings.TrimSpace(req.Reason),
+		ExpiresAt:   expiresAt,
+		CreatedBy:   createdBy,
+	}
+	if err := s.db.Create(override).Error; err != nil {
+		return nil, err
+	}
+	return override, nil
+}
+
+// DeleteOverride 撤销放行
+func (s *AIBudgetService) DeleteOverride(budgetID, overrideID uint) error {
+	result := s.db.Where("id = ? AND budget_id = ?", overrideID, budgetID).Delete(&models.AIBudgetOverride{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// UsageReport 统计 [from, to) 内的 token 用量与费用，按模型、用户组（团队）和用户分组
+func (s *AIBudgetService) UsageReport(from, to time.Time) (*AIUsageReport, error) {
+	const sums = "COALESCE(SUM(s.request_count), 0) AS requests, COALESCE(SUM(s.tokens_used), 0) AS tokens, " +
+		"COALESCE(SUM(s.input_tokens), 0) AS input_tokens, COALESCE(SUM(s.output_tokens), 0) AS output_tokens, " +
+		"COALESCE(SUM(s.cost), 0) AS cost"
+	stats := func() *gorm.DB {
+		return s.db.Table("ai_usage_stats AS s").Where("s.date >= ? AND s.date < ?", from, to)
+	}
+
+	report := &AIUsageReport{From: from, To: to, ByModel: []AIUsageBreakdown{}, ByTeam: []AIUsageBreakdown{}, ByUser: []AIUsageBreakdown{}}
+	if err := stats().Select(sums).Scan(&report.Total).Error; err != nil {
+		return nil, err
+	}
+	report.Total.Name = "total"
+
+	if err := stats().Select("COALESCE(c.provider, '') AS provider, COALESCE(c.model, '') AS name, " + sums).
+		Joins("LEFT JOIN ai_assistant_configs c ON c.id = s.config_id").
+		Group("c.provider, c.model").Order("cost DESC, tokens DESC").
+		Scan(&report.ByModel).Error; err != nil {
+		return nil, err
+	}
+	for i := range report.ByModel {
+		if report.ByModel[i].Name == "" {
+			report.ByModel[i].Name = "unknown"
+		}
+	}
+
+	if err := stats().Select("g.id AS id, g.name AS name, " + sums).
+		Joins("JOIN user_group_memberships m ON m.user_id = s.user_id").
+		Joins("JOIN user_groups g ON g.id = m.user_group_id AND g.deleted_at IS NULL").
+		Group("g.id, g.name").Order("cost DESC, tokens DESC").
+		Scan(&report.ByTeam).Error; err != nil {
+		return nil, err
+	}
+	var ungrouped AIUsageBreakdown
+	if err := stats().Select(sums).
+		Where("s.user_id NOT IN (?)", s.db.Model(&models.UserGroupMembership{}).Select("user_id")).
+		Scan(&ungrouped).Error; err != nil {
+		return nil, err
+	}
+	if ungrouped.Requests > 0 {
+		ungrouped.Name = "未分组"
+		report.ByTeam = append(report.ByTeam, ungrouped)
+		sort.SliceStable(report.ByTeam, func(i, j int) bool { return report.ByTeam[i].Cost > report.ByTeam[j].Cost })
+	}
+
+	if err := stats().Select("s.user_id AS id, COALESCE(u.username, '') AS name, " + sums).
+		Joins("LEFT JOIN users u ON u.id = s.user_id").
+		Group("s.user_id, u.username").Order("cost DESC, tokens DESC").
+		Scan(&report.ByUser).Error; err != nil {
+		return nil, err
+	}
+	return report, nil
+}
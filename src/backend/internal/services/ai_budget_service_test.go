@@ -0,0 +1,181 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/services/ai_providers"
+)
+
+func TestEvaluateAIBudget(t *testing.T) {
+	now := time.Date(2026, 10, 18, 15, 30, 0, 0, time.UTC)
+	budget := models.AIBudget{ID: 1, Scope: models.AIBudgetScopeGroup, Period: models.AIBudgetPeriodDaily, TokenLimit: 1000, CostLimit: 2}
+
+	if s := evaluateAIBudget(budget, 100, 0.1, nil, 7, now); s.State != models.AIBudgetStateOK || s.Percent != 10 {
+		t.Fatalf("低用量应为 ok: %+v", s)
+	}
+	if s := evaluateAIBudget(budget, 100, 1.7, nil, 7, now); s.State != models.AIBudgetStateWarning || s.Percent != 85 {
+		t.Fatalf("费用达到默认预警比例应提示: %+v", s)
+	}
+	if s := evaluateAIBudget(budget, 1000, 0, nil, 7, now); s.State != models.AIBudgetStateExceeded {
+		t.Fatalf("达到 token 限额应拦截: %+v", s)
+	}
+	if s := evaluateAIBudget(budget, 0, 2.5, nil, 7, now); s.State != models.AIBudgetStateExceeded {
+		t.Fatalf("达到费用限额应拦截: %+v", s)
+	}
+
+	overrides := []models.AIBudgetOverride{
+		{BudgetID: 1, UserID: 7, ExtraTokens: 500, ExpiresAt: now.Add(time.Hour)},
+		{BudgetID: 1, UserID: 8, Unlimited: true, ExpiresAt: now.Add(time.Hour)},
+		{BudgetID: 1, Unlimited: true, ExpiresAt: now.Add(-time.Minute)},
+		{BudgetID: 2, Unlimited: true, ExpiresAt: now.Add(time.Hour)},
+	}
+	if s := evaluateAIBudget(budget, 1100, 0, overrides, 7, now); s.State != models.AIBudgetStateOK || s.TokenLimit != 1500 {
+		t.Fatalf("追加额度应提高有效限额: %+v", s)
+	}
+	if s := evaluateAIBudget(budget, 1200, 0, overrides, 8, now); s.State != models.AIBudgetStateOverridden {
+		t.Fatalf("不限制放行应允许继续使用: %+v", s)
+	}
+	if s := evaluateAIBudget(budget, 1200, 0, overrides, 9, now); s.State != models.AIBudgetStateExceeded || s.TokenLimit != 1000 {
+		t.Fatalf("其他用户、过期或其他预算的放行不应生效: %+v", s)
+	}
+	if s := evaluateAIBudget(budget, 1200, 0, overrides, 0, now); s.State != models.AIBudgetStateExceeded {
+		t.Fatalf("按范围查看时只计入整体放行: %+v", s)
+	}
+
+	tokenOnly := models.AIBudget{ID: 3, Period: models.AIBudgetPeriodMonthly, TokenLimit: 1000, WarnPercent: 95}
+	if s := evaluateAIBudget(tokenOnly, 900, 1000, nil, 7, now); s.State != models.AIBudgetStateOK || s.CostLimit != 0 {
+		t.Fatalf("未设置费用限额时不应按费用拦截: %+v", s)
+	}
+}
+
+func TestAIBudgetPeriod(t *testing.T) {
+	now := time.Date(2026, 2, 14, 23, 30, 0, 0, time.FixedZone("CST", 8*3600))
+
+	start, end := aiBudgetPeriod(models.AIBudgetPeriodDaily, now)
+	if !start.Equal(time.Date(2026, 2, 14, 0, 0, 0, 0, time.UTC)) || end.Sub(start) != 24*time.Hour {
+		t.Fatalf("日预算应按 UTC 日期划分: %v ~ %v", start, end)
+	}
+	if !start.Equal(now.Truncate(24 * time.Hour)) {
+		t.Fatal("日预算起点应与使用统计的日期一致")
+	}
+
+	start, end = aiBudgetPeriod(models.AIBudgetPeriodMonthly, now)
+	if !start.Equal(time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)) || !end.Equal(time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("月预算应覆盖自然月: %v ~ %v", start, end)
+	}
+}
+
+func TestAIModelPrice(t *testing.T) {
+	var list []ai_providers.ModelInfo
+	for _, m := range []struct {
+		id          string
+		input, outp float64
+	}{{"gpt-4o", 0.005, 0.015}, {"gpt-4o-mini", 0.00015, 0.0006}} {
+		info := ai_providers.ModelInfo{ID: m.id}
+		info.Cost.InputTokenPrice, info.Cost.OutputTokenPrice = m.input, m.outp
+		list = append(list, info)
+	}
+
+	if price, ok := matchAIModelPrice(list, "gpt-4o-mini"); !ok || price.InputPer1K != 0.00015 {
+		t.Fatalf("应精确匹配: %+v", price)
+	}
+	if price, ok := matchAIModelPrice(list, "gpt-4o-mini-2024-07-18"); !ok || price.OutputPer1K != 0.0006 {
+		t.Fatalf("应匹配最长前缀: %+v", price)
+	}
+	if _, ok := matchAIModelPrice(list, "deepseek-chat"); ok {
+		t.Fatal("未知模型不应匹配")
+	}
+
+	price := AIModelPrice{InputPer1K: 0.005, OutputPer1K: 0.015}
+	if got := price.Cost(1000, 2000, 3000); math.Abs(got-0.035) > 1e-9 {
+		t.Fatalf("按输入/输出分别计费: %v", got)
+	}
+	if got := price.Cost(0, 0, 2000); math.Abs(got-0.02) > 1e-9 {
+		t.Fatalf("未区分输入/输出时按平均单价计费: %v", got)
+	}
+}
+
+// priceListProvider 按次返回预设模型列表的提供商，记录查询次数
+type priceListProvider struct {
+	ai_providers.AIProvider
+	lists [][]ai_providers.ModelInfo
+	calls int
+}
+
+func (p *priceListProvider) GetAvailableModels(ctx context.Context) ([]ai_providers.ModelInfo, error) {
+	p.calls++
+	if len(p.lists) == 0 {
+		return nil, errors.New("unavailable")
+	}
+	list := p.lists[0]
+	p.lists = p.lists[1:]
+	return list, nil
+}
+
+func (p *priceListProvider) CreateProvider(*models.AIAssistantConfig) (ai_providers.AIProvider, error) {
+	return p, nil
+}
+
+func (p *priceListProvider) GetSupportedProviders() []string { return nil }
+
+func TestLookupAIModelPrice(t *testing.T) {
+	priced := ai_providers.ModelInfo{ID: "price-test-model"}
+	priced.Cost.InputTokenPrice = 0.001
+	p := &priceListProvider{lists: [][]ai_providers.ModelInfo{{{ID: "price-test-model"}}, {priced}}}
+	defer aiModelPrices.Delete("openai/price-test-model")
+
+	if price := lookupAIModelPrice(p, models.ProviderOpenAI, "price-test-model"); !price.IsZero() {
+		t.Fatalf("未公布单价时应按 0 计费: %+v", price)
+	}
+	if price := lookupAIModelPrice(p, models.ProviderOpenAI, "price-test-model"); price.InputPer1K != 0.001 {
+		t.Fatalf("零单价不应被缓存，应重新查询: %+v", price)
+	}
+	if price := lookupAIModelPrice(p, models.ProviderOpenAI, "price-test-model"); price.InputPer1K != 0.001 || p.calls != 2 {
+		t.Fatalf("查到的单价应被缓存: %+v, calls=%d", price, p.calls)
+	}
+
+	failing := &priceListProvider{}
+	defer aiModelPrices.Delete("openai/price-test-missing")
+	lookupAIModelPrice(failing, models.ProviderOpenAI, "price-test-missing")
+	lookupAIModelPrice(failing, models.ProviderOpenAI, "price-test-missing")
+	if failing.calls != 2 {
+		t.Fatalf("查询失败不应被缓存: calls=%d", failing.calls)
+	}
+}
+
+func TestAIBudgetExceededError(t *testing.T) {
+	err := error(&AIBudgetExceededError{Status: AIBudgetStatus{Budget: models.AIBudget{Scope: models.AIBudgetScopeUser, Period: models.AIBudgetPeriodDaily}, ScopeName: "alice"}})
+	if !errors.Is(err, ErrAIBudgetExceeded) {
+		t.Fatal("应能以 ErrAIBudgetExceeded 判断")
+	}
+	var exceeded *AIBudgetExceededError
+	if !errors.As(err, &exceeded) || exceeded.Status.ScopeName != "alice" {
+		t.Fatal("应能取出触发的预算")
+	}
+}
+
+func TestAnyAIModelPriced(t *testing.T) {
+	priced := ai_providers.ModelInfo{ID: "price-test-priced"}
+	priced.Cost.OutputTokenPrice = 0.002
+	p := &priceListProvider{lists: [][]ai_providers.ModelInfo{{{ID: "price-test-free"}}, {priced}}}
+	defer aiModelPrices.Delete("openai/price-test-priced")
+
+	configs := []models.AIAssistantConfig{
+		{Provider: models.ProviderOpenAI, Model: "price-test-free"},
+		{Provider: models.ProviderOpenAI, Model: "price-test-priced"},
+	}
+	if !anyAIModelPriced(p, configs) {
+		t.Fatal("存在已定价模型时应允许费用预算")
+	}
+	if anyAIModelPriced(&priceListProvider{}, configs[:1]) {
+		t.Fatal("没有已定价模型时应拒绝费用预算")
+	}
+	if anyAIModelPriced(p, nil) {
+		t.Fatal("范围内没有配置时应拒绝费用预算")
+	}
+}
@@ -0,0 +1,405 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
+)
+
+const (
+	// MCP 会话空闲超过该时长后关闭
+	mcpSessionIdleTimeout = 10 * time.Minute
+	// 工具名最大长度（OpenAI / Claude 函数名限制）
+	mcpToolNameLimit = 64
+	// 资源、提示词伪工具描述中列出的最大条目数
+	mcpListingLimit = 20
+
+	mcpReadResourceTool = "read_resource"
+	mcpGetPromptTool    = "get_prompt"
+)
+
+var mcpToolNameSanitizer = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// mcpSession 已连接的 MCP 服务器及其工具、资源、提示词列表
+type mcpSession struct {
+	client      *MCPClient
+	fingerprint string
+	tools       []MCPToolInfo
+	resources   []MCPResourceInfo
+	prompts     []MCPPromptInfo
+	lastUsed    time.Time
+}
+
+// MCPService 按助手配置管理 MCP 服务器会话，并把允许的工具转换为 AI 助手工具
+type MCPService struct {
+	mu       sync.Mutex
+	sessions map[string]*mcpSession
+}
+
+var (
+	mcpServiceInstance *MCPService
+	mcpServiceOnce     sync.Once
+)
+
+// NewMCPService 创建 MCP 服务（单例）
+func NewMCPService() *MCPService {
+	mcpServiceOnce.Do(func() {
+		mcpServiceInstance = &MCPService{sessions: make(map[string]*mcpSession)}
+	})
+	return mcpServiceInstance
+}
+
+// GetMCPService 获取 MCP 服务实例
+func GetMCPService() *MCPService {
+	return NewMCPService()
+}
+
+// MCPServerStatus 配置中一个 MCP 服务器的探测结果
+type MCPServerStatus struct {
+	Name      string            `json:"name"`
+	Transport string            `json:"transport"`
+	Connected bool              `json:"connected"`
+	Error     string            `json:"error,omitempty"`
+	Server    MCPServerInfo     `json:"server_info"`
+	Tools     []MCPToolStatus   `json:"tools"`
+	Resources []MCPResourceInfo `json:"resources"`
+	Prompts   []MCPPromptInfo   `json:"prompts"`
+	Exposed   []string          `json:"exposed_tools"` // 实际提供给模型的工具名
+	Allowed   map[string]bool   `json:"allowed"`       // read_resource / get_prompt 是否允许
+}
+
+// MCPToolStatus 服务器工具及其在本配置中的可用性
+type MCPToolStatus struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	ReadOnly    bool   `json:"read_only"`
+	Allowed     bool   `json:"allowed"`
+	ExposedAs   string `json:"exposed_as,omitempty"`
+}
+
+// ToolsForConfig 返回配置允许调用的 MCP 工具；连接失败的服务器记录日志后跳过，不影响对话
+func (s *MCPService) ToolsForConfig(ctx context.Context, config *models.AIAssistantConfig) []*AITool {
+	if config == nil || config.MCPConfig == nil || len(config.MCPConfig.AllowedTools) == 0 {
+		return nil
+	}
+	allowed := config.MCPConfig.AllowedTools
+	var tools []*AITool
+	seen := make(map[string]bool)
+	add := func(tool *AITool) {
+		if !seen[tool.Name] {
+			seen[tool.Name] = true
+			tools = append(tools, tool)
+		}
+	}
+
+	for _, server := range config.MCPConfig.EffectiveServers() {
+		if !mcpServerMentioned(allowed, server.Name) {
+			continue
+		}
+		session, err := s.session(ctx, config.ID, server, false)
+		if err != nil {
+			log.Printf("[MCP] 配置 %d 连接服务器 %s 失败: %v", config.ID, server.Name, err)
+			continue
+		}
+		for _, info := range session.tools {
+			if mcpToolAllowed(allowed, server.Name, info.Name) {
+				add(s.remoteTool(config.ID, server, info))
+			}
+		}
+		if len(session.resources) > 0 && mcpToolAllowed(allowed, server.Name, mcpReadResourceTool) {
+			add(s.readResourceTool(config.ID, server, session.resources))
+		}
+		if len(session.prompts) > 0 && mcpToolAllowed(allowed, server.Name, mcpGetPromptTool) {
+			add(s.getPromptTool(config.ID, server, session.prompts))
+		}
+	}
+	return tools
+}
+
+// Discover 连接配置中的全部 MCP 服务器并重新获取工具、资源与提示词列表
+func (s *MCPService) Discover(ctx context.Context, config *models.AIAssistantConfig) []MCPServerStatus {
+	if config == nil || config.MCPConfig == nil {
+		return nil
+	}
+	allowed := config.MCPConfig.AllowedTools
+	var statuses []MCPServerStatus
+	for _, server := range config.MCPConfig.EffectiveServers() {
+		status := MCPServerStatus{Name: server.Name, Transport: server.Transport, Allowed: map[string]bool{}}
+		session, err := s.session(ctx, config.ID, server, true)
+		if err != nil {
+			status.Error = err.Error()
+			statuses = append(statuses, status)
+			continue
+		}
+		status.Connected = true
+		status.Server = session.client.Info
+		status.Resources = session.resources
+		status.Prompts = session.prompts
+		for _, info := range session.tools {
+			tool := MCPToolStatus{
+				Name:        info.Name,
+				Description: info.Description,
+				ReadOnly:    info.ReadOnly(),
+				Allowed:     mcpToolAllowed(allowed, server.Name, info.Name),
+			}
+			if tool.Allowed {
+				tool.ExposedAs = mcpToolName(server.Name, info.Name)
+				status.Exposed = append(status.Exposed, tool.ExposedAs)
+			}
+			status.Tools = append(status.Tools, tool)
+		}
+		for _, name := range []string{mcpReadResourceTool, mcpGetPromptTool} {
+			status.Allowed[name] = mcpToolAllowed(allowed, server.Name, name)
+		}
+		if len(session.resources) > 0 && status.Allowed[mcpReadResourceTool] {
+			status.Exposed = append(status.Exposed, mcpToolName(server.Name, mcpReadResourceTool))
+		}
+		if len(session.prompts) > 0 && status.Allowed[mcpGetPromptTool] {
+			status.Exposed = append(status.Exposed, mcpToolName(server.Name, mcpGetPromptTool))
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// session 获取或建立到服务器的会话；服务器配置变化或 refresh 时重新连接并获取列表
+func (s *MCPService) session(ctx context.Context, configID uint, server models.MCPServerConfig, refresh bool) (*mcpSession, error) {
+	key := fmt.Sprintf("%d/%s", configID, server.Name)
+	fingerprint, _ := json.Marshal(server)
+
+	s.mu.Lock()
+	s.reapLocked()
+	session, ok := s.sessions[key]
+	if ok && (refresh || session.fingerprint != string(fingerprint)) {
+		delete(s.sessions, key)
+		go session.client.Close()
+		ok = false
+	}
+	if ok {
+		session.lastUsed = time.Now()
+		s.mu.Unlock()
+		return session, nil
+	}
+	s.mu.Unlock()
+
+	client, err := DialMCPServer(ctx, server)
+	if err != nil {
+		return nil, err
+	}
+	session = &mcpSession{client: client, fingerprint: string(fingerprint), lastUsed: time.Now()}
+	if session.tools, err = client.ListTools(ctx); err == nil {
+		if session.resources, err = client.ListResources(ctx); err == nil {
+			session.prompts, err = client.ListPrompts(ctx)
+		}
+	}
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("list mcp server %s: %v", server.Name, err)
+	}
+
+	s.mu.Lock()
+	if existing, ok := s.sessions[key]; ok {
+		// 并发建立的会话，保留先完成的一个
+		s.mu.Unlock()
+		client.Close()
+		return existing, nil
+	}
+	s.sessions[key] = session
+	s.mu.Unlock()
+	return session, nil
+}
+
+// drop 关闭出错的会话，下次调用时重新连接
+func (s *MCPService) drop(configID uint, server string, session *mcpSession) {
+	key := fmt.Sprintf("%d/%s", configID, server)
+	s.mu.Lock()
+	if s.sessions[key] == session {
+		delete(s.sessions, key)
+	}
+	s.mu.Unlock()
+	session.client.Close()
+}
+
+func (s *MCPService) reapLocked() {
+	for key, session := range s.sessions {
+		if time.Since(session.lastUsed) > mcpSessionIdleTimeout {
+			delete(s.sessions, key)
+			go session.client.Close()
+		}
+	}
+}
+
+// withSession 在服务器会话上执行调用；传输层错误（非服务器返回的错误）时丢弃会话
+func (s *MCPService) withSession(ctx context.Context, configID uint, server models.MCPServerConfig, fn func(*MCPClient) (interface{}, error)) (interface{}, error) {
+	session, err := s.session(ctx, configID, server, false)
+	if err != nil {
+		return nil, err
+	}
+	result, err := fn(session.client)
+	var rpcErr *mcpError
+	if err != nil && !errors.As(err, &rpcErr) {
+		s.drop(configID, server.Name, session)
+	}
+	return result, err
+}
+
+// remoteTool 将 MCP 服务器工具转换为 AI 助手工具；未声明只读的工具按变更类处理，需用户确认
+func (s *MCPService) remoteTool(configID uint, server models.MCPServerConfig, info MCPToolInfo) *AITool {
+	parameters := info.InputSchema
+	if parameters == nil {
+		parameters = aiToolSchema(map[string]interface{}{})
+	}
+	description := info.Description
+	if description == "" {
+		description = info.Title
+	}
+	name := info.Name
+	return &AITool{
+		Name:        mcpToolName(server.Name, name),
+		Description: fmt.Sprintf("[MCP %s] %s", server.Name, description),
+		Parameters:  parameters,
+		Mutating:    !info.ReadOnly(),
+		Category:    models.AuditCategorySystem,
+		Source:      "mcp:" + server.Name,
+		// 可调用的 MCP 工具由配置的 AllowedTools 决定，确认执行时按配置重新解析
+		Authorize: func(ctx context.Context, caller AIToolCaller, args json.RawMessage) error { return nil },
+		Run: func(ctx context.Context, caller AIToolCaller, args json.RawMessage) (interface{}, error) {
+			result, err := s.withSession(ctx, configID, server, func(client *MCPClient) (interface{}, error) {
+				return client.CallTool(ctx, name, args)
+			})
+			if err != nil {
+				return nil, err
+			}
+			toolResult := result.(*MCPToolResult)
+			if toolResult.IsError {
+				return nil, errors.New(toolResult.Text())
+			}
+			return map[string]interface{}{"content": toolResult.Text()}, nil
+		},
+		Summarize: func(args json.RawMessage) string {
+			return fmt.Sprintf("调用 MCP 服务器 %s 的工具 %s，参数：%s", server.Name, name, truncateText(string(args), 500))
+		},
+	}
+}
+
+func (s *MCPService) readResourceTool(configID uint, server models.MCPServerConfig, resources []MCPResourceInfo) *AITool {
+	var listing []string
+	for i, r := range resources {
+		if i == mcpListingLimit {
+			listing = append(listing, "...")
+			break
+		}
+		listing = append(listing, fmt.Sprintf("%s (%s)", r.URI, firstNonEmpty(r.Description, r.Name)))
+	}
+	return &AITool{
+		Name:        mcpToolName(server.Name, mcpReadResourceTool),
+		Description: fmt.Sprintf("[MCP %s] 读取资源内容。可用资源：%s", server.Name, strings.Join(listing, "; ")),
+		Parameters: aiToolSchema(map[string]interface{}{
+			"uri": map[string]interface{}{"type": "string", "description": "资源 URI"},
+		}, "uri"),
+		Category:  models.AuditCategorySystem,
+		Source:    "mcp:" + server.Name,
+		Authorize: func(ctx context.Context, caller AIToolCaller, args json.RawMessage) error { return nil },
+		Run: func(ctx context.Context, caller AIToolCaller, args json.RawMessage) (interface{}, error) {
+			var params struct {
+				URI string `json:"uri"`
+			}
+			if err := json.Unmarshal(args, &params); err != nil || params.URI == "" {
+				return nil, errors.New("uri is required")
+			}
+			return s.withSession(ctx, configID, server, func(client *MCPClient) (interface{}, error) {
+				contents, err := client.ReadResource(ctx, params.URI)
+				if err != nil {
+					return nil, err
+				}
+				return map[string]interface{}{"contents": contents}, nil
+			})
+		},
+	}
+}
+
+func (s *MCPService) getPromptTool(configID uint, server models.MCPServerConfig, prompts []MCPPromptInfo) *AITool {
+	var listing []string
+	for i, p := range prompts {
+		if i == mcpListingLimit {
+			listing = append(listing, "...")
+			break
+		}
+		args := make([]string, 0, len(p.Arguments))
+		for _, a := range p.Arguments {
+			args = append(args, a.Name)
+		}
+		listing = append(listing, fmt.Sprintf("%s(%s)", p.Name, strings.Join(args, ", ")))
+	}
+	return &AITool{
+		Name:        mcpToolName(server.Name, mcpGetPromptTool),
+		Description: fmt.Sprintf("[MCP %s] 获取提示词模板内容。可用模板：%s", server.Name, strings.Join(listing, "; ")),
+		Parameters: aiToolSchema(map[string]interface{}{
+			"name": map[string]interface{}{"type": "string", "description": "模板名称"},
+			"arguments": map[string]interface{}{
+				"type":                 "object",
+				"description":          "模板参数",
+				"additionalProperties": map[string]interface{}{"type": "string"},
+			},
+		}, "name"),
+		Category:  models.AuditCategorySystem,
+		Source:    "mcp:" + server.Name,
+		Authorize: func(ctx context.Context, caller AIToolCaller, args json.RawMessage) error { return nil },
+		Run: func(ctx context.Context, caller AIToolCaller, args json.RawMessage) (interface{}, error) {
+			var params struct {
+				Name      string            `json:"name"`
+				Arguments map[string]string `json:"arguments"`
+			}
+			if err := json.Unmarshal(args, &params); err != nil || params.Name == "" {
+				return nil, errors.New("name is required")
+			}
+			return s.withSession(ctx, configID, server, func(client *MCPClient) (interface{}, error) {
+				text, err := client.GetPrompt(ctx, params.Name, params.Arguments)
+				if err != nil {
+					return nil, err
+				}
+				return map[string]interface{}{"prompt": text}, nil
+			})
+		},
+	}
+}
+
+// mcpToolName 生成提供给模型的工具名 mcp__服务器__工具，仅含模型接受的字符并限制长度
+func mcpToolName(server, tool string) string {
+	name := "mcp__" + mcpToolNameSanitizer.ReplaceAllString(server, "_") + "__" + mcpToolNameSanitizer.ReplaceAllString(tool, "_")
+	if len(name) > mcpToolNameLimit {
+		name = name[:mcpToolNameLimit]
+	}
+	return name
+}
+
+// mcpToolAllowed 判断 "服务器/工具" 是否匹配允许列表中的任一模式
+func mcpToolAllowed(allowed []string, server, tool string) bool {
+	target := server + "/" + tool
+	for _, pattern := range allowed {
+		if ok, err := path.Match(pattern, target); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+// mcpServerMentioned 允许列表中是否有可能匹配该服务器的模式，避免连接未授权任何工具的服务器
+func mcpServerMentioned(allowed []string, server string) bool {
+	for _, pattern := range allowed {
+		prefix, _, _ := strings.Cut(pattern, "/")
+		if ok, err := path.Match(prefix, server); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
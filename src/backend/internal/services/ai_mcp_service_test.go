@@ -0,0 +1,235 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
+)
+
+// newTestMCPServer 模拟 Streamable HTTP MCP 服务器：tools/call 以 SSE 返回，其余以 JSON 返回
+func newTestMCPServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var req struct {
+			ID     *int64          `json:"id"`
+			Method string          `json:"method"`
+			Params json.RawMessage `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if req.Method != "initialize" && r.Header.Get("Mcp-Session-Id") != "sess-1" {
+			t.Errorf("%s 未携带会话ID", req.Method)
+		}
+		if req.ID == nil {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+
+		var result interface{}
+		switch req.Method {
+		case "initialize":
+			w.Header().Set("Mcp-Session-Id", "sess-1")
+			result = map[string]interface{}{
+				"protocolVersion": mcpProtocolVersion,
+				"capabilities":    map[string]interface{}{"tools": map[string]interface{}{}, "resources": map[string]interface{}{}},
+				"serverInfo":      map[string]string{"name": "test", "version": "0.1"},
+			}
+		case "tools/list":
+			result = map[string]interface{}{"tools": []map[string]interface{}{
+				{"name": "echo", "description": "回显", "inputSchema": map[string]interface{}{"type": "object"}, "annotations": map[string]bool{"readOnlyHint": true}},
+				{"name": "delete.all", "description": "删除全部"},
+			}}
+		case "resources/list":
+			result = map[string]interface{}{"resources": []map[string]string{{"uri": "file:///readme", "name": "readme"}}}
+		case "tools/call":
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprintf(w, "data: {\"jsonrpc\":\"2.0\",\"method\":\"notifications/progress\"}\n\n")
+			fmt.Fprintf(w, "event: message\ndata: {\"jsonrpc\":\"2.0\",\"id\":%d,\"result\":{\"content\":[{\"type\":\"text\",\"text\":%q}]}}\n\n", *req.ID, string(req.Params))
+			return
+		default:
+			json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": *req.ID, "error": map[string]interface{}{"code": -32601, "message": "not found"}})
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": *req.ID, "result": result})
+	}))
+}
+
+func TestMCPToolsForConfig(t *testing.T) {
+	t.Setenv("MCP_ALLOWED_HOSTS", "127.0.0.1")
+	server := newTestMCPServer(t)
+	defer server.Close()
+
+	s := &MCPService{sessions: make(map[string]*mcpSession)}
+	config := &models.AIAssistantConfig{ID: 7, MCPConfig: &models.MCPConfig{
+		Servers: []models.MCPServerConfig{
+			{Name: "srv", Transport: models.MCPTransportHTTP, URL: server.URL, Headers: map[string]string{"Authorization": "Bearer secret"}},
+			{Name: "other", Transport: models.MCPTransportHTTP, URL: "http://127.0.0.1:1"},
+		},
+		AllowedTools: []string{"srv/echo", "srv/read_resource"},
+	}}
+	defer func() {
+		for _, session := range s.sessions {
+			session.client.Close()
+		}
+	}()
+
+	tools := s.ToolsForConfig(context.Background(), config)
+	if len(tools) != 2 || tools[0].Name != "mcp__srv__echo" || tools[1].Name != "mcp__srv__read_resource" {
+		t.Fatalf("应仅提供允许列表中的工具: %+v", tools)
+	}
+	if tools[0].Mutating || tools[0].Source != "mcp:srv" {
+		t.Fatalf("声明只读的工具不应需要确认: %+v", tools[0])
+	}
+	if _, ok := s.sessions["7/other"]; ok {
+		t.Fatal("不应连接未授权任何工具的服务器")
+	}
+
+	result, err := tools[0].Run(context.Background(), AIToolCaller{}, json.RawMessage(`{"text":"hi"}`))
+	if err != nil {
+		t.Fatalf("call tool failed: %v", err)
+	}
+	if content := result.(map[string]interface{})["content"]; !strings.Contains(content.(string), `"text":"hi"`) {
+		t.Fatalf("应返回 SSE 响应中的工具结果: %v", content)
+	}
+
+	config.MCPConfig.AllowedTools = []string{"srv/*"}
+	tools = s.ToolsForConfig(context.Background(), config)
+	if len(tools) != 3 || tools[1].Name != "mcp__srv__delete_all" || !tools[1].Mutating {
+		t.Fatalf("未声明只读的工具应按变更类处理: %+v", tools)
+	}
+}
+
+func TestMCPHTTPTransportRechecksRedirects(t *testing.T) {
+	t.Setenv("MCP_ALLOWED_HOSTS", "127.0.0.1")
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("不应跟随重定向访问未允许的主机")
+	}))
+	defer internal.Close()
+	redirect := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, strings.Replace(internal.URL, "127.0.0.1", "localhost", 1), http.StatusTemporaryRedirect)
+	}))
+	defer redirect.Close()
+
+	transport, err := newMCPHTTPTransport(models.MCPServerConfig{Name: "srv", URL: redirect.URL}, 0)
+	if err != nil {
+		t.Fatalf("new transport: %v", err)
+	}
+	if _, err := transport.client.Get(redirect.URL); err == nil || !strings.Contains(err.Error(), "redirect rejected") {
+		t.Fatalf("重定向到未允许的主机应被拒绝: %v", err)
+	}
+}
+
+func TestValidateMCPConfig(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("MCP_SERVERS_DIR", dir)
+	t.Setenv("MCP_ALLOWED_HOSTS", "mcp.internal:8080, *.example.com")
+	command := filepath.Join(dir, "server")
+	if err := os.WriteFile(command, nil, 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	valid := &models.MCPConfig{Servers: []models.MCPServerConfig{
+		{Name: "local", Transport: models.MCPTransportStdio, Command: command, Env: map[string]string{"API_TOKEN": "x"}},
+		{Name: "remote", Transport: models.MCPTransportHTTP, URL: "https://tools.example.com/mcp"},
+		{Name: "port", Transport: models.MCPTransportHTTP, URL: "http://mcp.internal:8080/mcp"},
+		{Name: "off", Transport: models.MCPTransportHTTP, URL: "http://169.254.169.254/", Disabled: true},
+	}}
+	if err := ValidateMCPConfig(valid); err != nil {
+		t.Fatalf("合法配置应通过: %v", err)
+	}
+
+	for name, server := range map[string]models.MCPServerConfig{
+		"目录外程序":      {Transport: models.MCPTransportStdio, Command: "/bin/sh"},
+		"LD_PRELOAD": {Transport: models.MCPTransportStdio, Command: command, Env: map[string]string{"LD_PRELOAD": "/tmp/x.so"}},
+		"PYTHONPATH": {Transport: models.MCPTransportStdio, Command: command, Env: map[string]string{"pythonpath": "/tmp"}},
+		"PATH":       {Transport: models.MCPTransportStdio, Command: command, Env: map[string]string{"PATH": "/tmp"}},
+		"非法变量名":      {Transport: models.MCPTransportStdio, Command: command, Env: map[string]string{"A=B": "x"}},
+		"未授权主机":      {Transport: models.MCPTransportHTTP, URL: "http://169.254.169.254/latest"},
+		"端口不符":       {Transport: models.MCPTransportHTTP, URL: "http://mcp.internal:9090/mcp"},
+		"通配不含根域名":    {Transport: models.MCPTransportHTTP, URL: "https://example.com/mcp"},
+		"非HTTP协议":    {Transport: models.MCPTransportHTTP, URL: "file:///etc/passwd"},
+		"未知传输方式":     {Transport: "ws", URL: "ws://mcp.internal:8080"},
+	} {
+		server.Name = name
+		if err := ValidateMCPConfig(&models.MCPConfig{Servers: []models.MCPServerConfig{server}}); err == nil {
+			t.Errorf("%s 应被拒绝", name)
+		}
+	}
+	if err := ValidateMCPConfig(&models.MCPConfig{ServerURL: "http://10.0.0.1/mcp"}); err == nil {
+		t.Error("兼容的 ServerURL 也应检查允许列表")
+	}
+}
+
+func TestMCPToolAllowed(t *testing.T) {
+	allowed := []string{"github/list_*", "fs/read_resource", "*/search"}
+	cases := map[string]bool{
+		"github/list_issues":  true,
+		"github/create_issue": false,
+		"fs/read_resource":    true,
+		"fs/get_prompt":       false,
+		"jira/search":         true,
+	}
+	for target, want := range cases {
+		server, tool, _ := strings.Cut(target, "/")
+		if got := mcpToolAllowed(allowed, server, tool); got != want {
+			t.Errorf("%s: got %v, want %v", target, got, want)
+		}
+	}
+	if mcpToolAllowed(nil, "github", "list_issues") {
+		t.Error("允许列表为空时不应提供任何工具")
+	}
+	if !mcpServerMentioned(allowed, "jira") || mcpServerMentioned([]string{"github/*"}, "jira") {
+		t.Error("服务器匹配结果不正确")
+	}
+}
+
+func TestMCPToolName(t *testing.T) {
+	if got := mcpToolName("my server", "files.read"); got != "mcp__my_server__files_read" {
+		t.Fatalf("got %q", got)
+	}
+	if got := mcpToolName("srv", strings.Repeat("x", 100)); len(got) != mcpToolNameLimit {
+		t.Fatalf("工具名应截断到 %d 个字符: %d", mcpToolNameLimit, len(got))
+	}
+}
+
+func TestMCPConfigSecrets(t *testing.T) {
+	stored := models.MCPConfig{
+		AllowedTools: []string{"gh/*"},
+		Servers:      []models.MCPServerConfig{{Name: "gh", URL: "https://mcp.example.com", Headers: map[string]string{"Authorization": "Bearer x"}}},
+	}
+	value, err := stored.Value()
+	if err != nil {
+		t.Fatalf("value failed: %v", err)
+	}
+	var scanned models.MCPConfig
+	if err := scanned.Scan(value); err != nil || scanned.Servers[0].Headers["Authorization"] != "Bearer x" {
+		t.Fatalf("序列化往返失败: %v %+v", err, scanned)
+	}
+
+	scanned.MaskSecrets()
+	if scanned.Servers[0].Headers["Authorization"] != "***" || stored.Servers[0].Headers["Authorization"] != "Bearer x" {
+		t.Fatalf("应仅遮盖返回副本中的请求头: %+v", scanned.Servers[0].Headers)
+	}
+	scanned.RestoreMaskedSecrets(&stored)
+	if scanned.Servers[0].Headers["Authorization"] != "Bearer x" {
+		t.Fatalf("更新时应恢复被遮盖的值: %+v", scanned.Servers[0].Headers)
+	}
+}
@@ -1,11 +1,15 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/services/ai_providers"
 	"github.com/sirupsen/logrus"
 )
 
@@ -78,7 +82,7 @@ func (p *AIMessageProcessor) handleChatRequest(message *Message) error {
 	// 检查消息是否已被停止
 	if p.isMessageStopped(message.ID) {
 		logrus.Infof("消息 %s 已被停止，跳过处理", message.ID)
-		return p.markMessageStopped(message.ID)
+		return p.markMessageStopped(message.ID, nil)
 	}
 
 	// 更新处理状态
@@ -95,7 +99,7 @@ func (p *AIMessageProcessor) handleChatRequest(message *Message) error {
 	if message.ConversationID == nil {
 		// 在创建对话前再次检查是否被停止
 		if p.isMessageStopped(message.ID) {
-			return p.markMessageStopped(message.ID)
+			return p.markMessageStopped(message.ID, nil)
 		}
 
 		conversation, createErr := p.createConversationFromContext(message)
@@ -142,19 +146,42 @@ func (p *AIMessageProcessor) handleChatRequest(message *Message) error {
 
 	// 在发送到AI前最后检查一次是否被停止
 	if p.isMessageStopped(message.ID) {
-		return p.markMessageStopped(message.ID)
+		return p.markMessageStopped(message.ID, nil)
 	}
 
-	// 发送消息并获取AI回复
-	aiMessage, err := p.aiService.SendMessage(conversationID, message.Content)
+	// 流式生成回复：增量写入该消息的事件流供 SSE 端点转发，用户停止时中途取消生成
+	ctx, cancel := context.WithTimeout(context.Background(), p.generateTimeout())
+	defer cancel()
+	go p.watchStop(ctx, message.ID, cancel)
+
+	aiMessage, err := p.aiService.SendMessageStream(ctx, conversationID, message.Content, func(event AIStreamEvent) {
+		p.publishStreamEvent(message.ID, event)
+	})
+	if errors.Is(err, ErrAIMessageStopped) {
+		logrus.Infof("消息 %s 在生成过程中被停止", message.ID)
+		if aiMessage != nil {
+			p.cacheService.DeleteKeysWithPattern(fmt.Sprintf("messages:%d:*", conversationID))
+		}
+		return p.markMessageStopped(message.ID, aiMessage)
+	}
+	if errors.Is(err, ErrAIBudgetExceeded) {
+		p.publishStreamEvent(message.ID, AIStreamEvent{Type: AIStreamEventError, Error: err.Error()})
+		return fmt.Errorf("%w: %v", ErrMessageNotRetryable, err)
+	}
 	if err != nil {
+		// 还会重试时通知前端丢弃本次已收到的增量
+		if message.RetryCount < message.MaxRetries {
+			p.publishStreamEvent(message.ID, AIStreamEvent{Type: AIStreamEventRetry, Error: err.Error()})
+		} else {
+			p.publishStreamEvent(message.ID, AIStreamEvent{Type: AIStreamEventError, Error: err.Error()})
+		}
 		return fmt.Errorf("failed to send message to AI: %v", err)
 	}
 
 	// 处理完成后检查是否被停止（虽然AI已经处理了，但可以标记状态）
 	if p.isMessageStopped(message.ID) {
 		logrus.Infof("消息 %s 在AI处理完成后被标记为停止", message.ID)
-		return p.markMessageStopped(message.ID)
+		return p.markMessageStopped(message.ID, aiMessage)
 	}
 
 	// ===== 缓存管理优化 =====
@@ -187,6 +214,7 @@ func (p *AIMessageProcessor) handleChatRequest(message *Message) error {
 	status.Result = aiMessage.Content
 	status.ProcessedAt = time.Now()
 	p.messageQueueService.SetMessageStatus(message.ID, status)
+	p.publishStreamEvent(message.ID, AIStreamEvent{Type: AIStreamEventDone, Message: aiMessage})
 
 	// 发送实时通知给前端
 	p.sendRealtimeNotification(message.UserID, conversationID, aiMessage)
@@ -196,6 +224,7 @@ func (p *AIMessageProcessor) handleChatRequest(message *Message) error {
 }
 
 // handleClusterOperation 处理集群操作请求
+// 操作名对应已注册的 AI 工具，按请求用户的权限执行；变更类工具只生成待确认调用，由用户确认后执行
 func (p *AIMessageProcessor) handleClusterOperation(message *Message) error {
 	logrus.Infof("Processing cluster operation: %s", message.ID)
 
@@ -206,7 +235,7 @@ func (p *AIMessageProcessor) handleClusterOperation(message *Message) error {
 	}
 
 	// 执行集群操作
-	result, err := p.executeClusterOperation(operation)
+	result, err := p.executeClusterOperation(message.UserID, operation)
 	if err != nil {
 		return fmt.Errorf("failed to execute cluster operation: %v", err)
 	}
@@ -271,11 +300,19 @@ func (p *AIMessageProcessor) contextToString(context map[string]interface{}) str
 	return string(data)
 }
 
-// parseClusterOperation 解析集群操作
+// parseClusterOperation 解析集群操作，操作类型即工具名（可通过 GET /api/ai/tools 查询）
 func (p *AIMessageProcessor) parseClusterOperation(content string, context map[string]interface{}) (*ClusterOperation, error) {
-	// 使用AI或规则引擎解析操作内容
+	opType := strings.TrimSpace(content)
+	toolService := GetAIToolService()
+	if toolService == nil {
+		return nil, fmt.Errorf("AI tool service is not initialized")
+	}
+	if _, ok := toolService.Tool(opType); !ok {
+		return nil, fmt.Errorf("unsupported operation type: %s", opType)
+	}
+
 	operation := &ClusterOperation{
-		Type:       p.detectOperationType(content),
+		Type:       opType,
 		Content:    content,
 		Parameters: context,
 		CreatedAt:  time.Now(),
@@ -284,92 +321,36 @@ func (p *AIMessageProcessor) parseClusterOperation(content string, context map[s
 	return operation, nil
 }
 
-// detectOperationType 检测操作类型
-func (p *AIMessageProcessor) detectOperationType(content string) string {
-	// 简单的关键词匹配，可以扩展为更复杂的NLP分析
-	keywords := map[string]string{
-		"部署":     "deployment",
-		"deploy": "deployment",
-		"扩容":     "scaling",
-		"scale":  "scaling",
-		"监控":     "monitoring",
-		"查看状态":   "status",
-		"删除":     "deletion",
-		"更新":     "update",
-	}
-
-	for keyword, opType := range keywords {
-		if contains(content, keyword) {
-			return opType
-		}
+// executeClusterOperation 以请求用户身份调用对应工具，返回工具结果或待确认信息（JSON）
+func (p *AIMessageProcessor) executeClusterOperation(userID uint, operation *ClusterOperation) (string, error) {
+	toolService := GetAIToolService()
+	if toolService == nil {
+		return "", fmt.Errorf("AI tool service is not initialized")
 	}
 
-	return "unknown"
-}
-
-// contains 检查字符串是否包含子串（不区分大小写）
-func contains(s, substr string) bool {
-	// 简化版本，实际可以使用更复杂的字符串匹配
-	return len(s) >= len(substr) &&
-		(s == substr ||
-			(len(s) > len(substr) &&
-				(s[:len(substr)] == substr ||
-					s[len(s)-len(substr):] == substr ||
-					findInString(s, substr))))
-}
-
-func findInString(s, substr string) bool {
-	for i := 0; i <= len(s)-len(substr); i++ {
-		if s[i:i+len(substr)] == substr {
-			return true
-		}
+	params := operation.Parameters
+	if params == nil {
+		params = map[string]interface{}{}
 	}
-	return false
-}
-
-// executeClusterOperation 执行集群操作
-func (p *AIMessageProcessor) executeClusterOperation(operation *ClusterOperation) (string, error) {
-	switch operation.Type {
-	case "deployment":
-		return p.executeDeployment(operation)
-	case "scaling":
-		return p.executeScaling(operation)
-	case "monitoring":
-		return p.executeMonitoring(operation)
-	default:
-		return "", fmt.Errorf("unsupported operation type: %s", operation.Type)
+	args, err := json.Marshal(params)
+	if err != nil {
+		return "", fmt.Errorf("invalid operation parameters: %v", err)
 	}
-}
-
-// executeDeployment 执行部署操作
-func (p *AIMessageProcessor) executeDeployment(operation *ClusterOperation) (string, error) {
-	// 这里集成Ansible或K8s客户端
-	logrus.Infof("Executing deployment operation: %s", operation.Content)
 
-	// 模拟执行结果
-	result := fmt.Sprintf("部署操作已提交执行，操作ID: %s",
-		fmt.Sprintf("deploy_%d", time.Now().Unix()))
+	caller := toolService.ResolveCaller(userID)
+	result, trace := toolService.Invoke(context.Background(), caller, AIToolScope{}, ai_providers.ToolCall{
+		ID:        fmt.Sprintf("op_%d", operation.CreatedAt.UnixNano()),
+		Name:      operation.Type,
+		Arguments: string(args),
+	})
+	logrus.Infof("Cluster operation %s by user %d: %s", operation.Type, userID, trace.Status)
 
-	return result, nil
-}
-
-// executeScaling 执行扩容操作
-func (p *AIMessageProcessor) executeScaling(operation *ClusterOperation) (string, error) {
-	logrus.Infof("Executing scaling operation: %s", operation.Content)
-
-	result := fmt.Sprintf("扩容操作已提交执行，操作ID: %s",
-		fmt.Sprintf("scale_%d", time.Now().Unix()))
-
-	return result, nil
-}
-
-// executeMonitoring 执行监控操作
-func (p *AIMessageProcessor) executeMonitoring(operation *ClusterOperation) (string, error) {
-	logrus.Infof("Executing monitoring operation: %s", operation.Content)
-
-	result := fmt.Sprintf("监控查询已完成，数据已更新到仪表板")
-
-	return result, nil
+	switch trace.Status {
+	case "success", "pending_confirmation":
+		return result, nil
+	default:
+		return "", errors.New(trace.Error)
+	}
 }
 
 // sendRealtimeNotification 发送实时通知
@@ -439,14 +420,18 @@ func (p *AIMessageProcessor) isMessageStopped(messageID string) bool {
 	return false
 }
 
-// markMessageStopped 标记消息为已停止状态
-func (p *AIMessageProcessor) markMessageStopped(messageID string) error {
+// markMessageStopped 标记消息为已停止状态，aiMessage 为停止前已保存的部分回复（可为空）
+func (p *AIMessageProcessor) markMessageStopped(messageID string, aiMessage *models.AIMessage) error {
 	status := &MessageStatus{
 		ID:          messageID,
 		Status:      "stopped",
 		Error:       "消息处理已被用户停止",
 		ProcessedAt: time.Now(),
 	}
+	if aiMessage != nil {
+		status.Result = aiMessage.Content
+	}
+	p.publishStreamEvent(messageID, AIStreamEvent{Type: AIStreamEventStopped, Message: aiMessage})
 
 	err := p.messageQueueService.SetMessageStatus(messageID, status)
 	if err != nil {
@@ -457,3 +442,36 @@ func (p *AIMessageProcessor) markMessageStopped(messageID string) error {
 	logrus.Infof("消息 %s 已标记为停止状态", messageID)
 	return nil
 }
+
+// watchStop 轮询停止标志，用户停止消息后取消正在进行的生成
+func (p *AIMessageProcessor) watchStop(ctx context.Context, messageID string, cancel context.CancelFunc) {
+	ticker := time.NewTicker(aiStopPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if p.isMessageStopped(messageID) {
+				cancel()
+				return
+			}
+		}
+	}
+}
+
+// generateTimeout 生成回复的超时时间，与消息队列的处理超时保持一致
+func (p *AIMessageProcessor) generateTimeout() time.Duration {
+	if mqsImpl, ok := p.messageQueueService.(*messageQueueServiceImpl); ok && mqsImpl.handlerTimeout > 0 {
+		return mqsImpl.handlerTimeout
+	}
+	return 60 * time.Second
+}
+
+// publishStreamEvent 写入流式回复事件，失败只记录日志，不影响消息处理
+func (p *AIMessageProcessor) publishStreamEvent(messageID string, event AIStreamEvent) {
+	event.MessageID = messageID
+	if err := p.messageQueueService.PublishStreamEvent(messageID, &event); err != nil {
+		logrus.Warnf("写入流式事件失败 %s: %v", messageID, err)
+	}
+}
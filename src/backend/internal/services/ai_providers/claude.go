@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
@@ -51,45 +52,11 @@ func (p *ClaudeProvider) ValidateConfig(config *models.AIAssistantConfig) error
 func (p *ClaudeProvider) Chat(ctx context.Context, request ChatRequest) (*ChatResponse, error) {
 	startTime := time.Now()
 
-	// 构建Claude请求格式
-	messages := make([]map[string]string, 0, len(request.Messages))
-	for _, msg := range request.Messages {
-		// Claude不支持system角色，将其转换为user消息
-		role := msg.Role
-		if role == "system" {
-			role = "user"
-		}
-		messages = append(messages, map[string]string{
-			"role":    role,
-			"content": msg.Content,
-		})
-	}
-
-	requestBody := map[string]interface{}{
-		"model":      request.Model,
-		"max_tokens": request.MaxTokens,
-		"messages":   messages,
-	}
-
-	// Claude使用system参数而不是system消息
-	if request.SystemPrompt != "" {
-		requestBody["system"] = request.SystemPrompt
-	}
-
-	jsonData, err := json.Marshal(requestBody)
+	req, err := p.newRequest(ctx, p.requestBody(request))
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %v", err)
-	}
-
-	req, err := http.NewRequestWithContext(ctx, "POST", p.config.APIEndpoint, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %v", err)
+		return nil, err
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("x-api-key", p.config.APIKey)
-	req.Header.Set("anthropic-version", "2023-06-01")
-
 	resp, err := p.client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("request failed: %v", err)
@@ -108,7 +75,11 @@ func (p *ClaudeProvider) Chat(ctx context.Context, request ChatRequest) (*ChatRe
 	// 解析Claude响应
 	var claudeResp struct {
 		Content []struct {
-			Text string `json:"text"`
+			Type  string          `json:"type"`
+			Text  string          `json:"text"`
+			ID    string          `json:"id"`
+			Name  string          `json:"name"`
+			Input json.RawMessage `json:"input"`
 		} `json:"content"`
 		Usage struct {
 			InputTokens  int `json:"input_tokens"`
@@ -127,8 +98,25 @@ func (p *ClaudeProvider) Chat(ctx context.Context, request ChatRequest) (*ChatRe
 	responseTime := int(time.Since(startTime).Milliseconds())
 	totalTokens := claudeResp.Usage.InputTokens + claudeResp.Usage.OutputTokens
 
+	// 拼接文本块，tool_use 块转换为工具调用
+	var text strings.Builder
+	var toolCalls []ToolCall
+	for _, block := range claudeResp.Content {
+		switch block.Type {
+		case "tool_use":
+			args := string(block.Input)
+			if args == "" {
+				args = "{}"
+			}
+			toolCalls = append(toolCalls, ToolCall{ID: block.ID, Name: block.Name, Arguments: args})
+		default:
+			text.WriteString(block.Text)
+		}
+	}
+
 	return &ChatResponse{
-		Content:      claudeResp.Content[0].Text,
+		Content:      text.String(),
+		ToolCalls:    toolCalls,
 		TokensUsed:   totalTokens,
 		ResponseTime: responseTime,
 		Metadata: map[string]interface{}{
@@ -140,6 +128,288 @@ func (p *ClaudeProvider) Chat(ctx context.Context, request ChatRequest) (*ChatRe
 	}, nil
 }
 
+// ChatStream 发送流式聊天请求，解析 Anthropic Messages API 的 SSE 事件：
+// message_start 携带输入 token，content_block_* 传递文本与 tool_use 参数片段，
+// message_delta 携带累计输出 token，message_stop 结束
+func (p *ClaudeProvider) ChatStream(ctx context.Context, request ChatRequest) (<-chan StreamResponse, error) {
+	body := p.requestBody(request)
+	body["stream"] = true
+
+	req, err := p.newRequest(ctx, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := streamingClient(p.client).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		errBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(errBody))
+	}
+
+	ch := make(chan StreamResponse, 16)
+	go func() {
+		defer close(ch)
+		defer resp.Body.Close()
+
+		type block struct {
+			kind, id, name string
+			input          strings.Builder
+		}
+		blocks := map[int]*block{}
+		var order []int
+		inputTokens, outputTokens := 0, 0
+		stopReason := ""
+		stopped := false
+		// 已收到的输出，用于取消时估算尚未下发的输出 token
+		var streamed strings.Builder
+
+		err := readSSE(resp.Body, func(_ string, data string) (bool, error) {
+			var event struct {
+				Type    string `json:"type"`
+				Index   int    `json:"index"`
+				Message struct {
+					Usage struct {
+						InputTokens  int `json:"input_tokens"`
+						OutputTokens int `json:"output_tokens"`
+					} `json:"usage"`
+				} `json:"message"`
+				ContentBlock struct {
+					Type string `json:"type"`
+					ID   string `json:"id"`
+					Name string `json:"name"`
+				} `json:"content_block"`
+				Delta struct {
+					Type        string `json:"type"`
+					Text        string `json:"text"`
+					PartialJSON string `json:"partial_json"`
+					StopReason  string `json:"stop_reason"`
+				} `json:"delta"`
+				Usage struct {
+					OutputTokens int `json:"output_tokens"`
+				} `json:"usage"`
+				Error struct {
+					Type    string `json:"type"`
+					Message string `json:"message"`
+				} `json:"error"`
+			}
+			if err := json.Unmarshal([]byte(data), &event); err != nil {
+				return false, fmt.Errorf("failed to parse stream event: %v", err)
+			}
+
+			switch event.Type {
+			case "message_start":
+				inputTokens = event.Message.Usage.InputTokens
+				outputTokens = event.Message.Usage.OutputTokens
+				if !sendStream(ctx, ch, StreamResponse{TokensUsed: inputTokens + outputTokens}) {
+					return true, nil
+				}
+			case "content_block_start":
+				if _, ok := blocks[event.Index]; !ok {
+					order = append(order, event.Index)
+				}
+				blocks[event.Index] = &block{kind: event.ContentBlock.Type, id: event.ContentBlock.ID, name: event.ContentBlock.Name}
+			case "content_block_delta":
+				switch event.Delta.Type {
+				case "text_delta":
+					streamed.WriteString(event.Delta.Text)
+					if event.Delta.Text != "" && !sendStream(ctx, ch, StreamResponse{Content: event.Delta.Text}) {
+						return true, nil
+					}
+				case "input_json_delta":
+					if b, ok := blocks[event.Index]; ok {
+						b.input.WriteString(event.Delta.PartialJSON)
+					}
+					streamed.WriteString(event.Delta.PartialJSON)
+				}
+			case "message_delta":
+				if event.Delta.StopReason != "" {
+					stopReason = event.Delta.StopReason
+				}
+				if event.Usage.OutputTokens > 0 {
+					outputTokens = event.Usage.OutputTokens
+				}
+			case "message_stop":
+				stopped = true
+				return true, nil
+			case "error":
+				return false, fmt.Errorf("stream error (%s): %s", event.Error.Type, event.Error.Message)
+			}
+			return false, nil
+		})
+		// 中途取消：输入 token 以 message_start 为准，输出 token 取已报告值与估算值中较大者
+		if ctx.Err() != nil {
+			input, output := inputTokens, outputTokens
+			if input == 0 {
+				input = estimateRequestTokens(request)
+			}
+			if estimated := estimateTokens(streamed.String()); estimated > output {
+				output = estimated
+			}
+			sendCanceledStream(ch, StreamResponse{
+				Done:       true,
+				Error:      ctx.Err(),
+				TokensUsed: input + output,
+				Metadata: map[string]interface{}{
+					"provider":      "claude",
+					"model":         request.Model,
+					"input_tokens":  input,
+					"output_tokens": output,
+				},
+			})
+			return
+		}
+		if err == nil && !stopped {
+			err = fmt.Errorf("stream ended without message_stop")
+		}
+		if err != nil {
+			sendStream(ctx, ch, StreamResponse{Done: true, Error: err, TokensUsed: inputTokens + outputTokens})
+			return
+		}
+
+		var toolCalls []ToolCall
+		for _, index := range order {
+			b := blocks[index]
+			if b.kind != "tool_use" {
+				continue
+			}
+			args := b.input.String()
+			if args == "" {
+				args = "{}"
+			}
+			toolCalls = append(toolCalls, ToolCall{ID: b.id, Name: b.name, Arguments: args})
+		}
+		sendStream(ctx, ch, StreamResponse{
+			Done:       true,
+			ToolCalls:  toolCalls,
+			TokensUsed: inputTokens + outputTokens,
+			Metadata: map[string]interface{}{
+				"provider":      "claude",
+				"model":         request.Model,
+				"stop_reason":   stopReason,
+				"input_tokens":  inputTokens,
+				"output_tokens": outputTokens,
+			},
+		})
+	}()
+	return ch, nil
+}
+
+// requestBody 构建 Claude 请求体
+func (p *ClaudeProvider) requestBody(request ChatRequest) map[string]interface{} {
+	// 构建Claude请求格式
+	messages := claudeMessages(request.Messages)
+
+	requestBody := map[string]interface{}{
+		"model":      request.Model,
+		"max_tokens": request.MaxTokens,
+		"messages":   messages,
+	}
+	if len(request.Tools) > 0 {
+		tools := make([]map[string]interface{}, 0, len(request.Tools))
+		for _, tool := range request.Tools {
+			tools = append(tools, map[string]interface{}{
+				"name":         tool.Name,
+				"description":  tool.Description,
+				"input_schema": tool.Parameters,
+			})
+		}
+		requestBody["tools"] = tools
+	}
+
+	// Claude使用system参数而不是system消息
+	if request.SystemPrompt != "" {
+		requestBody["system"] = request.SystemPrompt
+	}
+
+	return requestBody
+}
+
+// newRequest 构建带认证头的 HTTP 请求
+func (p *ClaudeProvider) newRequest(ctx context.Context, requestBody map[string]interface{}) (*http.Request, error) {
+	jsonData, err := json.Marshal(requestBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", p.config.APIEndpoint, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", p.config.APIKey)
+	req.Header.Set("anthropic-version", "2023-06-01")
+	return req, nil
+}
+
+// claudeMessages 转换为 Claude 消息格式
+// Claude 不支持 system 角色，将其转换为 user 消息；工具调用使用 tool_use 块，
+// 工具结果以 tool_result 块放入 user 消息，连续的多条结果合并为一条消息
+func claudeMessages(chatMessages []ChatMessage) []map[string]interface{} {
+	messages := make([]map[string]interface{}, 0, len(chatMessages))
+	for _, msg := range chatMessages {
+		switch {
+		case msg.Role == "tool":
+			block := map[string]interface{}{
+				"type":        "tool_result",
+				"tool_use_id": msg.ToolCallID,
+				"content":     msg.Content,
+			}
+			if n := len(messages); n > 0 && messages[n-1]["role"] == "user" {
+				if blocks, ok := messages[n-1]["content"].([]map[string]interface{}); ok {
+					messages[n-1]["content"] = append(blocks, block)
+					continue
+				}
+			}
+			messages = append(messages, map[string]interface{}{
+				"role":    "user",
+				"content": []map[string]interface{}{block},
+			})
+		case len(msg.ToolCalls) > 0:
+			blocks := make([]map[string]interface{}, 0, len(msg.ToolCalls)+1)
+			if msg.Content != "" {
+				blocks = append(blocks, map[string]interface{}{"type": "text", "text": msg.Content})
+			}
+			for _, call := range msg.ToolCalls {
+				input := json.RawMessage(call.Arguments)
+				if !json.Valid(input) {
+					input = json.RawMessage("{}")
+				}
+				blocks = append(blocks, map[string]interface{}{
+					"type":  "tool_use",
+					"id":    call.ID,
+					"name":  call.Name,
+					"input": input,
+				})
+			}
+			messages = append(messages, map[string]interface{}{
+				"role":    "assistant",
+				"content": blocks,
+			})
+		default:
+			role := msg.Role
+			if role == "system" {
+				role = "user"
+			}
+			messages = append(messages, map[string]interface{}{
+				"role":    role,
+				"content": msg.Content,
+			})
+		}
+	}
+	return messages
+}
+
+// SupportsToolCalling Claude Messages API 通过 tools 参数支持工具调用
+func (p *ClaudeProvider) SupportsToolCalling() bool {
+	return true
+}
+
 // GetAvailableModels 获取可用模型列表
 func (p *ClaudeProvider) GetAvailableModels(ctx context.Context) ([]ModelInfo, error) {
 	// Claude模型列表
@@ -200,5 +470,5 @@ func (p *ClaudeProvider) TestConnection(ctx context.Context) error {
 
 // GetSupportedCapabilities 获取支持的功能
 func (p *ClaudeProvider) GetSupportedCapabilities() []string {
-	return []string{"chat", "analysis", "long_context"}
+	return []string{"chat", "analysis", "long_context", "function_calling", "streaming"}
 }
@@ -212,7 +212,27 @@ func (f *DefaultProviderFactory) createLocalProvider(config *models.AIAssistantC
 }
 
 // createMCPProvider 创建MCP提供商
+// MCP 服务器只提供工具、资源与提示词，对话由 MCPConfig.BaseProvider 指定的模型提供商完成，
+// 配置中允许的 MCP 工具在工具调用循环中提供给模型
 func (f *DefaultProviderFactory) createMCPProvider(config *models.AIAssistantConfig) (AIProvider, error) {
-	// MCP (Model Context Protocol) 提供商是未来扩展，暂时返回错误
-	return nil, fmt.Errorf("MCP provider not implemented yet")
+	if config.MCPConfig == nil {
+		return nil, fmt.Errorf("invalid MCP config: mcp_config is required")
+	}
+	base := config.MCPConfig.BaseProvider
+	if base == "" {
+		base = models.ProviderOpenAI
+	}
+	if base == models.ProviderMCP {
+		return nil, fmt.Errorf("invalid MCP config: base_provider cannot be mcp")
+	}
+
+	baseConfig := *config
+	baseConfig.Provider = base
+	provider, err := f.CreateProvider(&baseConfig)
+	if err != nil {
+		return nil, err
+	}
+	config.APIEndpoint = baseConfig.APIEndpoint
+	config.Model = baseConfig.Model
+	return provider, nil
 }
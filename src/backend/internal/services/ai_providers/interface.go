@@ -8,19 +8,36 @@ import (
 
 // ChatMessage 统一的聊天消息格式
 type ChatMessage struct {
-	Role    string `json:"role"` // user, assistant, system
-	Content string `json:"content"`
+	Role       string     `json:"role"` // user, assistant, system, tool
+	Content    string     `json:"content"`
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`   // assistant 发起的工具调用
+	ToolCallID string     `json:"tool_call_id,omitempty"` // role 为 tool 时对应的调用ID
+}
+
+// ToolDefinition 提供给模型的工具定义，Parameters 为 JSON Schema
+type ToolDefinition struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Parameters  map[string]interface{} `json:"parameters"`
+}
+
+// ToolCall 模型发起的一次工具调用，Arguments 为 JSON 字符串
+type ToolCall struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
 }
 
 // ChatRequest 统一的聊天请求格式
 type ChatRequest struct {
-	Model        string        `json:"model"`
-	Messages     []ChatMessage `json:"messages"`
-	MaxTokens    int           `json:"max_tokens,omitempty"`
-	Temperature  float32       `json:"temperature,omitempty"`
-	TopP         float32       `json:"top_p,omitempty"`
-	SystemPrompt string        `json:"system_prompt,omitempty"`
-	Stream       bool          `json:"stream,omitempty"`
+	Model        string           `json:"model"`
+	Messages     []ChatMessage    `json:"messages"`
+	MaxTokens    int              `json:"max_tokens,omitempty"`
+	Temperature  float32          `json:"temperature,omitempty"`
+	TopP         float32          `json:"top_p,omitempty"`
+	SystemPrompt string           `json:"system_prompt,omitempty"`
+	Stream       bool             `json:"stream,omitempty"`
+	Tools        []ToolDefinition `json:"tools,omitempty"`
 }
 
 // ChatResponse 统一的聊天响应格式
@@ -31,6 +48,27 @@ type ChatResponse struct {
 	Metadata     map[string]interface{} `json:"metadata,omitempty"`
 	Error        error                  `json:"error,omitempty"`
 	RequestID    string                 `json:"request_id,omitempty"`
+	ToolCalls    []ToolCall             `json:"tool_calls,omitempty"` // 非空时需执行工具并回传结果
+}
+
+// TokenSplit 从响应元数据中读取输入/输出 token 数，提供商未返回时均为 0
+func (r *ChatResponse) TokenSplit() (input, output int) {
+	if r == nil {
+		return 0, 0
+	}
+	return metadataInt(r.Metadata, "input_tokens"), metadataInt(r.Metadata, "output_tokens")
+}
+
+func metadataInt(metadata map[string]interface{}, key string) int {
+	switch v := metadata[key].(type) {
+	case int:
+		return v
+	case int64:
+		return int(v)
+	case float64:
+		return int(v)
+	}
+	return 0
 }
 
 // ModelInfo 模型信息
@@ -78,17 +116,36 @@ type ProviderFactory interface {
 }
 
 // StreamResponse 流式响应
+// Content 为本段文本增量；Done 为 true 的最后一段携带完整的工具调用与 token 用量
 type StreamResponse struct {
-	Content  string                 `json:"content"`
-	Done     bool                   `json:"done"`
-	Metadata map[string]interface{} `json:"metadata,omitempty"`
-	Error    error                  `json:"error,omitempty"`
+	Content    string                 `json:"content"`
+	Done       bool                   `json:"done"`
+	Metadata   map[string]interface{} `json:"metadata,omitempty"`
+	Error      error                  `json:"error,omitempty"`
+	TokensUsed int                    `json:"tokens_used,omitempty"`
+	ToolCalls  []ToolCall             `json:"tool_calls,omitempty"`
 }
 
 // StreamingProvider 支持流式响应的提供商接口
 type StreamingProvider interface {
 	AIProvider
 
-	// ChatStream 发送流式聊天请求
+	// ChatStream 发送流式聊天请求，通道在最后一段（Done 或 Error）之后关闭
 	ChatStream(ctx context.Context, request ChatRequest) (<-chan StreamResponse, error)
 }
+
+// ToolCallingProvider 支持函数调用（工具调用）的提供商接口
+type ToolCallingProvider interface {
+	AIProvider
+
+	// SupportsToolCalling 当前配置是否支持在 ChatRequest.Tools 中传入工具
+	SupportsToolCalling() bool
+}
+
+// EmbeddingProvider 支持文本向量化的提供商接口
+type EmbeddingProvider interface {
+	AIProvider
+
+	// Embed 将输入文本逐条向量化，返回与输入顺序一致的向量
+	Embed(ctx context.Context, model string, inputs []string) ([][]float32, error)
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
@@ -51,45 +52,11 @@ func (p *OpenAIProvider) ValidateConfig(config *models.AIAssistantConfig) error
 func (p *OpenAIProvider) Chat(ctx context.Context, request ChatRequest) (*ChatResponse, error) {
 	startTime := time.Now()
 
-	// 构建OpenAI请求格式
-	messages := make([]map[string]string, 0, len(request.Messages))
-	for _, msg := range request.Messages {
-		messages = append(messages, map[string]string{
-			"role":    msg.Role,
-			"content": msg.Content,
-		})
-	}
-
-	requestBody := map[string]interface{}{
-		"model":       request.Model,
-		"messages":    messages,
-		"max_tokens":  request.MaxTokens,
-		"temperature": request.Temperature,
-	}
-
-	// 添加系统提示（如果有）
-	if request.SystemPrompt != "" {
-		systemMessage := map[string]string{
-			"role":    "system",
-			"content": request.SystemPrompt,
-		}
-		messages = append([]map[string]string{systemMessage}, messages...)
-		requestBody["messages"] = messages
-	}
-
-	jsonData, err := json.Marshal(requestBody)
+	req, err := p.newRequest(ctx, p.requestBody(request))
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %v", err)
+		return nil, err
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", p.config.APIEndpoint, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %v", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+p.config.APIKey)
-
 	resp, err := p.client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("request failed: %v", err)
@@ -109,11 +76,20 @@ func (p *OpenAIProvider) Chat(ctx context.Context, request ChatRequest) (*ChatRe
 	var openaiResp struct {
 		Choices []struct {
 			Message struct {
-				Content string `json:"content"`
+				Content   string `json:"content"`
+				ToolCalls []struct {
+					ID       string `json:"id"`
+					Function struct {
+						Name      string `json:"name"`
+						Arguments string `json:"arguments"`
+					} `json:"function"`
+				} `json:"tool_calls"`
 			} `json:"message"`
 		} `json:"choices"`
 		Usage struct {
-			TotalTokens int `json:"total_tokens"`
+			PromptTokens     int `json:"prompt_tokens"`
+			CompletionTokens int `json:"completion_tokens"`
+			TotalTokens      int `json:"total_tokens"`
 		} `json:"usage"`
 	}
 
@@ -127,17 +103,273 @@ func (p *OpenAIProvider) Chat(ctx context.Context, request ChatRequest) (*ChatRe
 
 	responseTime := int(time.Since(startTime).Milliseconds())
 
+	var toolCalls []ToolCall
+	for _, call := range openaiResp.Choices[0].Message.ToolCalls {
+		toolCalls = append(toolCalls, ToolCall{ID: call.ID, Name: call.Function.Name, Arguments: call.Function.Arguments})
+	}
+
 	return &ChatResponse{
 		Content:      openaiResp.Choices[0].Message.Content,
+		ToolCalls:    toolCalls,
 		TokensUsed:   openaiResp.Usage.TotalTokens,
 		ResponseTime: responseTime,
 		Metadata: map[string]interface{}{
-			"provider": "openai",
-			"model":    request.Model,
+			"provider":      "openai",
+			"model":         request.Model,
+			"input_tokens":  openaiResp.Usage.PromptTokens,
+			"output_tokens": openaiResp.Usage.CompletionTokens,
 		},
 	}, nil
 }
 
+// ChatStream 发送流式聊天请求，解析 OpenAI 兼容接口的 SSE 增量（data: {...} 直至 [DONE]）
+// 工具调用按 index 拼接参数片段；通过 stream_options.include_usage 在末尾获取 token 用量
+func (p *OpenAIProvider) ChatStream(ctx context.Context, request ChatRequest) (<-chan StreamResponse, error) {
+	body := p.requestBody(request)
+	body["stream"] = true
+	body["stream_options"] = map[string]interface{}{"include_usage": true}
+
+	req, err := p.newRequest(ctx, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := streamingClient(p.client).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		errBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(errBody))
+	}
+
+	ch := make(chan StreamResponse, 16)
+	go func() {
+		defer close(ch)
+		defer resp.Body.Close()
+
+		type partialCall struct {
+			id, name string
+			args     strings.Builder
+		}
+		var calls []*partialCall
+		var usage struct {
+			PromptTokens     int `json:"prompt_tokens"`
+			CompletionTokens int `json:"completion_tokens"`
+			TotalTokens      int `json:"total_tokens"`
+		}
+		finishReason := ""
+		// 已收到的输出，用于提供商未返回用量时估算
+		var streamed strings.Builder
+		partialUsage := func(err error) StreamResponse {
+			input, output := usage.PromptTokens, usage.CompletionTokens
+			if usage.TotalTokens == 0 {
+				input, output = estimateRequestTokens(request), estimateTokens(streamed.String())
+			}
+			return StreamResponse{
+				Done:       true,
+				Error:      err,
+				TokensUsed: input + output,
+				Metadata: map[string]interface{}{
+					"provider":      "openai",
+					"model":         request.Model,
+					"input_tokens":  input,
+					"output_tokens": output,
+				},
+			}
+		}
+
+		err := readSSE(resp.Body, func(_ string, data string) (bool, error) {
+			if data == "[DONE]" {
+				return true, nil
+			}
+			var chunk struct {
+				Choices []struct {
+					Delta struct {
+						Content   string `json:"content"`
+						ToolCalls []struct {
+							Index    int    `json:"index"`
+							ID       string `json:"id"`
+							Function struct {
+								Name      string `json:"name"`
+								Arguments string `json:"arguments"`
+							} `json:"function"`
+						} `json:"tool_calls"`
+					} `json:"delta"`
+					FinishReason string `json:"finish_reason"`
+				} `json:"choices"`
+				Usage *struct {
+					PromptTokens     int `json:"prompt_tokens"`
+					CompletionTokens int `json:"completion_tokens"`
+					TotalTokens      int `json:"total_tokens"`
+				} `json:"usage"`
+				Error *struct {
+					Message string `json:"message"`
+				} `json:"error"`
+			}
+			if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+				return false, fmt.Errorf("failed to parse stream chunk: %v", err)
+			}
+			if chunk.Error != nil {
+				return false, fmt.Errorf("stream error: %s", chunk.Error.Message)
+			}
+			if chunk.Usage != nil {
+				usage.PromptTokens = chunk.Usage.PromptTokens
+				usage.CompletionTokens = chunk.Usage.CompletionTokens
+				usage.TotalTokens = chunk.Usage.TotalTokens
+			}
+			if len(chunk.Choices) == 0 {
+				return false, nil
+			}
+			choice := chunk.Choices[0]
+			if choice.FinishReason != "" {
+				finishReason = choice.FinishReason
+			}
+			for _, delta := range choice.Delta.ToolCalls {
+				for len(calls) <= delta.Index {
+					calls = append(calls, &partialCall{})
+				}
+				call := calls[delta.Index]
+				if delta.ID != "" {
+					call.id = delta.ID
+				}
+				if delta.Function.Name != "" {
+					call.name = delta.Function.Name
+				}
+				call.args.WriteString(delta.Function.Arguments)
+				streamed.WriteString(delta.Function.Name)
+				streamed.WriteString(delta.Function.Arguments)
+			}
+			streamed.WriteString(choice.Delta.Content)
+			if choice.Delta.Content != "" && !sendStream(ctx, ch, StreamResponse{Content: choice.Delta.Content}) {
+				return true, nil
+			}
+			return false, nil
+		})
+		// 中途取消或出错时仍回传已知（或估算的）用量，避免按 0 token 计费
+		if ctx.Err() != nil {
+			sendCanceledStream(ch, partialUsage(ctx.Err()))
+			return
+		}
+		if err != nil {
+			sendStream(ctx, ch, partialUsage(err))
+			return
+		}
+
+		var toolCalls []ToolCall
+		for _, call := range calls {
+			if call.name == "" {
+				continue
+			}
+			toolCalls = append(toolCalls, ToolCall{ID: call.id, Name: call.name, Arguments: call.args.String()})
+		}
+		sendStream(ctx, ch, StreamResponse{
+			Done:       true,
+			ToolCalls:  toolCalls,
+			TokensUsed: usage.TotalTokens,
+			Metadata: map[string]interface{}{
+				"provider":      "openai",
+				"model":         request.Model,
+				"finish_reason": finishReason,
+				"input_tokens":  usage.PromptTokens,
+				"output_tokens": usage.CompletionTokens,
+			},
+		})
+	}()
+	return ch, nil
+}
+
+// requestBody 构建 OpenAI 请求体
+func (p *OpenAIProvider) requestBody(request ChatRequest) map[string]interface{} {
+	messages := make([]map[string]interface{}, 0, len(request.Messages)+1)
+	// 添加系统提示（如果有）
+	if request.SystemPrompt != "" {
+		messages = append(messages, map[string]interface{}{
+			"role":    "system",
+			"content": request.SystemPrompt,
+		})
+	}
+	for _, msg := range request.Messages {
+		messages = append(messages, openAIMessage(msg))
+	}
+
+	requestBody := map[string]interface{}{
+		"model":       request.Model,
+		"messages":    messages,
+		"max_tokens":  request.MaxTokens,
+		"temperature": request.Temperature,
+	}
+	if len(request.Tools) > 0 {
+		tools := make([]map[string]interface{}, 0, len(request.Tools))
+		for _, tool := range request.Tools {
+			tools = append(tools, map[string]interface{}{
+				"type": "function",
+				"function": map[string]interface{}{
+					"name":        tool.Name,
+					"description": tool.Description,
+					"parameters":  tool.Parameters,
+				},
+			})
+		}
+		requestBody["tools"] = tools
+	}
+
+	return requestBody
+}
+
+// newRequest 构建带认证头的 HTTP 请求
+func (p *OpenAIProvider) newRequest(ctx context.Context, requestBody map[string]interface{}) (*http.Request, error) {
+	jsonData, err := json.Marshal(requestBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", p.config.APIEndpoint, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+p.config.APIKey)
+	return req, nil
+}
+
+// openAIMessage 转换为 OpenAI 消息格式（含工具调用及工具结果）
+func openAIMessage(msg ChatMessage) map[string]interface{} {
+	m := map[string]interface{}{
+		"role":    msg.Role,
+		"content": msg.Content,
+	}
+	if msg.Role == "tool" {
+		m["tool_call_id"] = msg.ToolCallID
+	}
+	if len(msg.ToolCalls) > 0 {
+		calls := make([]map[string]interface{}, 0, len(msg.ToolCalls))
+		for _, call := range msg.ToolCalls {
+			calls = append(calls, map[string]interface{}{
+				"id":   call.ID,
+				"type": "function",
+				"function": map[string]interface{}{
+					"name":      call.Name,
+					"arguments": call.Arguments,
+				},
+			})
+		}
+		m["tool_calls"] = calls
+		if msg.Content == "" {
+			m["content"] = nil
+		}
+	}
+	return m
+}
+
+// SupportsToolCalling OpenAI 兼容接口（含 DeepSeek、通义千问、智谱等）均按 tools 字段支持函数调用
+func (p *OpenAIProvider) SupportsToolCalling() bool {
+	return true
+}
+
 // GetAvailableModels 获取可用模型列表
 func (p *OpenAIProvider) GetAvailableModels(ctx context.Context) ([]ModelInfo, error) {
 	// OpenAI常用模型列表
@@ -200,3 +432,72 @@ func (p *OpenAIProvider) TestConnection(ctx context.Context) error {
 func (p *OpenAIProvider) GetSupportedCapabilities() []string {
 	return []string{"chat", "function_calling", "streaming"}
 }
+
+// Embed 调用 OpenAI 兼容的 /embeddings 接口（含本地 Ollama 的 /v1/embeddings）
+func (p *OpenAIProvider) Embed(ctx context.Context, model string, inputs []string) ([][]float32, error) {
+	if len(inputs) == 0 {
+		return nil, nil
+	}
+	jsonData, err := json.Marshal(map[string]interface{}{
+		"model": model,
+		"input": inputs,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", openAIEmbeddingsEndpoint(p.config.APIEndpoint), bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+p.config.APIKey)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var embeddingResp struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &embeddingResp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %v", err)
+	}
+	if len(embeddingResp.Data) != len(inputs) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(inputs), len(embeddingResp.Data))
+	}
+
+	vectors := make([][]float32, len(inputs))
+	for _, item := range embeddingResp.Data {
+		if item.Index < 0 || item.Index >= len(inputs) || len(item.Embedding) == 0 {
+			return nil, fmt.Errorf("invalid embedding at index %d", item.Index)
+		}
+		vectors[item.Index] = item.Embedding
+	}
+	return vectors, nil
+}
+
+// openAIEmbeddingsEndpoint 由对话端点推导向量化端点
+func openAIEmbeddingsEndpoint(chatEndpoint string) string {
+	base := strings.TrimRight(chatEndpoint, "/")
+	if strings.HasSuffix(base, "/embeddings") {
+		return base
+	}
+	if strings.HasSuffix(base, "/chat/completions") {
+		return strings.TrimSuffix(base, "/chat/completions") + "/embeddings"
+	}
+	return base + "/embeddings"
+}
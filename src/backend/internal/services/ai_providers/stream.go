@@ -0,0 +1,158 @@
+package ai_providers
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// 单行 SSE 数据的最大长度
+const maxSSELineSize = 1 << 20
+
+// 上下文取消后等待调用方接收最后一段（含用量）响应的最长时间
+const streamCanceledSendTimeout = time.Second
+
+// readSSE 逐个解析 Server-Sent Events 事件并回调，handle 返回 true 时提前结束
+func readSSE(r io.Reader, handle func(event, data string) (bool, error)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxSSELineSize)
+
+	var event string
+	var data []string
+	dispatch := func() (bool, error) {
+		if len(data) == 0 {
+			event = ""
+			return false, nil
+		}
+		stop, err := handle(event, strings.Join(data, "\n"))
+		event, data = "", data[:0]
+		return stop, err
+	}
+
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if line == "" {
+			if stop, err := dispatch(); stop || err != nil {
+				return err
+			}
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			event = value
+		case "data":
+			data = append(data, value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	_, err := dispatch()
+	return err
+}
+
+// streamingClient 流式请求不设整体超时，由调用方的上下文控制生命周期
+func streamingClient(client *http.Client) *http.Client {
+	clone := *client
+	clone.Timeout = 0
+	return &clone
+}
+
+// sendStream 向流式通道发送一段响应，上下文取消时放弃发送
+func sendStream(ctx context.Context, ch chan<- StreamResponse, resp StreamResponse) bool {
+	select {
+	case ch <- resp:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// sendCanceledStream 上下文取消后发送最后一段响应（携带已知用量），调用方不再接收时超时放弃
+func sendCanceledStream(ch chan<- StreamResponse, resp StreamResponse) {
+	select {
+	case ch <- resp:
+	case <-time.After(streamCanceledSendTimeout):
+	}
+}
+
+// estimateTokens 粗略估算文本的 token 数：ASCII 约 4 字符 1 个，其余（如中文）按每字符 1 个计
+// 仅用于提供商未返回用量时（如流式请求中途取消）的计费兜底
+func estimateTokens(text string) int {
+	ascii, other := 0, 0
+	for _, r := range text {
+		if r < utf8.RuneSelf {
+			ascii++
+		} else {
+			other++
+		}
+	}
+	return (ascii+3)/4 + other
+}
+
+// estimateRequestTokens 估算请求提示（系统提示、消息及工具调用）的 token 数
+func estimateRequestTokens(request ChatRequest) int {
+	tokens := estimateTokens(request.SystemPrompt)
+	for _, msg := range request.Messages {
+		tokens += estimateTokens(msg.Content)
+		for _, call := range msg.ToolCalls {
+			tokens += estimateTokens(call.Name) + estimateTokens(call.Arguments)
+		}
+	}
+	return tokens
+}
+
+// CollectStream 消费流式响应，逐段回调文本增量并汇总为完整响应
+// 出错或上下文取消时返回已收到的部分内容及错误
+func CollectStream(ctx context.Context, provider StreamingProvider, request ChatRequest, onDelta func(string)) (*ChatResponse, error) {
+	startTime := time.Now()
+	request.Stream = true
+	stream, err := provider.ChatStream(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	var content strings.Builder
+	resp := &ChatResponse{}
+	finish := func(err error) (*ChatResponse, error) {
+		resp.Content = content.String()
+		resp.ResponseTime = int(time.Since(startTime).Milliseconds())
+		return resp, err
+	}
+
+	for chunk := range stream {
+		if chunk.TokensUsed > 0 {
+			resp.TokensUsed = chunk.TokensUsed
+		}
+		if chunk.Metadata != nil {
+			resp.Metadata = chunk.Metadata
+		}
+		if chunk.Error != nil {
+			return finish(chunk.Error)
+		}
+		if chunk.Content != "" {
+			content.WriteString(chunk.Content)
+			if onDelta != nil {
+				onDelta(chunk.Content)
+			}
+		}
+		if chunk.Done {
+			resp.ToolCalls = chunk.ToolCalls
+			return finish(nil)
+		}
+	}
+	if ctx.Err() != nil {
+		return finish(ctx.Err())
+	}
+	return finish(errors.New("stream closed before completion"))
+}
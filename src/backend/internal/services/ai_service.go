@@ -3,8 +3,10 @@ package services
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
@@ -38,11 +40,13 @@ type AIService interface {
 
 	// 消息处理
 	SendMessage(conversationID uint, userMessage string) (*models.AIMessage, error)
+	SendMessageStream(ctx context.Context, conversationID uint, userMessage string, emit AIStreamHandler) (*models.AIMessage, error)
 	GetMessages(conversationID uint) ([]models.AIMessage, error)
 
 	// 统计
 	GetUsageStats(userID uint, startDate, endDate time.Time) ([]models.AIUsageStats, error)
 	RecordUsage(userID, configID uint, tokenUsed, responseTime int, success bool) error
+	RecordChatUsage(userID uint, config *models.AIAssistantConfig, response *ai_providers.ChatResponse, responseTime int, success bool) error
 
 	// 新增的机器人管理功能
 	TestConnection(config *models.AIAssistantConfig) (map[string]interface{}, error)
@@ -159,6 +163,14 @@ func (s *aiServiceImpl) GetDefaultConfig() (*models.AIAssistantConfig, error) {
 }
 
 func (s *aiServiceImpl) UpdateConfig(config *models.AIAssistantConfig) error {
+	// MCP请求头与环境变量中仍为占位符的值保留原值
+	if config.MCPConfig != nil {
+		var existingConfig models.AIAssistantConfig
+		if err := s.db.First(&existingConfig, config.ID).Error; err == nil {
+			config.MCPConfig.RestoreMaskedSecrets(existingConfig.MCPConfig)
+		}
+	}
+
 	// 如果API密钥是占位符"***"，则从数据库获取原有密钥
 	if config.APIKey == "***" {
 		var existingConfig models.AIAssistantConfig
@@ -213,11 +225,14 @@ func (s *aiServiceImpl) ListConfigs() ([]models.AIAssistantConfig, error) {
 	var configs []models.AIAssistantConfig
 	err := s.db.Find(&configs).Error
 
-	// 不返回解密的API密钥（出于安全考虑）
+	// 不返回解密的API密钥及MCP请求头、环境变量（出于安全考虑）
 	for i := range configs {
 		if configs[i].APIKey != "" {
 			configs[i].APIKey = "***"
 		}
+		if configs[i].MCPConfig != nil {
+			configs[i].MCPConfig.MaskSecrets()
+		}
 	}
 
 	return configs, err
@@ -329,6 +344,16 @@ func (s *aiServiceImpl) ResumeConversation(id uint, userID uint) error {
 
 // 消息处理
 func (s *aiServiceImpl) SendMessage(conversationID uint, userMessage string) (*models.AIMessage, error) {
+	return s.sendMessage(context.Background(), conversationID, userMessage, nil)
+}
+
+// SendMessageStream 流式发送消息，模型输出的文本增量与工具调用记录通过 emit 实时回调
+// 提供商不支持流式时退化为一次性回复；ctx 被取消（用户停止）时保存已生成的部分回复并返回 ErrAIMessageStopped
+func (s *aiServiceImpl) SendMessageStream(ctx context.Context, conversationID uint, userMessage string, emit AIStreamHandler) (*models.AIMessage, error) {
+	return s.sendMessage(ctx, conversationID, userMessage, emit)
+}
+
+func (s *aiServiceImpl) sendMessage(ctx context.Context, conversationID uint, userMessage string, emit AIStreamHandler) (*models.AIMessage, error) {
 	// 获取对话信息
 	conversation, err := s.GetConversation(conversationID)
 	if err != nil {
@@ -351,6 +376,17 @@ func (s *aiServiceImpl) SendMessage(conversationID uint, userMessage string) (*m
 		}
 	}
 
+	// 预算检查：超额时拒绝请求，接近限额时在回复元数据中提示
+	var budgetWarnings []AIBudgetStatus
+	if budgets := GetAIBudgetService(); budgets != nil {
+		if budgetWarnings, err = budgets.Check(conversation.UserID, config); err != nil {
+			if errors.Is(err, ErrAIBudgetExceeded) {
+				return nil, err
+			}
+			logrus.Warnf("AI budget check failed: %v", err)
+		}
+	}
+
 	// 保存用户消息
 	userMsg := &models.AIMessage{
 		ConversationID: conversationID,
@@ -408,20 +444,60 @@ func (s *aiServiceImpl) SendMessage(conversationID uint, userMessage string) (*m
 		SystemPrompt: config.SystemPrompt,
 	}
 
-	// 调用AI API
-	ctx := context.Background()
+	// 配置了知识源时检索相关片段注入系统提示词，检索失败不影响对话
+	toolService := GetAIToolService()
+	citations := s.retrieveCitations(ctx, config, toolService, conversation.UserID, userMessage)
+	if len(citations) > 0 {
+		request.SystemPrompt = strings.TrimSpace(request.SystemPrompt + "\n\n" + FormatRAGContext(citations))
+	}
+
+	// 调用AI API；提供商支持函数调用时进入工具调用循环，工具以对话所属用户的权限执行，
+	// 配置中允许的 MCP 工具一并提供给模型；流式请求且提供商支持时逐段回调输出
 	startTime := time.Now()
-	response, err := provider.Chat(ctx, request)
+	var response *ai_providers.ChatResponse
+	var toolTraces []AIToolTrace
+	streamer, streaming := provider.(ai_providers.StreamingProvider)
+	streaming = streaming && emit != nil
+	if tp, ok := provider.(ai_providers.ToolCallingProvider); ok && tp.SupportsToolCalling() && toolService != nil {
+		caller := toolService.ResolveCaller(conversation.UserID)
+		scope := AIToolScope{
+			ConversationID: conversationID,
+			ConfigID:       config.ID,
+			Extra:          GetMCPService().ToolsForConfig(ctx, config),
+		}
+		if streaming {
+			response, toolTraces, err = toolService.ConverseStream(ctx, streamer, request, caller, scope, emit)
+		} else {
+			response, toolTraces, err = toolService.Converse(ctx, provider, request, caller, scope)
+		}
+	} else if streaming {
+		response, err = ai_providers.CollectStream(ctx, streamer, request, func(delta string) {
+			emit(AIStreamEvent{Type: AIStreamEventDelta, Content: delta})
+		})
+	} else {
+		response, err = provider.Chat(ctx, request)
+		if err == nil && emit != nil {
+			emit(AIStreamEvent{Type: AIStreamEventDelta, Content: response.Content})
+		}
+	}
 	responseTime := int(time.Since(startTime).Milliseconds())
 
+	// 用户中途停止：保留已生成的部分回复
+	stopped := err != nil && emit != nil && errors.Is(ctx.Err(), context.Canceled)
+
 	// 记录使用统计
-	tokensUsed := 0
-	if response != nil {
-		tokensUsed = response.TokensUsed
-	}
-	s.RecordUsage(conversation.UserID, config.ID, tokensUsed, responseTime, err == nil)
+	s.RecordChatUsage(conversation.UserID, config, response, responseTime, err == nil || stopped)
 
-	if err != nil {
+	if stopped {
+		if response == nil || response.Content == "" {
+			return nil, ErrAIMessageStopped
+		}
+		if response.Metadata == nil {
+			response.Metadata = map[string]interface{}{}
+		}
+		response.Metadata["stopped"] = true
+		response.ResponseTime = responseTime
+	} else if err != nil {
 		return nil, fmt.Errorf("AI API call failed: %v", err)
 	}
 
@@ -437,7 +513,25 @@ func (s *aiServiceImpl) SendMessage(conversationID uint, userMessage string) (*m
 		UpdatedAt:      time.Now(),
 	}
 
-	// 序列化metadata
+	// 序列化metadata（含本轮工具调用记录，前端据 pending_confirmation 展示确认入口）
+	if len(toolTraces) > 0 {
+		if response.Metadata == nil {
+			response.Metadata = map[string]interface{}{}
+		}
+		response.Metadata["tool_calls"] = toolTraces
+	}
+	if len(citations) > 0 {
+		if response.Metadata == nil {
+			response.Metadata = map[string]interface{}{}
+		}
+		response.Metadata["sources"] = citations
+	}
+	if len(budgetWarnings) > 0 {
+		if response.Metadata == nil {
+			response.Metadata = map[string]interface{}{}
+		}
+		response.Metadata["budget_warnings"] = budgetWarnings
+	}
 	if response.Metadata != nil {
 		if metadataBytes, err := json.Marshal(response.Metadata); err == nil {
 			aiMsg.Metadata = string(metadataBytes)
@@ -470,9 +564,35 @@ func (s *aiServiceImpl) SendMessage(conversationID uint, userMessage string) (*m
 		}
 	}
 
+	if stopped {
+		return aiMsg, ErrAIMessageStopped
+	}
 	return aiMsg, nil
 }
 
+// retrieveCitations 按配置的知识源检索与用户消息相关的片段，以对话所属用户的身份过滤作业日志
+func (s *aiServiceImpl) retrieveCitations(ctx context.Context, config *models.AIAssistantConfig, toolService *AIToolService, userID uint, userMessage string) []RAGCitation {
+	if config.RAGConfig == nil || len(config.RAGConfig.SourceIDs) == 0 {
+		return nil
+	}
+	ragService := GetRAGService()
+	if ragService == nil {
+		return nil
+	}
+	isAdmin := false
+	if toolService != nil {
+		isAdmin = toolService.ResolveCaller(userID).IsAdmin
+	}
+	ctx, cancel := context.WithTimeout(ctx, ragRetrieveTimeout)
+	defer cancel()
+	citations, err := ragService.Retrieve(ctx, config.RAGConfig, userMessage, userID, isAdmin)
+	if err != nil {
+		logrus.Warnf("knowledge retrieval failed for config %d: %v", config.ID, err)
+		return nil
+	}
+	return citations
+}
+
 func (s *aiServiceImpl) GetMessages(conversationID uint) ([]models.AIMessage, error) {
 	// 使用优化的消息检索服务
 	if s.messageRetrieval != nil {
@@ -511,38 +631,49 @@ func (s *aiServiceImpl) GetUsageStats(userID uint, startDate, endDate time.Time)
 }
 
 func (s *aiServiceImpl) RecordUsage(userID, configID uint, tokenUsed, responseTime int, success bool) error {
-	stats := &models.AIUsageStats{
-		UserID:          userID,
-		ConfigID:        configID,
-		Date:            time.Now().Truncate(24 * time.Hour),
-		TokensUsed:      tokenUsed,
-		RequestCount:    1,
-		SuccessCount:    0,
-		ErrorCount:      0,
-		AverageResponse: responseTime,
+	var config models.AIAssistantConfig
+	if err := s.db.Select("id", "provider", "model").First(&config, configID).Error; err != nil {
+		config.ID = configID
+	}
+	return s.recordUsage(userID, &config, tokenUsed, 0, 0, responseTime, success)
+}
+
+// RecordChatUsage 按模型响应记录使用统计，输入/输出 token 分别按模型单价计费
+func (s *aiServiceImpl) RecordChatUsage(userID uint, config *models.AIAssistantConfig, response *ai_providers.ChatResponse, responseTime int, success bool) error {
+	tokensUsed := 0
+	if response != nil {
+		tokensUsed = response.TokensUsed
 	}
+	input, output := response.TokenSplit()
+	return s.recordUsage(userID, config, tokensUsed, input, output, responseTime, success)
+}
 
+func (s *aiServiceImpl) recordUsage(userID uint, config *models.AIAssistantConfig, tokenUsed, input, output, responseTime int, success bool) error {
+	var cost float64
+	if tokenUsed > 0 && config.Model != "" {
+		cost = lookupAIModelPrice(s.providerFactory, config.Provider, config.Model).Cost(input, output, tokenUsed)
+	}
+	successCount, errorCount := 0, 1
 	if success {
-		stats.SuccessCount = 1
-	} else {
-		stats.ErrorCount = 1
+		successCount, errorCount = 1, 0
 	}
 
 	// 使用UPSERT语法更新或插入统计数据
-	err := s.db.Raw(`
-		INSERT INTO ai_usage_stats (user_id, config_id, date, tokens_used, request_count, success_count, error_count, average_response, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, NOW(), NOW())
+	return s.db.Exec(`
+		INSERT INTO ai_usage_stats (user_id, config_id, date, tokens_used, input_tokens, output_tokens, cost, request_count, success_count, error_count, average_response, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NOW(), NOW())
 		ON CONFLICT (user_id, config_id, date)
 		DO UPDATE SET
 			tokens_used = ai_usage_stats.tokens_used + EXCLUDED.tokens_used,
+			input_tokens = ai_usage_stats.input_tokens + EXCLUDED.input_tokens,
+			output_tokens = ai_usage_stats.output_tokens + EXCLUDED.output_tokens,
+			cost = ai_usage_stats.cost + EXCLUDED.cost,
 			request_count = ai_usage_stats.request_count + EXCLUDED.request_count,
 			success_count = ai_usage_stats.success_count + EXCLUDED.success_count,
 			error_count = ai_usage_stats.error_count + EXCLUDED.error_count,
 			average_response = (ai_usage_stats.average_response + EXCLUDED.average_response) / 2,
 			updated_at = NOW()
-	`, userID, configID, stats.Date, tokenUsed, 1, stats.SuccessCount, stats.ErrorCount, responseTime).Error
-
-	return err
+	`, userID, config.ID, time.Now().Truncate(24*time.Hour), tokenUsed, input, output, cost, 1, successCount, errorCount, responseTime).Error
 }
 
 // TestConnection 测试机器人连接
@@ -1036,6 +1167,16 @@ func (s *aiServiceImpl) CompareModels(conversationID uint, userMessage string, c
 				}
 			}
 
+			// 超出预算的配置不参与对比
+			if budgets := GetAIBudgetService(); budgets != nil {
+				if _, err := budgets.Check(conversation.UserID, config); errors.Is(err, ErrAIBudgetExceeded) {
+					result.Status = "error"
+					result.Error = err.Error()
+					resultChan <- modelResult{index: index, response: result, err: err}
+					return
+				}
+			}
+
 			// 创建AI提供商
 			provider, err := s.providerFactory.CreateProvider(config)
 			if err != nil {
@@ -1101,7 +1242,7 @@ func (s *aiServiceImpl) CompareModels(conversationID uint, userMessage string, c
 			result.Metadata = response.Metadata
 
 			// 记录成功统计
-			s.RecordUsage(conversation.UserID, config.ID, response.TokensUsed, responseTime, true)
+			s.RecordChatUsage(conversation.UserID, config, response, responseTime, true)
 
 			resultChan <- modelResult{index: index, response: result, err: nil}
 
@@ -0,0 +1,48 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
+)
+
+// 流式回复事件类型
+const (
+	AIStreamEventStart   = "start"   // 请求已受理，携带消息ID
+	AIStreamEventDelta   = "delta"   // 模型输出的文本增量
+	AIStreamEventTool    = "tool"    // 一次工具调用的执行记录
+	AIStreamEventDone    = "done"    // 回复完成，携带已保存的消息
+	AIStreamEventStopped = "stopped" // 用户中途停止，携带已保存的部分回复
+	AIStreamEventError   = "error"   // 处理失败
+	AIStreamEventRetry   = "retry"   // 本次生成失败并将重试，前端应丢弃已收到的增量
+)
+
+// 停止标志的轮询间隔
+const aiStopPollInterval = 500 * time.Millisecond
+
+// ErrAIMessageStopped 流式生成被用户中途停止，已生成的部分回复会一并保存并返回
+var ErrAIMessageStopped = errors.New("message generation stopped by user")
+
+// AIStreamEvent 流式回复事件，由消息处理器写入 Redis，SSE 端点按顺序转发给前端
+type AIStreamEvent struct {
+	ID        string            `json:"-"` // Redis Stream 条目ID，作为 SSE 的 id 用于断线续传
+	Type      string            `json:"type"`
+	MessageID string            `json:"message_id,omitempty"`
+	Content   string            `json:"content,omitempty"`
+	Tool      *AIToolTrace      `json:"tool,omitempty"`
+	Message   *models.AIMessage `json:"message,omitempty"`
+	Error     string            `json:"error,omitempty"`
+}
+
+// Final 是否为结束事件
+func (e *AIStreamEvent) Final() bool {
+	switch e.Type {
+	case AIStreamEventDone, AIStreamEventStopped, AIStreamEventError:
+		return true
+	}
+	return false
+}
+
+// AIStreamHandler 接收流式回复事件的回调
+type AIStreamHandler func(event AIStreamEvent)
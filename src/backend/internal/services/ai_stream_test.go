@@ -0,0 +1,213 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/services/ai_providers"
+)
+
+// newSSEServer 按请求顺序依次返回预设的 SSE 响应体，并记录请求体
+func newSSEServer(t *testing.T, bodies ...string) (*httptest.Server, *[]map[string]interface{}) {
+	t.Helper()
+	var mu sync.Mutex
+	var requests []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&req)
+		mu.Lock()
+		index := len(requests)
+		requests = append(requests, req)
+		mu.Unlock()
+		if index >= len(bodies) {
+			index = len(bodies) - 1
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, bodies[index])
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func openAIChunk(v string) string {
+	return "data: " + v + "\n\n"
+}
+
+func TestOpenAIChatStream(t *testing.T) {
+	server, requests := newSSEServer(t, strings.Join([]string{
+		": keep-alive\n\n",
+		openAIChunk(`{"choices":[{"delta":{"role":"assistant","content":"集群"}}]}`),
+		openAIChunk(`{"choices":[{"delta":{"content":"正常"}}]}`),
+		openAIChunk(`{"choices":[{"delta":{"tool_calls":[{"index":0,"id":"call_1","function":{"name":"slurm_list_nodes","arguments":"{\"part"}}]}}]}`),
+		openAIChunk(`{"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"ition\":\"gpu\"}"}}]},"finish_reason":"tool_calls"}]}`),
+		openAIChunk(`{"choices":[],"usage":{"prompt_tokens":12,"completion_tokens":8,"total_tokens":20}}`),
+		"data: [DONE]\n\n",
+	}, ""))
+	provider := ai_providers.NewOpenAIProvider(&models.AIAssistantConfig{APIKey: "k", APIEndpoint: server.URL})
+
+	var deltas []string
+	resp, err := ai_providers.CollectStream(context.Background(), provider, ai_providers.ChatRequest{Model: "gpt-4o"}, func(delta string) {
+		deltas = append(deltas, delta)
+	})
+	if err != nil {
+		t.Fatalf("stream failed: %v", err)
+	}
+	if strings.Join(deltas, "|") != "集群|正常" || resp.Content != "集群正常" {
+		t.Fatalf("应逐段回调文本增量: %v %q", deltas, resp.Content)
+	}
+	if len(resp.ToolCalls) != 1 || resp.ToolCalls[0].ID != "call_1" || resp.ToolCalls[0].Arguments != `{"partition":"gpu"}` {
+		t.Fatalf("应按 index 拼接工具调用参数: %+v", resp.ToolCalls)
+	}
+	if resp.TokensUsed != 20 || resp.Metadata["output_tokens"] != 8 {
+		t.Fatalf("应记录末尾的 token 用量: %d %v", resp.TokensUsed, resp.Metadata)
+	}
+
+	req := (*requests)[0]
+	options, _ := req["stream_options"].(map[string]interface{})
+	if req["stream"] != true || options["include_usage"] != true {
+		t.Fatalf("流式请求应开启 stream 及 include_usage: %v", req)
+	}
+}
+
+func TestClaudeChatStream(t *testing.T) {
+	event := func(name, data string) string {
+		return fmt.Sprintf("event: %s\ndata: %s\n\n", name, data)
+	}
+	server, requests := newSSEServer(t, strings.Join([]string{
+		event("message_start", `{"type":"message_start","message":{"usage":{"input_tokens":25,"output_tokens":1}}}`),
+		event("content_block_start", `{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`),
+		event("ping", `{"type":"ping"}`),
+		event("content_block_delta", `{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"查询"}}`),
+		event("content_block_delta", `{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"节点"}}`),
+		event("content_block_stop", `{"type":"content_block_stop","index":0}`),
+		event("content_block_start", `{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_1","name":"slurm_list_nodes","input":{}}}`),
+		event("content_block_delta", `{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"state\":"}}`),
+		event("content_block_delta", `{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"\"idle\"}"}}`),
+		event("content_block_stop", `{"type":"content_block_stop","index":1}`),
+		event("message_delta", `{"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":30}}`),
+		event("message_stop", `{"type":"message_stop"}`),
+	}, ""))
+	provider := ai_providers.NewClaudeProvider(&models.AIAssistantConfig{APIKey: "k", APIEndpoint: server.URL})
+
+	resp, err := ai_providers.CollectStream(context.Background(), provider, ai_providers.ChatRequest{Model: "claude"}, nil)
+	if err != nil {
+		t.Fatalf("stream failed: %v", err)
+	}
+	if resp.Content != "查询节点" || resp.TokensUsed != 55 || resp.Metadata["stop_reason"] != "tool_use" {
+		t.Fatalf("应拼接文本并合计输入输出 token: %+v", resp)
+	}
+	if len(resp.ToolCalls) != 1 || resp.ToolCalls[0].ID != "toolu_1" || resp.ToolCalls[0].Arguments != `{"state":"idle"}` {
+		t.Fatalf("应拼接 tool_use 参数片段: %+v", resp.ToolCalls)
+	}
+	if (*requests)[0]["stream"] != true {
+		t.Fatalf("请求应开启 stream: %v", (*requests)[0])
+	}
+
+	// 服务端错误事件与提前断开均应返回错误
+	for _, body := range []string{
+		event("error", `{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`),
+		event("message_start", `{"type":"message_start","message":{"usage":{"input_tokens":5}}}`),
+	} {
+		server, _ := newSSEServer(t, body)
+		provider := ai_providers.NewClaudeProvider(&models.AIAssistantConfig{APIKey: "k", APIEndpoint: server.URL})
+		resp, err := ai_providers.CollectStream(context.Background(), provider, ai_providers.ChatRequest{}, nil)
+		if err == nil {
+			t.Fatalf("应返回错误: %q", body)
+		}
+		if strings.Contains(body, "input_tokens") && resp.TokensUsed != 5 {
+			t.Fatalf("部分响应应保留已知 token 用量: %+v", resp)
+		}
+	}
+}
+
+func TestChatStreamCancel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, openAIChunk(`{"choices":[{"delta":{"content":"部分"}}]}`))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+	provider := ai_providers.NewOpenAIProvider(&models.AIAssistantConfig{APIKey: "k", APIEndpoint: server.URL})
+
+	request := ai_providers.ChatRequest{Messages: []ai_providers.ChatMessage{{Role: "user", Content: "查看节点状态"}}}
+	ctx, cancel := context.WithCancel(context.Background())
+	resp, err := ai_providers.CollectStream(ctx, provider, request, func(string) { cancel() })
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("取消后应返回 context.Canceled: %v", err)
+	}
+	if resp == nil || resp.Content != "部分" {
+		t.Fatalf("应保留取消前已收到的内容: %+v", resp)
+	}
+	// 提供商未返回用量时按提示与已收到的输出估算
+	if input, output := resp.TokenSplit(); input != 6 || output != 2 || resp.TokensUsed != 8 {
+		t.Fatalf("取消后应回传估算用量: %+v", resp)
+	}
+}
+
+func TestClaudeChatStreamCancelKeepsUsage(t *testing.T) {
+	event := func(name, data string) string {
+		return fmt.Sprintf("event: %s\ndata: %s\n\n", name, data)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, event("message_start", `{"type":"message_start","message":{"usage":{"input_tokens":25,"output_tokens":1}}}`))
+		io.WriteString(w, event("content_block_delta", `{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"部分回复"}}`))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+	provider := ai_providers.NewClaudeProvider(&models.AIAssistantConfig{APIKey: "k", APIEndpoint: server.URL})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	resp, err := ai_providers.CollectStream(ctx, provider, ai_providers.ChatRequest{}, func(string) { cancel() })
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("取消后应返回 context.Canceled: %v", err)
+	}
+	if input, output := resp.TokenSplit(); input != 25 || output != 4 || resp.TokensUsed != 29 {
+		t.Fatalf("输入 token 应取 message_start，输出按已收到内容估算: %+v", resp)
+	}
+}
+
+func TestAIToolConverseStream(t *testing.T) {
+	server, requests := newSSEServer(t,
+		openAIChunk(`{"choices":[{"delta":{"content":"先查一下"}}]}`)+
+			openAIChunk(`{"choices":[{"delta":{"tool_calls":[{"index":0,"id":"call_1","function":{"name":"no_such_tool","arguments":"{}"}}]}}]}`)+
+			openAIChunk(`{"choices":[],"usage":{"total_tokens":10}}`)+"data: [DONE]\n\n",
+		openAIChunk(`{"choices":[{"delta":{"content":"集群状态正常"}}]}`)+
+			openAIChunk(`{"choices":[],"usage":{"total_tokens":7}}`)+"data: [DONE]\n\n",
+	)
+	provider := ai_providers.NewOpenAIProvider(&models.AIAssistantConfig{APIKey: "k", APIEndpoint: server.URL})
+	request := ai_providers.ChatRequest{Messages: []ai_providers.ChatMessage{{Role: "user", Content: "查看节点"}}}
+
+	var events []AIStreamEvent
+	resp, traces, err := newTestAIToolService().ConverseStream(context.Background(), provider, request, AIToolCaller{UserID: 1}, AIToolScope{ConversationID: 1}, func(event AIStreamEvent) {
+		events = append(events, event)
+	})
+	if err != nil {
+		t.Fatalf("converse failed: %v", err)
+	}
+	if resp.Content != "集群状态正常" || resp.TokensUsed != 17 || len(traces) != 1 {
+		t.Fatalf("应返回最终回复并累加各轮用量: %+v %+v", resp, traces)
+	}
+
+	var kinds []string
+	for _, event := range events {
+		kinds = append(kinds, event.Type)
+	}
+	if strings.Join(kinds, ",") != "delta,tool,delta" || events[1].Tool.Status != "failed" {
+		t.Fatalf("事件应按增量、工具调用、增量的顺序回调: %+v", events)
+	}
+	if len(*requests) != 2 || len((*requests)[1]["messages"].([]interface{})) != 3 {
+		t.Fatalf("第二轮应携带工具调用往返消息: %v", *requests)
+	}
+}
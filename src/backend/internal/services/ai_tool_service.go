@@ -0,0 +1,534 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/database"
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/services/ai_providers"
+	"gorm.io/gorm"
+)
+
+const (
+	// 单条消息内工具调用循环的最大轮数
+	aiToolMaxRounds = 6
+	// 单次工具执行超时
+	aiToolRunTimeout = 2 * time.Minute
+	// 变更类工具调用等待用户确认的有效期
+	aiToolConfirmationTTL = 30 * time.Minute
+	// 回传给模型的单个工具结果的最大长度
+	aiToolResultLimit = 16 * 1024
+	// 写入对话记录的单个工具结果的最大长度
+	aiToolTraceResultLimit = 2000
+	// 写入审计日志的工具参数的最大长度
+	aiToolAuditArgsLimit = 4000
+)
+
+// ErrAIToolConfirmationNotPending 工具调用不处于待确认状态（已处理或已过期）
+var ErrAIToolConfirmationNotPending = errors.New("tool call is not pending confirmation")
+
+// AIToolCaller 发起工具调用的用户，工具以该用户的 RBAC 角色与集群权限执行
+type AIToolCaller struct {
+	UserID   uint
+	Username string
+	IsAdmin  bool
+}
+
+// AITool AI 助手可调用的工具
+type AITool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Parameters  map[string]interface{} `json:"parameters"` // JSON Schema
+	// Mutating 变更类工具，模型调用后需用户在对话中确认才会执行
+	Mutating bool                 `json:"mutating"`
+	Category models.AuditCategory `json:"category"`
+	// Source 工具来源，内置工具为空，MCP 工具为 "mcp:服务器名"
+	Source string `json:"source,omitempty"`
+
+	// Authorize 校验调用者对本次调用的权限，确认执行前会再次校验
+	Authorize func(ctx context.Context, caller AIToolCaller, args json.RawMessage) error `json:"-"`
+	// Run 执行工具，返回值序列化为 JSON 回传给模型
+	Run func(ctx context.Context, caller AIToolCaller, args json.RawMessage) (interface{}, error) `json:"-"`
+	// Summarize 生成展示给用户确认的操作说明（变更类工具）
+	Summarize func(args json.RawMessage) string `json:"-"`
+}
+
+// AIToolTrace 一次工具调用的记录，写入助手消息的元数据
+type AIToolTrace struct {
+	Name           string `json:"name"`
+	Arguments      string `json:"arguments"`
+	Status         string `json:"status"` // success, failed, denied, pending_confirmation
+	ConfirmationID uint   `json:"confirmation_id,omitempty"`
+	Source         string `json:"source,omitempty"`
+	Result         string `json:"result,omitempty"` // 截断后的执行结果
+	Error          string `json:"error,omitempty"`
+}
+
+// AIToolScope 一次对话中工具调用的上下文
+type AIToolScope struct {
+	ConversationID uint // 0 表示来自集群操作接口
+	ConfigID       uint // 对话使用的助手配置
+	// Extra 仅对本次对话可用的工具（如配置中允许的 MCP 工具）
+	Extra []*AITool
+}
+
+// AIToolService AI 助手工具注册表与工具调用循环
+type AIToolService struct {
+	db    *gorm.DB
+	mu    sync.RWMutex
+	tools map[string]*AITool
+	order []string
+}
+
+var (
+	aiToolServiceInstance *AIToolService
+	aiToolServiceOnce     sync.Once
+)
+
+// NewAIToolService 创建 AI 工具服务（单例），并注册内置的集群工具
+func NewAIToolService(db *gorm.DB) *AIToolService {
+	aiToolServiceOnce.Do(func() {
+		aiToolServiceInstance = &AIToolService{db: db, tools: make(map[string]*AITool)}
+		if err := db.AutoMigrate(&models.AIToolConfirmation{}); err != nil {
+			log.Printf("[AITool] 自动迁移失败: %v", err)
+		}
+		for _, tool := range builtinAITools(db) {
+			aiToolServiceInstance.Register(tool)
+		}
+	})
+	return aiToolServiceInstance
+}
+
+// GetAIToolService 获取 AI 工具服务实例，未初始化时使用全局数据库连接
+func GetAIToolService() *AIToolService {
+	if aiToolServiceInstance == nil && database.DB != nil {
+		return NewAIToolService(database.DB)
+	}
+	return aiToolServiceInstance
+}
+
+// Register 注册工具，同名工具覆盖
+func (s *AIToolService) Register(tool *AITool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.tools[tool.Name]; !exists {
+		s.order = append(s.order, tool.Name)
+	}
+	s.tools[tool.Name] = tool
+}
+
+// Tool 按名称查找工具
+func (s *AIToolService) Tool(name string) (*AITool, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	tool, ok := s.tools[name]
+	return tool, ok
+}
+
+// Tools 按注册顺序返回全部工具
+func (s *AIToolService) Tools() []*AITool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	tools := make([]*AITool, 0, len(s.order))
+	for _, name := range s.order {
+		tools = append(tools, s.tools[name])
+	}
+	return tools
+}
+
+// Definitions 返回传给模型的内置工具定义
+func (s *AIToolService) Definitions() []ai_providers.ToolDefinition {
+	return aiToolDefinitions(s.Tools())
+}
+
+// lookup 在作用域的附加工具与内置工具中按名称查找
+func (s *AIToolService) lookup(name string, scope AIToolScope) (*AITool, bool) {
+	for _, tool := range scope.Extra {
+		if tool.Name == name {
+			return tool, true
+		}
+	}
+	return s.Tool(name)
+}
+
+func aiToolDefinitions(tools []*AITool) []ai_providers.ToolDefinition {
+	defs := make([]ai_providers.ToolDefinition, 0, len(tools))
+	for _, tool := range tools {
+		description := tool.Description
+		if tool.Mutating {
+			description += "（变更操作：调用后不会立即执行，需用户在对话中确认）"
+		}
+		defs = append(defs, ai_providers.ToolDefinition{Name: tool.Name, Description: description, Parameters: tool.Parameters})
+	}
+	return defs
+}
+
+// ResolveCaller 按用户ID解析调用者身份与管理员角色
+func (s *AIToolService) ResolveCaller(userID uint) AIToolCaller {
+	return resolveAIToolCaller(s.db, userID)
+}
+
+// resolveAIToolCaller 按用户ID解析工具调用者；AI 助手与平台 MCP 服务端共用，保证两处管理员判定一致
+func resolveAIToolCaller(db *gorm.DB, userID uint) AIToolCaller {
+	var user models.User
+	if err := db.Preload("Roles").First(&user, userID).Error; err != nil {
+		return AIToolCaller{UserID: userID}
+	}
+	return aiToolCallerFromUser(user)
+}
+
+// aiToolCallerFromUser 以 admin 角色判定管理员（与 AdminMiddleware 一致）
+func aiToolCallerFromUser(user models.User) AIToolCaller {
+	caller := AIToolCaller{UserID: user.ID, Username: user.Username}
+	for _, role := range user.Roles {
+		if role.Name == "admin" {
+			caller.IsAdmin = true
+			break
+		}
+	}
+	return caller
+}
+
+// Converse 执行工具调用循环：模型返回工具调用时逐个执行并回传结果，直到模型给出不含工具调用的回复
+// 返回的 TokensUsed 与 ResponseTime 为各轮之和
+func (s *AIToolService) Converse(ctx context.Context, provider ai_providers.AIProvider, request ai_providers.ChatRequest, caller AIToolCaller, scope AIToolScope) (*ai_providers.ChatResponse, []AIToolTrace, error) {
+	return s.converse(ctx, provider.Chat, request, caller, scope, nil)
+}
+
+// ConverseStream 与 Converse 相同，但每一轮均以流式请求模型，文本增量与工具调用记录通过 emit 实时回调
+// 出错或上下文取消时返回当前轮已生成的部分回复（累计 token 用量）及错误
+func (s *AIToolService) ConverseStream(ctx context.Context, provider ai_providers.StreamingProvider, request ai_providers.ChatRequest, caller AIToolCaller, scope AIToolScope, emit AIStreamHandler) (*ai_providers.ChatResponse, []AIToolTrace, error) {
+	chat := func(ctx context.Context, request ai_providers.ChatRequest) (*ai_providers.ChatResponse, error) {
+		return ai_providers.CollectStream(ctx, provider, request, func(delta string) {
+			emit(AIStreamEvent{Type: AIStreamEventDelta, Content: delta})
+		})
+	}
+	return s.converse(ctx, chat, request, caller, scope, emit)
+}
+
+func (s *AIToolService) converse(ctx context.Context, chat func(context.Context, ai_providers.ChatRequest) (*ai_providers.ChatResponse, error), request ai_providers.ChatRequest, caller AIToolCaller, scope AIToolScope, emit AIStreamHandler) (*ai_providers.ChatResponse, []AIToolTrace, error) {
+	request.Tools = append(s.Definitions(), aiToolDefinitions(scope.Extra)...)
+	request.Messages = append([]ai_providers.ChatMessage(nil), request.Messages...)
+
+	var traces []AIToolTrace
+	var last *ai_providers.ChatResponse
+	tokens, elapsed, input, output := 0, 0, 0, 0
+	for round := 0; round < aiToolMaxRounds; round++ {
+		resp, err := chat(ctx, request)
+		if err != nil {
+			if resp != nil {
+				resp.TokensUsed += tokens
+				resp.ResponseTime += elapsed
+				setAITokenSplit(resp, input, output)
+			}
+			return resp, traces, err
+		}
+		tokens += resp.TokensUsed
+		elapsed += resp.ResponseTime
+		in, out := resp.TokenSplit()
+		input += in
+		output += out
+		last = resp
+		if len(resp.ToolCalls) == 0 {
+			break
+		}
+
+		request.Messages = append(request.Messages, ai_providers.ChatMessage{
+			Role:      "assistant",
+			Content:   resp.Content,
+			ToolCalls: resp.ToolCalls,
+		})
+		for _, call := range resp.ToolCalls {
+			content, trace := s.Invoke(ctx, caller, scope, call)
+			traces = append(traces, trace)
+			if emit != nil {
+				emit(AIStreamEvent{Type: AIStreamEventTool, Tool: &trace})
+			}
+			request.Messages = append(request.Messages, ai_providers.ChatMessage{
+				Role:       "tool",
+				ToolCallID: call.ID,
+				Content:    content,
+			})
+		}
+	}
+
+	if len(last.ToolCalls) > 0 {
+		last.Content += fmt.Sprintf("\n\n（工具调用已达 %d 轮上限，请缩小问题范围后继续）", aiToolMaxRounds)
+		last.ToolCalls = nil
+	}
+	last.TokensUsed = tokens
+	last.ResponseTime = elapsed
+	if last.Metadata == nil {
+		last.Metadata = map[string]interface{}{}
+	}
+	last.Metadata["input_tokens"] = input
+	last.Metadata["output_tokens"] = output
+	return last, traces, nil
+}
+
+// setAITokenSplit 将此前各轮的输入/输出 token 数累加到响应元数据中
+func setAITokenSplit(resp *ai_providers.ChatResponse, input, output int) {
+	in, out := resp.TokenSplit()
+	if resp.Metadata == nil {
+		resp.Metadata = map[string]interface{}{}
+	}
+	resp.Metadata["input_tokens"] = in + input
+	resp.Metadata["output_tokens"] = out + output
+}
+
+// Invoke 处理模型发起的一次工具调用，返回回传给模型的结果（JSON 字符串）
+// 只读工具校验权限后直接执行；变更类工具校验权限后仅记录待确认调用
+func (s *AIToolService) Invoke(ctx context.Context, caller AIToolCaller, scope AIToolScope, call ai_providers.ToolCall) (string, AIToolTrace) {
+	trace := AIToolTrace{Name: call.Name, Arguments: call.Arguments}
+	fail := func(status string, err error) (string, AIToolTrace) {
+		trace.Status = status
+		trace.Error = err.Error()
+		return aiToolResultJSON(map[string]interface{}{"error": err.Error()}), trace
+	}
+
+	tool, ok := s.lookup(call.Name, scope)
+	if !ok {
+		return fail("failed", fmt.Errorf("unknown tool: %s", call.Name))
+	}
+	trace.Source = tool.Source
+	args := aiToolArguments(call.Arguments)
+	if !json.Valid(args) {
+		return fail("failed", errors.New("arguments must be a JSON object"))
+	}
+	if err := tool.Authorize(ctx, caller, args); err != nil {
+		s.audit(tool, caller, "", string(args), models.AuditStatusFailed, err)
+		return fail("denied", err)
+	}
+
+	if tool.Mutating {
+		confirmation, err := s.requestConfirmation(tool, caller, scope, call.ID, args)
+		if err != nil {
+			return fail("failed", err)
+		}
+		trace.Status = "pending_confirmation"
+		trace.ConfirmationID = confirmation.ID
+		return aiToolResultJSON(map[string]interface{}{
+			"status":          "pending_confirmation",
+			"confirmation_id": confirmation.ID,
+			"summary":         confirmation.Summary,
+			"message":         "该操作尚未执行。请向用户说明将要执行的操作，由用户在对话中确认或拒绝后再继续。",
+		}), trace
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, aiToolRunTimeout)
+	defer cancel()
+	result, err := tool.Run(runCtx, caller, args)
+	if err != nil {
+		s.audit(tool, caller, "", string(args), models.AuditStatusFailed, err)
+		return fail("failed", err)
+	}
+	s.audit(tool, caller, "", string(args), models.AuditStatusSuccess, nil)
+	content := aiToolResultJSON(result)
+	trace.Status = "success"
+	trace.Result = truncateText(content, aiToolTraceResultLimit)
+	return content, trace
+}
+
+func (s *AIToolService) requestConfirmation(tool *AITool, caller AIToolCaller, scope AIToolScope, callID string, args json.RawMessage) (*models.AIToolConfirmation, error) {
+	summary := tool.Name
+	if tool.Summarize != nil {
+		summary = tool.Summarize(args)
+	}
+	confirmation := &models.AIToolConfirmation{
+		ConversationID: scope.ConversationID,
+		ConfigID:       scope.ConfigID,
+		UserID:         caller.UserID,
+		Username:       caller.Username,
+		ToolName:       tool.Name,
+		ToolCallID:     callID,
+		Arguments:      string(args),
+		Summary:        summary,
+		Status:         models.AIToolConfirmationPending,
+		ExpiresAt:      time.Now().Add(aiToolConfirmationTTL),
+	}
+	if err := s.db.Create(confirmation).Error; err != nil {
+		return nil, err
+	}
+	return confirmation, nil
+}
+
+// ListConfirmations 查询用户的变更类工具调用（conversationID 为 0 时不按对话过滤）
+func (s *AIToolService) ListConfirmations(userID, conversationID uint, status string) ([]models.AIToolConfirmation, error) {
+	s.db.Model(&models.AIToolConfirmation{}).
+		Where("user_id = ? AND status = ? AND expires_at < ?", userID, models.AIToolConfirmationPending, time.Now()).
+		Update("status", models.AIToolConfirmationExpired)
+
+	query := s.db.Where("user_id = ?", userID)
+	if conversationID != 0 {
+		query = query.Where("conversation_id = ?", conversationID)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var confirmations []models.AIToolConfirmation
+	if err := query.Order("id DESC").Limit(100).Find(&confirmations).Error; err != nil {
+		return nil, err
+	}
+	return confirmations, nil
+}
+
+// Confirm 用户确认变更类工具调用：重新校验权限后按记录的参数执行一次，并把结果追加到对话
+func (s *AIToolService) Confirm(ctx context.Context, id uint, caller AIToolCaller) (*models.AIToolConfirmation, error) {
+	confirmation, err := s.claimConfirmation(id, caller.UserID, models.AIToolConfirmationRunning)
+	if err != nil {
+		return nil, err
+	}
+
+	args := json.RawMessage(confirmation.Arguments)
+	var result interface{}
+	tool, ok := s.confirmationTool(ctx, confirmation)
+	if !ok {
+		err = fmt.Errorf("unknown tool: %s", confirmation.ToolName)
+	} else if err = tool.Authorize(ctx, caller, args); err == nil {
+		runCtx, cancel := context.WithTimeout(ctx, aiToolRunTimeout)
+		result, err = tool.Run(runCtx, caller, args)
+		cancel()
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{"decided_at": &now}
+	status := models.AuditStatusSuccess
+	if err != nil {
+		status = models.AuditStatusFailed
+		updates["status"] = models.AIToolConfirmationFailed
+		updates["error"] = err.Error()
+	} else {
+		updates["status"] = models.AIToolConfirmationExecuted
+		updates["result"] = aiToolResultJSON(result)
+	}
+	if dbErr := s.db.Model(&models.AIToolConfirmation{}).Where("id = ?", id).Updates(updates).Error; dbErr != nil {
+		return nil, dbErr
+	}
+	if tool != nil {
+		s.audit(tool, caller, strconv.FormatUint(uint64(id), 10), confirmation.Arguments, status, err)
+	}
+
+	if dbErr := s.db.First(confirmation, id).Error; dbErr != nil {
+		return nil, dbErr
+	}
+	if err != nil {
+		s.appendConversationNote(confirmation, fmt.Sprintf("已确认执行：%s\n执行失败：%s", confirmation.Summary, confirmation.Error))
+	} else {
+		s.appendConversationNote(confirmation, fmt.Sprintf("已确认执行：%s\n执行结果：\n```json\n%s\n```", confirmation.Summary, confirmation.Result))
+	}
+	return confirmation, nil
+}
+
+// confirmationTool 查找待确认调用对应的工具；非内置工具按调用所属配置当前允许的 MCP 工具解析，
+// 配置已移除的工具不再执行
+func (s *AIToolService) confirmationTool(ctx context.Context, confirmation *models.AIToolConfirmation) (*AITool, bool) {
+	if tool, ok := s.Tool(confirmation.ToolName); ok || confirmation.ConfigID == 0 {
+		return tool, ok
+	}
+	var config models.AIAssistantConfig
+	if err := s.db.First(&config, confirmation.ConfigID).Error; err != nil {
+		return nil, false
+	}
+	return s.lookup(confirmation.ToolName, AIToolScope{Extra: GetMCPService().ToolsForConfig(ctx, &config)})
+}
+
+// Reject 用户拒绝变更类工具调用
+func (s *AIToolService) Reject(id uint, caller AIToolCaller) (*models.AIToolConfirmation, error) {
+	confirmation, err := s.claimConfirmation(id, caller.UserID, models.AIToolConfirmationRejected)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.First(confirmation, id).Error; err != nil {
+		return nil, err
+	}
+	s.appendConversationNote(confirmation, fmt.Sprintf("用户已拒绝执行：%s", confirmation.Summary))
+	return confirmation, nil
+}
+
+// claimConfirmation 将待确认调用原子地切换到目标状态，防止重复执行；过期的调用标记为 expired
+func (s *AIToolService) claimConfirmation(id, userID uint, status string) (*models.AIToolConfirmation, error) {
+	var confirmation models.AIToolConfirmation
+	if err := s.db.Where("id = ? AND user_id = ?", id, userID).First(&confirmation).Error; err != nil {
+		return nil, err
+	}
+	if confirmation.Status != models.AIToolConfirmationPending {
+		return nil, ErrAIToolConfirmationNotPending
+	}
+	now := time.Now()
+	if now.After(confirmation.ExpiresAt) {
+		s.db.Model(&confirmation).Update("status", models.AIToolConfirmationExpired)
+		return nil, ErrAIToolConfirmationNotPending
+	}
+	updates := map[string]interface{}{"status": status}
+	if status == models.AIToolConfirmationRejected {
+		updates["decided_at"] = &now
+	}
+	res := s.db.Model(&models.AIToolConfirmation{}).
+		Where("id = ? AND status = ?", id, models.AIToolConfirmationPending).
+		Updates(updates)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, ErrAIToolConfirmationNotPending
+	}
+	return &confirmation, nil
+}
+
+// appendConversationNote 把确认结果作为助手消息追加到对话，后续轮次的模型可据此继续
+func (s *AIToolService) appendConversationNote(confirmation *models.AIToolConfirmation, content string) {
+	if confirmation.ConversationID == 0 {
+		return
+	}
+	metadata, _ := json.Marshal(map[string]interface{}{
+		"tool_confirmation_id": confirmation.ID,
+		"tool":                 confirmation.ToolName,
+		"status":               confirmation.Status,
+	})
+	msg := &models.AIMessage{
+		ConversationID: confirmation.ConversationID,
+		Role:           "assistant",
+		Content:        content,
+		Metadata:       string(metadata),
+	}
+	if err := s.db.Create(msg).Error; err != nil {
+		log.Printf("[AITool] 追加确认结果到对话 %d 失败: %v", confirmation.ConversationID, err)
+	}
+}
+
+func (s *AIToolService) audit(tool *AITool, caller AIToolCaller, resourceID, args string, status models.AuditStatus, err error) {
+	entry := GetAuditService().NewAuditEntry(tool.Category, models.AuditActionExecute).
+		WithUser(caller.UserID, caller.Username, "").
+		WithResource("ai_tool", resourceID, tool.Name).
+		WithRequestParams(truncateText(args, aiToolAuditArgsLimit)).
+		WithStatus(status).
+		WithTags("ai", "ai_tool")
+	if err != nil {
+		entry = entry.WithErrorMessage(err.Error())
+	}
+	entry.SaveAsync()
+}
+
+// aiToolArguments 规范化模型给出的参数，空参数视为空对象
+func aiToolArguments(raw string) json.RawMessage {
+	if raw == "" {
+		return json.RawMessage("{}")
+	}
+	return json.RawMessage(raw)
+}
+
+// aiToolResultJSON 序列化工具结果，超长时截断
+func aiToolResultJSON(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf(`{"error":%q}`, err.Error())
+	}
+	return truncateText(string(data), aiToolResultLimit)
+}
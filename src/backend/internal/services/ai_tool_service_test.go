@@ -0,0 +1,136 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/services/ai_providers"
+)
+
+// scriptedProvider 依次返回预设响应，并记录每轮请求
+type scriptedProvider struct {
+	responses []*ai_providers.ChatResponse
+	requests  []ai_providers.ChatRequest
+}
+
+func (p *scriptedProvider) GetName() string { return "scripted" }
+
+func (p *scriptedProvider) Chat(ctx context.Context, request ai_providers.ChatRequest) (*ai_providers.ChatResponse, error) {
+	p.requests = append(p.requests, request)
+	resp := p.responses[0]
+	if len(p.responses) > 1 {
+		p.responses = p.responses[1:]
+	}
+	copied := *resp
+	return &copied, nil
+}
+
+func (p *scriptedProvider) GetAvailableModels(ctx context.Context) ([]ai_providers.ModelInfo, error) {
+	return nil, nil
+}
+func (p *scriptedProvider) TestConnection(ctx context.Context) error              { return nil }
+func (p *scriptedProvider) ValidateConfig(config *models.AIAssistantConfig) error { return nil }
+func (p *scriptedProvider) GetSupportedCapabilities() []string                    { return nil }
+func (p *scriptedProvider) SupportsToolCalling() bool                             { return true }
+
+func newTestAIToolService() *AIToolService {
+	s := &AIToolService{tools: make(map[string]*AITool)}
+	s.Register(&AITool{Name: "slurm_list_nodes", Description: "查询节点"})
+	s.Register(&AITool{Name: "slurm_scale_down", Description: "缩容", Mutating: true})
+	return s
+}
+
+func TestAIToolDefinitions(t *testing.T) {
+	defs := newTestAIToolService().Definitions()
+	if len(defs) != 2 || defs[0].Name != "slurm_list_nodes" || defs[1].Name != "slurm_scale_down" {
+		t.Fatalf("工具定义应按注册顺序返回: %+v", defs)
+	}
+	if strings.Contains(defs[0].Description, "确认") || !strings.Contains(defs[1].Description, "确认") {
+		t.Fatalf("仅变更类工具应提示需要确认: %+v", defs)
+	}
+}
+
+func TestAIToolConverseLoop(t *testing.T) {
+	provider := &scriptedProvider{responses: []*ai_providers.ChatResponse{
+		{TokensUsed: 10, ResponseTime: 5, ToolCalls: []ai_providers.ToolCall{
+			{ID: "call_1", Name: "no_such_tool", Arguments: `{}`},
+		}, Metadata: map[string]interface{}{"input_tokens": 8, "output_tokens": 2}},
+		{Content: "集群状态正常", TokensUsed: 7, ResponseTime: 3, Metadata: map[string]interface{}{"input_tokens": 5, "output_tokens": 2}},
+	}}
+	request := ai_providers.ChatRequest{Messages: []ai_providers.ChatMessage{{Role: "user", Content: "查看节点"}}}
+
+	resp, traces, err := newTestAIToolService().Converse(context.Background(), provider, request, AIToolCaller{UserID: 1}, AIToolScope{ConversationID: 1})
+	if err != nil {
+		t.Fatalf("converse failed: %v", err)
+	}
+	if resp.Content != "集群状态正常" || resp.TokensUsed != 17 || resp.ResponseTime != 8 {
+		t.Fatalf("应返回最终回复并累加各轮用量: %+v", resp)
+	}
+	if input, output := resp.TokenSplit(); input != 13 || output != 4 {
+		t.Fatalf("应累加各轮输入/输出 token: %d/%d", input, output)
+	}
+	if len(traces) != 1 || traces[0].Status != "failed" || !strings.Contains(traces[0].Error, "unknown tool") {
+		t.Fatalf("未知工具应作为失败结果回传: %+v", traces)
+	}
+
+	second := provider.requests[1]
+	if len(second.Tools) != 2 || len(second.Messages) != 3 {
+		t.Fatalf("第二轮请求应携带工具定义及工具调用往返消息: %+v", second)
+	}
+	if m := second.Messages[1]; m.Role != "assistant" || len(m.ToolCalls) != 1 {
+		t.Fatalf("应回放模型的工具调用: %+v", m)
+	}
+	if m := second.Messages[2]; m.Role != "tool" || m.ToolCallID != "call_1" || !strings.Contains(m.Content, "error") {
+		t.Fatalf("工具结果应以 tool 消息回传: %+v", m)
+	}
+	if len(request.Messages) != 1 {
+		t.Fatal("不应修改调用方的消息列表")
+	}
+}
+
+func TestAIToolConverseRoundLimit(t *testing.T) {
+	provider := &scriptedProvider{responses: []*ai_providers.ChatResponse{
+		{Content: "继续查询", TokensUsed: 1, ToolCalls: []ai_providers.ToolCall{{ID: "c", Name: "no_such_tool"}}},
+	}}
+	resp, traces, err := newTestAIToolService().Converse(context.Background(), provider, ai_providers.ChatRequest{}, AIToolCaller{}, AIToolScope{})
+	if err != nil {
+		t.Fatalf("converse failed: %v", err)
+	}
+	if len(provider.requests) != aiToolMaxRounds || len(traces) != aiToolMaxRounds {
+		t.Fatalf("应在 %d 轮后停止: requests=%d traces=%d", aiToolMaxRounds, len(provider.requests), len(traces))
+	}
+	if len(resp.ToolCalls) != 0 || !strings.Contains(resp.Content, "上限") || resp.TokensUsed != aiToolMaxRounds {
+		t.Fatalf("达到上限时应返回提示且不再携带工具调用: %+v", resp)
+	}
+}
+
+func TestValidateAIToolNames(t *testing.T) {
+	if err := validateAIToolNames("nodes", []string{"cn01", "gpu-node.02"}, 10); err != nil {
+		t.Fatalf("合法节点名被拒绝: %v", err)
+	}
+	for _, names := range [][]string{
+		nil,
+		{"cn*"},
+		{"cn01;reboot"},
+		{"cn01 State=DOWN"},
+		{"cn01", "cn01"},
+		{"a", "b", "c"},
+	} {
+		if err := validateAIToolNames("nodes", names, 2); err == nil {
+			t.Errorf("应拒绝 %q", names)
+		}
+	}
+}
+
+func TestAIToolCallerFromUser(t *testing.T) {
+	user := models.User{ID: 3, Username: "alice", Roles: []models.Role{{Name: "user"}, {Name: "admin"}}}
+	if caller := aiToolCallerFromUser(user); caller.UserID != 3 || caller.Username != "alice" || !caller.IsAdmin {
+		t.Fatalf("admin 角色应判定为管理员: %+v", caller)
+	}
+	user.Roles = []models.Role{{Name: "super-admin"}}
+	if aiToolCallerFromUser(user).IsAdmin {
+		t.Fatal("仅 admin 角色视为管理员")
+	}
+}
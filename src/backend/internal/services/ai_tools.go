@@ -0,0 +1,518 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/database"
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// 单个工具返回的最大条目数
+const aiToolMaxItems = 200
+
+// aiToolSaltReadOnlyFunctions salt_run 允许调用的只读 Salt 函数
+var aiToolSaltReadOnlyFunctions = []string{
+	"test.ping",
+	"grains.get",
+	"grains.item",
+	"grains.items",
+	"status.uptime",
+	"status.loadavg",
+	"status.meminfo",
+	"status.cpuinfo",
+	"status.diskusage",
+	"disk.usage",
+	"network.interfaces",
+	"network.ip_addrs",
+	"pkg.version",
+	"pkg.list_pkgs",
+	"service.status",
+	"service.get_all",
+	"sys.list_functions",
+}
+
+// aiToolSlurmNodeActions slurm_set_node_state 支持的操作及对应的 Slurm 节点状态
+var aiToolSlurmNodeActions = map[string]string{
+	"drain":  "DRAIN",
+	"resume": "RESUME",
+	"down":   "DOWN",
+}
+
+var aiToolNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// builtinAITools 内置集群工具：Slurm 节点/作业查询、只读 Salt 函数、监控指标查询、失败作业诊断、节点状态变更与缩容
+func builtinAITools(db *gorm.DB) []*AITool {
+	slurmDB := database.GetSlurmDB()
+	if slurmDB == nil {
+		slurmDB = db
+	}
+	slurm := NewSlurmServiceWithStores(slurmDB, db)
+	perms := NewClusterPermissionService(db)
+
+	requireSlurm := func(ctx context.Context, caller AIToolCaller, verb models.ClusterPermissionVerb, partition string) error {
+		if caller.IsAdmin {
+			return nil
+		}
+		result, err := perms.CheckSlurmAccess(ctx, caller.UserID, defaultSlurmClusterID, verb, partition)
+		if err != nil {
+			return err
+		}
+		if !result.Allowed {
+			return fmt.Errorf("permission denied: %s", result.Reason)
+		}
+		return nil
+	}
+
+	return []*AITool{
+		{
+			Name:        "slurm_list_nodes",
+			Description: "查询 Slurm 集群节点及其状态、CPU、内存和分区",
+			Category:    models.AuditCategorySlurm,
+			Parameters: aiToolSchema(map[string]interface{}{
+				"partition": aiToolString("按分区过滤"),
+				"state":     aiToolString("按状态过滤，如 idle、allocated、mixed、drained、down"),
+			}),
+			Authorize: func(ctx context.Context, caller AIToolCaller, raw json.RawMessage) error {
+				var args struct{ Partition string }
+				if err := json.Unmarshal(raw, &args); err != nil {
+					return err
+				}
+				return requireSlurm(ctx, caller, models.VerbView, args.Partition)
+			},
+			Run: func(ctx context.Context, caller AIToolCaller, raw json.RawMessage) (interface{}, error) {
+				var args struct{ Partition, State string }
+				if err := json.Unmarshal(raw, &args); err != nil {
+					return nil, err
+				}
+				nodes, unavailable, err := slurm.GetNodes(ctx)
+				if err != nil {
+					return nil, err
+				}
+				if unavailable {
+					return nil, errors.New("slurm cluster is unreachable")
+				}
+				matched := make([]SlurmNode, 0, len(nodes))
+				for _, node := range nodes {
+					if args.Partition != "" && strings.TrimSuffix(node.Partition, "*") != args.Partition {
+						continue
+					}
+					if args.State != "" && !strings.Contains(strings.ToLower(node.State), strings.ToLower(args.State)) {
+						continue
+					}
+					matched = append(matched, node)
+				}
+				return aiToolList("nodes", matched), nil
+			},
+		},
+		{
+			Name:        "slurm_list_jobs",
+			Description: "查询 Slurm 作业队列，包括作业状态、用户、分区、运行时长和等待原因",
+			Category:    models.AuditCategorySlurm,
+			Parameters: aiToolSchema(map[string]interface{}{
+				"user":      aiToolString("按提交用户过滤"),
+				"state":     aiToolString("按状态过滤，如 RUNNING、PENDING"),
+				"partition": aiToolString("按分区过滤"),
+			}),
+			Authorize: func(ctx context.Context, caller AIToolCaller, raw json.RawMessage) error {
+				var args struct{ Partition string }
+				if err := json.Unmarshal(raw, &args); err != nil {
+					return err
+				}
+				return requireSlurm(ctx, caller, models.VerbView, args.Partition)
+			},
+			Run: func(ctx context.Context, caller AIToolCaller, raw json.RawMessage) (interface{}, error) {
+				var args struct{ User, State, Partition string }
+				if err := json.Unmarshal(raw, &args); err != nil {
+					return nil, err
+				}
+				jobs, unavailable, err := slurm.GetJobs(ctx)
+				if err != nil {
+					return nil, err
+				}
+				if unavailable {
+					return nil, errors.New("slurm cluster is unreachable")
+				}
+				matched := make([]SlurmJob, 0, len(jobs))
+				for _, job := range jobs {
+					if args.User != "" && job.User != args.User {
+						continue
+					}
+					if args.State != "" && !strings.EqualFold(job.State, args.State) {
+						continue
+					}
+					if args.Partition != "" && job.Partition != args.Partition {
+						continue
+					}
+					matched = append(matched, job)
+				}
+				return aiToolList("jobs", matched), nil
+			},
+		},
+		{
+			Name:        "salt_run",
+			Description: "在指定 Salt minion 上执行只读 Salt 函数（如 grains.items、status.loadavg、disk.usage、service.status）",
+			Category:    models.AuditCategorySaltstack,
+			Parameters: aiToolSchema(map[string]interface{}{
+				"minions": map[string]interface{}{
+					"type":        "array",
+					"items":       map[string]interface{}{"type": "string"},
+					"minItems":    1,
+					"maxItems":    50,
+					"description": "目标 minion ID 列表（不支持通配符）",
+				},
+				"function": map[string]interface{}{
+					"type":        "string",
+					"enum":        aiToolSaltReadOnlyFunctions,
+					"description": "只读 Salt 函数",
+				},
+				"args": map[string]interface{}{
+					"type":        "array",
+					"items":       map[string]interface{}{"type": "string"},
+					"description": "函数位置参数，如 grains.item 的 grain 名称",
+				},
+			}, "minions", "function"),
+			Authorize: func(ctx context.Context, caller AIToolCaller, raw json.RawMessage) error {
+				var args struct {
+					Minions  []string
+					Function string
+				}
+				if err := json.Unmarshal(raw, &args); err != nil {
+					return err
+				}
+				if !containsString(aiToolSaltReadOnlyFunctions, args.Function) {
+					return fmt.Errorf("function %q is not an allowed read-only function", args.Function)
+				}
+				if err := validateAIToolNames("minions", args.Minions, 50); err != nil {
+					return err
+				}
+				if caller.IsAdmin {
+					return nil
+				}
+				for _, minion := range args.Minions {
+					result, err := perms.CheckSaltstackAccess(ctx, caller.UserID, "", models.VerbExecute, minion, args.Function)
+					if err != nil {
+						return err
+					}
+					if !result.Allowed {
+						return fmt.Errorf("permission denied on minion %s: %s", minion, result.Reason)
+					}
+				}
+				return nil
+			},
+			Run: func(ctx context.Context, caller AIToolCaller, raw json.RawMessage) (interface{}, error) {
+				var args struct {
+					Minions  []string
+					Function string
+					Args     []string
+				}
+				if err := json.Unmarshal(raw, &args); err != nil {
+					return nil, err
+				}
+				groups := map[string][]string{"": args.Minions}
+				if masterService := GetSaltMasterService(); masterService != nil {
+					var unresolved []string
+					groups, unresolved = masterService.GroupMinionsByMaster(args.Minions)
+					if len(unresolved) > 0 {
+						groups[""] = append(groups[""], unresolved...)
+					}
+				}
+				funcArgs := make([]interface{}, 0, len(args.Args))
+				for _, a := range args.Args {
+					funcArgs = append(funcArgs, a)
+				}
+				results := map[string]interface{}{}
+				for masterID, minions := range groups {
+					salt, err := SaltServiceForMaster(masterID)
+					if err != nil {
+						return nil, err
+					}
+					ret, err := salt.RunLocal(ctx, strings.Join(minions, ","), "list", args.Function, funcArgs, nil, 30)
+					if err != nil {
+						return nil, err
+					}
+					for minion, value := range ret {
+						results[minion] = value
+					}
+				}
+				for _, minion := range args.Minions {
+					if _, ok := results[minion]; !ok {
+						results[minion] = "no response"
+					}
+				}
+				return results, nil
+			},
+		},
+		{
+			Name:        "query_metrics",
+			Description: "查询监控指标：指定 hostname 返回该主机的 CPU、内存、磁盘、网络指标；promql 执行即时查询（仅管理员）",
+			Category:    models.AuditCategoryMonitor,
+			Parameters: aiToolSchema(map[string]interface{}{
+				"hostname": aiToolString("主机名或 IP"),
+				"promql":   aiToolString("PromQL 即时查询表达式"),
+			}),
+			Authorize: func(ctx context.Context, caller AIToolCaller, raw json.RawMessage) error {
+				var args struct{ Hostname, PromQL string }
+				if err := json.Unmarshal(raw, &args); err != nil {
+					return err
+				}
+				switch {
+				case (args.Hostname == "") == (args.PromQL == ""):
+					return errors.New("exactly one of hostname or promql is required")
+				case caller.IsAdmin:
+					return nil
+				case args.PromQL != "":
+					return errors.New("permission denied: promql queries require admin role")
+				}
+				inventory := GetInventoryService()
+				if inventory == nil {
+					return errors.New("permission denied: host inventory is unavailable")
+				}
+				hosts, err := inventory.VisibleHosts(ctx, InventoryViewer{UserID: caller.UserID, Username: caller.Username}, InventoryExportQuery{})
+				if err != nil {
+					return err
+				}
+				for _, host := range hosts {
+					if strings.EqualFold(host.Hostname, args.Hostname) || host.PrimaryIP == args.Hostname || containsString(host.IPs, args.Hostname) {
+						return nil
+					}
+				}
+				return fmt.Errorf("permission denied: host %s is not visible to you", args.Hostname)
+			},
+			Run: func(ctx context.Context, caller AIToolCaller, raw json.RawMessage) (interface{}, error) {
+				var args struct{ Hostname, PromQL string }
+				if err := json.Unmarshal(raw, &args); err != nil {
+					return nil, err
+				}
+				metrics := NewMetricsService()
+				if args.PromQL != "" {
+					return metrics.Query(args.PromQL)
+				}
+				return metrics.GetHostMetrics(args.Hostname)
+			},
+		},
+		{
+			Name:        "diagnose_job",
+			Description: "诊断失败的作业：根据 sacct 记录、stderr、节点状态、监控指标与 slurmd 日志分析失败原因并给出修复建议；默认返回已保存的诊断",
+			Category:    models.AuditCategorySlurm,
+			Parameters: aiToolSchema(map[string]interface{}{
+				"job_id":  map[string]interface{}{"type": "integer", "description": "平台作业ID"},
+				"refresh": map[string]interface{}{"type": "boolean", "description": "忽略已保存的诊断并重新分析"},
+			}, "job_id"),
+			Authorize: func(ctx context.Context, caller AIToolCaller, raw json.RawMessage) error {
+				var args struct {
+					JobID uint `json:"job_id"`
+				}
+				if err := json.Unmarshal(raw, &args); err != nil {
+					return err
+				}
+				var job models.Job
+				if err := db.Select("id", "user_id").First(&job, args.JobID).Error; err != nil {
+					return fmt.Errorf("job %d not found", args.JobID)
+				}
+				if !caller.IsAdmin && job.UserID != caller.UserID {
+					return fmt.Errorf("permission denied: job %d belongs to another user", args.JobID)
+				}
+				return nil
+			},
+			Run: func(ctx context.Context, caller AIToolCaller, raw json.RawMessage) (interface{}, error) {
+				var args struct {
+					JobID   uint `json:"job_id"`
+					Refresh bool `json:"refresh"`
+				}
+				if err := json.Unmarshal(raw, &args); err != nil {
+					return nil, err
+				}
+				diagnoser := GetJobDiagnosisService()
+				if diagnoser == nil {
+					return nil, errors.New("job diagnosis service is unavailable")
+				}
+				job, err := diagnoser.LoadJob(args.JobID, caller)
+				if err != nil {
+					return nil, err
+				}
+				if job.Diagnosis != nil && !args.Refresh {
+					return job.Diagnosis, nil
+				}
+				diagnosis, _, err := diagnoser.Diagnose(ctx, args.JobID, caller, models.JobDiagnoseRequest{})
+				return diagnosis, err
+			},
+		},
+		{
+			Name:        "slurm_set_node_state",
+			Description: "变更 Slurm 节点状态：drain 排空节点、resume 恢复节点、down 下线节点",
+			Category:    models.AuditCategorySlurm,
+			Mutating:    true,
+			Parameters: aiToolSchema(map[string]interface{}{
+				"nodes": map[string]interface{}{
+					"type":        "array",
+					"items":       map[string]interface{}{"type": "string"},
+					"minItems":    1,
+					"description": "节点名列表",
+				},
+				"action": map[string]interface{}{
+					"type": "string",
+					"enum": []string{"drain", "resume", "down"},
+				},
+				"reason": aiToolString("变更原因，drain/down 时记录到 Slurm"),
+			}, "nodes", "action"),
+			Authorize: func(ctx context.Context, caller AIToolCaller, raw json.RawMessage) error {
+				var args struct {
+					Nodes  []string
+					Action string
+				}
+				if err := json.Unmarshal(raw, &args); err != nil {
+					return err
+				}
+				if _, ok := aiToolSlurmNodeActions[args.Action]; !ok {
+					return fmt.Errorf("unsupported action: %s", args.Action)
+				}
+				if err := validateAIToolNames("nodes", args.Nodes, 100); err != nil {
+					return err
+				}
+				return requireSlurm(ctx, caller, models.VerbManage, "")
+			},
+			Summarize: func(raw json.RawMessage) string {
+				var args struct {
+					Nodes          []string
+					Action, Reason string
+				}
+				_ = json.Unmarshal(raw, &args)
+				summary := fmt.Sprintf("将 Slurm 节点 %s 设置为 %s", strings.Join(args.Nodes, ","), aiToolSlurmNodeActions[args.Action])
+				if args.Reason != "" {
+					summary += "（原因：" + args.Reason + "）"
+				}
+				return summary
+			},
+			Run: func(ctx context.Context, caller AIToolCaller, raw json.RawMessage) (interface{}, error) {
+				var args struct {
+					Nodes          []string
+					Action, Reason string
+				}
+				if err := json.Unmarshal(raw, &args); err != nil {
+					return nil, err
+				}
+				state := aiToolSlurmNodeActions[args.Action]
+				reason := strings.NewReplacer("'", "", "\"", "", "\n", " ").Replace(args.Reason)
+				if reason == "" && state != "RESUME" {
+					reason = fmt.Sprintf("由 %s 通过 AI 助手执行 %s 操作", caller.Username, state)
+				}
+
+				if slurm.GetUseSlurmRestd() {
+					var failed []string
+					for _, node := range args.Nodes {
+						if err := slurm.UpdateNodeViaAPI(ctx, node, SlurmNodeUpdate{State: state, Reason: reason}); err != nil {
+							failed = append(failed, node)
+						}
+					}
+					if len(failed) > 0 {
+						return nil, fmt.Errorf("failed to update nodes: %s", strings.Join(failed, ","))
+					}
+					return map[string]interface{}{"nodes": args.Nodes, "state": state}, nil
+				}
+
+				command := fmt.Sprintf("scontrol update NodeName=%s State=%s", strings.Join(args.Nodes, ","), state)
+				if reason != "" {
+					command += fmt.Sprintf(" Reason='%s'", reason)
+				}
+				output, err := slurm.ExecuteSlurmCommand(ctx, command)
+				if err != nil {
+					return nil, fmt.Errorf("%v: %s", err, output)
+				}
+				return map[string]interface{}{"nodes": args.Nodes, "state": state, "output": output}, nil
+			},
+		},
+		{
+			Name:        "slurm_scale_down",
+			Description: "缩容 Slurm 集群：从集群中移除指定节点",
+			Category:    models.AuditCategorySlurm,
+			Mutating:    true,
+			Parameters: aiToolSchema(map[string]interface{}{
+				"nodes": map[string]interface{}{
+					"type":        "array",
+					"items":       map[string]interface{}{"type": "string"},
+					"minItems":    1,
+					"description": "要移除的节点名列表",
+				},
+			}, "nodes"),
+			Authorize: func(ctx context.Context, caller AIToolCaller, raw json.RawMessage) error {
+				var args struct{ Nodes []string }
+				if err := json.Unmarshal(raw, &args); err != nil {
+					return err
+				}
+				if err := validateAIToolNames("nodes", args.Nodes, 100); err != nil {
+					return err
+				}
+				return requireSlurm(ctx, caller, models.VerbScale, "")
+			},
+			Summarize: func(raw json.RawMessage) string {
+				var args struct{ Nodes []string }
+				_ = json.Unmarshal(raw, &args)
+				return "从 Slurm 集群移除节点 " + strings.Join(args.Nodes, ",")
+			},
+			Run: func(ctx context.Context, caller AIToolCaller, raw json.RawMessage) (interface{}, error) {
+				var args struct{ Nodes []string }
+				if err := json.Unmarshal(raw, &args); err != nil {
+					return nil, err
+				}
+				return slurm.ScaleDown(ctx, args.Nodes)
+			},
+		},
+	}
+}
+
+// aiToolSchema 构造 object 类型的 JSON Schema
+func aiToolSchema(properties map[string]interface{}, required ...string) map[string]interface{} {
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func aiToolString(description string) map[string]interface{} {
+	return map[string]interface{}{"type": "string", "description": description}
+}
+
+// aiToolList 返回列表结果，超过上限时截断并标注总数
+func aiToolList[T any](key string, items []T) map[string]interface{} {
+	result := map[string]interface{}{"total": len(items)}
+	if len(items) > aiToolMaxItems {
+		items = items[:aiToolMaxItems]
+		result["truncated"] = true
+	}
+	result[key] = items
+	return result
+}
+
+// validateAIToolNames 校验节点名/minion ID，拒绝通配符和命令注入字符
+func validateAIToolNames(field string, names []string, max int) error {
+	if len(names) == 0 {
+		return fmt.Errorf("%s is required", field)
+	}
+	if len(names) > max {
+		return fmt.Errorf("too many %s: %d (max %d)", field, len(names), max)
+	}
+	for _, name := range names {
+		if !aiToolNamePattern.MatchString(name) {
+			return fmt.Errorf("invalid name in %s: %q", field, name)
+		}
+	}
+	sorted := append([]string(nil), names...)
+	sort.Strings(sorted)
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i] == sorted[i-1] {
+			return fmt.Errorf("duplicate name in %s: %q", field, sorted[i])
+		}
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package services
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// splitAllowedHosts 拆分逗号分隔的主机允许列表，支持 host、host:port 与 *.example.com
+func splitAllowedHosts(value string) []string {
+	var hosts []string
+	for _, host := range strings.Split(value, ",") {
+		if host = strings.ToLower(strings.TrimSpace(host)); host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
+
+// hostAllowed 判断 URL 的主机是否匹配允许列表中的任一项
+func hostAllowed(allowed []string, u *url.URL) bool {
+	host := strings.ToLower(u.Hostname())
+	hostPort := host
+	if port := u.Port(); port != "" {
+		hostPort = strings.ToLower(u.Host)
+	}
+	for _, pattern := range allowed {
+		switch {
+		case pattern == host || pattern == hostPort:
+			return true
+		case strings.HasPrefix(pattern, "*.") && strings.HasSuffix(host, pattern[1:]):
+			return true
+		}
+	}
+	return false
+}
+
+// checkAllowedURL 校验后端主动请求的地址：仅允许 http/https，且主机须在环境变量 envName 列出的允许列表中，
+// 未配置时拒绝所有主机，防止借用户提供的地址访问内网（SSRF）
+func checkAllowedURL(rawURL, envName string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid url %q", rawURL)
+	}
+	if !hostAllowed(splitAllowedHosts(os.Getenv(envName)), u) {
+		return fmt.Errorf("host %s is not in %s", u.Host, envName)
+	}
+	return nil
+}
+
+// checkWebhookURL 校验通知 Webhook 地址，主机须在 WEBHOOK_ALLOWED_HOSTS 中
+func checkWebhookURL(rawURL string) error {
+	return checkAllowedURL(rawURL, "WEBHOOK_ALLOWED_HOSTS")
+}
@@ -0,0 +1,34 @@
+package services
+
+import "testing"
+
+func TestCheckAllowedURL(t *testing.T) {
+	t.Setenv("TEST_ALLOWED_HOSTS", " Hooks.internal , api.example.com:8443,*.corp.example ")
+	for _, raw := range []string{
+		"https://hooks.internal/notify",
+		"http://HOOKS.internal:9000/x",
+		"https://api.example.com:8443/hook",
+		"https://chat.corp.example/hook",
+	} {
+		if err := checkAllowedURL(raw, "TEST_ALLOWED_HOSTS"); err != nil {
+			t.Errorf("%s 应被允许: %v", raw, err)
+		}
+	}
+	for _, raw := range []string{
+		"https://api.example.com/hook",
+		"https://corp.example/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"file:///etc/passwd",
+		"gopher://hooks.internal/",
+		"not a url",
+	} {
+		if err := checkAllowedURL(raw, "TEST_ALLOWED_HOSTS"); err == nil {
+			t.Errorf("%s 应被拒绝", raw)
+		}
+	}
+
+	t.Setenv("TEST_ALLOWED_HOSTS", "")
+	if err := checkAllowedURL("https://hooks.internal/notify", "TEST_ALLOWED_HOSTS"); err == nil {
+		t.Error("未配置允许列表时应拒绝所有主机")
+	}
+}
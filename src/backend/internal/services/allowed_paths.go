@@ -0,0 +1,39 @@
+package services
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// splitAllowedDirs 拆分冒号分隔的目录列表（用于 *_DIR/*_DIRS 环境变量）
+func splitAllowedDirs(value string) []string {
+	var dirs []string
+	for _, dir := range strings.Split(value, ":") {
+		if dir = strings.TrimSpace(dir); dir != "" {
+			dirs = append(dirs, filepath.Clean(dir))
+		}
+	}
+	return dirs
+}
+
+// resolveAllowedPath 解析符号链接后确认路径位于允许的目录内，防止读取/执行任意文件
+func resolveAllowedPath(path string, allowed []string) (string, error) {
+	if path == "" || !filepath.IsAbs(path) {
+		return "", fmt.Errorf("path must be absolute: %q", path)
+	}
+	resolved, err := filepath.EvalSymlinks(filepath.Clean(path))
+	if err != nil {
+		return "", fmt.Errorf("path %s: %v", path, err)
+	}
+	for _, dir := range allowed {
+		if realDir, err := filepath.EvalSymlinks(dir); err == nil {
+			dir = realDir
+		}
+		rel, err := filepath.Rel(dir, resolved)
+		if err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return resolved, nil
+		}
+	}
+	return "", fmt.Errorf("path %s is outside the allowed directories %v", path, allowed)
+}
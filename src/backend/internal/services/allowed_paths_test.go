@@ -0,0 +1,43 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestResolveAllowedPath(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := resolveAllowedPath("/etc/passwd", []string{dir}); err == nil {
+		t.Fatal("允许目录之外的路径应被拒绝")
+	}
+	if _, err := resolveAllowedPath(dir+"/../x", []string{dir}); err == nil {
+		t.Fatal("越出允许目录的相对路径应被拒绝")
+	}
+	if _, err := resolveAllowedPath("relative/x", []string{dir}); err == nil {
+		t.Fatal("相对路径应被拒绝")
+	}
+
+	file := filepath.Join(dir, "server")
+	if err := os.WriteFile(file, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := resolveAllowedPath(file, []string{dir}); err != nil || filepath.Base(got) != "server" {
+		t.Fatalf("允许目录内的文件应通过: %q %v", got, err)
+	}
+
+	link := filepath.Join(dir, "passwd")
+	if err := os.Symlink("/etc/passwd", link); err == nil {
+		if _, err := resolveAllowedPath(link, []string{dir}); err == nil {
+			t.Fatal("指向允许目录之外的符号链接应被拒绝")
+		}
+	}
+}
+
+func TestSplitAllowedDirs(t *testing.T) {
+	got := splitAllowedDirs(" /a/b/ :: /c/../d:")
+	if want := []string{"/a/b", "/d"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
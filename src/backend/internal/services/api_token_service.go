@@ -0,0 +1,159 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/database"
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	// 未指定有效期时的默认天数
+	defaultAPITokenDays = 90
+	// 每个用户最多持有的有效令牌数
+	maxAPITokensPerUser = 20
+	// 最后使用时间的更新间隔，避免每次请求都写库
+	apiTokenTouchInterval = time.Minute
+)
+
+var (
+	// ErrInvalidAPIToken 令牌不存在、已吊销、已过期或所属用户已禁用
+	ErrInvalidAPIToken = errors.New("invalid or expired api token")
+	// ErrTooManyAPITokens 用户有效令牌数已达上限
+	ErrTooManyAPITokens = errors.New("too many active api tokens")
+)
+
+// APITokenService 个人 API 令牌管理与认证
+type APITokenService struct {
+	db *gorm.DB
+}
+
+var (
+	apiTokenServiceInstance *APITokenService
+	apiTokenServiceOnce     sync.Once
+)
+
+// NewAPITokenService 创建 API 令牌服务（单例）
+func NewAPITokenService(db *gorm.DB) *APITokenService {
+	apiTokenServiceOnce.Do(func() {
+		apiTokenServiceInstance = &APITokenService{db: db}
+		if err := db.AutoMigrate(&models.APIToken{}); err != nil {
+			log.Printf("[APIToken] 自动迁移失败: %v", err)
+		}
+	})
+	return apiTokenServiceInstance
+}
+
+// GetAPITokenService 获取 API 令牌服务实例，未初始化时使用全局数据库连接
+func GetAPITokenService() *APITokenService {
+	if apiTokenServiceInstance == nil && database.DB != nil {
+		return NewAPITokenService(database.DB)
+	}
+	return apiTokenServiceInstance
+}
+
+// Create 为用户创建令牌，返回仅此一次可见的明文令牌
+func (s *APITokenService) Create(userID uint, req models.CreateAPITokenRequest) (string, *models.APIToken, error) {
+	var active int64
+	if err := s.db.Model(&models.APIToken{}).
+		Where("user_id = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", userID, time.Now()).
+		Count(&active).Error; err != nil {
+		return "", nil, err
+	}
+	if active >= maxAPITokensPerUser {
+		return "", nil, ErrTooManyAPITokens
+	}
+
+	plain, err := generateAPIToken()
+	if err != nil {
+		return "", nil, err
+	}
+	days := req.ExpiresInDays
+	if days <= 0 {
+		days = defaultAPITokenDays
+	}
+	expiresAt := time.Now().AddDate(0, 0, days)
+	token := &models.APIToken{
+		UserID:    userID,
+		Name:      strings.TrimSpace(req.Name),
+		TokenHash: hashAPIToken(plain),
+		Hint:      plain[:len(models.APITokenPrefix)+6],
+		ExpiresAt: &expiresAt,
+	}
+	if err := s.db.Create(token).Error; err != nil {
+		return "", nil, err
+	}
+	return plain, token, nil
+}
+
+// List 列出用户的令牌（不含明文）
+func (s *APITokenService) List(userID uint) ([]models.APIToken, error) {
+	var tokens []models.APIToken
+	err := s.db.Where("user_id = ?", userID).Order("id DESC").Find(&tokens).Error
+	return tokens, err
+}
+
+// Revoke 吊销用户自己的令牌
+func (s *APITokenService) Revoke(userID, id uint) error {
+	now := time.Now()
+	res := s.db.Model(&models.APIToken{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", &now)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Authenticate 校验令牌并返回所属用户（含角色）
+func (s *APITokenService) Authenticate(plain, clientIP string) (*models.User, *models.APIToken, error) {
+	if !IsAPIToken(plain) {
+		return nil, nil, ErrInvalidAPIToken
+	}
+	var token models.APIToken
+	if err := s.db.Where("token_hash = ?", hashAPIToken(plain)).First(&token).Error; err != nil {
+		return nil, nil, ErrInvalidAPIToken
+	}
+	now := time.Now()
+	if !token.Active(now) {
+		return nil, nil, ErrInvalidAPIToken
+	}
+	var user models.User
+	if err := s.db.Preload("Roles").First(&user, token.UserID).Error; err != nil || !user.IsActive {
+		return nil, nil, ErrInvalidAPIToken
+	}
+
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) > apiTokenTouchInterval {
+		s.db.Model(&token).Updates(map[string]interface{}{"last_used_at": &now, "last_used_ip": clientIP})
+	}
+	return &user, &token, nil
+}
+
+// IsAPIToken 判断凭据是否为个人 API 令牌格式
+func IsAPIToken(value string) bool {
+	return strings.HasPrefix(value, models.APITokenPrefix) && len(value) == len(models.APITokenPrefix)+64
+}
+
+func generateAPIToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return models.APITokenPrefix + hex.EncodeToString(buf), nil
+}
+
+func hashAPIToken(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/utils"
+)
+
+func TestBatchInstallCredentialsRoundTrip(t *testing.T) {
+	if err := utils.InitEncryptionService("batch-install-test-key"); err != nil {
+		t.Fatal(err)
+	}
+
+	req := BatchInstallRequest{
+		MasterHost:      "10.0.0.1",
+		SudoPass:        "sudo-secret",
+		AutoAccept:      true,
+		InstallCategraf: true,
+		N9EHost:         "10.0.0.2",
+		JumpHosts:       models.SSHJumpChain{{Host: "bastion", Port: 22, Username: "ops", Password: "bastion-secret"}},
+		Hosts: []HostInstallConfig{
+			{Host: "10.0.0.5", Port: 22, Username: "root", Password: "host-secret"},
+			{Host: "10.0.0.6", Port: 22, Username: "ubuntu", Password: "other-secret",
+				JumpHosts: models.SSHJumpChain{{Host: "inner", Port: 2222, Username: "ops", PrivateKey: "KEY"}}},
+		},
+	}
+
+	sealed, err := sealBatchInstallRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"host-secret", "sudo-secret", "bastion-secret"} {
+		if strings.Contains(sealed, secret) {
+			t.Fatalf("加密结果中不应出现明文 %q", secret)
+		}
+	}
+
+	restored, err := openBatchInstallRequest(sealed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.SudoPass != "sudo-secret" || !restored.InstallCategraf || restored.N9EHost != "10.0.0.2" {
+		t.Fatalf("请求参数未完整恢复: %+v", restored)
+	}
+	if len(restored.Hosts) != 2 || restored.Hosts[0].Password != "host-secret" {
+		t.Fatalf("主机凭据未恢复: %+v", restored.Hosts)
+	}
+	if len(restored.JumpHosts) != 1 || restored.JumpHosts[0].Password != "bastion-secret" {
+		t.Fatalf("请求级跳板机凭据未恢复: %+v", restored.JumpHosts)
+	}
+	if len(restored.Hosts[1].JumpHosts) != 1 || restored.Hosts[1].JumpHosts[0].PrivateKey != "KEY" {
+		t.Fatalf("主机级跳板机凭据未恢复: %+v", restored.Hosts[1].JumpHosts)
+	}
+	if len(restored.Hosts[0].JumpHosts) != 0 {
+		t.Fatalf("未配置跳板机的主机不应恢复出跳板机: %+v", restored.Hosts[0].JumpHosts)
+	}
+}
+
+func TestPlanBatchInstallRetry(t *testing.T) {
+	hosts := []HostInstallConfig{{Host: "a"}, {Host: "b"}, {Host: "c"}, {Host: "d"}}
+	results := []models.InstallationHostResult{
+		{ID: 1, Host: "a", Status: "failed", Stage: "connect"},
+		{ID: 5, Host: "a", Status: "success", Stage: "done"},
+		{ID: 2, Host: "b", Status: "failed", Stage: "accept_key"},
+		{ID: 6, Host: "c", Status: "success", Stage: "done"},
+		{ID: 7, Host: "c", Status: "failed", Stage: "ping"},
+	}
+
+	retry, resume, prior := planBatchInstallRetry(hosts, results, nil)
+	if len(retry) != 3 || retry[0].Host != "b" || retry[1].Host != "c" || retry[2].Host != "d" {
+		t.Fatalf("应重试最近一次未成功及尚无结果的主机: %+v", retry)
+	}
+	if resume["b"] != "accept_key" || resume["c"] != "ping" {
+		t.Fatalf("应从最近一次的失败阶段继续: %v", resume)
+	}
+	if _, ok := resume["d"]; ok {
+		t.Fatal("尚无结果的主机应从头开始")
+	}
+	if prior.Success != 1 || prior.Failed != 0 {
+		t.Fatalf("已成功主机应计入成功数: %+v", prior)
+	}
+
+	retry, _, prior = planBatchInstallRetry(hosts, results, []string{"c"})
+	if len(retry) != 1 || retry[0].Host != "c" || prior.Success != 1 || prior.Failed != 2 {
+		t.Fatalf("仅重试指定主机，其余未成功主机计入失败数: %+v %+v", retry, prior)
+	}
+}
+
+func TestCheckBatchInstallRetry(t *testing.T) {
+	task := &models.InstallationTask{UserID: 7, Status: "failed", Credentials: "sealed"}
+	if err := checkBatchInstallRetry(task, 7, false); err != nil {
+		t.Fatalf("创建者应可以重试: %v", err)
+	}
+	if err := checkBatchInstallRetry(task, 8, true); err != nil {
+		t.Fatalf("管理员应可以重试: %v", err)
+	}
+	if err := checkBatchInstallRetry(task, 8, false); err == nil {
+		t.Fatal("其他用户不应重试他人的任务")
+	}
+	if err := checkBatchInstallRetry(&models.InstallationTask{Status: "failed", Credentials: "sealed"}, 0, false); err == nil {
+		t.Fatal("未记录创建者的任务仅管理员可以重试")
+	}
+	if err := checkBatchInstallRetry(&models.InstallationTask{UserID: 7, Status: "running", Credentials: "sealed"}, 7, false); err == nil {
+		t.Fatal("运行中的任务不应重试")
+	}
+	if err := checkBatchInstallRetry(&models.InstallationTask{UserID: 7, Status: "failed"}, 7, false); err == nil {
+		t.Fatal("未保存凭据的任务不能重试")
+	}
+}
@@ -56,6 +56,7 @@ type BatchInstallRequest struct {
 	N9EHost         string              `json:"n9e_host"`         // Nightingale 服务器地址
 	N9EPort         string              `json:"n9e_port"`         // Nightingale 端口（默认 17000）
 	CategrafVersion string              `json:"categraf_version"` // Categraf 版本
+	JumpHosts       models.SSHJumpChain `json:"jump_hosts"`       // 默认跳板机链（主机未单独配置时使用）
 }
 
 // HostInstallConfig 单主机安装配置
@@ -70,6 +71,8 @@ type HostInstallConfig struct {
 	SudoPass        string `json:"sudo_pass,omitempty"`        // 覆盖全局设置
 	Group           string `json:"group,omitempty"`            // 分组名称
 	InstallCategraf bool   `json:"install_categraf,omitempty"` // 是否安装 Categraf
+
+	JumpHosts models.SSHJumpChain `json:"jump_hosts,omitempty"` // 跳板机链（ProxyJump），覆盖请求级配置
 }
 
 // BatchInstallResult 批量安装结果
@@ -82,11 +85,43 @@ type BatchInstallResult struct {
 	HostResults  []HostInstallResult `json:"host_results"`
 }
 
+// 批量安装阶段（按执行顺序），主机结果记录失败所在阶段，重试时从该阶段继续
+const (
+	BatchInstallStageConnect   = "connect"
+	BatchInstallStageDetectOS  = "detect_os"
+	BatchInstallStageInstall   = "install"
+	BatchInstallStageAcceptKey = "accept_key"
+	BatchInstallStagePing      = "ping"
+	BatchInstallStageCategraf  = "categraf"
+	BatchInstallStageDone      = "done"
+)
+
+var batchInstallStages = []string{
+	BatchInstallStageConnect,
+	BatchInstallStageDetectOS,
+	BatchInstallStageInstall,
+	BatchInstallStageAcceptKey,
+	BatchInstallStagePing,
+	BatchInstallStageCategraf,
+	BatchInstallStageDone,
+}
+
+// batchInstallStageIndex 阶段序号，未知或空阶段视为从头开始
+func batchInstallStageIndex(stage string) int {
+	for i, st := range batchInstallStages {
+		if st == stage {
+			return i
+		}
+	}
+	return 0
+}
+
 // HostInstallResult 单主机安装结果
 type HostInstallResult struct {
 	Host     string   `json:"host"`
 	MinionID string   `json:"minion_id,omitempty"` // 安装的 minion ID
 	Status   string   `json:"status"`              // success, failed, partial
+	Stage    string   `json:"stage,omitempty"`     // 失败所在阶段（成功为 done）
 	Message  string   `json:"message"`
 	Duration int64    `json:"duration"` // 耗时（毫秒）
 	Error    string   `json:"error,omitempty"`
@@ -312,10 +347,17 @@ func (s *BatchInstallService) BatchInstallSaltMinion(ctx context.Context, req Ba
 		Status:     "running",
 		TotalHosts: len(req.Hosts),
 		StartTime:  time.Now(),
+		UserID:     userID,
 	}
 	// 存储脱敏后的配置到数据库（保护敏感信息）
 	maskedReq := s.maskBatchInstallRequest(req)
 	task.SetConfig(maskedReq)
+	// 完整请求加密保存，用于重试失败主机
+	if sealed, err := sealBatchInstallRequest(req); err != nil {
+		logrus.WithError(err).Warn("Failed to store encrypted credentials, retry will be unavailable for this task")
+	} else {
+		task.Credentials = sealed
+	}
 
 	if database.DB != nil {
 		if err := database.DB.Create(task).Error; err != nil {
@@ -327,7 +369,7 @@ func (s *BatchInstallService) BatchInstallSaltMinion(ctx context.Context, req Ba
 	s.GetSSEChannel(taskID)
 
 	// 启动异步安装
-	go s.runBatchInstall(ctx, taskID, task, req, userID)
+	go s.runBatchInstall(ctx, taskID, task, req, userID, nil, batchInstallTally{})
 
 	logrus.WithFields(logrus.Fields{
 		"task_id":     taskID,
@@ -338,8 +380,182 @@ func (s *BatchInstallService) BatchInstallSaltMinion(ctx context.Context, req Ba
 	return taskID, nil
 }
 
+// RetryFailedHosts 重试任务中未成功的主机
+// 复用加密保存的请求与凭据，跳过已成功的主机，各主机从上次失败的阶段继续，日志追加到同一任务
+// 仅任务创建者或管理员可以重试；hosts 为空时重试全部未成功主机，返回本次重试的主机数
+func (s *BatchInstallService) RetryFailedHosts(ctx context.Context, taskID string, hosts []string, userID uint, isAdmin bool) (int, error) {
+	if database.DB == nil {
+		return 0, fmt.Errorf("database not initialized")
+	}
+	task, err := s.GetTask(taskID)
+	if err != nil {
+		return 0, fmt.Errorf("task not found: %w", err)
+	}
+	if err := checkBatchInstallRetry(task, userID, isAdmin); err != nil {
+		return 0, err
+	}
+	req, err := openBatchInstallRequest(task.Credentials)
+	if err != nil {
+		return 0, err
+	}
+
+	retryHosts, resume, prior := planBatchInstallRetry(req.Hosts, task.HostResults, hosts)
+	if len(retryHosts) == 0 {
+		return 0, fmt.Errorf("no failed hosts to retry in task %s", taskID)
+	}
+
+	req.Hosts = retryHosts
+	req.Parallel = GetParallelInfo(len(retryHosts), 0, 100).Parallel
+
+	// 条件更新认领任务，并发的重试只有一个能把状态改为 running
+	res := database.DB.Model(&models.InstallationTask{}).
+		Where("id = ? AND status <> ?", task.ID, "running").
+		Updates(map[string]interface{}{"status": "running", "end_time": nil})
+	if res.Error != nil {
+		return 0, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return 0, fmt.Errorf("task %s is still running", taskID)
+	}
+	task.Status = "running"
+	task.EndTime = nil
+
+	s.GetSSEChannel(taskID)
+	s.logToDatabase(taskID, "info", "", fmt.Sprintf("Retrying %d failed hosts (skipping %d succeeded)", len(retryHosts), prior.Success))
+
+	// 安装在后台执行，不随 HTTP 请求取消
+	go s.runBatchInstall(context.WithoutCancel(ctx), taskID, task, req, userID, resume, prior)
+
+	logrus.WithFields(logrus.Fields{
+		"task_id":     taskID,
+		"retry_hosts": len(retryHosts),
+		"skipped":     prior.Success,
+	}).Info("Batch Salt Minion installation retry started")
+
+	return len(retryHosts), nil
+}
+
+// checkBatchInstallRetry 检查任务是否可以由该用户重试：须为任务创建者或管理员，任务未在运行且保存了凭据
+func checkBatchInstallRetry(task *models.InstallationTask, userID uint, isAdmin bool) error {
+	if !isAdmin && (task.UserID == 0 || task.UserID != userID) {
+		return fmt.Errorf("only the task owner or an admin can retry this task")
+	}
+	if task.Status == "running" {
+		return fmt.Errorf("task is still running")
+	}
+	if task.Credentials == "" {
+		return fmt.Errorf("task has no stored credentials and cannot be retried")
+	}
+	return nil
+}
+
+// planBatchInstallRetry 确定需要重试的主机：已成功的主机计入成功数；指定 selected 时未选中的主机计入失败数，
+// 其余主机重试，并从各自最近一次结果的失败阶段继续
+func planBatchInstallRetry(hosts []HostInstallConfig, results []models.InstallationHostResult, selected []string) ([]HostInstallConfig, map[string]string, batchInstallTally) {
+	latest := make(map[string]models.InstallationHostResult, len(results))
+	for _, r := range results {
+		if prev, ok := latest[r.Host]; !ok || r.ID > prev.ID {
+			latest[r.Host] = r
+		}
+	}
+	picked := make(map[string]bool, len(selected))
+	for _, h := range selected {
+		picked[h] = true
+	}
+
+	var retryHosts []HostInstallConfig
+	resume := make(map[string]string)
+	var prior batchInstallTally
+	for _, host := range hosts {
+		r, ok := latest[host.Host]
+		switch {
+		case ok && r.Status == "success":
+			prior.Success++
+		case len(picked) > 0 && !picked[host.Host]:
+			prior.Failed++
+		default:
+			retryHosts = append(retryHosts, host)
+			if ok {
+				resume[host.Host] = r.Stage
+			}
+		}
+	}
+	return retryHosts, resume, prior
+}
+
+// batchInstallCredentials 加密保存的完整安装请求
+// 跳板机凭据在 JSON 序列化时会被隐藏，因此单独按数据库存储格式保存
+type batchInstallCredentials struct {
+	Request       BatchInstallRequest `json:"request"`
+	JumpHosts     json.RawMessage     `json:"jump_hosts,omitempty"`
+	HostJumpHosts []json.RawMessage   `json:"host_jump_hosts,omitempty"`
+}
+
+// sealBatchInstallRequest 加密序列化安装请求（含主机密码、sudo 密码和跳板机凭据）
+func sealBatchInstallRequest(req BatchInstallRequest) (string, error) {
+	encryption := utils.GetEncryptionService()
+	if encryption == nil {
+		return "", fmt.Errorf("encryption service not initialized")
+	}
+
+	creds := batchInstallCredentials{Request: req}
+	creds.JumpHosts = jumpChainRecord(req.JumpHosts)
+	for _, host := range req.Hosts {
+		creds.HostJumpHosts = append(creds.HostJumpHosts, jumpChainRecord(host.JumpHosts))
+	}
+	data, err := json.Marshal(creds)
+	if err != nil {
+		return "", err
+	}
+	return encryption.Encrypt(string(data))
+}
+
+// openBatchInstallRequest 解密安装请求
+func openBatchInstallRequest(sealed string) (BatchInstallRequest, error) {
+	encryption := utils.GetEncryptionService()
+	if encryption == nil {
+		return BatchInstallRequest{}, fmt.Errorf("encryption service not initialized")
+	}
+	data, err := encryption.Decrypt(sealed)
+	if err != nil {
+		return BatchInstallRequest{}, fmt.Errorf("failed to decrypt stored credentials: %w", err)
+	}
+
+	var creds batchInstallCredentials
+	if err := json.Unmarshal([]byte(data), &creds); err != nil {
+		return BatchInstallRequest{}, fmt.Errorf("invalid stored credentials: %w", err)
+	}
+	req := creds.Request
+	if len(creds.JumpHosts) > 0 {
+		req.JumpHosts.Scan([]byte(creds.JumpHosts))
+	}
+	for i := range req.Hosts {
+		if i < len(creds.HostJumpHosts) && len(creds.HostJumpHosts[i]) > 0 {
+			req.Hosts[i].JumpHosts.Scan([]byte(creds.HostJumpHosts[i]))
+		}
+	}
+	return req, nil
+}
+
+// jumpChainRecord 跳板机链的存储格式（保留凭据）
+func jumpChainRecord(chain models.SSHJumpChain) json.RawMessage {
+	value, err := chain.Value()
+	if err != nil {
+		return nil
+	}
+	data, _ := value.([]byte)
+	return data
+}
+
+// batchInstallTally 重试时未参与本次执行的主机统计
+type batchInstallTally struct {
+	Success int
+	Failed  int
+}
+
 // runBatchInstall 执行批量安装
-func (s *BatchInstallService) runBatchInstall(ctx context.Context, taskID string, task *models.InstallationTask, req BatchInstallRequest, userID uint) {
+// resume 为重试时各主机的起始阶段，prior 为未参与本次执行的主机统计（首次安装时均为空）
+func (s *BatchInstallService) runBatchInstall(ctx context.Context, taskID string, task *models.InstallationTask, req BatchInstallRequest, userID uint, resume map[string]string, prior batchInstallTally) {
 	defer func() {
 		if r := recover(); r != nil {
 			logrus.WithFields(logrus.Fields{
@@ -388,7 +604,7 @@ func (s *BatchInstallService) runBatchInstall(ctx context.Context, taskID string
 		go func(workerID int) {
 			defer wg.Done()
 			for hostConfig := range jobs {
-				result := s.installSingleHost(ctx, taskID, hostConfig, req)
+				result := s.installSingleHost(ctx, taskID, hostConfig, req, resume[hostConfig.Host])
 				results <- result
 			}
 		}(i)
@@ -403,6 +619,9 @@ func (s *BatchInstallService) runBatchInstall(ctx context.Context, taskID string
 		if host.SudoPass == "" && req.SudoPass != "" {
 			host.SudoPass = req.SudoPass
 		}
+		if len(host.JumpHosts) == 0 {
+			host.JumpHosts = req.JumpHosts
+		}
 		if host.Port == 0 {
 			host.Port = 22
 		}
@@ -418,9 +637,9 @@ func (s *BatchInstallService) runBatchInstall(ctx context.Context, taskID string
 
 	// 收集结果
 	var hostResults []HostInstallResult
-	successCount := 0
-	failedCount := 0
-	totalHosts := len(req.Hosts)
+	successCount := prior.Success
+	failedCount := prior.Failed
+	totalHosts := len(req.Hosts) + prior.Success + prior.Failed
 
 	for result := range results {
 		hostResults = append(hostResults, result)
@@ -430,17 +649,25 @@ func (s *BatchInstallService) runBatchInstall(ctx context.Context, taskID string
 			failedCount++
 		}
 
-		// 保存主机结果到数据库
+		// 保存主机结果到数据库（重试时更新同一主机的记录）
 		if database.DB != nil && task.ID > 0 {
-			hostResult := &models.InstallationHostResult{
-				TaskID:   task.ID,
-				Host:     result.Host,
-				Status:   result.Status,
-				Error:    result.Error,
-				Duration: result.Duration,
-				Output:   result.Message,
+			var hostResult models.InstallationHostResult
+			exists := database.DB.Where("task_id = ? AND host = ?", task.ID, result.Host).First(&hostResult).Error == nil
+			hostResult.TaskID = task.ID
+			hostResult.Host = result.Host
+			hostResult.Status = result.Status
+			hostResult.Stage = result.Stage
+			hostResult.MinionID = result.MinionID
+			hostResult.Error = result.Error
+			hostResult.Duration = result.Duration
+			hostResult.Output = result.Message
+			if exists {
+				hostResult.Attempts++
+				database.DB.Save(&hostResult)
+			} else {
+				hostResult.Attempts = 1
+				database.DB.Create(&hostResult)
 			}
-			database.DB.Create(hostResult)
 
 			// 实时更新任务统计 - 直接设置计数，不调用 UpdateHostStats
 			// 因为 UpdateHostStats 会从 task.HostResults 重新计算，但我们的 HostResults 未加载
@@ -531,81 +758,51 @@ func (s *BatchInstallService) runBatchInstall(ctx context.Context, taskID string
 	}).Info("Batch Salt Minion installation completed")
 }
 
-// installSingleHost 安装单个主机
-func (s *BatchInstallService) installSingleHost(ctx context.Context, taskID string, hostConfig HostInstallConfig, req BatchInstallRequest) HostInstallResult {
+// installSingleHost 安装单个主机，startStage 为空表示完整安装
+// 重试时从 startStage 继续，跳过已完成的阶段；SSH 连接只在需要执行远程命令（安装、Categraf）时建立
+func (s *BatchInstallService) installSingleHost(ctx context.Context, taskID string, hostConfig HostInstallConfig, req BatchInstallRequest, startStage string) HostInstallResult {
 	startTime := time.Now()
 	result := HostInstallResult{
 		Host:   hostConfig.Host,
 		Status: "failed",
 	}
-
-	s.sendEvent(taskID, SSEEvent{
-		Type:    "log",
-		Host:    hostConfig.Host,
-		Message: fmt.Sprintf("Starting installation on %s", hostConfig.Host),
-	})
-
-	// 记录日志到数据库
-	s.logToDatabase(taskID, "info", hostConfig.Host, fmt.Sprintf("Starting Salt Minion installation on %s:%d", hostConfig.Host, hostConfig.Port))
-
-	// 建立 SSH 连接
-	client, err := s.connectSSH(hostConfig)
-	if err != nil {
-		result.Error = fmt.Sprintf("SSH connection failed: %v", err)
-		result.Message = result.Error
+	runs := func(stage string) bool {
+		return batchInstallStageIndex(stage) >= batchInstallStageIndex(startStage)
+	}
+	fail := func(stage, message string) HostInstallResult {
+		// 重试时连接失败不回退阶段，避免下次重试重复已完成的安装
+		if batchInstallStageIndex(stage) < batchInstallStageIndex(startStage) {
+			stage = startStage
+		}
+		result.Stage = stage
+		result.Error = message
+		result.Message = message
 		result.Duration = time.Since(startTime).Milliseconds()
 		s.sendEvent(taskID, SSEEvent{
 			Type:    "error",
 			Host:    hostConfig.Host,
-			Message: result.Error,
+			Message: message,
 		})
-		s.logToDatabase(taskID, "error", hostConfig.Host, result.Error)
+		s.logToDatabase(taskID, "error", hostConfig.Host, message)
 		return result
 	}
-	defer client.Close()
-
-	s.sendEvent(taskID, SSEEvent{
-		Type:    "log",
-		Host:    hostConfig.Host,
-		Message: "SSH connection established",
-	})
 
-	// 检测操作系统
-	osInfo, err := s.detectOS(client)
-	if err != nil {
-		result.Error = fmt.Sprintf("OS detection failed: %v", err)
-		result.Message = result.Error
-		result.Duration = time.Since(startTime).Milliseconds()
+	if startStage == "" {
 		s.sendEvent(taskID, SSEEvent{
-			Type:    "error",
+			Type:    "log",
 			Host:    hostConfig.Host,
-			Message: result.Error,
+			Message: fmt.Sprintf("Starting installation on %s", hostConfig.Host),
 		})
-		s.logToDatabase(taskID, "error", hostConfig.Host, result.Error)
-		return result
-	}
 
-	s.sendEvent(taskID, SSEEvent{
-		Type:    "log",
-		Host:    hostConfig.Host,
-		Message: fmt.Sprintf("Detected OS: %s %s (%s)", osInfo.OS, osInfo.Version, osInfo.Arch),
-	})
-
-	// 确定是否使用 sudo
-	useSudo := hostConfig.UseSudo || req.UseSudo
-	sudoPass := hostConfig.SudoPass
-	if sudoPass == "" {
-		sudoPass = req.SudoPass
-	}
-	if sudoPass == "" {
-		sudoPass = hostConfig.Password // 默认使用登录密码
-	}
-
-	// 构建 sudo 前缀 - 使用标准 sudo 格式，密码通过环境变量传递
-	// 注意：非 root 用户需要 sudo，root 用户不需要
-	sudoPrefix := ""
-	if hostConfig.Username != "root" && useSudo {
-		sudoPrefix = "sudo "
+		// 记录日志到数据库
+		s.logToDatabase(taskID, "info", hostConfig.Host, fmt.Sprintf("Starting Salt Minion installation on %s:%d", hostConfig.Host, hostConfig.Port))
+	} else {
+		s.sendEvent(taskID, SSEEvent{
+			Type:    "log",
+			Host:    hostConfig.Host,
+			Message: fmt.Sprintf("Retrying installation on %s from stage %s", hostConfig.Host, startStage),
+		})
+		s.logToDatabase(taskID, "info", hostConfig.Host, fmt.Sprintf("Retrying Salt Minion installation on %s:%d from stage %s", hostConfig.Host, hostConfig.Port, startStage))
 	}
 
 	// 获取 minion ID
@@ -614,30 +811,87 @@ func (s *BatchInstallService) installSingleHost(ctx context.Context, taskID stri
 		minionID = hostConfig.Host
 	}
 
-	// 安装 Salt Minion
-	err = s.installSaltMinion(client, osInfo, req.MasterHost, minionID, req.Version, sudoPrefix, taskID, hostConfig.Host)
-	if err != nil {
-		result.Error = fmt.Sprintf("Installation failed: %v", err)
-		result.Message = result.Error
-		result.Duration = time.Since(startTime).Milliseconds()
+	// 检查全局设置或单主机设置
+	shouldInstallCategraf := req.InstallCategraf || hostConfig.InstallCategraf
+
+	var client *PooledSSHClient
+	var osInfo *models.OSInfo
+	var support *ResolvedOS
+	sudoPrefix := ""
+	if runs(BatchInstallStageInstall) || shouldInstallCategraf {
+		// 建立 SSH 连接
+		var err error
+		client, err = s.connectSSH(hostConfig)
+		if err != nil {
+			return fail(BatchInstallStageConnect, fmt.Sprintf("SSH connection failed: %v", err))
+		}
+		defer client.Close()
+
 		s.sendEvent(taskID, SSEEvent{
-			Type:    "error",
+			Type:    "log",
 			Host:    hostConfig.Host,
-			Message: result.Error,
+			Message: "SSH connection established",
 		})
-		s.logToDatabase(taskID, "error", hostConfig.Host, result.Error)
-		return result
+
+		// 检测操作系统
+		osInfo, err = s.detectOS(client.Client)
+		if err != nil {
+			return fail(BatchInstallStageDetectOS, fmt.Sprintf("OS detection failed: %v", err))
+		}
+
+		s.sendEvent(taskID, SSEEvent{
+			Type:    "log",
+			Host:    hostConfig.Host,
+			Message: fmt.Sprintf("Detected OS: %s %s (%s)", osInfo.OS, osInfo.Version, osInfo.Arch),
+		})
+
+		// 校验操作系统是否在支持矩阵中
+		support, err = GetScriptLoader().ResolveOS(*osInfo)
+		if err != nil {
+			return fail(BatchInstallStageDetectOS, err.Error())
+		}
+
+		// 确定是否使用 sudo
+		// 构建 sudo 前缀 - 使用标准 sudo 格式
+		// 注意：非 root 用户需要 sudo，root 用户不需要
+		useSudo := hostConfig.UseSudo || req.UseSudo
+		if hostConfig.Username != "root" && useSudo {
+			sudoPrefix = "sudo "
+		}
 	}
 
-	s.sendEvent(taskID, SSEEvent{
-		Type:    "log",
-		Host:    hostConfig.Host,
-		Message: "Salt Minion installed, waiting for key registration...",
-	})
+	if runs(BatchInstallStageInstall) {
+		// 安装 Salt Minion
+		if err := s.installSaltMinion(client.Client, support, req.MasterHost, minionID, req.Version, sudoPrefix, taskID, hostConfig.Host); err != nil {
+			return fail(BatchInstallStageInstall, fmt.Sprintf("Installation failed: %v", err))
+		}
+
+		// 下发 SSH 用户 CA 信任，之后可使用平台签发的短期证书登录（失败不影响安装结果）
+		if ca := GetSSHCAService(); ca != nil {
+			if err := ca.InstallTrust(client.Client, sudoPrefix); err != nil {
+				s.sendEvent(taskID, SSEEvent{
+					Type:    "warning",
+					Host:    hostConfig.Host,
+					Message: fmt.Sprintf("Failed to configure SSH CA trust: %v", err),
+				})
+				s.logToDatabase(taskID, "warn", hostConfig.Host, fmt.Sprintf("Failed to configure SSH CA trust: %v", err))
+			}
+		}
+
+		s.sendEvent(taskID, SSEEvent{
+			Type:    "log",
+			Host:    hostConfig.Host,
+			Message: "Salt Minion installed, waiting for key registration...",
+		})
+	}
+
+	// 未完成的可重试阶段（Minion 已安装，后续步骤失败时主机状态为 partial）
+	pendingStage := ""
+	pendingError := ""
 
 	// 自动接受 Minion Key（如果启用）
 	keyAccepted := false
-	if req.AutoAccept {
+	if req.AutoAccept && runs(BatchInstallStageAcceptKey) {
 		s.sendEvent(taskID, SSEEvent{
 			Type:    "log",
 			Host:    hostConfig.Host,
@@ -653,7 +907,9 @@ func (s *BatchInstallService) installSingleHost(ctx context.Context, taskID stri
 				Message: fmt.Sprintf("Warning: Failed to auto-accept minion key: %v", acceptErr),
 			})
 			s.logToDatabase(taskID, "warn", hostConfig.Host, fmt.Sprintf("Minion installed but key not auto-accepted: %v", acceptErr))
-			// 不标记为失败，因为 minion 已安装，只是 key 未被接受
+			// Minion 已安装，只是 key 未被接受，重试时从该阶段继续
+			pendingStage = BatchInstallStageAcceptKey
+			pendingError = fmt.Sprintf("Minion installed but key not accepted: %v", acceptErr)
 		} else {
 			keyAccepted = true
 			s.sendEvent(taskID, SSEEvent{
@@ -662,10 +918,13 @@ func (s *BatchInstallService) installSingleHost(ctx context.Context, taskID stri
 				Message: fmt.Sprintf("Minion key accepted for %s", minionID),
 			})
 		}
+	} else if req.AutoAccept {
+		// 从更后的阶段重试：key 已在上一次执行中接受
+		keyAccepted = true
 	}
 
 	// 验证 minion 是否能响应 test.ping（如果 key 已接受）
-	if keyAccepted {
+	if keyAccepted && runs(BatchInstallStagePing) {
 		s.sendEvent(taskID, SSEEvent{
 			Type:    "log",
 			Host:    hostConfig.Host,
@@ -675,6 +934,7 @@ func (s *BatchInstallService) installSingleHost(ctx context.Context, taskID stri
 		pingErr := s.verifyMinionPing(ctx, minionID, taskID, hostConfig.Host)
 		if pingErr != nil {
 			result.Status = "partial"
+			result.Stage = BatchInstallStagePing
 			result.Error = fmt.Sprintf("Minion installed but not responding: %v", pingErr)
 			result.Message = result.Error
 			result.Duration = time.Since(startTime).Milliseconds()
@@ -695,8 +955,6 @@ func (s *BatchInstallService) installSingleHost(ctx context.Context, taskID stri
 	}
 
 	// 安装 Categraf 监控代理（如果启用）
-	// 检查全局设置或单主机设置
-	shouldInstallCategraf := req.InstallCategraf || hostConfig.InstallCategraf
 	if shouldInstallCategraf {
 		s.sendEvent(taskID, SSEEvent{
 			Type:    "log",
@@ -705,7 +963,7 @@ func (s *BatchInstallService) installSingleHost(ctx context.Context, taskID stri
 		})
 		s.logToDatabase(taskID, "info", hostConfig.Host, "Installing Categraf monitoring agent...")
 
-		categrafErr := s.installCategraf(client, osInfo, req, sudoPrefix, taskID, hostConfig.Host, minionID)
+		categrafErr := s.installCategraf(client.Client, osInfo, req, sudoPrefix, taskID, hostConfig.Host, minionID)
 		if categrafErr != nil {
 			s.sendEvent(taskID, SSEEvent{
 				Type:    "warning",
@@ -713,7 +971,11 @@ func (s *BatchInstallService) installSingleHost(ctx context.Context, taskID stri
 				Message: fmt.Sprintf("Categraf installation failed: %v (Salt Minion was installed successfully)", categrafErr),
 			})
 			s.logToDatabase(taskID, "warn", hostConfig.Host, fmt.Sprintf("Categraf installation failed: %v", categrafErr))
-			// 不标记为失败，因为 Salt Minion 已安装成功
+			// Salt Minion 已安装成功，重试时只需重新安装 Categraf
+			if pendingStage == "" {
+				pendingStage = BatchInstallStageCategraf
+				pendingError = fmt.Sprintf("Categraf installation failed: %v", categrafErr)
+			}
 		} else {
 			s.sendEvent(taskID, SSEEvent{
 				Type:    "log",
@@ -798,8 +1060,27 @@ func (s *BatchInstallService) installSingleHost(ctx context.Context, taskID stri
 		}
 	}
 
-	result.Status = "success"
 	result.MinionID = minionID // 记录 minion ID
+	if pendingStage != "" {
+		result.Status = "partial"
+		result.Stage = pendingStage
+		result.Error = pendingError
+		result.Message = pendingError
+		result.Duration = time.Since(startTime).Milliseconds()
+		s.logToDatabase(taskID, "warn", hostConfig.Host, result.Message)
+		return result
+	}
+
+	result.Status = "success"
+	result.Stage = BatchInstallStageDone
+	ObserveInventoryAsync(models.InventoryObservation{
+		Hostname:   hostConfig.Host,
+		Port:       hostConfig.Port,
+		Source:     models.InventorySourceSalt,
+		SourceID:   minionID,
+		Name:       minionID,
+		Attributes: map[string]string{"install_task": taskID},
+	})
 	result.Message = "Salt Minion installed and started successfully"
 	if keyAccepted {
 		result.Message = "Salt Minion installed, key accepted, and verified responding"
@@ -819,8 +1100,8 @@ func (s *BatchInstallService) installSingleHost(ctx context.Context, taskID stri
 	return result
 }
 
-// connectSSH 建立 SSH 连接
-func (s *BatchInstallService) connectSSH(config HostInstallConfig) (*ssh.Client, error) {
+// connectSSH 从连接池获取 SSH 连接（调用方 Close 即归还）
+func (s *BatchInstallService) connectSSH(config HostInstallConfig) (*PooledSSHClient, error) {
 	var authMethods []ssh.AuthMethod
 
 	// 密码认证
@@ -853,6 +1134,8 @@ func (s *BatchInstallService) connectSSH(config HostInstallConfig) (*ssh.Client,
 		}
 	}
 
+	authMethods = withSSHCAAuth(authMethods)
+
 	if len(authMethods) == 0 {
 		logrus.WithField("host", config.Host).Error("[connectSSH] No authentication method available")
 		return nil, fmt.Errorf("no authentication method available")
@@ -861,7 +1144,7 @@ func (s *BatchInstallService) connectSSH(config HostInstallConfig) (*ssh.Client,
 	sshConfig := &ssh.ClientConfig{
 		User:            config.Username,
 		Auth:            authMethods,
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		HostKeyCallback: KnownHostsCallback(),
 		Timeout:         30 * time.Second,
 	}
 
@@ -873,7 +1156,15 @@ func (s *BatchInstallService) connectSSH(config HostInstallConfig) (*ssh.Client,
 		"addr":     addr,
 	}).Debug("[connectSSH] Attempting SSH connection")
 
-	client, err := ssh.Dial("tcp", addr, sshConfig)
+	if len(config.JumpHosts) > 0 {
+		logrus.WithFields(logrus.Fields{
+			"host": config.Host,
+			"via":  config.JumpHosts.String(),
+		}).Debug("[connectSSH] Connecting through jump hosts")
+	}
+
+	key := NewSSHPoolKey(config.Host, config.Port, config.Username, config.Password, sshKeyCredential(config.KeyPath))
+	client, err := GetSSHClientPool().DialVia(key, config.JumpHosts, sshConfig)
 	if err != nil {
 		logrus.WithFields(logrus.Fields{
 			"host":  config.Host,
@@ -931,7 +1222,7 @@ func (s *BatchInstallService) detectOS(client *ssh.Client) (*models.OSInfo, erro
 
 // installSaltMinion 安装 Salt Minion
 // 优先尝试从 AppHub 下载安装包，如果失败则回退到在线安装（使用 Salt Bootstrap 脚本）
-func (s *BatchInstallService) installSaltMinion(client *ssh.Client, osInfo *models.OSInfo, masterHost, minionID, version, sudoPrefix, taskID, host string) error {
+func (s *BatchInstallService) installSaltMinion(client *ssh.Client, support *ResolvedOS, masterHost, minionID, version, sudoPrefix, taskID, host string) error {
 	// 构建 AppHub URL
 	appHubHost := os.Getenv("EXTERNAL_HOST")
 	if appHubHost == "" {
@@ -943,22 +1234,6 @@ func (s *BatchInstallService) installSaltMinion(client *ssh.Client, osInfo *mode
 	}
 	appHubURL := fmt.Sprintf("http://%s:%s", appHubHost, appHubPort)
 
-	// 获取架构
-	arch := osInfo.Arch
-	if arch == "x86_64" {
-		arch = "amd64"
-	} else if arch == "aarch64" {
-		arch = "arm64"
-	}
-
-	// RPM 架构名称
-	rpmArch := osInfo.Arch
-	if rpmArch == "amd64" {
-		rpmArch = "x86_64"
-	} else if rpmArch == "arm64" {
-		rpmArch = "aarch64"
-	}
-
 	// 生成 Master 公钥获取 URL（使用一次性令牌）
 	masterPubURL := ""
 	if saltKeyHandler := GetSaltKeyHandler(); saltKeyHandler != nil {
@@ -977,16 +1252,19 @@ func (s *BatchInstallService) installSaltMinion(client *ssh.Client, osInfo *mode
 	// 使用 ScriptLoader 生成安装脚本
 	scriptLoader := GetScriptLoader()
 	installCmd, err := scriptLoader.GenerateSaltInstallScript(SaltInstallParams{
-		AppHubURL:    appHubURL,
-		MasterHost:   masterHost,
-		MinionID:     minionID,
-		Version:      version,
-		Arch:         arch,
-		RpmArch:      rpmArch,
-		SudoPrefix:   sudoPrefix,
-		OS:           osInfo.OS,
-		OSVersion:    osInfo.Version,
-		MasterPubURL: masterPubURL,
+		AppHubURL:      appHubURL,
+		MasterHost:     masterHost,
+		MinionID:       minionID,
+		Version:        version,
+		Arch:           support.DebArch,
+		RpmArch:        support.RpmArch,
+		SudoPrefix:     sudoPrefix,
+		OS:             support.Distribution,
+		OSVersion:      support.Version,
+		MasterPubURL:   masterPubURL,
+		PackageManager: support.PackageManager,
+		Packages:       support.Packages,
+		RepoSetup:      support.RepoSetup,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to generate install script: %v", err)
@@ -1555,7 +1833,7 @@ func (s *BatchInstallService) UninstallSaltMinion(ctx context.Context, config Ho
 	logrus.WithField("host", config.Host).Info("[UninstallMinion] SSH connection established")
 
 	// 检测操作系统
-	osInfo, err := s.detectOS(client)
+	osInfo, err := s.detectOS(client.Client)
 	if err != nil {
 		logrus.WithError(err).WithField("host", config.Host).Error("[UninstallMinion] OS detection failed")
 		return fmt.Errorf("OS detection failed: %v", err)
@@ -1829,6 +2107,7 @@ func (s *BatchInstallService) maskBatchInstallRequest(req BatchInstallRequest) B
 		SudoPass:    utils.MaskPassword(req.SudoPass),
 		AutoAccept:  req.AutoAccept,
 		Version:     req.Version,
+		JumpHosts:   req.JumpHosts, // 序列化时自动隐藏跳板机凭据
 	}
 
 	for i, host := range req.Hosts {
@@ -1841,6 +2120,8 @@ func (s *BatchInstallService) maskBatchInstallRequest(req BatchInstallRequest) B
 			MinionID: host.MinionID,
 			UseSudo:  host.UseSudo,
 			SudoPass: utils.MaskPassword(host.SudoPass),
+
+			JumpHosts: host.JumpHosts,
 		}
 	}
 
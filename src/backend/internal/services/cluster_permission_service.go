@@ -548,7 +548,28 @@ func (s *ClusterPermissionService) GetUserSaltstackPermissions(ctx context.Conte
 }
 
 // CheckSaltstackAccess 检查用户对SaltStack集群的访问权限
+// 注册了多 Master 后，masterID 为空或 default 的检查按 minion 的归属 Master 匹配权限记录（见 resolveSaltPermissionMaster），
+// 因此已归属某个 Master 的 minion 需要在该 Master 上授权，原先授予 default 的权限只覆盖未归属的 minion
 func (s *ClusterPermissionService) CheckSaltstackAccess(ctx context.Context, userID uint, masterID string, requiredVerb models.ClusterPermissionVerb, minionID string, function string) (*models.VerifyPermissionResult, error) {
+	if masterService := GetSaltMasterService(); masterService != nil {
+		resolved, denied := resolveSaltPermissionMaster(masterID, minionID,
+			func(id string) (string, error) {
+				owner, err := masterService.ResolveMinionMaster(id)
+				if err != nil {
+					return "", err
+				}
+				return owner.MasterID, nil
+			},
+			func(id string) error {
+				_, err := masterService.GetMasterByMasterID(id)
+				return err
+			})
+		if denied != "" {
+			return &models.VerifyPermissionResult{Allowed: false, Reason: denied}, nil
+		}
+		masterID = resolved
+	}
+
 	var perm models.SaltstackClusterPermission
 	err := s.db.Where("user_id = ? AND master_id = ? AND is_active = ? AND (expires_at IS NULL OR expires_at > ?)",
 		userID, masterID, true, time.Now()).First(&perm).Error
@@ -590,6 +611,23 @@ func (s *ClusterPermissionService) CheckSaltstackAccess(ctx context.Context, use
 	return result, nil
 }
 
+// resolveSaltPermissionMaster 确定 Salt 权限检查使用的 masterID
+// masterID 为空或 default 且指定了 minion 时改用持有该 minion 密钥的 Master；minion 未归属任何 Master 时保持原值；
+// 显式指定的 Master 不存在时返回拒绝原因
+func resolveSaltPermissionMaster(masterID, minionID string, owner func(minionID string) (string, error), lookup func(masterID string) error) (string, string) {
+	if (masterID == "" || masterID == "default") && minionID != "" {
+		if id, err := owner(minionID); err == nil {
+			masterID = id
+		}
+	}
+	if masterID != "" && masterID != "default" {
+		if err := lookup(masterID); errors.Is(err, gorm.ErrRecordNotFound) {
+			return masterID, fmt.Sprintf("Unknown Salt master: %s", masterID)
+		}
+	}
+	return masterID, ""
+}
+
 // ========================================
 // 用户权限汇总
 // ========================================
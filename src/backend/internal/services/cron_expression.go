@@ -0,0 +1,207 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CronExpression 标准 5 段 cron 表达式（分 时 日 月 周）
+// 支持 *、*/n、a-b、a-b/n、逗号列表、月份/星期英文缩写，以及 @hourly/@daily/@weekly/@monthly/@yearly 宏
+type CronExpression struct {
+	raw    string
+	minute uint64
+	hour   uint64
+	dom    uint64
+	month  uint64
+	dow    uint64
+	// 日和周均被限制时，按 cron 惯例取并集
+	domRestricted bool
+	dowRestricted bool
+}
+
+type cronField struct {
+	name  string
+	min   int
+	max   int
+	names map[string]int
+}
+
+var (
+	cronMinuteField = cronField{name: "minute", min: 0, max: 59}
+	cronHourField   = cronField{name: "hour", min: 0, max: 23}
+	cronDomField    = cronField{name: "day-of-month", min: 1, max: 31}
+	cronMonthField  = cronField{name: "month", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	cronDowField = cronField{name: "day-of-week", min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+
+	cronMacros = map[string]string{
+		"@yearly":   "0 0 1 1 *",
+		"@annually": "0 0 1 1 *",
+		"@monthly":  "0 0 1 * *",
+		"@weekly":   "0 0 * * 0",
+		"@daily":    "0 0 * * *",
+		"@midnight": "0 0 * * *",
+		"@hourly":   "0 * * * *",
+	}
+)
+
+// ParseCronExpression 解析 cron 表达式
+func ParseCronExpression(expr string) (*CronExpression, error) {
+	spec := strings.TrimSpace(expr)
+	if spec == "" {
+		return nil, fmt.Errorf("empty cron expression")
+	}
+	if macro, ok := cronMacros[strings.ToLower(spec)]; ok {
+		spec = macro
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression must have 5 fields, got %d", len(fields))
+	}
+
+	c := &CronExpression{raw: strings.TrimSpace(expr)}
+	var err error
+	if c.minute, err = parseCronField(fields[0], cronMinuteField); err != nil {
+		return nil, err
+	}
+	if c.hour, err = parseCronField(fields[1], cronHourField); err != nil {
+		return nil, err
+	}
+	if c.dom, err = parseCronField(fields[2], cronDomField); err != nil {
+		return nil, err
+	}
+	if c.month, err = parseCronField(fields[3], cronMonthField); err != nil {
+		return nil, err
+	}
+	if c.dow, err = parseCronField(fields[4], cronDowField); err != nil {
+		return nil, err
+	}
+	// 7 与 0 都表示周日
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	c.domRestricted = fields[2] != "*" && fields[2] != "?"
+	c.dowRestricted = fields[4] != "*" && fields[4] != "?"
+	return c, nil
+}
+
+// String 返回原始表达式
+func (c *CronExpression) String() string {
+	return c.raw
+}
+
+// Next 返回严格晚于 t 的下一次触发时间（按 t 所在时区计算）
+// 在 5 年内找不到匹配时返回零值
+func (c *CronExpression) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !c.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (c *CronExpression) dayMatches(t time.Time) bool {
+	domMatch := c.dom&(1<<uint(t.Day())) != 0
+	dowMatch := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domRestricted && c.dowRestricted {
+		return domMatch || dowMatch
+	}
+	return domMatch && dowMatch
+}
+
+// parseCronField 将单个字段解析为位图
+func parseCronField(field string, spec cronField) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		if part == "" {
+			return 0, fmt.Errorf("invalid %s field: %q", spec.name, field)
+		}
+
+		step := 1
+		rangePart := part
+		if idx := strings.Index(part, "/"); idx >= 0 {
+			rangePart = part[:idx]
+			s, err := strconv.Atoi(part[idx+1:])
+			if err != nil || s <= 0 {
+				return 0, fmt.Errorf("invalid step in %s field: %q", spec.name, part)
+			}
+			step = s
+		}
+
+		start, end := spec.min, spec.max
+		switch {
+		case rangePart == "*" || rangePart == "?":
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			lo, err := parseCronValue(bounds[0], spec)
+			if err != nil {
+				return 0, err
+			}
+			hi, err := parseCronValue(bounds[1], spec)
+			if err != nil {
+				return 0, err
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("invalid range in %s field: %q", spec.name, part)
+			}
+			start, end = lo, hi
+		default:
+			v, err := parseCronValue(rangePart, spec)
+			if err != nil {
+				return 0, err
+			}
+			start = v
+			// "5/15" 表示从 5 开始每 15 个单位
+			if step == 1 {
+				end = v
+			}
+		}
+
+		for v := start; v <= end; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func parseCronValue(s string, spec cronField) (int, error) {
+	if spec.names != nil {
+		if v, ok := spec.names[strings.ToLower(s)]; ok {
+			return v, nil
+		}
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value in %s field: %q", spec.name, s)
+	}
+	if v < spec.min || v > spec.max {
+		return 0, fmt.Errorf("%s value %d out of range [%d, %d]", spec.name, v, spec.min, spec.max)
+	}
+	return v, nil
+}
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCronExpression_Invalid(t *testing.T) {
+	invalid := []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"foo * * * *",
+	}
+	for _, expr := range invalid {
+		if _, err := ParseCronExpression(expr); err == nil {
+			t.Errorf("表达式 %q 应解析失败", expr)
+		}
+	}
+}
+
+func TestCronExpression_Next(t *testing.T) {
+	base := time.Date(2024, 1, 31, 10, 7, 30, 0, time.UTC) // 周三
+
+	cases := []struct {
+		expr string
+		want time.Time
+	}{
+		{"*/15 * * * *", time.Date(2024, 1, 31, 10, 15, 0, 0, time.UTC)},
+		{"0 2 * * *", time.Date(2024, 2, 1, 2, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2024, 1, 31, 11, 0, 0, 0, time.UTC)},
+		{"30 9 * * mon-fri", time.Date(2024, 2, 1, 9, 30, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2024, 2, 4, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 feb *", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// 日和周同时限制时取并集：1 号或周五
+		{"0 8 1 * 5", time.Date(2024, 2, 1, 8, 0, 0, 0, time.UTC)},
+		{"5/20 * * * *", time.Date(2024, 1, 31, 10, 25, 0, 0, time.UTC)},
+	}
+
+	for _, tc := range cases {
+		expr, err := ParseCronExpression(tc.expr)
+		if err != nil {
+			t.Fatalf("解析 %q 失败: %v", tc.expr, err)
+		}
+		got := expr.Next(base)
+		if !got.Equal(tc.want) {
+			t.Errorf("%q 的下次触发时间 = %s, 期望 %s", tc.expr, got, tc.want)
+		}
+	}
+}
+
+func TestCronExpression_NextTimezone(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Skipf("缺少时区数据: %v", err)
+	}
+	expr, err := ParseCronExpression("0 2 * * *")
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+
+	// UTC 18:30 即上海时间 02:30，下次应为上海时间次日 02:00
+	base := time.Date(2024, 3, 1, 18, 30, 0, 0, time.UTC).In(loc)
+	got := expr.Next(base)
+	want := time.Date(2024, 3, 3, 2, 0, 0, 0, loc)
+	if !got.Equal(want) {
+		t.Errorf("下次触发时间 = %s, 期望 %s", got, want)
+	}
+}
+
+func TestCronExpression_NeverFires(t *testing.T) {
+	expr, err := ParseCronExpression("0 0 31 2 *")
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if got := expr.Next(time.Now()); !got.IsZero() {
+		t.Errorf("2 月 31 日不应触发, 得到 %s", got)
+	}
+}
+
+func TestParseCronField(t *testing.T) {
+	bitsOf := func(vals ...int) uint64 {
+		var bits uint64
+		for _, v := range vals {
+			bits |= 1 << uint(v)
+		}
+		return bits
+	}
+
+	cases := []struct {
+		name  string
+		field string
+		spec  cronField
+		want  uint64
+	}{
+		{"单值", "5", cronMinuteField, bitsOf(5)},
+		{"列表", "1,15,30", cronMinuteField, bitsOf(1, 15, 30)},
+		{"范围", "9-12", cronHourField, bitsOf(9, 10, 11, 12)},
+		{"范围加步长", "0-10/5", cronMinuteField, bitsOf(0, 5, 10)},
+		{"通配加步长", "*/6", cronHourField, bitsOf(0, 6, 12, 18)},
+		{"起点加步长", "10/20", cronMinuteField, bitsOf(10, 30, 50)},
+		{"范围与单值混合", "1-3,7", cronDomField, bitsOf(1, 2, 3, 7)},
+		{"月份缩写", "jan,JUL", cronMonthField, bitsOf(1, 7)},
+		{"月份缩写范围", "oct-dec", cronMonthField, bitsOf(10, 11, 12)},
+		{"星期缩写范围", "mon-wed", cronDowField, bitsOf(1, 2, 3)},
+		{"星期缩写加步长", "sun-sat/2", cronDowField, bitsOf(0, 2, 4, 6)},
+	}
+	for _, tc := range cases {
+		got, err := parseCronField(tc.field, tc.spec)
+		if err != nil {
+			t.Errorf("%s: 解析 %q 失败: %v", tc.name, tc.field, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("%s: %q = %b, 期望 %b", tc.name, tc.field, got, tc.want)
+		}
+	}
+
+	for _, field := range []string{"1,", "1-", "mon", "jan-", "*/x", "0-10/0"} {
+		if _, err := parseCronField(field, cronMinuteField); err == nil {
+			t.Errorf("分钟字段 %q 应解析失败", field)
+		}
+	}
+}
+
+func TestCronExpression_DayOfMonthOrDayOfWeek(t *testing.T) {
+	// 2024-03：1 号为周五
+	day := func(d int) time.Time { return time.Date(2024, 3, d, 0, 0, 0, 0, time.UTC) }
+
+	cases := []struct {
+		expr string
+		day  int
+		want bool
+	}{
+		// 日、周均受限：任一满足即触发
+		{"0 0 15 * mon", 15, true}, // 周五，仅日匹配
+		{"0 0 15 * mon", 4, true},  // 周一，仅周匹配
+		{"0 0 15 * mon", 5, false}, // 均不匹配
+		// 仅限制日：周为 * 时不参与并集
+		{"0 0 15 * *", 15, true},
+		{"0 0 15 * *", 4, false},
+		// 仅限制周：日为 * 或 ? 时不参与并集
+		{"0 0 * * mon", 4, true},
+		{"0 0 * * mon", 15, false},
+		{"0 0 ? * mon", 15, false},
+		// 周日可写作 0 或 7
+		{"0 0 * * 7", 3, true},
+		{"0 0 31 * sun", 3, true},
+	}
+	for _, tc := range cases {
+		expr, err := ParseCronExpression(tc.expr)
+		if err != nil {
+			t.Fatalf("解析 %q 失败: %v", tc.expr, err)
+		}
+		if got := expr.dayMatches(day(tc.day)); got != tc.want {
+			t.Errorf("%q 在 3 月 %d 日匹配 = %v, 期望 %v", tc.expr, tc.day, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// cronRunnerInterval 调度循环检查到期任务的间隔
+const cronRunnerInterval = 30 * time.Second
+
+// cronDueTask 到期任务的调度字段
+type cronDueTask struct {
+	ID        uint
+	Name      string
+	CronExpr  string
+	Timezone  string
+	NextRunAt *time.Time
+}
+
+// cronRunner 定时任务调度循环（Salt 定时作业与漂移扫描策略共用）
+// 定期查询到期任务，以乐观锁抢占本次触发并推进 next_run_at 后回调 fire，多实例部署时每次触发只执行一次
+type cronRunner struct {
+	name      string // 日志前缀
+	db        *gorm.DB
+	model     interface{} // 任务表模型，需含 enabled、cron_expr、timezone、next_run_at、last_run_at 列
+	fire      func(id uint, scheduledAt time.Time)
+	startOnce sync.Once
+	stopOnce  sync.Once
+	stopCh    chan struct{}
+}
+
+func newCronRunner(name string, db *gorm.DB, model interface{}, fire func(id uint, scheduledAt time.Time)) *cronRunner {
+	return &cronRunner{name: name, db: db, model: model, fire: fire, stopCh: make(chan struct{})}
+}
+
+// Start 启动调度循环，onStart 在首次启动时执行（如清理重启前遗留的运行记录）
+func (r *cronRunner) Start(onStart func()) {
+	r.startOnce.Do(func() {
+		if onStart != nil {
+			onStart()
+		}
+		go func() {
+			ticker := time.NewTicker(cronRunnerInterval)
+			defer ticker.Stop()
+
+			r.tick()
+			for {
+				select {
+				case <-ticker.C:
+					r.tick()
+				case <-r.stopCh:
+					log.Printf("[%s] 调度器已停止", r.name)
+					return
+				}
+			}
+		}()
+		log.Printf("[%s] 调度器已启动", r.name)
+	})
+}
+
+// Stop 停止调度循环
+func (r *cronRunner) Stop() {
+	r.stopOnce.Do(func() {
+		close(r.stopCh)
+	})
+}
+
+// tick 检查到期任务并触发
+func (r *cronRunner) tick() {
+	now := time.Now()
+	var due []cronDueTask
+	if err := r.db.Model(r.model).
+		Where("enabled = ? AND next_run_at IS NOT NULL AND next_run_at <= ?", true, now).
+		Find(&due).Error; err != nil {
+		log.Printf("[%s] 查询到期任务失败: %v", r.name, err)
+		return
+	}
+
+	for _, task := range due {
+		scheduledAt := *task.NextRunAt
+		next, err := nextCronRun(task.CronExpr, task.Timezone, now)
+		if err != nil {
+			log.Printf("[%s] 计算下次运行时间失败: name=%s, err=%v", r.name, task.Name, err)
+			continue
+		}
+		// 乐观锁抢占本次触发，避免多实例部署时重复执行
+		res := r.db.Model(r.model).
+			Where("id = ? AND next_run_at = ?", task.ID, scheduledAt).
+			Updates(map[string]interface{}{"next_run_at": next, "last_run_at": now})
+		if res.Error != nil || res.RowsAffected == 0 {
+			continue
+		}
+		r.fire(task.ID, scheduledAt)
+	}
+}
+
+// nextCronRun 按时区计算 cron 表达式在 from 之后的下一次触发时间
+func nextCronRun(cronExpr, timezone string, from time.Time) (*time.Time, error) {
+	expr, err := ParseCronExpression(cronExpr)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := loadScheduleLocation(timezone)
+	if err != nil {
+		return nil, err
+	}
+	next := expr.Next(from.In(loc))
+	if next.IsZero() {
+		return nil, fmt.Errorf("cron expression %q never fires", cronExpr)
+	}
+	return &next, nil
+}
+
+func loadScheduleLocation(tz string) (*time.Location, error) {
+	if tz == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q: %v", tz, err)
+	}
+	return loc, nil
+}
+
+// postWebhook 以 JSON 回调通知 Webhook
+// 允许列表可能在保存后收紧，发送前再次校验
+func postWebhook(client *http.Client, webhook string, payload interface{}) error {
+	if err := checkWebhookURL(webhook); err != nil {
+		return err
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Post(webhook, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNextCronRun(t *testing.T) {
+	from := time.Date(2024, 1, 31, 23, 30, 0, 0, time.UTC)
+	next, err := nextCronRun("0 8 * * *", "Asia/Shanghai", from)
+	if err != nil {
+		t.Fatalf("next run: %v", err)
+	}
+	// UTC 23:30 即上海时间次日 07:30，下一次为当天 08:00
+	if want := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC); !next.Equal(want) {
+		t.Fatalf("next = %v, want %v", next.UTC(), want)
+	}
+	if _, err := nextCronRun("0 8 * * *", "Mars/Olympus", from); err == nil {
+		t.Fatal("无效时区应返回错误")
+	}
+	if _, err := nextCronRun("bad", "", from); err == nil {
+		t.Fatal("无效表达式应返回错误")
+	}
+}
+
+func TestPostWebhook(t *testing.T) {
+	var got map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+	client := server.Client()
+	payload := map[string]interface{}{"event": "salt_node_drifted"}
+
+	t.Setenv("WEBHOOK_ALLOWED_HOSTS", "")
+	if err := postWebhook(client, server.URL, payload); err == nil || got != nil {
+		t.Fatal("不在允许列表中的主机不应发送")
+	}
+
+	t.Setenv("WEBHOOK_ALLOWED_HOSTS", "127.0.0.1")
+	if err := postWebhook(client, server.URL, payload); err != nil {
+		t.Fatalf("post: %v", err)
+	}
+	if got["event"] != "salt_node_drifted" {
+		t.Fatalf("payload = %v", got)
+	}
+	if err := postWebhook(client, server.URL+"/fail", payload); err == nil {
+		t.Fatal("非 2xx 状态应返回错误")
+	}
+}
@@ -38,7 +38,7 @@ func (fs *FilesService) ListDirectory(ctx context.Context, clusterID, dir string
 
 	// 使用 ls -la --time-style=+%s 统一时间格式（秒时间戳）
 	cmd := fmt.Sprintf("/bin/sh -lc 'ls -la --time-style=+%s %s'", "%s", escapePath(dir))
-	out, err := fs.sshSvc.ExecuteCommand(cluster.Host, cluster.Port, "root", "", cmd)
+	out, err := fs.sshSvc.ExecuteCommandVia(cluster.Host, cluster.Port, "root", "", cluster.JumpHosts, cmd)
 	if err != nil {
 		return nil, fmt.Errorf("list directory failed: %w", err)
 	}
@@ -105,7 +105,7 @@ func (fs *FilesService) DownloadFile(ctx context.Context, clusterID, filePath st
 
 	// 使用 base64 进行安全传输，避免编码问题
 	cmd := fmt.Sprintf("/bin/sh -lc 'base64 -w0 %s'", escapePath(filePath))
-	out, err := fs.sshSvc.ExecuteCommand(cluster.Host, cluster.Port, "root", "", cmd)
+	out, err := fs.sshSvc.ExecuteCommandVia(cluster.Host, cluster.Port, "root", "", cluster.JumpHosts, cmd)
 	if err != nil {
 		return nil, fmt.Errorf("read remote file failed: %w", err)
 	}
@@ -136,7 +136,7 @@ func (fs *FilesService) UploadFile(ctx context.Context, clusterID, filePath stri
 		chunk := b64[i:end]
 		// 逐块追加到临时文件
 		appendCmd := fmt.Sprintf("/bin/sh -lc 'printf %s >> /tmp/.aimatrix_upload.b64'", singleQuoted(chunk))
-		if _, err := fs.sshSvc.ExecuteCommand(cluster.Host, cluster.Port, "root", "", appendCmd); err != nil {
+		if _, err := fs.sshSvc.ExecuteCommandVia(cluster.Host, cluster.Port, "root", "", cluster.JumpHosts, appendCmd); err != nil {
 			return fmt.Errorf("upload chunk failed: %w", err)
 		}
 	}
@@ -144,7 +144,7 @@ func (fs *FilesService) UploadFile(ctx context.Context, clusterID, filePath stri
 
 	// 解码到目标路径并清理临时文件
 	finalize := fmt.Sprintf("/bin/sh -lc 'base64 -d /tmp/.aimatrix_upload.b64 > %s && rm -f /tmp/.aimatrix_upload.b64'", escapePath(filePath))
-	if _, err := fs.sshSvc.ExecuteCommand(cluster.Host, cluster.Port, "root", "", finalize); err != nil {
+	if _, err := fs.sshSvc.ExecuteCommandVia(cluster.Host, cluster.Port, "root", "", cluster.JumpHosts, finalize); err != nil {
 		return fmt.Errorf("finalize upload failed: %w", err)
 	}
 	return nil
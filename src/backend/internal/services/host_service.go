@@ -19,6 +19,7 @@ func (s *HostService) CreateHost(host *models.Host) error {
 	if err := database.DB.Create(host).Error; err != nil {
 		return fmt.Errorf("failed to create host: %w", err)
 	}
+	ObserveInventoryAsync(AnsibleHostObservation(host))
 
 	// 清除相关缓存
 	cache.Delete(cache.HostsKey(host.ProjectID))
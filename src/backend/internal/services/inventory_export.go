@@ -0,0 +1,490 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+// 清单导出格式
+const (
+	InventoryExportAnsibleJSON   = "ansible-json"   // Ansible 动态清单 JSON（--list 输出格式）
+	InventoryExportAnsibleYAML   = "ansible-yaml"   // Ansible YAML 静态清单
+	InventoryExportSaltRoster    = "salt-roster"    // Salt-SSH roster
+	InventoryExportSlurmHostlist = "slurm-hostlist" // Slurm hostlist 表达式（如 node[01-04,07]）
+	InventoryExportCSV           = "csv"            // 与 /api/saltstack/hosts 导入兼容的 CSV（不含密码）
+)
+
+// InventoryViewer 导出清单的调用者，非管理员只能看到自己有权限的主机
+type InventoryViewer struct {
+	UserID   uint
+	Username string
+	IsAdmin  bool
+}
+
+// InventoryExportQuery 导出过滤条件与格式参数
+type InventoryExportQuery struct {
+	Label  string // key=value
+	Rack   string
+	Owner  string
+	Source string
+	Group  string // 仅导出该 Ansible 组内的主机
+	User   string // Salt roster 中的 SSH 用户，默认 root
+	Sudo   bool   // Salt roster 中启用 sudo
+}
+
+// InventoryExport 导出结果
+type InventoryExport struct {
+	Content     []byte
+	ContentType string
+	Filename    string
+}
+
+// inventoryScope 非管理员可见范围：自有主机、自有项目、有 view 权限的 Slurm 集群与 Salt Master
+type inventoryScope struct {
+	viewer       InventoryViewer
+	projects     map[string]bool
+	clusters     map[string]bool
+	saltPerms    []models.SaltstackClusterPermission
+	unrestricted bool
+}
+
+func (s *InventoryService) resolveScope(ctx context.Context, viewer InventoryViewer) (*inventoryScope, error) {
+	scope := &inventoryScope{viewer: viewer, unrestricted: viewer.IsAdmin}
+	if viewer.IsAdmin {
+		return scope, nil
+	}
+
+	var projectIDs []uint
+	if err := s.db.WithContext(ctx).Model(&models.Project{}).Where("user_id = ?", viewer.UserID).Pluck("id", &projectIDs).Error; err != nil {
+		return nil, err
+	}
+	scope.projects = make(map[string]bool, len(projectIDs))
+	for _, id := range projectIDs {
+		scope.projects[fmt.Sprintf("project-%d", id)] = true
+	}
+
+	permService := NewClusterPermissionService(s.db)
+	slurmPerms, err := permService.GetUserSlurmPermissions(ctx, viewer.UserID)
+	if err != nil {
+		return nil, err
+	}
+	scope.clusters = make(map[string]bool, len(slurmPerms))
+	for i := range slurmPerms {
+		if slurmPerms[i].HasVerb(models.VerbView) {
+			scope.clusters[SSHCAClusterScope(slurmPerms[i].ClusterID)] = true
+		}
+	}
+
+	saltPerms, err := permService.GetUserSaltstackPermissions(ctx, viewer.UserID)
+	if err != nil {
+		return nil, err
+	}
+	for _, perm := range saltPerms {
+		if perm.HasVerb(models.VerbView) {
+			scope.saltPerms = append(scope.saltPerms, perm)
+		}
+	}
+	return scope, nil
+}
+
+// allows 判断主机是否在可见范围内
+func (sc *inventoryScope) allows(host *models.InventoryHost) bool {
+	if sc.unrestricted {
+		return true
+	}
+	if host.Owner != "" && host.Owner == sc.viewer.Username {
+		return true
+	}
+	for _, link := range host.Links {
+		switch link.Source {
+		case models.InventorySourceAnsible:
+			if sc.projects[link.Scope] {
+				return true
+			}
+		case models.InventorySourceSlurm:
+			if sc.clusters[link.Scope] {
+				return true
+			}
+		case models.InventorySourceSalt:
+			for i := range sc.saltPerms {
+				perm := &sc.saltPerms[i]
+				if (link.Scope == "" || link.Scope == perm.MasterID) && perm.HasMinionAccess(link.Name) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// VisibleHosts 按过滤条件查询调用者可见的清单主机（含关联）
+func (s *InventoryService) VisibleHosts(ctx context.Context, viewer InventoryViewer, q InventoryExportQuery) ([]models.InventoryHost, error) {
+	scope, err := s.resolveScope(ctx, viewer)
+	if err != nil {
+		return nil, err
+	}
+
+	query := s.db.WithContext(ctx).Preload("Links")
+	if q.Owner != "" {
+		query = query.Where("owner = ?", q.Owner)
+	}
+	if q.Rack != "" {
+		query = query.Where("rack = ?", q.Rack)
+	}
+	if q.Source != "" {
+		query = query.Where("id IN (?)", s.db.Model(&models.InventoryHostLink{}).Select("host_id").Where("source = ?", q.Source))
+	}
+	var hosts []models.InventoryHost
+	if err := query.Order("hostname, id").Find(&hosts).Error; err != nil {
+		return nil, err
+	}
+
+	labelKey, labelValue, hasLabel := strings.Cut(q.Label, "=")
+	visible := hosts[:0]
+	for i := range hosts {
+		if hasLabel && hosts[i].Labels[labelKey] != labelValue {
+			continue
+		}
+		if q.Group != "" && !containsString(inventoryHostGroups(&hosts[i]), q.Group) {
+			continue
+		}
+		if scope.allows(&hosts[i]) {
+			visible = append(visible, hosts[i])
+		}
+	}
+	return visible, nil
+}
+
+// Export 按格式导出调用者可见的主机
+func (s *InventoryService) Export(ctx context.Context, viewer InventoryViewer, format string, q InventoryExportQuery) (*InventoryExport, error) {
+	hosts, err := s.VisibleHosts(ctx, viewer, q)
+	if err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case InventoryExportAnsibleJSON, "ansible", "json":
+		content, err := RenderAnsibleInventoryJSON(hosts)
+		return &InventoryExport{Content: content, ContentType: "application/json", Filename: "inventory.json"}, err
+	case InventoryExportAnsibleYAML, "yaml":
+		content, err := RenderAnsibleInventoryYAML(hosts)
+		return &InventoryExport{Content: content, ContentType: "application/x-yaml", Filename: "inventory.yml"}, err
+	case InventoryExportSaltRoster, "roster":
+		content, err := RenderSaltRoster(hosts, q.User, q.Sudo)
+		return &InventoryExport{Content: content, ContentType: "application/x-yaml", Filename: "roster"}, err
+	case InventoryExportSlurmHostlist, "hostlist":
+		content := []byte(CompressSlurmHostlist(inventorySlurmNames(hosts)) + "\n")
+		return &InventoryExport{Content: content, ContentType: "text/plain; charset=utf-8", Filename: "hostlist"}, nil
+	case InventoryExportCSV:
+		configs := make([]HostConfig, 0, len(hosts))
+		for i := range hosts {
+			configs = append(configs, HostConfig{
+				Host:     inventoryAnsibleName(&hosts[i]),
+				Port:     inventoryHostPort(&hosts[i]),
+				MinionID: inventorySaltID(&hosts[i]),
+				Group:    strings.Join(inventoryHostGroups(&hosts[i]), ","),
+			})
+		}
+		content, err := NewHostParserService().ExportHosts(configs, "csv")
+		return &InventoryExport{Content: content, ContentType: "text/csv; charset=utf-8", Filename: "hosts.csv"}, err
+	default:
+		return nil, fmt.Errorf("unsupported export format: %s", format)
+	}
+}
+
+// AnsibleHostVars 单台主机的 hostvars（ansible-inventory --host 输出），主机不可见时返回空对象
+func (s *InventoryService) AnsibleHostVars(ctx context.Context, viewer InventoryViewer, name string) (map[string]interface{}, error) {
+	hosts, err := s.VisibleHosts(ctx, viewer, InventoryExportQuery{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range hosts {
+		if inventoryAnsibleName(&hosts[i]) == name {
+			return inventoryHostVars(&hosts[i]), nil
+		}
+	}
+	return map[string]interface{}{}, nil
+}
+
+// ==================== 渲染 ====================
+
+var inventoryGroupNameRe = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+
+// inventoryGroupName 转换为合法的 Ansible 组名
+func inventoryGroupName(parts ...string) string {
+	name := inventoryGroupNameRe.ReplaceAllString(strings.Join(parts, "_"), "_")
+	name = strings.Trim(name, "_")
+	if name != "" && name[0] >= '0' && name[0] <= '9' {
+		name = "g_" + name
+	}
+	return strings.ToLower(name)
+}
+
+// inventoryHostGroups 主机所属的 Ansible 组：group/groups 标签、机柜、关联的项目/集群/Master
+func inventoryHostGroups(host *models.InventoryHost) []string {
+	var groups []string
+	add := func(name string) {
+		if name != "" && name != "all" && name != "ungrouped" && !containsString(groups, name) {
+			groups = append(groups, name)
+		}
+	}
+	add(inventoryGroupName(host.Labels["group"]))
+	for _, g := range strings.Split(host.Labels["groups"], ",") {
+		add(inventoryGroupName(g))
+	}
+	if host.Rack != "" {
+		add(inventoryGroupName("rack", host.Rack))
+	}
+	for _, link := range host.Links {
+		switch link.Source {
+		case models.InventorySourceAnsible:
+			add(inventoryGroupName(link.Scope))
+			add(inventoryGroupName(link.Attributes["group"]))
+		case models.InventorySourceSlurm:
+			add(inventoryGroupName("slurm", link.Scope))
+		case models.InventorySourceSalt:
+			if link.Scope != "" {
+				add(inventoryGroupName("salt", link.Scope))
+			}
+		}
+	}
+	sort.Strings(groups)
+	return groups
+}
+
+// inventoryAnsibleName 主机在导出清单中的名称：主机名，缺失时使用主地址
+func inventoryAnsibleName(host *models.InventoryHost) string {
+	if host.Hostname != "" {
+		return host.Hostname
+	}
+	return host.PrimaryIP
+}
+
+func inventoryHostPort(host *models.InventoryHost) int {
+	if host.Port > 0 {
+		return host.Port
+	}
+	return 22
+}
+
+// inventorySaltID Salt Minion ID：优先使用关联的 Minion，否则使用主机名
+func inventorySaltID(host *models.InventoryHost) string {
+	for _, link := range host.Links {
+		if link.Source == models.InventorySourceSalt && link.Name != "" {
+			return link.Name
+		}
+	}
+	return inventoryAnsibleName(host)
+}
+
+// inventorySlurmNames Slurm 节点名：优先使用关联的节点名，否则取主机名的短名
+func inventorySlurmNames(hosts []models.InventoryHost) []string {
+	names := make([]string, 0, len(hosts))
+	for i := range hosts {
+		name := ""
+		for _, link := range hosts[i].Links {
+			if link.Source == models.InventorySourceSlurm && link.Name != "" {
+				name = link.Name
+				break
+			}
+		}
+		if name == "" && hosts[i].Hostname != "" {
+			name = strings.SplitN(hosts[i].Hostname, ".", 2)[0]
+		}
+		if name == "" {
+			name = hosts[i].PrimaryIP
+		}
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// inventoryHostVars 主机变量（不包含任何凭据）
+func inventoryHostVars(host *models.InventoryHost) map[string]interface{} {
+	vars := map[string]interface{}{"inventory_id": host.ID}
+	if host.PrimaryIP != "" {
+		vars["ansible_host"] = host.PrimaryIP
+	}
+	if port := inventoryHostPort(host); port != 22 {
+		vars["ansible_port"] = port
+	}
+	if host.Rack != "" {
+		vars["rack"] = host.Rack
+	}
+	if host.Location != "" {
+		vars["location"] = host.Location
+	}
+	if host.Owner != "" {
+		vars["owner"] = host.Owner
+	}
+	if len(host.Labels) > 0 {
+		vars["inventory_labels"] = map[string]string(host.Labels)
+	}
+	return vars
+}
+
+// RenderAnsibleInventoryJSON 生成 Ansible 动态清单 JSON（含 _meta.hostvars，可直接作为 --list 输出）
+func RenderAnsibleInventoryJSON(hosts []models.InventoryHost) ([]byte, error) {
+	inventory := map[string]interface{}{}
+	hostvars := make(map[string]interface{}, len(hosts))
+	members := map[string][]string{}
+	var ungrouped []string
+	for i := range hosts {
+		name := inventoryAnsibleName(&hosts[i])
+		if name == "" {
+			continue
+		}
+		hostvars[name] = inventoryHostVars(&hosts[i])
+		groups := inventoryHostGroups(&hosts[i])
+		if len(groups) == 0 {
+			ungrouped = append(ungrouped, name)
+		}
+		for _, g := range groups {
+			members[g] = append(members[g], name)
+		}
+	}
+
+	children := make([]string, 0, len(members)+1)
+	for g, list := range members {
+		inventory[g] = map[string]interface{}{"hosts": list}
+		children = append(children, g)
+	}
+	sort.Strings(children)
+	children = append(children, "ungrouped")
+	inventory["ungrouped"] = map[string]interface{}{"hosts": nonNilStrings(ungrouped)}
+	inventory["all"] = map[string]interface{}{"children": children}
+	inventory["_meta"] = map[string]interface{}{"hostvars": hostvars}
+	return json.MarshalIndent(inventory, "", "  ")
+}
+
+// RenderAnsibleInventoryYAML 生成 Ansible YAML 清单（all.hosts 保存变量，children 列出组成员）
+func RenderAnsibleInventoryYAML(hosts []models.InventoryHost) ([]byte, error) {
+	allHosts := map[string]interface{}{}
+	children := map[string]interface{}{}
+	for i := range hosts {
+		name := inventoryAnsibleName(&hosts[i])
+		if name == "" {
+			continue
+		}
+		allHosts[name] = inventoryHostVars(&hosts[i])
+		for _, g := range inventoryHostGroups(&hosts[i]) {
+			group, ok := children[g].(map[string]interface{})
+			if !ok {
+				group = map[string]interface{}{"hosts": map[string]interface{}{}}
+				children[g] = group
+			}
+			group["hosts"].(map[string]interface{})[name] = nil
+		}
+	}
+	all := map[string]interface{}{"hosts": allHosts}
+	if len(children) > 0 {
+		all["children"] = children
+	}
+	return yaml.Marshal(map[string]interface{}{"all": all})
+}
+
+// RenderSaltRoster 生成 Salt-SSH roster（以 Minion ID 为键，不包含密码）
+func RenderSaltRoster(hosts []models.InventoryHost, user string, sudo bool) ([]byte, error) {
+	if user == "" {
+		user = "root"
+	}
+	roster := make(map[string]interface{}, len(hosts))
+	for i := range hosts {
+		id := inventorySaltID(&hosts[i])
+		address := hosts[i].PrimaryIP
+		if address == "" {
+			address = hosts[i].Hostname
+		}
+		if id == "" || address == "" {
+			continue
+		}
+		entry := map[string]interface{}{"host": address, "port": inventoryHostPort(&hosts[i]), "user": user}
+		if sudo && user != "root" {
+			entry["sudo"] = true
+		}
+		grains := map[string]string{}
+		for k, v := range hosts[i].Labels {
+			grains[k] = v
+		}
+		if hosts[i].Rack != "" {
+			grains["rack"] = hosts[i].Rack
+		}
+		if len(grains) > 0 {
+			entry["minion_opts"] = map[string]interface{}{"grains": grains}
+		}
+		roster[id] = entry
+	}
+	return yaml.Marshal(roster)
+}
+
+var slurmHostnameRe = regexp.MustCompile(`^(.*?)(\d+)$`)
+
+// CompressSlurmHostlist 将节点名压缩为 Slurm hostlist 表达式，如 node01,node02,node03,gpu1 -> gpu1,node[01-03]
+// 仅合并前缀相同且数字位数相同的名称
+func CompressSlurmHostlist(names []string) string {
+	type bucket struct {
+		prefix string
+		width  int
+		nums   []int
+	}
+	buckets := map[string]*bucket{}
+	var plain []string
+	seen := map[string]bool{}
+	for _, name := range names {
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		m := slurmHostnameRe.FindStringSubmatch(name)
+		if m == nil || strings.ContainsAny(m[1], "[],") {
+			plain = append(plain, name)
+			continue
+		}
+		n, err := strconv.Atoi(m[2])
+		if err != nil {
+			plain = append(plain, name)
+			continue
+		}
+		key := m[1] + "\x00" + strconv.Itoa(len(m[2]))
+		b, ok := buckets[key]
+		if !ok {
+			b = &bucket{prefix: m[1], width: len(m[2])}
+			buckets[key] = b
+		}
+		b.nums = append(b.nums, n)
+	}
+
+	parts := append([]string{}, plain...)
+	for _, b := range buckets {
+		sort.Ints(b.nums)
+		if len(b.nums) == 1 {
+			parts = append(parts, fmt.Sprintf("%s%0*d", b.prefix, b.width, b.nums[0]))
+			continue
+		}
+		var ranges []string
+		for i := 0; i < len(b.nums); {
+			j := i
+			for j+1 < len(b.nums) && b.nums[j+1] == b.nums[j]+1 {
+				j++
+			}
+			if i == j {
+				ranges = append(ranges, fmt.Sprintf("%0*d", b.width, b.nums[i]))
+			} else {
+				ranges = append(ranges, fmt.Sprintf("%0*d-%0*d", b.width, b.nums[i], b.width, b.nums[j]))
+			}
+			i = j + 1
+		}
+		parts = append(parts, fmt.Sprintf("%s[%s]", b.prefix, strings.Join(ranges, ",")))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
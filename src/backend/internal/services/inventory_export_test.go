@@ -0,0 +1,119 @@
+package services
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+func TestCompressSlurmHostlist(t *testing.T) {
+	cases := []struct {
+		names []string
+		want  string
+	}{
+		{[]string{"node01", "node02", "node03", "node07"}, "node[01-03,07]"},
+		{[]string{"gpu1", "gpu2", "gpu10", "login"}, "gpu10,gpu[1-2],login"},
+		{[]string{"cn003", "cn001", "cn002", "cn001"}, "cn[001-003]"},
+		{[]string{"single5"}, "single5"},
+	}
+	for _, tc := range cases {
+		if got := CompressSlurmHostlist(tc.names); got != tc.want {
+			t.Errorf("CompressSlurmHostlist(%v) = %q, want %q", tc.names, got, tc.want)
+		}
+	}
+}
+
+func testInventoryHosts() []models.InventoryHost {
+	return []models.InventoryHost{
+		{
+			ID: 1, Hostname: "gpu-node01", PrimaryIP: "10.0.0.5", Port: 2222, Rack: "A01",
+			Labels: models.InventoryLabels{"group": "gpu"},
+			Links: []models.InventoryHostLink{
+				{Source: models.InventorySourceSlurm, Scope: "cluster-2", Name: "cn01"},
+				{Source: models.InventorySourceSalt, Scope: "master-a", Name: "hpc-cn01"},
+			},
+		},
+		{ID: 2, PrimaryIP: "10.0.0.9", Port: 22, Owner: "alice"},
+	}
+}
+
+func TestRenderAnsibleInventoryJSON(t *testing.T) {
+	content, err := RenderAnsibleInventoryJSON(testInventoryHosts())
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+	var inv struct {
+		Meta struct {
+			HostVars map[string]map[string]interface{} `json:"hostvars"`
+		} `json:"_meta"`
+		All struct {
+			Children []string `json:"children"`
+		} `json:"all"`
+		Gpu       struct{ Hosts []string } `json:"gpu"`
+		Ungrouped struct{ Hosts []string } `json:"ungrouped"`
+	}
+	if err := json.Unmarshal(content, &inv); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	vars := inv.Meta.HostVars["gpu-node01"]
+	if vars["ansible_host"] != "10.0.0.5" || vars["ansible_port"] != float64(2222) || vars["rack"] != "A01" {
+		t.Fatalf("hostvars 不正确: %v", vars)
+	}
+	if !reflect.DeepEqual(inv.Gpu.Hosts, []string{"gpu-node01"}) || !reflect.DeepEqual(inv.Ungrouped.Hosts, []string{"10.0.0.9"}) {
+		t.Fatalf("分组不正确: %s", content)
+	}
+	want := []string{"gpu", "rack_a01", "salt_master_a", "slurm_cluster_2", "ungrouped"}
+	if !reflect.DeepEqual(inv.All.Children, want) {
+		t.Fatalf("all.children = %v, want %v", inv.All.Children, want)
+	}
+}
+
+func TestRenderSaltRoster(t *testing.T) {
+	content, err := RenderSaltRoster(testInventoryHosts(), "ops", true)
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+	var roster map[string]map[string]interface{}
+	if err := yaml.Unmarshal(content, &roster); err != nil {
+		t.Fatalf("invalid yaml: %v", err)
+	}
+	entry := roster["hpc-cn01"]
+	if entry["host"] != "10.0.0.5" || entry["port"] != 2222 || entry["user"] != "ops" || entry["sudo"] != true {
+		t.Fatalf("roster 条目不正确: %v", entry)
+	}
+	if _, ok := roster["10.0.0.9"]; !ok {
+		t.Fatalf("无 Minion 关联的主机应以地址为 ID: %s", content)
+	}
+}
+
+func TestInventoryScopeAllows(t *testing.T) {
+	hosts := testInventoryHosts()
+	scope := &inventoryScope{
+		viewer:   InventoryViewer{UserID: 3, Username: "alice"},
+		clusters: map[string]bool{},
+	}
+	if scope.allows(&hosts[0]) {
+		t.Fatal("无任何权限时不应看到集群节点")
+	}
+	if !scope.allows(&hosts[1]) {
+		t.Fatal("应看到归属自己的主机")
+	}
+
+	scope.clusters["cluster-2"] = true
+	if !scope.allows(&hosts[0]) {
+		t.Fatal("有集群 view 权限时应看到该集群节点")
+	}
+
+	scope.clusters = map[string]bool{}
+	scope.saltPerms = []models.SaltstackClusterPermission{{MasterID: "master-b", AllMinions: true}}
+	if scope.allows(&hosts[0]) {
+		t.Fatal("其他 Master 的权限不应生效")
+	}
+	scope.saltPerms = []models.SaltstackClusterPermission{{MasterID: "master-a", MinionPatterns: models.StringArray{"hpc-*"}}}
+	if !scope.allows(&hosts[0]) {
+		t.Fatal("匹配的 Minion 模式应可见")
+	}
+}
@@ -0,0 +1,705 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
+)
+
+const (
+	defaultInventorySourceTimeout = 30 * time.Second
+	maxInventorySourceBody        = 32 << 20 // 单次拉取内容上限 32MB
+	netboxPageLimit               = 1000
+)
+
+// InventorySourceHost 外部来源中的一台主机（已映射为清单字段）
+type InventorySourceHost struct {
+	Key      string            `json:"key"` // 来源内唯一标识（NetBox 对象 ID、MAC、主机名等）
+	Hostname string            `json:"hostname"`
+	IPs      []string          `json:"ips"`
+	Labels   map[string]string `json:"labels"`
+	Rack     string            `json:"rack"`
+	Location string            `json:"location"`
+	Owner    string            `json:"owner"`
+}
+
+// InventoryFetcher 外部清单来源抓取器
+type InventoryFetcher interface {
+	Fetch(ctx context.Context, src *models.InventorySource) ([]InventorySourceHost, error)
+}
+
+// NewInventoryFetcher 按来源类型创建抓取器
+func NewInventoryFetcher(sourceType string) (InventoryFetcher, error) {
+	switch sourceType {
+	case models.InventorySourceTypeNetBox:
+		return &netboxInventoryFetcher{}, nil
+	case models.InventorySourceTypePrometheus:
+		return &prometheusInventoryFetcher{}, nil
+	case models.InventorySourceTypeDHCP:
+		return &dhcpInventoryFetcher{}, nil
+	case models.InventorySourceTypeAnsible:
+		return &ansibleScriptInventoryFetcher{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported inventory source type: %s", sourceType)
+	}
+}
+
+// ValidateInventorySource 检查来源配置是否完整，本地路径必须位于允许的目录内
+func ValidateInventorySource(src *models.InventorySource) error {
+	if _, err := NewInventoryFetcher(src.Type); err != nil {
+		return err
+	}
+	cfg := src.Config
+	switch src.Type {
+	case models.InventorySourceTypeNetBox:
+		if cfg.URL == "" {
+			return fmt.Errorf("netbox source requires config.url")
+		}
+	case models.InventorySourceTypePrometheus:
+		if cfg.URL == "" && cfg.Path == "" {
+			return fmt.Errorf("prometheus source requires config.url or config.path")
+		}
+	case models.InventorySourceTypeDHCP:
+		if cfg.URL == "" && cfg.Path == "" {
+			return fmt.Errorf("dhcp source requires config.url or config.path")
+		}
+	case models.InventorySourceTypeAnsible:
+		if cfg.URL == "" && cfg.Path == "" {
+			return fmt.Errorf("ansible_script source requires config.path (script) or config.url (inventory JSON)")
+		}
+		if cfg.URL == "" {
+			_, err := resolveAllowedPath(cfg.Path, inventoryScriptDirs())
+			return err
+		}
+		return nil
+	}
+	if cfg.URL != "" {
+		u, err := url.Parse(cfg.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid config.url: %s", cfg.URL)
+		}
+		return nil
+	}
+	_, err := resolveAllowedPath(cfg.Path, inventoryFileDirs())
+	return err
+}
+
+// inventoryFileDirs 允许读取的本地清单文件目录（INVENTORY_SOURCE_DIRS，冒号分隔）
+func inventoryFileDirs() []string {
+	return splitAllowedDirs(getEnvOrDefault("INVENTORY_SOURCE_DIRS", "/var/lib/dhcp:/var/lib/misc:/etc/ai-infra/inventory"))
+}
+
+// inventoryScriptDirs 允许执行的动态清单脚本目录（INVENTORY_SCRIPTS_DIR，冒号分隔）
+func inventoryScriptDirs() []string {
+	return splitAllowedDirs(getEnvOrDefault("INVENTORY_SCRIPTS_DIR", "/etc/ai-infra/inventory-scripts"))
+}
+
+func inventorySourceTimeout(src *models.InventorySource) time.Duration {
+	if src.Config.TimeoutSeconds > 0 {
+		return time.Duration(src.Config.TimeoutSeconds) * time.Second
+	}
+	return defaultInventorySourceTimeout
+}
+
+// fetchInventoryURL 以 GET 拉取来源内容，authHeader 为空时不携带令牌
+func fetchInventoryURL(ctx context.Context, src *models.InventorySource, rawURL, authHeader string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if authHeader != "" && src.Token != "" {
+		req.Header.Set("Authorization", authHeader+" "+src.Token)
+	}
+
+	client := &http.Client{
+		Timeout: inventorySourceTimeout(src),
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: src.Config.InsecureSkipVerify},
+		},
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxInventorySourceBody))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: HTTP %d: %s", rawURL, resp.StatusCode, truncateText(strings.TrimSpace(string(body)), 200))
+	}
+	return body, nil
+}
+
+// readInventoryContent 从 URL（Bearer 令牌）或允许目录内的本地文件读取来源内容
+func readInventoryContent(ctx context.Context, src *models.InventorySource) ([]byte, error) {
+	if src.Config.URL != "" {
+		return fetchInventoryURL(ctx, src, src.Config.URL, "Bearer")
+	}
+	path, err := resolveAllowedPath(src.Config.Path, inventoryFileDirs())
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(io.LimitReader(f, maxInventorySourceBody))
+}
+
+// ==================== NetBox ====================
+
+type netboxInventoryFetcher struct{}
+
+type netboxRef struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	Slug  string `json:"slug"`
+	Value string `json:"value"`
+}
+
+type netboxIP struct {
+	Address string `json:"address"`
+}
+
+type netboxObject struct {
+	ID          int         `json:"id"`
+	Name        string      `json:"name"`
+	PrimaryIP4  *netboxIP   `json:"primary_ip4"`
+	PrimaryIP6  *netboxIP   `json:"primary_ip6"`
+	PrimaryIP   *netboxIP   `json:"primary_ip"`
+	Site        *netboxRef  `json:"site"`
+	Rack        *netboxRef  `json:"rack"`
+	Tenant      *netboxRef  `json:"tenant"`
+	Role        *netboxRef  `json:"role"`
+	DeviceRole  *netboxRef  `json:"device_role"` // NetBox 3.x 字段名
+	Platform    *netboxRef  `json:"platform"`
+	Status      *netboxRef  `json:"status"`
+	Cluster     *netboxRef  `json:"cluster"`
+	Tags        []netboxRef `json:"tags"`
+	Description string      `json:"description"`
+}
+
+type netboxPage struct {
+	Next    string         `json:"next"`
+	Results []netboxObject `json:"results"`
+}
+
+// Fetch 拉取 dcim/devices 与 virtualization/virtual-machines，按 next 链接分页
+func (f *netboxInventoryFetcher) Fetch(ctx context.Context, src *models.InventorySource) ([]InventorySourceHost, error) {
+	base := strings.TrimRight(src.Config.URL, "/")
+	base = strings.TrimSuffix(base, "/api")
+
+	var hosts []InventorySourceHost
+	for _, endpoint := range []struct{ path, kind string }{
+		{"/api/dcim/devices/", "device"},
+		{"/api/virtualization/virtual-machines/", "vm"},
+	} {
+		next := fmt.Sprintf("%s%s?limit=%d", base, endpoint.path, netboxPageLimit)
+		if src.Config.Query != "" {
+			next += "&" + strings.TrimPrefix(src.Config.Query, "?")
+		}
+		for pages := 0; next != "" && pages < 1000; pages++ {
+			body, err := fetchInventoryURL(ctx, src, next, "Token")
+			if err != nil {
+				return nil, err
+			}
+			page, err := parseNetBoxPage(body, endpoint.kind)
+			if err != nil {
+				return nil, fmt.Errorf("parse %s: %v", endpoint.path, err)
+			}
+			hosts = append(hosts, page.hosts...)
+			next = page.next
+		}
+	}
+	return hosts, nil
+}
+
+type parsedNetBoxPage struct {
+	hosts []InventorySourceHost
+	next  string
+}
+
+// parseNetBoxPage 解析一页 NetBox 设备/虚拟机列表，kind 为 device 或 vm
+func parseNetBoxPage(data []byte, kind string) (*parsedNetBoxPage, error) {
+	var page netboxPage
+	if err := json.Unmarshal(data, &page); err != nil {
+		return nil, err
+	}
+	result := &parsedNetBoxPage{next: page.Next}
+	for _, obj := range page.Results {
+		host := InventorySourceHost{
+			Key:      fmt.Sprintf("%s:%d", kind, obj.ID),
+			Hostname: obj.Name,
+			Labels:   map[string]string{"netbox_kind": kind},
+		}
+		for _, ip := range []*netboxIP{obj.PrimaryIP4, obj.PrimaryIP6, obj.PrimaryIP} {
+			if ip == nil || ip.Address == "" {
+				continue
+			}
+			addr := strings.SplitN(ip.Address, "/", 2)[0]
+			if !containsString(host.IPs, addr) {
+				host.IPs = append(host.IPs, addr)
+			}
+		}
+		if obj.Site != nil {
+			host.Location = obj.Site.Name
+			host.Labels["site"] = firstNonEmpty(obj.Site.Slug, obj.Site.Name)
+		}
+		if obj.Rack != nil {
+			host.Rack = obj.Rack.Name
+		}
+		if obj.Tenant != nil {
+			host.Owner = firstNonEmpty(obj.Tenant.Slug, obj.Tenant.Name)
+		}
+		role := obj.Role
+		if role == nil {
+			role = obj.DeviceRole
+		}
+		if role != nil {
+			host.Labels["role"] = firstNonEmpty(role.Slug, role.Name)
+		}
+		if obj.Platform != nil {
+			host.Labels["platform"] = firstNonEmpty(obj.Platform.Slug, obj.Platform.Name)
+		}
+		if obj.Status != nil {
+			host.Labels["status"] = firstNonEmpty(obj.Status.Value, obj.Status.Name)
+		}
+		if obj.Cluster != nil {
+			host.Labels["cluster"] = obj.Cluster.Name
+		}
+		if len(obj.Tags) > 0 {
+			tags := make([]string, 0, len(obj.Tags))
+			for _, tag := range obj.Tags {
+				tags = append(tags, firstNonEmpty(tag.Slug, tag.Name))
+			}
+			sort.Strings(tags)
+			host.Labels["tags"] = strings.Join(tags, ",")
+		}
+		if host.Hostname == "" && len(host.IPs) == 0 {
+			continue
+		}
+		result.hosts = append(result.hosts, host)
+	}
+	return result, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// ==================== Prometheus / Nightingale ====================
+
+type prometheusInventoryFetcher struct{}
+
+// Fetch 读取 Prometheus /api/v1/targets 响应或 file_sd JSON（Nightingale 等可导出该格式）
+func (f *prometheusInventoryFetcher) Fetch(ctx context.Context, src *models.InventorySource) ([]InventorySourceHost, error) {
+	cfg := src.Config
+	if cfg.URL != "" && cfg.Format != "file_sd" && !strings.Contains(cfg.URL, "/api/v1/targets") {
+		// 仅配置了 Prometheus 地址时补全 targets 接口
+		cfg.URL = strings.TrimRight(cfg.URL, "/") + "/api/v1/targets?state=active"
+	}
+	copied := *src
+	copied.Config = cfg
+	body, err := readInventoryContent(ctx, &copied)
+	if err != nil {
+		return nil, err
+	}
+	return parsePrometheusTargets(body)
+}
+
+type prometheusTargetGroup struct {
+	Targets []string          `json:"targets"`
+	Labels  map[string]string `json:"labels"`
+}
+
+type prometheusTargetsResponse struct {
+	Status string `json:"status"`
+	Data   struct {
+		ActiveTargets []struct {
+			Labels map[string]string `json:"labels"`
+			Health string            `json:"health"`
+		} `json:"activeTargets"`
+	} `json:"data"`
+}
+
+// parsePrometheusTargets 解析 targets 列表，同一主机的多个抓取目标合并为一台主机，job 以逗号拼接
+func parsePrometheusTargets(data []byte) ([]InventorySourceHost, error) {
+	var groups []prometheusTargetGroup
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &groups); err != nil {
+			return nil, fmt.Errorf("parse file_sd targets: %v", err)
+		}
+	} else {
+		var resp prometheusTargetsResponse
+		if err := json.Unmarshal(trimmed, &resp); err != nil {
+			return nil, fmt.Errorf("parse prometheus targets: %v", err)
+		}
+		if resp.Status != "" && resp.Status != "success" {
+			return nil, fmt.Errorf("prometheus targets status: %s", resp.Status)
+		}
+		for _, t := range resp.Data.ActiveTargets {
+			labels := make(map[string]string, len(t.Labels)+1)
+			for k, v := range t.Labels {
+				labels[k] = v
+			}
+			if t.Health != "" {
+				labels["health"] = t.Health
+			}
+			groups = append(groups, prometheusTargetGroup{Targets: []string{t.Labels["instance"]}, Labels: labels})
+		}
+	}
+
+	byHost := make(map[string]*InventorySourceHost)
+	jobs := make(map[string][]string)
+	var order []string
+	for _, group := range groups {
+		for _, target := range group.Targets {
+			addr := prometheusTargetHost(target)
+			if addr == "" {
+				continue
+			}
+			host, ok := byHost[addr]
+			if !ok {
+				host = &InventorySourceHost{Key: addr, Labels: map[string]string{}}
+				if ip := net.ParseIP(addr); ip != nil {
+					host.IPs = []string{ip.String()}
+				} else {
+					host.Hostname = addr
+				}
+				byHost[addr] = host
+				order = append(order, addr)
+			}
+			for k, v := range group.Labels {
+				switch {
+				case strings.HasPrefix(k, "__"), k == "instance":
+				case k == "job":
+					if !containsString(jobs[addr], v) {
+						jobs[addr] = append(jobs[addr], v)
+					}
+				case k == "rack":
+					host.Rack = v
+				case k == "location" || k == "idc" || k == "region":
+					host.Location = v
+				case k == "owner" || k == "team":
+					host.Owner = v
+				default:
+					host.Labels[k] = v
+				}
+			}
+		}
+	}
+
+	hosts := make([]InventorySourceHost, 0, len(order))
+	for _, addr := range order {
+		host := byHost[addr]
+		if list := jobs[addr]; len(list) > 0 {
+			sort.Strings(list)
+			host.Labels["job"] = strings.Join(list, ",")
+		}
+		hosts = append(hosts, *host)
+	}
+	return hosts, nil
+}
+
+// prometheusTargetHost 从 instance（host:port 或 URL）中取出主机部分
+func prometheusTargetHost(target string) string {
+	target = strings.TrimSpace(target)
+	if target == "" {
+		return ""
+	}
+	if strings.Contains(target, "://") {
+		if u, err := url.Parse(target); err == nil {
+			return strings.ToLower(u.Hostname())
+		}
+	}
+	if host, _, err := net.SplitHostPort(target); err == nil {
+		return strings.ToLower(host)
+	}
+	return strings.ToLower(strings.Trim(target, "[]"))
+}
+
+// ==================== DHCP 租约 ====================
+
+type dhcpInventoryFetcher struct{}
+
+// Fetch 读取租约文件，仅保留有效租约
+func (f *dhcpInventoryFetcher) Fetch(ctx context.Context, src *models.InventorySource) ([]InventorySourceHost, error) {
+	body, err := readInventoryContent(ctx, src)
+	if err != nil {
+		return nil, err
+	}
+	return parseDHCPLeases(body, src.Config.Format, time.Now())
+}
+
+// parseDHCPLeases 解析 ISC dhcpd.leases 或 dnsmasq 租约，format 为空时自动识别
+// 以 MAC 为主机标识（无 MAC 时使用 IP），同一 MAC 以文件中最后一条有效租约为准
+func parseDHCPLeases(data []byte, format string, now time.Time) ([]InventorySourceHost, error) {
+	if format == "" {
+		format = "dnsmasq"
+		if bytes.Contains(data, []byte("lease ")) && bytes.Contains(data, []byte("{")) {
+			format = "isc"
+		}
+	}
+	var leases []InventorySourceHost
+	switch format {
+	case "isc":
+		leases = parseISCLeases(string(data), now)
+	case "dnsmasq":
+		leases = parseDnsmasqLeases(string(data), now)
+	default:
+		return nil, fmt.Errorf("unsupported dhcp lease format: %s", format)
+	}
+
+	index := make(map[string]int)
+	var hosts []InventorySourceHost
+	for _, lease := range leases {
+		if i, ok := index[lease.Key]; ok {
+			hosts[i] = lease
+			continue
+		}
+		index[lease.Key] = len(hosts)
+		hosts = append(hosts, lease)
+	}
+	return hosts, nil
+}
+
+func newDHCPLeaseHost(ip, mac, hostname string) InventorySourceHost {
+	mac = strings.ToLower(mac)
+	host := InventorySourceHost{Key: mac, Hostname: hostname, IPs: []string{ip}, Labels: map[string]string{}}
+	if mac == "" {
+		host.Key = ip
+	} else {
+		host.Labels["mac"] = mac
+	}
+	return host
+}
+
+func parseISCLeases(content string, now time.Time) []InventorySourceHost {
+	var hosts []InventorySourceHost
+	var (
+		inLease             bool
+		ip, mac, name, bind string
+		expired             bool
+	)
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "#") || line == "" {
+			continue
+		}
+		if !inLease {
+			if strings.HasPrefix(line, "lease ") && strings.HasSuffix(line, "{") {
+				fields := strings.Fields(line)
+				inLease = len(fields) >= 2 && net.ParseIP(fields[1]) != nil
+				if inLease {
+					ip, mac, name, bind, expired = fields[1], "", "", "", false
+				}
+			}
+			continue
+		}
+		if line == "}" {
+			inLease = false
+			if bind == "active" || (bind == "" && !expired) {
+				hosts = append(hosts, newDHCPLeaseHost(ip, mac, name))
+			}
+			continue
+		}
+		line = strings.TrimSuffix(line, ";")
+		fields := strings.Fields(line)
+		switch {
+		case strings.HasPrefix(line, "binding state "):
+			bind = strings.TrimPrefix(line, "binding state ")
+		case strings.HasPrefix(line, "hardware ethernet ") && len(fields) == 3:
+			mac = fields[2]
+		case strings.HasPrefix(line, "client-hostname "):
+			name = strings.Trim(strings.TrimPrefix(line, "client-hostname "), `"`)
+		case strings.HasPrefix(line, "ends ") && len(fields) >= 4:
+			// ends <weekday> YYYY/MM/DD HH:MM:SS（UTC）
+			if ends, err := time.Parse("2006/01/02 15:04:05", fields[2]+" "+fields[3]); err == nil {
+				expired = ends.Before(now)
+			}
+		}
+	}
+	return hosts
+}
+
+// parseDnsmasqLeases 每行：<过期时间戳> <MAC> <IP> <主机名|*> <客户端ID|*>，过期时间 0 表示永久
+func parseDnsmasqLeases(content string, now time.Time) []InventorySourceHost {
+	var hosts []InventorySourceHost
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || net.ParseIP(fields[2]) == nil {
+			continue
+		}
+		expiry, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil || (expiry != 0 && time.Unix(expiry, 0).Before(now)) {
+			continue
+		}
+		name := fields[3]
+		if name == "*" {
+			name = ""
+		}
+		hosts = append(hosts, newDHCPLeaseHost(fields[2], fields[1], name))
+	}
+	return hosts
+}
+
+// ==================== Ansible 动态清单 ====================
+
+type ansibleScriptInventoryFetcher struct{}
+
+// Fetch 执行允许目录内的动态清单脚本（--list），或从 URL 读取其 JSON 输出
+func (f *ansibleScriptInventoryFetcher) Fetch(ctx context.Context, src *models.InventorySource) ([]InventorySourceHost, error) {
+	if src.Config.URL != "" {
+		body, err := fetchInventoryURL(ctx, src, src.Config.URL, "Bearer")
+		if err != nil {
+			return nil, err
+		}
+		return parseAnsibleInventoryJSON(body)
+	}
+
+	script, err := resolveAllowedPath(src.Config.Path, inventoryScriptDirs())
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, inventorySourceTimeout(src))
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, script, "--list")
+	cmd.Dir = filepath.Dir(script)
+	if src.Token != "" {
+		cmd.Env = append(os.Environ(), "INVENTORY_SOURCE_TOKEN="+src.Token)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("run %s --list: %v: %s", script, err, truncateText(strings.TrimSpace(stderr.String()), 500))
+	}
+	if stdout.Len() > maxInventorySourceBody {
+		return nil, fmt.Errorf("inventory script output exceeds %d bytes", maxInventorySourceBody)
+	}
+	return parseAnsibleInventoryJSON(stdout.Bytes())
+}
+
+// parseAnsibleInventoryJSON 解析 ansible-inventory --list 格式
+// 组名写入 groups 标签，hostvars 中的 ansible_host/rack/location/owner 映射为清单字段
+func parseAnsibleInventoryJSON(data []byte) ([]InventorySourceHost, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parse ansible inventory: %v", err)
+	}
+
+	var meta struct {
+		HostVars map[string]map[string]interface{} `json:"hostvars"`
+	}
+	if m, ok := raw["_meta"]; ok {
+		if err := json.Unmarshal(m, &meta); err != nil {
+			return nil, fmt.Errorf("parse _meta: %v", err)
+		}
+	}
+
+	groups := make(map[string][]string)
+	var order []string
+	seen := make(map[string]bool)
+	addHost := func(name, group string) {
+		if !seen[name] {
+			seen[name] = true
+			order = append(order, name)
+		}
+		if group != "" && group != "all" && group != "ungrouped" && !containsString(groups[name], group) {
+			groups[name] = append(groups[name], group)
+		}
+	}
+
+	groupNames := make([]string, 0, len(raw))
+	for name := range raw {
+		if name != "_meta" {
+			groupNames = append(groupNames, name)
+		}
+	}
+	sort.Strings(groupNames)
+	for _, group := range groupNames {
+		var hostList []string
+		if err := json.Unmarshal(raw[group], &hostList); err == nil {
+			for _, h := range hostList {
+				addHost(h, group)
+			}
+			continue
+		}
+		var g struct {
+			Hosts []string `json:"hosts"`
+		}
+		if err := json.Unmarshal(raw[group], &g); err != nil {
+			return nil, fmt.Errorf("parse group %s: %v", group, err)
+		}
+		for _, h := range g.Hosts {
+			addHost(h, group)
+		}
+	}
+	hostvarNames := make([]string, 0, len(meta.HostVars))
+	for name := range meta.HostVars {
+		hostvarNames = append(hostvarNames, name)
+	}
+	sort.Strings(hostvarNames)
+	for _, name := range hostvarNames {
+		addHost(name, "")
+	}
+
+	hosts := make([]InventorySourceHost, 0, len(order))
+	for _, name := range order {
+		host := InventorySourceHost{Key: name, Hostname: name, Labels: map[string]string{}}
+		vars := meta.HostVars[name]
+		if addr, ok := vars["ansible_host"].(string); ok {
+			if ip := net.ParseIP(addr); ip != nil {
+				host.IPs = []string{ip.String()}
+			} else if addr != "" {
+				host.Hostname = addr
+			}
+		}
+		if v, ok := vars["rack"].(string); ok {
+			host.Rack = v
+		}
+		if v, ok := vars["location"].(string); ok {
+			host.Location = v
+		}
+		if v, ok := vars["owner"].(string); ok {
+			host.Owner = v
+		}
+		if list := groups[name]; len(list) > 0 {
+			sort.Strings(list)
+			host.Labels["groups"] = strings.Join(list, ",")
+		}
+		hosts = append(hosts, host)
+	}
+	return hosts, nil
+}
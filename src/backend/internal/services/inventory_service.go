@@ -0,0 +1,915 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/database"
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInventoryHostNotFound 清单中不存在该主机
+var ErrInventoryHostNotFound = errors.New("inventory host not found")
+
+// InventoryService 统一主机清单（CMDB）
+// 每台机器一条 InventoryHost，Ansible 主机、Slurm 节点、Salt Minion、主机模板等按 IP/主机名
+// 去重后通过 InventoryHostLink 关联到同一记录
+type InventoryService struct {
+	db *gorm.DB
+	// mu 串行化合并过程，避免并发导入同一台主机时重复创建
+	mu sync.Mutex
+}
+
+var (
+	inventoryServiceInstance *InventoryService
+	inventoryServiceOnce     sync.Once
+)
+
+// NewInventoryService 创建主机清单服务（单例）
+func NewInventoryService(db *gorm.DB) *InventoryService {
+	inventoryServiceOnce.Do(func() {
+		inventoryServiceInstance = &InventoryService{db: db}
+		if err := db.AutoMigrate(&models.InventoryHost{}, &models.InventoryHostAddress{}, &models.InventoryHostLink{}); err != nil {
+			log.Printf("[Inventory] 自动迁移失败: %v", err)
+		}
+	})
+	return inventoryServiceInstance
+}
+
+// GetInventoryService 获取主机清单服务实例，未初始化时使用全局数据库连接
+func GetInventoryService() *InventoryService {
+	if inventoryServiceInstance == nil && database.DB != nil {
+		return NewInventoryService(database.DB)
+	}
+	return inventoryServiceInstance
+}
+
+// ObserveInventoryAsync 异步记录子系统观察到的主机（子系统创建/发现主机时调用，失败仅记录日志）
+func ObserveInventoryAsync(obs models.InventoryObservation) {
+	svc := GetInventoryService()
+	if svc == nil {
+		return
+	}
+	go func() {
+		if _, _, err := svc.Observe(obs); err != nil {
+			log.Printf("[Inventory] 记录主机 %s%v (%s/%s) 失败: %v", obs.Hostname, obs.IPs, obs.Source, obs.SourceID, err)
+		}
+	}()
+}
+
+// normalizeInventoryObservation 规范化主机名与地址：主机名小写，IP 形式的主机名归入地址列表
+func normalizeInventoryObservation(obs *models.InventoryObservation) {
+	obs.Hostname = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(obs.Hostname), "."))
+	if ip := net.ParseIP(obs.Hostname); ip != nil {
+		obs.IPs = append([]string{ip.String()}, obs.IPs...)
+		obs.Hostname = ""
+	}
+
+	seen := make(map[string]bool)
+	ips := make([]string, 0, len(obs.IPs))
+	for _, raw := range obs.IPs {
+		ip := net.ParseIP(strings.TrimSpace(raw))
+		if ip == nil {
+			continue
+		}
+		addr := ip.String()
+		if !seen[addr] {
+			seen[addr] = true
+			ips = append(ips, addr)
+		}
+	}
+	obs.IPs = ips
+}
+
+// mergeInventoryObservation 将观察结果合并进已有记录：补全空字段、合并地址，已有标签以清单为准
+// obs.Overwrite 为 true 时（已审批的外部来源变更）非空字段与标签覆盖清单中的值
+func mergeInventoryObservation(host *models.InventoryHost, obs models.InventoryObservation) {
+	if host.Hostname == "" || (obs.Overwrite && obs.Hostname != "") {
+		host.Hostname = obs.Hostname
+	}
+	for _, ip := range obs.IPs {
+		if !containsString(host.IPs, ip) {
+			host.IPs = append(host.IPs, ip)
+		}
+	}
+	if host.PrimaryIP == "" && len(host.IPs) > 0 {
+		host.PrimaryIP = host.IPs[0]
+	}
+	if (host.Port == 0 || host.Port == 22) && obs.Port > 0 {
+		host.Port = obs.Port
+	}
+	if host.CredentialRef == "" {
+		host.CredentialRef = obs.CredentialRef
+	}
+	if host.Rack == "" || (obs.Overwrite && obs.Rack != "") {
+		host.Rack = obs.Rack
+	}
+	if host.Location == "" || (obs.Overwrite && obs.Location != "") {
+		host.Location = obs.Location
+	}
+	if host.Owner == "" || (obs.Overwrite && obs.Owner != "") {
+		host.Owner = obs.Owner
+	}
+	for k, v := range obs.Labels {
+		if host.Labels == nil {
+			host.Labels = models.InventoryLabels{}
+		}
+		if _, ok := host.Labels[k]; !ok || obs.Overwrite {
+			host.Labels[k] = v
+		}
+	}
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Observe 合并一条主机观察记录，按 来源关联 > IP > 主机名 的顺序匹配已有主机，返回主机与是否新建
+func (s *InventoryService) Observe(obs models.InventoryObservation) (*models.InventoryHost, bool, error) {
+	normalizeInventoryObservation(&obs)
+	if obs.Hostname == "" && len(obs.IPs) == 0 {
+		return nil, false, fmt.Errorf("主机名和 IP 不能同时为空")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var host *models.InventoryHost
+	created := false
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		host, err = s.matchHost(tx, obs)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		if host == nil {
+			host = &models.InventoryHost{Labels: models.InventoryLabels{}, Port: 22}
+			created = true
+		}
+		mergeInventoryObservation(host, obs)
+		host.LastSeenAt = &now
+		if err := tx.Save(host).Error; err != nil {
+			return fmt.Errorf("保存清单主机失败: %v", err)
+		}
+		if err := s.claimAddresses(tx, host.ID, obs.IPs); err != nil {
+			return err
+		}
+		if obs.Source != "" && obs.SourceID != "" {
+			return s.upsertLink(tx, host.ID, obs, now)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return host, created, nil
+}
+
+// matchHost 查找与观察结果对应的已有主机
+func (s *InventoryService) matchHost(tx *gorm.DB, obs models.InventoryObservation) (*models.InventoryHost, error) {
+	var hostID uint
+	if obs.Source != "" && obs.SourceID != "" {
+		var link models.InventoryHostLink
+		if err := tx.Where("source = ? AND source_id = ?", obs.Source, obs.SourceID).Limit(1).Find(&link).Error; err != nil {
+			return nil, err
+		}
+		hostID = link.HostID
+	}
+	if hostID == 0 && len(obs.IPs) > 0 {
+		var addr models.InventoryHostAddress
+		if err := tx.Where("address IN ?", obs.IPs).Order("host_id").Limit(1).Find(&addr).Error; err != nil {
+			return nil, err
+		}
+		hostID = addr.HostID
+	}
+	if hostID == 0 && obs.Hostname != "" {
+		var byName models.InventoryHost
+		if err := tx.Where("hostname = ?", obs.Hostname).Order("id").Limit(1).Find(&byName).Error; err != nil {
+			return nil, err
+		}
+		hostID = byName.ID
+	}
+	if hostID == 0 {
+		return nil, nil
+	}
+
+	var host models.InventoryHost
+	if err := tx.First(&host, hostID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &host, nil
+}
+
+// claimAddresses 为主机登记地址索引，已属于其他主机的地址保持不变
+func (s *InventoryService) claimAddresses(tx *gorm.DB, hostID uint, ips []string) error {
+	for _, ip := range ips {
+		addr := models.InventoryHostAddress{HostID: hostID, Address: ip}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&addr).Error; err != nil {
+			return fmt.Errorf("登记主机地址 %s 失败: %v", ip, err)
+		}
+	}
+	return nil
+}
+
+func (s *InventoryService) upsertLink(tx *gorm.DB, hostID uint, obs models.InventoryObservation, now time.Time) error {
+	link := models.InventoryHostLink{
+		HostID:     hostID,
+		Source:     obs.Source,
+		SourceID:   obs.SourceID,
+		Scope:      obs.Scope,
+		Name:       obs.Name,
+		Attributes: obs.Attributes,
+		LastSeenAt: now,
+	}
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "source"}, {Name: "source_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"host_id", "scope", "name", "attributes", "last_seen_at", "updated_at"}),
+	}).Create(&link).Error
+}
+
+// List 查询调用者可见的清单主机
+// q 匹配主机名/IP，label 形如 key=value，source 过滤关联来源
+func (s *InventoryService) List(ctx context.Context, viewer InventoryViewer, q, label, owner, rack, source string, page, pageSize int) ([]models.InventoryHost, int64, error) {
+	scope, err := s.resolveScope(ctx, viewer)
+	if err != nil {
+		return nil, 0, err
+	}
+	query := s.db.WithContext(ctx).Model(&models.InventoryHost{})
+	if q = strings.TrimSpace(q); q != "" {
+		like := "%" + strings.ToLower(q) + "%"
+		query = query.Where("hostname LIKE ? OR primary_ip LIKE ? OR id IN (?)", like, like,
+			s.db.Model(&models.InventoryHostAddress{}).Select("host_id").Where("address LIKE ?", like))
+	}
+	if owner != "" {
+		query = query.Where("owner = ?", owner)
+	}
+	if rack != "" {
+		query = query.Where("rack = ?", rack)
+	}
+	if source != "" {
+		query = query.Where("id IN (?)", s.db.Model(&models.InventoryHostLink{}).Select("host_id").Where("source = ?", source))
+	}
+
+	var hosts []models.InventoryHost
+	if key, value, ok := strings.Cut(label, "="); ok || !scope.unrestricted {
+		// 标签为 JSON 字段，不同数据库的 JSON 查询语法不一致；非管理员的可见范围依赖关联记录，均在内存中过滤
+		if err := query.Preload("Links").Order("id").Find(&hosts).Error; err != nil {
+			return nil, 0, err
+		}
+		filtered := hosts[:0]
+		for i := range hosts {
+			if ok && hosts[i].Labels[key] != value {
+				continue
+			}
+			if scope.allows(&hosts[i]) {
+				filtered = append(filtered, hosts[i])
+			}
+		}
+		total := int64(len(filtered))
+		return paginateInventoryHosts(filtered, page, pageSize), total, nil
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 500 {
+		pageSize = 50
+	}
+	if err := query.Order("id").Offset((page - 1) * pageSize).Limit(pageSize).Find(&hosts).Error; err != nil {
+		return nil, 0, err
+	}
+	return hosts, total, nil
+}
+
+func paginateInventoryHosts(hosts []models.InventoryHost, page, pageSize int) []models.InventoryHost {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 500 {
+		pageSize = 50
+	}
+	start := (page - 1) * pageSize
+	if start >= len(hosts) {
+		return []models.InventoryHost{}
+	}
+	end := start + pageSize
+	if end > len(hosts) {
+		end = len(hosts)
+	}
+	return hosts[start:end]
+}
+
+// Get 获取清单主机（含关联）
+func (s *InventoryService) Get(id uint) (*models.InventoryHost, error) {
+	var host models.InventoryHost
+	if err := s.db.Preload("Links").First(&host, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInventoryHostNotFound
+		}
+		return nil, err
+	}
+	return &host, nil
+}
+
+// Lookup 按 ID、IP、主机名或子系统名称（节点名、Minion ID）查找调用者可见的主机，不可见时视为不存在
+func (s *InventoryService) Lookup(ctx context.Context, viewer InventoryViewer, key string) (*models.InventoryHost, error) {
+	host, err := s.lookup(key)
+	if err != nil {
+		return nil, err
+	}
+	scope, err := s.resolveScope(ctx, viewer)
+	if err != nil {
+		return nil, err
+	}
+	if !scope.allows(host) {
+		return nil, ErrInventoryHostNotFound
+	}
+	return host, nil
+}
+
+func (s *InventoryService) lookup(key string) (*models.InventoryHost, error) {
+	key = strings.TrimSpace(key)
+	if id, err := strconv.ParseUint(key, 10, 64); err == nil {
+		return s.Get(uint(id))
+	}
+
+	var hostID uint
+	if ip := net.ParseIP(key); ip != nil {
+		var addr models.InventoryHostAddress
+		if err := s.db.Where("address = ?", ip.String()).Limit(1).Find(&addr).Error; err != nil {
+			return nil, err
+		}
+		hostID = addr.HostID
+	}
+	if hostID == 0 {
+		var host models.InventoryHost
+		if err := s.db.Where("hostname = ?", strings.ToLower(key)).Order("id").Limit(1).Find(&host).Error; err != nil {
+			return nil, err
+		}
+		hostID = host.ID
+	}
+	if hostID == 0 {
+		var link models.InventoryHostLink
+		if err := s.db.Where("name = ?", key).Order("id").Limit(1).Find(&link).Error; err != nil {
+			return nil, err
+		}
+		hostID = link.HostID
+	}
+	if hostID == 0 {
+		return nil, ErrInventoryHostNotFound
+	}
+	return s.Get(hostID)
+}
+
+// Create 手工登记主机，若 IP/主机名已存在则合并到已有记录
+func (s *InventoryService) Create(req *models.InventoryHostRequest, username string) (*models.InventoryHost, bool, error) {
+	host, created, err := s.Observe(models.InventoryObservation{
+		Hostname: req.Hostname,
+		IPs:      req.IPs,
+		Port:     req.Port,
+		Labels:   req.Labels,
+		Rack:     req.Rack,
+		Location: req.Location,
+		Owner:    req.Owner,
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	if req.Description != "" && host.Description == "" {
+		host.Description = req.Description
+		s.db.Model(host).Update("description", req.Description)
+	}
+	s.audit(models.AuditActionCreate, host, username, nil)
+	return host, created, nil
+}
+
+// Update 更新主机的归属信息（标签、机架、位置、负责人等以请求为准）
+func (s *InventoryService) Update(id uint, req *models.InventoryHostRequest, username string) (*models.InventoryHost, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	host, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	before := *host
+
+	obs := models.InventoryObservation{Hostname: req.Hostname, IPs: req.IPs}
+	normalizeInventoryObservation(&obs)
+	if obs.Hostname != "" {
+		host.Hostname = obs.Hostname
+	}
+	if req.Port > 0 {
+		host.Port = req.Port
+	}
+	if req.Labels != nil {
+		host.Labels = models.InventoryLabels(req.Labels)
+	}
+	host.Rack = req.Rack
+	host.Location = req.Location
+	host.Owner = req.Owner
+	host.Description = req.Description
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if len(obs.IPs) > 0 {
+			var conflict models.InventoryHostAddress
+			if err := tx.Where("address IN ? AND host_id <> ?", obs.IPs, id).Limit(1).Find(&conflict).Error; err != nil {
+				return err
+			}
+			if conflict.ID != 0 {
+				return fmt.Errorf("地址 %s 已属于主机 #%d，请先合并主机", conflict.Address, conflict.HostID)
+			}
+			if err := tx.Where("host_id = ?", id).Delete(&models.InventoryHostAddress{}).Error; err != nil {
+				return err
+			}
+			host.IPs = obs.IPs
+			host.PrimaryIP = obs.IPs[0]
+			if err := s.claimAddresses(tx, id, obs.IPs); err != nil {
+				return err
+			}
+		}
+		return tx.Omit("Links").Save(host).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.audit(models.AuditActionUpdate, host, username, &before)
+	return host, nil
+}
+
+// Delete 删除清单主机及其地址索引和关联（不影响各子系统中的记录）
+func (s *InventoryService) Delete(id uint, username string) error {
+	host, err := s.Get(id)
+	if err != nil {
+		return err
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("host_id = ?", id).Delete(&models.InventoryHostAddress{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("host_id = ?", id).Delete(&models.InventoryHostLink{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.InventoryHost{}, id).Error
+	})
+	if err != nil {
+		return err
+	}
+	s.audit(models.AuditActionDelete, host, username, nil)
+	return nil
+}
+
+// RemoveLink 移除某个来源记录与清单主机的关联；主机不再有任何关联时一并删除
+// 返回被删除的主机 ID（未删除主机时为 0）
+func (s *InventoryService) RemoveLink(source, sourceID string) (uint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var removedHost uint
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var link models.InventoryHostLink
+		if err := tx.Where("source = ? AND source_id = ?", source, sourceID).Limit(1).Find(&link).Error; err != nil {
+			return err
+		}
+		if link.ID == 0 {
+			return nil
+		}
+		if err := tx.Delete(&link).Error; err != nil {
+			return err
+		}
+		var remaining int64
+		if err := tx.Model(&models.InventoryHostLink{}).Where("host_id = ?", link.HostID).Count(&remaining).Error; err != nil {
+			return err
+		}
+		if remaining > 0 {
+			return nil
+		}
+		if err := tx.Where("host_id = ?", link.HostID).Delete(&models.InventoryHostAddress{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&models.InventoryHost{}, link.HostID).Error; err != nil {
+			return err
+		}
+		removedHost = link.HostID
+		return nil
+	})
+	return removedHost, err
+}
+
+// Merge 将重复主机 sourceID 合并到 targetID：迁移地址与关联、补全字段后删除 sourceID
+func (s *InventoryService) Merge(targetID, sourceID uint, username string) (*models.InventoryHost, error) {
+	if targetID == sourceID {
+		return nil, fmt.Errorf("不能将主机合并到自身")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	target, err := s.Get(targetID)
+	if err != nil {
+		return nil, err
+	}
+	source, err := s.Get(sourceID)
+	if err != nil {
+		return nil, err
+	}
+
+	mergeInventoryObservation(target, models.InventoryObservation{
+		Hostname:      source.Hostname,
+		IPs:           source.IPs,
+		Port:          source.Port,
+		CredentialRef: source.CredentialRef,
+		Labels:        source.Labels,
+		Rack:          source.Rack,
+		Location:      source.Location,
+		Owner:         source.Owner,
+	})
+	if target.Description == "" {
+		target.Description = source.Description
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.InventoryHostAddress{}).Where("host_id = ?", sourceID).Update("host_id", targetID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.InventoryHostLink{}).Where("host_id = ?", sourceID).Update("host_id", targetID).Error; err != nil {
+			return err
+		}
+		if err := tx.Omit("Links").Save(target).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.InventoryHost{}, sourceID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	GetAuditService().NewAuditEntry(models.AuditCategorySystem, models.AuditActionUpdate).
+		WithUser(0, username, "").
+		WithResource("inventory_host", strconv.FormatUint(uint64(targetID), 10), target.Hostname).
+		WithNotes(fmt.Sprintf("merged inventory host #%d into #%d", sourceID, targetID)).
+		WithStatus(models.AuditStatusSuccess).
+		WithTags("inventory", "merge").
+		SaveAsync()
+	return s.Get(targetID)
+}
+
+// Import 导入主机文件（CSV/JSON/YAML/Ansible INI，经 HostParserService 安全解析），按 IP/主机名去重合并
+func (s *InventoryService) Import(req *models.InventoryImportRequest, userID uint, username string) (*models.InventoryImportResult, error) {
+	hosts, err := NewHostParserService().ValidateAndParse([]byte(req.Content), strings.ToLower(req.Format))
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.InventoryImportResult{Hosts: []models.InventoryHost{}}
+	for _, h := range hosts {
+		labels := make(map[string]string, len(req.Labels)+1)
+		for k, v := range req.Labels {
+			labels[k] = v
+		}
+		if h.Group != "" {
+			labels["group"] = h.Group
+		}
+		obs := models.InventoryObservation{
+			Hostname: h.Host,
+			Port:     h.Port,
+			Labels:   labels,
+			Rack:     req.Rack,
+			Location: req.Location,
+			Owner:    req.Owner,
+			Source:   models.InventorySourceImport,
+			SourceID: h.Host,
+			Name:     h.MinionID,
+		}
+		host, created, err := s.Observe(obs)
+		if err != nil {
+			result.Failed++
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", h.Host, err))
+			continue
+		}
+		if req.StoreCredentials && h.Username != "" && (h.Password != "" || host.CredentialRef == "") {
+			if ref, err := s.storeCredential(host, h, userID); err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("%s: 保存凭据失败: %v", h.Host, err))
+			} else {
+				host.CredentialRef = ref
+			}
+		}
+		if created {
+			result.Created++
+		} else {
+			result.Merged++
+		}
+		result.Hosts = append(result.Hosts, *host)
+	}
+
+	GetAuditService().NewAuditEntry(models.AuditCategorySystem, models.AuditActionImport).
+		WithUser(userID, username, "").
+		WithResource("inventory_host", "", "host import").
+		WithMetadata(map[string]interface{}{"created": result.Created, "merged": result.Merged, "failed": result.Failed}).
+		WithStatus(models.AuditStatusSuccess).
+		WithTags("inventory", "import").
+		SaveAsync()
+	return result, nil
+}
+
+// storeCredential 将导入文件中的 SSH 凭据加密存入 KeyVault，主机记录仅保存引用
+func (s *InventoryService) storeCredential(host *models.InventoryHost, h HostConfig, userID uint) (string, error) {
+	payload, err := json.Marshal(map[string]interface{}{
+		"username": h.Username,
+		"password": h.Password,
+		"port":     h.Port,
+		"use_sudo": h.UseSudo,
+	})
+	if err != nil {
+		return "", err
+	}
+	name := fmt.Sprintf("inventory_host_%d_ssh", host.ID)
+	if _, err := NewKeyVaultService().StoreKey(name, KeyTypeHostCredential, string(payload),
+		fmt.Sprintf("SSH credential for inventory host %s", inventoryHostDisplayName(host)), nil, userID, nil); err != nil {
+		return "", err
+	}
+	ref := "keyvault:" + name
+	if err := s.db.Model(host).Update("credential_ref", ref).Error; err != nil {
+		return "", err
+	}
+	return ref, nil
+}
+
+func inventoryHostDisplayName(host *models.InventoryHost) string {
+	if host.Hostname != "" {
+		return host.Hostname
+	}
+	return host.PrimaryIP
+}
+
+// Overview 汇总调用者可见的某台主机在各子系统中的全部信息（key 同 Lookup）
+func (s *InventoryService) Overview(ctx context.Context, viewer InventoryViewer, key string) (*models.InventoryHostOverview, error) {
+	host, err := s.Lookup(ctx, viewer, key)
+	if err != nil {
+		return nil, err
+	}
+
+	// 用于跨子系统匹配的主机标识：主机名、IP 以及关联记录中的名称
+	keys := make([]string, 0, len(host.IPs)+len(host.Links)+1)
+	if host.Hostname != "" {
+		keys = append(keys, host.Hostname)
+	}
+	keys = append(keys, host.IPs...)
+	for _, link := range host.Links {
+		if link.Name != "" && !containsString(keys, link.Name) {
+			keys = append(keys, link.Name)
+		}
+	}
+
+	overview := &models.InventoryHostOverview{
+		Host:           *host,
+		AnsibleHosts:   []models.Host{},
+		SlurmNodes:     []models.SlurmNode{},
+		SaltMinions:    []models.SaltMasterMinion{},
+		InstallResults: []models.InstallationHostResult{},
+		HostKeys:       []models.SSHKnownHost{},
+	}
+	if len(keys) == 0 {
+		return overview, nil
+	}
+
+	db := s.db.WithContext(ctx)
+	if err := db.Where("ip IN ? OR name IN ?", keys, keys).Find(&overview.AnsibleHosts).Error; err != nil {
+		log.Printf("[Inventory] 查询 Ansible 主机失败: %v", err)
+	}
+	slurmDB := database.SlurmDB
+	if slurmDB == nil {
+		slurmDB = s.db
+	}
+	if err := slurmDB.WithContext(ctx).Where("host IN ? OR node_name IN ?", keys, keys).Find(&overview.SlurmNodes).Error; err != nil {
+		log.Printf("[Inventory] 查询 Slurm 节点失败: %v", err)
+	}
+	if err := db.Where("minion_id IN ?", keys).Find(&overview.SaltMinions).Error; err != nil {
+		log.Printf("[Inventory] 查询 Salt Minion 失败: %v", err)
+	}
+	if err := db.Omit("output", "steps_json").Where("host IN ?", keys).Order("updated_at DESC").Limit(20).Find(&overview.InstallResults).Error; err != nil {
+		log.Printf("[Inventory] 查询安装记录失败: %v", err)
+	}
+	if err := db.Where("host IN ?", keys).Find(&overview.HostKeys).Error; err != nil {
+		log.Printf("[Inventory] 查询主机密钥失败: %v", err)
+	}
+	return overview, nil
+}
+
+// Sync 从各子系统回填清单，并移除来源记录已不存在的关联
+func (s *InventoryService) Sync(ctx context.Context, username string) (*models.InventorySyncResult, error) {
+	result := &models.InventorySyncResult{Sources: map[string]int{}}
+	started := time.Now()
+
+	observe := func(obs models.InventoryObservation) {
+		_, created, err := s.Observe(obs)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s/%s: %v", obs.Source, obs.SourceID, err))
+			return
+		}
+		result.Sources[obs.Source]++
+		if created {
+			result.Created++
+		} else {
+			result.Merged++
+		}
+	}
+
+	synced := make([]string, 0, 4)
+	for _, source := range []struct {
+		name    string
+		collect func(context.Context) ([]models.InventoryObservation, error)
+	}{
+		{models.InventorySourceAnsible, s.collectAnsibleHosts},
+		{models.InventorySourceSlurm, s.collectSlurmNodes},
+		{models.InventorySourceSalt, s.collectSaltMinions},
+		{models.InventorySourceHostTemplate, s.collectHostTemplates},
+	} {
+		observations, err := source.collect(ctx)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", source.name, err))
+			continue
+		}
+		for _, obs := range observations {
+			observe(obs)
+		}
+		synced = append(synced, source.name)
+	}
+
+	// 仅清理本次成功同步的来源，避免某个子系统不可用时误删关联
+	if len(synced) > 0 {
+		pruned := s.db.WithContext(ctx).Where("source IN ? AND last_seen_at < ?", synced, started).Delete(&models.InventoryHostLink{})
+		if pruned.Error != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("prune: %v", pruned.Error))
+		}
+		result.Pruned = int(pruned.RowsAffected)
+	}
+
+	GetAuditService().NewAuditEntry(models.AuditCategorySystem, models.AuditActionSync).
+		WithUser(0, username, "").
+		WithResource("inventory_host", "", "inventory sync").
+		WithMetadata(result).
+		WithStatus(models.AuditStatusSuccess).
+		WithTags("inventory", "sync").
+		SaveAsync()
+	return result, nil
+}
+
+func (s *InventoryService) collectAnsibleHosts(ctx context.Context) ([]models.InventoryObservation, error) {
+	var hosts []models.Host
+	if err := s.db.WithContext(ctx).Find(&hosts).Error; err != nil {
+		return nil, err
+	}
+	observations := make([]models.InventoryObservation, 0, len(hosts))
+	for _, h := range hosts {
+		observations = append(observations, AnsibleHostObservation(&h))
+	}
+	return observations, nil
+}
+
+func (s *InventoryService) collectSlurmNodes(ctx context.Context) ([]models.InventoryObservation, error) {
+	slurmDB := database.SlurmDB
+	if slurmDB == nil {
+		slurmDB = s.db
+	}
+	var nodes []models.SlurmNode
+	if err := slurmDB.WithContext(ctx).Find(&nodes).Error; err != nil {
+		return nil, err
+	}
+	observations := make([]models.InventoryObservation, 0, len(nodes))
+	for i := range nodes {
+		observations = append(observations, SlurmNodeObservation(&nodes[i]))
+	}
+	return observations, nil
+}
+
+func (s *InventoryService) collectSaltMinions(ctx context.Context) ([]models.InventoryObservation, error) {
+	var minions []models.SaltMasterMinion
+	if err := s.db.WithContext(ctx).Where("key_status = ?", "accepted").Find(&minions).Error; err != nil {
+		return nil, err
+	}
+	observations := make([]models.InventoryObservation, 0, len(minions))
+	for _, m := range minions {
+		observations = append(observations, models.InventoryObservation{
+			Hostname: m.MinionID,
+			Source:   models.InventorySourceSalt,
+			SourceID: m.MinionID,
+			Scope:    m.MasterID,
+			Name:     m.MinionID,
+		})
+	}
+	return observations, nil
+}
+
+func (s *InventoryService) collectHostTemplates(ctx context.Context) ([]models.InventoryObservation, error) {
+	var templates []models.HostTemplate
+	if err := s.db.WithContext(ctx).Find(&templates).Error; err != nil {
+		return nil, err
+	}
+	var observations []models.InventoryObservation
+	for i := range templates {
+		hosts, err := templates[i].GetHosts()
+		if err != nil {
+			log.Printf("[Inventory] 解密主机模板 #%d 失败: %v", templates[i].ID, err)
+			continue
+		}
+		observations = append(observations, HostTemplateObservations(&templates[i], hosts)...)
+	}
+	return observations, nil
+}
+
+// AnsibleHostObservation Ansible 项目主机对应的清单观察记录
+func AnsibleHostObservation(h *models.Host) models.InventoryObservation {
+	obs := models.InventoryObservation{
+		Hostname: h.Name,
+		IPs:      []string{h.IP},
+		Port:     h.Port,
+		Source:   models.InventorySourceAnsible,
+		SourceID: strconv.FormatUint(uint64(h.ID), 10),
+		Scope:    fmt.Sprintf("project-%d", h.ProjectID),
+		Name:     h.Name,
+	}
+	if h.Group != "" {
+		obs.Attributes = map[string]string{"group": h.Group}
+	}
+	return obs
+}
+
+// SlurmNodeObservation Slurm 节点对应的清单观察记录（凭据仍由节点记录保存）
+func SlurmNodeObservation(node *models.SlurmNode) models.InventoryObservation {
+	obs := models.InventoryObservation{
+		Hostname:      node.NodeName,
+		IPs:           []string{node.Host},
+		Port:          node.Port,
+		CredentialRef: fmt.Sprintf("slurm_node:%d", node.ID),
+		Source:        models.InventorySourceSlurm,
+		SourceID:      strconv.FormatUint(uint64(node.ID), 10),
+		Scope:         SSHCAClusterScope(node.ClusterID),
+		Name:          node.NodeName,
+		Attributes:    map[string]string{"node_type": node.NodeType, "status": node.Status},
+	}
+	if net.ParseIP(node.Host) == nil {
+		// Host 为主机名（如 FQDN）时不作为地址，保留在关联属性中
+		obs.IPs = nil
+		obs.Attributes["host"] = node.Host
+	}
+	if node.SaltMinionID != "" {
+		obs.Attributes["salt_minion_id"] = node.SaltMinionID
+	}
+	return obs
+}
+
+// HostTemplateObservations 主机模板中各主机对应的清单观察记录（凭据仍由模板加密保存）
+func HostTemplateObservations(t *models.HostTemplate, hosts []models.HostTemplateHost) []models.InventoryObservation {
+	observations := make([]models.InventoryObservation, 0, len(hosts))
+	for _, h := range hosts {
+		obs := models.InventoryObservation{
+			Hostname:      h.Host,
+			Port:          h.Port,
+			CredentialRef: fmt.Sprintf("host_template:%d", t.ID),
+			Source:        models.InventorySourceHostTemplate,
+			SourceID:      fmt.Sprintf("%d:%s", t.ID, h.Host),
+			Scope:         t.Name,
+			Name:          h.MinionID,
+		}
+		if h.Group != "" {
+			obs.Labels = map[string]string{"group": h.Group}
+		}
+		observations = append(observations, obs)
+	}
+	return observations
+}
+
+func (s *InventoryService) audit(action models.AuditAction, host *models.InventoryHost, username string, before *models.InventoryHost) {
+	entry := GetAuditService().NewAuditEntry(models.AuditCategorySystem, action).
+		WithUser(0, username, "").
+		WithResource("inventory_host", strconv.FormatUint(uint64(host.ID), 10), inventoryHostDisplayName(host)).
+		WithStatus(models.AuditStatusSuccess).
+		WithTags("inventory")
+	if before != nil {
+		entry = entry.WithChange(before, host, "inventory host updated")
+	}
+	entry.SaveAsync()
+}
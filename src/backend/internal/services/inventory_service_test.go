@@ -0,0 +1,76 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
+)
+
+func TestNormalizeInventoryObservation(t *testing.T) {
+	obs := models.InventoryObservation{
+		Hostname: "10.0.0.5",
+		IPs:      []string{" 10.0.0.5", "10.0.0.6", "not-an-ip", "10.0.0.6"},
+	}
+	normalizeInventoryObservation(&obs)
+	if obs.Hostname != "" {
+		t.Fatalf("IP 形式的主机名应归入地址列表, hostname=%q", obs.Hostname)
+	}
+	if !reflect.DeepEqual(obs.IPs, []string{"10.0.0.5", "10.0.0.6"}) {
+		t.Fatalf("地址应去重并过滤非法值: %v", obs.IPs)
+	}
+
+	obs = models.InventoryObservation{Hostname: " GPU-Node01.Example.COM. "}
+	normalizeInventoryObservation(&obs)
+	if obs.Hostname != "gpu-node01.example.com" {
+		t.Fatalf("主机名应小写并去掉末尾的点: %q", obs.Hostname)
+	}
+}
+
+func TestMergeInventoryObservation(t *testing.T) {
+	host := &models.InventoryHost{
+		Hostname:  "node01",
+		IPs:       models.StringArray{"10.0.0.5"},
+		PrimaryIP: "10.0.0.5",
+		Port:      22,
+		Owner:     "ops",
+		Labels:    models.InventoryLabels{"env": "prod"},
+	}
+	mergeInventoryObservation(host, models.InventoryObservation{
+		Hostname:      "other-name",
+		IPs:           []string{"10.0.0.5", "192.168.1.5"},
+		Port:          2222,
+		CredentialRef: "slurm_node:3",
+		Owner:         "someone-else",
+		Rack:          "A01",
+		Labels:        map[string]string{"env": "dev", "gpu": "a100"},
+	})
+
+	if host.Hostname != "node01" || host.Owner != "ops" {
+		t.Fatalf("已有字段不应被导入数据覆盖: %+v", host)
+	}
+	if !reflect.DeepEqual([]string(host.IPs), []string{"10.0.0.5", "192.168.1.5"}) || host.PrimaryIP != "10.0.0.5" {
+		t.Fatalf("地址应合并且主地址不变: %v %s", host.IPs, host.PrimaryIP)
+	}
+	if host.Port != 2222 || host.Rack != "A01" || host.CredentialRef != "slurm_node:3" {
+		t.Fatalf("空字段应被补全: %+v", host)
+	}
+	if host.Labels["env"] != "prod" || host.Labels["gpu"] != "a100" {
+		t.Fatalf("标签合并不正确: %v", host.Labels)
+	}
+}
+
+func TestSlurmNodeObservation(t *testing.T) {
+	obs := SlurmNodeObservation(&models.SlurmNode{ID: 7, ClusterID: 2, NodeName: "cn01", Host: "cn01.hpc.local", Port: 22, SaltMinionID: "hpc-cn01"})
+	if len(obs.IPs) != 0 || obs.Attributes["host"] != "cn01.hpc.local" {
+		t.Fatalf("主机名形式的 Host 不应作为地址: %+v", obs)
+	}
+	if obs.SourceID != "7" || obs.Scope != "cluster-2" || obs.CredentialRef != "slurm_node:7" || obs.Attributes["salt_minion_id"] != "hpc-cn01" {
+		t.Fatalf("关联信息不正确: %+v", obs)
+	}
+
+	obs = SlurmNodeObservation(&models.SlurmNode{ID: 8, NodeName: "cn02", Host: "10.1.0.2"})
+	if !reflect.DeepEqual(obs.IPs, []string{"10.1.0.2"}) {
+		t.Fatalf("IP 形式的 Host 应作为地址: %+v", obs)
+	}
+}
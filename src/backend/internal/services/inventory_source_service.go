@@ -0,0 +1,627 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/database"
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// ErrInventoryChangesetNotPending 变更集已被处理
+var ErrInventoryChangesetNotPending = errors.New("inventory changeset is not pending")
+
+// InventorySourceService 外部清单来源同步服务
+// 定期从 NetBox、Prometheus、DHCP 租约、Ansible 动态清单拉取主机，与上次批准的结果比较后生成
+// 增/改/删变更集，管理员批准后才通过 InventoryService 写入统一清单
+type InventorySourceService struct {
+	db        *gorm.DB
+	mu        sync.Mutex
+	active    map[uint]bool // source ID -> 是否有同步在运行
+	startOnce sync.Once
+	stopOnce  sync.Once
+	stopCh    chan struct{}
+}
+
+var (
+	inventorySourceServiceInstance *InventorySourceService
+	inventorySourceServiceOnce     sync.Once
+)
+
+// NewInventorySourceService 创建外部清单来源服务（单例）
+func NewInventorySourceService(db *gorm.DB) *InventorySourceService {
+	inventorySourceServiceOnce.Do(func() {
+		inventorySourceServiceInstance = &InventorySourceService{
+			db:     db,
+			active: make(map[uint]bool),
+			stopCh: make(chan struct{}),
+		}
+		if err := db.AutoMigrate(&models.InventorySource{}, &models.InventoryChangeset{}); err != nil {
+			log.Printf("[InventorySource] 自动迁移失败: %v", err)
+		}
+	})
+	return inventorySourceServiceInstance
+}
+
+// GetInventorySourceService 获取外部清单来源服务实例，未初始化时使用全局数据库连接
+func GetInventorySourceService() *InventorySourceService {
+	if inventorySourceServiceInstance == nil && database.DB != nil {
+		return NewInventorySourceService(database.DB)
+	}
+	return inventorySourceServiceInstance
+}
+
+// ==================== 来源管理 ====================
+
+func (s *InventorySourceService) buildSource(src *models.InventorySource, req *models.InventorySourceRequest) error {
+	src.Name = strings.TrimSpace(req.Name)
+	src.Type = req.Type
+	src.Description = req.Description
+	src.Config = req.Config
+	src.Labels = req.Labels
+	if req.Token != "" {
+		src.Token = req.Token
+	}
+	if req.IntervalMinutes != nil {
+		if *req.IntervalMinutes < 0 {
+			return fmt.Errorf("interval_minutes must not be negative")
+		}
+		src.IntervalMinutes = *req.IntervalMinutes
+	}
+	if req.Enabled != nil {
+		src.Enabled = *req.Enabled
+	}
+	if src.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if err := ValidateInventorySource(src); err != nil {
+		return err
+	}
+	src.NextSyncAt = nil
+	if src.Enabled && src.IntervalMinutes > 0 {
+		next := time.Now().Add(time.Duration(src.IntervalMinutes) * time.Minute)
+		if src.LastSyncAt == nil {
+			next = time.Now()
+		}
+		src.NextSyncAt = &next
+	}
+	return nil
+}
+
+// ListSources 获取所有外部来源
+func (s *InventorySourceService) ListSources() ([]models.InventorySource, error) {
+	var sources []models.InventorySource
+	err := s.db.Order("name ASC").Find(&sources).Error
+	return sources, err
+}
+
+// GetSource 获取外部来源
+func (s *InventorySourceService) GetSource(id uint) (*models.InventorySource, error) {
+	var src models.InventorySource
+	if err := s.db.First(&src, id).Error; err != nil {
+		return nil, err
+	}
+	return &src, nil
+}
+
+// CreateSource 创建外部来源
+func (s *InventorySourceService) CreateSource(req *models.InventorySourceRequest, username string) (*models.InventorySource, error) {
+	src := &models.InventorySource{Enabled: true, IntervalMinutes: 60, CreatedBy: username}
+	if err := s.buildSource(src, req); err != nil {
+		return nil, err
+	}
+	if err := s.db.Create(src).Error; err != nil {
+		return nil, err
+	}
+	s.audit(models.AuditActionCreate, src, username, fmt.Sprintf("inventory source %s (%s) created", src.Name, src.Type))
+	return s.GetSource(src.ID)
+}
+
+// UpdateSource 更新外部来源（令牌为空时保持不变）
+func (s *InventorySourceService) UpdateSource(id uint, req *models.InventorySourceRequest, username string) (*models.InventorySource, error) {
+	src, err := s.GetSource(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.buildSource(src, req); err != nil {
+		return nil, err
+	}
+	if err := s.db.Save(src).Error; err != nil {
+		return nil, err
+	}
+	s.audit(models.AuditActionUpdate, src, username, fmt.Sprintf("inventory source %s updated", src.Name))
+	return s.GetSource(id)
+}
+
+// DeleteSource 删除外部来源，未处理的变更集一并作废（已写入清单的主机保留）
+func (s *InventorySourceService) DeleteSource(id uint, username string) error {
+	src, err := s.GetSource(id)
+	if err != nil {
+		return err
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.InventoryChangeset{}).
+			Where("source_id = ? AND status = ?", id, models.InventoryChangesetPending).
+			Update("status", models.InventoryChangesetSuperseded).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.InventorySource{}, id).Error
+	})
+	if err != nil {
+		return err
+	}
+	s.audit(models.AuditActionDelete, src, username, fmt.Sprintf("inventory source %s deleted", src.Name))
+	return nil
+}
+
+// Preview 拉取来源当前的主机（不生成变更集），用于配置时校验映射结果
+func (s *InventorySourceService) Preview(ctx context.Context, id uint) ([]InventorySourceHost, error) {
+	src, err := s.GetSource(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.fetch(ctx, src)
+}
+
+func (s *InventorySourceService) fetch(ctx context.Context, src *models.InventorySource) ([]InventorySourceHost, error) {
+	fetcher, err := NewInventoryFetcher(src.Type)
+	if err != nil {
+		return nil, err
+	}
+	hosts, err := fetcher.Fetch(ctx, src)
+	if err != nil {
+		return nil, err
+	}
+	return applyInventorySourceLabels(src, hosts), nil
+}
+
+// applyInventorySourceLabels 合并来源级标签（单台主机的标签优先），并按 Key 去重
+func applyInventorySourceLabels(src *models.InventorySource, hosts []InventorySourceHost) []InventorySourceHost {
+	seen := make(map[string]bool, len(hosts))
+	result := make([]InventorySourceHost, 0, len(hosts))
+	for _, host := range hosts {
+		if host.Key == "" || seen[host.Key] {
+			continue
+		}
+		seen[host.Key] = true
+		labels := make(map[string]string, len(src.Labels)+len(host.Labels)+1)
+		for k, v := range src.Labels {
+			labels[k] = v
+		}
+		for k, v := range host.Labels {
+			labels[k] = v
+		}
+		labels["inventory_source"] = src.Name
+		host.Labels = labels
+		result = append(result, host)
+	}
+	return result
+}
+
+// ==================== 同步与变更集 ====================
+
+// inventorySourceLinkID 外部来源主机在 InventoryHostLink 中的标识
+func inventorySourceLinkID(sourceID uint, key string) string {
+	return fmt.Sprintf("%d:%s", sourceID, key)
+}
+
+// SyncSource 拉取来源并生成待审批变更集；没有变化时返回 nil
+func (s *InventorySourceService) SyncSource(ctx context.Context, id uint, trigger string) (*models.InventoryChangeset, error) {
+	src, err := s.GetSource(id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	if s.active[src.ID] {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("inventory source %s is already syncing", src.Name)
+	}
+	s.active[src.ID] = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.active, src.ID)
+		s.mu.Unlock()
+	}()
+
+	changeset, hostCount, err := s.buildChangeset(ctx, src, trigger)
+	now := time.Now()
+	updates := map[string]interface{}{"last_sync_at": now, "host_count": hostCount, "last_error": ""}
+	switch {
+	case err != nil:
+		updates["last_status"] = "failed"
+		updates["last_error"] = err.Error()
+	case changeset == nil:
+		updates["last_status"] = "no_changes"
+	default:
+		updates["last_status"] = "success"
+	}
+	if dbErr := s.db.Model(&models.InventorySource{}).Where("id = ?", src.ID).Updates(updates).Error; dbErr != nil {
+		log.Printf("[InventorySource] 更新来源 %s 同步状态失败: %v", src.Name, dbErr)
+	}
+	if err != nil {
+		log.Printf("[InventorySource] 同步来源 %s 失败: %v", src.Name, err)
+		return nil, err
+	}
+	return changeset, nil
+}
+
+func (s *InventorySourceService) buildChangeset(ctx context.Context, src *models.InventorySource, trigger string) (*models.InventoryChangeset, int, error) {
+	desired, err := s.fetch(ctx, src)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	prefix := inventorySourceLinkID(src.ID, "")
+	var links []models.InventoryHostLink
+	if err := s.db.WithContext(ctx).Where("source = ? AND source_id LIKE ?", models.InventorySourceExternal, prefix+"%").Find(&links).Error; err != nil {
+		return nil, len(desired), err
+	}
+	hostIDs := make([]uint, 0, len(links))
+	for _, link := range links {
+		hostIDs = append(hostIDs, link.HostID)
+	}
+	hostsByID := make(map[uint]models.InventoryHost, len(hostIDs))
+	if len(hostIDs) > 0 {
+		var hosts []models.InventoryHost
+		if err := s.db.WithContext(ctx).Where("id IN ?", hostIDs).Find(&hosts).Error; err != nil {
+			return nil, len(desired), err
+		}
+		for _, h := range hosts {
+			hostsByID[h.ID] = h
+		}
+	}
+	existing := make(map[string]models.InventoryHost, len(links))
+	for _, link := range links {
+		if !strings.HasPrefix(link.SourceID, prefix) {
+			continue
+		}
+		if host, ok := hostsByID[link.HostID]; ok {
+			existing[strings.TrimPrefix(link.SourceID, prefix)] = host
+		}
+	}
+
+	// 来源异常返回空列表时不生成"全部删除"的变更集
+	if len(desired) == 0 && len(existing) > 0 {
+		return nil, 0, fmt.Errorf("source returned no hosts while %d are linked; refusing to propose removing all of them", len(existing))
+	}
+
+	changes := computeInventoryChanges(desired, existing)
+	if len(changes) == 0 {
+		return nil, len(desired), nil
+	}
+	// 新增主机按 IP/主机名预先匹配已有清单记录，便于审批时判断是合并还是新建
+	inventory := GetInventoryService()
+	for i := range changes {
+		if changes[i].Action != models.InventoryChangeAdd || inventory == nil {
+			continue
+		}
+		obs := models.InventoryObservation{Hostname: changes[i].Hostname, IPs: changes[i].IPs}
+		normalizeInventoryObservation(&obs)
+		if host, err := inventory.matchHost(s.db.WithContext(ctx), obs); err == nil && host != nil {
+			changes[i].HostID = host.ID
+		}
+	}
+
+	changeset := &models.InventoryChangeset{
+		SourceID:   src.ID,
+		SourceName: src.Name,
+		Status:     models.InventoryChangesetPending,
+		Trigger:    trigger,
+		Changes:    changes,
+	}
+	for _, c := range changes {
+		switch c.Action {
+		case models.InventoryChangeAdd:
+			changeset.Adds++
+		case models.InventoryChangeUpdate:
+			changeset.Updates++
+		case models.InventoryChangeRemove:
+			changeset.Removes++
+		}
+	}
+
+	// 同一来源只保留最新的待审批变更集
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.InventoryChangeset{}).
+			Where("source_id = ? AND status = ?", src.ID, models.InventoryChangesetPending).
+			Update("status", models.InventoryChangesetSuperseded).Error; err != nil {
+			return err
+		}
+		return tx.Create(changeset).Error
+	})
+	if err != nil {
+		return nil, len(desired), err
+	}
+	log.Printf("[InventorySource] 来源 %s 生成变更集 #%d: +%d ~%d -%d",
+		src.Name, changeset.ID, changeset.Adds, changeset.Updates, changeset.Removes)
+	return changeset, len(desired), nil
+}
+
+// computeInventoryChanges 比较来源当前主机与已关联的清单主机（按来源 Key）
+// 来源新增的主机为 add，字段/标签/地址有差异的为 update，来源中已消失的为 remove
+func computeInventoryChanges(desired []InventorySourceHost, existing map[string]models.InventoryHost) []models.InventoryChange {
+	var changes []models.InventoryChange
+	seen := make(map[string]bool, len(desired))
+	for _, want := range desired {
+		seen[want.Key] = true
+		obs := models.InventoryObservation{Hostname: want.Hostname, IPs: want.IPs}
+		normalizeInventoryObservation(&obs)
+		change := models.InventoryChange{
+			Key:      want.Key,
+			Hostname: obs.Hostname,
+			IPs:      obs.IPs,
+			Labels:   want.Labels,
+			Rack:     want.Rack,
+			Location: want.Location,
+			Owner:    want.Owner,
+		}
+
+		host, ok := existing[want.Key]
+		if !ok {
+			if obs.Hostname == "" && len(obs.IPs) == 0 {
+				continue
+			}
+			change.Action = models.InventoryChangeAdd
+			changes = append(changes, change)
+			continue
+		}
+
+		change.HostID = host.ID
+		diff := func(field, old, new string) {
+			if new != "" && old != new {
+				change.Diff = append(change.Diff, models.InventoryFieldChange{Field: field, Old: old, New: new})
+			}
+		}
+		diff("hostname", host.Hostname, obs.Hostname)
+		diff("rack", host.Rack, want.Rack)
+		diff("location", host.Location, want.Location)
+		diff("owner", host.Owner, want.Owner)
+		for _, ip := range obs.IPs {
+			if !containsString(host.IPs, ip) {
+				diff("ips", strings.Join(host.IPs, ","), strings.Join(obs.IPs, ","))
+				break
+			}
+		}
+		keys := make([]string, 0, len(want.Labels))
+		for k := range want.Labels {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			diff("labels."+k, host.Labels[k], want.Labels[k])
+		}
+		if len(change.Diff) > 0 {
+			change.Action = models.InventoryChangeUpdate
+			changes = append(changes, change)
+		}
+	}
+
+	removed := make([]string, 0)
+	for key := range existing {
+		if !seen[key] {
+			removed = append(removed, key)
+		}
+	}
+	sort.Strings(removed)
+	for _, key := range removed {
+		host := existing[key]
+		changes = append(changes, models.InventoryChange{
+			Action:   models.InventoryChangeRemove,
+			Key:      key,
+			HostID:   host.ID,
+			Hostname: host.Hostname,
+			IPs:      host.IPs,
+		})
+	}
+	return changes
+}
+
+// ApproveChangeset 批准并应用变更集：add/update 以来源数据覆盖清单字段，remove 移除来源关联
+// （主机不再被任何子系统引用时一并删除）
+func (s *InventorySourceService) ApproveChangeset(id uint, username, comment string) (*models.InventoryChangeset, error) {
+	changeset, err := s.claimChangeset(id, models.InventoryChangesetApplied, username, comment)
+	if err != nil {
+		return nil, err
+	}
+	inventory := GetInventoryService()
+	if inventory == nil {
+		return nil, fmt.Errorf("inventory service is not initialized")
+	}
+
+	var errs []string
+	for _, change := range changeset.Changes {
+		linkID := inventorySourceLinkID(changeset.SourceID, change.Key)
+		switch change.Action {
+		case models.InventoryChangeAdd, models.InventoryChangeUpdate:
+			_, _, err := inventory.Observe(models.InventoryObservation{
+				Hostname:   change.Hostname,
+				IPs:        change.IPs,
+				Labels:     change.Labels,
+				Rack:       change.Rack,
+				Location:   change.Location,
+				Owner:      change.Owner,
+				Overwrite:  true,
+				Source:     models.InventorySourceExternal,
+				SourceID:   linkID,
+				Scope:      changeset.SourceName,
+				Name:       change.Key,
+				Attributes: map[string]string{"changeset": strconv.FormatUint(uint64(changeset.ID), 10)},
+			})
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("%s %s: %v", change.Action, change.Key, err))
+			}
+		case models.InventoryChangeRemove:
+			if _, err := inventory.RemoveLink(models.InventorySourceExternal, linkID); err != nil {
+				errs = append(errs, fmt.Sprintf("remove %s: %v", change.Key, err))
+			}
+		}
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{"applied_at": now}
+	status := models.AuditStatusSuccess
+	if len(errs) > 0 {
+		updates["status"] = models.InventoryChangesetFailed
+		updates["error"] = strings.Join(errs, "\n")
+		status = models.AuditStatusFailed
+	}
+	if err := s.db.Model(&models.InventoryChangeset{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+		log.Printf("[InventorySource] 更新变更集 #%d 状态失败: %v", id, err)
+	}
+
+	GetAuditService().NewAuditEntry(models.AuditCategorySystem, models.AuditActionApprove).
+		WithUser(0, username, "").
+		WithResource("inventory_changeset", strconv.FormatUint(uint64(id), 10), changeset.SourceName).
+		WithMetadata(map[string]interface{}{"adds": changeset.Adds, "updates": changeset.Updates, "removes": changeset.Removes, "errors": errs}).
+		WithNotes(comment).
+		WithStatus(status).
+		WithTags("inventory", "inventory_source").
+		SaveAsync()
+	return s.GetChangeset(id)
+}
+
+// RejectChangeset 拒绝变更集，清单保持不变
+func (s *InventorySourceService) RejectChangeset(id uint, username, comment string) (*models.InventoryChangeset, error) {
+	changeset, err := s.claimChangeset(id, models.InventoryChangesetRejected, username, comment)
+	if err != nil {
+		return nil, err
+	}
+	GetAuditService().NewAuditEntry(models.AuditCategorySystem, models.AuditActionReject).
+		WithUser(0, username, "").
+		WithResource("inventory_changeset", strconv.FormatUint(uint64(id), 10), changeset.SourceName).
+		WithNotes(comment).
+		WithStatus(models.AuditStatusSuccess).
+		WithTags("inventory", "inventory_source").
+		SaveAsync()
+	return s.GetChangeset(id)
+}
+
+// claimChangeset 以状态条件更新抢占待审批变更集，避免重复审批
+func (s *InventorySourceService) claimChangeset(id uint, status, username, comment string) (*models.InventoryChangeset, error) {
+	now := time.Now()
+	res := s.db.Model(&models.InventoryChangeset{}).
+		Where("id = ? AND status = ?", id, models.InventoryChangesetPending).
+		Updates(map[string]interface{}{"status": status, "reviewed_by": username, "reviewed_at": now, "review_comment": comment})
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		if _, err := s.GetChangeset(id); err != nil {
+			return nil, err
+		}
+		return nil, ErrInventoryChangesetNotPending
+	}
+	return s.GetChangeset(id)
+}
+
+// GetChangeset 获取变更集
+func (s *InventorySourceService) GetChangeset(id uint) (*models.InventoryChangeset, error) {
+	var changeset models.InventoryChangeset
+	if err := s.db.First(&changeset, id).Error; err != nil {
+		return nil, err
+	}
+	return &changeset, nil
+}
+
+// ListChangesets 分页查询变更集（列表不含变更明细）
+func (s *InventorySourceService) ListChangesets(sourceID uint, status string, page, pageSize int) ([]models.InventoryChangeset, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	query := s.db.Model(&models.InventoryChangeset{})
+	if sourceID > 0 {
+		query = query.Where("source_id = ?", sourceID)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var total int64
+	query.Count(&total)
+
+	var changesets []models.InventoryChangeset
+	err := query.Omit("changes").Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&changesets).Error
+	return changesets, total, err
+}
+
+// ==================== 调度器 ====================
+
+// Start 启动周期同步调度循环
+func (s *InventorySourceService) Start() {
+	s.startOnce.Do(func() {
+		go func() {
+			ticker := time.NewTicker(time.Minute)
+			defer ticker.Stop()
+
+			s.tick()
+			for {
+				select {
+				case <-ticker.C:
+					s.tick()
+				case <-s.stopCh:
+					log.Printf("[InventorySource] 调度器已停止")
+					return
+				}
+			}
+		}()
+		log.Printf("[InventorySource] 调度器已启动")
+	})
+}
+
+// Stop 停止周期同步调度循环
+func (s *InventorySourceService) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stopCh)
+	})
+}
+
+// tick 同步到期的来源
+func (s *InventorySourceService) tick() {
+	now := time.Now()
+	var due []models.InventorySource
+	if err := s.db.Where("enabled = ? AND interval_minutes > 0 AND next_sync_at IS NOT NULL AND next_sync_at <= ?", true, now).
+		Find(&due).Error; err != nil {
+		log.Printf("[InventorySource] 查询到期来源失败: %v", err)
+		return
+	}
+
+	for i := range due {
+		src := due[i]
+		next := now.Add(time.Duration(src.IntervalMinutes) * time.Minute)
+		// 乐观锁抢占本次同步，避免多实例部署时重复执行
+		res := s.db.Model(&models.InventorySource{}).
+			Where("id = ? AND next_sync_at = ?", src.ID, *src.NextSyncAt).
+			Update("next_sync_at", next)
+		if res.Error != nil || res.RowsAffected == 0 {
+			continue
+		}
+		go func(id uint) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+			defer cancel()
+			_, _ = s.SyncSource(ctx, id, "schedule")
+		}(src.ID)
+	}
+}
+
+func (s *InventorySourceService) audit(action models.AuditAction, src *models.InventorySource, username, summary string) {
+	GetAuditService().NewAuditEntry(models.AuditCategorySystem, action).
+		WithUser(0, username, "").
+		WithResource("inventory_source", strconv.FormatUint(uint64(src.ID), 10), src.Name).
+		WithMetadata(map[string]interface{}{"type": src.Type, "config": src.Config, "interval_minutes": src.IntervalMinutes}).
+		WithNotes(summary).
+		WithStatus(models.AuditStatusSuccess).
+		WithTags("inventory", "inventory_source").
+		SaveAsync()
+}
@@ -0,0 +1,173 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
+)
+
+func TestParseNetBoxPage(t *testing.T) {
+	data := []byte(`{
+		"count": 2,
+		"next": "https://netbox.local/api/dcim/devices/?limit=1&offset=1",
+		"results": [{
+			"id": 12, "name": "gpu-node01",
+			"primary_ip4": {"address": "10.0.0.5/24"},
+			"site": {"name": "DC One", "slug": "dc1"},
+			"rack": {"name": "A01"},
+			"tenant": {"name": "ML Team", "slug": "ml"},
+			"device_role": {"slug": "compute"},
+			"status": {"value": "active", "label": "Active"},
+			"tags": [{"slug": "gpu"}, {"slug": "a100"}]
+		}]
+	}`)
+	page, err := parseNetBoxPage(data, "device")
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if page.next == "" || len(page.hosts) != 1 {
+		t.Fatalf("unexpected page: %+v", page)
+	}
+	h := page.hosts[0]
+	if h.Key != "device:12" || h.Hostname != "gpu-node01" || !reflect.DeepEqual(h.IPs, []string{"10.0.0.5"}) {
+		t.Fatalf("基本字段映射不正确: %+v", h)
+	}
+	if h.Rack != "A01" || h.Location != "DC One" || h.Owner != "ml" {
+		t.Fatalf("机柜/位置/归属映射不正确: %+v", h)
+	}
+	if h.Labels["role"] != "compute" || h.Labels["status"] != "active" || h.Labels["tags"] != "a100,gpu" || h.Labels["site"] != "dc1" {
+		t.Fatalf("标签映射不正确: %v", h.Labels)
+	}
+}
+
+func TestParsePrometheusTargets(t *testing.T) {
+	api := []byte(`{"status":"success","data":{"activeTargets":[
+		{"labels":{"instance":"10.0.0.5:9100","job":"node","rack":"A01"},"health":"up"},
+		{"labels":{"instance":"10.0.0.5:9400","job":"dcgm"},"health":"up"},
+		{"labels":{"instance":"https://web01.example.com/metrics","job":"blackbox"},"health":"down"}
+	]}}`)
+	hosts, err := parsePrometheusTargets(api)
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if len(hosts) != 2 {
+		t.Fatalf("同一主机的多个目标应合并: %+v", hosts)
+	}
+	if hosts[0].Key != "10.0.0.5" || hosts[0].Labels["job"] != "dcgm,node" || hosts[0].Rack != "A01" {
+		t.Fatalf("合并结果不正确: %+v", hosts[0])
+	}
+	if hosts[1].Hostname != "web01.example.com" || len(hosts[1].IPs) != 0 {
+		t.Fatalf("URL 形式的 instance 应取主机名: %+v", hosts[1])
+	}
+
+	fileSD := []byte(`[{"targets":["node02:9100"],"labels":{"job":"node","env":"prod"}}]`)
+	hosts, err = parsePrometheusTargets(fileSD)
+	if err != nil || len(hosts) != 1 || hosts[0].Hostname != "node02" || hosts[0].Labels["env"] != "prod" {
+		t.Fatalf("file_sd 解析不正确: %+v, %v", hosts, err)
+	}
+}
+
+func TestParseDHCPLeases(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	isc := []byte(`
+lease 10.0.0.20 {
+  starts 4 2026/10/18 08:00:00;
+  ends 4 2026/10/18 20:00:00;
+  binding state active;
+  hardware ethernet AA:BB:CC:00:00:01;
+  client-hostname "node20";
+}
+lease 10.0.0.21 {
+  binding state free;
+  hardware ethernet aa:bb:cc:00:00:02;
+}
+lease 10.0.0.22 {
+  ends 1 2026/10/12 20:00:00;
+  hardware ethernet aa:bb:cc:00:00:03;
+}
+lease 10.0.0.30 {
+  binding state active;
+  hardware ethernet aa:bb:cc:00:00:01;
+  client-hostname "node20";
+}
+`)
+	hosts, err := parseDHCPLeases(isc, "", now)
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if len(hosts) != 1 || hosts[0].Key != "aa:bb:cc:00:00:01" || !reflect.DeepEqual(hosts[0].IPs, []string{"10.0.0.30"}) {
+		t.Fatalf("仅保留有效租约，同一 MAC 以最后一条为准: %+v", hosts)
+	}
+
+	dnsmasq := []byte("1893456000 aa:bb:cc:00:00:04 10.0.0.40 node40 *\n" +
+		"1700000000 aa:bb:cc:00:00:05 10.0.0.41 expired *\n" +
+		"0 aa:bb:cc:00:00:06 10.0.0.42 * *\n")
+	hosts, err = parseDHCPLeases(dnsmasq, "", now)
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if len(hosts) != 2 || hosts[0].Hostname != "node40" || hosts[1].Hostname != "" || hosts[1].Labels["mac"] != "aa:bb:cc:00:00:06" {
+		t.Fatalf("dnsmasq 租约解析不正确: %+v", hosts)
+	}
+}
+
+func TestParseAnsibleInventoryJSON(t *testing.T) {
+	data := []byte(`{
+		"gpu": {"hosts": ["node01", "node02"], "vars": {"cuda": "12"}},
+		"login": ["node02"],
+		"all": {"children": ["gpu", "login", "ungrouped"]},
+		"_meta": {"hostvars": {
+			"node01": {"ansible_host": "10.0.0.5", "rack": "A01"},
+			"node03": {"ansible_host": "node03.example.com"}
+		}}
+	}`)
+	hosts, err := parseAnsibleInventoryJSON(data)
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	byKey := make(map[string]InventorySourceHost)
+	for _, h := range hosts {
+		byKey[h.Key] = h
+	}
+	if len(byKey) != 3 {
+		t.Fatalf("应包含组内主机及仅出现在 hostvars 中的主机: %+v", hosts)
+	}
+	if h := byKey["node01"]; !reflect.DeepEqual(h.IPs, []string{"10.0.0.5"}) || h.Rack != "A01" || h.Labels["groups"] != "gpu" {
+		t.Fatalf("node01 映射不正确: %+v", h)
+	}
+	if h := byKey["node02"]; h.Labels["groups"] != "gpu,login" {
+		t.Fatalf("node02 组标签不正确: %+v", h)
+	}
+	if h := byKey["node03"]; h.Hostname != "node03.example.com" || len(h.IPs) != 0 {
+		t.Fatalf("主机名形式的 ansible_host 应作为主机名: %+v", h)
+	}
+}
+
+func TestComputeInventoryChanges(t *testing.T) {
+	existing := map[string]models.InventoryHost{
+		"device:1": {ID: 1, Hostname: "node01", IPs: models.StringArray{"10.0.0.1"}, Rack: "A01", Labels: models.InventoryLabels{"role": "compute"}},
+		"device:2": {ID: 2, Hostname: "node02", IPs: models.StringArray{"10.0.0.2"}, Labels: models.InventoryLabels{"role": "compute", "manual": "x"}},
+		"device:3": {ID: 3, Hostname: "node03", IPs: models.StringArray{"10.0.0.3"}},
+	}
+	desired := []InventorySourceHost{
+		{Key: "device:1", Hostname: "node01", IPs: []string{"10.0.0.1"}, Rack: "A02", Labels: map[string]string{"role": "compute"}},
+		{Key: "device:2", Hostname: "node02", IPs: []string{"10.0.0.2"}, Labels: map[string]string{"role": "compute"}},
+		{Key: "device:4", Hostname: "Node04", IPs: []string{"10.0.0.4"}},
+	}
+	changes := computeInventoryChanges(desired, existing)
+	if len(changes) != 3 {
+		t.Fatalf("应生成 1 条修改、1 条新增、1 条删除: %+v", changes)
+	}
+	if c := changes[0]; c.Action != models.InventoryChangeUpdate || c.HostID != 1 ||
+		!reflect.DeepEqual(c.Diff, []models.InventoryFieldChange{{Field: "rack", Old: "A01", New: "A02"}}) {
+		t.Fatalf("修改不正确: %+v", c)
+	}
+	if c := changes[1]; c.Action != models.InventoryChangeAdd || c.Hostname != "node04" {
+		t.Fatalf("新增不正确: %+v", c)
+	}
+	if c := changes[2]; c.Action != models.InventoryChangeRemove || c.HostID != 3 {
+		t.Fatalf("删除不正确: %+v", c)
+	}
+}
@@ -0,0 +1,625 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/database"
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/services/ai_providers"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultDiagnosisStderrLines = 100
+	maxDiagnosisStderrLines     = 1000
+	diagnosisStdoutLines        = 30
+	maxDiagnosisNodes           = 4
+	diagnosisSlurmdLogLines     = 150
+	diagnosisLogRuneLimit       = 4000  // 单个节点 slurmd 日志的字符上限
+	diagnosisStderrRuneLimit    = 12000 // stderr 末尾内容的字符上限
+	diagnosisMaxTokens          = 1500
+	diagnosisMetricsMaxWindow   = 6 * time.Hour
+	diagnosisMetricsMinWindow   = 5 * time.Minute
+	diagnosisLogMargin          = 5 * time.Minute
+)
+
+// 诊断时采集的 sacct 字段
+const diagnosisSacctFormat = "JobID,JobName,Partition,State,ExitCode,DerivedExitCode,Elapsed,Timelimit,Start,End,NodeList,ReqMem,MaxRSS,MaxVMSize,AllocTRES"
+
+var (
+	// ErrJobDiagnosisRunning 同一作业的诊断正在进行
+	ErrJobDiagnosisRunning = errors.New("job diagnosis is already running")
+	// ErrJobNotFinished 作业尚未结束
+	ErrJobNotFinished = errors.New("job has not finished yet")
+	// ErrAIConfigDisabled 指定的 AI 配置已停用
+	ErrAIConfigDisabled = errors.New("AI config is disabled")
+
+	scontrolFieldPattern = regexp.MustCompile(`(?:^|\s)([A-Za-z][A-Za-z:/]*)=`)
+)
+
+// jobDiagnosisSystemPrompt 要求模型只输出 JSON，便于结构化保存
+const jobDiagnosisSystemPrompt = `你是 HPC 集群的 Slurm 作业故障诊断专家。根据用户提供的作业记账信息、stderr、节点状态、监控指标与 slurmd 日志判断作业失败的最可能原因。
+只输出一个 JSON 对象，不要输出其他内容，格式如下：
+{"probable_cause":"一句话说明最可能的原因","category":"oom|timeout|node_failure|application_error|configuration|environment|unknown","confidence":"high|medium|low","evidence":["引用材料中的具体行或数值作为依据"],"suggested_fix":["可执行的修复建议，如调整 --mem、--time 或排查节点"]}
+证据不足时 category 使用 unknown、confidence 使用 low，并在 suggested_fix 中说明还需要收集哪些信息。`
+
+// JobDiagnosisNode 作业所在节点的健康信息
+type JobDiagnosisNode struct {
+	Name      string             `json:"name"`
+	State     string             `json:"state,omitempty"`
+	Reason    string             `json:"reason,omitempty"`
+	BootTime  string             `json:"boot_time,omitempty"`
+	Metrics   map[string]float64 `json:"metrics,omitempty"`  // 故障时间窗口内的监控指标
+	Hardware  map[string]float64 `json:"hardware,omitempty"` // Salt 采集的节点硬件指标峰值
+	SlurmdLog string             `json:"slurmd_log,omitempty"`
+}
+
+// JobDiagnosisContext 诊断时采集到的全部材料
+type JobDiagnosisContext struct {
+	Job        *models.Job         `json:"-"`
+	Accounting []map[string]string `json:"accounting,omitempty"`
+	StderrTail string              `json:"stderr_tail,omitempty"`
+	StdoutTail string              `json:"stdout_tail,omitempty"`
+	Nodes      []JobDiagnosisNode  `json:"nodes,omitempty"`
+	WindowFrom time.Time           `json:"window_from"`
+	WindowTo   time.Time           `json:"window_to"`
+	Errors     map[string]string   `json:"errors,omitempty"` // 采集失败的材料及原因
+}
+
+// Sources 成功采集到的材料类型
+func (c *JobDiagnosisContext) Sources() []string {
+	var sources []string
+	if len(c.Accounting) > 0 {
+		sources = append(sources, "sacct")
+	}
+	if c.StderrTail != "" || c.StdoutTail != "" {
+		sources = append(sources, "stderr")
+	}
+	var state, metrics, logs bool
+	for _, node := range c.Nodes {
+		state = state || node.State != ""
+		metrics = metrics || len(node.Metrics) > 0 || len(node.Hardware) > 0
+		logs = logs || node.SlurmdLog != ""
+	}
+	if state {
+		sources = append(sources, "node_state")
+	}
+	if metrics {
+		sources = append(sources, "metrics")
+	}
+	if logs {
+		sources = append(sources, "slurmd_log")
+	}
+	return sources
+}
+
+func (c *JobDiagnosisContext) fail(source string, err error) {
+	if c.Errors == nil {
+		c.Errors = map[string]string{}
+	}
+	c.Errors[source] = truncateText(err.Error(), 300)
+}
+
+// JobDiagnosisService 失败作业诊断：采集作业记账、输出、节点状态、监控指标与 slurmd 日志后交由 AI 分析
+type JobDiagnosisService struct {
+	db        *gorm.DB
+	jobs      *JobService
+	providers ai_providers.ProviderFactory
+	running   sync.Map
+}
+
+var (
+	jobDiagnosisServiceInstance *JobDiagnosisService
+	jobDiagnosisServiceOnce     sync.Once
+)
+
+// NewJobDiagnosisService 创建作业诊断服务（单例）
+func NewJobDiagnosisService(jobService *JobService) *JobDiagnosisService {
+	jobDiagnosisServiceOnce.Do(func() {
+		jobDiagnosisServiceInstance = &JobDiagnosisService{
+			db:        jobService.db,
+			jobs:      jobService,
+			providers: ai_providers.NewProviderFactory(),
+		}
+		if err := jobService.db.AutoMigrate(&models.Job{}); err != nil {
+			log.Printf("[JobDiagnosis] 自动迁移失败: %v", err)
+		}
+	})
+	return jobDiagnosisServiceInstance
+}
+
+// GetJobDiagnosisService 获取作业诊断服务实例，未初始化时返回 nil
+func GetJobDiagnosisService() *JobDiagnosisService {
+	return jobDiagnosisServiceInstance
+}
+
+// LoadJob 按平台作业ID加载作业，仅作业所有者与管理员可访问
+func (s *JobDiagnosisService) LoadJob(jobID uint, caller AIToolCaller) (*models.Job, error) {
+	var job models.Job
+	if err := s.db.First(&job, jobID).Error; err != nil {
+		return nil, err
+	}
+	if !caller.IsAdmin && job.UserID != caller.UserID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &job, nil
+}
+
+// Diagnose 采集材料、调用 AI 生成诊断并保存到作业记录；返回诊断结论与采集到的材料
+func (s *JobDiagnosisService) Diagnose(ctx context.Context, jobID uint, caller AIToolCaller, req models.JobDiagnoseRequest) (*models.JobDiagnosis, *JobDiagnosisContext, error) {
+	job, err := s.LoadJob(jobID, caller)
+	if err != nil {
+		return nil, nil, err
+	}
+	if status := strings.ToUpper(job.Status); status == "PENDING" || status == "RUNNING" || status == "QUEUED" {
+		return nil, nil, ErrJobNotFinished
+	}
+	if _, running := s.running.LoadOrStore(job.ID, true); running {
+		return nil, nil, ErrJobDiagnosisRunning
+	}
+	defer s.running.Delete(job.ID)
+
+	aiService := NewAIService()
+	var config *models.AIAssistantConfig
+	if req.ConfigID > 0 {
+		config, err = aiService.GetConfig(req.ConfigID)
+	} else {
+		config, err = aiService.GetDefaultConfig()
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("AI config not found: %v", err)
+	}
+	// 停用的配置对所有用户不可见，不得通过 ConfigID 绕过
+	if !config.IsEnabled {
+		return nil, nil, ErrAIConfigDisabled
+	}
+	if budgets := GetAIBudgetService(); budgets != nil {
+		if _, err := budgets.Check(caller.UserID, config); errors.Is(err, ErrAIBudgetExceeded) {
+			return nil, nil, err
+		}
+	}
+	provider, err := s.providers.CreateProvider(config)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create AI provider: %v", err)
+	}
+
+	dctx := s.Collect(ctx, job, req.StderrLines)
+
+	startTime := time.Now()
+	response, err := provider.Chat(ctx, ai_providers.ChatRequest{
+		Model:        config.Model,
+		SystemPrompt: jobDiagnosisSystemPrompt,
+		Messages:     []ai_providers.ChatMessage{{Role: "user", Content: buildJobDiagnosisPrompt(dctx)}},
+		MaxTokens:    diagnosisMaxTokens,
+		Temperature:  0.2,
+	})
+	aiService.RecordChatUsage(caller.UserID, config, response, int(time.Since(startTime).Milliseconds()), err == nil)
+	if err != nil {
+		return nil, dctx, fmt.Errorf("AI API call failed: %v", err)
+	}
+
+	diagnosis := parseJobDiagnosis(response.Content)
+	diagnosis.Sources = dctx.Sources()
+	diagnosis.ConfigID = config.ID
+	diagnosis.Model = config.Model
+	diagnosis.TokensUsed = response.TokensUsed
+	diagnosis.CreatedBy = caller.UserID
+	diagnosis.CreatedAt = time.Now()
+	if err := s.db.Model(job).Update("diagnosis", diagnosis).Error; err != nil {
+		return nil, dctx, fmt.Errorf("save diagnosis failed: %v", err)
+	}
+	return diagnosis, dctx, nil
+}
+
+// Collect 采集诊断材料；单项失败记录在 Errors 中，不影响其他材料
+func (s *JobDiagnosisService) Collect(ctx context.Context, job *models.Job, stderrLines int) *JobDiagnosisContext {
+	if stderrLines <= 0 {
+		stderrLines = defaultDiagnosisStderrLines
+	}
+	if stderrLines > maxDiagnosisStderrLines {
+		stderrLines = maxDiagnosisStderrLines
+	}
+	dctx := &JobDiagnosisContext{Job: job}
+
+	// sacct 记账信息（含各 step）
+	nodeList := ""
+	if out, err := s.jobs.execOnCluster(job.ClusterID, fmt.Sprintf("sacct -j %d --parsable2 --format=%s", job.JobID, diagnosisSacctFormat)); err != nil {
+		dctx.fail("sacct", err)
+	} else {
+		dctx.Accounting = parseSacctOutput(out)
+		for _, row := range dctx.Accounting {
+			if !strings.Contains(row["JobID"], ".") {
+				nodeList = row["NodeList"]
+				break
+			}
+		}
+	}
+	dctx.WindowFrom, dctx.WindowTo = jobDiagnosisWindow(job, dctx.Accounting)
+
+	// stderr / stdout 末尾内容
+	output, err := s.jobs.GetJobOutput(ctx, strconv.FormatUint(uint64(job.UserID), 10), job.ClusterID, job.ID)
+	if err != nil {
+		dctx.fail("job_output", err)
+	} else {
+		dctx.StderrTail = tailRunes(tailLines(output.StdErr, stderrLines), diagnosisStderrRuneLimit)
+		dctx.StdoutTail = tailRunes(tailLines(output.StdOut, diagnosisStdoutLines), diagnosisLogRuneLimit)
+	}
+
+	nodes := ExpandSlurmHostlist(nodeList, maxDiagnosisNodes)
+	if len(nodes) == 0 {
+		return dctx
+	}
+	for _, name := range nodes {
+		dctx.Nodes = append(dctx.Nodes, JobDiagnosisNode{Name: name})
+	}
+
+	// 节点当前在 Slurm 中的状态与原因
+	if out, err := s.jobs.execOnCluster(job.ClusterID, fmt.Sprintf("scontrol show node %s -o", CompressSlurmHostlist(nodes))); err != nil {
+		dctx.fail("node_state", err)
+	} else {
+		states := parseScontrolNodes(out)
+		for i := range dctx.Nodes {
+			if fields, ok := states[dctx.Nodes[i].Name]; ok {
+				dctx.Nodes[i].State = fields["State"]
+				dctx.Nodes[i].Reason = fields["Reason"]
+				dctx.Nodes[i].BootTime = fields["BootTime"]
+			}
+		}
+	}
+
+	s.collectMetrics(dctx)
+	s.collectSlurmdLogs(ctx, dctx)
+	return dctx
+}
+
+// collectMetrics 采集故障时间窗口内的 VictoriaMetrics 指标与 Salt 上报的节点硬件指标
+func (s *JobDiagnosisService) collectMetrics(dctx *JobDiagnosisContext) {
+	window := dctx.WindowTo.Sub(dctx.WindowFrom)
+	if window < diagnosisMetricsMinWindow {
+		window = diagnosisMetricsMinWindow
+	}
+	if window > diagnosisMetricsMaxWindow {
+		window = diagnosisMetricsMaxWindow
+	}
+	rangeExpr := fmt.Sprintf("%ds", int(window.Seconds()))
+	queries := map[string]string{
+		"cpu_usage_avg":   `100 - avg(avg_over_time(cpu_usage_idle{ident="%s"}[%s]))`,
+		"mem_usage_peak":  `100 - min_over_time(mem_available_percent{ident="%s"}[%s])`,
+		"disk_usage_root": `max_over_time(disk_used_percent{ident="%s",path="/"}[%s])`,
+		"load1_peak":      `max_over_time(system_load1{ident="%s"}[%s])`,
+	}
+
+	metrics := NewMetricsService()
+	var lastErr error
+	for i := range dctx.Nodes {
+		node := &dctx.Nodes[i]
+		for name, query := range queries {
+			result, err := metrics.QueryAt(fmt.Sprintf(query, node.Name, rangeExpr), dctx.WindowTo)
+			if err != nil {
+				lastErr = err
+				continue
+			}
+			if len(result.Data.Result) == 0 {
+				continue
+			}
+			if node.Metrics == nil {
+				node.Metrics = map[string]float64{}
+			}
+			node.Metrics[name] = roundMetric(metrics.ExtractValue(result))
+		}
+
+		var samples []models.NodeMetrics
+		if err := s.db.Where("minion_id = ? AND timestamp BETWEEN ? AND ?", node.Name, dctx.WindowFrom.Add(-diagnosisLogMargin), dctx.WindowTo.Add(diagnosisLogMargin)).
+			Order("timestamp ASC").Limit(500).Find(&samples).Error; err == nil && len(samples) > 0 {
+			node.Hardware = summarizeNodeMetrics(samples)
+		}
+	}
+	if lastErr != nil {
+		dctx.fail("metrics", lastErr)
+	}
+}
+
+// collectSlurmdLogs 通过 Salt 读取各节点故障时间窗口内的 slurmd 日志及提及该作业的日志行
+func (s *JobDiagnosisService) collectSlurmdLogs(ctx context.Context, dctx *JobDiagnosisContext) {
+	minionByNode := s.nodeMinions(dctx.Nodes)
+	minions := make([]string, 0, len(minionByNode))
+	for _, minion := range minionByNode {
+		minions = append(minions, minion)
+	}
+	groups := map[string][]string{"": minions}
+	if masterService := GetSaltMasterService(); masterService != nil {
+		var unresolved []string
+		groups, unresolved = masterService.GroupMinionsByMaster(minions)
+		if len(unresolved) > 0 {
+			groups[""] = append(groups[""], unresolved...)
+		}
+	}
+
+	cmd := slurmdLogCommand(dctx.Job.JobID, dctx.WindowFrom.Add(-diagnosisLogMargin), dctx.WindowTo.Add(diagnosisLogMargin))
+	results := map[string]interface{}{}
+	for masterID, targets := range groups {
+		if len(targets) == 0 {
+			continue
+		}
+		salt, err := SaltServiceForMaster(masterID)
+		if err != nil {
+			dctx.fail("slurmd_log", err)
+			continue
+		}
+		ret, err := salt.RunLocal(ctx, strings.Join(targets, ","), "list", "cmd.run", []interface{}{cmd}, map[string]interface{}{"python_shell": true}, 30)
+		if err != nil {
+			dctx.fail("slurmd_log", err)
+			continue
+		}
+		for minion, value := range ret {
+			results[minion] = value
+		}
+	}
+	for i := range dctx.Nodes {
+		if text, ok := results[minionByNode[dctx.Nodes[i].Name]].(string); ok {
+			dctx.Nodes[i].SlurmdLog = tailRunes(strings.TrimSpace(text), diagnosisLogRuneLimit)
+		}
+	}
+}
+
+// nodeMinions 节点名到 Salt minion ID 的映射，未登记 minion ID 的节点使用节点名
+func (s *JobDiagnosisService) nodeMinions(nodes []JobDiagnosisNode) map[string]string {
+	result := make(map[string]string, len(nodes))
+	names := make([]string, len(nodes))
+	for i, node := range nodes {
+		names[i] = node.Name
+		result[node.Name] = node.Name
+	}
+	slurmDB := database.GetSlurmDB()
+	if slurmDB == nil {
+		slurmDB = s.db
+	}
+	var registered []models.SlurmNode
+	if err := slurmDB.Where("node_name IN ?", names).Find(&registered).Error; err == nil {
+		for _, node := range registered {
+			if node.SaltMinionID != "" {
+				result[node.NodeName] = node.SaltMinionID
+			}
+		}
+	}
+	return result
+}
+
+// slurmdLogCommand 优先读取 journald 中时间窗口内的 slurmd 日志，并从日志文件中查找提及作业ID的行
+func slurmdLogCommand(slurmJobID uint32, from, to time.Time) string {
+	const layout = "2006-01-02 15:04:05"
+	return fmt.Sprintf(
+		"journalctl -u slurmd --no-pager -o short-iso --since '%s' --until '%s' 2>/dev/null | tail -n %d; "+
+			"grep -hE '(JobId=|job[ _]?)%d([^0-9]|$)' /var/log/slurm/slurmd.log /var/log/slurmd.log 2>/dev/null | tail -n 50",
+		from.Format(layout), to.Format(layout), diagnosisSlurmdLogLines, slurmJobID)
+}
+
+// jobDiagnosisWindow 故障时间窗口：优先使用 sacct 的 Start/End，其次为作业记录中的时间
+func jobDiagnosisWindow(job *models.Job, accounting []map[string]string) (time.Time, time.Time) {
+	var from, to time.Time
+	for _, row := range accounting {
+		if strings.Contains(row["JobID"], ".") {
+			continue
+		}
+		if t, err := time.ParseInLocation("2006-01-02T15:04:05", row["Start"], time.Local); err == nil {
+			from = t
+		}
+		if t, err := time.ParseInLocation("2006-01-02T15:04:05", row["End"], time.Local); err == nil {
+			to = t
+		}
+		break
+	}
+	if from.IsZero() && job.StartTime != nil {
+		from = *job.StartTime
+	}
+	if to.IsZero() && job.EndTime != nil {
+		to = *job.EndTime
+	}
+	if to.IsZero() {
+		to = job.UpdatedAt
+	}
+	if from.IsZero() || from.After(to) {
+		from = to.Add(-diagnosisMetricsMinWindow)
+	}
+	return from, to
+}
+
+// parseSacctOutput 解析 sacct --parsable2 输出（首行为字段名，以 | 分隔）
+func parseSacctOutput(out string) []map[string]string {
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) < 2 {
+		return nil
+	}
+	header := strings.Split(strings.TrimSpace(lines[0]), "|")
+	var rows []map[string]string
+	for _, line := range lines[1:] {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		values := strings.Split(line, "|")
+		row := make(map[string]string, len(header))
+		for i, key := range header {
+			if i < len(values) && values[i] != "" {
+				row[key] = values[i]
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// parseScontrolNodes 解析 scontrol show node -o 输出，值中可能包含空格（如 Reason）
+func parseScontrolNodes(out string) map[string]map[string]string {
+	nodes := map[string]map[string]string{}
+	for _, line := range strings.Split(out, "\n") {
+		matches := scontrolFieldPattern.FindAllStringSubmatchIndex(line, -1)
+		fields := make(map[string]string, len(matches))
+		for i, m := range matches {
+			end := len(line)
+			if i+1 < len(matches) {
+				end = matches[i+1][0]
+			}
+			fields[line[m[2]:m[3]]] = strings.TrimSpace(line[m[1]:end])
+		}
+		if name := fields["NodeName"]; name != "" {
+			nodes[name] = fields
+		}
+	}
+	return nodes
+}
+
+// summarizeNodeMetrics 汇总时间窗口内 Salt 上报的节点指标峰值
+func summarizeNodeMetrics(samples []models.NodeMetrics) map[string]float64 {
+	summary := map[string]float64{"samples": float64(len(samples))}
+	peak := func(key string, v float64) {
+		if v > summary[key] {
+			summary[key] = v
+		}
+	}
+	for _, m := range samples {
+		peak("mem_usage_peak", m.MemoryUsagePercent)
+		peak("cpu_usage_peak", m.CPUUsagePercent)
+		peak("gpu_mem_usage_peak", m.GPUMemoryUsagePercent)
+		peak("gpu_util_peak", m.GPUAvgUtilization)
+		peak("ib_ports_down", float64(m.IBDownCount))
+		peak("gpu_count", float64(m.GPUCount))
+	}
+	for key, v := range summary {
+		summary[key] = roundMetric(v)
+	}
+	return summary
+}
+
+// buildJobDiagnosisPrompt 将采集到的材料组织为诊断提示词
+func buildJobDiagnosisPrompt(dctx *JobDiagnosisContext) string {
+	job := dctx.Job
+	var b strings.Builder
+	fmt.Fprintf(&b, "## 作业\n作业: %s (Slurm ID %d)\n集群: %s\n分区: %s\n平台记录状态: %s\n", job.Name, job.JobID, job.ClusterID, job.Partition, job.Status)
+	if job.ExitCode != nil {
+		fmt.Fprintf(&b, "退出码: %d\n", *job.ExitCode)
+	}
+	fmt.Fprintf(&b, "申请资源: nodes=%d cpus=%d mem=%s time=%s\n", job.Nodes, job.CPUs, job.Memory, job.TimeLimit)
+	if job.Command != "" {
+		fmt.Fprintf(&b, "命令:\n```\n%s\n```\n", truncateRunes(job.Command, 2000))
+	}
+	fmt.Fprintf(&b, "故障时间窗口: %s ~ %s\n", dctx.WindowFrom.Format(time.RFC3339), dctx.WindowTo.Format(time.RFC3339))
+
+	if len(dctx.Accounting) > 0 {
+		b.WriteString("\n## sacct\n")
+		keys := strings.Split(diagnosisSacctFormat, ",")
+		for _, row := range dctx.Accounting {
+			parts := make([]string, 0, len(keys))
+			for _, key := range keys {
+				if v := row[key]; v != "" {
+					parts = append(parts, key+"="+v)
+				}
+			}
+			b.WriteString(strings.Join(parts, " ") + "\n")
+		}
+	}
+	if dctx.StderrTail != "" {
+		fmt.Fprintf(&b, "\n## stderr（末尾）\n```\n%s\n```\n", dctx.StderrTail)
+	}
+	if dctx.StdoutTail != "" {
+		fmt.Fprintf(&b, "\n## stdout（末尾）\n```\n%s\n```\n", dctx.StdoutTail)
+	}
+	for _, node := range dctx.Nodes {
+		fmt.Fprintf(&b, "\n## 节点 %s\n", node.Name)
+		if node.State != "" {
+			fmt.Fprintf(&b, "当前状态: %s\n", node.State)
+		}
+		if node.Reason != "" {
+			fmt.Fprintf(&b, "原因: %s\n", node.Reason)
+		}
+		if node.BootTime != "" {
+			fmt.Fprintf(&b, "启动时间: %s\n", node.BootTime)
+		}
+		if len(node.Metrics) > 0 {
+			fmt.Fprintf(&b, "窗口内监控指标: %s\n", formatMetricMap(node.Metrics))
+		}
+		if len(node.Hardware) > 0 {
+			fmt.Fprintf(&b, "窗口内硬件指标: %s\n", formatMetricMap(node.Hardware))
+		}
+		if node.SlurmdLog != "" {
+			fmt.Fprintf(&b, "slurmd 日志:\n```\n%s\n```\n", node.SlurmdLog)
+		}
+	}
+	if len(dctx.Errors) > 0 {
+		b.WriteString("\n## 未能采集的材料\n")
+		keys := make([]string, 0, len(dctx.Errors))
+		for key := range dctx.Errors {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Fprintf(&b, "- %s: %s\n", key, dctx.Errors[key])
+		}
+	}
+	return b.String()
+}
+
+// parseJobDiagnosis 解析模型输出的 JSON（允许包裹在代码块中），无法解析时将原文作为原因
+func parseJobDiagnosis(content string) *models.JobDiagnosis {
+	diagnosis := &models.JobDiagnosis{}
+	start, end := strings.Index(content, "{"), strings.LastIndex(content, "}")
+	if start < 0 || end <= start || json.Unmarshal([]byte(content[start:end+1]), diagnosis) != nil || diagnosis.ProbableCause == "" {
+		return &models.JobDiagnosis{
+			ProbableCause: truncateRunes(strings.TrimSpace(content), 2000),
+			Category:      models.JobFailureUnknown,
+			Confidence:    "low",
+		}
+	}
+	switch diagnosis.Category {
+	case models.JobFailureOOM, models.JobFailureTimeout, models.JobFailureNode, models.JobFailureApplication,
+		models.JobFailureConfig, models.JobFailureEnvironment:
+	default:
+		diagnosis.Category = models.JobFailureUnknown
+	}
+	switch diagnosis.Confidence {
+	case "high", "medium", "low":
+	default:
+		diagnosis.Confidence = "low"
+	}
+	return diagnosis
+}
+
+func formatMetricMap(metrics map[string]float64) string {
+	keys := make([]string, 0, len(metrics))
+	for key := range metrics {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		parts[i] = fmt.Sprintf("%s=%g", key, metrics[key])
+	}
+	return strings.Join(parts, " ")
+}
+
+func roundMetric(v float64) float64 {
+	return float64(int64(v*100+0.5)) / 100
+}
+
+// tailLines 保留文本末尾的 n 行
+func tailLines(s string, n int) string {
+	s = strings.TrimRight(s, "\n")
+	if s == "" {
+		return ""
+	}
+	lines := strings.Split(s, "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
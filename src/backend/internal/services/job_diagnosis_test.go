@@ -0,0 +1,90 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
+)
+
+func TestParseSacctAndScontrol(t *testing.T) {
+	rows := parseSacctOutput("JobID|State|ExitCode|NodeList|MaxRSS\n" +
+		"1234|OUT_OF_MEMORY|0:125|node[01-02]|\n" +
+		"1234.batch|OUT_OF_MEMORY|0:125|node01|31G\n")
+	if len(rows) != 2 || rows[0]["State"] != "OUT_OF_MEMORY" || rows[1]["MaxRSS"] != "31G" {
+		t.Fatalf("sacct 解析结果不正确: %+v", rows)
+	}
+	if _, ok := rows[0]["MaxRSS"]; ok {
+		t.Fatal("空字段不应出现在结果中")
+	}
+
+	nodes := parseScontrolNodes("NodeName=node01 Arch=x86_64 CPULoad=0.01 State=DOWN+DRAIN Reason=Kill task failed [root@2026-10-01T10:00:00] BootTime=2026-09-30T08:00:00\n" +
+		"NodeName=node02 State=IDLE Reason=(null)\n")
+	if got := nodes["node01"]; got["State"] != "DOWN+DRAIN" || got["Reason"] != "Kill task failed [root@2026-10-01T10:00:00]" || got["BootTime"] != "2026-09-30T08:00:00" {
+		t.Fatalf("scontrol 解析结果不正确: %+v", got)
+	}
+	if nodes["node02"]["State"] != "IDLE" {
+		t.Fatalf("应解析多行输出: %+v", nodes)
+	}
+}
+
+func TestParseJobDiagnosis(t *testing.T) {
+	diagnosis := parseJobDiagnosis("分析如下：\n```json\n{\"probable_cause\":\"内存超出申请量\",\"category\":\"oom\",\"confidence\":\"high\"," +
+		"\"evidence\":[\"MaxRSS=31G\"],\"suggested_fix\":[\"--mem=48G\"]}\n```")
+	if diagnosis.Category != models.JobFailureOOM || diagnosis.Confidence != "high" || len(diagnosis.Evidence) != 1 || diagnosis.SuggestedFix[0] != "--mem=48G" {
+		t.Fatalf("应解析代码块中的 JSON: %+v", diagnosis)
+	}
+
+	diagnosis = parseJobDiagnosis(`{"probable_cause":"磁盘写满","category":"disk","confidence":"certain"}`)
+	if diagnosis.Category != models.JobFailureUnknown || diagnosis.Confidence != "low" {
+		t.Fatalf("未知分类与置信度应归一化: %+v", diagnosis)
+	}
+
+	diagnosis = parseJobDiagnosis("无法判断，缺少日志")
+	if diagnosis.ProbableCause != "无法判断，缺少日志" || diagnosis.Category != models.JobFailureUnknown || diagnosis.Confidence != "low" {
+		t.Fatalf("非 JSON 输出应作为原因保存: %+v", diagnosis)
+	}
+}
+
+func TestBuildJobDiagnosisPrompt(t *testing.T) {
+	exitCode := 137
+	end := time.Date(2026, 10, 1, 10, 0, 0, 0, time.Local)
+	job := &models.Job{Name: "train", JobID: 1234, ClusterID: "c1", Status: "FAILED", ExitCode: &exitCode, EndTime: &end}
+	accounting := []map[string]string{{"JobID": "1234", "State": "FAILED", "Start": "2026-10-01T09:00:00", "End": "2026-10-01T10:00:00"}}
+
+	from, to := jobDiagnosisWindow(job, accounting)
+	if to.Sub(from) != time.Hour {
+		t.Fatalf("应使用 sacct 的起止时间: %v ~ %v", from, to)
+	}
+
+	dctx := &JobDiagnosisContext{
+		Job:        job,
+		Accounting: accounting,
+		StderrTail: tailLines("line1\nline2\nline3\n", 2),
+		Nodes: []JobDiagnosisNode{{
+			Name:      "node01",
+			State:     "DRAIN",
+			Metrics:   map[string]float64{"mem_usage_peak": 99.5},
+			SlurmdLog: "error: Detected 1 oom_kill event",
+		}},
+		WindowFrom: from,
+		WindowTo:   to,
+	}
+	dctx.fail("metrics", errors.New("victoriametrics unreachable"))
+
+	prompt := buildJobDiagnosisPrompt(dctx)
+	for _, want := range []string{"退出码: 137", "State=FAILED", "line2\nline3", "## 节点 node01", "mem_usage_peak=99.5", "oom_kill", "metrics: victoriametrics unreachable"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("提示词缺少 %q:\n%s", want, prompt)
+		}
+	}
+	if strings.Contains(prompt, "line1") {
+		t.Error("stderr 应只保留末尾行")
+	}
+	if got := dctx.Sources(); !reflect.DeepEqual(got, []string{"sacct", "stderr", "node_state", "metrics", "slurmd_log"}) {
+		t.Fatalf("Sources = %v", got)
+	}
+}
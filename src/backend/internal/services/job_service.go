@@ -113,21 +113,21 @@ func (js *JobService) submitToSlurm(job *models.Job) error {
 
 	// 上传脚本到集群
 	scriptPath := fmt.Sprintf("/tmp/job_%d.sh", job.ID)
-	if err := js.sshSvc.UploadFile(cluster.Host, cluster.Port, username, password, []byte(script), scriptPath); err != nil {
+	if err := js.sshSvc.UploadFileVia(cluster.Host, cluster.Port, username, password, cluster.JumpHosts, []byte(script), scriptPath); err != nil {
 		js.updateJobStatus(job, "FAILED", fmt.Sprintf("Failed to upload script: %v", err))
 		return fmt.Errorf("upload script failed: %w", err)
 	}
 
 	// 设置脚本可执行权限
 	chmodCmd := fmt.Sprintf("chmod +x %s", scriptPath)
-	if _, err := js.sshSvc.ExecuteCommand(cluster.Host, cluster.Port, username, password, chmodCmd); err != nil {
+	if _, err := js.sshSvc.ExecuteCommandVia(cluster.Host, cluster.Port, username, password, cluster.JumpHosts, chmodCmd); err != nil {
 		js.updateJobStatus(job, "FAILED", fmt.Sprintf("Failed to set script permissions: %v", err))
 		return fmt.Errorf("set script permissions failed: %w", err)
 	}
 
 	// 提交作业
 	cmd := fmt.Sprintf("sbatch %s", scriptPath)
-	output, err := js.sshSvc.ExecuteCommand(cluster.Host, cluster.Port, username, password, cmd)
+	output, err := js.sshSvc.ExecuteCommandVia(cluster.Host, cluster.Port, username, password, cluster.JumpHosts, cmd)
 	if err != nil {
 		js.updateJobStatus(job, "FAILED", fmt.Sprintf("Failed to submit job: %v", err))
 		return fmt.Errorf("submit job failed: %w", err)
@@ -156,7 +156,7 @@ func (js *JobService) submitToSlurm(job *models.Job) error {
 
 	// 清理临时脚本文件
 	cleanupCmd := fmt.Sprintf("rm -f %s", scriptPath)
-	if _, err := js.sshSvc.ExecuteCommand(cluster.Host, cluster.Port, username, password, cleanupCmd); err != nil {
+	if _, err := js.sshSvc.ExecuteCommandVia(cluster.Host, cluster.Port, username, password, cluster.JumpHosts, cleanupCmd); err != nil {
 		fmt.Printf("Warning: failed to cleanup script file: %v\n", err)
 	}
 
@@ -178,6 +178,16 @@ func (js *JobService) getClusterAuth(cluster *models.Cluster) (string, string) {
 	return "root", ""
 }
 
+// execOnCluster 在作业所属集群的登录节点上执行命令
+func (js *JobService) execOnCluster(clusterID, cmd string) (string, error) {
+	var cluster models.Cluster
+	if err := js.db.Where("id = ?", clusterID).First(&cluster).Error; err != nil {
+		return "", fmt.Errorf("get cluster info failed: %w", err)
+	}
+	username, password := js.getClusterAuth(&cluster)
+	return js.sshSvc.ExecuteCommandVia(cluster.Host, cluster.Port, username, password, cluster.JumpHosts, cmd)
+}
+
 // updateJobStatus 更新作业状态的辅助方法
 func (js *JobService) updateJobStatus(job *models.Job, status, message string) {
 	job.Status = status
@@ -240,10 +250,10 @@ func (js *JobService) GetJobStatus(ctx context.Context, jobID uint) (*models.Job
 	username, password := js.getClusterAuth(&cluster)
 
 	cmd := fmt.Sprintf("squeue -h -j %d -o '%%T'", job.JobID)
-	output, err := js.sshSvc.ExecuteCommand(cluster.Host, cluster.Port, username, password, cmd)
+	output, err := js.sshSvc.ExecuteCommandVia(cluster.Host, cluster.Port, username, password, cluster.JumpHosts, cmd)
 	if err != nil {
 		cmd = fmt.Sprintf("sacct -j %d --format=State -n", job.JobID)
-		output, err = js.sshSvc.ExecuteCommand(cluster.Host, cluster.Port, username, password, cmd)
+		output, err = js.sshSvc.ExecuteCommandVia(cluster.Host, cluster.Port, username, password, cluster.JumpHosts, cmd)
 		if err != nil {
 			return nil, fmt.Errorf("query job status failed: %w", err)
 		}
@@ -287,7 +297,7 @@ func (js *JobService) CancelJob(ctx context.Context, userID, clusterID string, j
 
 	// 发送取消命令到SLURM
 	cmd := fmt.Sprintf("scancel %d", job.JobID)
-	_, err := js.sshSvc.ExecuteCommand(cluster.Host, cluster.Port, username, password, cmd)
+	_, err := js.sshSvc.ExecuteCommandVia(cluster.Host, cluster.Port, username, password, cluster.JumpHosts, cmd)
 	if err != nil {
 		return fmt.Errorf("cancel job failed: %w", err)
 	}
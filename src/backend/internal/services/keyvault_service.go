@@ -2,11 +2,13 @@ package services
 
 import (
 	"context"
+	"crypto/ed25519"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
+	"encoding/pem"
 	"fmt"
 	"strings"
 	"time"
@@ -15,6 +17,7 @@ import (
 	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/database"
 	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/utils"
 	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
 	"gorm.io/gorm"
 )
 
@@ -22,17 +25,19 @@ import (
 type KeyType string
 
 const (
-	KeyTypeSaltMaster KeyType = "salt_master"  // Salt Master 密钥
-	KeyTypeSaltMinion KeyType = "salt_minion"  // Salt Minion 密钥
-	KeyTypeMunge      KeyType = "munge"        // Munge 密钥
-	KeyTypeSSHPublic  KeyType = "ssh_public"   // SSH 公钥
-	KeyTypeSSHPrivate KeyType = "ssh_private"  // SSH 私钥
-	KeyTypeSSHHostKey KeyType = "ssh_host_key" // SSH Host Key
-	KeyTypeTLSCert    KeyType = "tls_cert"     // TLS 证书
-	KeyTypeTLSKey     KeyType = "tls_key"      // TLS 私钥
-	KeyTypeAPIKey     KeyType = "api_key"      // API 密钥
-	KeyTypeEncryption KeyType = "encryption"   // 加密密钥
-	KeyTypeCustom     KeyType = "custom"       // 自定义密钥
+	KeyTypeSaltMaster     KeyType = "salt_master"     // Salt Master 密钥
+	KeyTypeSaltMinion     KeyType = "salt_minion"     // Salt Minion 密钥
+	KeyTypeMunge          KeyType = "munge"           // Munge 密钥
+	KeyTypeSSHPublic      KeyType = "ssh_public"      // SSH 公钥
+	KeyTypeSSHPrivate     KeyType = "ssh_private"     // SSH 私钥
+	KeyTypeSSHHostKey     KeyType = "ssh_host_key"    // SSH Host Key
+	KeyTypeSSHCA          KeyType = "ssh_ca"          // SSH 证书颁发机构密钥
+	KeyTypeHostCredential KeyType = "host_credential" // 主机 SSH 凭据（统一主机清单）
+	KeyTypeTLSCert        KeyType = "tls_cert"        // TLS 证书
+	KeyTypeTLSKey         KeyType = "tls_key"         // TLS 私钥
+	KeyTypeAPIKey         KeyType = "api_key"         // API 密钥
+	KeyTypeEncryption     KeyType = "encryption"      // 加密密钥
+	KeyTypeCustom         KeyType = "custom"          // 自定义密钥
 )
 
 // KeyVaultEntry 密钥保管库条目
@@ -601,6 +606,93 @@ func (s *KeyVaultService) RotateKey(name string, newKeyData string, userID uint)
 	return s.StoreKey(name, entry.KeyType, newKeyData, entry.Description, nil, userID, entry.ExpiresAt)
 }
 
+// ==================== SSH 证书颁发机构 ====================
+
+const (
+	sshUserCAPrivateKeyName = "ssh_user_ca_private"
+	sshUserCAPublicKeyName  = "ssh_user_ca_public"
+)
+
+// EnsureSSHUserCA 获取 SSH 用户 CA 签名器，不存在时生成新的 ed25519 密钥对
+func (s *KeyVaultService) EnsureSSHUserCA(userID uint) (ssh.Signer, error) {
+	privateKey, _, err := s.GetKey(sshUserCAPrivateKeyName, userID, "", "", "")
+	if err == nil {
+		return ssh.ParsePrivateKey([]byte(privateKey))
+	}
+
+	var count int64
+	s.db.Model(&KeyVaultEntry{}).Where("name = ?", sshUserCAPrivateKeyName).Count(&count)
+	if count > 0 {
+		// 密钥存在但无法读取（过期/解密失败），不能静默覆盖
+		return nil, err
+	}
+	return s.generateSSHUserCA(userID)
+}
+
+// GetSSHUserCAPublicKey 获取 SSH 用户 CA 公钥（authorized_keys 格式）
+func (s *KeyVaultService) GetSSHUserCAPublicKey() (string, error) {
+	publicKey, _, err := s.GetKey(sshUserCAPublicKeyName, 0, "", "", "")
+	if err != nil {
+		return "", err
+	}
+	return publicKey, nil
+}
+
+// RotateSSHUserCA 轮换 SSH 用户 CA，旧密钥通过 RotateKey 保留备份
+func (s *KeyVaultService) RotateSSHUserCA(userID uint) (ssh.Signer, error) {
+	var count int64
+	s.db.Model(&KeyVaultEntry{}).Where("name = ?", sshUserCAPrivateKeyName).Count(&count)
+	if count == 0 {
+		return s.generateSSHUserCA(userID)
+	}
+
+	privatePEM, publicKey, signer, err := newSSHUserCAKeyPair()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.RotateKey(sshUserCAPrivateKeyName, privatePEM, userID); err != nil {
+		return nil, err
+	}
+	if _, err := s.RotateKey(sshUserCAPublicKeyName, publicKey, userID); err != nil {
+		return nil, err
+	}
+	return signer, nil
+}
+
+// generateSSHUserCA 生成并存储新的 SSH 用户 CA 密钥对
+func (s *KeyVaultService) generateSSHUserCA(userID uint) (ssh.Signer, error) {
+	privatePEM, publicKey, signer, err := newSSHUserCAKeyPair()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.StoreKey(sshUserCAPrivateKeyName, KeyTypeSSHCA, privatePEM, "SSH User CA Private Key", nil, userID, nil); err != nil {
+		return nil, err
+	}
+	if _, err := s.StoreKey(sshUserCAPublicKeyName, KeyTypeSSHCA, publicKey, "SSH User CA Public Key", nil, userID, nil); err != nil {
+		return nil, err
+	}
+	logrus.WithField("fingerprint", ssh.FingerprintSHA256(signer.PublicKey())).Info("Generated SSH user CA")
+	return signer, nil
+}
+
+// newSSHUserCAKeyPair 生成 ed25519 CA 密钥对，返回 OpenSSH 私钥、公钥和签名器
+func newSSHUserCAKeyPair() (string, string, ssh.Signer, error) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", nil, fmt.Errorf("failed to generate SSH CA key: %w", err)
+	}
+	block, err := ssh.MarshalPrivateKey(priv, "ai-infra-matrix user ca")
+	if err != nil {
+		return "", "", nil, fmt.Errorf("failed to marshal SSH CA key: %w", err)
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		return "", "", nil, err
+	}
+	publicKey := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(signer.PublicKey())))
+	return string(pem.EncodeToMemory(block)), publicKey, signer, nil
+}
+
 // ==================== 健康检查 ====================
 
 // HealthCheck 服务健康检查
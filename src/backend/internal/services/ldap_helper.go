@@ -2,9 +2,11 @@ package services
 
 import (
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"net"
 	"runtime"
+	"strconv"
 	"strings"
 	"time"
 
@@ -32,7 +34,7 @@ func (h *LDAPConnectionHelper) TestConnectionWithRetry(config *models.LDAPConfig
 			}
 			return result
 		}
-		lastErr = fmt.Errorf(result.Details)
+		lastErr = errors.New(result.Details)
 
 		// 短暂等待后重试
 		if i < maxRetries-1 {
@@ -60,7 +62,7 @@ func (h *LDAPConnectionHelper) testConnectionOnce(config *models.LDAPConfig) *mo
 
 // testConnectionWindows Windows专用连接测试
 func (h *LDAPConnectionHelper) testConnectionWindows(config *models.LDAPConfig) *models.LDAPTestResponse {
-	addr := fmt.Sprintf("%s:%d", config.Server, config.Port)
+	addr := net.JoinHostPort(config.Server, strconv.Itoa(config.Port))
 
 	// Windows环境下使用更长的超时时间
 	timeout := time.Duration(15) * time.Second
@@ -0,0 +1,786 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
+)
+
+const (
+	// mcpProtocolVersion 客户端声明的 MCP 协议版本
+	mcpProtocolVersion = "2025-06-18"
+	// 默认单次请求超时
+	defaultMCPRequestTimeout = 30 * time.Second
+	// 单条 MCP 消息的最大长度
+	maxMCPMessageSize = 8 * 1024 * 1024
+	// 列表接口的最大翻页次数
+	maxMCPListPages = 20
+)
+
+// mcpServerDirs 允许以 stdio 方式启动的 MCP 服务器程序目录（MCP_SERVERS_DIR，冒号分隔）
+func mcpServerDirs() []string {
+	return splitAllowedDirs(getEnvOrDefault("MCP_SERVERS_DIR", "/etc/ai-infra/mcp-servers"))
+}
+
+// mcpBlockedEnvPrefixes/mcpBlockedEnvKeys 禁止通过配置设置的环境变量，防止借动态链接器或解释器注入代码
+var (
+	mcpBlockedEnvPrefixes = []string{"LD_", "DYLD_", "PYTHON", "PERL5", "RUBY", "NODE_", "JAVA_", "_JAVA_", "JDK_JAVA_", "BASH_FUNC_"}
+	mcpBlockedEnvKeys     = map[string]bool{
+		"PATH": true, "HOME": true, "IFS": true, "ENV": true, "BASH_ENV": true, "SHELLOPTS": true, "PS4": true,
+		"PERLLIB": true, "GCONV_PATH": true, "LOCPATH": true, "HOSTALIASES": true, "LOCALDOMAIN": true, "RES_OPTIONS": true,
+	}
+)
+
+// checkMCPServerEnv 校验 stdio 服务器的环境变量名
+func checkMCPServerEnv(env map[string]string) error {
+	for key := range env {
+		if !isMCPEnvName(key) {
+			return fmt.Errorf("invalid env name %q", key)
+		}
+		upper := strings.ToUpper(key)
+		if mcpBlockedEnvKeys[upper] {
+			return fmt.Errorf("env %s is not allowed", key)
+		}
+		for _, prefix := range mcpBlockedEnvPrefixes {
+			if strings.HasPrefix(upper, prefix) {
+				return fmt.Errorf("env %s is not allowed", key)
+			}
+		}
+	}
+	return nil
+}
+
+func isMCPEnvName(key string) bool {
+	if key == "" || (key[0] >= '0' && key[0] <= '9') {
+		return false
+	}
+	for _, r := range key {
+		if !(r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkMCPServerURL 校验 HTTP 服务器地址的协议并确认主机在 MCP_ALLOWED_HOSTS 中
+func checkMCPServerURL(rawURL string) error {
+	return checkAllowedURL(rawURL, "MCP_ALLOWED_HOSTS")
+}
+
+// ValidateMCPConfig 保存配置前校验启用的 MCP 服务器：stdio 程序须位于 MCP_SERVERS_DIR 且不得设置危险的环境变量，HTTP 地址须在 MCP_ALLOWED_HOSTS 中
+func ValidateMCPConfig(config *models.MCPConfig) error {
+	for _, server := range config.EffectiveServers() {
+		var err error
+		switch server.Transport {
+		case models.MCPTransportStdio:
+			if _, err = resolveAllowedPath(server.Command, mcpServerDirs()); err == nil {
+				err = checkMCPServerEnv(server.Env)
+			}
+		case models.MCPTransportHTTP, "":
+			err = checkMCPServerURL(server.URL)
+		default:
+			err = fmt.Errorf("unsupported transport %q", server.Transport)
+		}
+		if err != nil {
+			return fmt.Errorf("mcp server %s: %v", server.Name, err)
+		}
+	}
+	return nil
+}
+
+// MCPServerInfo initialize 返回的服务器信息
+type MCPServerInfo struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// MCPServerCapabilities initialize 返回的服务器能力，仅关心是否提供工具/资源/提示词
+type MCPServerCapabilities struct {
+	Tools     *json.RawMessage `json:"tools,omitempty"`
+	Resources *json.RawMessage `json:"resources,omitempty"`
+	Prompts   *json.RawMessage `json:"prompts,omitempty"`
+}
+
+// MCPToolInfo tools/list 返回的工具
+type MCPToolInfo struct {
+	Name        string                 `json:"name"`
+	Title       string                 `json:"title,omitempty"`
+	Description string                 `json:"description,omitempty"`
+	InputSchema map[string]interface{} `json:"inputSchema"`
+	Annotations *struct {
+		ReadOnlyHint    *bool `json:"readOnlyHint,omitempty"`
+		DestructiveHint *bool `json:"destructiveHint,omitempty"`
+	} `json:"annotations,omitempty"`
+}
+
+// ReadOnly 服务器是否声明该工具只读
+func (t *MCPToolInfo) ReadOnly() bool {
+	return t.Annotations != nil && t.Annotations.ReadOnlyHint != nil && *t.Annotations.ReadOnlyHint
+}
+
+// MCPResourceInfo resources/list 返回的资源
+type MCPResourceInfo struct {
+	URI         string `json:"uri"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType,omitempty"`
+}
+
+// MCPPromptInfo prompts/list 返回的提示词模板
+type MCPPromptInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Arguments   []struct {
+		Name        string `json:"name"`
+		Description string `json:"description,omitempty"`
+		Required    bool   `json:"required,omitempty"`
+	} `json:"arguments,omitempty"`
+}
+
+// MCPContent 工具结果、资源内容与提示词消息中的内容块
+type MCPContent struct {
+	Type     string          `json:"type"`
+	Text     string          `json:"text,omitempty"`
+	MimeType string          `json:"mimeType,omitempty"`
+	URI      string          `json:"uri,omitempty"`
+	Resource json.RawMessage `json:"resource,omitempty"`
+}
+
+// MCPToolResult tools/call 的返回
+type MCPToolResult struct {
+	Content           []MCPContent    `json:"content"`
+	StructuredContent json.RawMessage `json:"structuredContent,omitempty"`
+	IsError           bool            `json:"isError,omitempty"`
+}
+
+// Text 拼接结果中的文本内容，非文本块以类型占位
+func (r *MCPToolResult) Text() string {
+	parts := make([]string, 0, len(r.Content))
+	for _, c := range r.Content {
+		switch c.Type {
+		case "text":
+			parts = append(parts, c.Text)
+		case "resource":
+			parts = append(parts, string(c.Resource))
+		default:
+			parts = append(parts, fmt.Sprintf("[%s %s]", c.Type, c.MimeType))
+		}
+	}
+	if len(parts) == 0 && len(r.StructuredContent) > 0 {
+		return string(r.StructuredContent)
+	}
+	return strings.Join(parts, "\n")
+}
+
+type mcpMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      *int64          `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  interface{}     `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *mcpError       `json:"error,omitempty"`
+}
+
+// 服务端发来的请求，ID 可能为字符串
+type mcpIncoming struct {
+	ID     json.RawMessage `json:"id,omitempty"`
+	Method string          `json:"method,omitempty"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  *mcpError       `json:"error,omitempty"`
+}
+
+type mcpError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *mcpError) Error() string {
+	return fmt.Sprintf("mcp error %d: %s", e.Code, e.Message)
+}
+
+// mcpTransport MCP 传输层：发送请求并等待同 ID 的响应，或发送通知
+type mcpTransport interface {
+	call(ctx context.Context, msg *mcpMessage) (json.RawMessage, error)
+	notify(ctx context.Context, msg *mcpMessage) error
+	close() error
+}
+
+// MCPClient 一个已初始化的 MCP 服务器会话
+type MCPClient struct {
+	Server       models.MCPServerConfig
+	Info         MCPServerInfo
+	Capabilities MCPServerCapabilities
+
+	transport mcpTransport
+	timeout   time.Duration
+	nextID    atomic.Int64
+}
+
+// DialMCPServer 连接 MCP 服务器并完成 initialize 握手
+func DialMCPServer(ctx context.Context, server models.MCPServerConfig) (*MCPClient, error) {
+	if server.Name == "" {
+		return nil, errors.New("mcp server name is required")
+	}
+	timeout := defaultMCPRequestTimeout
+	if server.TimeoutSeconds > 0 {
+		timeout = time.Duration(server.TimeoutSeconds) * time.Second
+	}
+
+	var transport mcpTransport
+	var err error
+	switch server.Transport {
+	case models.MCPTransportStdio:
+		transport, err = newMCPStdioTransport(server)
+	case models.MCPTransportHTTP, "":
+		transport, err = newMCPHTTPTransport(server, timeout)
+	default:
+		err = fmt.Errorf("unsupported mcp transport: %s", server.Transport)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	client := &MCPClient{Server: server, transport: transport, timeout: timeout}
+	var init struct {
+		ProtocolVersion string                `json:"protocolVersion"`
+		Capabilities    MCPServerCapabilities `json:"capabilities"`
+		ServerInfo      MCPServerInfo         `json:"serverInfo"`
+	}
+	err = client.request(ctx, "initialize", map[string]interface{}{
+		"protocolVersion": mcpProtocolVersion,
+		"capabilities":    map[string]interface{}{},
+		"clientInfo":      map[string]string{"name": "ai-infra-matrix", "version": "1.0"},
+	}, &init)
+	if err != nil {
+		transport.close()
+		return nil, fmt.Errorf("initialize mcp server %s: %v", server.Name, err)
+	}
+	client.Info = init.ServerInfo
+	client.Capabilities = init.Capabilities
+	if ht, ok := transport.(*mcpHTTPTransport); ok {
+		ht.protocolVersion = init.ProtocolVersion
+	}
+	if err := transport.notify(ctx, &mcpMessage{JSONRPC: "2.0", Method: "notifications/initialized"}); err != nil {
+		transport.close()
+		return nil, fmt.Errorf("initialize mcp server %s: %v", server.Name, err)
+	}
+	return client, nil
+}
+
+// Close 关闭会话（stdio 服务器进程随之退出）
+func (c *MCPClient) Close() error {
+	return c.transport.close()
+}
+
+func (c *MCPClient) request(ctx context.Context, method string, params interface{}, out interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	id := c.nextID.Add(1)
+	result, err := c.transport.call(ctx, &mcpMessage{JSONRPC: "2.0", ID: &id, Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(result, out)
+}
+
+// ListTools 列出服务器提供的全部工具
+func (c *MCPClient) ListTools(ctx context.Context) ([]MCPToolInfo, error) {
+	if c.Capabilities.Tools == nil {
+		return nil, nil
+	}
+	var tools []MCPToolInfo
+	cursor := ""
+	for page := 0; page < maxMCPListPages; page++ {
+		var resp struct {
+			Tools      []MCPToolInfo `json:"tools"`
+			NextCursor string        `json:"nextCursor"`
+		}
+		if err := c.request(ctx, "tools/list", mcpCursorParams(cursor), &resp); err != nil {
+			return nil, err
+		}
+		tools = append(tools, resp.Tools...)
+		if cursor = resp.NextCursor; cursor == "" {
+			break
+		}
+	}
+	return tools, nil
+}
+
+// ListResources 列出服务器提供的资源
+func (c *MCPClient) ListResources(ctx context.Context) ([]MCPResourceInfo, error) {
+	if c.Capabilities.Resources == nil {
+		return nil, nil
+	}
+	var resources []MCPResourceInfo
+	cursor := ""
+	for page := 0; page < maxMCPListPages; page++ {
+		var resp struct {
+			Resources  []MCPResourceInfo `json:"resources"`
+			NextCursor string            `json:"nextCursor"`
+		}
+		if err := c.request(ctx, "resources/list", mcpCursorParams(cursor), &resp); err != nil {
+			return nil, err
+		}
+		resources = append(resources, resp.Resources...)
+		if cursor = resp.NextCursor; cursor == "" {
+			break
+		}
+	}
+	return resources, nil
+}
+
+// ListPrompts 列出服务器提供的提示词模板
+func (c *MCPClient) ListPrompts(ctx context.Context) ([]MCPPromptInfo, error) {
+	if c.Capabilities.Prompts == nil {
+		return nil, nil
+	}
+	var prompts []MCPPromptInfo
+	cursor := ""
+	for page := 0; page < maxMCPListPages; page++ {
+		var resp struct {
+			Prompts    []MCPPromptInfo `json:"prompts"`
+			NextCursor string          `json:"nextCursor"`
+		}
+		if err := c.request(ctx, "prompts/list", mcpCursorParams(cursor), &resp); err != nil {
+			return nil, err
+		}
+		prompts = append(prompts, resp.Prompts...)
+		if cursor = resp.NextCursor; cursor == "" {
+			break
+		}
+	}
+	return prompts, nil
+}
+
+// CallTool 调用工具
+func (c *MCPClient) CallTool(ctx context.Context, name string, arguments json.RawMessage) (*MCPToolResult, error) {
+	if len(arguments) == 0 {
+		arguments = json.RawMessage("{}")
+	}
+	var result MCPToolResult
+	if err := c.request(ctx, "tools/call", map[string]interface{}{"name": name, "arguments": arguments}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ReadResource 读取资源内容
+func (c *MCPClient) ReadResource(ctx context.Context, uri string) ([]MCPContent, error) {
+	var resp struct {
+		Contents []MCPContent `json:"contents"`
+	}
+	if err := c.request(ctx, "resources/read", map[string]string{"uri": uri}, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Contents, nil
+}
+
+// GetPrompt 按参数展开提示词模板
+func (c *MCPClient) GetPrompt(ctx context.Context, name string, arguments map[string]string) (string, error) {
+	var resp struct {
+		Messages []struct {
+			Role    string     `json:"role"`
+			Content MCPContent `json:"content"`
+		} `json:"messages"`
+	}
+	if err := c.request(ctx, "prompts/get", map[string]interface{}{"name": name, "arguments": arguments}, &resp); err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	for _, m := range resp.Messages {
+		fmt.Fprintf(&b, "[%s] %s\n", m.Role, m.Content.Text)
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+func mcpCursorParams(cursor string) interface{} {
+	if cursor == "" {
+		return map[string]interface{}{}
+	}
+	return map[string]string{"cursor": cursor}
+}
+
+// ==================== stdio 传输 ====================
+
+// mcpStdioTransport 通过子进程 stdin/stdout 以换行分隔的 JSON-RPC 消息通信
+type mcpStdioTransport struct {
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	writeMu sync.Mutex
+
+	mu      sync.Mutex
+	pending map[int64]chan *mcpIncoming
+	done    chan struct{}
+	err     error
+}
+
+func newMCPStdioTransport(server models.MCPServerConfig) (*mcpStdioTransport, error) {
+	command, err := resolveAllowedPath(server.Command, mcpServerDirs())
+	if err != nil {
+		return nil, fmt.Errorf("mcp server %s: %v", server.Name, err)
+	}
+	if err := checkMCPServerEnv(server.Env); err != nil {
+		return nil, fmt.Errorf("mcp server %s: %v", server.Name, err)
+	}
+
+	cmd := exec.Command(command, server.Args...)
+	cmd.Dir = filepath.Dir(command)
+	cmd.Env = []string{"PATH=" + os.Getenv("PATH"), "HOME=" + os.Getenv("HOME")}
+	for k, v := range server.Env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	cmd.Stderr = &mcpStderrLogger{server: server.Name}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start mcp server %s: %v", server.Name, err)
+	}
+
+	t := &mcpStdioTransport{
+		cmd:     cmd,
+		stdin:   stdin,
+		pending: make(map[int64]chan *mcpIncoming),
+		done:    make(chan struct{}),
+	}
+	go t.readLoop(stdout)
+	return t, nil
+}
+
+func (t *mcpStdioTransport) readLoop(stdout io.Reader) {
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), maxMCPMessageSize)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var msg mcpIncoming
+		if err := json.Unmarshal(line, &msg); err != nil {
+			continue
+		}
+		if msg.Method != "" {
+			// 服务端请求（如 ping）或通知
+			if len(msg.ID) > 0 {
+				t.write(mcpReplyToServer(msg))
+			}
+			continue
+		}
+		var id int64
+		if err := json.Unmarshal(msg.ID, &id); err != nil {
+			continue
+		}
+		t.mu.Lock()
+		ch, ok := t.pending[id]
+		delete(t.pending, id)
+		t.mu.Unlock()
+		if ok {
+			ch <- &msg
+		}
+	}
+
+	t.mu.Lock()
+	t.err = scanner.Err()
+	if t.err == nil {
+		t.err = errors.New("mcp server closed the connection")
+	}
+	t.mu.Unlock()
+	close(t.done)
+}
+
+func (t *mcpStdioTransport) write(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	_, err = t.stdin.Write(append(data, '\n'))
+	return err
+}
+
+func (t *mcpStdioTransport) call(ctx context.Context, msg *mcpMessage) (json.RawMessage, error) {
+	ch := make(chan *mcpIncoming, 1)
+	t.mu.Lock()
+	t.pending[*msg.ID] = ch
+	t.mu.Unlock()
+	defer func() {
+		t.mu.Lock()
+		delete(t.pending, *msg.ID)
+		t.mu.Unlock()
+	}()
+
+	if err := t.write(msg); err != nil {
+		return nil, err
+	}
+	select {
+	case resp := <-ch:
+		if resp.Error != nil {
+			return nil, resp.Error
+		}
+		return resp.Result, nil
+	case <-t.done:
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		return nil, t.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (t *mcpStdioTransport) notify(ctx context.Context, msg *mcpMessage) error {
+	return t.write(msg)
+}
+
+func (t *mcpStdioTransport) close() error {
+	t.stdin.Close()
+	select {
+	case <-t.done:
+	case <-time.After(2 * time.Second):
+		t.cmd.Process.Kill()
+	}
+	return t.cmd.Wait()
+}
+
+// mcpStderrLogger 将 stdio 服务器的 stderr 输出写入日志
+type mcpStderrLogger struct {
+	server string
+}
+
+func (l *mcpStderrLogger) Write(p []byte) (int, error) {
+	for _, line := range strings.Split(strings.TrimSpace(string(p)), "\n") {
+		if line != "" {
+			log.Printf("[MCP %s] %s", l.server, truncateText(line, 500))
+		}
+	}
+	return len(p), nil
+}
+
+// mcpReplyToServer 响应服务端发来的请求：ping 返回空结果，其余请求（采样、根目录等）客户端未声明支持
+func mcpReplyToServer(msg mcpIncoming) map[string]interface{} {
+	reply := map[string]interface{}{"jsonrpc": "2.0", "id": msg.ID}
+	if msg.Method == "ping" {
+		reply["result"] = map[string]interface{}{}
+	} else {
+		reply["error"] = mcpError{Code: -32601, Message: "method not supported by client: " + msg.Method}
+	}
+	return reply
+}
+
+// ==================== Streamable HTTP 传输 ====================
+
+// mcpHTTPTransport 每条消息以 POST 发送，响应为 JSON 或 SSE 事件流
+type mcpHTTPTransport struct {
+	endpoint        string
+	headers         map[string]string
+	client          *http.Client
+	protocolVersion string
+
+	mu        sync.Mutex
+	sessionID string
+}
+
+func newMCPHTTPTransport(server models.MCPServerConfig, timeout time.Duration) (*mcpHTTPTransport, error) {
+	if err := checkMCPServerURL(server.URL); err != nil {
+		return nil, fmt.Errorf("mcp server %s: %v", server.Name, err)
+	}
+	return &mcpHTTPTransport{
+		endpoint: server.URL,
+		headers:  server.Headers,
+		client:   &http.Client{Timeout: timeout, CheckRedirect: checkMCPRedirect},
+	}, nil
+}
+
+// checkMCPRedirect 每次重定向都重新校验目标地址，防止经允许的主机跳转到内网地址
+func checkMCPRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+	if err := checkMCPServerURL(req.URL.String()); err != nil {
+		return fmt.Errorf("redirect rejected: %v", err)
+	}
+	return nil
+}
+
+func (t *mcpHTTPTransport) post(ctx context.Context, msg *mcpMessage) (*http.Response, error) {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	if t.protocolVersion != "" {
+		req.Header.Set("MCP-Protocol-Version", t.protocolVersion)
+	}
+	t.mu.Lock()
+	if t.sessionID != "" {
+		req.Header.Set("Mcp-Session-Id", t.sessionID)
+	}
+	t.mu.Unlock()
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if sid := resp.Header.Get("Mcp-Session-Id"); sid != "" {
+		t.mu.Lock()
+		t.sessionID = sid
+		t.mu.Unlock()
+	}
+	if resp.StatusCode >= 300 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		resp.Body.Close()
+		return nil, fmt.Errorf("mcp endpoint returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	return resp, nil
+}
+
+func (t *mcpHTTPTransport) call(ctx context.Context, msg *mcpMessage) (json.RawMessage, error) {
+	resp, err := t.post(ctx, msg)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		return readMCPEventStream(resp.Body, *msg.ID)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxMCPMessageSize))
+	if err != nil {
+		return nil, err
+	}
+	return matchMCPResponse(data, *msg.ID)
+}
+
+func (t *mcpHTTPTransport) notify(ctx context.Context, msg *mcpMessage) error {
+	resp, err := t.post(ctx, msg)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// close 通知服务器结束会话（服务器不支持时忽略）
+func (t *mcpHTTPTransport) close() error {
+	t.mu.Lock()
+	sid := t.sessionID
+	t.mu.Unlock()
+	if sid == "" {
+		return nil
+	}
+	req, err := http.NewRequest(http.MethodDelete, t.endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Mcp-Session-Id", sid)
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// readMCPEventStream 读取 SSE 事件流，直到收到指定 ID 的响应
+func readMCPEventStream(r io.Reader, id int64) (json.RawMessage, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxMCPMessageSize)
+	var data strings.Builder
+	flush := func() (json.RawMessage, bool, error) {
+		if data.Len() == 0 {
+			return nil, false, nil
+		}
+		payload := data.String()
+		data.Reset()
+		result, err := matchMCPResponse([]byte(payload), id)
+		if errors.Is(err, errMCPNoMatch) {
+			return nil, false, nil
+		}
+		return result, true, err
+	}
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if result, ok, err := flush(); ok {
+				return result, err
+			}
+		case strings.HasPrefix(line, "data:"):
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	if result, ok, err := flush(); ok {
+		return result, err
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, errors.New("mcp event stream ended without a response")
+}
+
+var errMCPNoMatch = errors.New("no matching mcp response")
+
+// matchMCPResponse 从单条消息或批量消息中取出指定 ID 的响应
+func matchMCPResponse(data []byte, id int64) (json.RawMessage, error) {
+	data = bytes.TrimSpace(data)
+	var messages []mcpIncoming
+	if len(data) > 0 && data[0] == '[' {
+		if err := json.Unmarshal(data, &messages); err != nil {
+			return nil, fmt.Errorf("invalid mcp response: %v", err)
+		}
+	} else {
+		var msg mcpIncoming
+		if err := json.Unmarshal(data, &msg); err != nil {
+			return nil, fmt.Errorf("invalid mcp response: %v", err)
+		}
+		messages = append(messages, msg)
+	}
+	for _, msg := range messages {
+		var msgID int64
+		if msg.Method != "" || json.Unmarshal(msg.ID, &msgID) != nil || msgID != id {
+			continue
+		}
+		if msg.Error != nil {
+			return nil, msg.Error
+		}
+		return msg.Result, nil
+	}
+	return nil, errMCPNoMatch
+}
@@ -0,0 +1,564 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	mcpServerName    = "ai-infra-matrix"
+	mcpServerVersion = "1.0"
+
+	mcpResourceJobTemplates = "aiinfra://job-templates"
+	mcpResourceClusters     = "aiinfra://clusters"
+
+	// JSON-RPC 错误码
+	mcpCodeParseError     = -32700
+	mcpCodeInvalidRequest = -32600
+	mcpCodeMethodNotFound = -32601
+	mcpCodeInvalidParams  = -32602
+)
+
+// mcpServerSupportedVersions 服务端接受的 MCP 协议版本，客户端请求其他版本时返回最新版本
+var mcpServerSupportedVersions = []string{mcpProtocolVersion, "2025-03-26", "2024-11-05"}
+
+// mcpServerSharedTools 对外暴露的 AI 助手只读工具，权限校验与 AI 助手一致
+var mcpServerSharedTools = []string{"slurm_list_jobs", "slurm_list_nodes", "salt_run"}
+
+// Slurm 内存与时间限制参数，仅允许数字、字母及 : . - 字符，防止注入作业脚本
+var mcpSlurmValuePattern = regexp.MustCompile(`^[0-9A-Za-z:.-]{1,32}$`)
+
+// MCPServerService 平台自身的 MCP 服务端：以个人 API 令牌认证的用户身份，
+// 向外部代理提供经过筛选的工具与资源，权限校验与 REST API 相同
+type MCPServerService struct {
+	db        *gorm.DB
+	perms     *ClusterPermissionService
+	templates *JobTemplateService
+	jobs      *JobService
+	tools     []*AITool
+}
+
+var (
+	mcpServerServiceInstance *MCPServerService
+	mcpServerServiceOnce     sync.Once
+)
+
+// NewMCPServerService 创建平台 MCP 服务端（单例）
+func NewMCPServerService(db *gorm.DB, jobService *JobService) *MCPServerService {
+	mcpServerServiceOnce.Do(func() {
+		s := &MCPServerService{
+			db:        db,
+			perms:     NewClusterPermissionService(db),
+			templates: NewJobTemplateService(db),
+			jobs:      jobService,
+		}
+		toolService := NewAIToolService(db)
+		for _, name := range mcpServerSharedTools {
+			if tool, ok := toolService.Tool(name); ok {
+				s.tools = append(s.tools, tool)
+			}
+		}
+		s.tools = append(s.tools, s.platformTools()...)
+		mcpServerServiceInstance = s
+	})
+	return mcpServerServiceInstance
+}
+
+// GetMCPServerService 获取平台 MCP 服务端实例
+func GetMCPServerService() *MCPServerService {
+	return mcpServerServiceInstance
+}
+
+// ResolveCaller 解析 API 令牌所属用户，与 AI 助手内调用工具时的身份判定一致
+func (s *MCPServerService) ResolveCaller(userID uint) AIToolCaller {
+	return resolveAIToolCaller(s.db, userID)
+}
+
+type mcpServerRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// MCPServerResponse JSON-RPC 响应
+type MCPServerResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *mcpError       `json:"error,omitempty"`
+}
+
+// Handle 处理一条 JSON-RPC 消息；通知与客户端响应返回 nil
+func (s *MCPServerService) Handle(ctx context.Context, caller AIToolCaller, clientIP string, body []byte) *MCPServerResponse {
+	var req mcpServerRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return &MCPServerResponse{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &mcpError{Code: mcpCodeParseError, Message: "parse error"}}
+	}
+	if len(req.ID) == 0 || req.Method == "" {
+		return nil
+	}
+	if req.JSONRPC != "2.0" {
+		return &MCPServerResponse{JSONRPC: "2.0", ID: req.ID, Error: &mcpError{Code: mcpCodeInvalidRequest, Message: "jsonrpc must be 2.0"}}
+	}
+
+	result, rpcErr := s.dispatch(ctx, caller, clientIP, req)
+	resp := &MCPServerResponse{JSONRPC: "2.0", ID: req.ID, Result: result, Error: rpcErr}
+	if rpcErr == nil && result == nil {
+		resp.Result = map[string]interface{}{}
+	}
+	return resp
+}
+
+func (s *MCPServerService) dispatch(ctx context.Context, caller AIToolCaller, clientIP string, req mcpServerRequest) (interface{}, *mcpError) {
+	switch req.Method {
+	case "initialize":
+		var params struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		}
+		_ = json.Unmarshal(req.Params, &params)
+		version := mcpProtocolVersion
+		if containsString(mcpServerSupportedVersions, params.ProtocolVersion) {
+			version = params.ProtocolVersion
+		}
+		return map[string]interface{}{
+			"protocolVersion": version,
+			"capabilities": map[string]interface{}{
+				"tools":     map[string]interface{}{"listChanged": false},
+				"resources": map[string]interface{}{},
+			},
+			"serverInfo":   map[string]string{"name": mcpServerName, "version": mcpServerVersion},
+			"instructions": "AI Infra Matrix 平台工具：查询 Slurm 队列与节点、使用作业模板提交作业、执行只读 Salt 查询、检索审计日志。所有操作以令牌所属用户的权限执行。",
+		}, nil
+
+	case "ping":
+		return map[string]interface{}{}, nil
+
+	case "tools/list":
+		tools := make([]map[string]interface{}, 0, len(s.tools))
+		for _, tool := range s.tools {
+			tools = append(tools, map[string]interface{}{
+				"name":        tool.Name,
+				"description": tool.Description,
+				"inputSchema": tool.Parameters,
+				"annotations": map[string]interface{}{
+					"readOnlyHint":    !tool.Mutating,
+					"destructiveHint": false,
+					"openWorldHint":   false,
+				},
+			})
+		}
+		return map[string]interface{}{"tools": tools}, nil
+
+	case "tools/call":
+		var params struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, &mcpError{Code: mcpCodeInvalidParams, Message: "invalid params"}
+		}
+		tool := s.tool(params.Name)
+		if tool == nil {
+			return nil, &mcpError{Code: mcpCodeInvalidParams, Message: "unknown tool: " + params.Name}
+		}
+		return s.callTool(ctx, caller, clientIP, tool, params.Arguments), nil
+
+	case "resources/list":
+		return map[string]interface{}{"resources": []map[string]string{
+			{"uri": mcpResourceJobTemplates, "name": "job-templates", "description": "当前用户可用的作业模板（自己的与公开的）", "mimeType": "application/json"},
+			{"uri": mcpResourceClusters, "name": "clusters", "description": "可提交作业的集群", "mimeType": "application/json"},
+		}}, nil
+
+	case "resources/templates/list":
+		return map[string]interface{}{"resourceTemplates": []map[string]string{
+			{"uriTemplate": mcpResourceJobTemplates + "/{id}", "name": "job-template", "description": "作业模板详情，含完整 sbatch 脚本", "mimeType": "application/json"},
+		}}, nil
+
+	case "resources/read":
+		var params struct {
+			URI string `json:"uri"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil || params.URI == "" {
+			return nil, &mcpError{Code: mcpCodeInvalidParams, Message: "uri is required"}
+		}
+		data, err := s.readResource(ctx, caller, params.URI)
+		if err != nil {
+			return nil, &mcpError{Code: mcpCodeInvalidParams, Message: err.Error()}
+		}
+		return map[string]interface{}{"contents": []map[string]string{
+			{"uri": params.URI, "mimeType": "application/json", "text": aiToolResultJSON(data)},
+		}}, nil
+
+	default:
+		return nil, &mcpError{Code: mcpCodeMethodNotFound, Message: "method not found: " + req.Method}
+	}
+}
+
+func (s *MCPServerService) tool(name string) *AITool {
+	for _, tool := range s.tools {
+		if tool.Name == name {
+			return tool
+		}
+	}
+	return nil
+}
+
+// callTool 校验权限后执行工具；权限不足与执行失败作为工具错误结果返回，便于代理据此调整
+func (s *MCPServerService) callTool(ctx context.Context, caller AIToolCaller, clientIP string, tool *AITool, args json.RawMessage) map[string]interface{} {
+	if len(args) == 0 || string(args) == "null" {
+		args = json.RawMessage("{}")
+	}
+	toolError := func(err error) map[string]interface{} {
+		s.audit(tool, caller, clientIP, string(args), models.AuditStatusFailed, err)
+		return map[string]interface{}{
+			"content": []map[string]string{{"type": "text", "text": err.Error()}},
+			"isError": true,
+		}
+	}
+
+	if err := tool.Authorize(ctx, caller, args); err != nil {
+		return toolError(err)
+	}
+	runCtx, cancel := context.WithTimeout(ctx, aiToolRunTimeout)
+	defer cancel()
+	result, err := tool.Run(runCtx, caller, args)
+	if err != nil {
+		return toolError(err)
+	}
+	s.audit(tool, caller, clientIP, string(args), models.AuditStatusSuccess, nil)
+	return map[string]interface{}{
+		"content": []map[string]string{{"type": "text", "text": aiToolResultJSON(result)}},
+	}
+}
+
+func (s *MCPServerService) audit(tool *AITool, caller AIToolCaller, clientIP, args string, status models.AuditStatus, err error) {
+	entry := GetAuditService().NewAuditEntry(tool.Category, models.AuditActionExecute).
+		WithUser(caller.UserID, caller.Username, "").
+		WithResource("mcp_tool", "", tool.Name).
+		WithClient(clientIP, "", "").
+		WithRequestParams(args).
+		WithStatus(status).
+		WithTags("mcp", "mcp_server")
+	if err != nil {
+		entry = entry.WithErrorMessage(err.Error())
+	}
+	entry.SaveAsync()
+}
+
+func (s *MCPServerService) readResource(ctx context.Context, caller AIToolCaller, uri string) (interface{}, error) {
+	switch {
+	case uri == mcpResourceJobTemplates:
+		templates, _, err := s.templates.ListTemplates(ctx, caller.UserID, "", nil, 1, aiToolMaxItems)
+		if err != nil {
+			return nil, err
+		}
+		summaries := make([]map[string]interface{}, 0, len(templates))
+		for _, t := range templates {
+			summaries = append(summaries, mcpJobTemplateSummary(&t))
+		}
+		return map[string]interface{}{"templates": summaries}, nil
+
+	case uri == mcpResourceClusters:
+		if s.jobs == nil {
+			return nil, errors.New("job service unavailable")
+		}
+		clusters, err := s.jobs.ListClusters(ctx)
+		if err != nil {
+			return nil, err
+		}
+		items := make([]map[string]string, 0, len(clusters))
+		for _, c := range clusters {
+			items = append(items, map[string]string{"id": c.ID, "name": c.Name, "description": c.Description, "status": c.Status})
+		}
+		return map[string]interface{}{"clusters": items}, nil
+
+	case strings.HasPrefix(uri, mcpResourceJobTemplates+"/"):
+		id, err := strconv.ParseUint(strings.TrimPrefix(uri, mcpResourceJobTemplates+"/"), 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template uri: %s", uri)
+		}
+		template, err := s.templates.GetTemplate(ctx, uint(id), caller.UserID)
+		if err != nil {
+			return nil, err
+		}
+		summary := mcpJobTemplateSummary(template)
+		summary["script"] = template.Script
+		summary["command"] = template.Command
+		summary["working_dir"] = template.WorkingDir
+		return summary, nil
+	}
+	return nil, fmt.Errorf("unknown resource: %s", uri)
+}
+
+func mcpJobTemplateSummary(t *models.JobTemplate) map[string]interface{} {
+	return map[string]interface{}{
+		"id":          t.ID,
+		"uri":         fmt.Sprintf("%s/%d", mcpResourceJobTemplates, t.ID),
+		"name":        t.Name,
+		"description": t.Description,
+		"category":    t.Category,
+		"partition":   t.Partition,
+		"nodes":       t.Nodes,
+		"cpus":        t.CPUs,
+		"memory":      t.Memory,
+		"time_limit":  t.TimeLimit,
+		"is_public":   t.IsPublic,
+	}
+}
+
+// mcpTemplateJobArgs 按模板提交作业的参数，未给出的资源参数沿用模板
+type mcpTemplateJobArgs struct {
+	TemplateID uint   `json:"template_id"`
+	ClusterID  string `json:"cluster_id"`
+	Name       string `json:"name"`
+	Partition  string `json:"partition"`
+	Nodes      int    `json:"nodes"`
+	CPUs       int    `json:"cpus"`
+	Memory     string `json:"memory"`
+	TimeLimit  string `json:"time_limit"`
+}
+
+// resolveTemplateJob 读取模板并合并覆盖参数，校验会写入作业脚本的字段
+func (s *MCPServerService) resolveTemplateJob(ctx context.Context, caller AIToolCaller, raw json.RawMessage) (*models.SubmitJobRequest, error) {
+	var args mcpTemplateJobArgs
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, err
+	}
+	if args.TemplateID == 0 || args.ClusterID == "" {
+		return nil, errors.New("template_id and cluster_id are required")
+	}
+	template, err := s.templates.GetTemplate(ctx, args.TemplateID, caller.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &models.SubmitJobRequest{
+		UserID:     strconv.FormatUint(uint64(caller.UserID), 10),
+		ClusterID:  args.ClusterID,
+		Name:       firstNonEmpty(args.Name, template.Name),
+		Command:    firstNonEmpty(template.Command, template.Script),
+		WorkingDir: template.WorkingDir,
+		Partition:  firstNonEmpty(args.Partition, template.Partition),
+		Nodes:      template.Nodes,
+		CPUs:       template.CPUs,
+		Memory:     firstNonEmpty(args.Memory, template.Memory),
+		TimeLimit:  firstNonEmpty(args.TimeLimit, template.TimeLimit),
+		TemplateID: template.ID,
+	}
+	if args.Nodes > 0 {
+		req.Nodes = args.Nodes
+	}
+	if args.CPUs > 0 {
+		req.CPUs = args.CPUs
+	}
+
+	if req.Command == "" {
+		return nil, errors.New("template has no command or script")
+	}
+	if !aiToolNamePattern.MatchString(req.Name) || len(req.Name) > 100 {
+		return nil, fmt.Errorf("invalid job name: %q", req.Name)
+	}
+	if req.Partition != "" && !aiToolNamePattern.MatchString(req.Partition) {
+		return nil, fmt.Errorf("invalid partition: %q", req.Partition)
+	}
+	for field, value := range map[string]string{"memory": req.Memory, "time_limit": req.TimeLimit} {
+		if value != "" && !mcpSlurmValuePattern.MatchString(value) {
+			return nil, fmt.Errorf("invalid %s: %q", field, value)
+		}
+	}
+	return req, nil
+}
+
+// platformTools MCP 服务端专有工具：作业模板、按模板提交作业、审计日志检索
+func (s *MCPServerService) platformTools() []*AITool {
+	return []*AITool{
+		{
+			Name:        "job_template_list",
+			Description: "列出当前用户可用的 Slurm 作业模板（自己的与公开的），用于 slurm_submit_job_from_template",
+			Category:    models.AuditCategorySlurm,
+			Parameters: aiToolSchema(map[string]interface{}{
+				"category": aiToolString("按模板分类过滤"),
+			}),
+			Authorize: func(ctx context.Context, caller AIToolCaller, raw json.RawMessage) error { return nil },
+			Run: func(ctx context.Context, caller AIToolCaller, raw json.RawMessage) (interface{}, error) {
+				var args struct{ Category string }
+				if err := json.Unmarshal(raw, &args); err != nil {
+					return nil, err
+				}
+				templates, total, err := s.templates.ListTemplates(ctx, caller.UserID, args.Category, nil, 1, aiToolMaxItems)
+				if err != nil {
+					return nil, err
+				}
+				summaries := make([]map[string]interface{}, 0, len(templates))
+				for _, t := range templates {
+					summaries = append(summaries, mcpJobTemplateSummary(&t))
+				}
+				return map[string]interface{}{"total": total, "templates": summaries}, nil
+			},
+		},
+		{
+			Name:        "slurm_submit_job_from_template",
+			Description: "使用作业模板提交 Slurm 作业，可覆盖作业名、分区、节点数、CPU、内存和时间限制；脚本内容取自模板，不可修改",
+			Mutating:    true,
+			Category:    models.AuditCategorySlurm,
+			Parameters: aiToolSchema(map[string]interface{}{
+				"template_id": map[string]interface{}{"type": "integer", "description": "作业模板 ID"},
+				"cluster_id":  aiToolString("目标集群 ID，见资源 " + mcpResourceClusters),
+				"name":        aiToolString("作业名，默认使用模板名"),
+				"partition":   aiToolString("分区，默认使用模板分区"),
+				"nodes":       map[string]interface{}{"type": "integer", "minimum": 1},
+				"cpus":        map[string]interface{}{"type": "integer", "minimum": 1, "description": "每任务 CPU 数"},
+				"memory":      aiToolString("内存，如 4G"),
+				"time_limit":  aiToolString("时间限制，如 01:00:00"),
+			}, "template_id", "cluster_id"),
+			Authorize: func(ctx context.Context, caller AIToolCaller, raw json.RawMessage) error {
+				req, err := s.resolveTemplateJob(ctx, caller, raw)
+				if err != nil {
+					return err
+				}
+				var cluster models.Cluster
+				if err := s.db.Where("id = ? AND status = ?", req.ClusterID, "active").First(&cluster).Error; err != nil {
+					return fmt.Errorf("cluster %s not found or inactive", req.ClusterID)
+				}
+				if caller.IsAdmin {
+					return nil
+				}
+				result, err := s.perms.CheckSlurmAccess(ctx, caller.UserID, defaultSlurmClusterID, models.VerbSubmit, req.Partition)
+				if err != nil {
+					return err
+				}
+				if !result.Allowed {
+					return fmt.Errorf("permission denied: %s", result.Reason)
+				}
+				if limits := result.ResourceLimits; limits != nil && limits.MaxCPUs > 0 && req.Nodes*req.CPUs > limits.MaxCPUs {
+					return fmt.Errorf("requested %d cpus exceeds limit %d", req.Nodes*req.CPUs, limits.MaxCPUs)
+				}
+				return nil
+			},
+			Run: func(ctx context.Context, caller AIToolCaller, raw json.RawMessage) (interface{}, error) {
+				if s.jobs == nil {
+					return nil, errors.New("job service unavailable")
+				}
+				req, err := s.resolveTemplateJob(ctx, caller, raw)
+				if err != nil {
+					return nil, err
+				}
+				job, err := s.jobs.SubmitJob(ctx, req, caller.UserID)
+				if err != nil {
+					return nil, err
+				}
+				return map[string]interface{}{
+					"id":         job.ID,
+					"name":       job.Name,
+					"cluster_id": job.ClusterID,
+					"status":     job.Status,
+					"message":    "作业已提交，通过 slurm_list_jobs 查询排队状态",
+				}, nil
+			},
+		},
+		{
+			Name:        "audit_search",
+			Description: "检索平台审计日志；非管理员只能查询自己的操作记录",
+			Category:    models.AuditCategorySecurity,
+			Parameters: aiToolSchema(map[string]interface{}{
+				"category":      aiToolString("审计类别，如 slurm、saltstack、ansible、security"),
+				"action":        aiToolString("操作动作，如 execute、create、delete"),
+				"status":        aiToolString("状态：success、failed"),
+				"resource_type": aiToolString("资源类型"),
+				"username":      aiToolString("操作用户（仅管理员可用）"),
+				"keywords":      aiToolString("关键词"),
+				"start_date":    aiToolString("开始日期 YYYY-MM-DD"),
+				"end_date":      aiToolString("结束日期 YYYY-MM-DD"),
+				"limit":         map[string]interface{}{"type": "integer", "minimum": 1, "maximum": 100},
+			}),
+			Authorize: func(ctx context.Context, caller AIToolCaller, raw json.RawMessage) error {
+				var args struct{ Username string }
+				if err := json.Unmarshal(raw, &args); err != nil {
+					return err
+				}
+				if args.Username != "" && !caller.IsAdmin && args.Username != caller.Username {
+					return errors.New("permission denied: only administrators can search other users' audit logs")
+				}
+				return nil
+			},
+			Run: func(ctx context.Context, caller AIToolCaller, raw json.RawMessage) (interface{}, error) {
+				var args struct {
+					Category     string `json:"category"`
+					Action       string `json:"action"`
+					Status       string `json:"status"`
+					ResourceType string `json:"resource_type"`
+					Username     string `json:"username"`
+					Keywords     string `json:"keywords"`
+					StartDate    string `json:"start_date"`
+					EndDate      string `json:"end_date"`
+					Limit        int    `json:"limit"`
+				}
+				if err := json.Unmarshal(raw, &args); err != nil {
+					return nil, err
+				}
+				req := &models.AuditLogQueryRequest{
+					Category:     args.Category,
+					Action:       args.Action,
+					Status:       args.Status,
+					ResourceType: args.ResourceType,
+					Keywords:     args.Keywords,
+					Page:         1,
+					PageSize:     args.Limit,
+				}
+				if req.PageSize <= 0 || req.PageSize > 100 {
+					req.PageSize = 20
+				}
+				if caller.IsAdmin {
+					req.Username = args.Username
+				} else {
+					req.UserID = caller.UserID
+				}
+				for _, d := range []struct {
+					value  string
+					target *time.Time
+				}{{args.StartDate, &req.StartDate}, {args.EndDate, &req.EndDate}} {
+					if d.value == "" {
+						continue
+					}
+					t, err := time.Parse("2006-01-02", d.value)
+					if err != nil {
+						return nil, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", d.value)
+					}
+					*d.target = t
+				}
+				result, err := GetAuditService().QueryAuditLogs(ctx, req)
+				if err != nil {
+					return nil, err
+				}
+				logs := make([]map[string]interface{}, 0, len(result.Data))
+				for _, entry := range result.Data {
+					logs = append(logs, map[string]interface{}{
+						"id":            entry.ID,
+						"time":          entry.CreatedAt,
+						"category":      entry.Category,
+						"action":        entry.Action,
+						"status":        entry.Status,
+						"username":      entry.Username,
+						"resource_type": entry.ResourceType,
+						"resource_id":   entry.ResourceID,
+						"resource_name": entry.ResourceName,
+						"client_ip":     entry.ClientIP,
+						"error":         entry.ErrorMessage,
+					})
+				}
+				return map[string]interface{}{"total": result.Total, "logs": logs}, nil
+			},
+		},
+	}
+}
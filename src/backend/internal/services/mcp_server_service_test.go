@@ -0,0 +1,95 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func newTestMCPServerService() *MCPServerService {
+	return &MCPServerService{tools: []*AITool{
+		{Name: "slurm_list_jobs", Description: "查询作业", Parameters: aiToolSchema(map[string]interface{}{})},
+		{Name: "slurm_submit_job_from_template", Description: "提交作业", Mutating: true},
+	}}
+}
+
+func mcpServerCall(t *testing.T, s *MCPServerService, body string) map[string]interface{} {
+	t.Helper()
+	resp := s.Handle(context.Background(), AIToolCaller{UserID: 1}, "127.0.0.1", []byte(body))
+	if resp == nil {
+		t.Fatalf("请求应有响应: %s", body)
+	}
+	data, _ := json.Marshal(resp)
+	var decoded map[string]interface{}
+	json.Unmarshal(data, &decoded)
+	return decoded
+}
+
+func TestMCPServerInitialize(t *testing.T) {
+	s := newTestMCPServerService()
+	resp := mcpServerCall(t, s, `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26"}}`)
+	result := resp["result"].(map[string]interface{})
+	if result["protocolVersion"] != "2025-03-26" {
+		t.Fatalf("应沿用客户端支持的协议版本: %v", result["protocolVersion"])
+	}
+
+	resp = mcpServerCall(t, s, `{"jsonrpc":"2.0","id":"a","method":"initialize","params":{"protocolVersion":"1999-01-01"}}`)
+	if resp["id"] != "a" || resp["result"].(map[string]interface{})["protocolVersion"] != mcpProtocolVersion {
+		t.Fatalf("不支持的版本应返回服务端最新版本: %v", resp)
+	}
+
+	if s.Handle(context.Background(), AIToolCaller{}, "", []byte(`{"jsonrpc":"2.0","method":"notifications/initialized"}`)) != nil {
+		t.Fatal("通知不应有响应")
+	}
+}
+
+func TestMCPServerToolsList(t *testing.T) {
+	resp := mcpServerCall(t, newTestMCPServerService(), `{"jsonrpc":"2.0","id":2,"method":"tools/list"}`)
+	tools := resp["result"].(map[string]interface{})["tools"].([]interface{})
+	if len(tools) != 2 {
+		t.Fatalf("tools: %v", tools)
+	}
+	read := tools[0].(map[string]interface{})["annotations"].(map[string]interface{})
+	write := tools[1].(map[string]interface{})["annotations"].(map[string]interface{})
+	if read["readOnlyHint"] != true || write["readOnlyHint"] != false {
+		t.Fatalf("只读标注不正确: %v %v", read, write)
+	}
+}
+
+func TestMCPServerErrors(t *testing.T) {
+	s := newTestMCPServerService()
+	cases := map[string]float64{
+		`not json`: mcpCodeParseError,
+		`{"jsonrpc":"1.0","id":1,"method":"ping"}`:                                 mcpCodeInvalidRequest,
+		`{"jsonrpc":"2.0","id":1,"method":"prompts/list"}`:                         mcpCodeMethodNotFound,
+		`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"rm_rf"}}`: mcpCodeInvalidParams,
+		`{"jsonrpc":"2.0","id":1,"method":"resources/read","params":{}}`:           mcpCodeInvalidParams,
+	}
+	for body, code := range cases {
+		resp := mcpServerCall(t, s, body)
+		rpcErr, ok := resp["error"].(map[string]interface{})
+		if !ok || rpcErr["code"] != code {
+			t.Errorf("%s: 期望错误码 %v，实际 %v", body, code, resp)
+		}
+	}
+}
+
+func TestAPITokenFormat(t *testing.T) {
+	token, err := generateAPIToken()
+	if err != nil {
+		t.Fatalf("generate failed: %v", err)
+	}
+	if !IsAPIToken(token) {
+		t.Fatalf("生成的令牌格式不正确: %s", token)
+	}
+	other, _ := generateAPIToken()
+	if token == other || hashAPIToken(token) == hashAPIToken(other) || len(hashAPIToken(token)) != 64 {
+		t.Fatal("令牌及其摘要应唯一")
+	}
+	for _, value := range []string{"", "aim_short", "eyJhbGciOiJIUzI1NiJ9." + strings.Repeat("a", 64)} {
+		if IsAPIToken(value) {
+			t.Errorf("不应识别为 API 令牌: %q", value)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package services
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"strconv"
@@ -23,6 +24,9 @@ const (
 	MessageTypeStatusUpdate MessageType = "status_update"
 )
 
+// ErrMessageNotRetryable 处理器返回的错误包装了该错误时不再重试，直接标记为失败
+var ErrMessageNotRetryable = errors.New("message is not retryable")
+
 // Message 消息结构
 type Message struct {
 	ID             string                 `json:"id"`
@@ -66,11 +70,23 @@ type MessageQueueService interface {
 	StopMessage(messageID string, userID uint) error
 	CanUserStopMessage(messageID string, userID uint) (bool, error)
 	IsMessageStopped(messageID string) bool
+	GetMessage(messageID string) (*Message, error)
+
+	// 流式回复事件
+	PublishStreamEvent(messageID string, event *AIStreamEvent) error
+	ReadStreamEvents(ctx context.Context, messageID, afterID string, block time.Duration) ([]AIStreamEvent, error)
 
 	// 健康检查
 	HealthCheck() error
 }
 
+const (
+	// 流式回复事件的保留时间，超时后无法再续传
+	aiStreamTTL = 10 * time.Minute
+	// 单条消息保留的事件数上限
+	aiStreamMaxLen = 20000
+)
+
 // MessageHandler 消息处理器
 type MessageHandler func(message *Message) error
 
@@ -334,7 +350,7 @@ func (s *messageQueueServiceImpl) processMessage(streamName, consumerGroup strin
 		logrus.Errorf("Failed to process message %s: %v", message.ID, err)
 
 		// 检查是否需要重试
-		if message.RetryCount < message.MaxRetries {
+		if message.RetryCount < message.MaxRetries && !errors.Is(err, ErrMessageNotRetryable) {
 			message.RetryCount++
 			s.retryMessage(streamName, &message)
 		} else {
@@ -380,7 +396,7 @@ func (s *messageQueueServiceImpl) processMessageWithHandler(streamName, consumer
 	// handler
 	if err := handler(&message); err != nil {
 		// processing failed
-		if message.RetryCount < message.MaxRetries {
+		if message.RetryCount < message.MaxRetries && !errors.Is(err, ErrMessageNotRetryable) {
 			message.RetryCount++
 			s.retryMessage(streamName, &message)
 			return fmt.Errorf("handler error, scheduled retry: %v", err)
@@ -395,6 +411,10 @@ func (s *messageQueueServiceImpl) processMessageWithHandler(streamName, consumer
 		return err
 	}
 
+	// 已被用户停止的消息保留 stopped 状态
+	if s.IsMessageStopped(message.ID) {
+		return nil
+	}
 	status.Status = "completed"
 	status.ProcessedAt = time.Now()
 	if err := s.SetMessageStatus(message.ID, status); err != nil {
@@ -594,3 +614,75 @@ func (s *messageQueueServiceImpl) IsMessageStopped(messageID string) bool {
 	}
 	return exists > 0
 }
+
+// GetMessage 获取已提交消息的详情（用于校验消息归属）
+func (s *messageQueueServiceImpl) GetMessage(messageID string) (*Message, error) {
+	data, err := s.redis.Get(s.ctx, fmt.Sprintf("message:details:%s", messageID)).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, fmt.Errorf("消息详情不存在")
+		}
+		return nil, err
+	}
+
+	var message Message
+	if err := json.Unmarshal([]byte(data), &message); err != nil {
+		return nil, fmt.Errorf("解析消息详情失败: %v", err)
+	}
+	return &message, nil
+}
+
+// PublishStreamEvent 追加一条流式回复事件，事件按消息ID写入独立的 Redis Stream，供 SSE 端点读取与续传
+func (s *messageQueueServiceImpl) PublishStreamEvent(messageID string, event *AIStreamEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	key := fmt.Sprintf("ai:stream:%s", messageID)
+	_, err = s.redis.Pipelined(s.ctx, func(pipe redis.Pipeliner) error {
+		pipe.XAdd(s.ctx, &redis.XAddArgs{
+			Stream: key,
+			MaxLen: aiStreamMaxLen,
+			Approx: true,
+			Values: map[string]interface{}{"data": data},
+		})
+		pipe.Expire(s.ctx, key, aiStreamTTL)
+		return nil
+	})
+	return err
+}
+
+// ReadStreamEvents 读取 afterID 之后的流式回复事件（afterID 为空时从头读取），无新事件时最多阻塞 block
+func (s *messageQueueServiceImpl) ReadStreamEvents(ctx context.Context, messageID, afterID string, block time.Duration) ([]AIStreamEvent, error) {
+	if afterID == "" {
+		afterID = "0"
+	}
+	streams, err := s.redis.XRead(ctx, &redis.XReadArgs{
+		Streams: []string{fmt.Sprintf("ai:stream:%s", messageID), afterID},
+		Count:   200,
+		Block:   block,
+	}).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var events []AIStreamEvent
+	for _, stream := range streams {
+		for _, msg := range stream.Messages {
+			data, _ := msg.Values["data"].(string)
+			// 无法解析的事件保留空类型，仅用于推进读取位置
+			var event AIStreamEvent
+			if err := json.Unmarshal([]byte(data), &event); err != nil {
+				logrus.Warnf("无法解析流式事件 %s: %v", msg.ID, err)
+				event = AIStreamEvent{}
+			}
+			event.ID = msg.ID
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
@@ -87,10 +87,19 @@ func NewMetricsService() *MetricsService {
 
 // Query 执行 PromQL 查询
 func (s *MetricsService) Query(promQL string) (*MetricsQueryResult, error) {
-	queryURL := fmt.Sprintf("%s/api/v1/query", s.vmURL)
+	return s.query(url.Values{"query": {promQL}})
+}
 
-	params := url.Values{}
-	params.Add("query", promQL)
+// QueryAt 在指定时间点执行 PromQL 即时查询，用于回看历史时刻的指标
+func (s *MetricsService) QueryAt(promQL string, at time.Time) (*MetricsQueryResult, error) {
+	return s.query(url.Values{
+		"query": {promQL},
+		"time":  {fmt.Sprintf("%d", at.Unix())},
+	})
+}
+
+func (s *MetricsService) query(params url.Values) (*MetricsQueryResult, error) {
+	queryURL := fmt.Sprintf("%s/api/v1/query", s.vmURL)
 
 	resp, err := s.client.Get(queryURL + "?" + params.Encode())
 	if err != nil {
@@ -0,0 +1,619 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// minionFactsCommand 采集派生事实：GPU 型号/数量（同 getGPUInfo 使用 nvidia-smi）与 IB 设备数量
+const minionFactsCommand = `echo "gpu_model=$(nvidia-smi --query-gpu=name --format=csv,noheader 2>/dev/null | head -1)"; ` +
+	`echo "gpu_count=$(nvidia-smi -L 2>/dev/null | wc -l)"; ` +
+	`echo "ib_devices=$(ls /sys/class/infiniband 2>/dev/null | wc -l)"`
+
+// MinionDynamicGroupService 基于 grains 规则的动态分组服务
+// 成员关系写入 minion_group_memberships，与手工分组共用查询接口，
+// 因此动态分组可直接作为 Salt 目标（tgt_type=group）和 Slurm 分区来源
+type MinionDynamicGroupService struct {
+	db        *gorm.DB
+	mu        sync.Mutex // 串行化刷新，避免全量与增量刷新交叉写入
+	startOnce sync.Once
+	stopOnce  sync.Once
+	stopCh    chan struct{}
+}
+
+var (
+	minionDynamicGroupServiceInstance *MinionDynamicGroupService
+	minionDynamicGroupServiceOnce     sync.Once
+)
+
+// NewMinionDynamicGroupService 创建动态分组服务（单例）
+func NewMinionDynamicGroupService(db *gorm.DB) *MinionDynamicGroupService {
+	minionDynamicGroupServiceOnce.Do(func() {
+		minionDynamicGroupServiceInstance = &MinionDynamicGroupService{
+			db:     db,
+			stopCh: make(chan struct{}),
+		}
+		if err := db.AutoMigrate(&models.MinionGroup{}); err != nil {
+			log.Printf("[MinionDynamicGroup] 自动迁移失败: %v", err)
+		}
+	})
+	return minionDynamicGroupServiceInstance
+}
+
+// GetMinionDynamicGroupService 获取动态分组服务实例
+func GetMinionDynamicGroupService() *MinionDynamicGroupService {
+	return minionDynamicGroupServiceInstance
+}
+
+// ==================== 规则 ====================
+
+// slurmPartitionNamePattern 动态分区名只允许出现在 slurm.conf 中安全的字符
+var slurmPartitionNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// ValidateSlurmPartitionName 校验动态分组绑定的 Slurm 分区名（空表示不绑定）
+// 不能与 generateSlurmConfig 生成的静态分区或 Slurm 保留的 DEFAULT 重名
+func ValidateSlurmPartitionName(name string) error {
+	if name == "" {
+		return nil
+	}
+	if !slurmPartitionNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid slurm_partition %q (allowed: letters, digits, _ . -)", name)
+	}
+	if strings.EqualFold(name, defaultSlurmPartition) || strings.EqualFold(name, "DEFAULT") {
+		return fmt.Errorf("slurm_partition %q is reserved", name)
+	}
+	return nil
+}
+
+// checkSlurmPartition 校验分区名，并确保没有其他动态分组绑定同一分区
+func (s *MinionDynamicGroupService) checkSlurmPartition(name string, groupID uint) error {
+	if err := ValidateSlurmPartitionName(name); err != nil {
+		return err
+	}
+	if name == "" {
+		return nil
+	}
+	var count int64
+	if err := s.db.Model(&models.MinionGroup{}).
+		Where("slurm_partition = ? AND id <> ?", name, groupID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("slurm_partition %q is already bound to another group", name)
+	}
+	return nil
+}
+
+// ValidateMinionGroupRule 校验动态分组规则
+func ValidateMinionGroupRule(rule *models.MinionGroupRule) error {
+	if rule == nil || len(rule.Conditions) == 0 {
+		return errors.New("rule requires at least one condition")
+	}
+	if rule.Match != "" && rule.Match != "all" && rule.Match != "any" {
+		return fmt.Errorf("invalid match %q (must be all or any)", rule.Match)
+	}
+	for i, cond := range rule.Conditions {
+		if cond.Grain == "" {
+			return fmt.Errorf("condition %d: grain is required", i)
+		}
+		switch cond.Op {
+		case "eq", "ne", "contains", "glob", "gt", "gte", "lt", "lte":
+			if cond.Value == nil {
+				return fmt.Errorf("condition %d: value is required for %s", i, cond.Op)
+			}
+		case "in":
+			if _, ok := cond.Value.([]interface{}); !ok {
+				return fmt.Errorf("condition %d: value must be a list for in", i)
+			}
+		case "regex":
+			pattern, _ := cond.Value.(string)
+			if _, err := regexp.Compile(pattern); err != nil {
+				return fmt.Errorf("condition %d: invalid regex: %v", i, err)
+			}
+		case "exists", "not_exists":
+		default:
+			return fmt.Errorf("condition %d: unsupported op %q", i, cond.Op)
+		}
+	}
+	return nil
+}
+
+// EvaluateMinionGroupRule 判断 minion 事实是否满足规则
+func EvaluateMinionGroupRule(rule *models.MinionGroupRule, facts map[string]interface{}) bool {
+	if rule == nil || len(rule.Conditions) == 0 {
+		return false
+	}
+	matchAny := rule.Match == "any"
+	for _, cond := range rule.Conditions {
+		matched := evaluateCondition(cond, facts)
+		if matchAny && matched {
+			return true
+		}
+		if !matchAny && !matched {
+			return false
+		}
+	}
+	return !matchAny
+}
+
+// lookupFact 按冒号路径读取事实，列表可用数字下标
+func lookupFact(facts map[string]interface{}, key string) (interface{}, bool) {
+	var current interface{} = facts
+	for _, part := range strings.Split(key, ":") {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			v, ok := node[part]
+			if !ok {
+				return nil, false
+			}
+			current = v
+		case []interface{}:
+			idx, err := strconv.Atoi(part)
+			if err != nil || idx < 0 || idx >= len(node) {
+				return nil, false
+			}
+			current = node[idx]
+		default:
+			return nil, false
+		}
+	}
+	return current, current != nil
+}
+
+func evaluateCondition(cond models.MinionGroupCondition, facts map[string]interface{}) bool {
+	value, exists := lookupFact(facts, cond.Grain)
+	switch cond.Op {
+	case "exists":
+		return exists
+	case "not_exists":
+		return !exists
+	}
+	if !exists {
+		return cond.Op == "ne"
+	}
+
+	// 列表型 grain（如 ipv4、roles）任一元素满足即可，ne 要求全部不等
+	if list, ok := value.([]interface{}); ok && cond.Op != "contains" {
+		if cond.Op == "ne" {
+			for _, item := range list {
+				if !compareFact(cond, item) {
+					return false
+				}
+			}
+			return true
+		}
+		for _, item := range list {
+			if compareFact(cond, item) {
+				return true
+			}
+		}
+		return false
+	}
+	return compareFact(cond, value)
+}
+
+func compareFact(cond models.MinionGroupCondition, value interface{}) bool {
+	actual := factString(value)
+	switch cond.Op {
+	case "eq":
+		return strings.EqualFold(actual, factString(cond.Value))
+	case "ne":
+		return !strings.EqualFold(actual, factString(cond.Value))
+	case "in":
+		list, _ := cond.Value.([]interface{})
+		for _, item := range list {
+			if strings.EqualFold(actual, factString(item)) {
+				return true
+			}
+		}
+		return false
+	case "contains":
+		needle := strings.ToLower(factString(cond.Value))
+		if list, ok := value.([]interface{}); ok {
+			for _, item := range list {
+				if strings.EqualFold(factString(item), needle) {
+					return true
+				}
+			}
+			return false
+		}
+		return strings.Contains(strings.ToLower(actual), needle)
+	case "glob":
+		ok, _ := path.Match(strings.ToLower(factString(cond.Value)), strings.ToLower(actual))
+		return ok
+	case "regex":
+		re, err := regexp.Compile(factString(cond.Value))
+		return err == nil && re.MatchString(actual)
+	case "gt", "gte", "lt", "lte":
+		a, errA := strconv.ParseFloat(actual, 64)
+		b, errB := strconv.ParseFloat(factString(cond.Value), 64)
+		if errA != nil || errB != nil {
+			return false
+		}
+		switch cond.Op {
+		case "gt":
+			return a > b
+		case "gte":
+			return a >= b
+		case "lt":
+			return a < b
+		default:
+			return a <= b
+		}
+	}
+	return false
+}
+
+func factString(v interface{}) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(t)
+	case nil:
+		return ""
+	default:
+		b, _ := json.Marshal(t)
+		return string(b)
+	}
+}
+
+// ==================== 事实采集 ====================
+
+// CollectMinionFacts 采集目标 minion 的 grains 及派生事实
+func (s *MinionDynamicGroupService) CollectMinionFacts(ctx context.Context, target, tgtType string) (map[string]map[string]interface{}, error) {
+	saltService := NewSaltStackService()
+	grains, err := saltService.RunLocal(ctx, target, tgtType, "grains.items", nil, nil, 60)
+	if err != nil {
+		return nil, fmt.Errorf("collect grains: %v", err)
+	}
+	extra, err := saltService.RunLocal(ctx, target, tgtType, "cmd.run", []interface{}{minionFactsCommand},
+		map[string]interface{}{"python_shell": true, "timeout": 20}, 60)
+	if err != nil {
+		log.Printf("[MinionDynamicGroup] 采集派生事实失败: %v", err)
+		extra = map[string]interface{}{}
+	}
+
+	facts := make(map[string]map[string]interface{}, len(grains))
+	for minion, raw := range grains {
+		g, ok := raw.(map[string]interface{})
+		if !ok {
+			continue // 未响应或返回错误
+		}
+		out, _ := extra[minion].(string)
+		facts[minion] = buildMinionFacts(minion, g, out)
+	}
+	return facts, nil
+}
+
+// buildMinionFacts 合并 grains 与派生事实
+func buildMinionFacts(minionID string, grains map[string]interface{}, cmdOutput string) map[string]interface{} {
+	facts := make(map[string]interface{}, len(grains)+6)
+	for k, v := range grains {
+		facts[k] = v
+	}
+
+	derived := map[string]string{}
+	for _, line := range strings.Split(cmdOutput, "\n") {
+		if k, v, ok := strings.Cut(strings.TrimSpace(line), "="); ok {
+			derived[k] = strings.TrimSpace(v)
+		}
+	}
+	gpuCount, _ := strconv.Atoi(derived["gpu_count"])
+	ibDevices, _ := strconv.Atoi(derived["ib_devices"])
+	facts["gpu_model"] = derived["gpu_model"]
+	facts["gpu_count"] = float64(gpuCount)
+	facts["ib_present"] = ibDevices > 0
+
+	// 无 nvidia-smi 时回退到 grains 中 lspci 识别的 GPU
+	if derived["gpu_model"] == "" {
+		if gpus, ok := grains["gpus"].([]interface{}); ok && len(gpus) > 0 {
+			if first, ok := gpus[0].(map[string]interface{}); ok {
+				facts["gpu_model"] = factString(first["model"])
+			}
+			if gpuCount == 0 {
+				facts["gpu_count"] = float64(len(gpus))
+			}
+		}
+	}
+
+	// 数据中心/机架标签：优先自定义 grains，其次 minion 所属 Master 的数据中心
+	for _, key := range []string{"datacenter", "rack"} {
+		if v, ok := lookupFact(grains, key); ok {
+			facts[key] = v
+		} else if v, ok := lookupFact(grains, "ai_infra:"+key); ok {
+			facts[key] = v
+		}
+	}
+	if _, ok := facts["datacenter"]; !ok {
+		if masterService := GetSaltMasterService(); masterService != nil {
+			if master, err := masterService.ResolveMinionMaster(minionID); err == nil && master.Datacenter != "" {
+				facts["datacenter"] = master.Datacenter
+			}
+		}
+	}
+	return facts
+}
+
+// ==================== 分组维护 ====================
+
+// CreateGroup 创建动态分组
+func (s *MinionDynamicGroupService) CreateGroup(group *models.MinionGroup, rule *models.MinionGroupRule) error {
+	if err := ValidateMinionGroupRule(rule); err != nil {
+		return err
+	}
+	if err := s.checkSlurmPartition(group.SlurmPartition, 0); err != nil {
+		return err
+	}
+	ruleJSON, _ := json.Marshal(rule)
+	group.Dynamic = true
+	group.RuleJSON = string(ruleJSON)
+	group.Rule = rule
+	if group.DisplayName == "" {
+		group.DisplayName = group.Name
+	}
+	return s.db.Create(group).Error
+}
+
+// UpdateRule 更新动态分组规则及 Slurm 分区来源
+func (s *MinionDynamicGroupService) UpdateRule(groupID uint, rule *models.MinionGroupRule, slurmPartition *string) (*models.MinionGroup, error) {
+	if err := ValidateMinionGroupRule(rule); err != nil {
+		return nil, err
+	}
+	var group models.MinionGroup
+	if err := s.db.First(&group, groupID).Error; err != nil {
+		return nil, err
+	}
+	if slurmPartition != nil {
+		if err := s.checkSlurmPartition(*slurmPartition, group.ID); err != nil {
+			return nil, err
+		}
+	}
+	ruleJSON, _ := json.Marshal(rule)
+	updates := map[string]interface{}{"dynamic": true, "rule": string(ruleJSON)}
+	if slurmPartition != nil {
+		updates["slurm_partition"] = *slurmPartition
+	}
+	if err := s.db.Model(&group).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+	if err := s.db.First(&group, groupID).Error; err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+// ListDynamicGroups 列出动态分组
+func (s *MinionDynamicGroupService) ListDynamicGroups() ([]models.MinionGroup, error) {
+	var groups []models.MinionGroup
+	err := s.db.Where("dynamic = ?", true).Order("priority DESC, name ASC").Find(&groups).Error
+	return groups, err
+}
+
+// Preview 预览规则匹配的 minion（不写入）
+func (s *MinionDynamicGroupService) Preview(ctx context.Context, rule *models.MinionGroupRule) ([]string, error) {
+	if err := ValidateMinionGroupRule(rule); err != nil {
+		return nil, err
+	}
+	facts, err := s.CollectMinionFacts(ctx, "*", "glob")
+	if err != nil {
+		return nil, err
+	}
+	var matched []string
+	for minion, f := range facts {
+		if EvaluateMinionGroupRule(rule, f) {
+			matched = append(matched, minion)
+		}
+	}
+	sort.Strings(matched)
+	return matched, nil
+}
+
+// RefreshAll 全量刷新所有动态分组
+// 未响应的 minion 保留原有成员关系，避免短暂离线导致分组抖动
+func (s *MinionDynamicGroupService) RefreshAll(ctx context.Context) error {
+	groups, err := s.ListDynamicGroups()
+	if err != nil || len(groups) == 0 {
+		return err
+	}
+	facts, err := s.CollectMinionFacts(ctx, "*", "glob")
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range groups {
+		if err := s.applyGroup(&groups[i], facts); err != nil {
+			log.Printf("[MinionDynamicGroup] 刷新分组 %s 失败: %v", groups[i].Name, err)
+		}
+	}
+	log.Printf("[MinionDynamicGroup] 已刷新 %d 个动态分组（%d 个 minion 响应）", len(groups), len(facts))
+	return nil
+}
+
+// RefreshMinion 增量刷新单个 minion 的动态分组成员关系（minion 启动事件触发）
+func (s *MinionDynamicGroupService) RefreshMinion(ctx context.Context, minionID string) error {
+	groups, err := s.ListDynamicGroups()
+	if err != nil || len(groups) == 0 {
+		return err
+	}
+	facts, err := s.CollectMinionFacts(ctx, minionID, "glob")
+	if err != nil {
+		return err
+	}
+	if _, ok := facts[minionID]; !ok {
+		return fmt.Errorf("minion %s did not return grains", minionID)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range groups {
+		if err := s.applyGroup(&groups[i], facts); err != nil {
+			log.Printf("[MinionDynamicGroup] 刷新分组 %s 失败: %v", groups[i].Name, err)
+		}
+	}
+	return nil
+}
+
+// applyGroup 根据已采集事实更新分组成员：满足规则的加入，不满足的移除，未出现在 facts 中的保持不变
+func (s *MinionDynamicGroupService) applyGroup(group *models.MinionGroup, facts map[string]map[string]interface{}) error {
+	if group.Rule == nil {
+		return nil
+	}
+	var add, remove []string
+	for minion, f := range facts {
+		if EvaluateMinionGroupRule(group.Rule, f) {
+			add = append(add, minion)
+		} else {
+			remove = append(remove, minion)
+		}
+	}
+
+	now := time.Now()
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if len(remove) > 0 {
+			if err := tx.Where("group_id = ? AND minion_id IN ?", group.ID, remove).Delete(&models.MinionGroupMembership{}).Error; err != nil {
+				return err
+			}
+		}
+		if len(add) > 0 {
+			var existing []string
+			tx.Model(&models.MinionGroupMembership{}).Where("group_id = ? AND minion_id IN ?", group.ID, add).Pluck("minion_id", &existing)
+			have := make(map[string]bool, len(existing))
+			for _, m := range existing {
+				have[m] = true
+			}
+			var rows []models.MinionGroupMembership
+			for _, m := range add {
+				if !have[m] {
+					rows = append(rows, models.MinionGroupMembership{MinionID: m, GroupID: group.ID})
+				}
+			}
+			if len(rows) > 0 {
+				if err := tx.CreateInBatches(rows, 500).Error; err != nil {
+					return err
+				}
+			}
+		}
+		var count int64
+		tx.Model(&models.MinionGroupMembership{}).Where("group_id = ?", group.ID).Count(&count)
+		return tx.Model(group).Updates(map[string]interface{}{"member_count": count, "last_evaluated_at": now}).Error
+	})
+}
+
+// SlurmPartitionMembers 返回作为 Slurm 分区来源的动态分组：分区名 -> minion 列表
+func (s *MinionDynamicGroupService) SlurmPartitionMembers() map[string][]string {
+	var groups []models.MinionGroup
+	if err := s.db.Where("dynamic = ? AND slurm_partition <> ''", true).Find(&groups).Error; err != nil {
+		return nil
+	}
+	result := make(map[string][]string, len(groups))
+	for _, g := range groups {
+		var minions []string
+		s.db.Model(&models.MinionGroupMembership{}).Where("group_id = ?", g.ID).Order("minion_id").Pluck("minion_id", &minions)
+		result[g.SlurmPartition] = minions
+	}
+	return result
+}
+
+// ExpandMinionGroupTarget 将 tgt_type=group 的目标（分组名）展开为 list 目标，其他类型原样返回
+func ExpandMinionGroupTarget(db *gorm.DB, target, tgtType string) (string, string, error) {
+	if tgtType != "group" {
+		return target, tgtType, nil
+	}
+	var group models.MinionGroup
+	if err := db.Where("name = ?", target).First(&group).Error; err != nil {
+		return "", "", fmt.Errorf("minion group %q not found", target)
+	}
+	var minions []string
+	db.Model(&models.MinionGroupMembership{}).Where("group_id = ?", group.ID).Order("minion_id").Pluck("minion_id", &minions)
+	if len(minions) == 0 {
+		return "", "", fmt.Errorf("minion group %q has no members", target)
+	}
+	return strings.Join(minions, ","), "list", nil
+}
+
+// ==================== 后台刷新 ====================
+
+// Start 启动定时全量刷新，并监听 salt/minion/*/start 事件做增量刷新
+func (s *MinionDynamicGroupService) Start(interval time.Duration) {
+	s.startOnce.Do(func() {
+		go func() {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					if err := s.RefreshAll(context.Background()); err != nil {
+						log.Printf("[MinionDynamicGroup] 定时刷新失败: %v", err)
+					}
+				case <-s.stopCh:
+					return
+				}
+			}
+		}()
+		go s.watchStartEvents()
+	})
+}
+
+// Stop 停止后台刷新
+func (s *MinionDynamicGroupService) Stop() {
+	s.stopOnce.Do(func() { close(s.stopCh) })
+}
+
+// watchStartEvents 监听 minion 启动事件，断线后退避重连
+func (s *MinionDynamicGroupService) watchStartEvents() {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-s.stopCh
+		cancel()
+	}()
+
+	backoff := 5 * time.Second
+	for ctx.Err() == nil {
+		started := time.Now()
+		err := NewSaltStackService().StreamEvents(ctx, func(event SaltEvent) {
+			parts := strings.Split(event.Tag, "/")
+			// salt/minion/<id>/start
+			if len(parts) == 4 && parts[0] == "salt" && parts[1] == "minion" && parts[3] == "start" {
+				minionID := parts[2]
+				go func() {
+					// 等待 minion 完成初始化再采集 grains
+					time.Sleep(10 * time.Second)
+					if err := s.RefreshMinion(ctx, minionID); err != nil {
+						log.Printf("[MinionDynamicGroup] minion %s 启动后刷新失败: %v", minionID, err)
+					}
+				}()
+			}
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(started) > time.Minute {
+			backoff = 5 * time.Second
+		}
+		log.Printf("[MinionDynamicGroup] 事件流断开: %v，%s 后重连", err, backoff)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return
+		}
+		if backoff < 5*time.Minute {
+			backoff *= 2
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
+)
+
+func TestEvaluateMinionGroupRule(t *testing.T) {
+	grains := map[string]interface{}{
+		"os":   "Ubuntu",
+		"rack": "3",
+		"gpus": []interface{}{
+			map[string]interface{}{"model": "GH100 [H100 SXM5 80GB]", "vendor": "nvidia"},
+		},
+		"ipv4": []interface{}{"127.0.0.1", "10.0.3.21"},
+	}
+	facts := buildMinionFacts("gpu-r3-01", grains, "gpu_model=NVIDIA H100 80GB HBM3\ngpu_count=8\nib_devices=4\n")
+
+	if facts["gpu_count"] != float64(8) || facts["ib_present"] != true {
+		t.Fatalf("派生事实错误: gpu_count=%v ib_present=%v", facts["gpu_count"], facts["ib_present"])
+	}
+
+	h100Rack3 := &models.MinionGroupRule{Conditions: []models.MinionGroupCondition{
+		{Grain: "gpu_model", Op: "contains", Value: "H100"},
+		{Grain: "rack", Op: "eq", Value: "3"},
+		{Grain: "gpu_count", Op: "gte", Value: float64(8)},
+	}}
+	if err := ValidateMinionGroupRule(h100Rack3); err != nil {
+		t.Fatalf("规则校验失败: %v", err)
+	}
+
+	cases := []struct {
+		name string
+		rule *models.MinionGroupRule
+		want bool
+	}{
+		{"H100 in rack 3", h100Rack3, true},
+		{"rack mismatch", &models.MinionGroupRule{Conditions: []models.MinionGroupCondition{
+			{Grain: "rack", Op: "eq", Value: "4"},
+		}}, false},
+		{"any of", &models.MinionGroupRule{Match: "any", Conditions: []models.MinionGroupCondition{
+			{Grain: "os", Op: "eq", Value: "CentOS"},
+			{Grain: "ib_present", Op: "eq", Value: true},
+		}}, true},
+		{"list grain glob", &models.MinionGroupRule{Conditions: []models.MinionGroupCondition{
+			{Grain: "ipv4", Op: "glob", Value: "10.0.3.*"},
+		}}, true},
+		{"nested path", &models.MinionGroupRule{Conditions: []models.MinionGroupCondition{
+			{Grain: "gpus:0:vendor", Op: "eq", Value: "NVIDIA"},
+		}}, true},
+		{"missing grain", &models.MinionGroupRule{Conditions: []models.MinionGroupCondition{
+			{Grain: "datacenter", Op: "exists"},
+		}}, false},
+	}
+	for _, tc := range cases {
+		if got := EvaluateMinionGroupRule(tc.rule, facts); got != tc.want {
+			t.Errorf("%s: 期望 %v，实际 %v", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestBuildMinionFactsFallsBackToGrainsGPUs(t *testing.T) {
+	grains := map[string]interface{}{
+		"gpus": []interface{}{
+			map[string]interface{}{"model": "A100"},
+			map[string]interface{}{"model": "A100"},
+		},
+		"ai_infra": map[string]interface{}{"datacenter": "bj1"},
+	}
+	facts := buildMinionFacts("gpu-02", grains, "")
+	if facts["gpu_model"] != "A100" || facts["gpu_count"] != float64(2) {
+		t.Errorf("回退 GPU 事实错误: %v / %v", facts["gpu_model"], facts["gpu_count"])
+	}
+	if facts["datacenter"] != "bj1" {
+		t.Errorf("datacenter = %v", facts["datacenter"])
+	}
+	if facts["ib_present"] != false {
+		t.Errorf("ib_present 应为 false")
+	}
+}
+
+func TestValidateMinionGroupRuleRejectsInvalid(t *testing.T) {
+	invalid := []*models.MinionGroupRule{
+		nil,
+		{Conditions: nil},
+		{Match: "some", Conditions: []models.MinionGroupCondition{{Grain: "os", Op: "eq", Value: "x"}}},
+		{Conditions: []models.MinionGroupCondition{{Grain: "os", Op: "like", Value: "x"}}},
+		{Conditions: []models.MinionGroupCondition{{Grain: "os", Op: "in", Value: "x"}}},
+		{Conditions: []models.MinionGroupCondition{{Grain: "os", Op: "regex", Value: "("}}},
+	}
+	for i, rule := range invalid {
+		if err := ValidateMinionGroupRule(rule); err == nil {
+			t.Errorf("case %d: 期望校验失败", i)
+		}
+	}
+}
+
+func TestValidateSlurmPartitionName(t *testing.T) {
+	for _, name := range []string{"", "gpu", "a100-80g", "gpu_v2.1"} {
+		if err := ValidateSlurmPartitionName(name); err != nil {
+			t.Errorf("%q: %v", name, err)
+		}
+	}
+	for _, name := range []string{"compute", "Compute", "DEFAULT", "default", "gpu Nodes=ALL", "gpu\nPartitionName=x", "a,b", "gpu=1"} {
+		if err := ValidateSlurmPartitionName(name); err == nil {
+			t.Errorf("%q: 期望校验失败", name)
+		}
+	}
+}
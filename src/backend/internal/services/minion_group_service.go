@@ -94,8 +94,8 @@ func (s *MinionGroupService) RemoveMinionFromGroup(minionID string, groupID uint
 
 // SetMinionGroup 设置 Minion 的分组（替换所有现有分组）
 func (s *MinionGroupService) SetMinionGroup(minionID string, groupName string) error {
-	// 先删除该 Minion 的所有分组关系
-	if err := s.db.Where("minion_id = ?", minionID).Delete(&models.MinionGroupMembership{}).Error; err != nil {
+	// 先删除该 Minion 的所有手工分组关系（动态分组成员由规则维护）
+	if err := s.db.Where("minion_id = ? AND group_id NOT IN (?)", minionID, dynamicGroupIDs(s.db)).Delete(&models.MinionGroupMembership{}).Error; err != nil {
 		return err
 	}
 
@@ -127,6 +127,11 @@ func (s *MinionGroupService) SetMinionGroup(minionID string, groupName string) e
 	return s.db.Create(&membership).Error
 }
 
+// dynamicGroupIDs 动态分组 ID 子查询
+func dynamicGroupIDs(db *gorm.DB) *gorm.DB {
+	return db.Session(&gorm.Session{NewDB: true}).Model(&models.MinionGroup{}).Select("id").Where("dynamic = ?", true)
+}
+
 // GetMinionGroups 获取 Minion 所属的分组
 func (s *MinionGroupService) GetMinionGroups(minionID string) ([]models.MinionGroup, error) {
 	var groups []models.MinionGroup
@@ -182,8 +187,8 @@ func (s *MinionGroupService) GetAllMinionGroupMap() (map[string]string, error) {
 func (s *MinionGroupService) BatchSetMinionGroups(minionGroups map[string]string) error {
 	return s.db.Transaction(func(tx *gorm.DB) error {
 		for minionID, groupName := range minionGroups {
-			// 先删除该 Minion 的所有分组关系
-			if err := tx.Where("minion_id = ?", minionID).Delete(&models.MinionGroupMembership{}).Error; err != nil {
+			// 先删除该 Minion 的所有手工分组关系
+			if err := tx.Where("minion_id = ? AND group_id NOT IN (?)", minionID, dynamicGroupIDs(tx)).Delete(&models.MinionGroupMembership{}).Error; err != nil {
 				return err
 			}
 
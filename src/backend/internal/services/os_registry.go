@@ -0,0 +1,250 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+// osRegistryFile 操作系统支持矩阵文件（相对脚本目录）
+const osRegistryFile = "templates/os-registry.yaml"
+
+// OSRegistry 操作系统支持矩阵
+// 以数据定义支持的 OS 家族/发行版/版本/架构，以及对应的安装模板、依赖包和软件源配置
+type OSRegistry struct {
+	Architectures map[string]OSArchitecture `yaml:"architectures" json:"architectures"`
+	Families      []OSFamily                `yaml:"families" json:"families"`
+}
+
+// OSArchitecture CPU 架构定义
+type OSArchitecture struct {
+	Aliases []string `yaml:"aliases" json:"aliases,omitempty"` // uname -m 等可能出现的别名
+	Deb     string   `yaml:"deb" json:"deb"`                   // DEB 包架构名
+	Rpm     string   `yaml:"rpm" json:"rpm"`                   // RPM 包架构名
+}
+
+// OSFamily 操作系统家族（同一家族共用安装模板）
+type OSFamily struct {
+	ID             string            `yaml:"id" json:"id"`
+	Name           string            `yaml:"name" json:"name"`
+	PackageFormat  string            `yaml:"package_format" json:"package_format"`
+	PackageManager string            `yaml:"package_manager" json:"package_manager"`
+	Packages       []string          `yaml:"packages" json:"packages,omitempty"`
+	RepoSetup      string            `yaml:"repo_setup" json:"repo_setup,omitempty"`
+	Architectures  []string          `yaml:"architectures" json:"architectures"`
+	Templates      map[string]string `yaml:"templates" json:"templates"`
+	Distributions  []OSDistribution  `yaml:"distributions" json:"distributions"`
+}
+
+// OSDistribution 发行版定义，非空字段覆盖家族配置
+type OSDistribution struct {
+	ID             string   `yaml:"id" json:"id"`
+	Name           string   `yaml:"name" json:"name"`
+	Aliases        []string `yaml:"aliases" json:"aliases,omitempty"`
+	Versions       []string `yaml:"versions" json:"versions,omitempty"` // 为空表示不限版本
+	Architectures  []string `yaml:"architectures" json:"architectures,omitempty"`
+	PackageManager string   `yaml:"package_manager" json:"package_manager,omitempty"`
+	Packages       []string `yaml:"packages" json:"packages,omitempty"`
+	RepoSetup      string   `yaml:"repo_setup" json:"repo_setup,omitempty"`
+}
+
+// ResolvedOS 主机在支持矩阵中的匹配结果
+type ResolvedOS struct {
+	Family         string            `json:"family"`
+	Distribution   string            `json:"distribution"`
+	Name           string            `json:"name"`
+	Version        string            `json:"version"`
+	Arch           string            `json:"arch"`
+	DebArch        string            `json:"deb_arch"`
+	RpmArch        string            `json:"rpm_arch"`
+	PackageFormat  string            `json:"package_format"`
+	PackageManager string            `json:"package_manager"`
+	Packages       []string          `json:"packages,omitempty"`
+	RepoSetup      string            `json:"repo_setup,omitempty"`
+	Templates      map[string]string `json:"templates"`
+}
+
+// UnsupportedOSError 主机操作系统不在支持矩阵中
+type UnsupportedOSError struct {
+	OS      string
+	Version string
+	Arch    string
+	Reason  string
+}
+
+func (e *UnsupportedOSError) Error() string {
+	return fmt.Sprintf("unsupported OS %s %s (%s): %s", e.OS, e.Version, e.Arch, e.Reason)
+}
+
+// ParseOSRegistry 解析并校验支持矩阵
+func ParseOSRegistry(data []byte) (*OSRegistry, error) {
+	var registry OSRegistry
+	if err := yaml.Unmarshal(data, &registry); err != nil {
+		return nil, fmt.Errorf("解析操作系统支持矩阵失败: %v", err)
+	}
+	if len(registry.Families) == 0 {
+		return nil, fmt.Errorf("操作系统支持矩阵未定义任何家族")
+	}
+
+	seen := make(map[string]string)
+	for _, family := range registry.Families {
+		if family.ID == "" {
+			return nil, fmt.Errorf("操作系统家族缺少 id")
+		}
+		if family.Templates["salt-install"] == "" {
+			return nil, fmt.Errorf("操作系统家族 %s 缺少 salt-install 模板", family.ID)
+		}
+		if err := registry.checkArchitectures(family.ID, family.Architectures); err != nil {
+			return nil, err
+		}
+		for _, dist := range family.Distributions {
+			if dist.ID == "" {
+				return nil, fmt.Errorf("操作系统家族 %s 中存在缺少 id 的发行版", family.ID)
+			}
+			for _, id := range append([]string{dist.ID}, dist.Aliases...) {
+				id = strings.ToLower(id)
+				if owner, ok := seen[id]; ok {
+					return nil, fmt.Errorf("发行版 %s 在 %s 与 %s 中重复定义", id, owner, family.ID)
+				}
+				seen[id] = family.ID
+			}
+			if err := registry.checkArchitectures(dist.ID, dist.Architectures); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &registry, nil
+}
+
+func (r *OSRegistry) checkArchitectures(owner string, arches []string) error {
+	for _, arch := range arches {
+		if _, ok := r.Architectures[arch]; !ok {
+			return fmt.Errorf("%s 引用了未定义的架构 %s", owner, arch)
+		}
+	}
+	return nil
+}
+
+// NormalizeArch 将 amd64/arm64 等别名规范为支持矩阵中的架构名，未知架构原样返回
+func (r *OSRegistry) NormalizeArch(arch string) string {
+	arch = strings.ToLower(strings.TrimSpace(arch))
+	if _, ok := r.Architectures[arch]; ok {
+		return arch
+	}
+	for name, def := range r.Architectures {
+		for _, alias := range def.Aliases {
+			if strings.EqualFold(alias, arch) {
+				return name
+			}
+		}
+	}
+	return arch
+}
+
+// lookup 按发行版 ID 查找定义
+func (r *OSRegistry) lookup(osID string) (*OSFamily, *OSDistribution) {
+	osID = strings.ToLower(strings.TrimSpace(osID))
+	for i := range r.Families {
+		family := &r.Families[i]
+		for j := range family.Distributions {
+			dist := &family.Distributions[j]
+			if strings.EqualFold(dist.ID, osID) {
+				return family, dist
+			}
+			for _, alias := range dist.Aliases {
+				if strings.EqualFold(alias, osID) {
+					return family, dist
+				}
+			}
+		}
+	}
+	return nil, nil
+}
+
+// Template 获取发行版对应的脚本模板，未登记时返回空字符串
+func (r *OSRegistry) Template(scriptType, osID string) string {
+	family, _ := r.lookup(osID)
+	if family == nil {
+		return ""
+	}
+	return family.Templates[scriptType]
+}
+
+// Resolve 将检测到的主机操作系统与支持矩阵匹配，不支持时返回 *UnsupportedOSError
+func (r *OSRegistry) Resolve(osInfo models.OSInfo) (*ResolvedOS, error) {
+	unsupported := func(reason string) error {
+		return &UnsupportedOSError{OS: osInfo.OS, Version: osInfo.Version, Arch: osInfo.Arch, Reason: reason}
+	}
+
+	family, dist := r.lookup(osInfo.OS)
+	if family == nil {
+		return nil, unsupported("distribution is not in the OS registry, supported: " + strings.Join(r.distributionIDs(), ", "))
+	}
+	if len(dist.Versions) > 0 && !matchOSVersion(dist.Versions, osInfo.Version) {
+		return nil, unsupported(fmt.Sprintf("%s supported versions: %s", dist.ID, strings.Join(dist.Versions, ", ")))
+	}
+
+	arches := dist.Architectures
+	if len(arches) == 0 {
+		arches = family.Architectures
+	}
+	arch := r.NormalizeArch(osInfo.Arch)
+	supportedArch := false
+	for _, a := range arches {
+		if a == arch {
+			supportedArch = true
+			break
+		}
+	}
+	if !supportedArch {
+		return nil, unsupported(fmt.Sprintf("%s supported architectures: %s", dist.ID, strings.Join(arches, ", ")))
+	}
+
+	resolved := &ResolvedOS{
+		Family:         family.ID,
+		Distribution:   dist.ID,
+		Name:           dist.Name,
+		Version:        osInfo.Version,
+		Arch:           arch,
+		DebArch:        r.Architectures[arch].Deb,
+		RpmArch:        r.Architectures[arch].Rpm,
+		PackageFormat:  family.PackageFormat,
+		PackageManager: family.PackageManager,
+		Packages:       family.Packages,
+		RepoSetup:      family.RepoSetup,
+		Templates:      family.Templates,
+	}
+	if dist.PackageManager != "" {
+		resolved.PackageManager = dist.PackageManager
+	}
+	if len(dist.Packages) > 0 {
+		resolved.Packages = dist.Packages
+	}
+	if dist.RepoSetup != "" {
+		resolved.RepoSetup = dist.RepoSetup
+	}
+	return resolved, nil
+}
+
+func (r *OSRegistry) distributionIDs() []string {
+	var ids []string
+	for _, family := range r.Families {
+		for _, dist := range family.Distributions {
+			ids = append(ids, dist.ID)
+		}
+	}
+	return ids
+}
+
+// matchOSVersion 版本完全一致或为登记版本的次版本（"9" 匹配 "9.3"）
+func matchOSVersion(versions []string, version string) bool {
+	version = strings.TrimSpace(version)
+	for _, v := range versions {
+		if version == v || strings.HasPrefix(version, v+".") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
+)
+
+func TestEmbeddedOSRegistryResolve(t *testing.T) {
+	loader := NewScriptLoader(t.TempDir())
+	registry, err := loader.OSRegistry()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resolved, err := registry.Resolve(models.OSInfo{OS: "rocky", Version: "9.3", Arch: "aarch64"})
+	if err != nil {
+		t.Fatalf("Rocky 9 aarch64 应受支持: %v", err)
+	}
+	if resolved.Family != "rhel" || resolved.RpmArch != "aarch64" || resolved.DebArch != "arm64" || resolved.PackageManager != "dnf" {
+		t.Fatalf("匹配结果不正确: %+v", resolved)
+	}
+	if resolved.Templates["salt-install"] != "templates/salt-install-rhel.sh.tmpl" {
+		t.Fatalf("模板不正确: %v", resolved.Templates)
+	}
+
+	// 发行版 ID 不区分大小写，架构别名被规范化
+	resolved, err = registry.Resolve(models.OSInfo{OS: "openEuler", Version: "22.03", Arch: "x86_64"})
+	if err != nil || resolved.Distribution != "openeuler" {
+		t.Fatalf("openEuler 应受支持: %+v %v", resolved, err)
+	}
+	resolved, err = registry.Resolve(models.OSInfo{OS: "ubuntu", Version: "22.04", Arch: "amd64"})
+	if err != nil || resolved.Arch != "x86_64" || resolved.PackageManager != "apt-get" {
+		t.Fatalf("Ubuntu amd64 应受支持: %+v %v", resolved, err)
+	}
+
+	// 发行版覆盖家族的包管理器
+	resolved, err = registry.Resolve(models.OSInfo{OS: "centos", Version: "7", Arch: "x86_64"})
+	if err != nil || resolved.PackageManager != "yum" {
+		t.Fatalf("CentOS 7 应使用 yum: %+v %v", resolved, err)
+	}
+
+	// 引入支持矩阵前已可安装的旧版本仍受支持
+	for _, info := range []models.OSInfo{
+		{OS: "ubuntu", Version: "18.04", Arch: "x86_64"},
+		{OS: "debian", Version: "10", Arch: "amd64"},
+		{OS: "rhel", Version: "7.9", Arch: "x86_64"},
+	} {
+		if _, err := registry.Resolve(info); err != nil {
+			t.Fatalf("%+v 应受支持: %v", info, err)
+		}
+	}
+
+	for _, info := range []models.OSInfo{
+		{OS: "arch", Version: "rolling", Arch: "x86_64"},
+		{OS: "ubuntu", Version: "12.04", Arch: "x86_64"},
+		{OS: "rocky", Version: "9.3", Arch: "ppc64le"},
+		{OS: "rocky", Version: "90", Arch: "x86_64"},
+	} {
+		_, err := registry.Resolve(info)
+		var unsupported *UnsupportedOSError
+		if !errors.As(err, &unsupported) {
+			t.Fatalf("%+v 应返回 UnsupportedOSError, got %v", info, err)
+		}
+		if !strings.Contains(err.Error(), "supported") {
+			t.Fatalf("错误信息应包含支持范围: %v", err)
+		}
+	}
+}
+
+func TestParseOSRegistryValidation(t *testing.T) {
+	cases := map[string]string{
+		"undefined arch": `
+architectures:
+  x86_64: {deb: amd64, rpm: x86_64}
+families:
+  - id: rhel
+    architectures: [riscv64]
+    templates: {salt-install: templates/salt-install-rhel.sh.tmpl}
+`,
+		"duplicate distribution": `
+architectures:
+  x86_64: {deb: amd64, rpm: x86_64}
+families:
+  - id: rhel
+    templates: {salt-install: a}
+    distributions: [{id: rocky}]
+  - id: other
+    templates: {salt-install: b}
+    distributions: [{id: Rocky}]
+`,
+		"missing template": `
+families:
+  - id: rhel
+`,
+	}
+	for name, content := range cases {
+		if _, err := ParseOSRegistry([]byte(content)); err == nil {
+			t.Errorf("%s: 应校验失败", name)
+		}
+	}
+}
+
+func TestGenerateSaltInstallScriptUsesRegistry(t *testing.T) {
+	loader := NewScriptLoader(t.TempDir())
+	script, err := loader.GenerateSaltInstallScript(SaltInstallParams{
+		AppHubURL: "http://apphub:28080",
+		OS:        "openeuler",
+		RpmArch:   "aarch64",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(script, "dnf install -y python3 python3-pip") {
+		t.Fatalf("安装脚本应使用支持矩阵中的包管理器和依赖包:\n%s", script)
+	}
+	if loader.getTemplateForOS("salt-install", "unknown-os") != "templates/salt-install-generic.sh.tmpl" {
+		t.Fatal("未登记的系统应使用默认模板")
+	}
+}
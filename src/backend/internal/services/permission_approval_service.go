@@ -329,6 +329,9 @@ func (s *PermissionApprovalService) ListPermissionRequests(userID uint, query *m
 	if query.OnlyPending {
 		db = db.Where("status = ?", models.ApprovalStatusPending)
 	}
+	if query.RequestType != "" {
+		db = db.Where("request_type = ?", query.RequestType)
+	}
 
 	// 申请人筛选
 	if query.RequesterID > 0 {
@@ -414,27 +417,32 @@ func (s *PermissionApprovalService) ApprovePermissionRequest(requestID uint, app
 		return fmt.Errorf("申请记录不存在: %v", err)
 	}
 
-	// 检查是否可以审批
-	if !request.CanBeApproved() {
-		return fmt.Errorf("该申请状态为 %s，无法审批", request.Status)
-	}
-
-	// 不能审批自己的申请
-	if request.RequesterID == approverID {
-		return errors.New("不能审批自己的申请")
+	if err := checkPermissionRequestApprovable(&request, approverID); err != nil {
+		return err
 	}
 
 	oldStatus := string(request.Status)
 	var newStatus models.PermissionApprovalStatus
 	var action string
 
+	// 危险命令执行申请的审批结果必须同步到执行记录，服务未启用时不能只改申请状态
+	isSaltCommand := request.RequestType == models.PermissionRequestTypeSaltCommand
+	var cmdApproval *SaltCommandApprovalService
+	if isSaltCommand {
+		if cmdApproval = GetSaltCommandApprovalService(); cmdApproval == nil {
+			return errors.New("危险命令审批服务未启用，无法审批该申请")
+		}
+	}
+
 	if input.Approved {
 		newStatus = models.ApprovalStatusApproved
 		action = "approve"
 		
-		// 批准时授予权限
-		if err := s.grantPermissions(&request, approverID); err != nil {
-			return fmt.Errorf("授予权限失败: %v", err)
+		// 批准时授予权限（危险命令执行申请不授予权限，只执行被批准的命令一次）
+		if !isSaltCommand {
+			if err := s.grantPermissions(&request, approverID); err != nil {
+				return fmt.Errorf("授予权限失败: %v", err)
+			}
 		}
 	} else {
 		newStatus = models.ApprovalStatusRejected
@@ -450,13 +458,34 @@ func (s *PermissionApprovalService) ApprovePermissionRequest(requestID uint, app
 		"approved_at":     now,
 	}
 
-	if err := s.db.Model(&request).Updates(updates).Error; err != nil {
-		return fmt.Errorf("更新申请状态失败: %v", err)
+	// 申请状态与执行记录在同一事务中更新，任一失败都整体回滚
+	var decided *models.SaltCommandApproval
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&request).Updates(updates).Error; err != nil {
+			return fmt.Errorf("更新申请状态失败: %v", err)
+		}
+		if cmdApproval == nil {
+			return nil
+		}
+		var err error
+		decided, err = cmdApproval.HandleDecision(tx, requestID, approverID, input.Approved, input.Comment)
+		if err != nil {
+			return fmt.Errorf("处理危险命令执行申请失败: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
 	// 记录审批日志
 	s.createApprovalLog(requestID, approverID, action, oldStatus, string(newStatus), input.Comment, ipAddress, userAgent)
 
+	// 事务提交后再记录审计并执行被批准的命令
+	if decided != nil {
+		cmdApproval.AfterDecision(decided, input.Comment)
+	}
+
 	// TODO: 发送通知（邮件/钉钉）
 	if request.NotifyEmail {
 		// s.sendEmailNotification(&request, input.Approved, input.Comment)
@@ -474,6 +503,28 @@ func (s *PermissionApprovalService) ApprovePermissionRequest(requestID uint, app
 	return nil
 }
 
+// checkPermissionRequestApprovable 检查申请是否可由 approverID 审批：仅待审批状态，且不能审批自己的申请
+func checkPermissionRequestApprovable(request *models.PermissionRequest, approverID uint) error {
+	if !request.CanBeApproved() {
+		return fmt.Errorf("该申请状态为 %s，无法审批", request.Status)
+	}
+	if request.RequesterID == approverID {
+		return errors.New("不能审批自己的申请")
+	}
+	return nil
+}
+
+// checkPermissionRequestCancelable 检查申请是否可由 userID 取消：仅申请人本人，且仅待审批状态
+func checkPermissionRequestCancelable(request *models.PermissionRequest, userID uint) error {
+	if request.RequesterID != userID {
+		return errors.New("只能取消自己的申请")
+	}
+	if request.Status != models.ApprovalStatusPending {
+		return errors.New("只能取消待审批状态的申请")
+	}
+	return nil
+}
+
 // CancelPermissionRequest 取消权限申请
 func (s *PermissionApprovalService) CancelPermissionRequest(requestID uint, userID uint, reason string) error {
 	var request models.PermissionRequest
@@ -481,19 +532,32 @@ func (s *PermissionApprovalService) CancelPermissionRequest(requestID uint, user
 		return fmt.Errorf("申请记录不存在: %v", err)
 	}
 
-	// 只有申请人可以取消自己的申请
-	if request.RequesterID != userID {
-		return errors.New("只能取消自己的申请")
+	if err := checkPermissionRequestCancelable(&request, userID); err != nil {
+		return err
 	}
 
-	// 只能取消待审批的申请
-	if request.Status != models.ApprovalStatusPending {
-		return errors.New("只能取消待审批状态的申请")
+	var cmdApproval *SaltCommandApprovalService
+	if request.RequestType == models.PermissionRequestTypeSaltCommand {
+		if cmdApproval = GetSaltCommandApprovalService(); cmdApproval == nil {
+			return errors.New("危险命令审批服务未启用，无法取消该申请")
+		}
 	}
 
 	oldStatus := string(request.Status)
-	if err := s.db.Model(&request).Update("status", models.ApprovalStatusCanceled).Error; err != nil {
-		return fmt.Errorf("取消申请失败: %v", err)
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&request).Update("status", models.ApprovalStatusCanceled).Error; err != nil {
+			return fmt.Errorf("取消申请失败: %v", err)
+		}
+		if cmdApproval == nil {
+			return nil
+		}
+		if err := cmdApproval.HandleCancel(tx, requestID); err != nil {
+			return fmt.Errorf("取消危险命令执行申请失败: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
 	s.createApprovalLog(requestID, userID, "cancel", oldStatus, string(models.ApprovalStatusCanceled), reason, "", "")
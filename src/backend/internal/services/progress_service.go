@@ -1,6 +1,7 @@
 package services
 
 import (
+    "fmt"
     "sync"
     "time"
     "github.com/google/uuid"
@@ -22,10 +23,18 @@ type OperationStatus string
 
 const (
     StatusRunning  OperationStatus = "running"
+    StatusPaused   OperationStatus = "paused"
     StatusFailed   OperationStatus = "failed"
     StatusComplete OperationStatus = "complete"
 )
 
+// Control actions accepted by operations that opt in via EnableControl.
+const (
+    ControlPause  = "pause"
+    ControlResume = "resume"
+    ControlAbort  = "abort"
+)
+
 // Operation holds in-memory state for a running progress operation.
 type Operation struct {
     ID          string
@@ -35,8 +44,10 @@ type Operation struct {
     Status      OperationStatus
     Events      []ProgressEvent
 
-    subs map[chan ProgressEvent]struct{}
-    mu   sync.Mutex
+    subs    map[chan ProgressEvent]struct{}
+    control chan string // nil unless the operation accepts pause/resume/abort
+    owner   string      // user allowed to send control actions (admins may always)
+    mu      sync.Mutex
 }
 
 func newOperation(name string) *Operation {
@@ -206,3 +217,73 @@ func (pm *ProgressManager) ListOperations() []*Operation {
     }
     return ops
 }
+
+// EnableControl allows the operation to receive pause/resume/abort actions from
+// owner and returns the channel the worker should read them from.
+func (pm *ProgressManager) EnableControl(id string, owner string) (<-chan string, bool) {
+    op, ok := pm.Get(id)
+    if !ok {
+        return nil, false
+    }
+    op.mu.Lock()
+    defer op.mu.Unlock()
+    if op.control == nil {
+        op.control = make(chan string, 4)
+        op.owner = owner
+    }
+    return op.control, true
+}
+
+// ControlOwner returns the user that may control the operation.
+func (pm *ProgressManager) ControlOwner(id string) (string, bool) {
+    op, ok := pm.Get(id)
+    if !ok {
+        return "", false
+    }
+    op.mu.Lock()
+    defer op.mu.Unlock()
+    return op.owner, true
+}
+
+// SendControl delivers a control action to a running or paused operation.
+func (pm *ProgressManager) SendControl(id string, action string) error {
+    if action != ControlPause && action != ControlResume && action != ControlAbort {
+        return fmt.Errorf("unsupported action %q", action)
+    }
+    op, ok := pm.Get(id)
+    if !ok {
+        return fmt.Errorf("operation not found")
+    }
+    op.mu.Lock()
+    defer op.mu.Unlock()
+    if op.control == nil {
+        return fmt.Errorf("operation does not support control actions")
+    }
+    if op.Status != StatusRunning && op.Status != StatusPaused {
+        return fmt.Errorf("operation already %s", op.Status)
+    }
+    select {
+    case op.control <- action:
+        return nil
+    default:
+        return fmt.Errorf("too many pending control actions")
+    }
+}
+
+// SetPaused switches a running operation between running and paused.
+func (pm *ProgressManager) SetPaused(id string, paused bool) {
+    op, ok := pm.Get(id)
+    if !ok {
+        return
+    }
+    op.mu.Lock()
+    defer op.mu.Unlock()
+    if op.Status != StatusRunning && op.Status != StatusPaused {
+        return
+    }
+    if paused {
+        op.Status = StatusPaused
+    } else {
+        op.Status = StatusRunning
+    }
+}
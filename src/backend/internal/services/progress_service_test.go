@@ -0,0 +1,82 @@
+package services
+
+import "testing"
+
+func TestProgressManager_Control(t *testing.T) {
+	pm := &ProgressManager{ops: make(map[string]*Operation)}
+	op := pm.Start("batch", "开始")
+
+	if err := pm.SendControl(op.ID, ControlPause); err == nil {
+		t.Fatal("未启用控制的操作不应接受控制请求")
+	}
+	if _, ok := pm.EnableControl("missing", "alice"); ok {
+		t.Fatal("不存在的操作不应启用控制")
+	}
+
+	control, ok := pm.EnableControl(op.ID, "alice")
+	if !ok {
+		t.Fatal("启用控制失败")
+	}
+	if again, _ := pm.EnableControl(op.ID, "bob"); again != control {
+		t.Fatal("重复启用应返回同一通道")
+	}
+	if owner, _ := pm.ControlOwner(op.ID); owner != "alice" {
+		t.Fatalf("控制者 = %q, 重复启用不应更换控制者", owner)
+	}
+
+	if err := pm.SendControl(op.ID, "restart"); err == nil {
+		t.Fatal("不支持的动作应被拒绝")
+	}
+	if err := pm.SendControl("missing", ControlAbort); err == nil {
+		t.Fatal("不存在的操作应返回错误")
+	}
+	if err := pm.SendControl(op.ID, ControlPause); err != nil {
+		t.Fatalf("发送暂停失败: %v", err)
+	}
+	if got := <-control; got != ControlPause {
+		t.Fatalf("收到 %q, 期望 %q", got, ControlPause)
+	}
+
+	// 通道缓冲已满时拒绝而不是阻塞
+	for i := 0; i < cap(op.control); i++ {
+		if err := pm.SendControl(op.ID, ControlResume); err != nil {
+			t.Fatalf("第 %d 个控制请求失败: %v", i+1, err)
+		}
+	}
+	if err := pm.SendControl(op.ID, ControlAbort); err == nil {
+		t.Fatal("积压过多的控制请求应被拒绝")
+	}
+	for len(control) > 0 {
+		<-control
+	}
+
+	pm.Complete(op.ID, false, "完成")
+	if err := pm.SendControl(op.ID, ControlAbort); err == nil {
+		t.Fatal("已结束的操作不应接受控制请求")
+	}
+}
+
+func TestProgressManager_SetPaused(t *testing.T) {
+	pm := &ProgressManager{ops: make(map[string]*Operation)}
+	op := pm.Start("batch", "开始")
+	pm.EnableControl(op.ID, "alice")
+
+	pm.SetPaused(op.ID, true)
+	if op.Status != StatusPaused {
+		t.Fatalf("状态 = %s, 期望 %s", op.Status, StatusPaused)
+	}
+	if err := pm.SendControl(op.ID, ControlResume); err != nil {
+		t.Fatalf("暂停中的操作应接受恢复请求: %v", err)
+	}
+	pm.SetPaused(op.ID, false)
+	if op.Status != StatusRunning {
+		t.Fatalf("状态 = %s, 期望 %s", op.Status, StatusRunning)
+	}
+
+	pm.Complete(op.ID, true, "失败")
+	pm.SetPaused(op.ID, true)
+	if op.Status != StatusFailed {
+		t.Fatalf("已结束的操作不应被重新暂停, 状态 = %s", op.Status)
+	}
+	pm.SetPaused("missing", true) // 不存在的操作应安全忽略
+}
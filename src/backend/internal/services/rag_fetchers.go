@@ -0,0 +1,344 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultKnowledgeMaxDocuments = 500
+	maxKnowledgeDocumentSize     = 10 << 20 // 单篇文档上限 10MB
+	defaultJobLogSinceDays       = 7
+	jobLogStderrTail             = 8000 // 作业日志保留的 stderr 末尾字符数
+	jobLogStdoutTail             = 4000 // 作业日志保留的 stdout 末尾字符数
+)
+
+var defaultKnowledgeExtensions = []string{".md", ".markdown", ".txt", ".pdf"}
+
+// KnowledgeSourceDocument 知识源中拉取到的一篇原始文档
+type KnowledgeSourceDocument struct {
+	Path    string // 来源内唯一路径
+	Title   string
+	URL     string
+	OwnerID uint // 0 表示所有用户可检索
+	Data    []byte
+}
+
+// KnowledgeFetcher 知识源文档抓取器
+type KnowledgeFetcher interface {
+	Fetch(ctx context.Context, src *models.KnowledgeSource) ([]KnowledgeSourceDocument, error)
+}
+
+// NewKnowledgeFetcher 按知识源类型创建抓取器
+func NewKnowledgeFetcher(sourceType string, db *gorm.DB, jobService *JobService) (KnowledgeFetcher, error) {
+	switch sourceType {
+	case models.KnowledgeSourceObjectStorage:
+		return &objectStorageKnowledgeFetcher{db: db}, nil
+	case models.KnowledgeSourceGitea:
+		return &giteaKnowledgeFetcher{
+			baseURL: strings.TrimRight(getEnvOrDefault("GITEA_BASE_URL", "http://gitea:3000"), "/"),
+			token:   os.Getenv("GITEA_ADMIN_TOKEN"),
+			client:  &http.Client{Timeout: 30 * time.Second},
+		}, nil
+	case models.KnowledgeSourceJobLogs:
+		if jobService == nil {
+			return nil, fmt.Errorf("job service not available")
+		}
+		return &jobLogKnowledgeFetcher{db: db, jobs: jobService}, nil
+	default:
+		return nil, fmt.Errorf("unsupported knowledge source type: %s", sourceType)
+	}
+}
+
+// knowledgeMaxDocuments 单次同步的文档数上限
+func knowledgeMaxDocuments(settings models.KnowledgeSourceSettings) int {
+	if settings.MaxDocuments > 0 {
+		return settings.MaxDocuments
+	}
+	return defaultKnowledgeMaxDocuments
+}
+
+// knowledgeExtensionAllowed 文件扩展名是否在纳入范围内
+func knowledgeExtensionAllowed(settings models.KnowledgeSourceSettings, name string) bool {
+	extensions := settings.Extensions
+	if len(extensions) == 0 {
+		extensions = defaultKnowledgeExtensions
+	}
+	ext := strings.ToLower(path.Ext(name))
+	for _, allowed := range extensions {
+		allowed = strings.ToLower(allowed)
+		if !strings.HasPrefix(allowed, ".") {
+			allowed = "." + allowed
+		}
+		if ext == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// ---- 对象存储 ----
+
+type objectStorageKnowledgeFetcher struct {
+	db *gorm.DB
+}
+
+func (f *objectStorageKnowledgeFetcher) Fetch(ctx context.Context, src *models.KnowledgeSource) ([]KnowledgeSourceDocument, error) {
+	settings := src.Settings
+	if settings.Bucket == "" {
+		return nil, fmt.Errorf("bucket is required")
+	}
+	var config models.ObjectStorageConfig
+	if err := f.db.First(&config, settings.StorageConfigID).Error; err != nil {
+		return nil, fmt.Errorf("对象存储配置不存在: %v", err)
+	}
+	client, err := minio.New(config.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(config.AccessKey, config.SecretKey, ""),
+		Secure: config.SSLEnabled,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("创建 S3 客户端失败: %v", err)
+	}
+
+	limit := knowledgeMaxDocuments(settings)
+	var docs []KnowledgeSourceDocument
+	for object := range client.ListObjects(ctx, settings.Bucket, minio.ListObjectsOptions{Prefix: settings.Prefix, Recursive: true}) {
+		if object.Err != nil {
+			return nil, fmt.Errorf("列出对象失败: %v", object.Err)
+		}
+		if strings.HasSuffix(object.Key, "/") || !knowledgeExtensionAllowed(settings, object.Key) {
+			continue
+		}
+		if object.Size > maxKnowledgeDocumentSize {
+			logrus.Warnf("[RAG] 跳过过大的对象 %s/%s (%d bytes)", settings.Bucket, object.Key, object.Size)
+			continue
+		}
+		if len(docs) >= limit {
+			break
+		}
+
+		reader, err := client.GetObject(ctx, settings.Bucket, object.Key, minio.GetObjectOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("读取对象 %s 失败: %v", object.Key, err)
+		}
+		data, err := io.ReadAll(io.LimitReader(reader, maxKnowledgeDocumentSize))
+		reader.Close()
+		if err != nil {
+			return nil, fmt.Errorf("读取对象 %s 失败: %v", object.Key, err)
+		}
+		docs = append(docs, KnowledgeSourceDocument{
+			Path: object.Key,
+			URL:  fmt.Sprintf("s3://%s/%s", settings.Bucket, object.Key),
+			Data: data,
+		})
+	}
+	return docs, nil
+}
+
+// ---- Gitea 仓库 ----
+
+type giteaKnowledgeFetcher struct {
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+type giteaTreeResponse struct {
+	Tree []struct {
+		Path string `json:"path"`
+		Type string `json:"type"`
+		Size int64  `json:"size"`
+	} `json:"tree"`
+	Truncated bool `json:"truncated"`
+}
+
+func (f *giteaKnowledgeFetcher) Fetch(ctx context.Context, src *models.KnowledgeSource) ([]KnowledgeSourceDocument, error) {
+	settings := src.Settings
+	if settings.Owner == "" || settings.Repo == "" {
+		return nil, fmt.Errorf("owner and repo are required")
+	}
+	repoPath := fmt.Sprintf("/repos/%s/%s", url.PathEscape(settings.Owner), url.PathEscape(settings.Repo))
+
+	ref := settings.Ref
+	if ref == "" {
+		var repo struct {
+			DefaultBranch string `json:"default_branch"`
+		}
+		if err := f.getJSON(ctx, repoPath, &repo); err != nil {
+			return nil, err
+		}
+		ref = repo.DefaultBranch
+	}
+
+	var tree giteaTreeResponse
+	if err := f.getJSON(ctx, fmt.Sprintf("%s/git/trees/%s?recursive=true&per_page=10000", repoPath, url.PathEscape(ref)), &tree); err != nil {
+		return nil, err
+	}
+	if tree.Truncated {
+		logrus.Warnf("[RAG] Gitea 仓库 %s/%s 文件树被截断，仅同步部分文档", settings.Owner, settings.Repo)
+	}
+
+	prefix := strings.TrimPrefix(settings.Prefix, "/")
+	limit := knowledgeMaxDocuments(settings)
+	var docs []KnowledgeSourceDocument
+	for _, entry := range tree.Tree {
+		if entry.Type != "blob" || !strings.HasPrefix(entry.Path, prefix) || !knowledgeExtensionAllowed(settings, entry.Path) {
+			continue
+		}
+		if entry.Size > maxKnowledgeDocumentSize {
+			continue
+		}
+		if len(docs) >= limit {
+			break
+		}
+		data, err := f.get(ctx, fmt.Sprintf("%s/raw/%s?ref=%s", repoPath, escapeGiteaPath(entry.Path), url.QueryEscape(ref)))
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, KnowledgeSourceDocument{
+			Path: entry.Path,
+			URL:  fmt.Sprintf("%s/%s/%s/src/branch/%s/%s", f.baseURL, settings.Owner, settings.Repo, ref, entry.Path),
+			Data: data,
+		})
+	}
+	return docs, nil
+}
+
+func (f *giteaKnowledgeFetcher) get(ctx context.Context, apiPath string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, f.baseURL+"/api/v1"+apiPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	if f.token != "" {
+		req.Header.Set("Authorization", "token "+f.token)
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("gitea request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxKnowledgeDocumentSize))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("gitea %s returned %d: %s", apiPath, resp.StatusCode, truncateText(string(body), 200))
+	}
+	return body, nil
+}
+
+func (f *giteaKnowledgeFetcher) getJSON(ctx context.Context, apiPath string, out interface{}) error {
+	body, err := f.get(ctx, apiPath)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("decode gitea response: %v", err)
+	}
+	return nil
+}
+
+// escapeGiteaPath 逐段转义仓库内路径
+func escapeGiteaPath(p string) string {
+	segments := strings.Split(p, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// ---- 作业日志 ----
+
+type jobLogKnowledgeFetcher struct {
+	db   *gorm.DB
+	jobs *JobService
+}
+
+func (f *jobLogKnowledgeFetcher) Fetch(ctx context.Context, src *models.KnowledgeSource) ([]KnowledgeSourceDocument, error) {
+	settings := src.Settings
+	statuses := make([]string, 0, len(settings.JobStatuses))
+	for _, status := range settings.JobStatuses {
+		statuses = append(statuses, strings.ToUpper(status))
+	}
+	if len(statuses) == 0 {
+		statuses = []string{"FAILED"}
+	}
+	sinceDays := settings.SinceDays
+	if sinceDays <= 0 {
+		sinceDays = defaultJobLogSinceDays
+	}
+
+	var jobs []models.Job
+	if err := f.db.Where("status IN ? AND updated_at >= ?", statuses, time.Now().AddDate(0, 0, -sinceDays)).
+		Order("updated_at DESC").
+		Limit(knowledgeMaxDocuments(settings)).
+		Find(&jobs).Error; err != nil {
+		return nil, fmt.Errorf("query jobs failed: %v", err)
+	}
+
+	docs := make([]KnowledgeSourceDocument, 0, len(jobs))
+	for _, job := range jobs {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		output, err := f.jobs.GetJobOutput(ctx, strconv.FormatUint(uint64(job.UserID), 10), job.ClusterID, job.ID)
+		if err != nil {
+			logrus.Warnf("[RAG] 读取作业 %d 输出失败: %v", job.ID, err)
+			continue
+		}
+		if output.StdOut == "" && output.StdErr == "" {
+			continue
+		}
+		docs = append(docs, KnowledgeSourceDocument{
+			Path:    fmt.Sprintf("jobs/%d", job.ID),
+			Title:   fmt.Sprintf("作业 %s (#%d)", job.Name, job.JobID),
+			OwnerID: job.UserID,
+			Data:    []byte(formatJobLogDocument(&job, output)),
+		})
+	}
+	return docs, nil
+}
+
+// formatJobLogDocument 作业元信息加 stderr、stdout 末尾内容，组织为 Markdown 便于按标题切分
+func formatJobLogDocument(job *models.Job, output *models.JobOutput) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# 作业 %s (#%d)\n\n", job.Name, job.JobID)
+	fmt.Fprintf(&b, "集群: %s\n分区: %s\n状态: %s\n", job.ClusterID, job.Partition, job.Status)
+	if job.ExitCode != nil {
+		fmt.Fprintf(&b, "退出码: %d\n", *job.ExitCode)
+	}
+	if job.Command != "" {
+		fmt.Fprintf(&b, "命令: %s\n", truncateText(job.Command, 500))
+	}
+	if output.StdErr != "" {
+		fmt.Fprintf(&b, "\n## stderr\n\n%s\n", tailRunes(output.StdErr, jobLogStderrTail))
+	}
+	if output.StdOut != "" {
+		fmt.Fprintf(&b, "\n## stdout\n\n%s\n", tailRunes(output.StdOut, jobLogStdoutTail))
+	}
+	return b.String()
+}
+
+// tailRunes 保留字符串末尾的 n 个字符
+func tailRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return "..." + string(runes[len(runes)-n:])
+}
@@ -0,0 +1,268 @@
+package services
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+// 单个 PDF 内容流解压后的最大长度
+const maxPDFStreamSize = 8 << 20
+
+var pdfStreamPattern = regexp.MustCompile(`(?s)<<(.*?)>>\s*stream\r?\n`)
+
+// extractPDFText 从 PDF 中提取文本（尽力而为）
+// 仅解析未压缩或 FlateDecode 压缩的内容流中的 Tj/TJ/'/" 文本操作符；
+// 使用 CID 字体且无 ToUnicode 映射的 PDF 只能得到不可读字符，此时返回空串
+func extractPDFText(data []byte) string {
+	var out strings.Builder
+	for _, loc := range pdfStreamPattern.FindAllSubmatchIndex(data, -1) {
+		dict := string(data[loc[2]:loc[3]])
+		start := loc[1]
+		end := bytes.Index(data[start:], []byte("endstream"))
+		if end < 0 {
+			break
+		}
+		if strings.Contains(dict, "/Image") || strings.Contains(dict, "/XRef") {
+			continue
+		}
+
+		raw := data[start : start+end]
+		switch {
+		case strings.Contains(dict, "/FlateDecode"):
+			reader, err := zlib.NewReader(bytes.NewReader(raw))
+			if err != nil {
+				continue
+			}
+			decoded, _ := io.ReadAll(io.LimitReader(reader, maxPDFStreamSize))
+			reader.Close()
+			raw = decoded
+		case strings.Contains(dict, "/Filter"):
+			// 其他压缩方式（DCT、LZW 等）不支持
+			continue
+		}
+		if text := pdfContentText(raw); text != "" {
+			out.WriteString(text)
+			out.WriteString("\n")
+		}
+	}
+
+	text := strings.TrimSpace(out.String())
+	if !mostlyReadable(text) {
+		return ""
+	}
+	return text
+}
+
+// pdfContentText 解析内容流中 BT...ET 文本块的字符串操作数
+func pdfContentText(content []byte) string {
+	var out strings.Builder
+	var operands []string
+	inText := false
+	for i := 0; i < len(content); {
+		c := content[i]
+		switch {
+		case c == '(':
+			s, next := pdfLiteralString(content, i)
+			operands = append(operands, s)
+			i = next
+		case c == '<' && i+1 < len(content) && content[i+1] != '<':
+			s, next := pdfHexString(content, i)
+			operands = append(operands, s)
+			i = next
+		case c == '%':
+			for i < len(content) && content[i] != '\n' && content[i] != '\r' {
+				i++
+			}
+		case isPDFRegular(c) && c != '[' && c != ']':
+			start := i
+			for i < len(content) && isPDFRegular(content[i]) && content[i] != '[' && content[i] != ']' && content[i] != '(' && content[i] != '<' {
+				i++
+			}
+			if i == start {
+				i++
+				continue
+			}
+			op := string(content[start:i])
+			switch op {
+			case "BT":
+				inText = true
+				operands = operands[:0]
+			case "ET":
+				inText = false
+				out.WriteString("\n")
+				operands = operands[:0]
+			case "Tj", "TJ":
+				if inText {
+					out.WriteString(strings.Join(operands, ""))
+				}
+				operands = operands[:0]
+			case "'", "\"":
+				if inText {
+					out.WriteString("\n")
+					out.WriteString(strings.Join(operands, ""))
+				}
+				operands = operands[:0]
+			case "Td", "TD", "T*", "Tm":
+				if inText {
+					out.WriteString("\n")
+				}
+				operands = operands[:0]
+			default:
+				// 数字等操作数不影响文本；其余操作符清空字符串操作数
+				if !isPDFNumber(op) {
+					operands = operands[:0]
+				}
+			}
+		default:
+			i++
+		}
+	}
+	return collapseBlankLines(out.String())
+}
+
+// pdfLiteralString 解析 (...) 字面量字符串，返回文本与下一个位置
+func pdfLiteralString(content []byte, i int) (string, int) {
+	var buf []byte
+	depth := 0
+	for i++; i < len(content); i++ {
+		c := content[i]
+		switch c {
+		case '\\':
+			i++
+			if i >= len(content) {
+				break
+			}
+			switch e := content[i]; e {
+			case 'n':
+				buf = append(buf, '\n')
+			case 'r':
+				buf = append(buf, '\r')
+			case 't':
+				buf = append(buf, '\t')
+			case 'b', 'f':
+			case '\r', '\n':
+				// 续行
+			default:
+				if e >= '0' && e <= '7' {
+					v, n := 0, 0
+					for n < 3 && i < len(content) && content[i] >= '0' && content[i] <= '7' {
+						v = v*8 + int(content[i]-'0')
+						i++
+						n++
+					}
+					i--
+					buf = append(buf, byte(v))
+				} else {
+					buf = append(buf, e)
+				}
+			}
+		case '(':
+			depth++
+			buf = append(buf, c)
+		case ')':
+			if depth == 0 {
+				return decodePDFString(buf), i + 1
+			}
+			depth--
+			buf = append(buf, c)
+		default:
+			buf = append(buf, c)
+		}
+	}
+	return decodePDFString(buf), i
+}
+
+// pdfHexString 解析 <...> 十六进制字符串
+func pdfHexString(content []byte, i int) (string, int) {
+	var digits []byte
+	for i++; i < len(content) && content[i] != '>'; i++ {
+		if c := content[i]; (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F') {
+			digits = append(digits, c)
+		}
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	buf := make([]byte, len(digits)/2)
+	for j := range buf {
+		buf[j] = hexNibble(digits[2*j])<<4 | hexNibble(digits[2*j+1])
+	}
+	return decodePDFString(buf), i + 1
+}
+
+// decodePDFString 按 UTF-16BE（带 BOM）或 PDFDocEncoding（近似 Latin-1）解码
+func decodePDFString(buf []byte) string {
+	if len(buf) >= 2 && buf[0] == 0xFE && buf[1] == 0xFF {
+		units := make([]uint16, 0, (len(buf)-2)/2)
+		for j := 2; j+1 < len(buf); j += 2 {
+			units = append(units, uint16(buf[j])<<8|uint16(buf[j+1]))
+		}
+		return string(utf16.Decode(units))
+	}
+	runes := make([]rune, len(buf))
+	for j, b := range buf {
+		runes[j] = rune(b)
+	}
+	return string(runes)
+}
+
+func hexNibble(c byte) byte {
+	switch {
+	case c >= 'a':
+		return c - 'a' + 10
+	case c >= 'A':
+		return c - 'A' + 10
+	default:
+		return c - '0'
+	}
+}
+
+func isPDFRegular(c byte) bool {
+	switch c {
+	case ' ', '\t', '\r', '\n', '\f', 0, '/', '{', '}', ')', '>':
+		return false
+	}
+	return true
+}
+
+func isPDFNumber(s string) bool {
+	for _, r := range s {
+		if (r < '0' || r > '9') && r != '.' && r != '-' && r != '+' {
+			return false
+		}
+	}
+	return true
+}
+
+// mostlyReadable 可打印字符占比足够高时才认为提取成功
+func mostlyReadable(text string) bool {
+	if text == "" {
+		return false
+	}
+	total, readable := 0, 0
+	for _, r := range text {
+		total++
+		if unicode.IsPrint(r) || unicode.IsSpace(r) {
+			readable++
+		}
+	}
+	return readable*10 >= total*9
+}
+
+func collapseBlankLines(text string) string {
+	lines := strings.Split(text, "\n")
+	out := lines[:0]
+	for _, line := range lines {
+		line = strings.TrimRightFunc(line, unicode.IsSpace)
+		if line == "" && (len(out) == 0 || out[len(out)-1] == "") {
+			continue
+		}
+		out = append(out, line)
+	}
+	return strings.TrimSpace(strings.Join(out, "\n"))
+}
@@ -0,0 +1,854 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/database"
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/services/ai_providers"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	ragChunkSize          = 1500 // 单个片段的最大字符数
+	ragChunkOverlap       = 200  // 同一小节内相邻片段重叠的字符数
+	ragEmbedBatchSize     = 16
+	ragDefaultTopK        = 4
+	ragMaxTopK            = 20
+	ragDefaultMinScore    = 0.3
+	ragSyncTimeout        = 30 * time.Minute
+	ragRetrieveTimeout    = 15 * time.Second
+	ragCitationSnippetLen = 300
+)
+
+// ErrKnowledgeSyncRunning 知识源正在同步
+var ErrKnowledgeSyncRunning = errors.New("knowledge source is already syncing")
+
+// RAGCitation 检索命中的片段，Index 为注入提示词时的引用编号（从 1 开始）
+type RAGCitation struct {
+	Index      int     `json:"index"`
+	SourceID   uint    `json:"source_id"`
+	SourceName string  `json:"source_name"`
+	DocumentID uint    `json:"document_id"`
+	Path       string  `json:"path"`
+	Title      string  `json:"title"`
+	URL        string  `json:"url,omitempty"`
+	Heading    string  `json:"heading,omitempty"`
+	Snippet    string  `json:"snippet"`
+	Score      float64 `json:"score"`
+	content    string
+}
+
+// ragChunk 文档切分结果
+type ragChunk struct {
+	Heading string
+	Content string
+}
+
+// ragHit 向量检索命中
+type ragHit struct {
+	chunkID uint
+	score   float64
+}
+
+// ragIndexEntry 本地索引中的一个片段向量（已归一化）
+type ragIndexEntry struct {
+	chunkID uint
+	ownerID uint
+	vector  []float32
+}
+
+// ragIndex 单个知识源的本地向量索引，知识源重新同步后失效
+type ragIndex struct {
+	version string
+	entries []ragIndexEntry
+}
+
+// RAGService 知识源同步（拉取、切分、向量化）与检索增强
+// 数据库为 PostgreSQL 且可启用 pgvector 扩展时在数据库中检索，否则使用进程内的余弦相似度索引
+type RAGService struct {
+	db        *gorm.DB
+	jobs      *JobService
+	providers ai_providers.ProviderFactory
+	pgvector  bool
+	syncing   sync.Map
+	mu        sync.Mutex
+	indexes   map[uint]*ragIndex
+}
+
+var (
+	ragServiceInstance *RAGService
+	ragServiceOnce     sync.Once
+)
+
+// NewRAGService 创建检索增强服务（单例）
+func NewRAGService(db *gorm.DB, jobService *JobService) *RAGService {
+	ragServiceOnce.Do(func() {
+		s := &RAGService{
+			db:        db,
+			jobs:      jobService,
+			providers: ai_providers.NewProviderFactory(),
+			indexes:   make(map[uint]*ragIndex),
+		}
+		if err := db.AutoMigrate(&models.KnowledgeSource{}, &models.KnowledgeDocument{}, &models.KnowledgeChunk{}); err != nil {
+			log.Printf("[RAG] 自动迁移失败: %v", err)
+		}
+		// 进程重启时中断的同步任务不会继续执行
+		db.Model(&models.KnowledgeSource{}).Where("status = ?", models.KnowledgeSyncRunning).
+			Updates(map[string]interface{}{"status": models.KnowledgeSyncFailed, "last_error": "sync interrupted"})
+		s.pgvector = s.enablePGVector()
+		ragServiceInstance = s
+	})
+	return ragServiceInstance
+}
+
+// GetRAGService 获取检索增强服务实例，未初始化时使用全局数据库连接
+func GetRAGService() *RAGService {
+	if ragServiceInstance == nil && database.DB != nil {
+		return NewRAGService(database.DB, nil)
+	}
+	return ragServiceInstance
+}
+
+// enablePGVector 按 RAG_VECTOR_STORE（auto、pgvector、local）决定是否使用 pgvector 存储向量
+func (s *RAGService) enablePGVector() bool {
+	mode := getEnvOrDefault("RAG_VECTOR_STORE", "auto")
+	if mode == "local" || s.db.Dialector.Name() != "postgres" {
+		return false
+	}
+	if err := s.db.Exec("CREATE EXTENSION IF NOT EXISTS vector").Error; err != nil {
+		log.Printf("[RAG] pgvector 不可用，使用本地向量索引: %v", err)
+		return false
+	}
+	if err := s.db.Exec("ALTER TABLE knowledge_chunks ADD COLUMN IF NOT EXISTS embedding_vec vector").Error; err != nil {
+		log.Printf("[RAG] 添加向量列失败，使用本地向量索引: %v", err)
+		return false
+	}
+	return true
+}
+
+// VectorStore 当前使用的向量存储
+func (s *RAGService) VectorStore() string {
+	if s.pgvector {
+		return "pgvector"
+	}
+	return "local"
+}
+
+// ---- 知识源管理 ----
+
+// ListSources 列出知识源
+func (s *RAGService) ListSources() ([]models.KnowledgeSource, error) {
+	var sources []models.KnowledgeSource
+	if err := s.db.Order("id ASC").Find(&sources).Error; err != nil {
+		return nil, err
+	}
+	return sources, nil
+}
+
+// GetSource 获取知识源
+func (s *RAGService) GetSource(id uint) (*models.KnowledgeSource, error) {
+	var source models.KnowledgeSource
+	if err := s.db.First(&source, id).Error; err != nil {
+		return nil, err
+	}
+	return &source, nil
+}
+
+// CreateSource 创建知识源
+func (s *RAGService) CreateSource(req *models.CreateKnowledgeSourceRequest, userID uint) (*models.KnowledgeSource, error) {
+	source := &models.KnowledgeSource{CreatedBy: userID, Enabled: true, Status: models.KnowledgeSyncIdle}
+	if err := s.applySourceRequest(source, req); err != nil {
+		return nil, err
+	}
+	if err := s.db.Create(source).Error; err != nil {
+		return nil, err
+	}
+	return source, nil
+}
+
+// UpdateSource 更新知识源；更换向量模型后需重新同步，旧片段在下次同步时全部重新向量化
+func (s *RAGService) UpdateSource(id uint, req *models.CreateKnowledgeSourceRequest) (*models.KnowledgeSource, error) {
+	source, err := s.GetSource(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applySourceRequest(source, req); err != nil {
+		return nil, err
+	}
+	if err := s.db.Save(source).Error; err != nil {
+		return nil, err
+	}
+	return source, nil
+}
+
+// DeleteSource 删除知识源及其文档、片段
+func (s *RAGService) DeleteSource(id uint) error {
+	if _, running := s.syncing.Load(id); running {
+		return ErrKnowledgeSyncRunning
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("source_id = ?", id).Delete(&models.KnowledgeChunk{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("source_id = ?", id).Delete(&models.KnowledgeDocument{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.KnowledgeSource{}, id).Error
+	})
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	delete(s.indexes, id)
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *RAGService) applySourceRequest(source *models.KnowledgeSource, req *models.CreateKnowledgeSourceRequest) error {
+	if _, err := NewKnowledgeFetcher(req.Type, s.db, s.jobs); err != nil {
+		return err
+	}
+	if _, _, err := s.embedder(req.EmbeddingConfigID); err != nil {
+		return err
+	}
+	source.Name = strings.TrimSpace(req.Name)
+	source.Type = req.Type
+	source.Settings = req.Settings
+	source.EmbeddingConfigID = req.EmbeddingConfigID
+	if req.Enabled != nil {
+		source.Enabled = *req.Enabled
+	}
+	return nil
+}
+
+// ListDocuments 列出知识源中的文档
+func (s *RAGService) ListDocuments(sourceID uint) ([]models.KnowledgeDocument, error) {
+	var docs []models.KnowledgeDocument
+	if err := s.db.Where("source_id = ?", sourceID).Order("path ASC").Find(&docs).Error; err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+// embedder 按 AI 配置创建向量化提供商，返回提供商与模型名
+func (s *RAGService) embedder(configID uint) (ai_providers.EmbeddingProvider, string, error) {
+	var config models.AIAssistantConfig
+	if err := s.db.First(&config, configID).Error; err != nil {
+		return nil, "", fmt.Errorf("embedding config %d not found", configID)
+	}
+	if config.APIKey != "" && database.CryptoService != nil && database.CryptoService.IsEncrypted(config.APIKey) {
+		config.APIKey = database.CryptoService.DecryptSafely(config.APIKey)
+	}
+	provider, err := s.providers.CreateProvider(&config)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create embedding provider: %v", err)
+	}
+	embedder, ok := provider.(ai_providers.EmbeddingProvider)
+	if !ok {
+		return nil, "", fmt.Errorf("provider %s does not support embeddings", config.Provider)
+	}
+	return embedder, config.Model, nil
+}
+
+// ---- 同步 ----
+
+// SyncSource 在后台同步知识源；同一知识源同时只允许一个同步任务
+func (s *RAGService) SyncSource(id uint) error {
+	source, err := s.GetSource(id)
+	if err != nil {
+		return err
+	}
+	if _, running := s.syncing.LoadOrStore(id, true); running {
+		return ErrKnowledgeSyncRunning
+	}
+	s.db.Model(source).Updates(map[string]interface{}{"status": models.KnowledgeSyncRunning, "last_error": ""})
+
+	go func() {
+		defer s.syncing.Delete(id)
+		ctx, cancel := context.WithTimeout(context.Background(), ragSyncTimeout)
+		defer cancel()
+		if err := s.syncSource(ctx, source); err != nil {
+			logrus.Warnf("[RAG] 知识源 %s 同步失败: %v", source.Name, err)
+		}
+	}()
+	return nil
+}
+
+// syncSource 拉取文档，仅对内容或向量模型变化的文档重新切分与向量化，并删除来源中已不存在的文档
+func (s *RAGService) syncSource(ctx context.Context, source *models.KnowledgeSource) error {
+	var syncErrors []string
+	finish := func(err error) error {
+		now := time.Now()
+		updates := map[string]interface{}{"status": models.KnowledgeSyncIdle, "last_sync_at": &now, "last_error": ""}
+		if err != nil {
+			syncErrors = append([]string{err.Error()}, syncErrors...)
+		}
+		if len(syncErrors) > 0 {
+			updates["status"] = models.KnowledgeSyncFailed
+			updates["last_error"] = truncateText(strings.Join(syncErrors, "; "), 2000)
+		}
+		var docCount, chunkCount int64
+		s.db.Model(&models.KnowledgeDocument{}).Where("source_id = ?", source.ID).Count(&docCount)
+		s.db.Model(&models.KnowledgeChunk{}).Where("source_id = ?", source.ID).Count(&chunkCount)
+		updates["document_count"] = int(docCount)
+		updates["chunk_count"] = int(chunkCount)
+		s.db.Model(&models.KnowledgeSource{}).Where("id = ?", source.ID).Updates(updates)
+		return err
+	}
+
+	embedder, model, err := s.embedder(source.EmbeddingConfigID)
+	if err != nil {
+		return finish(err)
+	}
+	fetcher, err := NewKnowledgeFetcher(source.Type, s.db, s.jobs)
+	if err != nil {
+		return finish(err)
+	}
+	fetched, err := fetcher.Fetch(ctx, source)
+	if err != nil {
+		return finish(fmt.Errorf("fetch failed: %v", err))
+	}
+
+	var existing []models.KnowledgeDocument
+	if err := s.db.Where("source_id = ?", source.ID).Find(&existing).Error; err != nil {
+		return finish(err)
+	}
+	existingByPath := make(map[string]*models.KnowledgeDocument, len(existing))
+	for i := range existing {
+		existingByPath[existing[i].Path] = &existing[i]
+	}
+
+	seen := make(map[string]bool, len(fetched))
+	for _, doc := range fetched {
+		seen[doc.Path] = true
+		hash := knowledgeContentHash(doc, source.EmbeddingConfigID, model)
+		if current, ok := existingByPath[doc.Path]; ok && current.ContentHash == hash {
+			continue
+		}
+		if err := s.indexDocument(ctx, source, embedder, model, doc, hash, existingByPath[doc.Path]); err != nil {
+			syncErrors = append(syncErrors, fmt.Sprintf("%s: %v", doc.Path, err))
+			if ctx.Err() != nil {
+				return finish(ctx.Err())
+			}
+		}
+	}
+
+	for _, doc := range existing {
+		if seen[doc.Path] {
+			continue
+		}
+		if err := s.deleteDocument(doc.ID); err != nil {
+			syncErrors = append(syncErrors, fmt.Sprintf("%s: %v", doc.Path, err))
+		}
+	}
+	return finish(nil)
+}
+
+// indexDocument 提取文本、切分、向量化后替换文档的全部片段
+func (s *RAGService) indexDocument(ctx context.Context, source *models.KnowledgeSource, embedder ai_providers.EmbeddingProvider, model string, doc KnowledgeSourceDocument, hash string, current *models.KnowledgeDocument) error {
+	text := knowledgeDocumentText(doc.Path, doc.Data)
+	chunks := splitKnowledgeText(text, ragChunkSize, ragChunkOverlap)
+	title := doc.Title
+	if title == "" {
+		title = knowledgeDocumentTitle(doc.Path, text)
+	}
+
+	inputs := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		inputs[i] = chunk.embeddingInput(title)
+	}
+	vectors := make([][]float32, 0, len(inputs))
+	for start := 0; start < len(inputs); start += ragEmbedBatchSize {
+		end := start + ragEmbedBatchSize
+		if end > len(inputs) {
+			end = len(inputs)
+		}
+		batch, err := embedder.Embed(ctx, model, inputs[start:end])
+		if err != nil {
+			return err
+		}
+		if len(batch) != end-start {
+			return fmt.Errorf("embedding count mismatch: got %d, want %d", len(batch), end-start)
+		}
+		vectors = append(vectors, batch...)
+	}
+
+	record := models.KnowledgeDocument{SourceID: source.ID, Path: doc.Path}
+	if current != nil {
+		record = *current
+	}
+	record.Title = truncateRunes(title, 255)
+	record.URL = doc.URL
+	record.OwnerID = doc.OwnerID
+	record.ContentHash = hash
+	record.ChunkCount = len(chunks)
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&record).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("document_id = ?", record.ID).Delete(&models.KnowledgeChunk{}).Error; err != nil {
+			return err
+		}
+		for i, chunk := range chunks {
+			row := models.KnowledgeChunk{
+				SourceID:   source.ID,
+				DocumentID: record.ID,
+				OwnerID:    record.OwnerID,
+				Ordinal:    i,
+				Heading:    truncateRunes(chunk.Heading, 500),
+				Content:    chunk.Content,
+				Dimensions: len(vectors[i]),
+				Embedding:  vectors[i],
+			}
+			if err := tx.Create(&row).Error; err != nil {
+				return err
+			}
+			if s.pgvector {
+				if err := tx.Exec("UPDATE knowledge_chunks SET embedding_vec = ?::vector WHERE id = ?", pgvectorLiteral(vectors[i]), row.ID).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+func (s *RAGService) deleteDocument(id uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("document_id = ?", id).Delete(&models.KnowledgeChunk{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.KnowledgeDocument{}, id).Error
+	})
+}
+
+// knowledgeContentHash 内容与向量模型共同决定是否需要重新向量化
+func knowledgeContentHash(doc KnowledgeSourceDocument, configID uint, model string) string {
+	h := sha256.New()
+	h.Write(doc.Data)
+	fmt.Fprintf(h, "\x00%s\x00%d\x00%s\x00%d", doc.Title, configID, model, doc.OwnerID)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// ---- 检索 ----
+
+// Retrieve 按助手配置的知识源检索与问题最相关的片段；非管理员只能命中共享文档与本人的作业日志
+func (s *RAGService) Retrieve(ctx context.Context, cfg *models.RAGConfig, query string, userID uint, isAdmin bool) ([]RAGCitation, error) {
+	if cfg == nil || len(cfg.SourceIDs) == 0 || strings.TrimSpace(query) == "" {
+		return nil, nil
+	}
+	return s.Search(ctx, models.KnowledgeSearchRequest{
+		Query:     query,
+		SourceIDs: cfg.SourceIDs,
+		TopK:      cfg.TopK,
+		MinScore:  cfg.MinScore,
+	}, userID, isAdmin)
+}
+
+// Search 在指定知识源（为空时为全部启用的知识源）中检索；使用同一向量模型的知识源共用一次查询向量化
+func (s *RAGService) Search(ctx context.Context, req models.KnowledgeSearchRequest, userID uint, isAdmin bool) ([]RAGCitation, error) {
+	topK := req.TopK
+	if topK <= 0 {
+		topK = ragDefaultTopK
+	}
+	if topK > ragMaxTopK {
+		topK = ragMaxTopK
+	}
+	minScore := req.MinScore
+	if minScore <= 0 {
+		minScore = ragDefaultMinScore
+	}
+
+	query := s.db.Where("enabled = ?", true)
+	if len(req.SourceIDs) > 0 {
+		query = query.Where("id IN ?", req.SourceIDs)
+	}
+	var sources []models.KnowledgeSource
+	if err := query.Find(&sources).Error; err != nil {
+		return nil, err
+	}
+	sourcesByID := make(map[uint]*models.KnowledgeSource, len(sources))
+	groups := make(map[uint][]*models.KnowledgeSource)
+	for i := range sources {
+		sourcesByID[sources[i].ID] = &sources[i]
+		groups[sources[i].EmbeddingConfigID] = append(groups[sources[i].EmbeddingConfigID], &sources[i])
+	}
+
+	var hits []ragHit
+	var errs []string
+	for configID, group := range groups {
+		embedder, model, err := s.embedder(configID)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		vectors, err := embedder.Embed(ctx, model, []string{req.Query})
+		if err != nil || len(vectors) != 1 {
+			errs = append(errs, fmt.Sprintf("embed query: %v", err))
+			continue
+		}
+		var groupHits []ragHit
+		if s.pgvector {
+			groupHits, err = s.searchPGVector(group, vectors[0], topK, userID, isAdmin)
+		} else {
+			groupHits, err = s.searchLocal(group, vectors[0], topK, userID, isAdmin)
+		}
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		hits = append(hits, groupHits...)
+	}
+	if len(hits) == 0 && len(errs) > 0 {
+		return nil, fmt.Errorf("knowledge search failed: %s", strings.Join(errs, "; "))
+	}
+
+	hits = rankRAGHits(hits, topK, minScore)
+	return s.buildCitations(hits, sourcesByID)
+}
+
+// rankRAGHits 按相似度降序取前 topK 个不低于 minScore 的命中
+func rankRAGHits(hits []ragHit, topK int, minScore float64) []ragHit {
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].score > hits[j].score })
+	result := make([]ragHit, 0, topK)
+	for _, hit := range hits {
+		if hit.score < minScore || len(result) >= topK {
+			break
+		}
+		result = append(result, hit)
+	}
+	return result
+}
+
+func (s *RAGService) searchPGVector(sources []*models.KnowledgeSource, vector []float32, topK int, userID uint, isAdmin bool) ([]ragHit, error) {
+	ids := make([]uint, len(sources))
+	for i, source := range sources {
+		ids[i] = source.ID
+	}
+	literal := pgvectorLiteral(vector)
+	query := s.db.Table("knowledge_chunks").
+		Select("id, 1 - (embedding_vec <=> ?::vector) AS score", literal).
+		Where("source_id IN ? AND dimensions = ? AND embedding_vec IS NOT NULL", ids, len(vector))
+	if !isAdmin {
+		query = query.Where("owner_id = 0 OR owner_id = ?", userID)
+	}
+	var rows []struct {
+		ID    uint
+		Score float64
+	}
+	if err := query.Order(gorm.Expr("embedding_vec <=> ?::vector", literal)).Limit(topK).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	hits := make([]ragHit, len(rows))
+	for i, row := range rows {
+		hits[i] = ragHit{chunkID: row.ID, score: row.Score}
+	}
+	return hits, nil
+}
+
+func (s *RAGService) searchLocal(sources []*models.KnowledgeSource, vector []float32, topK int, userID uint, isAdmin bool) ([]ragHit, error) {
+	query := normalizeVector(vector)
+	var hits []ragHit
+	for _, source := range sources {
+		index, err := s.localIndex(source)
+		if err != nil {
+			return nil, err
+		}
+		hits = append(hits, index.search(query, topK, userID, isAdmin)...)
+	}
+	return hits, nil
+}
+
+// localIndex 返回知识源的本地索引，知识源同步后按 LastSyncAt 与片段数重新加载
+func (s *RAGService) localIndex(source *models.KnowledgeSource) (*ragIndex, error) {
+	version := fmt.Sprintf("%d", source.ChunkCount)
+	if source.LastSyncAt != nil {
+		version += "@" + source.LastSyncAt.Format(time.RFC3339Nano)
+	}
+	s.mu.Lock()
+	index, ok := s.indexes[source.ID]
+	s.mu.Unlock()
+	if ok && index.version == version {
+		return index, nil
+	}
+
+	var chunks []models.KnowledgeChunk
+	if err := s.db.Select("id, owner_id, embedding").Where("source_id = ?", source.ID).Find(&chunks).Error; err != nil {
+		return nil, err
+	}
+	index = &ragIndex{version: version, entries: make([]ragIndexEntry, 0, len(chunks))}
+	for _, chunk := range chunks {
+		if len(chunk.Embedding) == 0 {
+			continue
+		}
+		index.entries = append(index.entries, ragIndexEntry{chunkID: chunk.ID, ownerID: chunk.OwnerID, vector: normalizeVector(chunk.Embedding)})
+	}
+	s.mu.Lock()
+	s.indexes[source.ID] = index
+	s.mu.Unlock()
+	return index, nil
+}
+
+// search 对已归一化的查询向量计算余弦相似度；维度不同的片段（更换过向量模型）跳过
+func (idx *ragIndex) search(query []float32, topK int, userID uint, isAdmin bool) []ragHit {
+	var hits []ragHit
+	for _, entry := range idx.entries {
+		if len(entry.vector) != len(query) || (!isAdmin && entry.ownerID != 0 && entry.ownerID != userID) {
+			continue
+		}
+		var dot float64
+		for i, v := range entry.vector {
+			dot += float64(v) * float64(query[i])
+		}
+		hits = append(hits, ragHit{chunkID: entry.chunkID, score: dot})
+	}
+	return rankRAGHits(hits, topK, math.Inf(-1))
+}
+
+func (s *RAGService) buildCitations(hits []ragHit, sources map[uint]*models.KnowledgeSource) ([]RAGCitation, error) {
+	if len(hits) == 0 {
+		return nil, nil
+	}
+	ids := make([]uint, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.chunkID
+	}
+	var chunks []models.KnowledgeChunk
+	if err := s.db.Select("id, source_id, document_id, heading, content").Where("id IN ?", ids).Find(&chunks).Error; err != nil {
+		return nil, err
+	}
+	chunkByID := make(map[uint]*models.KnowledgeChunk, len(chunks))
+	docIDs := make([]uint, 0, len(chunks))
+	for i := range chunks {
+		chunkByID[chunks[i].ID] = &chunks[i]
+		docIDs = append(docIDs, chunks[i].DocumentID)
+	}
+	var docs []models.KnowledgeDocument
+	if err := s.db.Where("id IN ?", docIDs).Find(&docs).Error; err != nil {
+		return nil, err
+	}
+	docByID := make(map[uint]*models.KnowledgeDocument, len(docs))
+	for i := range docs {
+		docByID[docs[i].ID] = &docs[i]
+	}
+
+	citations := make([]RAGCitation, 0, len(hits))
+	for _, hit := range hits {
+		chunk, ok := chunkByID[hit.chunkID]
+		if !ok {
+			continue
+		}
+		citation := RAGCitation{
+			Index:      len(citations) + 1,
+			SourceID:   chunk.SourceID,
+			DocumentID: chunk.DocumentID,
+			Heading:    chunk.Heading,
+			Snippet:    truncateRunes(chunk.Content, ragCitationSnippetLen),
+			Score:      math.Round(hit.score*1000) / 1000,
+			content:    chunk.Content,
+		}
+		if source, ok := sources[chunk.SourceID]; ok {
+			citation.SourceName = source.Name
+		}
+		if doc, ok := docByID[chunk.DocumentID]; ok {
+			citation.Path = doc.Path
+			citation.Title = doc.Title
+			citation.URL = doc.URL
+		}
+		citations = append(citations, citation)
+	}
+	return citations, nil
+}
+
+// FormatRAGContext 将检索结果组织为系统提示词中的参考资料段落
+func FormatRAGContext(citations []RAGCitation) string {
+	if len(citations) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("以下是从内部文档、运维手册与作业日志中检索到的参考资料。回答时优先依据这些资料，")
+	b.WriteString("引用时在句末用 [编号] 标注来源；资料与问题无关时忽略，不要编造引用。\n")
+	for _, c := range citations {
+		fmt.Fprintf(&b, "\n[%d] %s", c.Index, c.Title)
+		if c.Heading != "" {
+			fmt.Fprintf(&b, " > %s", c.Heading)
+		}
+		fmt.Fprintf(&b, " (%s)\n%s\n", c.Path, c.content)
+	}
+	return b.String()
+}
+
+// ---- 文本处理 ----
+
+// knowledgeDocumentText 按扩展名提取文档文本
+func knowledgeDocumentText(name string, data []byte) string {
+	if strings.EqualFold(path.Ext(name), ".pdf") {
+		return extractPDFText(data)
+	}
+	if !utf8.Valid(data) {
+		return strings.ToValidUTF8(string(data), "")
+	}
+	return string(data)
+}
+
+// knowledgeDocumentTitle 取 Markdown 一级标题，否则使用文件名
+func knowledgeDocumentTitle(name, text string) string {
+	for _, line := range strings.SplitN(text, "\n", 50) {
+		if strings.HasPrefix(line, "# ") {
+			return strings.TrimSpace(strings.TrimPrefix(line, "# "))
+		}
+	}
+	return path.Base(name)
+}
+
+func (c ragChunk) embeddingInput(title string) string {
+	if c.Heading == "" {
+		return title + "\n" + c.Content
+	}
+	return title + " > " + c.Heading + "\n" + c.Content
+}
+
+// splitKnowledgeText 按 Markdown 标题分节，超长小节按段落合并切分，单段过长时按字符窗口切分并保留重叠
+func splitKnowledgeText(text string, size, overlap int) []ragChunk {
+	type section struct {
+		heading string
+		body    []string
+	}
+	var sections []section
+	var headings []string
+	current := section{}
+	inFence := false
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			inFence = !inFence
+		}
+		if level := markdownHeadingLevel(trimmed); level > 0 && !inFence {
+			sections = append(sections, current)
+			if level <= len(headings) {
+				headings = headings[:level-1]
+			}
+			for len(headings) < level-1 {
+				headings = append(headings, "")
+			}
+			headings = append(headings, strings.TrimSpace(trimmed[level:]))
+			current = section{heading: joinHeadings(headings)}
+			continue
+		}
+		current.body = append(current.body, line)
+	}
+	sections = append(sections, current)
+
+	var chunks []ragChunk
+	for _, sec := range sections {
+		body := strings.TrimSpace(strings.Join(sec.body, "\n"))
+		if body == "" {
+			continue
+		}
+		for _, piece := range splitByParagraphs(body, size, overlap) {
+			chunks = append(chunks, ragChunk{Heading: sec.heading, Content: piece})
+		}
+	}
+	return chunks
+}
+
+func markdownHeadingLevel(line string) int {
+	level := 0
+	for level < len(line) && level < 6 && line[level] == '#' {
+		level++
+	}
+	if level == 0 || level >= len(line) || line[level] != ' ' {
+		return 0
+	}
+	return level
+}
+
+func joinHeadings(headings []string) string {
+	parts := make([]string, 0, len(headings))
+	for _, h := range headings {
+		if h != "" {
+			parts = append(parts, h)
+		}
+	}
+	return strings.Join(parts, " > ")
+}
+
+func splitByParagraphs(body string, size, overlap int) []string {
+	if utf8.RuneCountInString(body) <= size {
+		return []string{body}
+	}
+	var pieces []string
+	var current []rune
+	flush := func() {
+		if text := strings.TrimSpace(string(current)); text != "" {
+			pieces = append(pieces, text)
+		}
+		if len(current) > overlap {
+			current = append([]rune{}, current[len(current)-overlap:]...)
+		} else {
+			current = nil
+		}
+	}
+	for _, para := range strings.Split(body, "\n\n") {
+		runes := []rune(strings.TrimSpace(para))
+		if len(runes) == 0 {
+			continue
+		}
+		if len(current)+len(runes)+2 > size && len(current) > overlap {
+			flush()
+		}
+		if len(current) > 0 {
+			current = append(current, '\n', '\n')
+		}
+		current = append(current, runes...)
+		for len(current) > size {
+			pieces = append(pieces, strings.TrimSpace(string(current[:size])))
+			current = append([]rune{}, current[size-overlap:]...)
+		}
+	}
+	if strings.TrimSpace(string(current)) != "" && (len(pieces) == 0 || len(current) > overlap) {
+		pieces = append(pieces, strings.TrimSpace(string(current)))
+	}
+	return pieces
+}
+
+func normalizeVector(v []float32) []float32 {
+	var sum float64
+	for _, f := range v {
+		sum += float64(f) * float64(f)
+	}
+	out := make([]float32, len(v))
+	if sum == 0 {
+		return out
+	}
+	norm := math.Sqrt(sum)
+	for i, f := range v {
+		out[i] = float32(float64(f) / norm)
+	}
+	return out
+}
+
+// pgvectorLiteral pgvector 文本格式 [x,y,...]
+func pgvectorLiteral(v []float32) string {
+	parts := make([]string, len(v))
+	for i, f := range v {
+		parts[i] = strconv.FormatFloat(float64(f), 'g', -1, 32)
+	}
+	return "[" + strings.Join(parts, ",") + "]"
+}
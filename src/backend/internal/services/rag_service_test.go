@@ -0,0 +1,188 @@
+package services
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/services/ai_providers"
+)
+
+func TestSplitKnowledgeText(t *testing.T) {
+	long := strings.Repeat("甲", 80) + "\n\n" + strings.Repeat("乙", 80) + "\n\n" + strings.Repeat("丙", 80)
+	text := "# 运维手册\n\n简介\n\n## 故障处理\n\n### GPU 掉卡\n\n" + long +
+		"\n\n```bash\n# 这是注释不是标题\nnvidia-smi\n```\n\n## 联系人\n\n值班表"
+
+	chunks := splitKnowledgeText(text, 100, 20)
+	if chunks[0].Heading != "运维手册" || chunks[0].Content != "简介" {
+		t.Fatalf("一级标题下的内容应单独成段: %+v", chunks[0])
+	}
+	last := chunks[len(chunks)-1]
+	if last.Heading != "运维手册 > 联系人" || last.Content != "值班表" {
+		t.Fatalf("同级标题应替换上一个标题: %+v", last)
+	}
+
+	var gpu []ragChunk
+	for _, chunk := range chunks {
+		if chunk.Heading == "运维手册 > 故障处理 > GPU 掉卡" {
+			gpu = append(gpu, chunk)
+		}
+	}
+	if len(gpu) < 3 {
+		t.Fatalf("超长小节应切分为多个片段: %+v", gpu)
+	}
+	for _, chunk := range gpu {
+		if n := len([]rune(chunk.Content)); n > 100 {
+			t.Fatalf("片段不应超过上限: %d", n)
+		}
+	}
+	if !strings.HasPrefix(gpu[1].Content, strings.Repeat("甲", 20)) {
+		t.Fatalf("相邻片段应保留重叠: %q", gpu[1].Content)
+	}
+	if !strings.Contains(gpu[len(gpu)-1].Content, "# 这是注释不是标题") {
+		t.Fatalf("代码块中的 # 不应视为标题: %+v", gpu[len(gpu)-1])
+	}
+}
+
+func TestEmbeddingVectorRoundTrip(t *testing.T) {
+	original := models.EmbeddingVector{0.5, -1.25, 3e-7, float32(math.Pi)}
+	value, err := original.Value()
+	if err != nil {
+		t.Fatalf("value failed: %v", err)
+	}
+	var decoded models.EmbeddingVector
+	if err := decoded.Scan([]byte(value.(string))); err != nil {
+		t.Fatalf("scan failed: %v", err)
+	}
+	if len(decoded) != len(original) {
+		t.Fatalf("长度不一致: %v", decoded)
+	}
+	for i := range original {
+		if decoded[i] != original[i] {
+			t.Fatalf("第 %d 维不一致: %v != %v", i, decoded[i], original[i])
+		}
+	}
+	if err := decoded.Scan("not-base64!"); err == nil {
+		t.Fatalf("非法内容应返回错误")
+	}
+	if got := pgvectorLiteral([]float32{0.5, -2}); got != "[0.5,-2]" {
+		t.Fatalf("pgvector 文本格式错误: %s", got)
+	}
+}
+
+// buildTestPDF 构造包含一个 FlateDecode 内容流与一个未压缩内容流的最小 PDF
+func buildTestPDF(t *testing.T, compressed, plain string) []byte {
+	t.Helper()
+	var z bytes.Buffer
+	w := zlib.NewWriter(&z)
+	w.Write([]byte(compressed))
+	w.Close()
+
+	var pdf bytes.Buffer
+	pdf.WriteString("%PDF-1.4\n1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+	fmt.Fprintf(&pdf, "4 0 obj\n<< /Length %d /Filter /FlateDecode >>\nstream\n", z.Len())
+	pdf.Write(z.Bytes())
+	pdf.WriteString("\nendstream\nendobj\n")
+	fmt.Fprintf(&pdf, "5 0 obj\n<< /Length %d >>\nstream\n%s\nendstream\nendobj\n", len(plain), plain)
+	pdf.WriteString("trailer\n<< /Root 1 0 R >>\n%%EOF\n")
+	return pdf.Bytes()
+}
+
+func TestExtractPDFText(t *testing.T) {
+	data := buildTestPDF(t,
+		"BT /F1 12 Tf 72 712 Td (Restart slurmd \\(as root\\)) Tj 0 -14 Td [(Run) -250 (book)] TJ ET",
+		"BT /F1 12 Tf <FEFF4F5C4E1A59318D25> Tj ET",
+	)
+	text := knowledgeDocumentText("docs/runbook.PDF", data)
+	for _, want := range []string{"Restart slurmd (as root)", "Runbook", "作业失败"} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("应提取出 %q: %q", want, text)
+		}
+	}
+
+	if got := extractPDFText([]byte("%PDF-1.4\n<< /Filter /DCTDecode /Subtype /Image >>\nstream\n\xff\xd8\xff\nendstream\n")); got != "" {
+		t.Fatalf("图片流不应产生文本: %q", got)
+	}
+}
+
+func TestRAGLocalIndexSearch(t *testing.T) {
+	index := &ragIndex{entries: []ragIndexEntry{
+		{chunkID: 1, vector: normalizeVector([]float32{1, 0, 0})},
+		{chunkID: 2, vector: normalizeVector([]float32{1, 1, 0})},
+		{chunkID: 3, ownerID: 7, vector: normalizeVector([]float32{1, 0.1, 0})},
+		{chunkID: 4, vector: normalizeVector([]float32{0, 0, 1})},
+		{chunkID: 5, vector: normalizeVector([]float32{1, 0})}, // 维度不同的旧向量
+	}}
+	query := normalizeVector([]float32{2, 0, 0})
+
+	hits := index.search(query, 3, 8, false)
+	if len(hits) != 3 || hits[0].chunkID != 1 || hits[1].chunkID != 2 || hits[2].chunkID != 4 {
+		t.Fatalf("非管理员不应命中他人的作业日志，且按相似度排序: %+v", hits)
+	}
+	if hits := index.search(query, 2, 7, false); hits[1].chunkID != 3 {
+		t.Fatalf("本人的作业日志应可命中: %+v", hits)
+	}
+	if hits := index.search(query, 10, 0, true); len(hits) != 4 {
+		t.Fatalf("管理员可检索全部片段，维度不同的片段跳过: %+v", hits)
+	}
+
+	ranked := rankRAGHits(index.search(query, 10, 0, true), 3, ragDefaultMinScore)
+	if len(ranked) != 3 || ranked[2].chunkID != 2 {
+		t.Fatalf("应过滤低于阈值的命中: %+v", ranked)
+	}
+
+	prompt := FormatRAGContext([]RAGCitation{{Index: 1, Title: "GPU 运维", Heading: "掉卡", Path: "gpu.md", content: "重启 nvidia-persistenced"}})
+	if !strings.Contains(prompt, "[1] GPU 运维 > 掉卡 (gpu.md)\n重启 nvidia-persistenced") {
+		t.Fatalf("参考资料格式错误: %s", prompt)
+	}
+}
+
+func TestLocalProviderEmbed(t *testing.T) {
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		var req struct {
+			Model string   `json:"model"`
+			Input []string `json:"input"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Model != "nomic-embed-text" || len(req.Input) != 2 {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		// 故意乱序返回，应按 index 还原
+		w.Write([]byte(`{"data":[{"index":1,"embedding":[0,1]},{"index":0,"embedding":[1,0]}]}`))
+	}))
+	defer server.Close()
+
+	provider, err := ai_providers.NewProviderFactory().CreateProvider(&models.AIAssistantConfig{
+		Provider:    models.ProviderLocal,
+		APIEndpoint: server.URL + "/v1/chat/completions",
+		Model:       "nomic-embed-text",
+	})
+	if err != nil {
+		t.Fatalf("create provider: %v", err)
+	}
+	embedder, ok := provider.(ai_providers.EmbeddingProvider)
+	if !ok {
+		t.Fatalf("本地（Ollama）提供商应支持向量化")
+	}
+	vectors, err := embedder.Embed(context.Background(), "nomic-embed-text", []string{"a", "b"})
+	if err != nil {
+		t.Fatalf("embed failed: %v", err)
+	}
+	if path != "/v1/embeddings" {
+		t.Fatalf("应由对话端点推导出向量化端点: %s", path)
+	}
+	if vectors[0][0] != 1 || vectors[1][1] != 1 {
+		t.Fatalf("向量顺序应与输入一致: %v", vectors)
+	}
+}
@@ -0,0 +1,492 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// artifactModePattern 文件权限格式，如 644、0400
+var artifactModePattern = regexp.MustCompile(`^0?[0-7]{3}$`)
+
+// SaltArtifactService 制品库与制品分发服务
+// 制品保存在当前激活的对象存储中（按 sha256 内容寻址），分发时通过预签名 URL 作为
+// file.managed 的 source，并携带 source_hash，由 minion 端校验下载内容；
+// 随后以 file.get_hash 复核每个目标上的文件，报告逐节点不一致
+type SaltArtifactService struct {
+	db     *gorm.DB
+	bucket string
+}
+
+var (
+	saltArtifactServiceInstance *SaltArtifactService
+	saltArtifactServiceOnce     sync.Once
+)
+
+// NewSaltArtifactService 创建制品服务（单例）
+func NewSaltArtifactService(db *gorm.DB) *SaltArtifactService {
+	saltArtifactServiceOnce.Do(func() {
+		saltArtifactServiceInstance = &SaltArtifactService{
+			db:     db,
+			bucket: getEnvOrDefault("SALT_ARTIFACT_BUCKET", "salt-artifacts"),
+		}
+		if err := db.AutoMigrate(&models.SaltArtifact{}, &models.SaltArtifactDistribution{}, &models.SaltArtifactDistributionResult{}); err != nil {
+			log.Printf("[SaltArtifactService] 自动迁移失败: %v", err)
+		}
+	})
+	return saltArtifactServiceInstance
+}
+
+// GetSaltArtifactService 获取制品服务实例
+func GetSaltArtifactService() *SaltArtifactService {
+	return saltArtifactServiceInstance
+}
+
+// storage 获取当前激活的对象存储客户端
+func (s *SaltArtifactService) storage() (*SeaweedFSService, error) {
+	var config models.ObjectStorageConfig
+	if err := s.db.Where("is_active = ?", true).First(&config).Error; err != nil {
+		return nil, errors.New("no active object storage configured")
+	}
+	return NewSeaweedFSService(&config)
+}
+
+// ensureBucket 确保制品存储桶存在
+func (s *SaltArtifactService) ensureBucket(store *SeaweedFSService) error {
+	buckets, err := store.ListBuckets()
+	if err != nil {
+		return err
+	}
+	for _, b := range buckets {
+		if b.Name == s.bucket {
+			return nil
+		}
+	}
+	return store.CreateBucket(s.bucket)
+}
+
+// ==================== 制品管理 ====================
+
+// Upload 上传制品，计算 sha256 并写入对象存储
+func (s *SaltArtifactService) Upload(name, description, fileName, contentType string, size int64, reader io.Reader, uploadedBy string) (*models.SaltArtifact, error) {
+	store, err := s.storage()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.ensureBucket(store); err != nil {
+		return nil, fmt.Errorf("prepare bucket %s: %v", s.bucket, err)
+	}
+
+	// 先落盘到临时文件计算校验和，再以校验和为键上传（同内容只存一份）
+	tmp, err := os.CreateTemp("", "salt-artifact-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	hasher := sha256.New()
+	written, err := io.Copy(io.MultiWriter(tmp, hasher), reader)
+	if err != nil {
+		return nil, fmt.Errorf("read upload: %v", err)
+	}
+	if size > 0 && written != size {
+		return nil, fmt.Errorf("upload truncated: got %d of %d bytes", written, size)
+	}
+	size = written
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	sum := hex.EncodeToString(hasher.Sum(nil))
+	objectKey := fmt.Sprintf("artifacts/%s/%s", sum, path.Base(fileName))
+	if err := store.UploadObject(s.bucket, objectKey, tmp, size, contentType); err != nil {
+		return nil, err
+	}
+
+	if name == "" {
+		name = path.Base(fileName)
+	}
+	artifact := &models.SaltArtifact{
+		Name:        name,
+		Description: description,
+		FileName:    path.Base(fileName),
+		Bucket:      s.bucket,
+		ObjectKey:   objectKey,
+		Size:        size,
+		SHA256:      sum,
+		ContentType: contentType,
+		UploadedBy:  uploadedBy,
+	}
+	if err := s.db.Create(artifact).Error; err != nil {
+		return nil, err
+	}
+	log.Printf("[SaltArtifactService] 制品已上传: name=%s, sha256=%s, size=%d", artifact.Name, sum, size)
+	return artifact, nil
+}
+
+// List 列出制品
+func (s *SaltArtifactService) List(name string) ([]models.SaltArtifact, error) {
+	var artifacts []models.SaltArtifact
+	query := s.db.Order("created_at DESC")
+	if name != "" {
+		query = query.Where("name = ?", name)
+	}
+	err := query.Find(&artifacts).Error
+	return artifacts, err
+}
+
+// Get 获取制品
+func (s *SaltArtifactService) Get(id uint) (*models.SaltArtifact, error) {
+	var artifact models.SaltArtifact
+	if err := s.db.First(&artifact, id).Error; err != nil {
+		return nil, err
+	}
+	return &artifact, nil
+}
+
+// Delete 删除制品；仅当没有其他制品引用同一对象时删除存储对象
+func (s *SaltArtifactService) Delete(id uint) error {
+	artifact, err := s.Get(id)
+	if err != nil {
+		return err
+	}
+	if err := s.db.Delete(artifact).Error; err != nil {
+		return err
+	}
+	var refs int64
+	s.db.Model(&models.SaltArtifact{}).Where("object_key = ?", artifact.ObjectKey).Count(&refs)
+	if refs == 0 {
+		if store, err := s.storage(); err == nil {
+			if err := store.DeleteObject(artifact.Bucket, artifact.ObjectKey); err != nil {
+				log.Printf("[SaltArtifactService] 删除存储对象失败: %v", err)
+			}
+		}
+	}
+	return nil
+}
+
+// SourceURL 生成供 minion 下载的预签名 URL（对象存储端点需对 minion 可达）
+func (s *SaltArtifactService) SourceURL(artifact *models.SaltArtifact, expires time.Duration) (string, error) {
+	store, err := s.storage()
+	if err != nil {
+		return "", err
+	}
+	return store.GetPresignedURL(artifact.Bucket, artifact.ObjectKey, expires)
+}
+
+// ==================== 分发 ====================
+
+// Distribute 创建分发任务并在后台执行
+func (s *SaltArtifactService) Distribute(artifactID uint, req *models.SaltArtifactDistributeRequest, requestedBy string) (*models.SaltArtifactDistribution, error) {
+	artifact, err := s.Get(artifactID)
+	if err != nil {
+		return nil, err
+	}
+	if !path.IsAbs(req.DestPath) {
+		return nil, fmt.Errorf("dest_path must be an absolute path")
+	}
+	if req.Mode != "" && !artifactModePattern.MatchString(req.Mode) {
+		return nil, fmt.Errorf("invalid mode %q", req.Mode)
+	}
+
+	timeout := time.Duration(req.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Minute
+	}
+	// URL 有效期覆盖整个下载窗口
+	sourceURL, err := s.SourceURL(artifact, timeout+5*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	dist := &models.SaltArtifactDistribution{
+		ArtifactID:   artifact.ID,
+		Target:       req.Target,
+		TgtType:      req.TgtType,
+		MasterID:     req.MasterID,
+		DestPath:     req.DestPath,
+		Mode:         req.Mode,
+		FileUser:     req.User,
+		FileGroup:    req.Group,
+		ExpectedHash: artifact.SHA256,
+		Status:       models.ArtifactDistributionRunning,
+		RequestedBy:  requestedBy,
+	}
+	if dist.TgtType == "" {
+		dist.TgtType = "glob"
+	}
+	if err := s.db.Create(dist).Error; err != nil {
+		return nil, err
+	}
+
+	// 分组目标先展开为当前成员；未指定 Master 时按 minion 的密钥归属路由，并记录实际下发的 Master
+	target, tgtType, err := ExpandMinionGroupTarget(s.db, dist.Target, dist.TgtType)
+	if err != nil {
+		s.finish(dist, models.ArtifactDistributionFailed, err.Error())
+		return dist, err
+	}
+	owner, err := ResolveTargetMaster(dist.MasterID, target, tgtType)
+	if err != nil {
+		s.finish(dist, models.ArtifactDistributionFailed, err.Error())
+		return dist, err
+	}
+	if owner != dist.MasterID {
+		dist.MasterID = owner
+		s.db.Model(dist).Update("master_id", owner)
+	}
+	saltService, err := SaltServiceForMaster(owner)
+	if err != nil {
+		s.finish(dist, models.ArtifactDistributionFailed, err.Error())
+		return dist, err
+	}
+
+	// file.managed 通过 state.single 执行，source_hash 不一致时状态失败
+	kwarg := map[string]interface{}{
+		"fun":         "file.managed",
+		"name":        dist.DestPath,
+		"source":      sourceURL,
+		"source_hash": "sha256=" + artifact.SHA256,
+		"makedirs":    true,
+		"skip_verify": false,
+	}
+	if dist.Mode != "" {
+		kwarg["mode"] = dist.Mode
+	}
+	if dist.FileUser != "" {
+		kwarg["user"] = dist.FileUser
+	}
+	if dist.FileGroup != "" {
+		kwarg["group"] = dist.FileGroup
+	}
+
+	ctx := context.Background()
+	jid, minions, err := saltService.SubmitAsync(ctx, target, tgtType, "state.single", nil, kwarg)
+	if err != nil {
+		s.finish(dist, models.ArtifactDistributionFailed, err.Error())
+		return dist, err
+	}
+	dist.JID = jid
+	dist.TotalCount = len(minions)
+	s.db.Model(dist).Updates(map[string]interface{}{"jid": jid, "total_count": len(minions)})
+
+	if saltJobService := GetSaltJobService(); saltJobService != nil {
+		argsJSON, _ := json.Marshal([]string{"file.managed", dist.DestPath, artifact.Name})
+		saltJobService.CreateJob(ctx, &models.SaltJobHistory{
+			JID:       jid,
+			TaskID:    fmt.Sprintf("ARTIFACT-%d", dist.ID),
+			Function:  "state.single",
+			Arguments: string(argsJSON),
+			Target:    target,
+			TgtType:   tgtType,
+			User:      requestedBy,
+		})
+	}
+
+	go s.collect(dist, artifact, saltService, minions, timeout)
+	return dist, nil
+}
+
+// collect 等待分发作业结果，并以 file.get_hash 复核每个目标上的文件
+func (s *SaltArtifactService) collect(dist *models.SaltArtifactDistribution, artifact *models.SaltArtifact, saltService *SaltStackService, minions []string, timeout time.Duration) {
+	ctx := context.Background()
+	results, waitErr := saltService.WaitForJob(ctx, dist.JID, minions, timeout)
+
+	if saltJobService := GetSaltJobService(); saltJobService != nil {
+		if waitErr != nil {
+			saltJobService.TimeoutJob(ctx, dist.JID)
+		} else {
+			success, failed, _ := CountJobResults(results)
+			saltJobService.CompleteJob(ctx, dist.JID, results, success, failed)
+		}
+	}
+
+	// 复核：对有返回的 minion 计算实际校验和
+	hashes := map[string]interface{}{}
+	var responded []string
+	for minion := range results {
+		responded = append(responded, minion)
+	}
+	if len(responded) > 0 {
+		verifyJID, verifyMinions, err := saltService.SubmitAsync(ctx, strings.Join(responded, ","), "list", "file.get_hash", []interface{}{dist.DestPath, "sha256"}, nil)
+		if err != nil {
+			log.Printf("[SaltArtifactService] 下发校验作业失败: %v", err)
+		} else {
+			dist.VerifyJID = verifyJID
+			hashes, _ = saltService.WaitForJob(ctx, verifyJID, verifyMinions, 2*time.Minute)
+		}
+	}
+
+	rows := buildArtifactResults(dist, minions, results, hashes)
+	for _, r := range rows {
+		switch r.Status {
+		case models.ArtifactResultOK:
+			dist.SuccessCount++
+		case models.ArtifactResultMismatch:
+			dist.MismatchCount++
+		default:
+			dist.FailedCount++
+		}
+	}
+	if len(rows) > 0 {
+		if err := s.db.CreateInBatches(rows, 200).Error; err != nil {
+			log.Printf("[SaltArtifactService] 保存分发结果失败: %v", err)
+		}
+	}
+
+	status := models.ArtifactDistributionCompleted
+	switch {
+	case dist.SuccessCount == 0:
+		status = models.ArtifactDistributionFailed
+	case dist.MismatchCount > 0 || dist.FailedCount > 0:
+		status = models.ArtifactDistributionPartial
+	}
+	errMsg := ""
+	if waitErr != nil {
+		errMsg = fmt.Sprintf("timed out waiting for %d minion(s)", len(minions)-len(results))
+	}
+	s.db.Model(dist).Updates(map[string]interface{}{
+		"verify_jid":     dist.VerifyJID,
+		"success_count":  dist.SuccessCount,
+		"mismatch_count": dist.MismatchCount,
+		"failed_count":   dist.FailedCount,
+	})
+	s.finish(dist, status, errMsg)
+
+	auditStatus := models.AuditStatusSuccess
+	if status != models.ArtifactDistributionCompleted {
+		auditStatus = models.AuditStatusFailed
+	}
+
+	GetAuditService().NewAuditEntry(models.AuditCategorySaltstack, models.AuditActionDeploy).
+		WithUser(0, dist.RequestedBy, "").
+		WithResource("salt_artifact", fmt.Sprintf("%d", artifact.ID), artifact.Name).
+		WithMetadata(map[string]interface{}{
+			"distribution_id": dist.ID,
+			"target":          dist.Target,
+			"dest_path":       dist.DestPath,
+			"sha256":          artifact.SHA256,
+			"jid":             dist.JID,
+			"success":         dist.SuccessCount,
+			"mismatch":        dist.MismatchCount,
+			"failed":          dist.FailedCount,
+		}).
+		WithStatus(auditStatus).
+		SaveAsync()
+	log.Printf("[SaltArtifactService] 分发完成: id=%d, artifact=%s, ok=%d, mismatch=%d, failed=%d",
+		dist.ID, artifact.Name, dist.SuccessCount, dist.MismatchCount, dist.FailedCount)
+}
+
+// buildArtifactResults 合并分发结果与复核校验和，得到逐节点结果
+func buildArtifactResults(dist *models.SaltArtifactDistribution, minions []string, results, hashes map[string]interface{}) []models.SaltArtifactDistributionResult {
+	seen := map[string]bool{}
+	var all []string
+	for _, m := range minions {
+		if !seen[m] {
+			seen[m] = true
+			all = append(all, m)
+		}
+	}
+	for m := range results {
+		if !seen[m] {
+			seen[m] = true
+			all = append(all, m)
+		}
+	}
+
+	rows := make([]models.SaltArtifactDistributionResult, 0, len(all))
+	for _, minion := range all {
+		row := models.SaltArtifactDistributionResult{DistributionID: dist.ID, MinionID: minion}
+		ret, responded := results[minion]
+		if !responded {
+			row.Status = models.ArtifactResultMissing
+			row.Message = "no return from minion"
+			rows = append(rows, row)
+			continue
+		}
+
+		state := NormalizeMinionReturn("state.single", ret)
+		row.Changed = state.StatesChanged > 0
+		actual, _ := hashes[minion].(string)
+		row.ActualHash = strings.TrimPrefix(strings.TrimSpace(actual), "sha256:")
+
+		switch {
+		case !state.Success:
+			row.Status = models.ArtifactResultFailed
+			row.Message = state.Stderr
+			// file.managed 失败但文件仍存在时，校验和不一致更能说明问题
+			if row.ActualHash != "" && !strings.EqualFold(row.ActualHash, dist.ExpectedHash) {
+				row.Status = models.ArtifactResultMismatch
+			}
+		case row.ActualHash == "":
+			row.Status = models.ArtifactResultFailed
+			row.Message = "file missing after distribution"
+		case !strings.EqualFold(row.ActualHash, dist.ExpectedHash):
+			row.Status = models.ArtifactResultMismatch
+			row.Message = fmt.Sprintf("expected sha256 %s, got %s", dist.ExpectedHash, row.ActualHash)
+		default:
+			row.Status = models.ArtifactResultOK
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func (s *SaltArtifactService) finish(dist *models.SaltArtifactDistribution, status, errMsg string) {
+	now := time.Now()
+	dist.Status = status
+	dist.FinishedAt = &now
+	dist.ErrorMessage = errMsg
+	s.db.Model(dist).Updates(map[string]interface{}{
+		"status":        status,
+		"finished_at":   now,
+		"error_message": errMsg,
+	})
+}
+
+// ListDistributions 列出分发记录
+func (s *SaltArtifactService) ListDistributions(artifactID uint, page, pageSize int) ([]models.SaltArtifactDistribution, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	query := s.db.Model(&models.SaltArtifactDistribution{})
+	if artifactID > 0 {
+		query = query.Where("artifact_id = ?", artifactID)
+	}
+	var total int64
+	query.Count(&total)
+	var dists []models.SaltArtifactDistribution
+	err := query.Preload("Artifact").Order("created_at DESC").
+		Offset((page - 1) * pageSize).Limit(pageSize).Find(&dists).Error
+	return dists, total, err
+}
+
+// GetDistribution 获取分发详情（含逐节点结果，失败与不一致的节点排在前面）
+func (s *SaltArtifactService) GetDistribution(id uint) (*models.SaltArtifactDistribution, error) {
+	var dist models.SaltArtifactDistribution
+	err := s.db.Preload("Artifact").
+		Preload("Results", func(db *gorm.DB) *gorm.DB {
+			return db.Order("CASE status WHEN 'ok' THEN 1 ELSE 0 END, minion_id")
+		}).
+		First(&dist, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &dist, nil
+}
@@ -0,0 +1,55 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
+)
+
+func TestBuildArtifactResults(t *testing.T) {
+	const expected = "aaaa"
+	dist := &models.SaltArtifactDistribution{ID: 7, ExpectedHash: expected}
+	okState := map[string]interface{}{
+		"file_|-/etc/munge/munge.key_|-/etc/munge/munge.key_|-managed": map[string]interface{}{
+			"result": true, "changes": map[string]interface{}{"diff": "New file"}, "comment": "File updated",
+		},
+	}
+	failedState := map[string]interface{}{
+		"file_|-/etc/munge/munge.key_|-/etc/munge/munge.key_|-managed": map[string]interface{}{
+			"result": false, "changes": map[string]interface{}{}, "comment": "Specified sha256 checksum does not match",
+		},
+	}
+
+	results := map[string]interface{}{
+		"node-ok":       okState,
+		"node-mismatch": okState,
+		"node-failed":   failedState,
+	}
+	hashes := map[string]interface{}{
+		"node-ok":       expected,
+		"node-mismatch": "bbbb",
+	}
+	rows := buildArtifactResults(dist, []string{"node-ok", "node-mismatch", "node-failed", "node-silent"}, results, hashes)
+
+	got := map[string]models.SaltArtifactDistributionResult{}
+	for _, r := range rows {
+		if r.DistributionID != 7 {
+			t.Errorf("%s: distribution_id = %d", r.MinionID, r.DistributionID)
+		}
+		got[r.MinionID] = r
+	}
+	cases := map[string]string{
+		"node-ok":       models.ArtifactResultOK,
+		"node-mismatch": models.ArtifactResultMismatch,
+		"node-failed":   models.ArtifactResultFailed,
+		"node-silent":   models.ArtifactResultMissing,
+	}
+	for minion, status := range cases {
+		if got[minion].Status != status {
+			t.Errorf("%s: 期望状态 %s，实际 %s", minion, status, got[minion].Status)
+		}
+	}
+	if !got["node-ok"].Changed {
+		t.Errorf("node-ok 应标记为已变更")
+	}
+}
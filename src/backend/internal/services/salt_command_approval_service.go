@@ -0,0 +1,422 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// 审批通过后执行命令的默认等待时间
+const saltCommandApprovalTimeout = 5 * time.Minute
+
+// SaltCommandApprovalService 危险命令执行审批服务
+// 命中 high/critical 级别黑名单的命令不直接拒绝，而是生成待审批的执行申请，
+// 由另一名管理员通过权限审批流程批准后，按申请时的命令和目标精确执行一次
+type SaltCommandApprovalService struct {
+	db *gorm.DB
+}
+
+var (
+	saltCommandApprovalServiceInstance *SaltCommandApprovalService
+	saltCommandApprovalServiceOnce     sync.Once
+)
+
+// NewSaltCommandApprovalService 创建危险命令审批服务（单例）
+func NewSaltCommandApprovalService(db *gorm.DB) *SaltCommandApprovalService {
+	saltCommandApprovalServiceOnce.Do(func() {
+		saltCommandApprovalServiceInstance = &SaltCommandApprovalService{db: db}
+		if err := db.AutoMigrate(&models.SaltCommandApproval{}); err != nil {
+			log.Printf("[SaltCommandApprovalService] 自动迁移失败: %v", err)
+		}
+	})
+	return saltCommandApprovalServiceInstance
+}
+
+// GetSaltCommandApprovalService 获取危险命令审批服务实例
+func GetSaltCommandApprovalService() *SaltCommandApprovalService {
+	return saltCommandApprovalServiceInstance
+}
+
+// RequiresApproval 命中的规则是否走审批流程（high/critical），其余级别仍直接拦截
+func RequiresApproval(rule *models.DangerousCommand) bool {
+	return rule != nil && (rule.Severity == "high" || rule.Severity == "critical")
+}
+
+// SaltCommandApprovalInput 提交危险命令执行申请
+type SaltCommandApprovalInput struct {
+	RequesterID   uint
+	RequesterName string
+	Function      string
+	Args          []interface{}
+	Kwarg         map[string]interface{}
+	Target        string
+	TgtType       string
+	MasterID      string
+	Reason        string
+	Rule          *models.DangerousCommand
+}
+
+// Submit 创建待审批的执行申请；同一用户对相同命令和目标的待审批申请只保留一条
+func (s *SaltCommandApprovalService) Submit(input *SaltCommandApprovalInput) (*models.SaltCommandApproval, bool, error) {
+	approval, request, err := newSaltCommandApproval(input)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var existing models.SaltCommandApproval
+	err = s.db.Where("requester_id = ? AND status = ? AND function = ? AND arguments = ? AND kwarg = ? AND target = ? AND tgt_type = ? AND master_id = ?",
+		approval.RequesterID, models.SaltCommandApprovalPending, approval.Function, approval.Arguments, approval.Kwarg, approval.Target, approval.TgtType, approval.MasterID).
+		First(&existing).Error
+	if err == nil {
+		return &existing, false, nil
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(request).Error; err != nil {
+			return err
+		}
+
+		approval.PermissionRequestID = request.ID
+		if err := tx.Create(approval).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&models.PermissionApprovalLog{
+			PermissionRequestID: request.ID,
+			OperatorID:          input.RequesterID,
+			Action:              "submit",
+			NewStatus:           string(models.ApprovalStatusPending),
+			Comment:             "提交危险命令执行申请",
+		}).Error
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("创建执行申请失败: %v", err)
+	}
+
+	approval.Args = input.Args
+	approval.Kwargs = input.Kwarg
+	log.Printf("[SECURITY] 危险命令已提交审批: User=%s, Target=%s, Function=%s, Rule=%s, RequestID=%d",
+		input.RequesterName, input.Target, input.Function, input.Rule.Pattern, approval.PermissionRequestID)
+	return approval, true, nil
+}
+
+// newSaltCommandApproval 根据提交内容构造执行记录及对应的权限审批申请（均未入库）
+func newSaltCommandApproval(input *SaltCommandApprovalInput) (*models.SaltCommandApproval, *models.PermissionRequest, error) {
+	if input.Rule == nil {
+		return nil, nil, fmt.Errorf("missing matched rule")
+	}
+	argsJSON, err := json.Marshal(input.Args)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid args: %v", err)
+	}
+	kwargJSON := ""
+	if len(input.Kwarg) > 0 {
+		b, err := json.Marshal(input.Kwarg)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid kwarg: %v", err)
+		}
+		kwargJSON = string(b)
+	}
+	tgtType := input.TgtType
+	if tgtType == "" {
+		tgtType = "glob"
+	}
+
+	reason := input.Reason
+	if reason == "" {
+		reason = fmt.Sprintf("执行危险命令 %s %s（目标: %s，命中规则: %s）", input.Function, string(argsJSON), input.Target, input.Rule.Description)
+	}
+	priority := 1
+	if input.Rule.Severity == "critical" {
+		priority = 2
+	}
+
+	approval := &models.SaltCommandApproval{
+		RequesterID:     input.RequesterID,
+		RequesterName:   input.RequesterName,
+		Function:        input.Function,
+		Arguments:       string(argsJSON),
+		Kwarg:           kwargJSON,
+		Target:          input.Target,
+		TgtType:         tgtType,
+		MasterID:        input.MasterID,
+		RulePattern:     input.Rule.Pattern,
+		RuleSeverity:    input.Rule.Severity,
+		RuleDescription: input.Rule.Description,
+		Status:          models.SaltCommandApprovalPending,
+	}
+	request := &models.PermissionRequest{
+		RequestType:  models.PermissionRequestTypeSaltCommand,
+		RequesterID:  input.RequesterID,
+		TargetUserID: input.RequesterID,
+		Reason:       reason,
+		Status:       models.ApprovalStatusPending,
+		Priority:     priority,
+	}
+	request.SetRequestedModules([]string{string(models.ModuleSaltStack)})
+	request.SetRequestedVerbs([]string{"execute"})
+	return approval, request, nil
+}
+
+// saltCommandDecisionStatus 返回审批决定后的执行记录状态，只有待审批的记录可以做出决定
+func saltCommandDecisionStatus(current string, approved bool) (string, error) {
+	if current != models.SaltCommandApprovalPending {
+		return "", fmt.Errorf("执行申请状态为 %s，无法审批", current)
+	}
+	if approved {
+		return models.SaltCommandApprovalApproved, nil
+	}
+	return models.SaltCommandApprovalRejected, nil
+}
+
+// HandleDecision 权限审批流程对 salt_command 类型申请做出决定时调用，在调用方事务 tx 中更新执行记录；
+// 返回的记录需在事务提交后交给 AfterDecision 记录审计并执行
+func (s *SaltCommandApprovalService) HandleDecision(tx *gorm.DB, requestID, approverID uint, approved bool, comment string) (*models.SaltCommandApproval, error) {
+	var approval models.SaltCommandApproval
+	if err := tx.Where("permission_request_id = ?", requestID).First(&approval).Error; err != nil {
+		return nil, fmt.Errorf("执行申请不存在: %v", err)
+	}
+	status, err := saltCommandDecisionStatus(approval.Status, approved)
+	if err != nil {
+		return nil, err
+	}
+
+	var approver models.User
+	tx.Select("id", "username").First(&approver, approverID)
+
+	now := time.Now()
+	res := tx.Model(&models.SaltCommandApproval{}).
+		Where("id = ? AND status = ?", approval.ID, models.SaltCommandApprovalPending).
+		Updates(map[string]interface{}{
+			"status":          status,
+			"approver_id":     approverID,
+			"approver_name":   approver.Username,
+			"approve_comment": comment,
+			"decided_at":      now,
+		})
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, fmt.Errorf("执行申请已被处理，无法重复审批")
+	}
+
+	approval.Status = status
+	approval.ApproverID = &approverID
+	approval.ApproverName = approver.Username
+	approval.ApproveComment = comment
+	approval.DecidedAt = &now
+	return &approval, nil
+}
+
+// AfterDecision 审批事务提交后记录审计；批准时在后台执行一次被批准的命令
+func (s *SaltCommandApprovalService) AfterDecision(approval *models.SaltCommandApproval, comment string) {
+	approved := approval.Status == models.SaltCommandApprovalApproved
+	action := models.AuditActionReject
+	if approved {
+		action = models.AuditActionApprove
+	}
+	var approverID uint
+	if approval.ApproverID != nil {
+		approverID = *approval.ApproverID
+	}
+	GetAuditService().NewAuditEntry(models.AuditCategorySaltstack, action).
+		WithUser(approverID, approval.ApproverName, "").
+		WithResource("salt_command_approval", fmt.Sprintf("%d", approval.ID), approval.Function).
+		WithMetadata(map[string]interface{}{
+			"permission_request_id": approval.PermissionRequestID,
+			"requester":             approval.RequesterName,
+			"function":              approval.Function,
+			"args":                  approval.GetArgs(),
+			"target":                approval.Target,
+			"tgt_type":              approval.TgtType,
+			"master_id":             approval.MasterID,
+			"rule_pattern":          approval.RulePattern,
+			"rule_severity":         approval.RuleSeverity,
+		}).
+		WithNotes(comment).
+		WithSeverity(models.AuditSeverityWarning).
+		SaveAsync()
+
+	if approved {
+		go s.execute(approval.ID)
+	}
+}
+
+// HandleCancel 申请人取消 salt_command 类型申请，在调用方事务 tx 中更新执行记录
+func (s *SaltCommandApprovalService) HandleCancel(tx *gorm.DB, requestID uint) error {
+	res := tx.Model(&models.SaltCommandApproval{}).
+		Where("permission_request_id = ? AND status = ?", requestID, models.SaltCommandApprovalPending).
+		Update("status", models.SaltCommandApprovalCanceled)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("执行申请不存在或已被处理")
+	}
+	return nil
+}
+
+// execute 执行已批准的命令；通过状态抢占保证只执行一次
+func (s *SaltCommandApprovalService) execute(id uint) {
+	now := time.Now()
+	res := s.db.Model(&models.SaltCommandApproval{}).
+		Where("id = ? AND status = ?", id, models.SaltCommandApprovalApproved).
+		Updates(map[string]interface{}{"status": models.SaltCommandApprovalExecuting, "executed_at": now})
+	if res.Error != nil || res.RowsAffected == 0 {
+		return
+	}
+
+	var approval models.SaltCommandApproval
+	if err := s.db.First(&approval, id).Error; err != nil {
+		return
+	}
+
+	args := approval.GetArgs()
+	kwarg := approval.GetKwarg()
+	if approval.Function == "cmd.run" {
+		if kwarg == nil {
+			kwarg = map[string]interface{}{}
+		}
+		if _, ok := kwarg["python_shell"]; !ok {
+			kwarg["python_shell"] = true
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), saltCommandApprovalTimeout+30*time.Second)
+	defer cancel()
+
+	updates := map[string]interface{}{}
+	salt, err := SaltServiceForTarget(approval.MasterID, approval.Target, approval.TgtType)
+	if err != nil {
+		updates["status"] = models.SaltCommandApprovalFailed
+		updates["error_message"] = fmt.Sprintf("选择 Salt Master 失败: %v", err)
+		s.db.Model(&approval).Updates(updates)
+		s.auditExecution(&approval, "", 0, 0, err)
+		return
+	}
+	jid, minions, err := salt.SubmitAsync(ctx, approval.Target, approval.TgtType, approval.Function, args, kwarg)
+	if err != nil {
+		updates["status"] = models.SaltCommandApprovalFailed
+		updates["error_message"] = fmt.Sprintf("下发 Salt 作业失败: %v", err)
+		s.db.Model(&approval).Updates(updates)
+		s.auditExecution(&approval, "", 0, 0, err)
+		return
+	}
+	s.db.Model(&approval).Update("jid", jid)
+
+	saltJobService := GetSaltJobService()
+	if saltJobService != nil {
+		argsJSON, _ := json.Marshal(args)
+		if err := saltJobService.CreateJob(ctx, &models.SaltJobHistory{
+			JID:       jid,
+			TaskID:    fmt.Sprintf("APPROVAL-%d", approval.ID),
+			Function:  approval.Function,
+			Arguments: string(argsJSON),
+			Target:    approval.Target,
+			TgtType:   approval.TgtType,
+			User:      approval.RequesterName,
+			Status:    "running",
+			StartTime: now,
+		}); err != nil {
+			log.Printf("[SaltCommandApprovalService] 保存作业历史失败: %v", err)
+		}
+	}
+
+	results, waitErr := salt.WaitForJob(ctx, jid, minions, saltCommandApprovalTimeout)
+	successCount, failedCount, _ := CountJobResults(results)
+	if missing := len(minions) - len(results); missing > 0 {
+		failedCount += missing
+	}
+	if saltJobService != nil {
+		if waitErr != nil {
+			saltJobService.TimeoutJob(context.Background(), jid)
+		} else {
+			saltJobService.CompleteJob(context.Background(), jid, results, successCount, failedCount)
+		}
+	}
+
+	updates["success_count"] = successCount
+	updates["failed_count"] = failedCount
+	updates["status"] = models.SaltCommandApprovalExecuted
+	if waitErr != nil || failedCount > 0 {
+		updates["status"] = models.SaltCommandApprovalFailed
+	}
+	if waitErr != nil {
+		updates["error_message"] = waitErr.Error()
+	}
+	s.db.Model(&approval).Updates(updates)
+	s.auditExecution(&approval, jid, successCount, failedCount, waitErr)
+
+	log.Printf("[SECURITY] 已执行审批通过的危险命令: ID=%d, JID=%s, Requester=%s, Approver=%s, Success=%d, Failed=%d",
+		approval.ID, jid, approval.RequesterName, approval.ApproverName, successCount, failedCount)
+}
+
+// auditExecution 记录执行审计，包含审批人
+func (s *SaltCommandApprovalService) auditExecution(approval *models.SaltCommandApproval, jid string, successCount, failedCount int, err error) {
+	entry := GetAuditService().NewAuditEntry(models.AuditCategorySaltstack, models.AuditActionSaltExecute).
+		WithUser(approval.RequesterID, approval.RequesterName, "").
+		WithResource("salt_command", approval.Target, approval.Function).
+		WithSeverity(models.AuditSeverityWarning).
+		WithMetadata(map[string]interface{}{
+			"approval_id":           approval.ID,
+			"permission_request_id": approval.PermissionRequestID,
+			"approver_id":           approval.ApproverID,
+			"approver":              approval.ApproverName,
+			"function":              approval.Function,
+			"args":                  approval.GetArgs(),
+			"target":                approval.Target,
+			"tgt_type":              approval.TgtType,
+			"master_id":             approval.MasterID,
+			"rule_pattern":          approval.RulePattern,
+			"rule_severity":         approval.RuleSeverity,
+			"jid":                   jid,
+			"success_count":         successCount,
+			"failed_count":          failedCount,
+		}).
+		WithTags("dangerous_command", "approved")
+	if err != nil {
+		entry.WithError(err)
+	} else if failedCount > 0 {
+		entry.WithStatus(models.AuditStatusFailed)
+	}
+	entry.SaveAsync()
+}
+
+// Get 获取执行申请
+func (s *SaltCommandApprovalService) Get(id uint) (*models.SaltCommandApproval, error) {
+	var approval models.SaltCommandApproval
+	if err := s.db.First(&approval, id).Error; err != nil {
+		return nil, err
+	}
+	return &approval, nil
+}
+
+// List 查询执行申请；requesterID 为 0 时返回全部
+func (s *SaltCommandApprovalService) List(requesterID uint, status string, page, pageSize int) ([]models.SaltCommandApproval, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	query := s.db.Model(&models.SaltCommandApproval{})
+	if requesterID > 0 {
+		query = query.Where("requester_id = ?", requesterID)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	query.Count(&total)
+	var approvals []models.SaltCommandApproval
+	err := query.Order("created_at DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&approvals).Error
+	return approvals, total, err
+}
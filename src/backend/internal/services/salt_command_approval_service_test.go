@@ -0,0 +1,113 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
+)
+
+func TestNewSaltCommandApproval(t *testing.T) {
+	rule := &models.DangerousCommand{Pattern: "rm -rf /", Severity: "critical", Description: "删除根目录"}
+	approval, request, err := newSaltCommandApproval(&SaltCommandApprovalInput{
+		RequesterID:   7,
+		RequesterName: "alice",
+		Function:      "cmd.run",
+		Args:          []interface{}{"rm -rf /data/tmp"},
+		Kwarg:         map[string]interface{}{"runas": "root"},
+		Target:        "web-1",
+		MasterID:      "dc1",
+		Rule:          rule,
+	})
+	if err != nil {
+		t.Fatalf("构造申请失败: %v", err)
+	}
+
+	if approval.Status != models.SaltCommandApprovalPending || approval.TgtType != "glob" || approval.MasterID != "dc1" {
+		t.Errorf("执行记录字段不正确: %+v", approval)
+	}
+	if approval.Arguments != `["rm -rf /data/tmp"]` || approval.Kwarg != `{"runas":"root"}` {
+		t.Errorf("参数应以 JSON 保存: args=%s, kwarg=%s", approval.Arguments, approval.Kwarg)
+	}
+	if approval.RulePattern != rule.Pattern || approval.RuleSeverity != "critical" {
+		t.Errorf("应记录命中的规则: %+v", approval)
+	}
+
+	if request.RequestType != models.PermissionRequestTypeSaltCommand || request.Status != models.ApprovalStatusPending {
+		t.Errorf("审批申请类型或状态不正确: %+v", request)
+	}
+	if request.RequesterID != 7 || request.TargetUserID != 7 || request.Priority != 2 {
+		t.Errorf("critical 规则应为高优先级且申请人为本人: %+v", request)
+	}
+	if !reflect.DeepEqual(request.GetRequestedVerbs(), []string{"execute"}) ||
+		!reflect.DeepEqual(request.GetRequestedModules(), []string{string(models.ModuleSaltStack)}) {
+		t.Errorf("申请的模块/动作不正确: %v %v", request.GetRequestedModules(), request.GetRequestedVerbs())
+	}
+	if !strings.Contains(request.Reason, "web-1") || !strings.Contains(request.Reason, rule.Description) {
+		t.Errorf("未填写理由时应自动生成: %s", request.Reason)
+	}
+
+	_, request, err = newSaltCommandApproval(&SaltCommandApprovalInput{
+		Function: "cmd.run",
+		Target:   "*",
+		TgtType:  "list",
+		Reason:   "清理磁盘",
+		Rule:     &models.DangerousCommand{Severity: "high"},
+	})
+	if err != nil || request.Priority != 1 || request.Reason != "清理磁盘" {
+		t.Errorf("high 规则优先级或自定义理由不正确: %+v, err=%v", request, err)
+	}
+
+	if _, _, err := newSaltCommandApproval(&SaltCommandApprovalInput{Function: "cmd.run"}); err == nil {
+		t.Error("缺少命中规则时应返回错误")
+	}
+	if _, _, err := newSaltCommandApproval(&SaltCommandApprovalInput{Args: []interface{}{func() {}}, Rule: rule}); err == nil {
+		t.Error("无法序列化的参数应返回错误")
+	}
+}
+
+func TestCheckPermissionRequestApprovable(t *testing.T) {
+	pending := &models.PermissionRequest{RequesterID: 7, Status: models.ApprovalStatusPending}
+	if err := checkPermissionRequestApprovable(pending, 8); err != nil {
+		t.Errorf("其他管理员应可审批: %v", err)
+	}
+	if err := checkPermissionRequestApprovable(pending, 7); err == nil {
+		t.Error("不能审批自己的申请")
+	}
+	for _, status := range []models.PermissionApprovalStatus{models.ApprovalStatusApproved, models.ApprovalStatusRejected, models.ApprovalStatusCanceled} {
+		if err := checkPermissionRequestApprovable(&models.PermissionRequest{RequesterID: 7, Status: status}, 8); err == nil {
+			t.Errorf("状态为 %s 的申请不应可审批", status)
+		}
+	}
+}
+
+func TestSaltCommandDecisionStatus(t *testing.T) {
+	if got, err := saltCommandDecisionStatus(models.SaltCommandApprovalPending, true); err != nil || got != models.SaltCommandApprovalApproved {
+		t.Errorf("批准后应为 approved: %s, %v", got, err)
+	}
+	if got, err := saltCommandDecisionStatus(models.SaltCommandApprovalPending, false); err != nil || got != models.SaltCommandApprovalRejected {
+		t.Errorf("拒绝后应为 rejected: %s, %v", got, err)
+	}
+	for _, status := range []string{
+		models.SaltCommandApprovalApproved, models.SaltCommandApprovalRejected, models.SaltCommandApprovalCanceled,
+		models.SaltCommandApprovalExecuting, models.SaltCommandApprovalExecuted, models.SaltCommandApprovalFailed,
+	} {
+		if _, err := saltCommandDecisionStatus(status, true); err == nil {
+			t.Errorf("状态为 %s 的执行申请不应可重复审批", status)
+		}
+	}
+}
+
+func TestCheckPermissionRequestCancelable(t *testing.T) {
+	pending := &models.PermissionRequest{RequesterID: 7, Status: models.ApprovalStatusPending}
+	if err := checkPermissionRequestCancelable(pending, 7); err != nil {
+		t.Errorf("申请人应可取消待审批申请: %v", err)
+	}
+	if err := checkPermissionRequestCancelable(pending, 8); err == nil {
+		t.Error("只能取消自己的申请")
+	}
+	if err := checkPermissionRequestCancelable(&models.PermissionRequest{RequesterID: 7, Status: models.ApprovalStatusApproved}, 7); err == nil {
+		t.Error("已审批的申请不能取消")
+	}
+}
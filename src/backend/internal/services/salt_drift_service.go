@@ -0,0 +1,646 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SaltDriftService 配置漂移检测服务：周期性以 test=True 运行状态，记录待变更并在节点由合规转为漂移时告警
+type SaltDriftService struct {
+	db         *gorm.DB
+	httpClient *http.Client
+	mu         sync.Mutex
+	active     map[uint]bool // policy ID -> 是否有扫描在运行
+	runner     *cronRunner
+}
+
+var (
+	saltDriftServiceInstance *SaltDriftService
+	saltDriftServiceOnce     sync.Once
+)
+
+// NewSaltDriftService 创建配置漂移检测服务（单例）
+func NewSaltDriftService(db *gorm.DB) *SaltDriftService {
+	saltDriftServiceOnce.Do(func() {
+		saltDriftServiceInstance = &SaltDriftService{
+			db:         db,
+			httpClient: &http.Client{Timeout: 10 * time.Second},
+			active:     make(map[uint]bool),
+		}
+		saltDriftServiceInstance.runner = newCronRunner("SaltDriftService", db, &models.SaltDriftPolicy{}, saltDriftServiceInstance.fireDue)
+		if err := db.AutoMigrate(&models.SaltDriftPolicy{}, &models.SaltDriftRun{}, &models.SaltDriftNodeResult{},
+			&models.SaltDriftStateResult{}, &models.SaltDriftNodeStatus{}); err != nil {
+			log.Printf("[SaltDriftService] 自动迁移失败: %v", err)
+		}
+	})
+	return saltDriftServiceInstance
+}
+
+// GetSaltDriftService 获取配置漂移检测服务实例
+func GetSaltDriftService() *SaltDriftService {
+	return saltDriftServiceInstance
+}
+
+// ==================== 结果解析 ====================
+
+// minionDriftAnalysis 单个 minion 的 test=True 运行结果
+type minionDriftAnalysis struct {
+	Status        string
+	StatesTotal   int
+	StatesDrifted int
+	StatesFailed  int
+	Message       string
+	Drifts        []models.SaltDriftStateResult
+}
+
+// analyzeDriftReturn 解析 state.apply test=True 的单个 minion 返回
+// result 为 nil（或 true 但 changes 非空）表示存在待变更；result 为 false 表示执行失败
+func analyzeDriftReturn(value interface{}) minionDriftAnalysis {
+	a := minionDriftAnalysis{Status: models.SaltDriftNodeCompliant}
+
+	// full_return 包装
+	if m, ok := value.(map[string]interface{}); ok {
+		if ret, hasRet := m["ret"]; hasRet {
+			value = ret
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, key := range sortedKeys(v) {
+			state, ok := v[key].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			a.StatesTotal++
+
+			changes, _ := state["changes"].(map[string]interface{})
+			if len(changes) == 0 {
+				changes, _ = state["pchanges"].(map[string]interface{}) // 旧版本 Salt
+			}
+			result, hasResult := state["result"].(bool)
+			failed := hasResult && !result
+			drifted := !hasResult || len(changes) > 0
+			if !failed && !drifted {
+				continue
+			}
+
+			drift := models.SaltDriftStateResult{StateID: key, Failed: failed}
+			drift.SLS, _ = state["__sls__"].(string)
+			drift.Comment, _ = state["comment"].(string)
+			drift.Function, drift.Name = parseStateKey(key)
+			if name, ok := state["name"].(string); ok && name != "" {
+				drift.Name = name
+			}
+			if len(changes) > 0 {
+				drift.Changes = marshalResultValue(changes)
+			}
+			if failed {
+				a.StatesFailed++
+			} else {
+				a.StatesDrifted++
+			}
+			a.Drifts = append(a.Drifts, drift)
+		}
+	case []interface{}:
+		// 渲染/编译错误返回字符串列表
+		lines := make([]string, 0, len(v))
+		for _, item := range v {
+			lines = append(lines, factString(item))
+		}
+		a.Message = strings.Join(lines, "\n")
+		a.Status = models.SaltDriftNodeError
+		return a
+	case string:
+		a.Message = v
+		a.Status = models.SaltDriftNodeError
+		return a
+	default:
+		a.Message = marshalResultValue(v)
+		a.Status = models.SaltDriftNodeError
+		return a
+	}
+
+	switch {
+	case a.StatesFailed > 0:
+		a.Status = models.SaltDriftNodeError
+	case a.StatesDrifted > 0:
+		a.Status = models.SaltDriftNodeDrifted
+	}
+	return a
+}
+
+// parseStateKey 解析状态键 "<模块>_|-<ID>_|-<name>_|-<函数>" 为 模块.函数 和 name
+func parseStateKey(key string) (function, name string) {
+	parts := strings.Split(key, "_|-")
+	if len(parts) != 4 {
+		return "", key
+	}
+	return parts[0] + "." + parts[3], parts[2]
+}
+
+// ==================== 调度器 ====================
+
+// Start 启动后台扫描调度循环
+func (s *SaltDriftService) Start() {
+	s.runner.Start(func() {
+		// 服务重启前遗留的扫描无法再追踪，标记为失败
+		s.db.Model(&models.SaltDriftRun{}).Where("status = ?", models.SaltDriftRunRunning).
+			Updates(map[string]interface{}{"status": models.SaltDriftRunFailed, "error_message": "backend restarted before the scan finished"})
+	})
+}
+
+// Stop 停止后台扫描调度循环
+func (s *SaltDriftService) Stop() {
+	s.runner.Stop()
+}
+
+// fireDue 触发调度器抢占到的一次定时扫描
+func (s *SaltDriftService) fireDue(id uint, _ time.Time) {
+	policy, err := s.GetPolicy(id)
+	if err != nil {
+		log.Printf("[SaltDriftService] 加载到期策略失败: id=%d, err=%v", id, err)
+		return
+	}
+	if _, err := s.fire(policy, "cron", policy.CreatedBy); err != nil {
+		log.Printf("[SaltDriftService] 触发扫描失败: policy=%s, err=%v", policy.Name, err)
+	}
+}
+
+// fire 创建扫描记录并异步执行；同一策略同时只允许一次扫描
+func (s *SaltDriftService) fire(policy *models.SaltDriftPolicy, trigger, username string) (*models.SaltDriftRun, error) {
+	s.mu.Lock()
+	if s.active[policy.ID] {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("drift scan for policy %s is already running", policy.Name)
+	}
+	s.active[policy.ID] = true
+	s.mu.Unlock()
+
+	run := &models.SaltDriftRun{
+		PolicyID:    policy.ID,
+		Trigger:     trigger,
+		Status:      models.SaltDriftRunRunning,
+		TriggeredBy: username,
+		StartedAt:   time.Now(),
+	}
+	if err := s.db.Create(run).Error; err != nil {
+		s.release(policy.ID)
+		return nil, err
+	}
+	go s.execute(*policy, run)
+	return run, nil
+}
+
+func (s *SaltDriftService) release(policyID uint) {
+	s.mu.Lock()
+	delete(s.active, policyID)
+	s.mu.Unlock()
+}
+
+// ==================== 扫描执行 ====================
+
+// execute 下发 test=True 状态作业、等待结果并记录漂移
+func (s *SaltDriftService) execute(policy models.SaltDriftPolicy, run *models.SaltDriftRun) {
+	defer s.release(policy.ID)
+
+	results, minions, err := s.runTestJob(&policy, run)
+	if err != nil && !errors.Is(err, context.DeadlineExceeded) {
+		s.finishRun(&policy, run, models.SaltDriftRunFailed, err.Error())
+		return
+	}
+
+	newlyDrifted, recordErr := s.recordResults(&policy, run, minions, results)
+	if recordErr != nil {
+		s.finishRun(&policy, run, models.SaltDriftRunFailed, recordErr.Error())
+		return
+	}
+	run.NewlyDrifted = len(newlyDrifted)
+
+	status, message := models.SaltDriftRunCompleted, ""
+	if errors.Is(err, context.DeadlineExceeded) {
+		status = models.SaltDriftRunTimeout
+		message = fmt.Sprintf("%d minion(s) did not return within %ds", run.MissingCount, policy.Timeout)
+	}
+	s.finishRun(&policy, run, status, message)
+
+	for i := range newlyDrifted {
+		s.raiseDriftEvent(&policy, run, &newlyDrifted[i])
+	}
+	log.Printf("[SaltDriftService] 扫描结束: policy=%s, run=%d, jid=%s, compliant=%d, drifted=%d, error=%d, missing=%d, newly_drifted=%d",
+		policy.Name, run.ID, run.JID, run.CompliantCount, run.DriftedCount, run.ErrorCount, run.MissingCount, run.NewlyDrifted)
+}
+
+// runTestJob 以 test=True 下发 state.apply 并写入作业历史
+func (s *SaltDriftService) runTestJob(policy *models.SaltDriftPolicy, run *models.SaltDriftRun) (map[string]interface{}, []string, error) {
+	// 分组目标在扫描时展开为当前成员，路由与下发使用同一组 minion
+	target, tgtType, err := ExpandMinionGroupTarget(s.db, policy.Target, policy.TgtType)
+	if err != nil {
+		return nil, nil, err
+	}
+	saltService, err := SaltServiceForTarget(policy.MasterID, target, tgtType)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var args []interface{}
+	if states := strings.TrimSpace(policy.States); states != "" {
+		args = []interface{}{states}
+	}
+	kwarg := map[string]interface{}{"test": true}
+
+	ctx := context.Background()
+	jid, minions, err := saltService.SubmitAsync(ctx, target, tgtType, "state.apply", args, kwarg)
+	if err != nil {
+		return nil, nil, err
+	}
+	run.JID = jid
+	run.TotalCount = len(minions)
+	s.db.Model(run).Updates(map[string]interface{}{"jid": jid, "total_count": run.TotalCount})
+
+	saltJobService := GetSaltJobService()
+	if saltJobService != nil {
+		argsJSON, _ := json.Marshal(append(args, "test=True"))
+		saltJobService.CreateJob(ctx, &models.SaltJobHistory{
+			JID:       jid,
+			TaskID:    fmt.Sprintf("DRIFT-%d-%d", policy.ID, run.ID),
+			Function:  "state.apply",
+			Arguments: string(argsJSON),
+			Target:    target,
+			TgtType:   tgtType,
+			User:      run.TriggeredBy,
+		})
+	}
+
+	timeout := time.Duration(policy.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Minute
+	}
+	results, waitErr := saltService.WaitForJob(ctx, jid, minions, timeout)
+	if saltJobService != nil {
+		if waitErr != nil {
+			saltJobService.TimeoutJob(ctx, jid)
+		} else {
+			success, failed, _ := CountJobResults(results)
+			saltJobService.CompleteJob(ctx, jid, results, success, failed)
+		}
+	}
+	return results, minions, waitErr
+}
+
+// recordResults 保存逐节点结果并更新节点合规状态，返回由合规转为漂移的节点
+func (s *SaltDriftService) recordResults(policy *models.SaltDriftPolicy, run *models.SaltDriftRun, minions []string, results map[string]interface{}) ([]models.SaltDriftNodeResult, error) {
+	targets := make(map[string]bool, len(minions)+len(results))
+	for _, m := range minions {
+		targets[m] = true
+	}
+	for m := range results {
+		targets[m] = true
+	}
+	ids := make([]string, 0, len(targets))
+	for m := range targets {
+		ids = append(ids, m)
+	}
+	sort.Strings(ids)
+	run.TotalCount = len(ids)
+
+	previous := map[string]string{}
+	var statuses []models.SaltDriftNodeStatus
+	s.db.Where("policy_id = ? AND minion_id IN ?", policy.ID, ids).Find(&statuses)
+	for _, st := range statuses {
+		previous[st.MinionID] = st.Status
+	}
+
+	now := time.Now()
+	var newlyDrifted []models.SaltDriftNodeResult
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		for _, minion := range ids {
+			node := models.SaltDriftNodeResult{RunID: run.ID, PolicyID: policy.ID, MinionID: minion}
+			value, responded := results[minion]
+			if responded {
+				a := analyzeDriftReturn(value)
+				node.Status = a.Status
+				node.StatesTotal = a.StatesTotal
+				node.StatesDrifted = a.StatesDrifted
+				node.StatesFailed = a.StatesFailed
+				node.Message = a.Message
+				node.Drifts = a.Drifts
+			} else {
+				node.Status = models.SaltDriftNodeNoResponse
+			}
+			for i := range node.Drifts {
+				node.Drifts[i].RunID = run.ID
+				node.Drifts[i].PolicyID = policy.ID
+				node.Drifts[i].MinionID = minion
+			}
+			if err := tx.Create(&node).Error; err != nil {
+				return err
+			}
+
+			switch node.Status {
+			case models.SaltDriftNodeCompliant:
+				run.CompliantCount++
+			case models.SaltDriftNodeDrifted:
+				run.DriftedCount++
+			case models.SaltDriftNodeError:
+				run.ErrorCount++
+			default:
+				run.MissingCount++
+				continue // 未响应不改变节点已知状态
+			}
+
+			status := models.SaltDriftNodeStatus{
+				PolicyID:      policy.ID,
+				MinionID:      minion,
+				Status:        node.Status,
+				StatesDrifted: node.StatesDrifted,
+				LastRunID:     run.ID,
+				LastCheckedAt: now,
+			}
+			updateColumns := []string{"status", "states_drifted", "last_run_id", "last_checked_at", "updated_at"}
+			prev := previous[minion]
+			switch {
+			case node.Status == models.SaltDriftNodeDrifted && prev != models.SaltDriftNodeDrifted:
+				status.DriftedSince = &now
+				updateColumns = append(updateColumns, "drifted_since")
+			case node.Status != models.SaltDriftNodeDrifted:
+				updateColumns = append(updateColumns, "drifted_since") // 恢复合规后清空
+			}
+			if err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "policy_id"}, {Name: "minion_id"}},
+				DoUpdates: clause.AssignmentColumns(updateColumns),
+			}).Create(&status).Error; err != nil {
+				return err
+			}
+
+			if prev == models.SaltDriftNodeCompliant && node.Status == models.SaltDriftNodeDrifted {
+				newlyDrifted = append(newlyDrifted, node)
+			}
+		}
+		return nil
+	})
+	return newlyDrifted, err
+}
+
+// finishRun 更新扫描记录与策略的最近状态
+func (s *SaltDriftService) finishRun(policy *models.SaltDriftPolicy, run *models.SaltDriftRun, status, message string) {
+	now := time.Now()
+	run.Status = status
+	run.ErrorMessage = message
+	run.FinishedAt = &now
+	s.db.Model(run).Updates(map[string]interface{}{
+		"status":          run.Status,
+		"error_message":   run.ErrorMessage,
+		"finished_at":     now,
+		"total_count":     run.TotalCount,
+		"compliant_count": run.CompliantCount,
+		"drifted_count":   run.DriftedCount,
+		"error_count":     run.ErrorCount,
+		"missing_count":   run.MissingCount,
+		"newly_drifted":   run.NewlyDrifted,
+	})
+	s.db.Model(&models.SaltDriftPolicy{}).Where("id = ?", policy.ID).
+		Updates(map[string]interface{}{"last_status": status, "drifted_count": run.DriftedCount})
+}
+
+// raiseDriftEvent 节点由合规转为漂移：写入审计日志并回调 Webhook
+func (s *SaltDriftService) raiseDriftEvent(policy *models.SaltDriftPolicy, run *models.SaltDriftRun, node *models.SaltDriftNodeResult) {
+	states := make([]map[string]interface{}, 0, len(node.Drifts))
+	for _, d := range node.Drifts {
+		if d.Failed {
+			continue
+		}
+		states = append(states, map[string]interface{}{
+			"state_id": d.StateID,
+			"sls":      d.SLS,
+			"function": d.Function,
+			"name":     d.Name,
+			"comment":  d.Comment,
+		})
+	}
+	payload := map[string]interface{}{
+		"event":          "salt_node_drifted",
+		"policy_id":      policy.ID,
+		"policy_name":    policy.Name,
+		"minion_id":      node.MinionID,
+		"run_id":         run.ID,
+		"jid":            run.JID,
+		"states_drifted": node.StatesDrifted,
+		"states":         states,
+		"detected_at":    node.CreatedAt,
+	}
+
+	GetAuditService().NewAuditEntry(models.AuditCategorySaltstack, models.AuditActionSaltDriftDetect).
+		WithUser(0, policy.CreatedBy, "").
+		WithResource("salt_minion", node.MinionID, node.MinionID).
+		WithStatus(models.AuditStatusSuccess).
+		WithSeverity(models.AuditSeverityWarning).
+		WithMetadata(payload).
+		WithTags("salt_drift", "node_drifted").
+		SaveAsync()
+
+	if policy.NotifyWebhook == "" {
+		return
+	}
+	if err := postWebhook(s.httpClient, policy.NotifyWebhook, payload); err != nil {
+		log.Printf("[SaltDriftService] 漂移通知发送失败: policy=%s, minion=%s, err=%v", policy.Name, node.MinionID, err)
+	}
+}
+
+// ==================== 管理接口 ====================
+
+func (s *SaltDriftService) buildPolicy(policy *models.SaltDriftPolicy, req *models.SaltDriftPolicyRequest) error {
+	if _, err := ParseCronExpression(req.CronExpr); err != nil {
+		return err
+	}
+	if _, err := loadScheduleLocation(req.Timezone); err != nil {
+		return err
+	}
+	policy.Name = req.Name
+	policy.Description = req.Description
+	policy.Target = req.Target
+	policy.TgtType = req.TgtType
+	if policy.TgtType == "" {
+		policy.TgtType = "glob"
+	}
+	policy.MasterID = req.MasterID
+	if _, err := SaltServiceForTarget(policy.MasterID, policy.Target, policy.TgtType); err != nil {
+		return err
+	}
+	policy.States = strings.Trim(strings.ReplaceAll(req.States, " ", ""), ",")
+	policy.CronExpr = req.CronExpr
+	policy.Timezone = req.Timezone
+	if policy.Timezone == "" {
+		policy.Timezone = "UTC"
+	}
+	policy.Timeout = req.Timeout
+	if policy.Timeout <= 0 {
+		policy.Timeout = 600
+	}
+	policy.NotifyWebhook = strings.TrimSpace(req.NotifyWebhook)
+	if policy.NotifyWebhook != "" {
+		if err := checkWebhookURL(policy.NotifyWebhook); err != nil {
+			return fmt.Errorf("invalid notify_webhook: %v", err)
+		}
+	}
+	if req.Enabled != nil {
+		policy.Enabled = *req.Enabled
+	}
+
+	policy.NextRunAt = nil
+	if policy.Enabled {
+		next, err := nextCronRun(policy.CronExpr, policy.Timezone, time.Now())
+		if err != nil {
+			return err
+		}
+		policy.NextRunAt = next
+	}
+	return nil
+}
+
+// CreatePolicy 创建漂移扫描策略
+func (s *SaltDriftService) CreatePolicy(req *models.SaltDriftPolicyRequest, username string) (*models.SaltDriftPolicy, error) {
+	policy := &models.SaltDriftPolicy{Enabled: true, CreatedBy: username}
+	if err := s.buildPolicy(policy, req); err != nil {
+		return nil, err
+	}
+	if err := s.db.Create(policy).Error; err != nil {
+		return nil, err
+	}
+	log.Printf("[SaltDriftService] 漂移扫描策略已创建: name=%s, target=%s, states=%s, cron=%s, user=%s",
+		policy.Name, policy.Target, policy.States, policy.CronExpr, username)
+	return policy, nil
+}
+
+// UpdatePolicy 更新漂移扫描策略
+func (s *SaltDriftService) UpdatePolicy(id uint, req *models.SaltDriftPolicyRequest, username string) (*models.SaltDriftPolicy, error) {
+	policy, err := s.GetPolicy(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.buildPolicy(policy, req); err != nil {
+		return nil, err
+	}
+	policy.UpdatedBy = username
+	if err := s.db.Save(policy).Error; err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// DeletePolicy 删除漂移扫描策略（历史报告保留）
+func (s *SaltDriftService) DeletePolicy(id uint) error {
+	return s.db.Delete(&models.SaltDriftPolicy{}, id).Error
+}
+
+// GetPolicy 获取漂移扫描策略
+func (s *SaltDriftService) GetPolicy(id uint) (*models.SaltDriftPolicy, error) {
+	var policy models.SaltDriftPolicy
+	if err := s.db.First(&policy, id).Error; err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// ListPolicies 获取所有漂移扫描策略
+func (s *SaltDriftService) ListPolicies() ([]models.SaltDriftPolicy, error) {
+	var policies []models.SaltDriftPolicy
+	err := s.db.Order("name ASC").Find(&policies).Error
+	return policies, err
+}
+
+// TriggerNow 手动立即扫描
+func (s *SaltDriftService) TriggerNow(id uint, username string) (*models.SaltDriftRun, error) {
+	policy, err := s.GetPolicy(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.fire(policy, "manual", username)
+}
+
+// ListRuns 分页查询扫描记录
+func (s *SaltDriftService) ListRuns(policyID uint, page, pageSize int) ([]models.SaltDriftRun, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	query := s.db.Model(&models.SaltDriftRun{})
+	if policyID > 0 {
+		query = query.Where("policy_id = ?", policyID)
+	}
+	var total int64
+	query.Count(&total)
+
+	var runs []models.SaltDriftRun
+	err := query.Order("started_at DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&runs).Error
+	return runs, total, err
+}
+
+// GetRun 获取扫描详情（含逐节点结果及待变更状态）
+func (s *SaltDriftService) GetRun(id uint) (*models.SaltDriftRun, []models.SaltDriftNodeResult, error) {
+	var run models.SaltDriftRun
+	if err := s.db.First(&run, id).Error; err != nil {
+		return nil, nil, err
+	}
+	var nodes []models.SaltDriftNodeResult
+	err := s.db.Preload("Drifts").Where("run_id = ?", id).Order("minion_id ASC").Find(&nodes).Error
+	return &run, nodes, err
+}
+
+// ListNodeStatus 查询节点当前合规状态
+func (s *SaltDriftService) ListNodeStatus(policyID uint, status string) ([]models.SaltDriftNodeStatus, error) {
+	query := s.db.Model(&models.SaltDriftNodeStatus{})
+	if policyID > 0 {
+		query = query.Where("policy_id = ?", policyID)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var statuses []models.SaltDriftNodeStatus
+	err := query.Order("policy_id ASC, minion_id ASC").Find(&statuses).Error
+	return statuses, err
+}
+
+// NodeHistory 查询单个节点的漂移历史（按时间倒序）
+func (s *SaltDriftService) NodeHistory(minionID string, policyID uint, limit int) ([]models.SaltDriftNodeResult, error) {
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	query := s.db.Preload("Drifts").Where("minion_id = ?", minionID)
+	if policyID > 0 {
+		query = query.Where("policy_id = ?", policyID)
+	}
+	var nodes []models.SaltDriftNodeResult
+	err := query.Order("created_at DESC").Limit(limit).Find(&nodes).Error
+	return nodes, err
+}
+
+// StateReport 按状态聚合时间范围内的漂移情况
+func (s *SaltDriftService) StateReport(policyID uint, since time.Time, includeFailed bool) ([]models.SaltDriftStateSummary, error) {
+	query := s.db.Model(&models.SaltDriftStateResult{}).
+		Select("state_id, MAX(sls) AS sls, MAX(function) AS function, MAX(name) AS name, COUNT(*) AS occurrences, COUNT(DISTINCT minion_id) AS minion_count, MAX(created_at) AS last_seen_at").
+		Where("created_at >= ?", since)
+	if policyID > 0 {
+		query = query.Where("policy_id = ?", policyID)
+	}
+	if !includeFailed {
+		query = query.Where("failed = ?", false)
+	}
+	var summaries []models.SaltDriftStateSummary
+	err := query.Group("state_id").Order("occurrences DESC").Scan(&summaries).Error
+	return summaries, err
+}
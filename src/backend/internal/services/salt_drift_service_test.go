@@ -0,0 +1,60 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
+)
+
+func TestAnalyzeDriftReturn(t *testing.T) {
+	drifted := map[string]interface{}{
+		"ret": map[string]interface{}{
+			"file_|-slurm_conf_|-/etc/slurm/slurm.conf_|-managed": map[string]interface{}{
+				"result":  nil,
+				"comment": "The file /etc/slurm/slurm.conf is set to be changed",
+				"changes": map[string]interface{}{"diff": "-MaxTime=1\n+MaxTime=INFINITE"},
+				"name":    "/etc/slurm/slurm.conf",
+				"__sls__": "slurm.config",
+			},
+			"service_|-munge_|-munge_|-running": map[string]interface{}{
+				"result":  true,
+				"comment": "The service munge is already running",
+				"changes": map[string]interface{}{},
+				"__sls__": "munge",
+			},
+		},
+		"retcode": float64(0),
+	}
+	a := analyzeDriftReturn(drifted)
+	if a.Status != models.SaltDriftNodeDrifted || a.StatesTotal != 2 || a.StatesDrifted != 1 {
+		t.Fatalf("漂移节点解析错误: %+v", a)
+	}
+	d := a.Drifts[0]
+	if d.Function != "file.managed" || d.Name != "/etc/slurm/slurm.conf" || d.SLS != "slurm.config" || d.Failed {
+		t.Errorf("待变更状态解析错误: %+v", d)
+	}
+	if !strings.Contains(d.Changes, "MaxTime=INFINITE") {
+		t.Errorf("changes 未记录: %s", d.Changes)
+	}
+
+	compliant := map[string]interface{}{
+		"pkg_|-slurm_|-slurm_|-installed": map[string]interface{}{"result": true, "changes": map[string]interface{}{}},
+	}
+	if a := analyzeDriftReturn(compliant); a.Status != models.SaltDriftNodeCompliant || len(a.Drifts) != 0 {
+		t.Errorf("合规节点解析错误: %+v", a)
+	}
+
+	failed := map[string]interface{}{
+		"pkg_|-slurm_|-slurm_|-installed":   map[string]interface{}{"result": false, "comment": "No package slurm found"},
+		"file_|-motd_|-/etc/motd_|-managed": map[string]interface{}{"result": nil, "changes": map[string]interface{}{"diff": "x"}},
+	}
+	if a := analyzeDriftReturn(failed); a.Status != models.SaltDriftNodeError || a.StatesFailed != 1 || a.StatesDrifted != 1 {
+		t.Errorf("失败节点解析错误: %+v", a)
+	}
+
+	compileErr := []interface{}{"Rendering SLS 'base:slurm' failed: Jinja variable 'x' is undefined"}
+	if a := analyzeDriftReturn(compileErr); a.Status != models.SaltDriftNodeError || !strings.Contains(a.Message, "Rendering SLS") {
+		t.Errorf("编译错误解析错误: %+v", a)
+	}
+}
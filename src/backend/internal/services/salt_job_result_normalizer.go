@@ -0,0 +1,236 @@
+package services
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
+)
+
+// NormalizeSaltJobResults 将作业的原始返回（minion -> 返回值）拆分为每个 minion 的规范化结果
+// 兼容 full_return 格式（{"ret": ..., "retcode": N}）及 Salt REST 的 {"return": [{...}]} 包装
+func NormalizeSaltJobResults(function string, result map[string]interface{}) []models.SaltJobMinionResult {
+	if ret, ok := result["return"].([]interface{}); ok && len(result) == 1 {
+		if len(ret) == 0 {
+			return nil
+		}
+		m, ok := ret[0].(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		result = m
+	}
+
+	minions := make([]string, 0, len(result))
+	for minion := range result {
+		minions = append(minions, minion)
+	}
+	sort.Strings(minions)
+
+	out := make([]models.SaltJobMinionResult, 0, len(minions))
+	for _, minion := range minions {
+		r := NormalizeMinionReturn(function, result[minion])
+		r.MinionID = minion
+		r.Function = function
+		out = append(out, r)
+	}
+	return out
+}
+
+// NormalizeMinionReturn 规范化单个 minion 的返回值
+func NormalizeMinionReturn(function string, value interface{}) models.SaltJobMinionResult {
+	r := models.SaltJobMinionResult{Success: true}
+
+	// full_return 包装
+	if m, ok := value.(map[string]interface{}); ok {
+		if ret, hasRet := m["ret"]; hasRet {
+			if rc, ok := toInt(m["retcode"]); ok {
+				r.Retcode = rc
+				r.Success = rc == 0
+			}
+			if success, ok := m["success"].(bool); ok && !success {
+				r.Success = false
+			}
+			value = ret
+		}
+	}
+
+	switch {
+	case strings.HasPrefix(function, "state."):
+		normalizeStateReturn(&r, value)
+	case strings.HasPrefix(function, "pkg."):
+		normalizePkgReturn(&r, value)
+	default:
+		normalizeCmdReturn(&r, value)
+	}
+
+	if r.Retcode != 0 {
+		r.Success = false
+	}
+	return r
+}
+
+// normalizeCmdReturn cmd.run 返回字符串；cmd.run_all/cmd.script 返回 stdout/stderr/retcode
+func normalizeCmdReturn(r *models.SaltJobMinionResult, value interface{}) {
+	switch v := value.(type) {
+	case string:
+		r.Stdout = v
+	case map[string]interface{}:
+		stdout, hasStdout := v["stdout"].(string)
+		if !hasStdout {
+			r.Stdout = marshalResultValue(v)
+			return
+		}
+		r.Stdout = stdout
+		r.Stderr, _ = v["stderr"].(string)
+		if rc, ok := toInt(v["retcode"]); ok && rc != 0 {
+			r.Retcode = rc
+		}
+	case bool:
+		r.Stdout = strconv.FormatBool(v)
+		if !v {
+			r.Success = false
+		}
+	default:
+		r.Stdout = marshalResultValue(v)
+	}
+}
+
+// normalizeStateReturn state.apply/sls/highstate 返回状态ID -> 状态结果；编译错误时返回字符串列表
+func normalizeStateReturn(r *models.SaltJobMinionResult, value interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		var comments []string
+		var errs []string
+		for _, key := range sortedKeys(v) {
+			state, ok := v[key].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			r.StatesTotal++
+			if changes, ok := state["changes"].(map[string]interface{}); ok && len(changes) > 0 {
+				r.StatesChanged++
+			}
+			if d, ok := toFloat(state["duration"]); ok {
+				r.DurationMs += d
+			}
+			comment, _ := state["comment"].(string)
+			// result 为 nil 表示 test=True 下的待变更，不计为失败
+			if result, ok := state["result"].(bool); ok && !result {
+				r.StatesFailed++
+				errs = append(errs, key+": "+comment)
+			} else if comment != "" {
+				comments = append(comments, key+": "+comment)
+			}
+		}
+		r.Stdout = strings.Join(comments, "\n")
+		r.Stderr = strings.Join(errs, "\n")
+		if r.StatesFailed > 0 {
+			r.Success = false
+		}
+	case []interface{}:
+		lines := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				lines = append(lines, s)
+			} else {
+				lines = append(lines, marshalResultValue(item))
+			}
+		}
+		r.Stderr = strings.Join(lines, "\n")
+		r.Success = false
+	case string:
+		r.Stderr = v
+		r.Success = false
+	default:
+		r.Stdout = marshalResultValue(v)
+	}
+}
+
+// normalizePkgReturn pkg.install/remove/upgrade 返回包名 -> {old, new}
+func normalizePkgReturn(r *models.SaltJobMinionResult, value interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		var lines []string
+		for _, name := range sortedKeys(v) {
+			change, ok := v[name].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			oldVer, hasOld := change["old"]
+			newVer, hasNew := change["new"]
+			if !hasOld && !hasNew {
+				continue
+			}
+			r.StatesChanged++
+			lines = append(lines, name+": "+formatPkgVersion(oldVer)+" -> "+formatPkgVersion(newVer))
+		}
+		if len(lines) > 0 {
+			r.Stdout = strings.Join(lines, "\n")
+		} else {
+			r.Stdout = marshalResultValue(v)
+		}
+	case string:
+		if strings.HasPrefix(v, "ERROR") || strings.Contains(strings.ToLower(v), "error:") {
+			r.Stderr = v
+			r.Success = false
+		} else {
+			r.Stdout = v
+		}
+	default:
+		r.Stdout = marshalResultValue(v)
+	}
+}
+
+func formatPkgVersion(v interface{}) string {
+	if s, ok := v.(string); ok && s != "" {
+		return s
+	}
+	return "-"
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func marshalResultValue(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+func toInt(v interface{}) (int, bool) {
+	f, ok := toFloat(v)
+	return int(f), ok
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	case string:
+		// 旧版本 Salt 的 duration 为 "12.3 ms"
+		f, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(n, "ms")), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
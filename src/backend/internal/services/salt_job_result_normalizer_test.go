@@ -0,0 +1,95 @@
+package services
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func decodeResult(t *testing.T, raw string) map[string]interface{} {
+	t.Helper()
+	var m map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &m); err != nil {
+		t.Fatalf("解析测试数据失败: %v", err)
+	}
+	return m
+}
+
+func TestNormalizeSaltJobResults_CmdRun(t *testing.T) {
+	result := decodeResult(t, `{
+		"node-a": {"ret": "ECC error on DIMM 3", "retcode": 0},
+		"node-b": {"ret": "", "retcode": 2},
+		"node-c": "plain output"
+	}`)
+	rows := NormalizeSaltJobResults("cmd.run", result)
+	if len(rows) != 3 {
+		t.Fatalf("期望 3 条结果，实际 %d", len(rows))
+	}
+	if rows[0].MinionID != "node-a" || rows[0].Stdout != "ECC error on DIMM 3" || !rows[0].Success {
+		t.Errorf("node-a 结果不正确: %+v", rows[0])
+	}
+	if rows[1].Retcode != 2 || rows[1].Success {
+		t.Errorf("node-b 应为失败且 retcode=2: %+v", rows[1])
+	}
+	if rows[2].Stdout != "plain output" || !rows[2].Success {
+		t.Errorf("node-c 结果不正确: %+v", rows[2])
+	}
+}
+
+func TestNormalizeSaltJobResults_CmdRunAllWrapped(t *testing.T) {
+	result := decodeResult(t, `{"return": [{"node-a": {"stdout": "out", "stderr": "warn", "retcode": 1, "pid": 42}}]}`)
+	rows := NormalizeSaltJobResults("cmd.run_all", result)
+	if len(rows) != 1 {
+		t.Fatalf("期望 1 条结果，实际 %d", len(rows))
+	}
+	r := rows[0]
+	if r.Stdout != "out" || r.Stderr != "warn" || r.Retcode != 1 || r.Success {
+		t.Errorf("cmd.run_all 结果不正确: %+v", r)
+	}
+}
+
+func TestNormalizeMinionReturn_State(t *testing.T) {
+	value := decodeResult(t, `{
+		"ret": {
+			"file_|-motd_|-/etc/motd_|-managed": {"result": true, "changes": {"diff": "..."}, "duration": 12.5, "comment": "File updated"},
+			"pkg_|-vim_|-vim_|-installed": {"result": true, "changes": {}, "duration": 100, "comment": "already installed"},
+			"service_|-slurmd_|-slurmd_|-running": {"result": false, "changes": {}, "duration": "7.5 ms", "comment": "Service failed to start"}
+		},
+		"retcode": 2
+	}`)
+	r := NormalizeMinionReturn("state.apply", value)
+	if r.StatesTotal != 3 || r.StatesChanged != 1 || r.StatesFailed != 1 {
+		t.Errorf("状态统计不正确: total=%d changed=%d failed=%d", r.StatesTotal, r.StatesChanged, r.StatesFailed)
+	}
+	if r.DurationMs != 120 {
+		t.Errorf("期望耗时 120ms，实际 %v", r.DurationMs)
+	}
+	if r.Success || r.Retcode != 2 {
+		t.Errorf("state 应为失败: %+v", r)
+	}
+	if r.Stderr != "service_|-slurmd_|-slurmd_|-running: Service failed to start" {
+		t.Errorf("stderr 不正确: %q", r.Stderr)
+	}
+}
+
+func TestNormalizeMinionReturn_StateCompileError(t *testing.T) {
+	r := NormalizeMinionReturn("state.sls", []interface{}{"Rendering SLS 'base:slurm' failed"})
+	if r.Success || r.Stderr == "" {
+		t.Errorf("编译错误应标记为失败: %+v", r)
+	}
+}
+
+func TestNormalizeMinionReturn_Pkg(t *testing.T) {
+	value := decodeResult(t, `{"htop": {"old": "", "new": "3.2.1"}, "vim": {"old": "8.2", "new": "9.0"}}`)
+	r := NormalizeMinionReturn("pkg.install", value)
+	if r.StatesChanged != 2 || !r.Success {
+		t.Errorf("pkg 结果不正确: %+v", r)
+	}
+	if r.Stdout != "htop: - -> 3.2.1\nvim: 8.2 -> 9.0" {
+		t.Errorf("pkg stdout 不正确: %q", r.Stdout)
+	}
+
+	r = NormalizeMinionReturn("pkg.install", "ERROR: No package named foo")
+	if r.Success || r.Stderr == "" {
+		t.Errorf("pkg 错误应标记为失败: %+v", r)
+	}
+}
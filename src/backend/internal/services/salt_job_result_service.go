@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// saltJobResultTextExpr 全文检索使用的文本表达式（需与 GIN 索引表达式一致）
+const saltJobResultTextExpr = "to_tsvector('simple', coalesce(stdout, '') || ' ' || coalesce(stderr, ''))"
+
+// migrateMinionResults 迁移规范化结果表，PostgreSQL 下创建全文检索索引
+func (s *SaltJobService) migrateMinionResults() {
+	if err := s.db.AutoMigrate(&models.SaltJobMinionResult{}); err != nil {
+		log.Printf("[SaltJobService] 迁移规范化结果表失败: %v", err)
+		return
+	}
+	if s.db.Dialector.Name() != "postgres" {
+		return
+	}
+	if err := s.db.Exec("CREATE INDEX IF NOT EXISTS idx_salt_job_minion_results_fts ON salt_job_minion_results USING GIN (" + saltJobResultTextExpr + ")").Error; err != nil {
+		log.Printf("[SaltJobService] 创建全文检索索引失败: %v", err)
+	}
+}
+
+// recordMinionResults 将作业原始结果拆分为每个 minion 的规范化结果并写入（按 jid+minion 幂等）
+func (s *SaltJobService) recordMinionResults(job *models.SaltJobHistory, result map[string]interface{}) {
+	if len(result) == 0 || !IsUserTask(job.Function, job.TaskID) {
+		return
+	}
+	rows := NormalizeSaltJobResults(job.Function, result)
+	if len(rows) == 0 {
+		return
+	}
+	for i := range rows {
+		rows[i].JID = job.JID
+		rows[i].TaskID = job.TaskID
+		rows[i].User = job.User
+		rows[i].StartTime = job.StartTime
+		if rows[i].DurationMs == 0 && job.Duration > 0 {
+			rows[i].DurationMs = float64(job.Duration)
+		}
+	}
+	err := s.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "jid"}, {Name: "minion_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"success", "retcode", "stdout", "stderr", "duration_ms",
+			"states_total", "states_changed", "states_failed",
+		}),
+	}).CreateInBatches(rows, 200).Error
+	if err != nil {
+		log.Printf("[SaltJobService] 写入规范化结果失败: JID=%s, err=%v", job.JID, err)
+	}
+}
+
+// recordMinionResultsJSON 从 JSON 格式的原始结果写入规范化结果
+func (s *SaltJobService) recordMinionResultsJSON(job *models.SaltJobHistory) {
+	if job.Result == "" || job.Status == "running" {
+		return
+	}
+	var result map[string]interface{}
+	if err := json.Unmarshal([]byte(job.Result), &result); err != nil {
+		return
+	}
+	s.recordMinionResults(job, result)
+}
+
+// GetJobMinionResults 获取作业在各 minion 上的规范化结果
+func (s *SaltJobService) GetJobMinionResults(ctx context.Context, jid string) ([]models.SaltJobMinionResult, error) {
+	var results []models.SaltJobMinionResult
+	err := s.db.WithContext(ctx).Where("jid = ?", jid).Order("success ASC, minion_id ASC").Find(&results).Error
+	return results, err
+}
+
+// SearchMinionResults 检索作业输出
+// 默认使用 PostgreSQL 短语全文检索（如 "ECC error"），mode=substring 时使用不区分大小写的子串匹配
+func (s *SaltJobService) SearchMinionResults(ctx context.Context, params *models.SaltJobResultSearchParams) (*models.SaltJobResultListResponse, error) {
+	if params.Page < 1 {
+		params.Page = 1
+	}
+	if params.PageSize < 1 || params.PageSize > 500 {
+		params.PageSize = 50
+	}
+
+	query := s.db.WithContext(ctx).Model(&models.SaltJobMinionResult{})
+	if params.Query != "" {
+		if params.Mode == "substring" || s.db.Dialector.Name() != "postgres" {
+			like := "%" + params.Query + "%"
+			query = query.Where("LOWER(stdout) LIKE LOWER(?) OR LOWER(stderr) LIKE LOWER(?)", like, like)
+		} else {
+			query = query.Where(saltJobResultTextExpr+" @@ phraseto_tsquery('simple', ?)", params.Query)
+		}
+	}
+	query = applyMinionResultFilters(query, params.MinionID, params.Function, params.Failed, params.Since, params.Until)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, err
+	}
+	var results []models.SaltJobMinionResult
+	err := query.Order("start_time DESC, minion_id ASC").
+		Offset((params.Page - 1) * params.PageSize).Limit(params.PageSize).
+		Find(&results).Error
+	if err != nil {
+		return nil, err
+	}
+	return &models.SaltJobResultListResponse{Total: total, Page: params.Page, Size: params.PageSize, Data: results}, nil
+}
+
+// GetMinionTimeline 获取 minion 的作业时间线（按开始时间倒序）
+func (s *SaltJobService) GetMinionTimeline(ctx context.Context, minionID string, since, until time.Time, limit int) ([]models.SaltJobMinionResult, error) {
+	if limit <= 0 || limit > 1000 {
+		limit = 200
+	}
+	var results []models.SaltJobMinionResult
+	query := s.db.WithContext(ctx).Model(&models.SaltJobMinionResult{})
+	query = applyMinionResultFilters(query, minionID, "", nil, since, until)
+	err := query.Order("start_time DESC").Limit(limit).Find(&results).Error
+	return results, err
+}
+
+func applyMinionResultFilters(query *gorm.DB, minionID, function string, failed *bool, since, until time.Time) *gorm.DB {
+	if minionID != "" {
+		query = query.Where("minion_id = ?", minionID)
+	}
+	if function != "" {
+		query = query.Where("function = ?", function)
+	}
+	if failed != nil {
+		query = query.Where("success = ?", !*failed)
+	}
+	if !since.IsZero() {
+		query = query.Where("start_time >= ?", since)
+	}
+	if !until.IsZero() {
+		query = query.Where("start_time <= ?", until)
+	}
+	return query
+}
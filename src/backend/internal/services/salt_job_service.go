@@ -96,6 +96,7 @@ func NewSaltJobService(db *gorm.DB, redisClient *redis.Client) *SaltJobService {
 		if err := db.AutoMigrate(&models.SaltJobHistory{}, &models.SaltJobConfig{}); err != nil {
 			log.Printf("[SaltJobService] 自动迁移失败: %v", err)
 		}
+		saltJobServiceInstance.migrateMinionResults()
 		// 加载或初始化配置
 		saltJobServiceInstance.loadOrCreateConfig()
 		// 启动后台清理任务
@@ -241,6 +242,26 @@ func (s *SaltJobService) CheckDangerousCommand(command string) (bool, *models.Da
 	return false, nil, nil
 }
 
+// IsCommandExecutionFunction 判断 Salt 函数是否直接执行 shell 命令（需要做危险命令检查）
+func IsCommandExecutionFunction(function string) bool {
+	return function == "cmd.run" || function == "cmd.shell" || function == "cmd.exec_code"
+}
+
+// CheckDangerousInvocation 检查一次 Salt 调用（函数 + 参数）是否命中危险命令黑名单
+// 仅对 cmd.run 等命令执行函数生效，参数中的字符串会拼接为完整命令后检查
+func (s *SaltJobService) CheckDangerousInvocation(function string, args []interface{}) (bool, *models.DangerousCommand, error) {
+	if !IsCommandExecutionFunction(function) {
+		return false, nil, nil
+	}
+	var fullCommand string
+	for _, arg := range args {
+		if str, ok := arg.(string); ok {
+			fullCommand += str + " "
+		}
+	}
+	return s.CheckDangerousCommand(fullCommand)
+}
+
 // CreateJob 创建或更新作业记录
 // 存储策略：
 // - 用户任务（有 task_id 且不是监控函数）：存入数据库 + Redis
@@ -265,6 +286,7 @@ func (s *SaltJobService) CreateJob(ctx context.Context, job *models.SaltJobHisto
 	}
 
 	// 以下是用户任务的处理逻辑
+	incoming := *job
 	// 使用 Upsert (FirstOrCreate + Updates) 处理重复 JID
 	var existingJob models.SaltJobHistory
 	result := s.db.Where("jid = ?", job.JID).First(&existingJob)
@@ -318,6 +340,10 @@ func (s *SaltJobService) CreateJob(ctx context.Context, job *models.SaltJobHisto
 	// 同时更新 Redis 缓存（用于快速查询）
 	s.cacheJobToRedis(ctx, job)
 
+	// 写入每个 minion 的规范化结果（更新分支中 job 已替换为库中记录，使用本次传入的结果）
+	incoming.StartTime = job.StartTime
+	s.recordMinionResultsJSON(&incoming)
+
 	return nil
 }
 
@@ -395,6 +421,10 @@ func (s *SaltJobService) UpdateJobStatus(ctx context.Context, jid string, update
 	}
 	s.cacheJobToRedis(ctx, &job)
 
+	if update.Result != nil {
+		s.recordMinionResults(&job, update.Result)
+	}
+
 	log.Printf("[SaltJobService] 作业状态已更新: JID=%s, Status=%s, Duration=%dms", jid, update.Status, job.Duration)
 	return nil
 }
@@ -661,6 +691,7 @@ func (s *SaltJobService) runCleanup() {
 	if result.Error == nil && result.RowsAffected > 0 {
 		log.Printf("[SaltJobService] 按时间清理了 %d 条记录", result.RowsAffected)
 	}
+	s.db.Where("start_time < ?", cutoffTime).Delete(&models.SaltJobMinionResult{})
 
 	// 按数量清理（保留最新的 maxRecords 条）
 	var count int64
@@ -0,0 +1,500 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SaltMasterService 多 Master 拓扑管理服务
+// 负责 Master 注册、syndic 层级关系、每个 Master 的 minion 密钥清单同步，
+// 以及按密钥归属将 minion 路由到对应的 Master
+type SaltMasterService struct {
+	db        *gorm.DB
+	startOnce sync.Once
+	stopOnce  sync.Once
+	stopCh    chan struct{}
+}
+
+var (
+	saltMasterServiceInstance *SaltMasterService
+	saltMasterServiceOnce     sync.Once
+)
+
+// NewSaltMasterService 创建 Master 拓扑服务（单例）
+func NewSaltMasterService(db *gorm.DB) *SaltMasterService {
+	saltMasterServiceOnce.Do(func() {
+		saltMasterServiceInstance = &SaltMasterService{
+			db:     db,
+			stopCh: make(chan struct{}),
+		}
+		if err := db.AutoMigrate(&models.SaltMaster{}, &models.SaltMasterMinion{}); err != nil {
+			log.Printf("[SaltMasterService] 自动迁移失败: %v", err)
+		}
+	})
+	return saltMasterServiceInstance
+}
+
+// GetSaltMasterService 获取 Master 拓扑服务实例
+func GetSaltMasterService() *SaltMasterService {
+	return saltMasterServiceInstance
+}
+
+// ==================== Master 注册 ====================
+
+// validate 校验 Master 配置及 syndic 层级关系
+func (s *SaltMasterService) validate(master *models.SaltMaster) error {
+	return validateSaltMaster(master, func(masterID string) (*models.SaltMaster, error) {
+		return s.GetMasterByMasterID(masterID)
+	})
+}
+
+// validateSaltMaster 校验 Master 配置，lookup 按 MasterID 查询上级 Master
+func validateSaltMaster(master *models.SaltMaster, lookup func(masterID string) (*models.SaltMaster, error)) error {
+	if master.MasterID == "" {
+		return errors.New("master_id is required")
+	}
+	if parsed, err := url.Parse(master.APIURL); err != nil || parsed.Scheme == "" || parsed.Host == "" {
+		return fmt.Errorf("invalid api_url %q", master.APIURL)
+	}
+
+	switch master.Role {
+	case "":
+		master.Role = models.SaltMasterRoleMaster
+	case models.SaltMasterRoleMaster, models.SaltMasterRoleSyndic, models.SaltMasterRoleMasterOfMaster:
+	default:
+		return fmt.Errorf("invalid role %q (must be master, syndic or master_of_master)", master.Role)
+	}
+
+	if master.Role == models.SaltMasterRoleSyndic && master.ParentMasterID == "" {
+		return errors.New("syndic requires parent_master_id")
+	}
+	if master.Role == models.SaltMasterRoleMasterOfMaster && master.ParentMasterID != "" {
+		return errors.New("master_of_master cannot have a parent")
+	}
+	if master.ParentMasterID == "" {
+		return nil
+	}
+
+	// 沿上级链检查，防止出现环
+	seen := map[string]bool{master.MasterID: true}
+	parentID := master.ParentMasterID
+	for parentID != "" {
+		if seen[parentID] {
+			return fmt.Errorf("parent_master_id %q creates a cycle", master.ParentMasterID)
+		}
+		seen[parentID] = true
+		parent, err := lookup(parentID)
+		if err != nil {
+			return fmt.Errorf("parent master %q not found", parentID)
+		}
+		parentID = parent.ParentMasterID
+	}
+	return nil
+}
+
+func applySaltMasterRequest(master *models.SaltMaster, req *models.SaltMasterRequest) {
+	master.MasterID = strings.TrimSpace(req.MasterID)
+	master.Name = req.Name
+	if master.Name == "" {
+		master.Name = master.MasterID
+	}
+	master.Datacenter = req.Datacenter
+	master.APIURL = strings.TrimRight(strings.TrimSpace(req.APIURL), "/")
+	master.Username = req.Username
+	if req.Password != "" {
+		master.Password = req.Password
+	}
+	master.Eauth = req.Eauth
+	if master.Eauth == "" {
+		master.Eauth = "file"
+	}
+	master.Role = req.Role
+	master.ParentMasterID = strings.TrimSpace(req.ParentMasterID)
+	master.Priority = req.Priority
+	if req.Enabled != nil {
+		master.Enabled = *req.Enabled
+	}
+}
+
+// CreateMaster 注册 Master
+func (s *SaltMasterService) CreateMaster(req *models.SaltMasterRequest) (*models.SaltMaster, error) {
+	master := &models.SaltMaster{Enabled: true}
+	applySaltMasterRequest(master, req)
+	if err := s.validate(master); err != nil {
+		return nil, err
+	}
+	if err := s.db.Create(master).Error; err != nil {
+		return nil, err
+	}
+	log.Printf("[SaltMasterService] Master 已注册: id=%s, url=%s, role=%s, parent=%s", master.MasterID, master.APIURL, master.Role, master.ParentMasterID)
+	return master, nil
+}
+
+// UpdateMaster 更新 Master
+func (s *SaltMasterService) UpdateMaster(id uint, req *models.SaltMasterRequest) (*models.SaltMaster, error) {
+	master, err := s.GetMaster(id)
+	if err != nil {
+		return nil, err
+	}
+	oldMasterID := master.MasterID
+	applySaltMasterRequest(master, req)
+	if err := s.validate(master); err != nil {
+		return nil, err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(master).Error; err != nil {
+			return err
+		}
+		if oldMasterID != master.MasterID {
+			// 标识变更时同步更新清单和下级引用
+			if err := tx.Model(&models.SaltMasterMinion{}).Where("master_id = ?", oldMasterID).Update("master_id", master.MasterID).Error; err != nil {
+				return err
+			}
+			return tx.Model(&models.SaltMaster{}).Where("parent_master_id = ?", oldMasterID).Update("parent_master_id", master.MasterID).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetMaster(id)
+}
+
+// DeleteMaster 删除 Master（存在下级 syndic 时拒绝）
+func (s *SaltMasterService) DeleteMaster(id uint) error {
+	master, err := s.GetMaster(id)
+	if err != nil {
+		return err
+	}
+	var children int64
+	s.db.Model(&models.SaltMaster{}).Where("parent_master_id = ?", master.MasterID).Count(&children)
+	if children > 0 {
+		return fmt.Errorf("master %s still has %d child master(s)", master.MasterID, children)
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("master_id = ?", master.MasterID).Delete(&models.SaltMasterMinion{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(master).Error
+	})
+}
+
+// GetMaster 按主键获取 Master
+func (s *SaltMasterService) GetMaster(id uint) (*models.SaltMaster, error) {
+	var master models.SaltMaster
+	if err := s.db.First(&master, id).Error; err != nil {
+		return nil, err
+	}
+	return &master, nil
+}
+
+// GetMasterByMasterID 按 MasterID 获取 Master
+func (s *SaltMasterService) GetMasterByMasterID(masterID string) (*models.SaltMaster, error) {
+	var master models.SaltMaster
+	if err := s.db.Where("master_id = ?", masterID).First(&master).Error; err != nil {
+		return nil, err
+	}
+	return &master, nil
+}
+
+// ListMasters 列出 Master；onlyEnabled 为 true 时仅返回启用的
+func (s *SaltMasterService) ListMasters(onlyEnabled bool) ([]models.SaltMaster, error) {
+	var masters []models.SaltMaster
+	query := s.db.Order("priority ASC, master_id ASC")
+	if onlyEnabled {
+		query = query.Where("enabled = ?", true)
+	}
+	err := query.Find(&masters).Error
+	return masters, err
+}
+
+// Topology 返回按 syndic 层级组织的 Master 树
+func (s *SaltMasterService) Topology() ([]*models.SaltMasterTopologyNode, error) {
+	masters, err := s.ListMasters(false)
+	if err != nil {
+		return nil, err
+	}
+	nodes := make(map[string]*models.SaltMasterTopologyNode, len(masters))
+	for _, m := range masters {
+		nodes[m.MasterID] = &models.SaltMasterTopologyNode{SaltMaster: m}
+	}
+	var roots []*models.SaltMasterTopologyNode
+	for _, m := range masters {
+		node := nodes[m.MasterID]
+		if parent, ok := nodes[m.ParentMasterID]; ok && m.ParentMasterID != "" {
+			parent.Children = append(parent.Children, node)
+		} else {
+			roots = append(roots, node)
+		}
+	}
+	return roots, nil
+}
+
+// ==================== Minion 清单同步 ====================
+
+// SyncMaster 同步单个 Master 上的 minion 密钥清单
+func (s *SaltMasterService) SyncMaster(ctx context.Context, master *models.SaltMaster) error {
+	keys, err := NewSaltStackServiceForMaster(master).ListKeys(ctx)
+	now := time.Now()
+	if err != nil {
+		s.db.Model(master).Updates(map[string]interface{}{"healthy": false, "last_error": err.Error()})
+		return fmt.Errorf("sync master %s: %v", master.MasterID, err)
+	}
+
+	var rows []models.SaltMasterMinion
+	for status, ids := range keys {
+		for _, id := range ids {
+			rows = append(rows, models.SaltMasterMinion{MasterID: master.MasterID, MinionID: id, KeyStatus: status, LastSeenAt: now})
+		}
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if len(rows) > 0 {
+			if err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "master_id"}, {Name: "minion_id"}},
+				DoUpdates: clause.AssignmentColumns([]string{"key_status", "last_seen_at", "updated_at"}),
+			}).CreateInBatches(rows, 500).Error; err != nil {
+				return err
+			}
+		}
+		// 已从该 Master 删除的密钥
+		if err := tx.Where("master_id = ? AND last_seen_at < ?", master.MasterID, now).Delete(&models.SaltMasterMinion{}).Error; err != nil {
+			return err
+		}
+		return tx.Model(master).Updates(map[string]interface{}{
+			"healthy":      true,
+			"last_error":   "",
+			"minion_count": len(keys["accepted"]),
+			"last_sync_at": now,
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("[SaltMasterService] 已同步 Master %s: accepted=%d, pending=%d", master.MasterID, len(keys["accepted"]), len(keys["pending"]))
+	return nil
+}
+
+// SyncAll 同步所有启用的 Master
+func (s *SaltMasterService) SyncAll(ctx context.Context) map[string]error {
+	masters, err := s.ListMasters(true)
+	if err != nil {
+		return map[string]error{"*": err}
+	}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	errs := map[string]error{}
+	for i := range masters {
+		wg.Add(1)
+		go func(m *models.SaltMaster) {
+			defer wg.Done()
+			if err := s.SyncMaster(ctx, m); err != nil {
+				log.Printf("[SaltMasterService] %v", err)
+				mu.Lock()
+				errs[m.MasterID] = err
+				mu.Unlock()
+			}
+		}(&masters[i])
+	}
+	wg.Wait()
+	return errs
+}
+
+// Start 启动周期性清单同步
+func (s *SaltMasterService) Start(interval time.Duration) {
+	s.startOnce.Do(func() {
+		go func() {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			s.SyncAll(context.Background())
+			for {
+				select {
+				case <-ticker.C:
+					s.SyncAll(context.Background())
+				case <-s.stopCh:
+					return
+				}
+			}
+		}()
+	})
+}
+
+// Stop 停止周期性同步
+func (s *SaltMasterService) Stop() {
+	s.stopOnce.Do(func() { close(s.stopCh) })
+}
+
+// ListMinions 获取 Master 上的 minion 清单
+func (s *SaltMasterService) ListMinions(masterID, keyStatus string) ([]models.SaltMasterMinion, error) {
+	var minions []models.SaltMasterMinion
+	query := s.db.Where("master_id = ?", masterID)
+	if keyStatus != "" {
+		query = query.Where("key_status = ?", keyStatus)
+	}
+	err := query.Order("minion_id ASC").Find(&minions).Error
+	return minions, err
+}
+
+// ==================== 路由 ====================
+
+// ResolveMinionMaster 返回持有该 minion 已接受密钥的 Master
+// syndic 拓扑下同一 minion 可能出现在多个层级，优先取层级最低（直接持有密钥）的 Master
+func (s *SaltMasterService) ResolveMinionMaster(minionID string) (*models.SaltMaster, error) {
+	var owners []models.SaltMaster
+	err := s.db.Table("salt_masters").
+		Joins("JOIN salt_master_minions ON salt_master_minions.master_id = salt_masters.master_id").
+		Where("salt_master_minions.minion_id = ? AND salt_master_minions.key_status = ? AND salt_masters.enabled = ? AND salt_masters.deleted_at IS NULL",
+			minionID, "accepted", true).
+		Find(&owners).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(owners) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return pickSaltMinionOwner(owners), nil
+}
+
+// pickSaltMinionOwner 从持有密钥的 Master 中选出路由目标：层级最低优先，其次健康，最后按优先级
+func pickSaltMinionOwner(owners []models.SaltMaster) *models.SaltMaster {
+	sort.SliceStable(owners, func(i, j int) bool {
+		ri, rj := saltMasterRoleRank(owners[i].Role), saltMasterRoleRank(owners[j].Role)
+		if ri != rj {
+			return ri < rj
+		}
+		if owners[i].Healthy != owners[j].Healthy {
+			return owners[i].Healthy
+		}
+		return owners[i].Priority < owners[j].Priority
+	})
+	return &owners[0]
+}
+
+func saltMasterRoleRank(role string) int {
+	switch role {
+	case models.SaltMasterRoleMaster, "":
+		return 0
+	case models.SaltMasterRoleSyndic:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// SaltServiceForMaster 返回指定 Master 的 Salt API 客户端，空或 default 使用默认 Master；
+// 未注册或已停用的 Master 返回错误
+func SaltServiceForMaster(masterID string) (*SaltStackService, error) {
+	if masterID == "" || masterID == "default" {
+		return NewSaltStackService(), nil
+	}
+	masterService := GetSaltMasterService()
+	if masterService == nil {
+		return nil, fmt.Errorf("unknown salt master: %s", masterID)
+	}
+	master, err := masterService.GetMasterByMasterID(masterID)
+	if err != nil {
+		return nil, fmt.Errorf("unknown salt master: %s", masterID)
+	}
+	if !master.Enabled {
+		return nil, fmt.Errorf("salt master %s is disabled", masterID)
+	}
+	return NewSaltStackServiceForMaster(master), nil
+}
+
+// SaltServiceForTarget 返回目标所属 Master 的 Salt API 客户端，选择规则见 ResolveTargetMaster
+func SaltServiceForTarget(masterID, target, tgtType string) (*SaltStackService, error) {
+	owner, err := ResolveTargetMaster(masterID, target, tgtType)
+	if err != nil {
+		return nil, err
+	}
+	return SaltServiceForMaster(owner)
+}
+
+// ResolveTargetMaster 确定目标应下发到的 Master
+// 指定 masterID 时直接使用；否则目标为单个 minion 或 list 且全部归属同一 Master 时返回该 Master，
+// 跨多个 Master 时返回错误；未注册 Master、目标含通配符或为 grain 等无法确定具体 minion 的类型、
+// 以及 minion 尚未登记归属时返回空，由默认 Master 下发；tgt_type=group 按分组当前成员路由
+func ResolveTargetMaster(masterID, target, tgtType string) (string, error) {
+	if masterID != "" && masterID != "default" {
+		return masterID, nil
+	}
+	masterService := GetSaltMasterService()
+	if masterService == nil {
+		return "", nil
+	}
+	target, tgtType, err := ExpandMinionGroupTarget(masterService.db, target, tgtType)
+	if err != nil {
+		return "", err
+	}
+	return resolveTargetMaster(SaltTargetMinions(target, tgtType), masterService.GroupMinionsByMaster)
+}
+
+func resolveTargetMaster(minions []string, group func([]string) (map[string][]string, []string)) (string, error) {
+	if len(minions) == 0 {
+		return "", nil
+	}
+	groups, unresolved := group(minions)
+	if len(groups) > 1 || (len(groups) == 1 && len(unresolved) > 0) {
+		return "", errors.New("target spans multiple salt masters; specify master_id or split the target")
+	}
+	for owner := range groups {
+		return owner, nil
+	}
+	return "", nil
+}
+
+// SaltTargetMinions 提取目标中可以确定的具体 minion：list 目标逐项拆分，不含通配符的 glob 视为单个 minion，
+// 其余情况返回 nil
+func SaltTargetMinions(target, tgtType string) []string {
+	var minions []string
+	switch tgtType {
+	case "list":
+		for _, m := range strings.Split(target, ",") {
+			if m = strings.TrimSpace(m); m != "" {
+				minions = append(minions, m)
+			}
+		}
+	case "", "glob":
+		if target = strings.TrimSpace(target); target != "" && !strings.ContainsAny(target, "*?[,") {
+			minions = []string{target}
+		}
+	}
+	return minions
+}
+
+// GroupMinionsByMaster 按归属 Master 对 minion 分组；无法解析的 minion 归入 unresolved
+func (s *SaltMasterService) GroupMinionsByMaster(minionIDs []string) (map[string][]string, []string) {
+	return groupMinionsByMaster(minionIDs, func(id string) (string, error) {
+		master, err := s.ResolveMinionMaster(id)
+		if err != nil {
+			return "", err
+		}
+		return master.MasterID, nil
+	})
+}
+
+func groupMinionsByMaster(minionIDs []string, resolve func(minionID string) (string, error)) (map[string][]string, []string) {
+	groups := map[string][]string{}
+	var unresolved []string
+	for _, id := range minionIDs {
+		owner, err := resolve(id)
+		if err != nil {
+			unresolved = append(unresolved, id)
+			continue
+		}
+		groups[owner] = append(groups[owner], id)
+	}
+	return groups, unresolved
+}
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+func TestValidateSaltMaster(t *testing.T) {
+	registered := map[string]*models.SaltMaster{
+		"mom":   {MasterID: "mom", Role: models.SaltMasterRoleMasterOfMaster},
+		"dc1":   {MasterID: "dc1", Role: models.SaltMasterRoleSyndic, ParentMasterID: "mom"},
+		"loopa": {MasterID: "loopa", Role: models.SaltMasterRoleSyndic, ParentMasterID: "loopb"},
+		"loopb": {MasterID: "loopb", Role: models.SaltMasterRoleSyndic, ParentMasterID: "loopa"},
+	}
+	lookup := func(id string) (*models.SaltMaster, error) {
+		if m, ok := registered[id]; ok {
+			return m, nil
+		}
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	cases := []struct {
+		name    string
+		master  models.SaltMaster
+		wantErr bool
+	}{
+		{"普通 Master", models.SaltMaster{MasterID: "m1", APIURL: "https://salt-1:8000"}, false},
+		{"syndic 挂在 syndic 下", models.SaltMaster{MasterID: "dc1-rack", APIURL: "http://salt:8000", Role: models.SaltMasterRoleSyndic, ParentMasterID: "dc1"}, false},
+		{"缺少 master_id", models.SaltMaster{APIURL: "http://salt:8000"}, true},
+		{"api_url 无协议", models.SaltMaster{MasterID: "m1", APIURL: "salt:8000"}, true},
+		{"未知角色", models.SaltMaster{MasterID: "m1", APIURL: "http://salt:8000", Role: "minion"}, true},
+		{"syndic 缺少上级", models.SaltMaster{MasterID: "s1", APIURL: "http://salt:8000", Role: models.SaltMasterRoleSyndic}, true},
+		{"顶层 Master 不能有上级", models.SaltMaster{MasterID: "top", APIURL: "http://salt:8000", Role: models.SaltMasterRoleMasterOfMaster, ParentMasterID: "mom"}, true},
+		{"上级不存在", models.SaltMaster{MasterID: "s1", APIURL: "http://salt:8000", Role: models.SaltMasterRoleSyndic, ParentMasterID: "missing"}, true},
+		{"以自身为上级", models.SaltMaster{MasterID: "mom", APIURL: "http://salt:8000", ParentMasterID: "dc1"}, true},
+		{"上级链存在环", models.SaltMaster{MasterID: "s1", APIURL: "http://salt:8000", Role: models.SaltMasterRoleSyndic, ParentMasterID: "loopa"}, true},
+	}
+	for _, tc := range cases {
+		master := tc.master
+		err := validateSaltMaster(&master, lookup)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%s: err = %v, wantErr = %v", tc.name, err, tc.wantErr)
+		}
+	}
+
+	master := models.SaltMaster{MasterID: "m1", APIURL: "http://salt:8000"}
+	if err := validateSaltMaster(&master, lookup); err != nil || master.Role != models.SaltMasterRoleMaster {
+		t.Fatalf("未指定角色时应默认为 master: role=%q, err=%v", master.Role, err)
+	}
+}
+
+func TestPickSaltMinionOwner(t *testing.T) {
+	cases := []struct {
+		name   string
+		owners []models.SaltMaster
+		want   string
+	}{
+		{"直接持有密钥的 Master 优先于上级", []models.SaltMaster{
+			{MasterID: "mom", Role: models.SaltMasterRoleMasterOfMaster, Healthy: true},
+			{MasterID: "dc1", Role: models.SaltMasterRoleSyndic, Healthy: true},
+			{MasterID: "rack", Role: models.SaltMasterRoleMaster},
+		}, "rack"},
+		{"同层级时健康优先", []models.SaltMaster{
+			{MasterID: "a", Role: models.SaltMasterRoleMaster, Priority: 0},
+			{MasterID: "b", Role: models.SaltMasterRoleMaster, Healthy: true, Priority: 5},
+		}, "b"},
+		{"同层级同健康时按优先级", []models.SaltMaster{
+			{MasterID: "a", Healthy: true, Priority: 10},
+			{MasterID: "b", Healthy: true, Priority: 1},
+		}, "b"},
+	}
+	for _, tc := range cases {
+		if got := pickSaltMinionOwner(tc.owners); got.MasterID != tc.want {
+			t.Errorf("%s: 得到 %s, 期望 %s", tc.name, got.MasterID, tc.want)
+		}
+	}
+}
+
+func TestGroupMinionsByMaster(t *testing.T) {
+	owners := map[string]string{"web-1": "dc1", "web-2": "dc1", "db-1": "dc2"}
+	resolve := func(id string) (string, error) {
+		if owner, ok := owners[id]; ok {
+			return owner, nil
+		}
+		return "", gorm.ErrRecordNotFound
+	}
+
+	groups, unresolved := groupMinionsByMaster([]string{"web-1", "db-1", "new-1", "web-2"}, resolve)
+	want := map[string][]string{"dc1": {"web-1", "web-2"}, "dc2": {"db-1"}}
+	if !reflect.DeepEqual(groups, want) {
+		t.Errorf("分组 = %v, 期望 %v", groups, want)
+	}
+	if !reflect.DeepEqual(unresolved, []string{"new-1"}) {
+		t.Errorf("未解析 = %v", unresolved)
+	}
+
+	group := func(ids []string) (map[string][]string, []string) { return groupMinionsByMaster(ids, resolve) }
+	routeCases := []struct {
+		name    string
+		minions []string
+		want    string
+		wantErr bool
+	}{
+		{"无具体 minion 使用默认 Master", nil, "", false},
+		{"全部归属同一 Master", []string{"web-1", "web-2"}, "dc1", false},
+		{"均未登记使用默认 Master", []string{"new-1"}, "", false},
+		{"跨多个 Master", []string{"web-1", "db-1"}, "", true},
+		{"部分未登记", []string{"web-1", "new-1"}, "", true},
+	}
+	for _, tc := range routeCases {
+		got, err := resolveTargetMaster(tc.minions, group)
+		if (err != nil) != tc.wantErr || got != tc.want {
+			t.Errorf("%s: 得到 %q, err=%v; 期望 %q, wantErr=%v", tc.name, got, err, tc.want, tc.wantErr)
+		}
+	}
+}
+
+func TestSaltTargetMinions(t *testing.T) {
+	cases := []struct {
+		target, tgtType string
+		want            []string
+	}{
+		{"web-1", "", []string{"web-1"}},
+		{"web-1", "glob", []string{"web-1"}},
+		{"web-*", "glob", nil},
+		{"web-[12]", "glob", nil},
+		{"*", "", nil},
+		{"web-1, web-2,,", "list", []string{"web-1", "web-2"}},
+		{"os:Ubuntu", "grain", nil},
+		{"", "glob", nil},
+	}
+	for _, tc := range cases {
+		got := SaltTargetMinions(tc.target, tc.tgtType)
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("SaltTargetMinions(%q, %q) = %v, 期望 %v", tc.target, tc.tgtType, got, tc.want)
+		}
+	}
+}
+
+func TestResolveSaltPermissionMaster(t *testing.T) {
+	owner := func(id string) (string, error) {
+		if id == "web-1" {
+			return "dc1", nil
+		}
+		return "", gorm.ErrRecordNotFound
+	}
+	lookup := func(id string) error {
+		switch id {
+		case "dc1", "dc2":
+			return nil
+		case "flaky":
+			return errors.New("connection reset")
+		}
+		return gorm.ErrRecordNotFound
+	}
+
+	cases := []struct {
+		name       string
+		masterID   string
+		minionID   string
+		want       string
+		wantDenied bool
+	}{
+		{"空 masterID 映射到归属 Master", "", "web-1", "dc1", false},
+		{"default 映射到归属 Master", "default", "web-1", "dc1", false},
+		{"未归属的 minion 保持 default", "default", "new-1", "default", false},
+		{"未指定 minion 时保持原值", "", "", "", false},
+		{"显式指定的 Master 不按归属改写", "dc2", "web-1", "dc2", false},
+		{"未知 Master 拒绝", "dc9", "web-1", "dc9", true},
+		{"查询出错时不按未知处理", "flaky", "", "flaky", false},
+	}
+	for _, tc := range cases {
+		got, denied := resolveSaltPermissionMaster(tc.masterID, tc.minionID, owner, lookup)
+		if got != tc.want || (denied != "") != tc.wantDenied {
+			t.Errorf("%s: 得到 %q, denied=%q; 期望 %q, denied=%v", tc.name, got, denied, tc.want, tc.wantDenied)
+		}
+	}
+}
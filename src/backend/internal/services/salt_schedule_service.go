@@ -0,0 +1,550 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// DangerousCommandError 命令命中危险命令黑名单
+type DangerousCommandError struct {
+	Rule *models.DangerousCommand
+}
+
+func (e *DangerousCommandError) Error() string {
+	return fmt.Sprintf("命令被安全策略拦截: %s", e.Rule.Description)
+}
+
+// SaltScheduleService Salt 定时/周期作业服务
+type SaltScheduleService struct {
+	db         *gorm.DB
+	httpClient *http.Client
+	mu         sync.Mutex
+	active     map[uint]int // schedule ID -> 正在运行的次数
+	runner     *cronRunner
+}
+
+var (
+	saltScheduleServiceInstance *SaltScheduleService
+	saltScheduleServiceOnce     sync.Once
+)
+
+// NewSaltScheduleService 创建 Salt 定时作业服务（单例）
+func NewSaltScheduleService(db *gorm.DB) *SaltScheduleService {
+	saltScheduleServiceOnce.Do(func() {
+		saltScheduleServiceInstance = &SaltScheduleService{
+			db:         db,
+			httpClient: &http.Client{Timeout: 10 * time.Second},
+			active:     make(map[uint]int),
+		}
+		saltScheduleServiceInstance.runner = newCronRunner("SaltScheduleService", db, &models.SaltJobSchedule{}, saltScheduleServiceInstance.fireDue)
+		if err := db.AutoMigrate(&models.SaltJobSchedule{}, &models.SaltJobScheduleRun{}); err != nil {
+			log.Printf("[SaltScheduleService] 自动迁移失败: %v", err)
+		}
+	})
+	return saltScheduleServiceInstance
+}
+
+// GetSaltScheduleService 获取 Salt 定时作业服务实例
+func GetSaltScheduleService() *SaltScheduleService {
+	return saltScheduleServiceInstance
+}
+
+// ==================== 调度器 ====================
+
+// Start 启动后台调度循环
+func (s *SaltScheduleService) Start() {
+	s.runner.Start(func() {
+		// 服务重启前遗留的运行记录无法再追踪，标记为失败
+		s.db.Model(&models.SaltJobScheduleRun{}).
+			Where("status IN ?", []string{models.SaltScheduleRunRunning, models.SaltScheduleRunQueued}).
+			Updates(map[string]interface{}{"status": models.SaltScheduleRunFailed, "error_message": "backend restarted before the run finished"})
+	})
+}
+
+// Stop 停止后台调度循环
+func (s *SaltScheduleService) Stop() {
+	s.runner.Stop()
+}
+
+// fireDue 触发调度器抢占到的一次定时运行
+func (s *SaltScheduleService) fireDue(id uint, scheduledAt time.Time) {
+	schedule, err := s.GetSchedule(id)
+	if err != nil {
+		log.Printf("[SaltScheduleService] 加载到期作业失败: id=%d, err=%v", id, err)
+		return
+	}
+	s.fire(schedule, scheduledAt, "cron", schedule.CreatedBy)
+}
+
+// fire 按重叠策略触发一次运行
+func (s *SaltScheduleService) fire(schedule *models.SaltJobSchedule, scheduledAt time.Time, trigger, username string) *models.SaltJobScheduleRun {
+	run := &models.SaltJobScheduleRun{
+		ScheduleID:  schedule.ID,
+		TaskID:      fmt.Sprintf("SCHED-%d-%s", schedule.ID, time.Now().Format("20060102-150405.000")),
+		Trigger:     trigger,
+		ScheduledAt: scheduledAt,
+	}
+
+	s.mu.Lock()
+	busy := s.active[schedule.ID] > 0
+	switch {
+	case busy && schedule.OverlapPolicy == models.SaltScheduleOverlapSkip:
+		s.mu.Unlock()
+		run.Status = models.SaltScheduleRunSkipped
+		run.ErrorMessage = "previous run still in progress"
+		s.db.Create(run)
+		s.updateLastStatus(schedule.ID, run.Status)
+		log.Printf("[SaltScheduleService] 上次运行未结束，跳过: schedule=%s", schedule.Name)
+		return run
+	case busy && schedule.OverlapPolicy == models.SaltScheduleOverlapQueue:
+		s.mu.Unlock()
+		run.Status = models.SaltScheduleRunQueued
+		s.db.Create(run)
+		log.Printf("[SaltScheduleService] 上次运行未结束，已排队: schedule=%s", schedule.Name)
+		return run
+	}
+	s.active[schedule.ID]++
+	s.mu.Unlock()
+
+	run.Status = models.SaltScheduleRunRunning
+	s.db.Create(run)
+
+	go s.execute(*schedule, run, username)
+	return run
+}
+
+// execute 执行一次运行并在结束后处理排队的触发
+func (s *SaltScheduleService) execute(schedule models.SaltJobSchedule, run *models.SaltJobScheduleRun, username string) {
+	defer func() {
+		s.mu.Lock()
+		s.active[schedule.ID]--
+		if s.active[schedule.ID] <= 0 {
+			delete(s.active, schedule.ID)
+		}
+		s.mu.Unlock()
+		s.dispatchQueued(schedule.ID)
+	}()
+
+	start := time.Now()
+	run.StartedAt = &start
+	s.db.Model(run).Update("started_at", start)
+
+	err := s.runSaltJob(&schedule, run, username)
+
+	end := time.Now()
+	updates := map[string]interface{}{
+		"finished_at":   end,
+		"duration":      end.Sub(start).Milliseconds(),
+		"jid":           run.JID,
+		"success_count": run.SuccessCount,
+		"failed_count":  run.FailedCount,
+	}
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		run.Status = models.SaltScheduleRunTimeout
+		run.ErrorMessage = fmt.Sprintf("no result from all minions within %ds", schedule.Timeout)
+	case err != nil:
+		run.Status = models.SaltScheduleRunFailed
+		run.ErrorMessage = err.Error()
+	case run.FailedCount > 0:
+		run.Status = models.SaltScheduleRunFailed
+		run.ErrorMessage = fmt.Sprintf("%d minion(s) returned non-zero retcode", run.FailedCount)
+	default:
+		run.Status = models.SaltScheduleRunCompleted
+	}
+	updates["status"] = run.Status
+	updates["error_message"] = run.ErrorMessage
+	s.db.Model(run).Updates(updates)
+	s.updateLastStatus(schedule.ID, run.Status)
+
+	log.Printf("[SaltScheduleService] 运行结束: schedule=%s, run=%d, jid=%s, status=%s", schedule.Name, run.ID, run.JID, run.Status)
+
+	if run.Status != models.SaltScheduleRunCompleted && schedule.NotifyOnFail {
+		s.notifyFailure(&schedule, run)
+	}
+}
+
+// runSaltJob 下发 Salt 作业、写入作业历史并等待结果
+func (s *SaltScheduleService) runSaltJob(schedule *models.SaltJobSchedule, run *models.SaltJobScheduleRun, username string) error {
+	args := schedule.GetArgs()
+	kwarg := schedule.GetKwarg()
+
+	// 触发时再次检查，黑名单可能在创建后被更新
+	if err := checkScheduleDangerous(schedule.Function, args); err != nil {
+		return err
+	}
+	if schedule.Function == "cmd.run" {
+		if kwarg == nil {
+			kwarg = map[string]interface{}{}
+		}
+		if _, ok := kwarg["python_shell"]; !ok {
+			kwarg["python_shell"] = true
+		}
+	}
+
+	timeout := time.Duration(schedule.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = 300 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout+30*time.Second)
+	defer cancel()
+
+	// 分组目标在触发时展开为当前成员，路由与下发使用同一组 minion
+	target, tgtType, err := ExpandMinionGroupTarget(s.db, schedule.Target, schedule.TgtType)
+	if err != nil {
+		return err
+	}
+	salt, err := SaltServiceForTarget(schedule.MasterID, target, tgtType)
+	if err != nil {
+		return err
+	}
+	jid, minions, err := salt.SubmitAsync(ctx, target, tgtType, schedule.Function, args, kwarg)
+	if err != nil {
+		return fmt.Errorf("下发 Salt 作业失败: %v", err)
+	}
+	run.JID = jid
+	s.db.Model(run).Update("jid", jid)
+
+	saltJobService := GetSaltJobService()
+	if saltJobService != nil {
+		argsJSON, _ := json.Marshal(args)
+		if err := saltJobService.CreateJob(ctx, &models.SaltJobHistory{
+			JID:       jid,
+			TaskID:    run.TaskID,
+			Function:  schedule.Function,
+			Arguments: string(argsJSON),
+			Target:    target,
+			TgtType:   tgtType,
+			User:      username,
+			Status:    "running",
+			StartTime: time.Now(),
+		}); err != nil {
+			log.Printf("[SaltScheduleService] 保存作业历史失败: %v", err)
+		}
+	}
+
+	results, waitErr := salt.WaitForJob(ctx, jid, minions, timeout)
+	successCount, failedCount, _ := CountJobResults(results)
+	// 超时未返回的 minion 计为失败
+	if missing := len(minions) - len(results); missing > 0 {
+		failedCount += missing
+	}
+	run.SuccessCount = successCount
+	run.FailedCount = failedCount
+
+	if saltJobService != nil {
+		if waitErr != nil {
+			saltJobService.TimeoutJob(context.Background(), jid)
+		} else if err := saltJobService.CompleteJob(context.Background(), jid, results, successCount, failedCount); err != nil {
+			log.Printf("[SaltScheduleService] 更新作业历史失败: %v", err)
+		}
+	}
+	return waitErr
+}
+
+// dispatchQueued 当前运行结束后启动最早排队的一次运行
+func (s *SaltScheduleService) dispatchQueued(scheduleID uint) {
+	var run models.SaltJobScheduleRun
+	if err := s.db.Where("schedule_id = ? AND status = ?", scheduleID, models.SaltScheduleRunQueued).
+		Order("scheduled_at ASC").First(&run).Error; err != nil {
+		return
+	}
+
+	var schedule models.SaltJobSchedule
+	if err := s.db.First(&schedule, scheduleID).Error; err != nil {
+		s.db.Model(&run).Updates(map[string]interface{}{"status": models.SaltScheduleRunSkipped, "error_message": "schedule deleted"})
+		return
+	}
+
+	s.mu.Lock()
+	if s.active[scheduleID] > 0 {
+		s.mu.Unlock()
+		return
+	}
+	s.active[scheduleID]++
+	s.mu.Unlock()
+
+	run.Status = models.SaltScheduleRunRunning
+	s.db.Model(&run).Update("status", run.Status)
+	go s.execute(schedule, &run, schedule.CreatedBy)
+}
+
+func (s *SaltScheduleService) updateLastStatus(scheduleID uint, status string) {
+	s.db.Model(&models.SaltJobSchedule{}).Where("id = ?", scheduleID).Update("last_status", status)
+}
+
+// notifyFailure 失败通知：写入审计日志并回调 Webhook
+func (s *SaltScheduleService) notifyFailure(schedule *models.SaltJobSchedule, run *models.SaltJobScheduleRun) {
+	payload := map[string]interface{}{
+		"event":         "salt_schedule_failed",
+		"schedule_id":   schedule.ID,
+		"schedule_name": schedule.Name,
+		"target":        schedule.Target,
+		"function":      schedule.Function,
+		"run_id":        run.ID,
+		"jid":           run.JID,
+		"status":        run.Status,
+		"success_count": run.SuccessCount,
+		"failed_count":  run.FailedCount,
+		"error":         run.ErrorMessage,
+		"scheduled_at":  run.ScheduledAt,
+	}
+
+	GetAuditService().NewAuditEntry(models.AuditCategorySaltstack, models.AuditActionSaltExecute).
+		WithUser(0, schedule.CreatedBy, "").
+		WithResource("salt_schedule", fmt.Sprintf("%d", schedule.ID), schedule.Name).
+		WithStatus(models.AuditStatusFailed).
+		WithSeverity(models.AuditSeverityWarning).
+		WithErrorMessage(run.ErrorMessage).
+		WithMetadata(payload).
+		WithTags("salt_schedule", "notify").
+		SaveAsync()
+
+	if schedule.NotifyWebhook == "" {
+		return
+	}
+	if err := postWebhook(s.httpClient, schedule.NotifyWebhook, payload); err != nil {
+		log.Printf("[SaltScheduleService] 失败通知发送失败: schedule=%s, err=%v", schedule.Name, err)
+	}
+}
+
+// ==================== 管理接口 ====================
+
+// checkScheduleDangerous 使用 SaltJobService 的黑名单检查命令
+func checkScheduleDangerous(function string, args []interface{}) error {
+	saltJobService := GetSaltJobService()
+	if saltJobService == nil {
+		return nil
+	}
+	isDangerous, rule, err := saltJobService.CheckDangerousInvocation(function, args)
+	if err != nil {
+		return err
+	}
+	if isDangerous && rule != nil {
+		return &DangerousCommandError{Rule: rule}
+	}
+	return nil
+}
+
+// buildSchedule 校验请求并填充作业字段
+func (s *SaltScheduleService) buildSchedule(schedule *models.SaltJobSchedule, req *models.SaltJobScheduleRequest) error {
+	if _, err := ParseCronExpression(req.CronExpr); err != nil {
+		return fmt.Errorf("invalid cron expression: %v", err)
+	}
+	if _, err := loadScheduleLocation(req.Timezone); err != nil {
+		return err
+	}
+
+	overlap := req.OverlapPolicy
+	if overlap == "" {
+		overlap = models.SaltScheduleOverlapSkip
+	}
+	if overlap != models.SaltScheduleOverlapSkip && overlap != models.SaltScheduleOverlapQueue && overlap != models.SaltScheduleOverlapParallel {
+		return fmt.Errorf("invalid overlap_policy %q (must be skip, queue or parallel)", overlap)
+	}
+
+	if err := checkScheduleDangerous(req.Function, req.Args); err != nil {
+		return err
+	}
+
+	argsJSON, err := json.Marshal(req.Args)
+	if err != nil {
+		return fmt.Errorf("invalid args: %v", err)
+	}
+	kwargJSON := ""
+	if len(req.Kwarg) > 0 {
+		b, err := json.Marshal(req.Kwarg)
+		if err != nil {
+			return fmt.Errorf("invalid kwarg: %v", err)
+		}
+		kwargJSON = string(b)
+	}
+
+	schedule.Name = strings.TrimSpace(req.Name)
+	schedule.Description = req.Description
+	schedule.CronExpr = strings.TrimSpace(req.CronExpr)
+	schedule.Timezone = req.Timezone
+	if schedule.Timezone == "" {
+		schedule.Timezone = "UTC"
+	}
+	schedule.Target = req.Target
+	schedule.TgtType = req.TgtType
+	if schedule.TgtType == "" {
+		schedule.TgtType = "glob"
+	}
+	schedule.MasterID = strings.TrimSpace(req.MasterID)
+	if _, err := SaltServiceForTarget(schedule.MasterID, schedule.Target, schedule.TgtType); err != nil {
+		return err
+	}
+	schedule.Function = req.Function
+	schedule.Arguments = string(argsJSON)
+	schedule.Kwarg = kwargJSON
+	schedule.OverlapPolicy = overlap
+	schedule.Timeout = req.Timeout
+	if schedule.Timeout <= 0 {
+		schedule.Timeout = 300
+	}
+	if req.Enabled != nil {
+		schedule.Enabled = *req.Enabled
+	}
+	schedule.NotifyOnFail = req.NotifyOnFail
+	schedule.NotifyWebhook = strings.TrimSpace(req.NotifyWebhook)
+	if schedule.NotifyWebhook != "" {
+		if err := checkWebhookURL(schedule.NotifyWebhook); err != nil {
+			return fmt.Errorf("invalid notify_webhook: %v", err)
+		}
+	}
+
+	schedule.NextRunAt = nil
+	if schedule.Enabled {
+		next, err := nextCronRun(schedule.CronExpr, schedule.Timezone, time.Now())
+		if err != nil {
+			return err
+		}
+		schedule.NextRunAt = next
+	}
+	schedule.Args = req.Args
+	schedule.Kwargs = req.Kwarg
+	return nil
+}
+
+// CreateSchedule 创建定时作业（创建时即执行危险命令检查）
+func (s *SaltScheduleService) CreateSchedule(req *models.SaltJobScheduleRequest, username string) (*models.SaltJobSchedule, error) {
+	schedule := &models.SaltJobSchedule{Enabled: true, CreatedBy: username}
+	if err := s.buildSchedule(schedule, req); err != nil {
+		return nil, err
+	}
+	if err := s.db.Create(schedule).Error; err != nil {
+		return nil, err
+	}
+	log.Printf("[SaltScheduleService] 定时作业已创建: name=%s, cron=%s, tz=%s, user=%s", schedule.Name, schedule.CronExpr, schedule.Timezone, username)
+	return schedule, nil
+}
+
+// UpdateSchedule 更新定时作业
+func (s *SaltScheduleService) UpdateSchedule(id uint, req *models.SaltJobScheduleRequest, username string) (*models.SaltJobSchedule, error) {
+	schedule, err := s.GetSchedule(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.buildSchedule(schedule, req); err != nil {
+		return nil, err
+	}
+	schedule.UpdatedBy = username
+	if err := s.db.Save(schedule).Error; err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+// SetEnabled 启用/停用定时作业
+func (s *SaltScheduleService) SetEnabled(id uint, enabled bool, username string) (*models.SaltJobSchedule, error) {
+	schedule, err := s.GetSchedule(id)
+	if err != nil {
+		return nil, err
+	}
+	updates := map[string]interface{}{"enabled": enabled, "updated_by": username, "next_run_at": nil}
+	if enabled {
+		// 重新启用前再次检查黑名单
+		if err := checkScheduleDangerous(schedule.Function, schedule.GetArgs()); err != nil {
+			return nil, err
+		}
+		next, err := nextCronRun(schedule.CronExpr, schedule.Timezone, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		updates["next_run_at"] = next
+	}
+	if err := s.db.Model(schedule).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+	return s.GetSchedule(id)
+}
+
+// DeleteSchedule 删除定时作业（运行记录保留）
+func (s *SaltScheduleService) DeleteSchedule(id uint) error {
+	return s.db.Delete(&models.SaltJobSchedule{}, id).Error
+}
+
+// GetSchedule 获取定时作业
+func (s *SaltScheduleService) GetSchedule(id uint) (*models.SaltJobSchedule, error) {
+	var schedule models.SaltJobSchedule
+	if err := s.db.First(&schedule, id).Error; err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+// ListSchedules 获取所有定时作业
+func (s *SaltScheduleService) ListSchedules() ([]models.SaltJobSchedule, error) {
+	var schedules []models.SaltJobSchedule
+	err := s.db.Order("name ASC").Find(&schedules).Error
+	return schedules, err
+}
+
+// TriggerNow 手动立即触发一次（同样遵循重叠策略）
+func (s *SaltScheduleService) TriggerNow(id uint, username string) (*models.SaltJobScheduleRun, error) {
+	schedule, err := s.GetSchedule(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkScheduleDangerous(schedule.Function, schedule.GetArgs()); err != nil {
+		return nil, err
+	}
+	return s.fire(schedule, time.Now(), "manual", username), nil
+}
+
+// ListRuns 分页查询运行记录
+func (s *SaltScheduleService) ListRuns(scheduleID uint, page, pageSize int) (*models.SaltJobScheduleRunListResponse, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	query := s.db.Model(&models.SaltJobScheduleRun{}).Where("schedule_id = ?", scheduleID)
+	var total int64
+	query.Count(&total)
+
+	var runs []models.SaltJobScheduleRun
+	if err := query.Order("scheduled_at DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&runs).Error; err != nil {
+		return nil, err
+	}
+	return &models.SaltJobScheduleRunListResponse{Total: total, Page: page, Size: pageSize, Data: runs}, nil
+}
+
+// PreviewNextRuns 预览 cron 表达式在指定时区的后续 n 次触发时间
+func (s *SaltScheduleService) PreviewNextRuns(cronExpr, timezone string, n int) ([]time.Time, error) {
+	expr, err := ParseCronExpression(cronExpr)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := loadScheduleLocation(timezone)
+	if err != nil {
+		return nil, err
+	}
+	if n <= 0 || n > 50 {
+		n = 5
+	}
+	runs := make([]time.Time, 0, n)
+	t := time.Now().In(loc)
+	for i := 0; i < n; i++ {
+		t = expr.Next(t)
+		if t.IsZero() {
+			break
+		}
+		runs = append(runs, t)
+	}
+	return runs, nil
+}
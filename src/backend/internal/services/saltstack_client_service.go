@@ -155,11 +155,9 @@ func (s *SaltStackClientService) runInstallTask(ctx context.Context, task *Insta
 	}
 
 	sshConfig := &ssh.ClientConfig{
-		User: task.Config.Username,
-		Auth: []ssh.AuthMethod{
-			ssh.Password(task.Config.Password),
-		},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		User:            task.Config.Username,
+		Auth:            withSSHCAAuth([]ssh.AuthMethod{ssh.Password(task.Config.Password)}),
+		HostKeyCallback: KnownHostsCallback(),
 		Timeout:         30 * time.Second,
 	}
 
@@ -168,8 +166,8 @@ func (s *SaltStackClientService) runInstallTask(ctx context.Context, task *Insta
 		logrus.WithField("key_path", task.Config.KeyPath).Debug("SSH key authentication not implemented yet")
 	}
 
-	addr := fmt.Sprintf("%s:%d", task.Config.Host, task.Config.Port)
-	client, err := ssh.Dial("tcp", addr, sshConfig)
+	key := NewSSHPoolKey(task.Config.Host, task.Config.Port, task.Config.Username, task.Config.Password)
+	client, err := GetSSHClientPool().Dial(key, sshConfig)
 	if err != nil {
 		s.failStep(task, "connect", fmt.Sprintf("SSH connection failed: %v", err))
 		s.failTask(task, "SSH connection failed")
@@ -186,12 +184,17 @@ func (s *SaltStackClientService) runInstallTask(ctx context.Context, task *Insta
 		return
 	}
 
-	osInfo, err := s.detectOSInfo(client)
+	osInfo, err := s.detectOSInfo(client.Client)
 	if err != nil {
 		s.failStep(task, "detect", fmt.Sprintf("OS detection failed: %v", err))
 		s.failTask(task, "System detection failed")
 		return
 	}
+	if _, err := GetScriptLoader().ResolveOS(*osInfo); err != nil {
+		s.failStep(task, "detect", err.Error())
+		s.failTask(task, "Unsupported operating system")
+		return
+	}
 
 	s.completeStep(task, "detect", fmt.Sprintf("Detected OS: %s %s %s", osInfo.OS, osInfo.Version, osInfo.Arch))
 	task.Progress = 30
@@ -218,7 +221,7 @@ func (s *SaltStackClientService) runInstallTask(ctx context.Context, task *Insta
 		return
 	}
 
-	if err := s.installSaltStackMinion(client, binary, task.Config); err != nil {
+	if err := s.installSaltStackMinion(client.Client, binary, task.Config); err != nil {
 		s.failStep(task, "install", fmt.Sprintf("Installation failed: %v", err))
 		s.failTask(task, "SaltStack installation failed")
 		return
@@ -233,7 +236,7 @@ func (s *SaltStackClientService) runInstallTask(ctx context.Context, task *Insta
 		return
 	}
 
-	if err := s.configureSaltMinion(client, task.Config); err != nil {
+	if err := s.configureSaltMinion(client.Client, task.Config); err != nil {
 		s.failStep(task, "configure", fmt.Sprintf("Configuration failed: %v", err))
 		s.failTask(task, "Service configuration failed")
 		return
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
@@ -12,6 +13,9 @@ import (
 	"os/exec"
 	"strings"
 	"time"
+
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/database"
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
 )
 
 // SaltStackService SaltStack服务
@@ -93,6 +97,23 @@ func NewSaltStackService() *SaltStackService {
 	}
 }
 
+// NewSaltStackServiceForMaster 为指定的已注册 Master 创建服务（使用该 Master 自己的 API 凭证）
+func NewSaltStackServiceForMaster(master *models.SaltMaster) *SaltStackService {
+	s := NewSaltStackService()
+	s.masterURL = strings.TrimRight(master.APIURL, "/")
+	s.apiToken = ""
+	if master.Username != "" {
+		s.username = master.Username
+	}
+	if master.Password != "" {
+		s.password = master.Password
+	}
+	if master.Eauth != "" {
+		s.eauth = master.Eauth
+	}
+	return s
+}
+
 // GetStatus 获取SaltStack状态
 func (s *SaltStackService) GetStatus(ctx context.Context) (*SaltStackStatus, error) {
 	// 首先尝试获取真实的SaltStack状态
@@ -980,3 +1001,247 @@ func (s *SaltStackService) StartSlurmService(ctx context.Context, minionID strin
 	log.Printf("[DEBUG] StartSlurmService: slurmd 状态检查结果: %+v", result)
 	return nil
 }
+
+// SubmitAsync 以 local_async 方式下发作业，返回 JID 和匹配到的 minion 列表
+func (s *SaltStackService) SubmitAsync(ctx context.Context, target, tgtType, function string, args []interface{}, kwarg map[string]interface{}) (string, []string, error) {
+	// tgt_type=group 展开为分组成员列表
+	target, tgtType, err := ExpandMinionGroupTarget(database.DB, target, tgtType)
+	if err != nil {
+		return "", nil, err
+	}
+	payload := map[string]interface{}{
+		"client": "local_async",
+		"tgt":    target,
+		"fun":    function,
+	}
+	if tgtType != "" && tgtType != "glob" {
+		payload["tgt_type"] = tgtType
+	}
+	if len(args) > 0 {
+		payload["arg"] = args
+	}
+	if len(kwarg) > 0 {
+		payload["kwarg"] = kwarg
+	}
+
+	result, err := s.executeSaltCommand(ctx, payload)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// local_async 返回格式: {"return": [{"jid": "...", "minions": ["m1", ...]}]}
+	var jid string
+	var minions []string
+	if ret, ok := result["return"].([]interface{}); ok && len(ret) > 0 {
+		if m, ok := ret[0].(map[string]interface{}); ok {
+			jid, _ = m["jid"].(string)
+			if mins, ok := m["minions"].([]interface{}); ok {
+				for _, v := range mins {
+					if id, ok := v.(string); ok {
+						minions = append(minions, id)
+					}
+				}
+			}
+		}
+	}
+	if jid == "" {
+		return "", nil, fmt.Errorf("salt api did not return a jid (no minions matched target %q?)", target)
+	}
+	return jid, minions, nil
+}
+
+// LookupJob 通过 jobs.lookup_jid 查询作业结果，返回 minion -> 结果 的映射
+func (s *SaltStackService) LookupJob(ctx context.Context, jid string) (map[string]interface{}, error) {
+	result, err := s.executeSaltCommand(ctx, map[string]interface{}{
+		"client": "runner",
+		"fun":    "jobs.lookup_jid",
+		"kwarg":  map[string]interface{}{"jid": jid},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if ret, ok := result["return"].([]interface{}); ok && len(ret) > 0 {
+		if m, ok := ret[0].(map[string]interface{}); ok {
+			return m, nil
+		}
+	}
+	return map[string]interface{}{}, nil
+}
+
+// WaitForJob 轮询作业结果直到所有目标 minion 返回或超时
+// 超时时返回已收到的部分结果和 context.DeadlineExceeded
+func (s *SaltStackService) WaitForJob(ctx context.Context, jid string, minions []string, timeout time.Duration) (map[string]interface{}, error) {
+	deadline := time.Now().Add(timeout)
+	pollInterval := 2 * time.Second
+	results := map[string]interface{}{}
+
+	for {
+		if m, err := s.LookupJob(ctx, jid); err != nil {
+			log.Printf("[SaltStack] 查询作业 %s 失败: %v", jid, err)
+		} else if len(m) > 0 {
+			results = m
+			// 未知目标列表时，收到任意结果即视为完成
+			if len(minions) == 0 || len(results) >= len(minions) {
+				return results, nil
+			}
+		}
+
+		if time.Now().After(deadline) {
+			return results, context.DeadlineExceeded
+		}
+		select {
+		case <-ctx.Done():
+			return results, ctx.Err()
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// CountJobResults 统计作业结果中成功与失败的 minion 数量，并返回最大的非零返回码
+func CountJobResults(results map[string]interface{}) (successCount, failedCount, returnCode int) {
+	for _, v := range results {
+		vMap, ok := v.(map[string]interface{})
+		if !ok {
+			successCount++ // 简单结果视为成功
+			continue
+		}
+		retcode, ok := vMap["retcode"].(float64)
+		if !ok {
+			successCount++
+			continue
+		}
+		if retcode != 0 {
+			failedCount++
+			if returnCode == 0 || int(retcode) > returnCode {
+				returnCode = int(retcode)
+			}
+		} else {
+			successCount++
+		}
+	}
+	return successCount, failedCount, returnCode
+}
+
+// ListKeys 通过 wheel key.list_all 获取该 Master 上的密钥清单
+// 返回 key 状态 -> minion 列表，状态为 accepted, pending, rejected, denied
+func (s *SaltStackService) ListKeys(ctx context.Context) (map[string][]string, error) {
+	resp, err := s.executeSaltCommand(ctx, map[string]interface{}{
+		"client": "wheel",
+		"fun":    "key.list_all",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	keys := map[string][]string{}
+	fields := map[string]string{
+		"minions":          "accepted",
+		"minions_pre":      "pending",
+		"minions_rejected": "rejected",
+		"minions_denied":   "denied",
+	}
+	ret, ok := resp["return"].([]interface{})
+	if !ok || len(ret) == 0 {
+		return nil, fmt.Errorf("unexpected key.list_all response")
+	}
+	first, _ := ret[0].(map[string]interface{})
+	data, _ := first["data"].(map[string]interface{})
+	lists, ok := data["return"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unexpected key.list_all response")
+	}
+	for field, status := range fields {
+		if ids, ok := lists[field].([]interface{}); ok {
+			for _, id := range ids {
+				if str, ok := id.(string); ok {
+					keys[status] = append(keys[status], str)
+				}
+			}
+		}
+	}
+	return keys, nil
+}
+
+// RunLocal 以 local 方式同步执行并返回 minion -> 返回值（timeout 为 Salt 侧等待 minion 返回的秒数）
+func (s *SaltStackService) RunLocal(ctx context.Context, target, tgtType, function string, args []interface{}, kwarg map[string]interface{}, timeout int) (map[string]interface{}, error) {
+	payload := map[string]interface{}{
+		"client": "local",
+		"tgt":    target,
+		"fun":    function,
+	}
+	if tgtType != "" && tgtType != "glob" {
+		payload["tgt_type"] = tgtType
+	}
+	if len(args) > 0 {
+		payload["arg"] = args
+	}
+	if len(kwarg) > 0 {
+		payload["kwarg"] = kwarg
+	}
+	if timeout > 0 {
+		payload["timeout"] = timeout
+	}
+	result, err := s.executeSaltCommand(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	if ret, ok := result["return"].([]interface{}); ok && len(ret) > 0 {
+		if m, ok := ret[0].(map[string]interface{}); ok {
+			return m, nil
+		}
+	}
+	return map[string]interface{}{}, nil
+}
+
+// SaltEvent Salt 事件总线上的事件
+type SaltEvent struct {
+	Tag  string                 `json:"tag"`
+	Data map[string]interface{} `json:"data"`
+}
+
+// StreamEvents 订阅 Salt API 的 /events SSE 事件流，直到 ctx 取消或连接断开
+func (s *SaltStackService) StreamEvents(ctx context.Context, handler func(SaltEvent)) error {
+	if err := s.ensureToken(ctx); err != nil {
+		return fmt.Errorf("failed to get auth token: %v", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", s.masterURL+"/events", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("X-Auth-Token", s.apiToken)
+
+	// 事件流为长连接，不使用带超时的默认客户端
+	resp, err := (&http.Client{Transport: s.client.Transport}).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("events endpoint returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	var data strings.Builder
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "data:"):
+			data.WriteString(strings.TrimSpace(strings.TrimPrefix(line, "data:")))
+		case line == "":
+			if data.Len() > 0 {
+				var event SaltEvent
+				if err := json.Unmarshal([]byte(data.String()), &event); err == nil && event.Tag != "" {
+					handler(event)
+				}
+				data.Reset()
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return io.EOF
+}
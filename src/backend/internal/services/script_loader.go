@@ -11,10 +11,11 @@ import (
 	"sync"
 	"text/template"
 
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
 	"github.com/sirupsen/logrus"
 )
 
-//go:embed scripts/*.sh scripts/**/*.sh scripts/**/*.tmpl
+//go:embed scripts/*.sh scripts/**/*.sh scripts/**/*.tmpl scripts/**/*.yaml
 var embeddedScripts embed.FS
 
 // ScriptLoader 脚本加载器服务
@@ -24,9 +25,11 @@ var embeddedScripts embed.FS
 //   - 优先从 SCRIPTS_DIR 环境变量指定的目录加载（便于运维修改）
 //   - 文件系统找不到时回退到嵌入资源（确保程序可独立运行）
 //   - 模板文件使用 .tmpl 后缀
+//   - 各操作系统使用的模板由 templates/os-registry.yaml 支持矩阵决定
 type ScriptLoader struct {
 	scriptsDir    string
 	templateCache map[string]*template.Template
+	osRegistry    *OSRegistry
 	cacheMutex    sync.RWMutex
 }
 
@@ -45,6 +48,11 @@ type SaltInstallParams struct {
 	OS           string // 操作系统类型 (ubuntu, debian, centos, rhel, etc.)
 	OSVersion    string // 操作系统版本
 	MasterPubURL string // Master 公钥下载 URL（一次性令牌）
+
+	// 以下字段来自操作系统支持矩阵，为空时按 OS 自动填充
+	PackageManager string   // 包管理器 (apt-get, dnf, yum)
+	Packages       []string // 安装依赖包
+	RepoSetup      string   // 软件源配置 shell 片段
 }
 
 // SaltUninstallParams Salt Minion 卸载参数
@@ -70,41 +78,32 @@ type NodeMetricsDeployParams struct {
 	CollectScript   string // 采集脚本内容
 }
 
-// 模板文件映射 - 定义各类脚本对应的模板文件
+// SSHCATrustParams SSH 用户 CA 信任配置参数
+type SSHCATrustParams struct {
+	CAPublicKey         string   // CA 公钥（authorized_keys 格式）
+	AutomationPrincipal string   // 平台自动化证书的主体
+	Scopes              []string // 节点所属的授权范围
+	SudoPrefix          string   // sudo 前缀 (空或 "sudo ")
+}
+
+// 模板文件映射 - 定义各类脚本的默认模板
+// 按操作系统区分的模板登记在 templates/os-registry.yaml 中
 var templateFiles = map[string]map[string]string{
 	"salt-install": {
-		"ubuntu":    "templates/salt-install-debian.sh.tmpl",
-		"debian":    "templates/salt-install-debian.sh.tmpl",
-		"centos":    "templates/salt-install-rhel.sh.tmpl",
-		"rhel":      "templates/salt-install-rhel.sh.tmpl",
-		"rocky":     "templates/salt-install-rhel.sh.tmpl",
-		"almalinux": "templates/salt-install-rhel.sh.tmpl",
-		"fedora":    "templates/salt-install-rhel.sh.tmpl",
-		"default":   "templates/salt-install-generic.sh.tmpl",
+		"default": "templates/salt-install-generic.sh.tmpl",
 	},
 	"salt-uninstall": {
-		"ubuntu":    "templates/salt-uninstall-debian.sh.tmpl",
-		"debian":    "templates/salt-uninstall-debian.sh.tmpl",
-		"centos":    "templates/salt-uninstall-rhel.sh.tmpl",
-		"rhel":      "templates/salt-uninstall-rhel.sh.tmpl",
-		"rocky":     "templates/salt-uninstall-rhel.sh.tmpl",
-		"almalinux": "templates/salt-uninstall-rhel.sh.tmpl",
-		"fedora":    "templates/salt-uninstall-rhel.sh.tmpl",
-		"default":   "templates/salt-uninstall-generic.sh.tmpl",
+		"default": "templates/salt-uninstall-generic.sh.tmpl",
 	},
 	"categraf-install": {
-		"ubuntu":    "templates/categraf-install-debian.sh.tmpl",
-		"debian":    "templates/categraf-install-debian.sh.tmpl",
-		"centos":    "templates/categraf-install-rhel.sh.tmpl",
-		"rhel":      "templates/categraf-install-rhel.sh.tmpl",
-		"rocky":     "templates/categraf-install-rhel.sh.tmpl",
-		"almalinux": "templates/categraf-install-rhel.sh.tmpl",
-		"fedora":    "templates/categraf-install-rhel.sh.tmpl",
-		"default":   "templates/categraf-install-debian.sh.tmpl",
+		"default": "templates/categraf-install-debian.sh.tmpl",
 	},
 	"node-metrics-deploy": {
 		"default": "templates/node-metrics-deploy.sh.tmpl",
 	},
+	"ssh-ca-trust": {
+		"default": "templates/ssh-ca-trust.sh.tmpl",
+	},
 }
 
 var (
@@ -219,7 +218,7 @@ func (s *ScriptLoader) ListScripts() ([]string, error) {
 			if err != nil {
 				return nil // 忽略错误，继续遍历
 			}
-			if !info.IsDir() && (strings.HasSuffix(info.Name(), ".sh") || strings.HasSuffix(info.Name(), ".tmpl")) {
+			if !info.IsDir() && (strings.HasSuffix(info.Name(), ".sh") || strings.HasSuffix(info.Name(), ".tmpl") || strings.HasSuffix(info.Name(), ".yaml")) {
 				relPath, _ := filepath.Rel(s.scriptsDir, path)
 				scripts = append(scripts, relPath)
 				seen[relPath] = true
@@ -236,7 +235,7 @@ func (s *ScriptLoader) ListScripts() ([]string, error) {
 		if err != nil {
 			return nil
 		}
-		if !d.IsDir() && (strings.HasSuffix(d.Name(), ".sh") || strings.HasSuffix(d.Name(), ".tmpl")) {
+		if !d.IsDir() && (strings.HasSuffix(d.Name(), ".sh") || strings.HasSuffix(d.Name(), ".tmpl") || strings.HasSuffix(d.Name(), ".yaml")) {
 			relPath := strings.TrimPrefix(path, "scripts/")
 			if !seen[relPath] {
 				scripts = append(scripts, relPath)
@@ -256,6 +255,7 @@ func (s *ScriptLoader) ClearCache() {
 	s.cacheMutex.Lock()
 	defer s.cacheMutex.Unlock()
 	s.templateCache = make(map[string]*template.Template)
+	s.osRegistry = nil
 	logrus.Info("[ScriptLoader] 模板缓存已清除")
 }
 
@@ -264,6 +264,9 @@ func (s *ScriptLoader) ReloadScript(scriptName string) {
 	s.cacheMutex.Lock()
 	defer s.cacheMutex.Unlock()
 	delete(s.templateCache, scriptName)
+	if scriptName == osRegistryFile {
+		s.osRegistry = nil
+	}
 	logrus.Infof("[ScriptLoader] 脚本模板已从缓存移除: %s", scriptName)
 }
 
@@ -277,22 +280,98 @@ func (s *ScriptLoader) GetScriptsDir() string {
 // 模板文件位于 scripts/templates/ 目录
 // 运维人员可直接修改模板文件，无需重新编译程序
 
+// OSRegistry 获取操作系统支持矩阵
+// 外部目录中的支持矩阵无法解析时记录警告并回退到内嵌版本
+func (s *ScriptLoader) OSRegistry() (*OSRegistry, error) {
+	s.cacheMutex.RLock()
+	registry := s.osRegistry
+	s.cacheMutex.RUnlock()
+	if registry != nil {
+		return registry, nil
+	}
+
+	content, err := s.GetScript(osRegistryFile)
+	if err != nil {
+		return nil, err
+	}
+	registry, err = ParseOSRegistry([]byte(content))
+	if err != nil {
+		logrus.Warnf("[ScriptLoader] 加载操作系统支持矩阵失败: %v，使用内嵌版本", err)
+		embedded, readErr := embeddedScripts.ReadFile("scripts/" + osRegistryFile)
+		if readErr != nil {
+			return nil, err
+		}
+		if registry, err = ParseOSRegistry(embedded); err != nil {
+			return nil, err
+		}
+	}
+
+	s.cacheMutex.Lock()
+	s.osRegistry = registry
+	s.cacheMutex.Unlock()
+	return registry, nil
+}
+
+// ResolveOS 检查主机操作系统是否受支持，返回对应的模板、包管理器和架构信息
+func (s *ScriptLoader) ResolveOS(osInfo models.OSInfo) (*ResolvedOS, error) {
+	registry, err := s.OSRegistry()
+	if err != nil {
+		return nil, err
+	}
+	return registry.Resolve(osInfo)
+}
+
 // getTemplateForOS 根据操作系统获取对应的模板文件名
-func getTemplateForOS(scriptType, osName string) string {
+// 优先使用支持矩阵中登记的家族模板，未登记的系统使用默认模板
+func (s *ScriptLoader) getTemplateForOS(scriptType, osName string) string {
+	if registry, err := s.OSRegistry(); err == nil {
+		if tmplName := registry.Template(scriptType, osName); tmplName != "" {
+			return tmplName
+		}
+	}
 	osMap, ok := templateFiles[scriptType]
 	if !ok {
 		return ""
 	}
-	if tmplName, ok := osMap[osName]; ok {
-		return tmplName
-	}
 	return osMap["default"]
 }
 
+// fillPackageDefaults 按支持矩阵补全安装参数中的包管理器、依赖包和软件源配置
+func (s *ScriptLoader) fillPackageDefaults(params *SaltInstallParams) {
+	if params.PackageManager != "" {
+		return
+	}
+	registry, err := s.OSRegistry()
+	if err != nil {
+		return
+	}
+	family, dist := registry.lookup(params.OS)
+	if family == nil {
+		return
+	}
+	params.PackageManager = family.PackageManager
+	if dist.PackageManager != "" {
+		params.PackageManager = dist.PackageManager
+	}
+	if len(params.Packages) == 0 {
+		params.Packages = family.Packages
+		if len(dist.Packages) > 0 {
+			params.Packages = dist.Packages
+		}
+	}
+	if params.RepoSetup == "" {
+		params.RepoSetup = family.RepoSetup
+		if dist.RepoSetup != "" {
+			params.RepoSetup = dist.RepoSetup
+		}
+	}
+}
+
 // GenerateSaltInstallScript 生成 Salt Minion 安装脚本
 func (s *ScriptLoader) GenerateSaltInstallScript(params SaltInstallParams) (string, error) {
 	// 获取对应操作系统的模板
-	templateName := getTemplateForOS("salt-install", params.OS)
+	s.fillPackageDefaults(&params)
+	templateName := s.getTemplateForOS("salt-install", params.OS)
 	if templateName == "" {
 		templateName = "templates/salt-install-generic.sh.tmpl"
 	}
@@ -313,7 +392,7 @@ func (s *ScriptLoader) GenerateSaltInstallScript(params SaltInstallParams) (stri
 // GenerateSaltUninstallScript 生成 Salt Minion 卸载脚本
 func (s *ScriptLoader) GenerateSaltUninstallScript(params SaltUninstallParams) (string, error) {
 	// 获取对应操作系统的模板
-	templateName := getTemplateForOS("salt-uninstall", params.OS)
+	templateName := s.getTemplateForOS("salt-uninstall", params.OS)
 	if templateName == "" {
 		templateName = "templates/salt-uninstall-generic.sh.tmpl"
 	}
@@ -385,7 +464,7 @@ uname -a
 func (s *ScriptLoader) GenerateCategrafInstallScript(params map[string]string) (string, error) {
 	// 获取对应操作系统的模板
 	osType := params["OS"]
-	templateName := getTemplateForOS("categraf-install", osType)
+	templateName := s.getTemplateForOS("categraf-install", osType)
 	if templateName == "" {
 		templateName = "templates/categraf-install-debian.sh.tmpl"
 	}
@@ -420,3 +499,12 @@ func (s *ScriptLoader) GenerateNodeMetricsDeployScript(params NodeMetricsDeployP
 
 	return script, nil
 }
+
+// GenerateSSHCATrustScript 生成 SSH 用户 CA 信任配置脚本
+func (s *ScriptLoader) GenerateSSHCATrustScript(params SSHCATrustParams) (string, error) {
+	script, err := s.RenderScript(s.getTemplateForOS("ssh-ca-trust", ""), params)
+	if err != nil {
+		return "", fmt.Errorf("无法生成 SSH CA 信任配置脚本: %v", err)
+	}
+	return script, nil
+}
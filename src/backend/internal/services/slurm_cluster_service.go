@@ -71,6 +71,7 @@ func (s *SlurmClusterService) CreateCluster(ctx context.Context, req models.Crea
 		MasterPort:  req.MasterPort,
 		SaltMaster:  req.SaltMaster,
 		Config:      req.Config,
+		JumpHosts:   req.JumpHosts,
 		CreatedBy:   userID,
 	}
 
@@ -96,12 +97,14 @@ func (s *SlurmClusterService) CreateCluster(ctx context.Context, req models.Crea
 			Storage:      nodeReq.Storage,
 			GPUs:         nodeReq.GPUs,
 			NodeConfig:   nodeReq.NodeConfig,
+			JumpHosts:    nodeReq.JumpHosts,
 			SaltMinionID: fmt.Sprintf("%s-%s", cluster.Name, nodeReq.NodeName),
 		}
 
 		if err := s.db.Create(node).Error; err != nil {
 			return nil, fmt.Errorf("failed to create node %s: %v", nodeReq.NodeName, err)
 		}
+		ObserveInventoryAsync(SlurmNodeObservation(node))
 	}
 
 	// 重新加载集群和节点信息
@@ -402,16 +405,14 @@ func (s *SlurmClusterService) installSaltMinionOnNode(node *models.SlurmNode, cl
 
 	// 建立SSH连接
 	sshConfig := &ssh.ClientConfig{
-		User: node.Username,
-		Auth: []ssh.AuthMethod{
-			ssh.Password(node.Password),
-		},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		User:            node.Username,
+		Auth:            nodeAuthMethods(node),
+		HostKeyCallback: KnownHostsCallback(),
 		Timeout:         30 * time.Second,
 	}
 
-	addr := fmt.Sprintf("%s:%d", node.Host, node.Port)
-	client, err := ssh.Dial("tcp", addr, sshConfig)
+	key := NewSSHPoolKey(node.Host, node.Port, node.Username, node.Password)
+	client, err := GetSSHClientPool().DialVia(key, nodeJumpHosts(node, cluster), sshConfig)
 	if err != nil {
 		return fmt.Errorf("SSH connection failed: %v", err)
 	}
@@ -419,7 +420,7 @@ func (s *SlurmClusterService) installSaltMinionOnNode(node *models.SlurmNode, cl
 
 	// 步骤1: 检测操作系统
 	step1 := s.createInstallStep(installTask.ID, "detect-os", "ssh-connect", "Detecting operating system")
-	osInfo, err := s.detectOSInfo(client, sessionID, node, installTask, step1)
+	osInfo, err := s.detectOSInfo(client.Client, sessionID, node, installTask, step1)
 	if err != nil {
 		s.completeInstallStep(step1, "failed", err.Error())
 		return fmt.Errorf("OS detection failed: %v", err)
@@ -428,7 +429,7 @@ func (s *SlurmClusterService) installSaltMinionOnNode(node *models.SlurmNode, cl
 
 	// 步骤2: 安装SaltStack仓库
 	step2 := s.createInstallStep(installTask.ID, "install-repo", "install", "Installing SaltStack repository")
-	if err := s.installSaltRepository(client, osInfo, sessionID, node, installTask, step2); err != nil {
+	if err := s.installSaltRepository(client.Client, osInfo, sessionID, node, installTask, step2); err != nil {
 		s.completeInstallStep(step2, "failed", err.Error())
 		return fmt.Errorf("repository installation failed: %v", err)
 	}
@@ -436,7 +437,7 @@ func (s *SlurmClusterService) installSaltMinionOnNode(node *models.SlurmNode, cl
 
 	// 步骤3: 安装Salt Minion
 	step3 := s.createInstallStep(installTask.ID, "install-minion", "install", "Installing Salt Minion")
-	if err := s.installSaltMinionPackage(client, osInfo, sessionID, node, installTask, step3); err != nil {
+	if err := s.installSaltMinionPackage(client.Client, osInfo, sessionID, node, installTask, step3); err != nil {
 		s.completeInstallStep(step3, "failed", err.Error())
 		return fmt.Errorf("Salt Minion installation failed: %v", err)
 	}
@@ -444,7 +445,7 @@ func (s *SlurmClusterService) installSaltMinionOnNode(node *models.SlurmNode, cl
 
 	// 步骤4: 配置Salt Minion
 	step4 := s.createInstallStep(installTask.ID, "configure-minion", "configure", "Configuring Salt Minion")
-	if err := s.configureSaltMinion(client, cluster, node, sessionID, installTask, step4); err != nil {
+	if err := s.configureSaltMinion(client.Client, cluster, node, sessionID, installTask, step4); err != nil {
 		s.completeInstallStep(step4, "failed", err.Error())
 		return fmt.Errorf("Salt Minion configuration failed: %v", err)
 	}
@@ -452,15 +453,34 @@ func (s *SlurmClusterService) installSaltMinionOnNode(node *models.SlurmNode, cl
 
 	// 步骤5: 启动Salt Minion服务
 	step5 := s.createInstallStep(installTask.ID, "start-service", "start", "Starting Salt Minion service")
-	if err := s.startSaltMinionService(client, sessionID, node, installTask, step5); err != nil {
+	if err := s.startSaltMinionService(client.Client, sessionID, node, installTask, step5); err != nil {
 		s.completeInstallStep(step5, "failed", err.Error())
 		return fmt.Errorf("Salt Minion service start failed: %v", err)
 	}
 	s.completeInstallStep(step5, "completed", "Salt Minion service started")
 
+	// 步骤6: 下发 SSH 用户 CA 信任（失败不影响安装结果）
+	if ca := GetSSHCAService(); ca != nil {
+		step6 := s.createInstallStep(installTask.ID, "ssh-ca-trust", "configure", "Configuring SSH user CA trust")
+		if err := ca.InstallTrust(client.Client, sudoPrefixFor(node.Username), SSHCAClusterScope(cluster.ID)); err != nil {
+			logrus.WithError(err).Warnf("Failed to configure SSH CA trust on node %s", node.NodeName)
+			s.completeInstallStep(step6, "failed", err.Error())
+		} else {
+			s.completeInstallStep(step6, "completed", "SSH user CA trusted")
+		}
+	}
+
 	return nil
 }
 
+// sudoPrefixFor 非 root 用户执行特权命令时使用 sudo
+func sudoPrefixFor(username string) string {
+	if username == "root" || username == "" {
+		return ""
+	}
+	return "sudo "
+}
+
 // 辅助方法
 func (s *SlurmClusterService) generateDeploymentID() string {
 	bytes := make([]byte, 8)
@@ -596,7 +616,7 @@ func (s *SlurmClusterService) ConnectExternalCluster(ctx context.Context, req mo
 	// 创建SSH客户端配置
 	sshConfig := &ssh.ClientConfig{
 		User:            req.MasterSSH.Username,
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		HostKeyCallback: KnownHostsCallback(),
 		Timeout:         30 * time.Second,
 	}
 
@@ -614,8 +634,8 @@ func (s *SlurmClusterService) ConnectExternalCluster(ctx context.Context, req mo
 	}
 
 	// 测试SSH连接
-	address := fmt.Sprintf("%s:%d", req.MasterSSH.Host, req.MasterSSH.Port)
-	client, err := ssh.Dial("tcp", address, sshConfig)
+	key := NewSSHPoolKey(req.MasterSSH.Host, req.MasterSSH.Port, req.MasterSSH.Username, req.MasterSSH.Password)
+	client, err := GetSSHClientPool().DialVia(key, req.JumpHosts, sshConfig)
 	if err != nil {
 		logrus.WithError(err).Error("Failed to connect to SLURM master via SSH")
 		return nil, fmt.Errorf("failed to connect to SLURM master: %v", err)
@@ -663,6 +683,7 @@ func (s *SlurmClusterService) ConnectExternalCluster(ctx context.Context, req mo
 		MasterPort:  req.MasterPort,
 		MasterSSH:   &req.MasterSSH,
 		Config:      req.Config,
+		JumpHosts:   req.JumpHosts,
 		CreatedBy:   userID,
 	}
 
@@ -675,7 +696,7 @@ func (s *SlurmClusterService) ConnectExternalCluster(ctx context.Context, req mo
 	}
 
 	// 异步获取节点信息
-	go s.discoverClusterNodes(cluster.ID, req.MasterSSH)
+	go s.discoverClusterNodes(cluster.ID, req.MasterSSH, req.JumpHosts)
 
 	logrus.WithFields(logrus.Fields{
 		"cluster_id":   cluster.ID,
@@ -686,13 +707,13 @@ func (s *SlurmClusterService) ConnectExternalCluster(ctx context.Context, req mo
 }
 
 // discoverClusterNodes 发现集群节点
-func (s *SlurmClusterService) discoverClusterNodes(clusterID uint, sshConfig models.SSHConfig) {
+func (s *SlurmClusterService) discoverClusterNodes(clusterID uint, sshConfig models.SSHConfig, jumps models.SSHJumpChain) {
 	logrus.Infof("Discovering nodes for cluster %d", clusterID)
 
 	// 创建SSH客户端
 	clientConfig := &ssh.ClientConfig{
 		User:            sshConfig.Username,
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		HostKeyCallback: KnownHostsCallback(),
 		Timeout:         30 * time.Second,
 	}
 
@@ -701,9 +722,10 @@ func (s *SlurmClusterService) discoverClusterNodes(clusterID uint, sshConfig mod
 			ssh.Password(sshConfig.Password),
 		}
 	}
+	clientConfig.Auth = withSSHCAAuth(clientConfig.Auth)
 
-	address := fmt.Sprintf("%s:%d", sshConfig.Host, sshConfig.Port)
-	client, err := ssh.Dial("tcp", address, clientConfig)
+	key := NewSSHPoolKey(sshConfig.Host, sshConfig.Port, sshConfig.Username, sshConfig.Password)
+	client, err := GetSSHClientPool().DialVia(key, jumps, clientConfig)
 	if err != nil {
 		logrus.WithError(err).Error("Failed to connect for node discovery")
 		return
@@ -829,7 +851,7 @@ func (s *SlurmClusterService) getExternalClusterInfo(cluster models.SlurmCluster
 	// 创建SSH客户端
 	clientConfig := &ssh.ClientConfig{
 		User:            cluster.MasterSSH.Username,
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		HostKeyCallback: KnownHostsCallback(),
 		Timeout:         30 * time.Second,
 	}
 
@@ -838,9 +860,10 @@ func (s *SlurmClusterService) getExternalClusterInfo(cluster models.SlurmCluster
 			ssh.Password(cluster.MasterSSH.Password),
 		}
 	}
+	clientConfig.Auth = withSSHCAAuth(clientConfig.Auth)
 
-	address := fmt.Sprintf("%s:%d", cluster.MasterSSH.Host, cluster.MasterSSH.Port)
-	client, err := ssh.Dial("tcp", address, clientConfig)
+	key := NewSSHPoolKey(cluster.MasterSSH.Host, cluster.MasterSSH.Port, cluster.MasterSSH.Username, cluster.MasterSSH.Password)
+	client, err := GetSSHClientPool().DialVia(key, cluster.JumpHosts, clientConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to cluster: %v", err)
 	}
@@ -943,7 +966,11 @@ func (s *SlurmClusterService) CreateNode(ctx context.Context, node *models.Slurm
 		node.ThreadsPerCore = 1
 	}
 
-	return s.db.WithContext(ctx).Create(node).Error
+	if err := s.db.WithContext(ctx).Create(node).Error; err != nil {
+		return err
+	}
+	ObserveInventoryAsync(SlurmNodeObservation(node))
+	return nil
 }
 
 // DeleteNode 删除节点
@@ -971,6 +998,8 @@ func (s *SlurmClusterService) DeleteNode(ctx context.Context, nodeID uint, force
 			AuthType: node.AuthType,
 			Password: node.Password,
 			KeyPath:  node.KeyPath,
+
+			JumpHosts: s.NodeJumpHosts(&node),
 		}
 
 		// 尝试停止服务（忽略错误）
@@ -1085,12 +1114,12 @@ func (s *SlurmClusterService) removeNodeFromSlurmConfig(nodeName string) error {
 	sshConfig := &ssh.ClientConfig{
 		User:            slurmMasterUser,
 		Auth:            authMethods,
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		HostKeyCallback: KnownHostsCallback(),
 		Timeout:         30 * time.Second,
 	}
 
 	address := fmt.Sprintf("%s:%d", slurmMasterHost, slurmMasterPort)
-	client, err := ssh.Dial("tcp", address, sshConfig)
+	client, err := GetSSHClientPool().DialVia(NewSSHPoolKey(slurmMasterHost, slurmMasterPort, slurmMasterUser, os.Getenv("SLURM_MASTER_PASSWORD"), sshKeyCredential(keyPath)), slurmMasterJumpHosts(), sshConfig)
 	if err != nil {
 		return fmt.Errorf("创建SSH连接到SLURM Master失败: %v", err)
 	}
@@ -1285,12 +1314,14 @@ type RemoteNodeInitConfig struct {
 	Host            string // 节点主机地址
 	Port            int    // SSH端口
 	Username        string // SSH用户名
-	AuthType        string // 认证类型: password, key
+	AuthType        string // 认证类型: password, key, ca（平台 CA 证书）
 	Password        string // SSH密码
 	KeyPath         string // SSH私钥路径
 	MungeKeyContent string // Munge密钥内容(base64)
 	SlurmConfPath   string // SLURM配置文件路径(在master上)
 	InstallPackages bool   // 是否安装软件包
+
+	JumpHosts models.SSHJumpChain // 跳板机链（ProxyJump），为空则直连
 }
 
 // InitializeRemoteNode 初始化远程SLURM节点
@@ -1306,60 +1337,111 @@ func (s *SlurmClusterService) InitializeRemoteNode(ctx context.Context, config R
 	defer client.Close()
 
 	// 步骤1: 上传初始化脚本
-	if err := s.uploadInitScript(client); err != nil {
+	if err := s.uploadInitScript(client.Client); err != nil {
 		return fmt.Errorf("上传初始化脚本失败: %v", err)
 	}
 
 	// 步骤2: 安装软件包（可选）
 	if config.InstallPackages {
-		if err := s.installNodePackages(client); err != nil {
+		if err := s.installNodePackages(client.Client); err != nil {
 			return fmt.Errorf("安装软件包失败: %v", err)
 		}
 	}
 
 	// 步骤3: 设置目录和权限
-	if err := s.setupNodeDirectories(client); err != nil {
+	if err := s.setupNodeDirectories(client.Client); err != nil {
 		return fmt.Errorf("设置目录失败: %v", err)
 	}
 
 	// 步骤4: 同步Munge密钥
 	if config.MungeKeyContent != "" {
-		if err := s.syncMungeKey(client, config.MungeKeyContent); err != nil {
+		if err := s.syncMungeKey(client.Client, config.MungeKeyContent); err != nil {
 			return fmt.Errorf("同步Munge密钥失败: %v", err)
 		}
 	}
 
 	// 步骤5: 同步SLURM配置文件
 	if config.SlurmConfPath != "" {
-		if err := s.syncSlurmConfig(client, config.SlurmConfPath); err != nil {
+		if err := s.syncSlurmConfig(client.Client, config.SlurmConfPath); err != nil {
 			return fmt.Errorf("同步SLURM配置失败: %v", err)
 		}
 	}
 
 	// 步骤6: 启动Munge服务
-	if err := s.startMungeService(client); err != nil {
+	if err := s.startMungeService(client.Client); err != nil {
 		return fmt.Errorf("启动Munge服务失败: %v", err)
 	}
 
 	// 步骤7: 启动SLURMD服务
-	if err := s.startSlurmdService(client); err != nil {
+	if err := s.startSlurmdService(client.Client); err != nil {
 		return fmt.Errorf("启动SLURMD服务失败: %v", err)
 	}
 
 	// 步骤8: 验证节点状态
-	if err := s.verifyNodeStatus(client, config.NodeName); err != nil {
+	if err := s.verifyNodeStatus(client.Client, config.NodeName); err != nil {
 		return fmt.Errorf("节点验证失败: %v", err)
 	}
 
+	// 步骤9: 下发 SSH 用户 CA 信任（失败仅记录警告）
+	if ca := GetSSHCAService(); ca != nil {
+		var scopes []string
+		var node models.SlurmNode
+		if config.NodeID != 0 && s.db.Select("id", "cluster_id").First(&node, config.NodeID).Error == nil {
+			scopes = append(scopes, SSHCAClusterScope(node.ClusterID))
+		}
+		if err := ca.InstallTrust(client.Client, sudoPrefixFor(config.Username), scopes...); err != nil {
+			logrus.WithError(err).Warnf("节点 %s 配置 SSH CA 信任失败", config.NodeName)
+		}
+	}
+
 	logrus.Infof("远程节点初始化完成: %s", config.NodeName)
 	return nil
 }
 
-// createSSHClient 创建SSH客户端连接
-func (s *SlurmClusterService) createSSHClient(config RemoteNodeInitConfig) (*ssh.Client, error) {
+// nodeJumpHosts 节点的跳板机链：节点单独配置优先，否则使用集群默认配置
+func nodeJumpHosts(node *models.SlurmNode, cluster *models.SlurmCluster) models.SSHJumpChain {
+	if node != nil && len(node.JumpHosts) > 0 {
+		return node.JumpHosts
+	}
+	if cluster != nil {
+		return cluster.JumpHosts
+	}
+	return nil
+}
+
+// nodeAuthMethods 节点的 SSH 认证方式：已保存的密码，以及平台 CA 签发的自动化证书
+func nodeAuthMethods(node *models.SlurmNode) []ssh.AuthMethod {
+	var methods []ssh.AuthMethod
+	if node.Password != "" {
+		methods = append(methods, ssh.Password(node.Password))
+	}
+	return withSSHCAAuth(methods)
+}
+
+// NodeJumpHosts 解析节点生效的跳板机链（必要时从数据库加载所属集群）
+func (s *SlurmClusterService) NodeJumpHosts(node *models.SlurmNode) models.SSHJumpChain {
+	return resolveNodeJumpHosts(s.db, node)
+}
+
+func resolveNodeJumpHosts(db *gorm.DB, node *models.SlurmNode) models.SSHJumpChain {
+	if node == nil || len(node.JumpHosts) > 0 {
+		return nodeJumpHosts(node, nil)
+	}
+	if node.Cluster.ID == node.ClusterID && node.ClusterID != 0 {
+		return nodeJumpHosts(node, &node.Cluster)
+	}
+	var cluster models.SlurmCluster
+	if err := db.Select("id", "jump_hosts").First(&cluster, node.ClusterID).Error; err != nil {
+		return nil
+	}
+	return cluster.JumpHosts
+}
+
+// createSSHClient 从连接池获取SSH客户端连接（调用方 Close 即归还）
+func (s *SlurmClusterService) createSSHClient(config RemoteNodeInitConfig) (*PooledSSHClient, error) {
 	clientConfig := &ssh.ClientConfig{
 		User:            config.Username,
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		HostKeyCallback: KnownHostsCallback(),
 		Timeout:         30 * time.Second,
 	}
 
@@ -1383,12 +1465,15 @@ func (s *SlurmClusterService) createSSHClient(config RemoteNodeInitConfig) (*ssh
 		clientConfig.Auth = []ssh.AuthMethod{
 			ssh.PublicKeys(signer),
 		}
-	} else {
+	} else if config.AuthType != "ca" {
 		return nil, fmt.Errorf("无效的认证配置")
 	}
+	// 始终附加平台 CA 签发的自动化证书（AuthType 为 ca 时仅使用证书）
+	clientConfig.Auth = withSSHCAAuth(clientConfig.Auth)
 
 	address := fmt.Sprintf("%s:%d", config.Host, config.Port)
-	client, err := ssh.Dial("tcp", address, clientConfig)
+	key := NewSSHPoolKey(config.Host, config.Port, config.Username, config.Password, sshKeyCredential(config.KeyPath))
+	client, err := GetSSHClientPool().DialVia(key, config.JumpHosts, clientConfig)
 	if err != nil {
 		return nil, fmt.Errorf("SSH连接失败 (%s): %v", address, err)
 	}
@@ -1620,6 +1705,8 @@ func (s *SlurmClusterService) SyncSSHKeysToNodes(ctx context.Context, clusterID
 				AuthType: n.AuthType,
 				Password: n.Password,
 				KeyPath:  n.KeyPath,
+
+				JumpHosts: nodeJumpHosts(&n, &cluster),
 			}
 
 			if err := s.syncPublicKeyToNode(config, string(publicKeyContent)); err != nil {
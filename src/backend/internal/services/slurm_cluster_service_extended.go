@@ -136,16 +136,14 @@ func (s *SlurmClusterService) finalValidation(task *DeploymentTask, cluster *mod
 // testNodeSSHConnection 测试节点SSH连接
 func (s *SlurmClusterService) testNodeSSHConnection(node *models.SlurmNode) error {
 	sshConfig := &ssh.ClientConfig{
-		User: node.Username,
-		Auth: []ssh.AuthMethod{
-			ssh.Password(node.Password),
-		},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		User:            node.Username,
+		Auth:            nodeAuthMethods(node),
+		HostKeyCallback: KnownHostsCallback(),
 		Timeout:         10 * time.Second,
 	}
 
-	addr := fmt.Sprintf("%s:%d", node.Host, node.Port)
-	client, err := ssh.Dial("tcp", addr, sshConfig)
+	key := NewSSHPoolKey(node.Host, node.Port, node.Username, node.Password)
+	client, err := GetSSHClientPool().DialVia(key, resolveNodeJumpHosts(s.db, node), sshConfig)
 	if err != nil {
 		return err
 	}
@@ -411,6 +409,8 @@ func (s *SlurmClusterService) installSlurmOnNode(node *models.SlurmNode, cluster
 		Port:     node.Port,
 		Username: node.Username,
 		Password: node.Password,
+
+		JumpHosts: nodeJumpHosts(node, cluster),
 	}
 
 	// 建立SSH连接
@@ -421,39 +421,39 @@ func (s *SlurmClusterService) installSlurmOnNode(node *models.SlurmNode, cluster
 	defer client.Close()
 
 	// 1. 检测操作系统类型
-	osType, err := s.detectOSType(client)
+	osType, err := s.detectOSType(client.Client)
 	if err != nil {
 		return fmt.Errorf("检测操作系统失败: %v", err)
 	}
 	log.Printf("[INFO] 检测到操作系统: %s", osType)
 
 	// 2. 安装 SLURM 和 Munge 包
-	if err := s.installSlurmPackages(client, osType); err != nil {
+	if err := s.installSlurmPackages(client.Client, osType); err != nil {
 		return fmt.Errorf("安装SLURM包失败: %v", err)
 	}
 
 	// 3. 创建必要的目录和用户
-	if err := s.setupSlurmDirectories(client, osType); err != nil {
+	if err := s.setupSlurmDirectories(client.Client, osType); err != nil {
 		return fmt.Errorf("设置SLURM目录失败: %v", err)
 	}
 
 	// 4. 配置 Munge 密钥
-	if err := s.configureMungeKey(client, osType); err != nil {
+	if err := s.configureMungeKey(client.Client, osType); err != nil {
 		return fmt.Errorf("配置Munge密钥失败: %v", err)
 	}
 
 	// 5. 配置 slurm.conf
-	if err := s.configureSlurmConf(client, osType, cluster); err != nil {
+	if err := s.configureSlurmConf(client.Client, osType, cluster); err != nil {
 		return fmt.Errorf("配置slurm.conf失败: %v", err)
 	}
 
 	// 6. 启动 Munge 服务
-	if err := s.startMungeServiceDirect(client, osType); err != nil {
+	if err := s.startMungeServiceDirect(client.Client, osType); err != nil {
 		return fmt.Errorf("启动Munge服务失败: %v", err)
 	}
 
 	// 7. 启动 SLURMD 服务
-	if err := s.startSlurmdServiceDirect(client, osType); err != nil {
+	if err := s.startSlurmdServiceDirect(client.Client, osType); err != nil {
 		return fmt.Errorf("启动SLURMD服务失败: %v", err)
 	}
 
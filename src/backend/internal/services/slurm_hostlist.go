@@ -0,0 +1,139 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ExpandSlurmHostlist 展开 Slurm hostlist 表达式，如 gpu1,node[01-03] -> gpu1,node01,node02,node03
+// 最多返回 limit 个名称（limit <= 0 时不限制）
+func ExpandSlurmHostlist(expr string, limit int) []string {
+	var names []string
+	depth, start := 0, 0
+	for i := 0; i <= len(expr); i++ {
+		if i < len(expr) {
+			switch expr[i] {
+			case '[':
+				depth++
+				continue
+			case ']':
+				depth--
+				continue
+			case ',':
+				if depth > 0 {
+					continue
+				}
+			default:
+				continue
+			}
+		}
+		for _, name := range expandSlurmHostPattern(strings.TrimSpace(expr[start:i])) {
+			if limit > 0 && len(names) >= limit {
+				return names
+			}
+			names = append(names, name)
+		}
+		start = i + 1
+	}
+	return names
+}
+
+// expandSlurmHostPattern 展开单个名称中的第一个 [...]，其余部分递归展开
+func expandSlurmHostPattern(pattern string) []string {
+	if pattern == "" || pattern == "(null)" || strings.HasPrefix(pattern, "None") {
+		return nil
+	}
+	open := strings.IndexByte(pattern, '[')
+	closeIdx := strings.IndexByte(pattern, ']')
+	if open < 0 || closeIdx < open {
+		return []string{pattern}
+	}
+	prefix, body, rest := pattern[:open], pattern[open+1:closeIdx], pattern[closeIdx+1:]
+	suffixes := expandSlurmHostPattern(rest)
+	if rest == "" {
+		suffixes = []string{""}
+	}
+
+	var names []string
+	for _, part := range strings.Split(body, ",") {
+		lo, hi, isRange := strings.Cut(part, "-")
+		if !isRange {
+			hi = lo
+		}
+		from, err1 := strconv.Atoi(lo)
+		to, err2 := strconv.Atoi(hi)
+		if err1 != nil || err2 != nil || to < from || to-from > 100000 {
+			names = append(names, prefix+part+rest)
+			continue
+		}
+		for n := from; n <= to; n++ {
+			for _, suffix := range suffixes {
+				names = append(names, fmt.Sprintf("%s%0*d%s", prefix, len(lo), n, suffix))
+			}
+		}
+	}
+	return names
+}
+
+func nonNilStrings(list []string) []string {
+	if list == nil {
+		return []string{}
+	}
+	return list
+}
+
+// AnsibleInventoryScript 生成可直接作为 ansible -i 参数的动态清单脚本
+// 脚本以 AI_INFRA_TOKEN 作为 Bearer 令牌调用 /api/inventory/ansible，返回结果按令牌所属用户的权限过滤
+func AnsibleInventoryScript(baseURL string) string {
+	return strings.Replace(`#!/usr/bin/env python3
+"""AI Infra Matrix dynamic inventory for Ansible.
+
+Usage:
+  export AI_INFRA_TOKEN=<token>
+  ansible -i ai-infra-inventory.py all -m ping
+
+Environment:
+  AI_INFRA_URL        server base URL (default: __BASE_URL__)
+  AI_INFRA_TOKEN      bearer token used to authenticate (required)
+  AI_INFRA_QUERY      extra query string, e.g. "label=env=prod&source=slurm"
+  AI_INFRA_INSECURE   set to 1 to skip TLS certificate verification
+"""
+import json
+import os
+import ssl
+import sys
+import urllib.parse
+import urllib.request
+
+
+def fetch(params):
+    base = os.environ.get("AI_INFRA_URL", "__BASE_URL__").rstrip("/")
+    token = os.environ.get("AI_INFRA_TOKEN")
+    if not token:
+        sys.exit("AI_INFRA_TOKEN is not set")
+    query = urllib.parse.urlencode(params)
+    extra = os.environ.get("AI_INFRA_QUERY", "")
+    if extra:
+        query = "&".join(q for q in (query, extra) if q)
+    req = urllib.request.Request(base + "/api/inventory/ansible?" + query,
+                                 headers={"Authorization": "Bearer " + token, "Accept": "application/json"})
+    ctx = ssl._create_unverified_context() if os.environ.get("AI_INFRA_INSECURE") == "1" else None
+    with urllib.request.urlopen(req, context=ctx, timeout=60) as resp:
+        return json.load(resp)
+
+
+def main():
+    if len(sys.argv) == 3 and sys.argv[1] == "--host":
+        data = fetch({"host": sys.argv[2]})
+    elif len(sys.argv) == 2 and sys.argv[1] == "--list":
+        data = fetch({})
+    else:
+        sys.exit("usage: %s --list | --host <hostname>" % sys.argv[0])
+    json.dump(data, sys.stdout, indent=2)
+
+
+if __name__ == "__main__":
+    main()
+`, "__BASE_URL__", baseURL, -1)
+}
@@ -0,0 +1,34 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExpandSlurmHostlist(t *testing.T) {
+	cases := []struct {
+		expr  string
+		limit int
+		want  []string
+	}{
+		{"gpu1,node[01-03]", 0, []string{"gpu1", "node01", "node02", "node03"}},
+		{"cn[1,5-6]", 0, []string{"cn1", "cn5", "cn6"}},
+		{"rack[1-2]-n[1-2]", 0, []string{"rack1-n1", "rack1-n2", "rack2-n1", "rack2-n2"}},
+		{"node[001-100],gpu1", 2, []string{"node001", "node002"}},
+		{"node[1-2]-ib", 0, []string{"node1-ib", "node2-ib"}},
+		{"cn[a-b],cn[3-1]", 0, []string{"cna-b", "cn3-1"}},
+		{"None assigned", 0, nil},
+		{"(null)", 0, nil},
+		{"", 0, nil},
+	}
+	for _, tc := range cases {
+		if got := ExpandSlurmHostlist(tc.expr, tc.limit); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("ExpandSlurmHostlist(%q, %d) = %v, want %v", tc.expr, tc.limit, got, tc.want)
+		}
+	}
+
+	names := []string{"node01", "node02", "node03", "gpu7"}
+	if got := ExpandSlurmHostlist(CompressSlurmHostlist(names), 0); len(got) != len(names) {
+		t.Fatalf("压缩后展开应得到相同节点: %v", got)
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -628,6 +629,8 @@ type NodeConfig struct {
 	Password   string `json:"password"`
 	MinionID   string `json:"minion_id"`
 
+	JumpHosts models.SSHJumpChain `json:"jump_hosts,omitempty"` // 跳板机链（ProxyJump）
+
 	// 硬件配置
 	CPUs           int    `json:"cpus"`             // CPU 核心数
 	Memory         int    `json:"memory"`           // 内存大小 (MB)
@@ -1411,7 +1414,7 @@ func (s *SlurmService) restartSlurmdForNode(ctx context.Context, nodeName string
 		}
 	}
 	var buf bytes.Buffer
-	if err := s.startSlurmServicesViaSSH(ctx, host, port, user, password, privateKey, &buf); err != nil {
+	if err := s.startSlurmServicesViaSSH(ctx, host, port, user, password, privateKey, resolveNodeJumpHosts(s.db, node), &buf); err != nil {
 		return fmt.Errorf("远程重启 slurmd 失败: %w (输出: %s)", err, strings.TrimSpace(buf.String()))
 	}
 	log.Printf("[INFO] 节点 %s slurmd 已重新启动: %s", nodeName, strings.TrimSpace(buf.String()))
@@ -1572,7 +1575,7 @@ func (s *SlurmService) updateSlurmMasterConfig(ctx context.Context, config strin
 	// 1. 首先读取当前的基础配置（保留非节点相关的配置）
 	log.Printf("[DEBUG] 读取SLURM master现有配置...")
 	readCmd := "cat /etc/slurm/slurm.conf"
-	currentConfig, err := s.executeSSHCommandWithKey(slurmMasterHost, slurmMasterPort, slurmMasterUser, slurmMasterPassword, keyPath, readCmd)
+	currentConfig, err := s.executeSSHCommandWithKey(slurmMasterHost, slurmMasterPort, slurmMasterUser, slurmMasterPassword, keyPath, slurmMasterJumpHosts(), readCmd)
 	if err != nil {
 		log.Printf("[WARNING] 无法读取现有配置，将使用新配置: %v", err)
 	}
@@ -1586,14 +1589,14 @@ func (s *SlurmService) updateSlurmMasterConfig(ctx context.Context, config strin
 
 	// 使用here-doc方式写入，避免特殊字符问题
 	writeCmd := fmt.Sprintf("cat > /etc/slurm/slurm.conf << 'SLURM_CONFIG_EOF'\n%s\nSLURM_CONFIG_EOF", finalConfig)
-	_, err = s.executeSSHCommandWithKey(slurmMasterHost, slurmMasterPort, slurmMasterUser, slurmMasterPassword, keyPath, writeCmd)
+	_, err = s.executeSSHCommandWithKey(slurmMasterHost, slurmMasterPort, slurmMasterUser, slurmMasterPassword, keyPath, slurmMasterJumpHosts(), writeCmd)
 	if err != nil {
 		return fmt.Errorf("SSH写入配置文件失败: %w", err)
 	}
 
 	// 4. 验证配置文件已正确写入
 	verifyCmd := "wc -l /etc/slurm/slurm.conf"
-	verifyOutput, err := s.executeSSHCommandWithKey(slurmMasterHost, slurmMasterPort, slurmMasterUser, slurmMasterPassword, keyPath, verifyCmd)
+	verifyOutput, err := s.executeSSHCommandWithKey(slurmMasterHost, slurmMasterPort, slurmMasterUser, slurmMasterPassword, keyPath, slurmMasterJumpHosts(), verifyCmd)
 	if err != nil {
 		log.Printf("[WARNING] 无法验证配置文件: %v", err)
 	} else {
@@ -1654,7 +1657,7 @@ func (s *SlurmService) reloadSlurmConfig(ctx context.Context) error {
 
 	// 执行scontrol reconfigure
 	reconfigCmd := "scontrol reconfigure"
-	output, err := s.executeSSHCommandWithKey(slurmMasterHost, slurmMasterPort, slurmMasterUser, slurmMasterPassword, keyPath, reconfigCmd)
+	output, err := s.executeSSHCommandWithKey(slurmMasterHost, slurmMasterPort, slurmMasterUser, slurmMasterPassword, keyPath, slurmMasterJumpHosts(), reconfigCmd)
 	if err != nil {
 		// 如果错误是"Zero Bytes were transmitted"，这实际上是成功的（命令执行了但没有输出）
 		if strings.Contains(output, "Zero Bytes were transmitted") || strings.TrimSpace(output) == "" {
@@ -1668,7 +1671,7 @@ func (s *SlurmService) reloadSlurmConfig(ctx context.Context) error {
 
 	// 验证配置重新加载成功
 	verifyCmd := "scontrol ping"
-	verifyOutput, err := s.executeSSHCommandWithKey(slurmMasterHost, slurmMasterPort, slurmMasterUser, slurmMasterPassword, keyPath, verifyCmd)
+	verifyOutput, err := s.executeSSHCommandWithKey(slurmMasterHost, slurmMasterPort, slurmMasterUser, slurmMasterPassword, keyPath, slurmMasterJumpHosts(), verifyCmd)
 	if err != nil {
 		log.Printf("[WARNING] 无法验证slurmctld状态: %v", err)
 	} else {
@@ -1684,7 +1687,7 @@ func (s *SlurmService) executeSlurmCommand(ctx context.Context, command string)
 	slurmMasterHost, slurmMasterPort, slurmMasterUser, slurmMasterPassword, keyPath := s.getSlurmMasterSSHConfig()
 
 	// 使用密钥认证连接slurm-master
-	return s.executeSSHCommandWithKey(slurmMasterHost, slurmMasterPort, slurmMasterUser, slurmMasterPassword, keyPath, command)
+	return s.executeSSHCommandWithKey(slurmMasterHost, slurmMasterPort, slurmMasterUser, slurmMasterPassword, keyPath, slurmMasterJumpHosts(), command)
 }
 
 // ExecuteSlurmCommand 公开的SLURM命令执行方法（供Controller调用）
@@ -1694,15 +1697,15 @@ func (s *SlurmService) ExecuteSlurmCommand(ctx context.Context, command string)
 
 // executeSSHCommand 执行SSH命令的辅助函数（支持密码和密钥认证）
 func (s *SlurmService) executeSSHCommand(host string, port int, user, password, command string) (string, error) {
-	return s.executeSSHCommandWithKey(host, port, user, password, "", command)
+	return s.executeSSHCommandWithKey(host, port, user, password, "", nil, command)
 }
 
-// executeSSHCommandWithKey 执行SSH命令（支持密码和密钥认证）
-func (s *SlurmService) executeSSHCommandWithKey(host string, port int, user, password, privateKey, command string) (string, error) {
+// executeSSHCommandWithKey 执行SSH命令（支持密码和密钥认证，可经由跳板机链）
+func (s *SlurmService) executeSSHCommandWithKey(host string, port int, user, password, privateKey string, jumps models.SSHJumpChain, command string) (string, error) {
 	// 创建SSH客户端配置
 	config := &ssh.ClientConfig{
 		User:            user,
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(), // 生产环境应使用正确的host key验证
+		HostKeyCallback: KnownHostsCallback(),
 		Timeout:         30 * time.Second,
 	}
 
@@ -1740,6 +1743,7 @@ func (s *SlurmService) executeSSHCommandWithKey(host string, port int, user, pas
 	if password != "" {
 		authMethods = append(authMethods, ssh.Password(password))
 	}
+	authMethods = withSSHCAAuth(authMethods)
 
 	if len(authMethods) == 0 {
 		return "", fmt.Errorf("未提供任何认证方式（密码或私钥）")
@@ -1747,9 +1751,8 @@ func (s *SlurmService) executeSSHCommandWithKey(host string, port int, user, pas
 
 	config.Auth = authMethods
 
-	// 连接SSH服务器
-	addr := fmt.Sprintf("%s:%d", host, port)
-	client, err := ssh.Dial("tcp", addr, config)
+	// 从连接池获取SSH连接（同一主机的多条命令复用连接）
+	client, err := GetSSHClientPool().DialVia(NewSSHPoolKey(host, port, user, password, sshKeyCredential(trimmedKey)), jumps, config)
 	if err != nil {
 		return "", fmt.Errorf("SSH连接失败: %w", err)
 	}
@@ -1944,6 +1947,7 @@ func (s *SlurmService) generateSlurmConfig(nodes []models.SlurmNode) string {
 
 	// 添加节点定义
 	computeNodes := []string{}
+	minionToNode := map[string]string{} // minion ID / 主机名 -> NodeName，用于动态分组分区
 	log.Printf("[DEBUG] generateSlurmConfig: 处理 %d 个节点", len(nodes))
 	for i := range nodes {
 		node := &nodes[i]
@@ -2005,6 +2009,11 @@ func (s *SlurmService) generateSlurmConfig(nodes []models.SlurmNode) string {
 			builder.WriteString(nodeConfig)
 			builder.WriteString("\n")
 			computeNodes = append(computeNodes, nodeName)
+			for _, key := range []string{node.SaltMinionID, node.Host, nodeName} {
+				if key != "" {
+					minionToNode[key] = nodeName
+				}
+			}
 			log.Printf("[DEBUG] 已添加计算节点: %s (地址: %s, CPU:%d, 内存:%dMB, GPU:%d, XPU:%d)",
 				nodeName, node.Host, cpus, memory, node.GPUs, node.XPUs)
 		} else {
@@ -2015,8 +2024,8 @@ func (s *SlurmService) generateSlurmConfig(nodes []models.SlurmNode) string {
 	// 添加分区配置
 	log.Printf("[DEBUG] 计算节点列表: %v", computeNodes)
 	if len(computeNodes) > 0 {
-		partitionConfig := fmt.Sprintf("PartitionName=compute Nodes=%s Default=YES MaxTime=INFINITE State=UP",
-			strings.Join(computeNodes, ","))
+		partitionConfig := fmt.Sprintf("PartitionName=%s Nodes=%s Default=YES MaxTime=INFINITE State=UP",
+			defaultSlurmPartition, strings.Join(computeNodes, ","))
 		builder.WriteString(partitionConfig)
 		builder.WriteString("\n")
 		log.Printf("[DEBUG] 已添加分区配置: %s", partitionConfig)
@@ -2024,9 +2033,45 @@ func (s *SlurmService) generateSlurmConfig(nodes []models.SlurmNode) string {
 		log.Printf("[WARNING] 没有计算节点，跳过分区配置")
 	}
 
+	// 绑定了 Slurm 分区的动态分组：分区成员随分组规则自动变化
+	if dynamicService := GetMinionDynamicGroupService(); dynamicService != nil && len(minionToNode) > 0 {
+		partitions := dynamicService.SlurmPartitionMembers()
+		names := make([]string, 0, len(partitions))
+		for name := range partitions {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, partition := range names {
+			if ValidateSlurmPartitionName(partition) != nil {
+				log.Printf("[WARNING] 跳过无效的动态分区名: %q", partition)
+				continue
+			}
+			seen := map[string]bool{}
+			var partitionNodes []string
+			for _, minionID := range partitions[partition] {
+				if nodeName, ok := minionToNode[minionID]; ok && !seen[nodeName] {
+					seen[nodeName] = true
+					partitionNodes = append(partitionNodes, nodeName)
+				}
+			}
+			if len(partitionNodes) == 0 {
+				log.Printf("[WARNING] 动态分区 %s 没有匹配的计算节点，跳过", partition)
+				continue
+			}
+			partitionConfig := fmt.Sprintf("PartitionName=%s Nodes=%s MaxTime=INFINITE State=UP",
+				partition, strings.Join(partitionNodes, ","))
+			builder.WriteString(partitionConfig)
+			builder.WriteString("\n")
+			log.Printf("[DEBUG] 已添加动态分区配置: %s", partitionConfig)
+		}
+	}
+
 	return builder.String()
 }
 
+// defaultSlurmPartition generateSlurmConfig 生成的包含全部计算节点的默认分区
+const defaultSlurmPartition = "compute"
+
 func (s *SlurmService) loadBaseSlurmConfig() string {
 	candidates := s.resolveSlurmConfigTemplatePaths()
 	userProvided := strings.TrimSpace(os.Getenv("SLURM_BASE_CONFIG_PATH"))
@@ -2187,7 +2232,7 @@ MaxArraySize=10000
 
 func (s *SlurmService) fetchRemoteSlurmConfig() (string, error) {
 	slurmMasterHost, slurmMasterPort, slurmMasterUser, slurmMasterPassword, keyPath := s.getSlurmMasterSSHConfig()
-	return s.executeSSHCommandWithKey(slurmMasterHost, slurmMasterPort, slurmMasterUser, slurmMasterPassword, keyPath, "cat /etc/slurm/slurm.conf")
+	return s.executeSSHCommandWithKey(slurmMasterHost, slurmMasterPort, slurmMasterUser, slurmMasterPassword, keyPath, slurmMasterJumpHosts(), "cat /etc/slurm/slurm.conf")
 }
 
 // StartAutoRegisterLoop 自动同步已有SLURM节点到数据库并刷新配置
@@ -2390,15 +2435,13 @@ func (s *SlurmService) ScaleDown(ctx context.Context, nodeIDs []string) (*Scalin
 
 	// 建立SSH连接
 	sshConfig := &ssh.ClientConfig{
-		User: masterUser,
-		Auth: []ssh.AuthMethod{
-			ssh.Password(masterPassword),
-		},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		User:            masterUser,
+		Auth:            withSSHCAAuth([]ssh.AuthMethod{ssh.Password(masterPassword)}),
+		HostKeyCallback: KnownHostsCallback(),
 		Timeout:         10 * time.Second,
 	}
 
-	client, err := ssh.Dial("tcp", fmt.Sprintf("%s:%d", masterHost, masterPort), sshConfig)
+	client, err := GetSSHClientPool().DialVia(NewSSHPoolKey(masterHost, masterPort, masterUser, masterPassword), slurmMasterJumpHosts(), sshConfig)
 	if err != nil {
 		return nil, fmt.Errorf("连接SLURM Master失败: %v", err)
 	}
@@ -2801,11 +2844,11 @@ func (s *SlurmService) getJWTToken(ctx context.Context) (string, error) {
 	sshConfig := &ssh.ClientConfig{
 		User:            masterUser,
 		Auth:            authMethods,
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		HostKeyCallback: KnownHostsCallback(),
 		Timeout:         10 * time.Second,
 	}
 
-	client, err := ssh.Dial("tcp", fmt.Sprintf("%s:%d", masterHost, masterPort), sshConfig)
+	client, err := GetSSHClientPool().DialVia(NewSSHPoolKey(masterHost, masterPort, masterUser, os.Getenv("SLURM_MASTER_PASSWORD"), sshKeyCredential(keyPath)), slurmMasterJumpHosts(), sshConfig)
 	if err != nil {
 		return "", fmt.Errorf("连接SLURM Master失败: %v", err)
 	}
@@ -3355,7 +3398,7 @@ func (s *SlurmService) getSlurmMasterConfig(ctx context.Context) ([]byte, error)
 	// 使用SSH连接到slurm-master读取配置文件
 	masterHost, masterPort, _, _, keyPath := s.getSlurmMasterSSHConfig()
 
-	output, err := s.executeSSHCommandWithKey(masterHost, masterPort, "root", "", keyPath, "cat /etc/slurm/slurm.conf")
+	output, err := s.executeSSHCommandWithKey(masterHost, masterPort, "root", "", keyPath, slurmMasterJumpHosts(), "cat /etc/slurm/slurm.conf")
 	if err != nil {
 		return nil, fmt.Errorf("读取slurm.conf失败: %v", err)
 	}
@@ -3367,7 +3410,7 @@ func (s *SlurmService) getMungeKey(ctx context.Context) ([]byte, error) {
 	// 使用SSH连接到slurm-master读取munge密钥
 	masterHost, masterPort, _, _, keyPath := s.getSlurmMasterSSHConfig()
 
-	output, err := s.executeSSHCommandWithKey(masterHost, masterPort, "root", "", keyPath, "cat /etc/munge/munge.key")
+	output, err := s.executeSSHCommandWithKey(masterHost, masterPort, "root", "", keyPath, slurmMasterJumpHosts(), "cat /etc/munge/munge.key")
 	if err != nil {
 		return nil, err
 	}
@@ -3748,7 +3791,7 @@ func (s *SlurmService) startSlurmServices(ctx context.Context, req InstallSlurmN
 }
 
 // executeScriptViaSSH 通过SSH执行脚本（支持密码和密钥认证）
-func (s *SlurmService) executeScriptViaSSH(ctx context.Context, host string, port int, user, password, privateKey, scriptPath string) (string, error) {
+func (s *SlurmService) executeScriptViaSSH(ctx context.Context, host string, port int, user, password, privateKey string, jumps models.SSHJumpChain, scriptPath string) (string, error) {
 	// 读取脚本内容
 	scriptContent, err := os.ReadFile(scriptPath)
 	if err != nil {
@@ -3756,16 +3799,16 @@ func (s *SlurmService) executeScriptViaSSH(ctx context.Context, host string, por
 	}
 
 	// 通过SSH执行脚本
-	return s.executeSSHCommandWithKey(host, port, user, password, privateKey, string(scriptContent))
+	return s.executeSSHCommandWithKey(host, port, user, password, privateKey, jumps, string(scriptContent))
 }
 
 // startSlurmServicesViaSSH 通过真实SSH启动SLURM服务（非docker exec）
-func (s *SlurmService) startSlurmServicesViaSSH(ctx context.Context, host string, port int, user, password, privateKey string, logWriter io.Writer) error {
+func (s *SlurmService) startSlurmServicesViaSSH(ctx context.Context, host string, port int, user, password, privateKey string, jumps models.SSHJumpChain, logWriter io.Writer) error {
 	fmt.Fprintf(logWriter, "[INFO] 通过SSH启动 %s 上的服务\n", host)
 
 	// 执行启动脚本
 	scriptPath := "/root/scripts/start-slurmd.sh"
-	output, err := s.executeScriptViaSSH(ctx, host, port, user, password, privateKey, scriptPath)
+	output, err := s.executeScriptViaSSH(ctx, host, port, user, password, privateKey, jumps, scriptPath)
 
 	if err != nil {
 		fmt.Fprintf(logWriter, "[ERROR] 执行启动脚本失败: %v\n", err)
@@ -3778,15 +3821,15 @@ func (s *SlurmService) startSlurmServicesViaSSH(ctx context.Context, host string
 }
 
 // checkSlurmServicesViaSSH 通过SSH检查SLURM服务状态
-func (s *SlurmService) checkSlurmServicesViaSSH(ctx context.Context, host string, port int, user, password, privateKey string) (string, error) {
+func (s *SlurmService) checkSlurmServicesViaSSH(ctx context.Context, host string, port int, user, password, privateKey string, jumps models.SSHJumpChain) (string, error) {
 	scriptPath := "/root/scripts/check-slurmd.sh"
-	return s.executeScriptViaSSH(ctx, host, port, user, password, privateKey, scriptPath)
+	return s.executeScriptViaSSH(ctx, host, port, user, password, privateKey, jumps, scriptPath)
 }
 
 // stopSlurmServicesViaSSH 通过SSH停止SLURM服务
-func (s *SlurmService) stopSlurmServicesViaSSH(ctx context.Context, host string, port int, user, password, privateKey string) (string, error) {
+func (s *SlurmService) stopSlurmServicesViaSSH(ctx context.Context, host string, port int, user, password, privateKey string, jumps models.SSHJumpChain) (string, error) {
 	scriptPath := "/root/scripts/stop-slurmd.sh"
-	return s.executeScriptViaSSH(ctx, host, port, user, password, privateKey, scriptPath)
+	return s.executeScriptViaSSH(ctx, host, port, user, password, privateKey, jumps, scriptPath)
 }
 
 // detectNodeOSType 检测节点的操作系统类型
@@ -0,0 +1,365 @@
+package services
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/database"
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/utils"
+	"golang.org/x/crypto/ssh"
+	"gorm.io/gorm"
+)
+
+const (
+	// SSHCAAutomationPrincipal 平台自动化证书的主体，节点上任意登录用户都接受
+	SSHCAAutomationPrincipal = "ai-infra-automation"
+	// SSHCAScopeAll 所有安装了 CA 信任的节点都属于该范围
+	SSHCAScopeAll = "all"
+
+	defaultSSHUserCertTTL       = 8 * time.Hour
+	maxSSHUserCertTTL           = 24 * time.Hour
+	sshAutomationCertTTL        = 30 * time.Minute
+	sshAutomationCertRenewAhead = 5 * time.Minute
+	sshCertClockSkew            = 5 * time.Minute
+)
+
+// ErrNoSSHPrincipals 用户没有任何可登录节点的授权
+var ErrNoSSHPrincipals = errors.New("no SSH access granted: connect permission on at least one cluster is required")
+
+var sshLoginNamePattern = regexp.MustCompile(`^[a-z_][a-z0-9_.-]{0,31}$`)
+
+// SSHCAClusterScope 集群对应的授权范围
+func SSHCAClusterScope(clusterID uint) string {
+	return "cluster-" + strconv.FormatUint(uint64(clusterID), 10)
+}
+
+// SSHCAPrincipal 证书主体：以 login 身份登录 scope 范围内的节点
+func SSHCAPrincipal(login, scope string) string {
+	return login + "@" + scope
+}
+
+// SSHCAService SSH 用户证书颁发机构：CA 密钥保存在 KeyVault，签发短期用户证书
+type SSHCAService struct {
+	db    *gorm.DB
+	vault *KeyVaultService
+	mu    sync.Mutex
+
+	ca               ssh.Signer
+	automation       ssh.Signer
+	automationExpiry time.Time
+
+	userCertTTL time.Duration
+	distribute  bool
+}
+
+var (
+	sshCAServiceInstance *SSHCAService
+	sshCAServiceOnce     sync.Once
+)
+
+// NewSSHCAService 创建 SSH CA 服务（单例）
+// SSH_CA_USER_CERT_TTL 控制用户证书默认有效期，SSH_CA_DISTRIBUTE=false 时安装流程不下发 CA 信任
+func NewSSHCAService(db *gorm.DB) *SSHCAService {
+	sshCAServiceOnce.Do(func() {
+		ttl := defaultSSHUserCertTTL
+		if v := os.Getenv("SSH_CA_USER_CERT_TTL"); v != "" {
+			if d, err := time.ParseDuration(v); err == nil && d > 0 && d <= maxSSHUserCertTTL {
+				ttl = d
+			} else {
+				log.Printf("[SSHCA] 无效的 SSH_CA_USER_CERT_TTL=%q，使用 %s", v, ttl)
+			}
+		}
+		sshCAServiceInstance = &SSHCAService{
+			db:          db,
+			vault:       &KeyVaultService{db: db, encryptionService: utils.GetEncryptionService()},
+			userCertTTL: ttl,
+			distribute:  os.Getenv("SSH_CA_DISTRIBUTE") != "false",
+		}
+	})
+	return sshCAServiceInstance
+}
+
+// GetSSHCAService 获取 SSH CA 服务实例（数据库就绪后懒加载）
+func GetSSHCAService() *SSHCAService {
+	if sshCAServiceInstance == nil && database.DB != nil {
+		return NewSSHCAService(database.DB)
+	}
+	return sshCAServiceInstance
+}
+
+// signer 获取 CA 签名器，首次使用时在 KeyVault 中生成
+func (s *SSHCAService) signer() (ssh.Signer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ca != nil {
+		return s.ca, nil
+	}
+	ca, err := s.vault.EnsureSSHUserCA(0)
+	if err != nil {
+		return nil, fmt.Errorf("load SSH user CA: %w", err)
+	}
+	s.ca = ca
+	return ca, nil
+}
+
+// PublicKey 返回 CA 公钥（authorized_keys 格式），用于节点的 TrustedUserCAKeys
+func (s *SSHCAService) PublicKey() (string, error) {
+	ca, err := s.signer()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(ssh.MarshalAuthorizedKey(ca.PublicKey()))), nil
+}
+
+// Rotate 轮换 CA 密钥。已签发证书立即失效，节点需重新下发 CA 公钥
+func (s *SSHCAService) Rotate(userID uint, username string) (string, error) {
+	ca, err := s.vault.RotateSSHUserCA(userID)
+	if err != nil {
+		return "", err
+	}
+	s.mu.Lock()
+	s.ca = ca
+	s.automation = nil
+	s.mu.Unlock()
+
+	fingerprint := ssh.FingerprintSHA256(ca.PublicKey())
+	GetAuditService().NewAuditEntry(models.AuditCategorySecurity, models.AuditActionSSHCARotate).
+		WithUser(userID, username, "").
+		WithResource("ssh_ca", "user_ca", fingerprint).
+		WithStatus(models.AuditStatusSuccess).
+		WithSeverity(models.AuditSeverityCritical).
+		WithTags("ssh", "ca").
+		SaveAsync()
+	return strings.TrimSpace(string(ssh.MarshalAuthorizedKey(ca.PublicKey()))), nil
+}
+
+// UserPrincipals 根据平台用户名和集群权限计算证书主体
+// 平台管理员可在所有节点以 root 和本人身份登录；集群授权 connect 可以本人身份登录该集群节点，授权 admin 还可登录 root
+func (s *SSHCAService) UserPrincipals(ctx context.Context, userID uint, username string, isAdmin bool) ([]string, error) {
+	if !sshLoginNamePattern.MatchString(username) {
+		return nil, fmt.Errorf("username %q is not a valid login name for SSH certificates", username)
+	}
+
+	var principals []string
+	seen := make(map[string]bool)
+	add := func(login, scope string) {
+		p := SSHCAPrincipal(login, scope)
+		if !seen[p] {
+			seen[p] = true
+			principals = append(principals, p)
+		}
+	}
+
+	if isAdmin {
+		add(username, SSHCAScopeAll)
+		add("root", SSHCAScopeAll)
+	}
+
+	perms, err := NewClusterPermissionService(s.db).GetUserSlurmPermissions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, perm := range perms {
+		if perm.HasVerb(models.VerbConnect) {
+			add(username, SSHCAClusterScope(perm.ClusterID))
+		}
+		if perm.HasVerb(models.VerbAdmin) {
+			add("root", SSHCAClusterScope(perm.ClusterID))
+		}
+	}
+
+	if len(principals) == 0 {
+		return nil, ErrNoSSHPrincipals
+	}
+	return principals, nil
+}
+
+// SignUserKey 为用户公钥签发短期证书，主体由平台用户名和集群权限决定
+func (s *SSHCAService) SignUserKey(ctx context.Context, req *models.SSHUserCertRequest, userID uint, username string, isAdmin bool) (*models.SSHUserCertificate, error) {
+	pub, _, _, _, err := ssh.ParseAuthorizedKey([]byte(strings.TrimSpace(req.PublicKey)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key: %v", err)
+	}
+	if _, ok := pub.(*ssh.Certificate); ok {
+		return nil, errors.New("public key must be a plain key, not a certificate")
+	}
+
+	ttl := s.userCertTTL
+	if req.TTL != "" {
+		d, err := time.ParseDuration(req.TTL)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid ttl %q", req.TTL)
+		}
+		ttl = d
+	}
+	if ttl > maxSSHUserCertTTL {
+		ttl = maxSSHUserCertTTL
+	}
+
+	principals, err := s.UserPrincipals(ctx, userID, username, isAdmin)
+	if err != nil {
+		return nil, err
+	}
+	ca, err := s.signer()
+	if err != nil {
+		return nil, err
+	}
+
+	keyID := fmt.Sprintf("%s/%d", username, time.Now().Unix())
+	cert, err := signSSHUserCertificate(ca, pub, keyID, principals, ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	GetAuditService().NewAuditEntry(models.AuditCategorySecurity, models.AuditActionSSHCertSign).
+		WithUser(userID, username, "").
+		WithResource("ssh_certificate", strconv.FormatUint(cert.Serial, 10), keyID).
+		WithMetadata(map[string]interface{}{
+			"principals":   principals,
+			"fingerprint":  ssh.FingerprintSHA256(pub),
+			"valid_before": time.Unix(int64(cert.ValidBefore), 0),
+		}).
+		WithStatus(models.AuditStatusSuccess).
+		WithSeverity(models.AuditSeverityWarning).
+		WithTags("ssh", "ca").
+		SaveAsync()
+
+	return &models.SSHUserCertificate{
+		Certificate: strings.TrimSpace(string(ssh.MarshalAuthorizedKey(cert))),
+		KeyID:       keyID,
+		Serial:      cert.Serial,
+		Principals:  principals,
+		ValidAfter:  time.Unix(int64(cert.ValidAfter), 0),
+		ValidBefore: time.Unix(int64(cert.ValidBefore), 0),
+		CAPublicKey: strings.TrimSpace(string(ssh.MarshalAuthorizedKey(ca.PublicKey()))),
+	}, nil
+}
+
+// AutomationSigner 返回平台自动化使用的证书签名器（临时密钥 + 短期证书，临近过期自动续签）
+func (s *SSHCAService) AutomationSigner() (ssh.Signer, error) {
+	ca, err := s.signer()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.automation != nil && time.Until(s.automationExpiry) > sshAutomationCertRenewAhead {
+		return s.automation, nil
+	}
+
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	keySigner, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		return nil, err
+	}
+	keyID := fmt.Sprintf("automation/%d", time.Now().Unix())
+	cert, err := signSSHUserCertificate(ca, keySigner.PublicKey(), keyID, []string{SSHCAAutomationPrincipal}, sshAutomationCertTTL)
+	if err != nil {
+		return nil, err
+	}
+	certSigner, err := ssh.NewCertSigner(cert, keySigner)
+	if err != nil {
+		return nil, err
+	}
+	s.automation = certSigner
+	s.automationExpiry = time.Unix(int64(cert.ValidBefore), 0)
+	return certSigner, nil
+}
+
+// TrustScript 生成在节点上安装 CA 信任的脚本，scopes 为节点所属集群范围（始终包含 all）
+func (s *SSHCAService) TrustScript(sudoPrefix string, scopes ...string) (string, error) {
+	publicKey, err := s.PublicKey()
+	if err != nil {
+		return "", err
+	}
+	return GetScriptLoader().GenerateSSHCATrustScript(SSHCATrustParams{
+		CAPublicKey:         publicKey,
+		AutomationPrincipal: SSHCAAutomationPrincipal,
+		Scopes:              append([]string{SSHCAScopeAll}, scopes...),
+		SudoPrefix:          sudoPrefix,
+	})
+}
+
+// InstallTrust 在已连接的节点上下发 CA 公钥并配置 sshd（SSH_CA_DISTRIBUTE=false 时跳过）
+func (s *SSHCAService) InstallTrust(client *ssh.Client, sudoPrefix string, scopes ...string) error {
+	if !s.distribute {
+		return nil
+	}
+	script, err := s.TrustScript(sudoPrefix, scopes...)
+	if err != nil {
+		return err
+	}
+	session, err := client.NewSession()
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+	output, err := session.CombinedOutput(script)
+	if err != nil {
+		return fmt.Errorf("configure SSH CA trust: %v: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// signSSHUserCertificate 用 CA 为公钥签发用户证书
+func signSSHUserCertificate(ca ssh.Signer, pub ssh.PublicKey, keyID string, principals []string, ttl time.Duration) (*ssh.Certificate, error) {
+	var serial [8]byte
+	if _, err := rand.Read(serial[:]); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	cert := &ssh.Certificate{
+		Key:             pub,
+		Serial:          binary.BigEndian.Uint64(serial[:]),
+		CertType:        ssh.UserCert,
+		KeyId:           keyID,
+		ValidPrincipals: principals,
+		ValidAfter:      uint64(now.Add(-sshCertClockSkew).Unix()),
+		ValidBefore:     uint64(now.Add(ttl).Unix()),
+		Permissions: ssh.Permissions{
+			Extensions: map[string]string{
+				"permit-pty":              "",
+				"permit-port-forwarding":  "",
+				"permit-agent-forwarding": "",
+				"permit-user-rc":          "",
+			},
+		},
+	}
+	if err := cert.SignCert(rand.Reader, ca); err != nil {
+		return nil, fmt.Errorf("sign SSH certificate: %w", err)
+	}
+	return cert, nil
+}
+
+// withSSHCAAuth 追加平台自动化证书认证方式，使未保存密码/私钥的节点也能由平台登录
+// CA 不可用时不提供签名器，不影响其他认证方式
+func withSSHCAAuth(methods []ssh.AuthMethod) []ssh.AuthMethod {
+	svc := GetSSHCAService()
+	if svc == nil {
+		return methods
+	}
+	return append(methods, ssh.PublicKeysCallback(func() ([]ssh.Signer, error) {
+		signer, err := svc.AutomationSigner()
+		if err != nil {
+			log.Printf("[SSHCA] 自动化证书不可用: %v", err)
+			return nil, nil
+		}
+		return []ssh.Signer{signer}, nil
+	}))
+}
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
+	"golang.org/x/crypto/ssh"
+)
+
+func newTestSSHCA(t *testing.T) *SSHCAService {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &SSHCAService{ca: ca, userCertTTL: time.Hour}
+}
+
+// certAuthServer 启动只接受指定 CA 签发证书的 SSH 服务端，principal 为登录所需的证书主体
+func certAuthServer(t *testing.T, ca ssh.PublicKey, principal string) (string, int) {
+	_, hostPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostSigner, err := ssh.NewSignerFromKey(hostPriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checker := &ssh.CertChecker{
+		IsUserAuthority: func(auth ssh.PublicKey) bool {
+			return bytes.Equal(auth.Marshal(), ca.Marshal())
+		},
+	}
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			cert, ok := key.(*ssh.Certificate)
+			if !ok {
+				return nil, fmt.Errorf("certificate required")
+			}
+			// 模拟 AuthorizedPrincipalsCommand：按主体而非登录名校验
+			return checker.Authenticate(principalConn{conn, principal}, cert)
+		},
+	}
+	config.AddHostKey(hostSigner)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveTestSSHConn(conn, config, false)
+		}
+	}()
+	addr := listener.Addr().(*net.TCPAddr)
+	return addr.IP.String(), addr.Port
+}
+
+type principalConn struct {
+	ssh.ConnMetadata
+	principal string
+}
+
+func (c principalConn) User() string { return c.principal }
+
+func TestSignSSHUserCertificate(t *testing.T) {
+	svc := newTestSSHCA(t)
+	_, userPriv, _ := ed25519.GenerateKey(rand.Reader)
+	userSigner, _ := ssh.NewSignerFromKey(userPriv)
+
+	principals := []string{SSHCAPrincipal("alice", SSHCAClusterScope(3)), SSHCAPrincipal("root", SSHCAClusterScope(3))}
+	cert, err := signSSHUserCertificate(svc.ca, userSigner.PublicKey(), "alice/1", principals, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cert.CertType != ssh.UserCert || cert.KeyId != "alice/1" {
+		t.Fatalf("unexpected certificate: type=%d id=%s", cert.CertType, cert.KeyId)
+	}
+	if principals[0] != "alice@cluster-3" {
+		t.Fatalf("principal = %q", principals[0])
+	}
+
+	checker := &ssh.CertChecker{
+		IsUserAuthority: func(auth ssh.PublicKey) bool {
+			return bytes.Equal(auth.Marshal(), svc.ca.PublicKey().Marshal())
+		},
+	}
+	if err := checker.CheckCert("root@cluster-3", cert); err != nil {
+		t.Fatalf("证书应对授权主体有效: %v", err)
+	}
+	if err := checker.CheckCert("root@cluster-4", cert); err == nil {
+		t.Fatal("证书不应对其他集群有效")
+	}
+
+	// 过期证书被拒绝
+	checker.Clock = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	if err := checker.CheckCert("alice@cluster-3", cert); err == nil {
+		t.Fatal("过期证书应校验失败")
+	}
+}
+
+func TestSSHCAAutomationSignerLogin(t *testing.T) {
+	svc := newTestSSHCA(t)
+	host, port := certAuthServer(t, svc.ca.PublicKey(), SSHCAAutomationPrincipal)
+
+	signer, err := svc.AutomationSigner()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := svc.AutomationSigner(); again != signer {
+		t.Fatal("未临近过期时应复用自动化证书")
+	}
+
+	knownHosts := &SSHKnownHostsService{mode: models.SSHHostKeyModeInsecure}
+	client, err := ssh.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(port)), &ssh.ClientConfig{
+		User:            "ubuntu",
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+		HostKeyCallback: knownHosts.Verify,
+		Timeout:         5 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("自动化证书登录失败: %v", err)
+	}
+	client.Close()
+
+	// 其他 CA 签发的证书被拒绝
+	other := newTestSSHCA(t)
+	otherSigner, _ := other.AutomationSigner()
+	if _, err := ssh.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(port)), &ssh.ClientConfig{
+		User:            "ubuntu",
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(otherSigner)},
+		HostKeyCallback: knownHosts.Verify,
+		Timeout:         5 * time.Second,
+	}); err == nil {
+		t.Fatal("非受信 CA 签发的证书应登录失败")
+	}
+}
+
+func TestSSHCATrustScript(t *testing.T) {
+	svc := newTestSSHCA(t)
+	script, err := svc.TrustScript("sudo ", SSHCAClusterScope(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey, _ := svc.PublicKey()
+	for _, want := range []string{
+		publicKey,
+		"TrustedUserCAKeys $CA_FILE",
+		"AuthorizedPrincipalsCommand $PRINCIPALS_CMD %u",
+		`echo "` + SSHCAAutomationPrincipal + `"`,
+		"for scope in all cluster-7; do",
+		`SUDO="sudo "`,
+	} {
+		if !strings.Contains(script, want) {
+			t.Fatalf("信任脚本缺少 %q:\n%s", want, script)
+		}
+	}
+}
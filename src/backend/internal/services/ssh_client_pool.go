@@ -0,0 +1,438 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
+	"golang.org/x/crypto/ssh"
+)
+
+// SSHPoolKey 连接池键：同一主机、端口、用户与凭据共享连接
+type SSHPoolKey struct {
+	Host       string
+	Port       int
+	User       string
+	Credential string // 凭据摘要（不保存明文）
+	Via        string // 跳板机链，经不同跳板机的连接不共享
+}
+
+func (k SSHPoolKey) String() string {
+	if k.Via != "" {
+		return fmt.Sprintf("%s@%s:%d via %s", k.User, k.Host, k.Port, k.Via)
+	}
+	return fmt.Sprintf("%s@%s:%d", k.User, k.Host, k.Port)
+}
+
+// NewSSHPoolKey 由连接参数生成连接池键，credentials 为密码或私钥凭据（私钥路径须先经 sshKeyCredential 转换）
+func NewSSHPoolKey(host string, port int, user string, credentials ...string) SSHPoolKey {
+	h := sha256.New()
+	for _, c := range credentials {
+		h.Write([]byte(c))
+		h.Write([]byte{0})
+	}
+	return SSHPoolKey{Host: host, Port: port, User: user, Credential: hex.EncodeToString(h.Sum(nil))[:16]}
+}
+
+// sshKeyCredential 私钥在连接池键中的凭据：按公钥指纹区分，同一路径下的私钥轮换后不再复用旧连接
+// keyOrPath 为私钥内容或路径；无法解析（如带口令）时按私钥内容，无法读取时按原值
+func sshKeyCredential(keyOrPath string) string {
+	keyOrPath = strings.TrimSpace(keyOrPath)
+	if keyOrPath == "" {
+		return ""
+	}
+	data := []byte(keyOrPath)
+	if !strings.HasPrefix(keyOrPath, "-----BEGIN") {
+		content, err := os.ReadFile(keyOrPath)
+		if err != nil {
+			return keyOrPath
+		}
+		data = content
+	}
+	if signer, err := ssh.ParsePrivateKey(data); err == nil {
+		return ssh.FingerprintSHA256(signer.PublicKey())
+	}
+	return string(data)
+}
+
+// ErrSSHPoolExhausted 连接池已满且在等待时间内没有可用连接
+var ErrSSHPoolExhausted = errors.New("ssh client pool exhausted")
+
+// pooledConn 池中的一条 SSH 连接（可同时承载多个 session）
+type pooledConn struct {
+	key      SSHPoolKey
+	client   *ssh.Client
+	refs     int
+	lastUsed time.Time
+	broken   bool
+}
+
+// PooledSSHClient 从连接池借出的 SSH 客户端
+// Close 仅归还连接，不会断开底层 TCP 连接；可像 *ssh.Client 一样创建 session
+type PooledSSHClient struct {
+	*ssh.Client
+	pool *SSHClientPool
+	conn *pooledConn
+	once sync.Once
+}
+
+// Close 归还连接
+func (c *PooledSSHClient) Close() error {
+	c.once.Do(func() { c.pool.release(c.conn, false) })
+	return nil
+}
+
+// Discard 归还并丢弃连接（调用方确认连接已不可用时使用）
+func (c *PooledSSHClient) Discard() {
+	c.once.Do(func() { c.pool.release(c.conn, true) })
+}
+
+// SSHClientPool 有界 SSH 客户端连接池
+// 按主机/用户/凭据复用连接，单连接上多路复用 session，空闲连接定时回收并做保活探测
+type SSHClientPool struct {
+	mu                   sync.Mutex
+	conns                map[SSHPoolKey][]*pooledConn
+	total                int // 已建立和正在建立的连接数
+	released             chan struct{}
+	maxClients           int
+	maxSessionsPerClient int
+	idleTimeout          time.Duration
+	probeAfter           time.Duration
+	acquireTimeout       time.Duration
+
+	startOnce sync.Once
+	stopOnce  sync.Once
+	stopCh    chan struct{}
+}
+
+var (
+	sshClientPoolInstance *SSHClientPool
+	sshClientPoolOnce     sync.Once
+)
+
+// GetSSHClientPool 获取全局 SSH 连接池（首次调用时创建并启动回收协程）
+// 环境变量：SSH_POOL_MAX_CLIENTS、SSH_POOL_MAX_SESSIONS、SSH_POOL_IDLE_TIMEOUT
+func GetSSHClientPool() *SSHClientPool {
+	sshClientPoolOnce.Do(func() {
+		sshClientPoolInstance = NewSSHClientPool(
+			poolEnvInt("SSH_POOL_MAX_CLIENTS", 256),
+			poolEnvInt("SSH_POOL_MAX_SESSIONS", 8),
+			poolEnvDuration("SSH_POOL_IDLE_TIMEOUT", 5*time.Minute),
+		)
+		sshClientPoolInstance.Start()
+	})
+	return sshClientPoolInstance
+}
+
+// NewSSHClientPool 创建 SSH 连接池
+// maxSessionsPerClient 应不大于服务端 sshd 的 MaxSessions（默认 10）
+func NewSSHClientPool(maxClients, maxSessionsPerClient int, idleTimeout time.Duration) *SSHClientPool {
+	if maxClients <= 0 {
+		maxClients = 256
+	}
+	if maxSessionsPerClient <= 0 {
+		maxSessionsPerClient = 8
+	}
+	if idleTimeout <= 0 {
+		idleTimeout = 5 * time.Minute
+	}
+	return &SSHClientPool{
+		conns:                make(map[SSHPoolKey][]*pooledConn),
+		released:             make(chan struct{}),
+		maxClients:           maxClients,
+		maxSessionsPerClient: maxSessionsPerClient,
+		idleTimeout:          idleTimeout,
+		probeAfter:           30 * time.Second,
+		acquireTimeout:       60 * time.Second,
+		stopCh:               make(chan struct{}),
+	}
+}
+
+func poolEnvInt(name string, def int) int {
+	if v, err := strconv.Atoi(os.Getenv(name)); err == nil && v > 0 {
+		return v
+	}
+	return def
+}
+
+func poolEnvDuration(name string, def time.Duration) time.Duration {
+	if v, err := time.ParseDuration(os.Getenv(name)); err == nil && v > 0 {
+		return v
+	}
+	return def
+}
+
+// Start 启动空闲回收与保活探测
+func (p *SSHClientPool) Start() {
+	p.startOnce.Do(func() {
+		go func() {
+			ticker := time.NewTicker(30 * time.Second)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-p.stopCh:
+					return
+				case <-ticker.C:
+					p.evictIdle()
+				}
+			}
+		}()
+	})
+}
+
+// Stop 停止回收协程并关闭所有连接
+func (p *SSHClientPool) Stop() {
+	p.stopOnce.Do(func() {
+		close(p.stopCh)
+		p.mu.Lock()
+		var clients []*ssh.Client
+		for key, list := range p.conns {
+			for _, pc := range list {
+				clients = append(clients, pc.client)
+			}
+			delete(p.conns, key)
+		}
+		p.total = 0
+		p.mu.Unlock()
+		for _, c := range clients {
+			c.Close()
+		}
+	})
+}
+
+// Dial 借出一条到目标的连接；没有可复用连接时用 config 建立新连接
+func (p *SSHClientPool) Dial(key SSHPoolKey, config *ssh.ClientConfig) (*PooledSSHClient, error) {
+	return p.DialVia(key, nil, config)
+}
+
+// DialVia 同 Dial，但经由跳板机链建立连接
+func (p *SSHClientPool) DialVia(key SSHPoolKey, jumps models.SSHJumpChain, config *ssh.ClientConfig) (*PooledSSHClient, error) {
+	if err := jumps.Validate(); err != nil {
+		return nil, err
+	}
+	key.Via = jumps.String()
+	addr := fmt.Sprintf("%s:%d", key.Host, key.Port)
+	return p.Acquire(key, func() (*ssh.Client, error) {
+		return dialSSHVia(jumps, addr, config)
+	})
+}
+
+// Acquire 借出连接，dial 用于在需要时建立新连接
+func (p *SSHClientPool) Acquire(key SSHPoolKey, dial func() (*ssh.Client, error)) (*PooledSSHClient, error) {
+	deadline := time.Now().Add(p.acquireTimeout)
+	for {
+		p.mu.Lock()
+		if pc := p.pickLocked(key); pc != nil {
+			pc.refs++
+			idle := time.Since(pc.lastUsed)
+			pc.lastUsed = time.Now()
+			p.mu.Unlock()
+			// 空闲较久的连接先探活，失效则丢弃重试
+			if idle > p.probeAfter && !probeSSHClient(pc.client) {
+				p.release(pc, true)
+				continue
+			}
+			return &PooledSSHClient{Client: pc.client, pool: p, conn: pc}, nil
+		}
+		if p.total >= p.maxClients {
+			p.evictOneIdleLocked()
+		}
+		if p.total < p.maxClients {
+			p.total++
+			p.mu.Unlock()
+			return p.dialNew(key, dial)
+		}
+		wait := p.released
+		p.mu.Unlock()
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return nil, fmt.Errorf("%w: %s", ErrSSHPoolExhausted, key)
+		}
+		select {
+		case <-wait:
+		case <-time.After(remaining):
+		case <-p.stopCh:
+			return nil, errors.New("ssh client pool stopped")
+		}
+	}
+}
+
+// pickLocked 选择负载最低且未满的健康连接
+func (p *SSHClientPool) pickLocked(key SSHPoolKey) *pooledConn {
+	var best *pooledConn
+	for _, pc := range p.conns[key] {
+		if pc.broken || pc.refs >= p.maxSessionsPerClient {
+			continue
+		}
+		if best == nil || pc.refs < best.refs {
+			best = pc
+		}
+	}
+	return best
+}
+
+func (p *SSHClientPool) dialNew(key SSHPoolKey, dial func() (*ssh.Client, error)) (*PooledSSHClient, error) {
+	client, err := dial()
+	if err != nil {
+		p.mu.Lock()
+		p.total--
+		p.notifyLocked()
+		p.mu.Unlock()
+		return nil, err
+	}
+	pc := &pooledConn{key: key, client: client, refs: 1, lastUsed: time.Now()}
+	p.mu.Lock()
+	p.conns[key] = append(p.conns[key], pc)
+	p.mu.Unlock()
+
+	// 远端断开时立即从池中移除
+	go func() {
+		client.Wait()
+		p.mu.Lock()
+		pc.broken = true
+		if pc.refs == 0 {
+			p.removeLocked(pc)
+		}
+		p.mu.Unlock()
+	}()
+	return &PooledSSHClient{Client: client, pool: p, conn: pc}, nil
+}
+
+func (p *SSHClientPool) release(pc *pooledConn, discard bool) {
+	p.mu.Lock()
+	pc.refs--
+	pc.lastUsed = time.Now()
+	if discard {
+		pc.broken = true
+	}
+	closeNow := pc.broken && pc.refs <= 0 && p.removeLocked(pc)
+	p.notifyLocked()
+	p.mu.Unlock()
+	if closeNow {
+		pc.client.Close()
+	}
+}
+
+// removeLocked 从池中移除连接，返回是否确实移除
+func (p *SSHClientPool) removeLocked(pc *pooledConn) bool {
+	list := p.conns[pc.key]
+	for i, c := range list {
+		if c == pc {
+			list = append(list[:i], list[i+1:]...)
+			if len(list) == 0 {
+				delete(p.conns, pc.key)
+			} else {
+				p.conns[pc.key] = list
+			}
+			p.total--
+			p.notifyLocked()
+			return true
+		}
+	}
+	return false
+}
+
+// notifyLocked 唤醒等待连接的调用方
+func (p *SSHClientPool) notifyLocked() {
+	close(p.released)
+	p.released = make(chan struct{})
+}
+
+// evictOneIdleLocked 连接池已满时关闭最久未使用的空闲连接
+func (p *SSHClientPool) evictOneIdleLocked() {
+	var oldest *pooledConn
+	for _, list := range p.conns {
+		for _, pc := range list {
+			if pc.refs == 0 && (oldest == nil || pc.lastUsed.Before(oldest.lastUsed)) {
+				oldest = pc
+			}
+		}
+	}
+	if oldest != nil && p.removeLocked(oldest) {
+		go oldest.client.Close()
+	}
+}
+
+// evictIdle 回收超时空闲连接，探测其余空闲连接
+func (p *SSHClientPool) evictIdle() {
+	now := time.Now()
+	var expired, probe []*pooledConn
+	p.mu.Lock()
+	for _, list := range p.conns {
+		for _, pc := range list {
+			if pc.refs > 0 {
+				continue
+			}
+			if pc.broken || now.Sub(pc.lastUsed) > p.idleTimeout {
+				expired = append(expired, pc)
+			} else if now.Sub(pc.lastUsed) > p.probeAfter {
+				probe = append(probe, pc)
+			}
+		}
+	}
+	for _, pc := range expired {
+		p.removeLocked(pc)
+	}
+	p.mu.Unlock()
+
+	for _, pc := range expired {
+		pc.client.Close()
+	}
+	for _, pc := range probe {
+		if !probeSSHClient(pc.client) {
+			log.Printf("[SSHPool] 连接探测失败，移除: %s", pc.key)
+			p.mu.Lock()
+			pc.broken = true
+			removed := pc.refs == 0 && p.removeLocked(pc)
+			p.mu.Unlock()
+			if removed {
+				pc.client.Close()
+			}
+		}
+	}
+}
+
+// probeSSHClient 发送 keepalive 请求检查连接是否存活
+func probeSSHClient(client *ssh.Client) bool {
+	done := make(chan error, 1)
+	go func() {
+		_, _, err := client.SendRequest("keepalive@openssh.com", true, nil)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		return err == nil
+	case <-time.After(5 * time.Second):
+		return false
+	}
+}
+
+// SSHPoolStats 连接池统计
+type SSHPoolStats struct {
+	Clients        int `json:"clients"`
+	ActiveSessions int `json:"active_sessions"`
+	Hosts          int `json:"hosts"`
+	MaxClients     int `json:"max_clients"`
+}
+
+// Stats 返回连接池统计
+func (p *SSHClientPool) Stats() SSHPoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	stats := SSHPoolStats{Clients: p.total, Hosts: len(p.conns), MaxClients: p.maxClients}
+	for _, list := range p.conns {
+		for _, pc := range list {
+			stats.ActiveSessions += pc.refs
+		}
+	}
+	return stats
+}
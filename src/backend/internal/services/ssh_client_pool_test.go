@@ -0,0 +1,203 @@
+package services
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// testSSHDialer 连接到本地回环地址上的内存 SSH 服务端，统计拨号次数
+func testSSHDialer(t *testing.T, dials *int32) func() (*ssh.Client, error) {
+	_, hostPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostSigner, err := ssh.NewSignerFromKey(hostPriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverConfig := &ssh.ServerConfig{NoClientAuth: true}
+	serverConfig.AddHostKey(hostSigner)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				_, chans, reqs, err := ssh.NewServerConn(conn, serverConfig)
+				if err != nil {
+					return
+				}
+				go func() {
+					for req := range reqs {
+						if req.WantReply {
+							req.Reply(true, nil)
+						}
+					}
+				}()
+				for ch := range chans {
+					ch.Reject(ssh.Prohibited, "no channels in test")
+				}
+			}()
+		}
+	}()
+
+	return func() (*ssh.Client, error) {
+		atomic.AddInt32(dials, 1)
+		return ssh.Dial("tcp", listener.Addr().String(), &ssh.ClientConfig{
+			User:            "test",
+			HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+			Timeout:         5 * time.Second,
+		})
+	}
+}
+
+func TestSSHClientPoolReusesConnections(t *testing.T) {
+	var dials int32
+	dial := testSSHDialer(t, &dials)
+	pool := NewSSHClientPool(4, 2, time.Minute)
+	defer pool.Stop()
+	key := NewSSHPoolKey("node01", 22, "root", "secret")
+
+	a, err := pool.Acquire(key, dial)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := pool.Acquire(key, dial)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Client != b.Client || dials != 1 {
+		t.Fatalf("同一键的前两次借出应复用连接，拨号 %d 次", dials)
+	}
+	// 单连接 session 数已满，第三次借出需要新连接
+	c, err := pool.Acquire(key, dial)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Client == a.Client || dials != 2 {
+		t.Fatalf("超过单连接 session 上限应新建连接，拨号 %d 次", dials)
+	}
+	a.Close()
+	a.Close() // 重复 Close 不应重复归还
+	if stats := pool.Stats(); stats.Clients != 2 || stats.ActiveSessions != 2 {
+		t.Fatalf("统计不符: %+v", stats)
+	}
+
+	// 不同凭据不共享连接
+	other, err := pool.Acquire(NewSSHPoolKey("node01", 22, "root", "other"), dial)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dials != 3 {
+		t.Fatalf("不同凭据应单独建立连接，拨号 %d 次", dials)
+	}
+	other.Discard()
+	if stats := pool.Stats(); stats.Clients != 2 {
+		t.Fatalf("Discard 后连接应被移除: %+v", stats)
+	}
+	b.Close()
+	c.Close()
+}
+
+func TestSSHClientPoolBounded(t *testing.T) {
+	var dials int32
+	dial := testSSHDialer(t, &dials)
+	pool := NewSSHClientPool(1, 1, time.Minute)
+	pool.acquireTimeout = 100 * time.Millisecond
+	defer pool.Stop()
+
+	first, err := pool.Acquire(NewSSHPoolKey("node01", 22, "root", "x"), dial)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pool.Acquire(NewSSHPoolKey("node02", 22, "root", "x"), dial); !errors.Is(err, ErrSSHPoolExhausted) {
+		t.Fatalf("连接池已满时应返回 ErrSSHPoolExhausted，实际 %v", err)
+	}
+
+	// 归还后空闲连接可被淘汰，为其他主机腾出位置
+	first.Close()
+	second, err := pool.Acquire(NewSSHPoolKey("node02", 22, "root", "x"), dial)
+	if err != nil {
+		t.Fatalf("空闲连接应被淘汰: %v", err)
+	}
+	second.Close()
+	if stats := pool.Stats(); stats.Clients != 1 || stats.Hosts != 1 {
+		t.Fatalf("统计不符: %+v", stats)
+	}
+}
+
+func TestSSHClientPoolEvictsIdle(t *testing.T) {
+	var dials int32
+	dial := testSSHDialer(t, &dials)
+	pool := NewSSHClientPool(4, 4, 10*time.Millisecond)
+	defer pool.Stop()
+
+	client, err := pool.Acquire(NewSSHPoolKey("node01", 22, "root", "x"), dial)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !probeSSHClient(client.Client) {
+		t.Fatal("存活连接探测应成功")
+	}
+	client.Close()
+	time.Sleep(20 * time.Millisecond)
+	pool.evictIdle()
+	if stats := pool.Stats(); stats.Clients != 0 {
+		t.Fatalf("超时空闲连接应被回收: %+v", stats)
+	}
+}
+
+func TestSSHKeyCredentialFollowsKeyContent(t *testing.T) {
+	writeKey := func(path string) string {
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block, err := ssh.MarshalPrivateKey(priv, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		data := pem.EncodeToMemory(block)
+		if err := os.WriteFile(path, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	path := filepath.Join(t.TempDir(), "id_ed25519")
+	content := writeKey(path)
+	before := sshKeyCredential(path)
+	if before == path || before != sshKeyCredential(content) {
+		t.Fatalf("私钥路径与私钥内容应得到相同的指纹凭据: %q", before)
+	}
+
+	// 同一路径下轮换私钥后不应复用旧连接
+	writeKey(path)
+	if after := sshKeyCredential(path); after == before {
+		t.Fatal("私钥轮换后连接池凭据应变化")
+	}
+	if NewSSHPoolKey("node01", 22, "root", before) == NewSSHPoolKey("node01", 22, "root", sshKeyCredential(path)) {
+		t.Fatal("私钥轮换后连接池键应变化")
+	}
+
+	if got := sshKeyCredential(""); got != "" {
+		t.Fatalf("未配置私钥时凭据应为空: %q", got)
+	}
+}
@@ -0,0 +1,136 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
+)
+
+// slurmMasterJumpHosts 由环境变量 SLURM_MASTER_JUMP_HOSTS 配置的到 SLURM Master 的跳板机链
+// 格式为 JSON 数组，如 [{"host":"bastion","port":22,"username":"ops","key_path":"/root/.ssh/bastion"}]
+func slurmMasterJumpHosts() models.SSHJumpChain {
+	jumps, err := parseSSHJumpChain(os.Getenv("SLURM_MASTER_JUMP_HOSTS"))
+	if err != nil {
+		logrus.Warnf("忽略无效的 SLURM_MASTER_JUMP_HOSTS: %v", err)
+		return nil
+	}
+	return jumps
+}
+
+// parseSSHJumpChain 解析 JSON 格式的跳板机链（含凭据），空字符串表示直连
+func parseSSHJumpChain(raw string) (models.SSHJumpChain, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	var jumps models.SSHJumpChain
+	if err := json.Unmarshal([]byte(raw), &jumps); err != nil {
+		return nil, err
+	}
+	if err := jumps.Validate(); err != nil {
+		return nil, err
+	}
+	return jumps, nil
+}
+
+// jumpHostClientConfig 根据跳板机凭据构造 SSH 客户端配置，跳板机与目标使用同一主机密钥校验
+func jumpHostClientConfig(hop models.SSHJumpHost, timeout time.Duration, hostKeyCallback ssh.HostKeyCallback) (*ssh.ClientConfig, error) {
+	var authMethods []ssh.AuthMethod
+	if key := strings.TrimSpace(hop.PrivateKey); key != "" {
+		signer, err := ssh.ParsePrivateKey([]byte(key))
+		if err != nil {
+			return nil, fmt.Errorf("parse private key for jump host %s: %v", hop.Address(), err)
+		}
+		authMethods = append(authMethods, ssh.PublicKeys(signer))
+	} else if hop.KeyPath != "" {
+		keyBytes, err := os.ReadFile(hop.KeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("read private key for jump host %s: %v", hop.Address(), err)
+		}
+		signer, err := ssh.ParsePrivateKey(keyBytes)
+		if err != nil {
+			return nil, fmt.Errorf("parse private key for jump host %s: %v", hop.Address(), err)
+		}
+		authMethods = append(authMethods, ssh.PublicKeys(signer))
+	}
+	if hop.Password != "" {
+		authMethods = append(authMethods, ssh.Password(hop.Password))
+	}
+	if len(authMethods) == 0 {
+		return nil, fmt.Errorf("no credentials for jump host %s", hop.Address())
+	}
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	return &ssh.ClientConfig{
+		User:            hop.Username,
+		Auth:            authMethods,
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         timeout,
+	}, nil
+}
+
+// dialSSHVia 依次经过跳板机链建立到 addr 的 SSH 连接（等价于 ssh -J hop1,hop2 target）
+// 返回的客户端关闭后，沿途的跳板机连接会随之关闭
+func dialSSHVia(jumps models.SSHJumpChain, addr string, config *ssh.ClientConfig) (*ssh.Client, error) {
+	if len(jumps) == 0 {
+		return ssh.Dial("tcp", addr, config)
+	}
+
+	var hops []*ssh.Client
+	closeHops := func() {
+		for i := len(hops) - 1; i >= 0; i-- {
+			hops[i].Close()
+		}
+	}
+
+	// 逐跳建立连接：第一跳直连，后续每一跳都经由上一跳转发
+	var current *ssh.Client
+	for _, hop := range jumps {
+		hopConfig, err := jumpHostClientConfig(hop, config.Timeout, config.HostKeyCallback)
+		if err != nil {
+			closeHops()
+			return nil, err
+		}
+		next, err := dialSSHHop(current, hop.Address(), hopConfig)
+		if err != nil {
+			closeHops()
+			return nil, fmt.Errorf("jump host %s: %w", hop.Address(), err)
+		}
+		hops = append(hops, next)
+		current = next
+	}
+
+	target, err := dialSSHHop(current, addr, config)
+	if err != nil {
+		closeHops()
+		return nil, fmt.Errorf("via %s: %w", jumps, err)
+	}
+	go func() {
+		target.Wait()
+		closeHops()
+	}()
+	return target, nil
+}
+
+// dialSSHHop 直接或经由 via 建立 SSH 连接
+func dialSSHHop(via *ssh.Client, addr string, config *ssh.ClientConfig) (*ssh.Client, error) {
+	if via == nil {
+		return ssh.Dial("tcp", addr, config)
+	}
+	conn, err := via.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	clientConn, chans, reqs, err := ssh.NewClientConn(conn, addr, config)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return ssh.NewClient(clientConn, chans, reqs), nil
+}
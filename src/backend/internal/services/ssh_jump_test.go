@@ -0,0 +1,187 @@
+package services
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
+	"golang.org/x/crypto/ssh"
+)
+
+// startTestSSHServer 启动只接受指定密码的 SSH 服务端；允许 direct-tcpip 转发时可作为跳板机
+func startTestSSHServer(t *testing.T, password string, allowForward bool) (string, int) {
+	_, hostPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostSigner, err := ssh.NewSignerFromKey(hostPriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, pass []byte) (*ssh.Permissions, error) {
+			if string(pass) == password {
+				return nil, nil
+			}
+			return nil, io.EOF
+		},
+	}
+	config.AddHostKey(hostSigner)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveTestSSHConn(conn, config, allowForward)
+		}
+	}()
+	addr := listener.Addr().(*net.TCPAddr)
+	return addr.IP.String(), addr.Port
+}
+
+func serveTestSSHConn(conn net.Conn, config *ssh.ServerConfig, allowForward bool) {
+	_, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	for newChan := range chans {
+		if newChan.ChannelType() != "direct-tcpip" || !allowForward {
+			newChan.Reject(ssh.Prohibited, "not supported")
+			continue
+		}
+		var payload struct {
+			Host       string
+			Port       uint32
+			OriginHost string
+			OriginPort uint32
+		}
+		if err := ssh.Unmarshal(newChan.ExtraData(), &payload); err != nil {
+			newChan.Reject(ssh.ConnectionFailed, err.Error())
+			continue
+		}
+		upstream, err := net.Dial("tcp", net.JoinHostPort(payload.Host, strconv.Itoa(int(payload.Port))))
+		if err != nil {
+			newChan.Reject(ssh.ConnectionFailed, err.Error())
+			continue
+		}
+		ch, chReqs, err := newChan.Accept()
+		if err != nil {
+			upstream.Close()
+			continue
+		}
+		go ssh.DiscardRequests(chReqs)
+		go func() {
+			io.Copy(ch, upstream)
+			ch.Close()
+		}()
+		go func() {
+			io.Copy(upstream, ch)
+			upstream.Close()
+		}()
+	}
+}
+
+func TestDialSSHViaJumpChain(t *testing.T) {
+	bastionHost, bastionPort := startTestSSHServer(t, "bastion-pass", true)
+	innerHost, innerPort := startTestSSHServer(t, "inner-pass", true)
+	targetHost, targetPort := startTestSSHServer(t, "target-pass", false)
+
+	svc := &SSHKnownHostsService{mode: models.SSHHostKeyModeInsecure}
+	config := &ssh.ClientConfig{
+		User:            "root",
+		Auth:            []ssh.AuthMethod{ssh.Password("target-pass")},
+		HostKeyCallback: svc.Verify,
+		Timeout:         5 * time.Second,
+	}
+	targetAddr := net.JoinHostPort(targetHost, strconv.Itoa(targetPort))
+
+	// 两跳跳板机各自使用独立密码
+	jumps := models.SSHJumpChain{
+		{Host: bastionHost, Port: bastionPort, Username: "ops", Password: "bastion-pass"},
+		{Host: innerHost, Port: innerPort, Username: "ops", Password: "inner-pass"},
+	}
+	client, err := dialSSHVia(jumps, targetAddr, config)
+	if err != nil {
+		t.Fatalf("经两跳跳板机连接失败: %v", err)
+	}
+	if !probeSSHClient(client) {
+		t.Fatal("目标连接探测失败")
+	}
+	client.Close()
+
+	// 某一跳凭据错误时应报告具体跳板机
+	jumps[1].Password = "wrong"
+	if _, err := dialSSHVia(jumps, targetAddr, config); err == nil || !strings.Contains(err.Error(), "jump host") {
+		t.Fatalf("跳板机认证失败应返回错误，实际 %v", err)
+	}
+}
+
+func TestSSHJumpChainHidesCredentials(t *testing.T) {
+	chain := models.SSHJumpChain{{Host: "bastion", Port: 2222, Username: "ops", Password: "secret"}}
+
+	data, err := json.Marshal(chain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "secret") || !strings.Contains(string(data), `"has_credentials":true`) {
+		t.Fatalf("API 序列化不应包含凭据: %s", data)
+	}
+
+	// 数据库存储保留凭据
+	value, err := chain.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var restored models.SSHJumpChain
+	if err := restored.Scan(value); err != nil {
+		t.Fatal(err)
+	}
+	if len(restored) != 1 || restored[0].Password != "secret" || restored[0].Port != 2222 {
+		t.Fatalf("存储往返后凭据丢失: %+v", restored)
+	}
+	if got := chain.String(); got != "ops@bastion:2222" {
+		t.Fatalf("String() = %q", got)
+	}
+	if err := (models.SSHJumpChain{{Host: "bastion", Username: "ops"}}).Validate(); err == nil {
+		t.Fatal("缺少凭据的跳板机应校验失败")
+	}
+}
+
+func TestParseSSHJumpChain(t *testing.T) {
+	jumps, err := parseSSHJumpChain("")
+	if err != nil || jumps != nil {
+		t.Fatalf("空配置应直连, got %v %v", jumps, err)
+	}
+
+	jumps, err = parseSSHJumpChain(`[{"host":"bastion","port":2222,"username":"ops","password":"secret"},{"host":"10.0.0.1","username":"root","key_path":"/keys/inner"}]`)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if got := jumps.String(); got != "ops@bastion:2222,root@10.0.0.1:22" {
+		t.Fatalf("chain = %q", got)
+	}
+	if jumps[0].Password != "secret" || jumps[1].KeyPath != "/keys/inner" {
+		t.Fatalf("凭据应保留: %+v", jumps)
+	}
+
+	for _, raw := range []string{`not json`, `[{"host":"bastion","username":"ops"}]`, `[{"username":"ops","password":"x"}]`} {
+		if _, err := parseSSHJumpChain(raw); err == nil {
+			t.Errorf("%s: 应返回错误", raw)
+		}
+	}
+}
@@ -0,0 +1,517 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/database"
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
+	"golang.org/x/crypto/ssh"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// HostKeyMismatchError 主机密钥与已信任的指纹不一致（拒绝连接，需管理员审批新指纹）
+type HostKeyMismatchError struct {
+	Host      string
+	Port      int
+	KeyType   string
+	Expected  []string
+	Presented string
+}
+
+func (e *HostKeyMismatchError) Error() string {
+	return fmt.Sprintf("host key for %s:%d changed: %s presented %s, trusted %s; an administrator must approve the new fingerprint",
+		e.Host, e.Port, e.KeyType, e.Presented, strings.Join(e.Expected, ", "))
+}
+
+// HostKeyUnknownError strict 模式下主机没有任何已登记或已审批的密钥
+type HostKeyUnknownError struct {
+	Host      string
+	Port      int
+	KeyType   string
+	Presented string
+}
+
+func (e *HostKeyUnknownError) Error() string {
+	return fmt.Sprintf("host %s:%d is not in known_hosts (%s %s); register the host key or have an administrator approve it",
+		e.Host, e.Port, e.KeyType, e.Presented)
+}
+
+// HostKeyRejectedError 主机密钥已被管理员拒绝
+type HostKeyRejectedError struct {
+	Host        string
+	Port        int
+	Fingerprint string
+}
+
+func (e *HostKeyRejectedError) Error() string {
+	return fmt.Sprintf("host key %s for %s:%d has been rejected", e.Fingerprint, e.Host, e.Port)
+}
+
+// SSHKnownHostsService 集中式 known_hosts：首次信任（TOFU）、指纹变更检测与审批
+type SSHKnownHostsService struct {
+	db   *gorm.DB
+	mu   sync.RWMutex
+	mode string
+}
+
+var (
+	sshKnownHostsServiceInstance *SSHKnownHostsService
+	sshKnownHostsServiceOnce     sync.Once
+	hostKeyUnavailableWarnOnce   sync.Once
+)
+
+// sshKnownHostSettingID 校验设置所在的行
+const sshKnownHostSettingID = 1
+
+// NewSSHKnownHostsService 创建已知主机密钥服务（单例）
+// 模式优先取管理员运行时设置并持久化的值，其次取 SSH_HOST_KEY_MODE（默认 tofu）
+func NewSSHKnownHostsService(db *gorm.DB) *SSHKnownHostsService {
+	sshKnownHostsServiceOnce.Do(func() {
+		mode := strings.ToLower(strings.TrimSpace(os.Getenv("SSH_HOST_KEY_MODE")))
+		if !validSSHHostKeyMode(mode) {
+			if mode != "" {
+				log.Printf("[SSHKnownHosts] 无效的 SSH_HOST_KEY_MODE=%q，使用 tofu", mode)
+			}
+			mode = models.SSHHostKeyModeTOFU
+		}
+		if err := db.AutoMigrate(&models.SSHKnownHost{}, &models.SSHKnownHostSetting{}); err != nil {
+			log.Printf("[SSHKnownHosts] 自动迁移失败: %v", err)
+		}
+		var setting models.SSHKnownHostSetting
+		if err := db.Where("id = ?", sshKnownHostSettingID).Limit(1).Find(&setting).Error; err == nil && validSSHHostKeyMode(setting.Mode) {
+			mode = setting.Mode
+		}
+		sshKnownHostsServiceInstance = &SSHKnownHostsService{db: db, mode: mode}
+		log.Printf("[SSHKnownHosts] 主机密钥校验模式: %s", mode)
+	})
+	return sshKnownHostsServiceInstance
+}
+
+// GetSSHKnownHostsService 获取已知主机密钥服务实例
+func GetSSHKnownHostsService() *SSHKnownHostsService {
+	return sshKnownHostsServiceInstance
+}
+
+func validSSHHostKeyMode(mode string) bool {
+	switch mode {
+	case models.SSHHostKeyModeTOFU, models.SSHHostKeyModeStrict, models.SSHHostKeyModeInsecure:
+		return true
+	}
+	return false
+}
+
+// KnownHostsCallback 所有 SSH 连接共用的 HostKeyCallback
+func KnownHostsCallback() ssh.HostKeyCallback {
+	svc := GetSSHKnownHostsService()
+	if svc == nil && database.DB != nil {
+		svc = NewSSHKnownHostsService(database.DB)
+	}
+	if svc == nil {
+		hostKeyUnavailableWarnOnce.Do(func() {
+			log.Printf("[SSHKnownHosts] 数据库未初始化，主机密钥校验不可用，拒绝所有 SSH 连接")
+		})
+		return rejectHostKey
+	}
+	return svc.Verify
+}
+
+// rejectHostKey 无法校验主机密钥时拒绝连接（失败即关闭，不回退为不校验）
+func rejectHostKey(hostname string, _ net.Addr, _ ssh.PublicKey) error {
+	return fmt.Errorf("cannot verify host key for %s: known hosts store is not available", hostname)
+}
+
+// Mode 当前校验模式
+func (s *SSHKnownHostsService) Mode() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.mode
+}
+
+// SetMode 运行时切换校验模式并持久化（重启后保留）
+func (s *SSHKnownHostsService) SetMode(mode, username string) error {
+	mode = strings.ToLower(strings.TrimSpace(mode))
+	if !validSSHHostKeyMode(mode) {
+		return fmt.Errorf("invalid mode %q (must be tofu, strict or insecure)", mode)
+	}
+	setting := models.SSHKnownHostSetting{ID: sshKnownHostSettingID, Mode: mode, UpdatedBy: username}
+	if err := s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"mode", "updated_by", "updated_at"}),
+	}).Create(&setting).Error; err != nil {
+		return fmt.Errorf("save mode: %v", err)
+	}
+	s.mu.Lock()
+	previous := s.mode
+	s.mode = mode
+	s.mu.Unlock()
+
+	GetAuditService().NewAuditEntry(models.AuditCategorySecurity, models.AuditActionConfigUpdate).
+		WithUser(0, username, "").
+		WithResource("ssh_host_key_mode", "", mode).
+		WithChange(previous, mode, "ssh host key mode changed").
+		WithStatus(models.AuditStatusSuccess).
+		WithSeverity(models.AuditSeverityCritical).
+		WithTags("ssh", "known_hosts").
+		SaveAsync()
+	return nil
+}
+
+// splitKnownHostAddr 解析 Dial 传入的 host:port，失败时回退到远端地址
+func splitKnownHostAddr(hostname string, remote net.Addr) (string, int) {
+	host, portStr, err := net.SplitHostPort(hostname)
+	if err != nil && remote != nil {
+		host, portStr, err = net.SplitHostPort(remote.String())
+	}
+	if err != nil {
+		return hostname, 22
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port == 0 {
+		port = 22
+	}
+	return strings.ToLower(host), port
+}
+
+// hostKeyAction Verify 对出示密钥的处理方式
+type hostKeyAction int
+
+const (
+	hostKeyAccept        hostKeyAction = iota // 与受信任密钥一致
+	hostKeyRejected                           // 该密钥已被拒绝
+	hostKeyTrustFirstUse                      // 主机没有任何受信任密钥（tofu）：首次信任
+	hostKeyUnknown                            // 主机没有任何受信任密钥（strict）：记录待审批并拒绝
+	hostKeyChanged                            // 主机已有受信任密钥但出示了其他密钥（含新的密钥类型）：记录待审批并拒绝
+)
+
+// decideHostKey 根据主机（host:port）的全部已知密钥决定如何处理出示的密钥
+// 只要主机有任一类型的受信任密钥即视为已知主机，未受信任的新密钥（包括新类型）一律按密钥变化处理
+func decideHostKey(mode string, entries []models.SSHKnownHost, keyType, fingerprint string) (hostKeyAction, *models.SSHKnownHost, []string) {
+	var matched *models.SSHKnownHost
+	var trusted []string
+	for i := range entries {
+		if entries[i].KeyType == keyType && entries[i].Fingerprint == fingerprint {
+			matched = &entries[i]
+		}
+		if entries[i].Status == models.SSHKnownHostTrusted {
+			trusted = append(trusted, entries[i].KeyType+" "+entries[i].Fingerprint)
+		}
+	}
+
+	if matched != nil {
+		switch matched.Status {
+		case models.SSHKnownHostTrusted:
+			return hostKeyAccept, matched, trusted
+		case models.SSHKnownHostRejected:
+			return hostKeyRejected, matched, trusted
+		}
+	}
+	if len(trusted) > 0 {
+		return hostKeyChanged, matched, trusted
+	}
+	if mode == models.SSHHostKeyModeStrict {
+		return hostKeyUnknown, matched, trusted
+	}
+	return hostKeyTrustFirstUse, matched, trusted
+}
+
+// Verify 校验主机密钥（实现 ssh.HostKeyCallback）
+// 除 insecure 模式外，已知主机出示未受信任的密钥时均拒绝连接；strict 模式下未登记的主机同样拒绝
+func (s *SSHKnownHostsService) Verify(hostname string, remote net.Addr, key ssh.PublicKey) error {
+	mode := s.Mode()
+	if mode == models.SSHHostKeyModeInsecure {
+		return nil
+	}
+	host, port := splitKnownHostAddr(hostname, remote)
+	keyType := key.Type()
+	fingerprint := ssh.FingerprintSHA256(key)
+	now := time.Now()
+
+	var entries []models.SSHKnownHost
+	if err := s.db.Where("host = ? AND port = ?", host, port).Find(&entries).Error; err != nil {
+		return fmt.Errorf("load known hosts: %v", err)
+	}
+
+	action, matched, trusted := decideHostKey(mode, entries, keyType, fingerprint)
+	if matched != nil {
+		s.db.Model(matched).Updates(map[string]interface{}{"last_seen_at": now, "seen_count": gorm.Expr("seen_count + 1")})
+	}
+	if action == hostKeyTrustFirstUse {
+		var err error
+		if action, matched, trusted, err = s.trustFirstUse(mode, host, port, key, now); err != nil {
+			return err
+		}
+	}
+
+	switch action {
+	case hostKeyAccept:
+		return nil
+	case hostKeyRejected:
+		return &HostKeyRejectedError{Host: host, Port: port, Fingerprint: fingerprint}
+	}
+
+	// 密钥变化或 strict 模式下的未知主机：首次出现时记录为待审批并告警
+	event, source := "ssh_host_key_changed", "changed"
+	if action == hostKeyUnknown {
+		event, source = "ssh_host_unknown", "unknown"
+	}
+	if matched == nil {
+		pending := models.SSHKnownHost{
+			Host:        host,
+			Port:        port,
+			KeyType:     keyType,
+			Fingerprint: fingerprint,
+			PublicKey:   strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key))),
+			Status:      models.SSHKnownHostPending,
+			Source:      source,
+			SeenCount:   1,
+			FirstSeenAt: now,
+			LastSeenAt:  now,
+		}
+		s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&pending)
+
+		GetAuditService().NewAuditEntry(models.AuditCategorySecurity, models.AuditActionUpdate).
+			WithResource("ssh_known_host", fmt.Sprintf("%s:%d", host, port), host).
+			WithStatus(models.AuditStatusPending).
+			WithSeverity(models.AuditSeverityAlert).
+			WithMetadata(map[string]interface{}{
+				"event":       event,
+				"host":        host,
+				"port":        port,
+				"key_type":    keyType,
+				"presented":   fingerprint,
+				"trusted":     trusted,
+				"mode":        mode,
+				"remote_addr": fmt.Sprint(remote),
+			}).
+			WithTags("ssh", "known_hosts", strings.TrimPrefix(event, "ssh_")).
+			SaveAsync()
+	}
+
+	if action == hostKeyUnknown {
+		log.Printf("[SSHKnownHosts] 未登记的主机，拒绝连接（strict 模式，待审批）: %s:%d %s %s", host, port, keyType, fingerprint)
+		return &HostKeyUnknownError{Host: host, Port: port, KeyType: keyType, Presented: fingerprint}
+	}
+	log.Printf("[SSHKnownHosts] 主机密钥已变化，拒绝连接（待审批）: %s:%d %s %s", host, port, keyType, fingerprint)
+	return &HostKeyMismatchError{Host: host, Port: port, KeyType: keyType, Expected: trusted, Presented: fingerprint}
+}
+
+// trustFirstUse 首次信任：以 ON CONFLICT DO NOTHING 登记出示的密钥（已存在的待审批记录仅在仍为待审批时提升），
+// 然后重新读取主机的全部密钥再决定。并发的首次连接可能出示不同的密钥，只有最早登记的受信任密钥生效，其余降为待审批
+func (s *SSHKnownHostsService) trustFirstUse(mode, host string, port int, key ssh.PublicKey, now time.Time) (hostKeyAction, *models.SSHKnownHost, []string, error) {
+	keyType := key.Type()
+	fingerprint := ssh.FingerprintSHA256(key)
+	entry := models.SSHKnownHost{
+		Host:        host,
+		Port:        port,
+		KeyType:     keyType,
+		Fingerprint: fingerprint,
+		PublicKey:   strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key))),
+		Status:      models.SSHKnownHostTrusted,
+		Source:      "tofu",
+		SeenCount:   1,
+		FirstSeenAt: now,
+		LastSeenAt:  now,
+	}
+	res := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&entry)
+	if res.Error != nil {
+		return 0, nil, nil, fmt.Errorf("record host key: %v", res.Error)
+	}
+	claimed := res.RowsAffected == 1
+	if !claimed {
+		res = s.db.Model(&models.SSHKnownHost{}).
+			Where("host = ? AND port = ? AND key_type = ? AND fingerprint = ? AND status = ?", host, port, keyType, fingerprint, models.SSHKnownHostPending).
+			Updates(map[string]interface{}{"status": models.SSHKnownHostTrusted, "source": "tofu"})
+		if res.Error != nil {
+			return 0, nil, nil, fmt.Errorf("record host key: %v", res.Error)
+		}
+		claimed = res.RowsAffected == 1
+	}
+
+	var entries []models.SSHKnownHost
+	if err := s.db.Where("host = ? AND port = ?", host, port).Order("id ASC").Find(&entries).Error; err != nil {
+		return 0, nil, nil, fmt.Errorf("load known hosts: %v", err)
+	}
+	first := firstTrustedHostKey(entries)
+	demoted := false
+	for i := range entries {
+		e := &entries[i]
+		if claimed && e.KeyType == keyType && e.Fingerprint == fingerprint && e.ID != first {
+			s.db.Model(&models.SSHKnownHost{}).Where("id = ?", e.ID).
+				Updates(map[string]interface{}{"status": models.SSHKnownHostPending, "source": "changed"})
+			e.Status, e.Source = models.SSHKnownHostPending, "changed"
+			demoted = true
+		}
+	}
+
+	action, matched, trusted := decideHostKey(mode, entries, keyType, fingerprint)
+	switch {
+	case action == hostKeyAccept && claimed:
+		log.Printf("[SSHKnownHosts] 首次信任主机密钥: %s:%d %s %s", host, port, keyType, fingerprint)
+	case action == hostKeyTrustFirstUse:
+		// 重新读取后仍没有受信任密钥（记录被并发地修改）：不自动信任
+		action = hostKeyChanged
+	}
+	if demoted {
+		matched = nil // 与其他密钥竞争失败：按首次出现的变化密钥记录并告警
+	}
+	return action, matched, trusted, nil
+}
+
+// firstTrustedHostKey 返回最早登记（ID 最小）的受信任密钥 ID，没有时返回 0
+func firstTrustedHostKey(entries []models.SSHKnownHost) uint {
+	var first uint
+	for _, e := range entries {
+		if e.Status == models.SSHKnownHostTrusted && (first == 0 || e.ID < first) {
+			first = e.ID
+		}
+	}
+	return first
+}
+
+// ==================== 管理接口 ====================
+
+// List 查询已知主机密钥
+func (s *SSHKnownHostsService) List(host, status string) ([]models.SSHKnownHost, error) {
+	query := s.db.Model(&models.SSHKnownHost{})
+	if host != "" {
+		query = query.Where("host LIKE ?", "%"+strings.ToLower(host)+"%")
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var hosts []models.SSHKnownHost
+	err := query.Order("host ASC, port ASC, key_type ASC, status ASC").Find(&hosts).Error
+	return hosts, err
+}
+
+// Get 获取主机密钥记录
+func (s *SSHKnownHostsService) Get(id uint) (*models.SSHKnownHost, error) {
+	var entry models.SSHKnownHost
+	if err := s.db.First(&entry, id).Error; err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// Approve 信任该指纹；同一主机同类型的其他受信任指纹被轮换为 rejected
+func (s *SSHKnownHostsService) Approve(id uint, username string) (*models.SSHKnownHost, error) {
+	entry, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.SSHKnownHost{}).
+			Where("host = ? AND port = ? AND key_type = ? AND id <> ? AND status = ?", entry.Host, entry.Port, entry.KeyType, entry.ID, models.SSHKnownHostTrusted).
+			Update("status", models.SSHKnownHostRejected).Error; err != nil {
+			return err
+		}
+		return tx.Model(entry).Updates(map[string]interface{}{
+			"status":      models.SSHKnownHostTrusted,
+			"approved_by": username,
+			"approved_at": now,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.audit(models.AuditActionApprove, entry, username)
+	return s.Get(id)
+}
+
+// Reject 拒绝该指纹（之后出示该密钥的连接都会失败）
+func (s *SSHKnownHostsService) Reject(id uint, username string) (*models.SSHKnownHost, error) {
+	entry, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.Model(entry).Updates(map[string]interface{}{"status": models.SSHKnownHostRejected, "approved_by": username}).Error; err != nil {
+		return nil, err
+	}
+	s.audit(models.AuditActionReject, entry, username)
+	return s.Get(id)
+}
+
+// Delete 删除记录（主机不再有受信任密钥时，下次连接按首次连接处理）
+func (s *SSHKnownHostsService) Delete(id uint, username string) error {
+	entry, err := s.Get(id)
+	if err != nil {
+		return err
+	}
+	if err := s.db.Delete(entry).Error; err != nil {
+		return err
+	}
+	s.audit(models.AuditActionDelete, entry, username)
+	return nil
+}
+
+// Register 手动登记（或轮换）主机公钥，立即受信任并替换同类型旧指纹
+func (s *SSHKnownHostsService) Register(req *models.SSHKnownHostRequest, username string) (*models.SSHKnownHost, error) {
+	key, comment, _, _, err := ssh.ParseAuthorizedKey([]byte(strings.TrimSpace(req.PublicKey)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key: %v", err)
+	}
+	port := req.Port
+	if port == 0 {
+		port = 22
+	}
+	if req.Comment != "" {
+		comment = req.Comment
+	}
+	now := time.Now()
+	entry := models.SSHKnownHost{
+		Host:        strings.ToLower(strings.TrimSpace(req.Host)),
+		Port:        port,
+		KeyType:     key.Type(),
+		Fingerprint: ssh.FingerprintSHA256(key),
+		PublicKey:   strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key))),
+		Status:      models.SSHKnownHostPending,
+		Source:      "manual",
+		FirstSeenAt: now,
+		LastSeenAt:  now,
+		Comment:     comment,
+	}
+	if err := s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "host"}, {Name: "port"}, {Name: "key_type"}, {Name: "fingerprint"}},
+		DoUpdates: clause.AssignmentColumns([]string{"comment", "updated_at"}),
+	}).Create(&entry).Error; err != nil {
+		return nil, err
+	}
+	var stored models.SSHKnownHost
+	if err := s.db.Where("host = ? AND port = ? AND key_type = ? AND fingerprint = ?", entry.Host, entry.Port, entry.KeyType, entry.Fingerprint).
+		First(&stored).Error; err != nil {
+		return nil, err
+	}
+	return s.Approve(stored.ID, username)
+}
+
+func (s *SSHKnownHostsService) audit(action models.AuditAction, entry *models.SSHKnownHost, username string) {
+	GetAuditService().NewAuditEntry(models.AuditCategorySecurity, action).
+		WithUser(0, username, "").
+		WithResource("ssh_known_host", strconv.FormatUint(uint64(entry.ID), 10), fmt.Sprintf("%s:%d", entry.Host, entry.Port)).
+		WithMetadata(map[string]interface{}{
+			"key_type":    entry.KeyType,
+			"fingerprint": entry.Fingerprint,
+		}).
+		WithStatus(models.AuditStatusSuccess).
+		WithSeverity(models.AuditSeverityCritical).
+		WithTags("ssh", "known_hosts").
+		SaveAsync()
+}
+
+// IsHostKeyError 判断错误是否由主机密钥校验引起（便于调用方给出明确提示）
+func IsHostKeyError(err error) bool {
+	var mismatch *HostKeyMismatchError
+	var unknown *HostKeyUnknownError
+	var rejected *HostKeyRejectedError
+	return errors.As(err, &mismatch) || errors.As(err, &unknown) || errors.As(err, &rejected)
+}
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestSplitKnownHostAddr(t *testing.T) {
+	remote := &net.TCPAddr{IP: net.ParseIP("10.0.0.9"), Port: 2222}
+	cases := []struct {
+		hostname string
+		remote   net.Addr
+		host     string
+		port     int
+	}{
+		{"Node01.Example.com:22", nil, "node01.example.com", 22},
+		{"10.0.0.5:2200", nil, "10.0.0.5", 2200},
+		{"[fe80::1]:22", nil, "fe80::1", 22},
+		{"no-port", remote, "10.0.0.9", 2222},
+		{"no-port", nil, "no-port", 22},
+	}
+	for _, tc := range cases {
+		host, port := splitKnownHostAddr(tc.hostname, tc.remote)
+		if host != tc.host || port != tc.port {
+			t.Errorf("splitKnownHostAddr(%q) = %s:%d，期望 %s:%d", tc.hostname, host, port, tc.host, tc.port)
+		}
+	}
+}
+
+func TestValidSSHHostKeyMode(t *testing.T) {
+	for _, mode := range []string{models.SSHHostKeyModeTOFU, models.SSHHostKeyModeStrict, models.SSHHostKeyModeInsecure} {
+		if !validSSHHostKeyMode(mode) {
+			t.Errorf("%q 应为有效模式", mode)
+		}
+	}
+	if validSSHHostKeyMode("") || validSSHHostKeyMode("yes") {
+		t.Error("空值和未知值不应为有效模式")
+	}
+}
+
+func TestVerifyInsecureModeSkipsStore(t *testing.T) {
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// insecure 模式不访问数据库（db 为 nil 也不应 panic）
+	svc := &SSHKnownHostsService{mode: models.SSHHostKeyModeInsecure}
+	if err := svc.Verify("10.0.0.5:22", nil, key); err != nil {
+		t.Fatalf("insecure 模式应放行: %v", err)
+	}
+	if err := svc.SetMode("bogus", "admin"); err == nil {
+		t.Fatal("无效模式应返回错误")
+	}
+}
+
+func TestRejectHostKeyWithoutStore(t *testing.T) {
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 无法访问已知主机库时必须拒绝，不能回退为不校验
+	if err := rejectHostKey("10.0.0.5:22", nil, key); err == nil {
+		t.Fatal("无法校验时应拒绝连接")
+	}
+}
+
+func TestFirstTrustedHostKey(t *testing.T) {
+	entries := []models.SSHKnownHost{
+		{ID: 3, Status: models.SSHKnownHostTrusted},
+		{ID: 1, Status: models.SSHKnownHostRejected},
+		{ID: 2, Status: models.SSHKnownHostTrusted},
+		{ID: 4, Status: models.SSHKnownHostPending},
+	}
+	if got := firstTrustedHostKey(entries); got != 2 {
+		t.Fatalf("最早登记的受信任密钥 = %d，期望 2", got)
+	}
+	if got := firstTrustedHostKey(entries[1:2]); got != 0 {
+		t.Fatalf("没有受信任密钥时应返回 0，得到 %d", got)
+	}
+}
+
+func TestDecideHostKey(t *testing.T) {
+	const (
+		ed  = "ssh-ed25519"
+		rsa = "ssh-rsa"
+	)
+	trustedRSA := models.SSHKnownHost{KeyType: rsa, Fingerprint: "SHA256:rsa-old", Status: models.SSHKnownHostTrusted}
+	trustedEd := models.SSHKnownHost{KeyType: ed, Fingerprint: "SHA256:ed-old", Status: models.SSHKnownHostTrusted}
+	pendingEd := models.SSHKnownHost{KeyType: ed, Fingerprint: "SHA256:ed-new", Status: models.SSHKnownHostPending}
+	rejectedEd := models.SSHKnownHost{KeyType: ed, Fingerprint: "SHA256:ed-bad", Status: models.SSHKnownHostRejected}
+
+	cases := []struct {
+		name        string
+		mode        string
+		entries     []models.SSHKnownHost
+		keyType     string
+		fingerprint string
+		want        hostKeyAction
+	}{
+		{"受信任密钥放行", models.SSHHostKeyModeStrict, []models.SSHKnownHost{trustedEd}, ed, "SHA256:ed-old", hostKeyAccept},
+		{"已拒绝的密钥", models.SSHHostKeyModeTOFU, []models.SSHKnownHost{trustedEd, rejectedEd}, ed, "SHA256:ed-bad", hostKeyRejected},
+
+		// tofu 模式下密钥变化必须拒绝，不能记录待审批后放行
+		{"tofu 同类型密钥变化", models.SSHHostKeyModeTOFU, []models.SSHKnownHost{trustedEd}, ed, "SHA256:ed-new", hostKeyChanged},
+		{"tofu 再次出示待审批密钥", models.SSHHostKeyModeTOFU, []models.SSHKnownHost{trustedEd, pendingEd}, ed, "SHA256:ed-new", hostKeyChanged},
+		{"strict 同类型密钥变化", models.SSHHostKeyModeStrict, []models.SSHKnownHost{trustedEd}, ed, "SHA256:ed-new", hostKeyChanged},
+
+		// 已有其他类型受信任密钥时，出示新类型密钥不能按首次连接信任
+		{"tofu 已知主机出示新类型密钥", models.SSHHostKeyModeTOFU, []models.SSHKnownHost{trustedRSA}, ed, "SHA256:ed-new", hostKeyChanged},
+		{"strict 已知主机出示新类型密钥", models.SSHHostKeyModeStrict, []models.SSHKnownHost{trustedRSA}, ed, "SHA256:ed-new", hostKeyChanged},
+		{"多类型均受信任", models.SSHHostKeyModeStrict, []models.SSHKnownHost{trustedRSA, trustedEd}, rsa, "SHA256:rsa-old", hostKeyAccept},
+
+		// 首次连接
+		{"tofu 未知主机首次信任", models.SSHHostKeyModeTOFU, nil, ed, "SHA256:ed-new", hostKeyTrustFirstUse},
+		{"strict 未知主机拒绝", models.SSHHostKeyModeStrict, nil, ed, "SHA256:ed-new", hostKeyUnknown},
+		{"strict 仅有待审批记录仍视为未知", models.SSHHostKeyModeStrict, []models.SSHKnownHost{pendingEd}, ed, "SHA256:ed-new", hostKeyUnknown},
+		{"strict 仅有已拒绝记录仍视为未知", models.SSHHostKeyModeStrict, []models.SSHKnownHost{rejectedEd}, ed, "SHA256:ed-new", hostKeyUnknown},
+	}
+	for _, tc := range cases {
+		got, _, _ := decideHostKey(tc.mode, tc.entries, tc.keyType, tc.fingerprint)
+		if got != tc.want {
+			t.Errorf("%s: 得到 %d, 期望 %d", tc.name, got, tc.want)
+		}
+	}
+
+	_, matched, trusted := decideHostKey(models.SSHHostKeyModeTOFU, []models.SSHKnownHost{trustedRSA, pendingEd}, ed, "SHA256:ed-new")
+	if matched == nil || matched.Fingerprint != "SHA256:ed-new" {
+		t.Errorf("应返回匹配的待审批记录: %+v", matched)
+	}
+	if len(trusted) != 1 || trusted[0] != rsa+" SHA256:rsa-old" {
+		t.Errorf("应列出所有类型的受信任密钥: %v", trusted)
+	}
+}
+
+func TestIsHostKeyError(t *testing.T) {
+	mismatch := &HostKeyMismatchError{Host: "n1", Port: 22, KeyType: "ssh-ed25519", Expected: []string{"SHA256:a"}, Presented: "SHA256:b"}
+	if !IsHostKeyError(fmt.Errorf("dial: %w", mismatch)) {
+		t.Error("包装后的 HostKeyMismatchError 应被识别")
+	}
+	if !IsHostKeyError(fmt.Errorf("dial: %w", &HostKeyUnknownError{Host: "n1", Port: 22, KeyType: "ssh-ed25519", Presented: "SHA256:b"})) {
+		t.Error("HostKeyUnknownError 应被识别")
+	}
+	if !IsHostKeyError(&HostKeyRejectedError{Host: "n1", Port: 22, Fingerprint: "SHA256:b"}) {
+		t.Error("HostKeyRejectedError 应被识别")
+	}
+	if IsHostKeyError(fmt.Errorf("connection refused")) {
+		t.Error("普通错误不应被识别为主机密钥错误")
+	}
+}
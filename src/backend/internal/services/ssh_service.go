@@ -19,6 +19,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
 	"golang.org/x/crypto/ssh"
 )
 
@@ -89,6 +90,7 @@ type SSHConnection struct {
 	KeyPath    string
 	PrivateKey string // 新增：内联私钥内容
 	Password   string
+	JumpHosts  models.SSHJumpChain // 跳板机链（ProxyJump），为空则直连
 }
 
 // DeploymentResult 部署结果
@@ -254,7 +256,7 @@ func (s *SSHService) installPackagesOnSingleHost(ctx context.Context, conn SSHCo
 
 	// 步骤2: 执行salt-minion安装脚本
 	if config.EnableSaltMinion {
-		executeStep := s.executeSaltMinionScript(client, conn, config)
+		executeStep := s.executeSaltMinionScript(client.Client, conn, config)
 		result.Steps = append(result.Steps, executeStep)
 
 		if !executeStep.Success {
@@ -265,7 +267,7 @@ func (s *SSHService) installPackagesOnSingleHost(ctx context.Context, conn SSHCo
 
 	// 步骤3: 配置salt-minion
 	if config.EnableSaltMinion {
-		configStep := s.configureSaltMinion(client, config, conn.Host)
+		configStep := s.configureSaltMinion(client.Client, config, conn.Host)
 		result.Steps = append(result.Steps, configStep)
 
 		if !configStep.Success {
@@ -276,7 +278,7 @@ func (s *SSHService) installPackagesOnSingleHost(ctx context.Context, conn SSHCo
 
 	// 步骤4: 启动salt-minion服务
 	if config.EnableSaltMinion {
-		startStep := s.startSaltMinion(client)
+		startStep := s.startSaltMinion(client.Client)
 		result.Steps = append(result.Steps, startStep)
 
 		if !startStep.Success {
@@ -289,7 +291,7 @@ func (s *SSHService) installPackagesOnSingleHost(ctx context.Context, conn SSHCo
 	if config.EnableSlurmClient {
 		slurmSteps := s.generateSlurmInstallationSteps(config, conn.Host)
 		for _, step := range slurmSteps {
-			stepResult := s.executeInstallationStep(client, step)
+			stepResult := s.executeInstallationStep(client.Client, step)
 			result.Steps = append(result.Steps, stepResult)
 
 			if !stepResult.Success && step.Critical {
@@ -911,7 +913,7 @@ func (s *SSHService) deploySingleMinion(ctx context.Context, conn SSHConnection,
 	defer client.Close()
 
 	// 执行部署步骤
-	output, err := s.executeDeploymentSteps(client, config)
+	output, err := s.executeDeploymentSteps(client.Client, config)
 	if err != nil {
 		result.Error = fmt.Sprintf("部署失败: %v", err)
 		result.Output = output
@@ -923,12 +925,12 @@ func (s *SSHService) deploySingleMinion(ctx context.Context, conn SSHConnection,
 	return result
 }
 
-// connectSSH 建立SSH连接
-func (s *SSHService) connectSSH(conn SSHConnection) (*ssh.Client, error) {
+// connectSSH 从连接池获取SSH连接（调用方 Close 即归还）
+func (s *SSHService) connectSSH(conn SSHConnection) (*PooledSSHClient, error) {
 	config := &ssh.ClientConfig{
 		User:            conn.User,
 		Auth:            []ssh.AuthMethod{},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		HostKeyCallback: KnownHostsCallback(),
 		Timeout:         s.config.ConnectTimeout,
 	}
 
@@ -951,13 +953,14 @@ func (s *SSHService) connectSSH(conn SSHConnection) (*ssh.Client, error) {
 	if conn.Password != "" {
 		config.Auth = append(config.Auth, ssh.Password(conn.Password))
 	}
+	config.Auth = withSSHCAAuth(config.Auth)
 
 	if len(config.Auth) == 0 {
 		return nil, fmt.Errorf("未提供有效的认证方法")
 	}
 
-	addr := fmt.Sprintf("%s:%d", conn.Host, conn.Port)
-	return ssh.Dial("tcp", addr, config)
+	key := NewSSHPoolKey(conn.Host, conn.Port, conn.User, conn.Password, conn.PrivateKey, sshKeyCredential(conn.KeyPath))
+	return GetSSHClientPool().DialVia(key, conn.JumpHosts, config)
 }
 
 // loadPrivateKey 加载私钥
@@ -2017,7 +2020,7 @@ func (s *SSHService) executeCommandOnHost(conn SSHConnection, command string) De
 	}
 	defer client.Close()
 
-	output, err := s.executeCommand(client, command)
+	output, err := s.executeCommand(client.Client, command)
 	if err != nil {
 		result.Error = fmt.Sprintf("命令执行失败: %v", err)
 		result.Output = output
@@ -2077,7 +2080,7 @@ func (s *SSHService) executeScriptOnHost(ctx context.Context, conn SSHConnection
 		// 在远端下载脚本
 		downloadCmd := fmt.Sprintf(`/bin/sh -lc 'curl -fsSL %s -o %s || wget -q %s -O %s; chmod +x %s'`,
 			singleQuote(spec.URL), remoteScript, singleQuote(spec.URL), remoteScript, remoteScript)
-		if out, err := s.executeCommand(client, downloadCmd); err != nil {
+		if out, err := s.executeCommand(client.Client, downloadCmd); err != nil {
 			result.Output = out
 			result.Error = fmt.Sprintf("下载脚本失败: %v", err)
 			return result
@@ -2093,7 +2096,7 @@ func (s *SSHService) executeScriptOnHost(ctx context.Context, conn SSHConnection
 	cmd := s.buildScriptExecutionCommand(remoteScript, spec)
 
 	// 3) 按需设置超时并执行
-	out, err := s.executeCommandWithTimeout(client, cmd, spec.Timeout)
+	out, err := s.executeCommandWithTimeout(client.Client, cmd, spec.Timeout)
 	result.Output += out
 	if err != nil {
 		result.Error = fmt.Sprintf("脚本执行失败: %v", err)
@@ -2101,7 +2104,7 @@ func (s *SSHService) executeScriptOnHost(ctx context.Context, conn SSHConnection
 	}
 
 	// 4) 清理临时脚本
-	_, _ = s.executeCommand(client, fmt.Sprintf("/bin/sh -lc 'rm -f %s'", remoteScript))
+	_, _ = s.executeCommand(client.Client, fmt.Sprintf("/bin/sh -lc 'rm -f %s'", remoteScript))
 
 	result.Success = true
 	return result
@@ -2143,13 +2146,27 @@ func (s *SSHService) buildScriptExecutionCommand(remoteScript string, spec Scrip
 
 // ExecuteCommand 在指定主机上执行命令（导出方法，便于其他服务直接调用）
 func (s *SSHService) ExecuteCommand(host string, port int, user, password, command string) (string, error) {
-	conn := SSHConnection{Host: host, Port: port, User: user, Password: password, KeyPath: s.config.DefaultKeyPath}
+	return s.ExecuteCommandOn(s.passwordConnection(host, port, user, password, nil), command)
+}
+
+// ExecuteCommandOn 按完整连接信息（含跳板机链）执行命令
+func (s *SSHService) ExecuteCommandOn(conn SSHConnection, command string) (string, error) {
 	client, err := s.connectSSH(conn)
 	if err != nil {
 		return "", err
 	}
 	defer client.Close()
-	return s.executeCommand(client, command)
+	return s.executeCommand(client.Client, command)
+}
+
+// ExecuteCommandVia 经跳板机链在指定主机上执行命令
+func (s *SSHService) ExecuteCommandVia(host string, port int, user, password string, jumps models.SSHJumpChain, command string) (string, error) {
+	return s.ExecuteCommandOn(s.passwordConnection(host, port, user, password, jumps), command)
+}
+
+// passwordConnection 构造使用密码（及默认私钥）认证的连接信息
+func (s *SSHService) passwordConnection(host string, port int, user, password string, jumps models.SSHJumpChain) SSHConnection {
+	return SSHConnection{Host: host, Port: port, User: user, Password: password, KeyPath: s.config.DefaultKeyPath, JumpHosts: jumps}
 }
 
 // UploadFile 将内容写入远程文件（通过heredoc创建，避免外部SFTP依赖）
@@ -2158,6 +2175,11 @@ func (s *SSHService) UploadFile(host string, port int, user, password string, co
 	return s.UploadBinaryFile(host, port, user, password, content, remotePath, true)
 }
 
+// UploadFileVia 经跳板机链将内容写入远程文件
+func (s *SSHService) UploadFileVia(host string, port int, user, password string, jumps models.SSHJumpChain, content []byte, remotePath string) error {
+	return s.uploadBinaryFileOn(s.passwordConnection(host, port, user, password, jumps), content, remotePath, true)
+}
+
 // ReadFile 读取远程文件内容
 func (s *SSHService) ReadFile(host string, port int, user, password, remotePath string) (string, error) {
 	cmd := fmt.Sprintf("/bin/sh -c 'cat %s'", remotePath)
@@ -2166,12 +2188,16 @@ func (s *SSHService) ReadFile(host string, port int, user, password, remotePath
 
 // UploadBinaryFile 以base64方式安全上传二进制到远程，并可选设置可执行权限
 func (s *SSHService) UploadBinaryFile(host string, port int, user, password string, content []byte, remotePath string, makeExecutable bool) error {
+	return s.uploadBinaryFileOn(s.passwordConnection(host, port, user, password, nil), content, remotePath, makeExecutable)
+}
+
+func (s *SSHService) uploadBinaryFileOn(conn SSHConnection, content []byte, remotePath string, makeExecutable bool) error {
 	// 分块写入临时b64文件，避免命令长度限制
 	b64 := base64.StdEncoding.EncodeToString(content)
 	const chunkSize = 32 * 1024
 	tmp := "/tmp/.aimatrix_upload.b64"
 	// 清理旧文件
-	_, _ = s.ExecuteCommand(host, port, user, password, fmt.Sprintf("/bin/sh -lc 'rm -f %s'", tmp))
+	_, _ = s.ExecuteCommandOn(conn, fmt.Sprintf("/bin/sh -lc 'rm -f %s'", tmp))
 	for i := 0; i < len(b64); i += chunkSize {
 		end := i + chunkSize
 		if end > len(b64) {
@@ -2180,23 +2206,23 @@ func (s *SSHService) UploadBinaryFile(host string, port int, user, password stri
 		chunk := b64[i:end]
 		// 逐块追加
 		cmd := fmt.Sprintf("/bin/sh -lc 'printf %s >> %s'", singleQuote(chunk), tmp)
-		if _, err := s.ExecuteCommand(host, port, user, password, cmd); err != nil {
+		if _, err := s.ExecuteCommandOn(conn, cmd); err != nil {
 			return err
 		}
 	}
 	// 解码并写入目标
 	finalize := fmt.Sprintf("/bin/sh -lc 'base64 -d %s > %s'", tmp, remotePath)
-	if _, err := s.ExecuteCommand(host, port, user, password, finalize); err != nil {
+	if _, err := s.ExecuteCommandOn(conn, finalize); err != nil {
 		return err
 	}
 	// 设置权限
 	if makeExecutable {
-		if _, err := s.ExecuteCommand(host, port, user, password, fmt.Sprintf("/bin/sh -lc 'chmod +x %s'", remotePath)); err != nil {
+		if _, err := s.ExecuteCommandOn(conn, fmt.Sprintf("/bin/sh -lc 'chmod +x %s'", remotePath)); err != nil {
 			return err
 		}
 	}
 	// 清理临时文件
-	_, _ = s.ExecuteCommand(host, port, user, password, fmt.Sprintf("/bin/sh -lc 'rm -f %s'", tmp))
+	_, _ = s.ExecuteCommandOn(conn, fmt.Sprintf("/bin/sh -lc 'rm -f %s'", tmp))
 	return nil
 }
 
@@ -2303,60 +2329,6 @@ func (s *SSHService) executeLocalCommand(ctx context.Context, cmd string) (strin
 	return string(output), err
 }
 
-// waitForSSHReady 等待SSH服务就绪
-func (s *SSHService) waitForSSHReady(ctx context.Context, host string, port int, user, password string) DeploymentResult {
-	start := time.Now()
-	maxRetries := 30 // 最多等待30秒
-
-	for i := 0; i < maxRetries; i++ {
-		select {
-		case <-ctx.Done():
-			return DeploymentResult{
-				Host:     host,
-				Success:  false,
-				Output:   "",
-				Error:    "等待SSH就绪时超时",
-				Duration: time.Since(start),
-			}
-		default:
-		}
-
-		// 尝试SSH连接
-		config := &ssh.ClientConfig{
-			User: user,
-			Auth: []ssh.AuthMethod{
-				ssh.Password(password),
-			},
-			HostKeyCallback: ssh.InsecureIgnoreHostKey(),
-			Timeout:         3 * time.Second,
-		}
-
-		address := fmt.Sprintf("%s:%d", host, port)
-		conn, err := ssh.Dial("tcp", address, config)
-		if err == nil {
-			conn.Close()
-			return DeploymentResult{
-				Host:     host,
-				Success:  true,
-				Output:   fmt.Sprintf("SSH连接就绪，耗时 %v", time.Since(start)),
-				Error:    "",
-				Duration: time.Since(start),
-			}
-		}
-
-		// 等待1秒后重试
-		time.Sleep(1 * time.Second)
-	}
-
-	return DeploymentResult{
-		Host:     host,
-		Success:  false,
-		Output:   "",
-		Error:    fmt.Sprintf("等待SSH就绪超时，最后错误: 连接超时"),
-		Duration: time.Since(start),
-	}
-}
-
 // TestSSHConnection 测试SSH连接
 func (s *SSHService) TestSSHConnection(ctx context.Context, conn SSHConnection) (string, error) {
 	client, err := s.connectSSH(conn)
@@ -2647,7 +2619,7 @@ func (s *SSHService) DetectNodeHardware(ctx context.Context, host string, port i
 	info := &NodeHardwareInfo{}
 
 	// 1. 检测CPU信息
-	cpuInfo, err := s.executeCommand(client, "lscpu | grep -E '^CPU\\(s\\)|^Socket|^Core|^Thread|^Architecture'")
+	cpuInfo, err := s.executeCommand(client.Client, "lscpu | grep -E '^CPU\\(s\\)|^Socket|^Core|^Thread|^Architecture'")
 	if err != nil {
 		log.Printf("[WARN] 获取CPU信息失败: %v", err)
 	} else {
@@ -2655,7 +2627,7 @@ func (s *SSHService) DetectNodeHardware(ctx context.Context, host string, port i
 	}
 
 	// 2. 检测内存信息（MB）
-	memInfo, err := s.executeCommand(client, "free -m | grep '^Mem:' | awk '{print $2}'")
+	memInfo, err := s.executeCommand(client.Client, "free -m | grep '^Mem:' | awk '{print $2}'")
 	if err != nil {
 		log.Printf("[WARN] 获取内存信息失败: %v", err)
 	} else {
@@ -2665,7 +2637,7 @@ func (s *SSHService) DetectNodeHardware(ctx context.Context, host string, port i
 	}
 
 	// 3. 检测磁盘信息（GB）
-	diskInfo, err := s.executeCommand(client, "df -BG / | tail -1 | awk '{print $2}' | sed 's/G//'")
+	diskInfo, err := s.executeCommand(client.Client, "df -BG / | tail -1 | awk '{print $2}' | sed 's/G//'")
 	if err != nil {
 		log.Printf("[WARN] 获取磁盘信息失败: %v", err)
 	} else {
@@ -2675,7 +2647,7 @@ func (s *SSHService) DetectNodeHardware(ctx context.Context, host string, port i
 	}
 
 	// 4. 检测GPU信息（NVIDIA）
-	gpuInfo, err := s.executeCommand(client, "nvidia-smi -L 2>/dev/null | wc -l")
+	gpuInfo, err := s.executeCommand(client.Client, "nvidia-smi -L 2>/dev/null | wc -l")
 	if err == nil {
 		if gpus, err := strconv.Atoi(strings.TrimSpace(gpuInfo)); err == nil && gpus > 0 {
 			info.GPUs = gpus
@@ -2683,7 +2655,7 @@ func (s *SSHService) DetectNodeHardware(ctx context.Context, host string, port i
 	}
 
 	// 5. 检测XPU信息（昆仑芯）
-	xpuInfo, err := s.executeCommand(client, "xpu-smi 2>/dev/null | grep -c 'Device'")
+	xpuInfo, err := s.executeCommand(client.Client, "xpu-smi 2>/dev/null | grep -c 'Device'")
 	if err == nil {
 		if xpus, err := strconv.Atoi(strings.TrimSpace(xpuInfo)); err == nil && xpus > 0 {
 			info.XPUs = xpus
@@ -2691,7 +2663,7 @@ func (s *SSHService) DetectNodeHardware(ctx context.Context, host string, port i
 	}
 
 	// 6. 检测操作系统信息
-	osInfo, err := s.executeCommand(client, "cat /etc/os-release | grep -E '^ID=|^VERSION_ID='")
+	osInfo, err := s.executeCommand(client.Client, "cat /etc/os-release | grep -E '^ID=|^VERSION_ID='")
 	if err == nil {
 		info.parseOSInfo(osInfo)
 	}
@@ -1,5 +1,4 @@
 package services
-package services
 
 import (
 	"testing"
@@ -142,9 +141,6 @@ func TestCgroupCollector_Collect(t *testing.T) {
 }
 
 func TestSystemMetricsCollectorService_Collect(t *testing.T) {
-	// 重置单例以便测试
-	systemMetricsCollectorOnce.Do(func() {}) // 空操作，确保单例初始化
-
 	svc := NewSystemMetricsCollectorService()
 
 	t.Logf("部署类型: %s", svc.GetDeploymentType())
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// TerminalRecorder 以 asciicast v2 格式记录终端会话
+// 格式：首行为 JSON 头，其后每行一个事件 [秒数, "o"|"r", 数据]
+type TerminalRecorder struct {
+	mu      sync.Mutex
+	file    *os.File
+	writer  *bufio.Writer
+	start   time.Time
+	pending []byte // 被截断的 UTF-8 尾部字节，等待与下一段输出拼接
+	closed  bool
+}
+
+// asciicastHeader asciicast v2 文件头
+type asciicastHeader struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// NewTerminalRecorder 创建录像文件并写入文件头
+func NewTerminalRecorder(cols, rows int, title string) (*TerminalRecorder, error) {
+	file, err := os.CreateTemp("", "terminal-*.cast")
+	if err != nil {
+		return nil, err
+	}
+	r := &TerminalRecorder{file: file, writer: bufio.NewWriter(file), start: time.Now()}
+	header, _ := json.Marshal(asciicastHeader{
+		Version:   2,
+		Width:     cols,
+		Height:    rows,
+		Timestamp: r.start.Unix(),
+		Title:     title,
+		Env:       map[string]string{"TERM": "xterm-256color", "SHELL": "/bin/bash"},
+	})
+	r.writer.Write(header)
+	r.writer.WriteByte('\n')
+	return r, nil
+}
+
+// Output 记录终端输出，返回可安全发送给浏览器的完整 UTF-8 文本
+func (r *TerminalRecorder) Output(data []byte) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	buf := append(r.pending, data...)
+	complete, rest := splitValidUTF8(buf)
+	r.pending = append([]byte(nil), rest...)
+	if len(complete) == 0 {
+		return ""
+	}
+	text := string(complete)
+	r.writeEvent("o", text)
+	return text
+}
+
+// Resize 记录终端尺寸变化
+func (r *TerminalRecorder) Resize(cols, rows int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.writeEvent("r", fmt.Sprintf("%dx%d", cols, rows))
+}
+
+func (r *TerminalRecorder) writeEvent(kind, data string) {
+	if r.closed {
+		return
+	}
+	elapsed := time.Since(r.start).Seconds()
+	event, _ := json.Marshal([]interface{}{float64(int64(elapsed*1e6)) / 1e6, kind, data})
+	r.writer.Write(event)
+	r.writer.WriteByte('\n')
+}
+
+// Close 刷新缓冲并关闭文件，返回录像文件路径与大小
+func (r *TerminalRecorder) Close() (string, int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.closed {
+		if len(r.pending) > 0 {
+			r.writeEvent("o", string(r.pending))
+			r.pending = nil
+		}
+		r.closed = true
+		r.writer.Flush()
+		r.file.Close()
+	}
+	info, err := os.Stat(r.file.Name())
+	if err != nil {
+		return r.file.Name(), 0, err
+	}
+	return r.file.Name(), info.Size(), nil
+}
+
+// Remove 删除本地录像文件
+func (r *TerminalRecorder) Remove() {
+	os.Remove(r.file.Name())
+}
+
+// splitValidUTF8 将缓冲区拆分为完整的 UTF-8 前缀与末尾不完整的多字节序列
+func splitValidUTF8(buf []byte) (complete, rest []byte) {
+	// UTF-8 字符最多 4 字节，只需检查末尾 3 字节
+	for i := 1; i <= 3 && i <= len(buf); i++ {
+		c := buf[len(buf)-i]
+		if c < 0x80 {
+			break // ASCII，之前不可能有未完成的序列
+		}
+		if utf8.RuneStart(c) {
+			if !utf8.FullRune(buf[len(buf)-i:]) {
+				return buf[:len(buf)-i], buf[len(buf)-i:]
+			}
+			break
+		}
+	}
+	return buf, nil
+}
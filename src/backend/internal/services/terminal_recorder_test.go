@@ -0,0 +1,77 @@
+package services
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+func TestSplitValidUTF8(t *testing.T) {
+	full := []byte("ok 中文")
+	cases := []struct {
+		in       []byte
+		complete string
+		rest     int
+	}{
+		{full, "ok 中文", 0},
+		{full[:len(full)-1], "ok 中", 2},
+		{full[:len(full)-2], "ok 中", 1},
+		{[]byte("plain"), "plain", 0},
+		{[]byte{}, "", 0},
+	}
+	for _, tc := range cases {
+		complete, rest := splitValidUTF8(tc.in)
+		if string(complete) != tc.complete || len(rest) != tc.rest {
+			t.Errorf("splitValidUTF8(%q) = %q, %d 字节剩余；期望 %q, %d", tc.in, complete, len(rest), tc.complete, tc.rest)
+		}
+	}
+}
+
+func TestTerminalRecorderAsciicast(t *testing.T) {
+	r, err := NewTerminalRecorder(80, 24, "root@node-1:22")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Remove()
+
+	text := []byte("$ echo 你好\r\n")
+	split := len("$ echo 你") + 1 // 截断在 “好” 的第二个字节之前
+	if got := r.Output(text[:split]); got != "$ echo 你" {
+		t.Errorf("第一段输出 = %q", got)
+	}
+	if got := r.Output(text[split:]); got != "好\r\n" {
+		t.Errorf("第二段输出 = %q", got)
+	}
+	r.Resize(100, 30)
+
+	path, size, err := r.Close()
+	if err != nil || size == 0 {
+		t.Fatalf("Close() = %s, %d, %v", path, size, err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+
+	scanner.Scan()
+	var header asciicastHeader
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil || header.Version != 2 || header.Width != 80 || header.Height != 24 {
+		t.Fatalf("文件头错误: %s (%v)", scanner.Text(), err)
+	}
+	var kinds, data []string
+	for scanner.Scan() {
+		var event []interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil || len(event) != 3 {
+			t.Fatalf("事件格式错误: %s", scanner.Text())
+		}
+		kinds = append(kinds, event[1].(string))
+		data = append(data, event[2].(string))
+	}
+	if len(kinds) != 3 || kinds[0] != "o" || kinds[1] != "o" || kinds[2] != "r" || data[2] != "100x30" {
+		t.Errorf("事件序列错误: %v %q", kinds, data)
+	}
+}
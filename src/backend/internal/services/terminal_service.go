@@ -0,0 +1,422 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aresnasa/ai-infra-matrix/src/backend/internal/models"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/ssh"
+	"gorm.io/gorm"
+)
+
+// TerminalAccessDeniedError 用户无权连接目标主机
+type TerminalAccessDeniedError struct {
+	Reason string
+}
+
+func (e *TerminalAccessDeniedError) Error() string {
+	return "terminal access denied: " + e.Reason
+}
+
+// TerminalTarget 终端连接目标（含解密后的凭据，不落库、不返回前端）
+type TerminalTarget struct {
+	Kind       string
+	TargetID   uint
+	ClusterID  uint
+	MinionID   string
+	Host       string
+	Port       int
+	User       string
+	Password   string
+	KeyPath    string
+	PrivateKey string
+	JumpHosts  models.SSHJumpChain
+}
+
+// Label 目标的可读名称
+func (t *TerminalTarget) Label() string {
+	return fmt.Sprintf("%s@%s:%d", t.User, t.Host, t.Port)
+}
+
+// TerminalService Web 终端服务：目标解析、授权、SSH 连接与会话录像
+type TerminalService struct {
+	db          *gorm.DB
+	bucket      string
+	IdleTimeout time.Duration
+	MaxDuration time.Duration
+}
+
+var (
+	terminalServiceInstance *TerminalService
+	terminalServiceOnce     sync.Once
+)
+
+// NewTerminalService 创建 Web 终端服务（单例）
+func NewTerminalService(db *gorm.DB) *TerminalService {
+	terminalServiceOnce.Do(func() {
+		terminalServiceInstance = &TerminalService{
+			db:          db,
+			bucket:      getEnvOrDefault("TERMINAL_RECORDING_BUCKET", "terminal-recordings"),
+			IdleTimeout: parseDurationEnv("TERMINAL_IDLE_TIMEOUT", 15*time.Minute),
+			MaxDuration: parseDurationEnv("TERMINAL_MAX_DURATION", 8*time.Hour),
+		}
+		if err := db.AutoMigrate(&models.TerminalSession{}); err != nil {
+			log.Printf("[TerminalService] 自动迁移失败: %v", err)
+		}
+		// 服务重启前未结束的会话已随连接断开
+		db.Model(&models.TerminalSession{}).Where("status = ?", models.TerminalSessionActive).
+			Updates(map[string]interface{}{"status": models.TerminalSessionClosed, "close_reason": models.TerminalCloseShutdown})
+	})
+	return terminalServiceInstance
+}
+
+// GetTerminalService 获取 Web 终端服务实例
+func GetTerminalService() *TerminalService {
+	return terminalServiceInstance
+}
+
+func parseDurationEnv(key string, def time.Duration) time.Duration {
+	if v := os.Getenv(key); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+		log.Printf("[TerminalService] 无效的 %s=%q，使用默认值 %s", key, v, def)
+	}
+	return def
+}
+
+// ==================== 目标解析与授权 ====================
+
+// ResolveSlurmNodeTarget 使用 SlurmNode 中保存的凭据
+func (s *TerminalService) ResolveSlurmNodeTarget(nodeID uint) (*TerminalTarget, error) {
+	var node models.SlurmNode
+	if err := s.db.First(&node, nodeID).Error; err != nil {
+		return nil, err
+	}
+	target := &TerminalTarget{
+		Kind:      models.TerminalTargetSlurmNode,
+		TargetID:  node.ID,
+		ClusterID: node.ClusterID,
+		MinionID:  node.SaltMinionID,
+		Host:      node.Host,
+		Port:      node.Port,
+		User:      node.Username,
+		Password:  node.Password,
+		JumpHosts: resolveNodeJumpHosts(s.db, &node),
+	}
+	if node.AuthType == "key" {
+		target.KeyPath = node.KeyPath
+	}
+	return target, nil
+}
+
+// ResolveHostTemplateTarget 使用主机模板中保存的凭据（按主机地址或 minion ID 匹配）
+func (s *TerminalService) ResolveHostTemplateTarget(templateID uint, host string) (*TerminalTarget, error) {
+	var template models.HostTemplate
+	if err := s.db.First(&template, templateID).Error; err != nil {
+		return nil, err
+	}
+	hosts, err := template.GetHosts()
+	if err != nil {
+		return nil, fmt.Errorf("decrypt host template: %v", err)
+	}
+	for _, h := range hosts {
+		if h.Host != host && (h.MinionID == "" || h.MinionID != host) {
+			continue
+		}
+		minionID := h.MinionID
+		if minionID == "" {
+			minionID = h.Host
+		}
+		return &TerminalTarget{
+			Kind:     models.TerminalTargetHostTemplate,
+			TargetID: template.ID,
+			MinionID: minionID,
+			Host:     h.Host,
+			Port:     h.Port,
+			User:     h.Username,
+			Password: h.Password,
+		}, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+// Authorize 通过集群权限校验用户是否可连接目标（管理员直接放行）
+// Slurm 节点要求集群 connect 权限；minion 主机要求所属 Master 的 connect 权限
+func (s *TerminalService) Authorize(ctx context.Context, userID uint, isAdmin bool, target *TerminalTarget) error {
+	if isAdmin {
+		return nil
+	}
+	permService := NewClusterPermissionService(s.db)
+	var (
+		result *models.VerifyPermissionResult
+		err    error
+	)
+	if target.Kind == models.TerminalTargetSlurmNode {
+		result, err = permService.CheckSlurmAccess(ctx, userID, target.ClusterID, models.VerbConnect, "")
+	} else {
+		result, err = permService.CheckSaltstackAccess(ctx, userID, "", models.VerbConnect, target.MinionID, "")
+	}
+	if err != nil {
+		return err
+	}
+	if !result.Allowed {
+		return &TerminalAccessDeniedError{Reason: result.Reason}
+	}
+	return nil
+}
+
+// Dial 建立到目标主机的 SSH 连接
+func (s *TerminalService) Dial(target *TerminalTarget) (*ssh.Client, error) {
+	config := &ssh.ClientConfig{
+		User:            target.User,
+		HostKeyCallback: KnownHostsCallback(),
+		Timeout:         15 * time.Second,
+	}
+	switch {
+	case target.PrivateKey != "":
+		signer, err := ssh.ParsePrivateKey([]byte(target.PrivateKey))
+		if err != nil {
+			return nil, fmt.Errorf("parse private key: %v", err)
+		}
+		config.Auth = append(config.Auth, ssh.PublicKeys(signer))
+	case target.KeyPath != "":
+		keyData, err := os.ReadFile(target.KeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("read private key: %v", err)
+		}
+		signer, err := ssh.ParsePrivateKey(keyData)
+		if err != nil {
+			return nil, fmt.Errorf("parse private key: %v", err)
+		}
+		config.Auth = append(config.Auth, ssh.PublicKeys(signer))
+	}
+	if target.Password != "" {
+		config.Auth = append(config.Auth, ssh.Password(target.Password),
+			ssh.KeyboardInteractive(func(user, instruction string, questions []string, echos []bool) ([]string, error) {
+				answers := make([]string, len(questions))
+				for i := range questions {
+					answers[i] = target.Password
+				}
+				return answers, nil
+			}))
+	}
+	if len(config.Auth) == 0 {
+		return nil, errors.New("no credentials stored for target host")
+	}
+	port := target.Port
+	if port == 0 {
+		port = 22
+	}
+	if err := target.JumpHosts.Validate(); err != nil {
+		return nil, err
+	}
+	return dialSSHVia(target.JumpHosts, fmt.Sprintf("%s:%d", target.Host, port), config)
+}
+
+// ==================== 会话记录 ====================
+
+// StartSession 创建会话记录
+func (s *TerminalService) StartSession(userID uint, username, clientIP string, target *TerminalTarget, cols, rows int) (*models.TerminalSession, error) {
+	port := target.Port
+	if port == 0 {
+		port = 22
+	}
+	session := &models.TerminalSession{
+		SessionID:  uuid.New().String(),
+		UserID:     userID,
+		Username:   username,
+		ClientIP:   clientIP,
+		TargetKind: target.Kind,
+		TargetID:   target.TargetID,
+		ClusterID:  target.ClusterID,
+		MinionID:   target.MinionID,
+		Host:       target.Host,
+		Port:       port,
+		SSHUser:    target.User,
+		Cols:       cols,
+		Rows:       rows,
+		Status:     models.TerminalSessionActive,
+		StartedAt:  time.Now(),
+	}
+	if err := s.db.Create(session).Error; err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// FinishSession 上传录像、更新会话记录并写入审计日志
+func (s *TerminalService) FinishSession(session *models.TerminalSession, recorder *TerminalRecorder, reason string, sessionErr error) {
+	now := time.Now()
+	session.EndedAt = &now
+	session.DurationMs = now.Sub(session.StartedAt).Milliseconds()
+	session.CloseReason = reason
+	session.Status = models.TerminalSessionClosed
+	if sessionErr != nil {
+		session.Status = models.TerminalSessionFailed
+		session.ErrorMessage = sessionErr.Error()
+	}
+
+	if recorder != nil {
+		if err := s.uploadRecording(session, recorder); err != nil {
+			log.Printf("[TerminalService] 会话录像上传失败: session=%s, err=%v", session.SessionID, err)
+			if session.ErrorMessage == "" {
+				session.ErrorMessage = "recording upload failed: " + err.Error()
+			}
+		}
+	}
+
+	s.db.Model(session).Updates(map[string]interface{}{
+		"status":           session.Status,
+		"close_reason":     session.CloseReason,
+		"error_message":    session.ErrorMessage,
+		"bytes_in":         session.BytesIn,
+		"bytes_out":        session.BytesOut,
+		"cols":             session.Cols,
+		"rows":             session.Rows,
+		"recording_bucket": session.RecordingBucket,
+		"recording_key":    session.RecordingKey,
+		"recording_size":   session.RecordingSize,
+		"ended_at":         now,
+		"duration_ms":      session.DurationMs,
+	})
+
+	status := models.AuditStatusSuccess
+	if session.Status == models.TerminalSessionFailed {
+		status = models.AuditStatusFailed
+	}
+	GetAuditService().NewAuditEntry(models.AuditCategorySecurity, models.AuditActionTerminalSession).
+		WithUser(session.UserID, session.Username, session.ClientIP).
+		WithResource("terminal_session", session.SessionID, session.SSHUser+"@"+session.Host).
+		WithStatus(status).
+		WithErrorMessage(session.ErrorMessage).
+		WithMetadata(map[string]interface{}{
+			"session_id":       session.SessionID,
+			"target_kind":      session.TargetKind,
+			"target_id":        session.TargetID,
+			"host":             session.Host,
+			"minion_id":        session.MinionID,
+			"close_reason":     session.CloseReason,
+			"duration_ms":      session.DurationMs,
+			"bytes_in":         session.BytesIn,
+			"bytes_out":        session.BytesOut,
+			"recording_bucket": session.RecordingBucket,
+			"recording_key":    session.RecordingKey,
+		}).
+		WithTags("terminal", "recording").
+		SaveAsync()
+}
+
+func (s *TerminalService) uploadRecording(session *models.TerminalSession, recorder *TerminalRecorder) error {
+	path, size, err := recorder.Close()
+	defer recorder.Remove()
+	if err != nil {
+		return err
+	}
+
+	var config models.ObjectStorageConfig
+	if err := s.db.Where("is_active = ?", true).First(&config).Error; err != nil {
+		return errors.New("no active object storage configured")
+	}
+	store, err := NewSeaweedFSService(&config)
+	if err != nil {
+		return err
+	}
+	buckets, err := store.ListBuckets()
+	if err != nil {
+		return err
+	}
+	exists := false
+	for _, b := range buckets {
+		if b.Name == s.bucket {
+			exists = true
+			break
+		}
+	}
+	if !exists {
+		if err := store.CreateBucket(s.bucket); err != nil {
+			return fmt.Errorf("prepare bucket %s: %v", s.bucket, err)
+		}
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	key := fmt.Sprintf("%s/%s/%s.cast", session.StartedAt.Format("2006/01/02"), sanitizeObjectSegment(session.Username), session.SessionID)
+	if err := store.UploadObject(s.bucket, key, file, size, "application/x-asciicast"); err != nil {
+		return err
+	}
+	session.RecordingBucket = s.bucket
+	session.RecordingKey = key
+	session.RecordingSize = size
+	return nil
+}
+
+func sanitizeObjectSegment(v string) string {
+	v = strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r < 0x20 {
+			return '_'
+		}
+		return r
+	}, v)
+	if v == "" {
+		return "unknown"
+	}
+	return v
+}
+
+// ListSessions 分页查询会话；userID 为 0 时返回全部
+func (s *TerminalService) ListSessions(userID uint, host string, page, pageSize int) ([]models.TerminalSession, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	query := s.db.Model(&models.TerminalSession{})
+	if userID > 0 {
+		query = query.Where("user_id = ?", userID)
+	}
+	if host != "" {
+		query = query.Where("host = ? OR minion_id = ?", host, host)
+	}
+	var total int64
+	query.Count(&total)
+	var sessions []models.TerminalSession
+	err := query.Order("started_at DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&sessions).Error
+	return sessions, total, err
+}
+
+// GetSession 获取会话记录
+func (s *TerminalService) GetSession(id uint) (*models.TerminalSession, error) {
+	var session models.TerminalSession
+	if err := s.db.First(&session, id).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// RecordingURL 生成录像的临时下载地址（供 asciinema-player 回放）
+func (s *TerminalService) RecordingURL(session *models.TerminalSession, expires time.Duration) (string, error) {
+	if session.RecordingKey == "" {
+		return "", errors.New("session has no recording")
+	}
+	var config models.ObjectStorageConfig
+	if err := s.db.Where("is_active = ?", true).First(&config).Error; err != nil {
+		return "", errors.New("no active object storage configured")
+	}
+	store, err := NewSeaweedFSService(&config)
+	if err != nil {
+		return "", err
+	}
+	return store.GetPresignedURL(session.RecordingBucket, session.RecordingKey, expires)
+}
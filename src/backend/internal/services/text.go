@@ -0,0 +1,21 @@
+package services
+
+// truncateText 按字符截断文本（不截断多字节字符），超过 max 个字符时保留前 max 个并追加 "..."
+func truncateText(s string, max int) string {
+	if t := truncateRunes(s, max); len(t) < len(s) {
+		return t + "..."
+	}
+	return s
+}
+
+// truncateRunes 按字符截断文本，不追加省略号（用于有长度上限的存储字段）
+func truncateRunes(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max])
+}
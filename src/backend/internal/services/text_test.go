@@ -0,0 +1,32 @@
+package services
+
+import "testing"
+
+func TestTruncateText(t *testing.T) {
+	cases := []struct {
+		in   string
+		max  int
+		want string
+	}{
+		{"short", 10, "short"},
+		{"exactly10!", 10, "exactly10!"},
+		{"hello world", 5, "hello..."},
+		{"节点不可达", 5, "节点不可达"}, // 字节数超过 max 但字符数未超过
+		{"节点不可达，请检查网络", 5, "节点不可达..."},
+		{"", 0, ""},
+	}
+	for _, tc := range cases {
+		if got := truncateText(tc.in, tc.max); got != tc.want {
+			t.Errorf("truncateText(%q, %d) = %q, 期望 %q", tc.in, tc.max, got, tc.want)
+		}
+	}
+}
+
+func TestTruncateRunes(t *testing.T) {
+	if got := truncateRunes("节点不可达，请检查网络", 5); got != "节点不可达" {
+		t.Errorf("truncateRunes 截断结果 = %q", got)
+	}
+	if got := truncateRunes("short", 10); got != "short" {
+		t.Errorf("truncateRunes 未超长时应原样返回, got %q", got)
+	}
+}